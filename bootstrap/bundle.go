@@ -0,0 +1,120 @@
+// Package bootstrap delivers to the guest what it needs to connect to the build server:
+// the address of the server, the build ID and the TLS material of the guest.
+package bootstrap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+)
+
+// ErrIncompleteBundle is returned when a bundle misses the values needed to connect.
+var ErrIncompleteBundle = errors.New("incomplete bootstrap bundle")
+
+// Bundle is the guest bootstrap bundle.
+type Bundle struct {
+	// Address is the host and port of the server as reachable from the guest,
+	// optionally prefixed with the scheme of a transport.
+	Address string `json:"address"`
+	// BuildID is the ID of the build the guest executes.
+	BuildID string `json:"buildId,omitempty"`
+	// ServerName is the name the guest verifies the certificate of the server against.
+	ServerName string `json:"serverName,omitempty"`
+	// Token is an optional opaque credential the host application issues to the guest,
+	// for example to authorize the guest at a proxy in front of the server. The server
+	// authenticates the guest by its client certificate.
+	Token string `json:"token,omitempty"`
+	// CA contains the PEM encoded certificates the guest trusts the server certificate by.
+	CA string `json:"ca,omitempty"`
+	// Certificate is the PEM encoded client certificate of the guest.
+	Certificate string `json:"certificate,omitempty"`
+	// Key is the PEM encoded private key of the client certificate.
+	Key string `json:"key,omitempty"`
+}
+
+// NewBundle creates the bundle of a started server with the auto-generated CA, the address
+// is the address of the server as reachable from the guest, for example the address of the tap interface.
+func NewBundle(cfg *rootfs.GRPCServiceConfig, address, buildID string) (*Bundle, error) {
+	if cfg.TLSConfigClient == nil || len(cfg.TLSConfigClient.Certificates) == 0 || len(cfg.TLSCAPEMChain) == 0 {
+		return nil, fmt.Errorf("%w: the server was not started with the auto-generated CA", ErrIncompleteBundle)
+	}
+	certificate := cfg.TLSConfigClient.Certificates[0]
+	certificatePEM := []byte{}
+	for _, der := range certificate.Certificate {
+		certificatePEM = append(certificatePEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding client key: %v", err)
+	}
+	return &Bundle{
+		Address:     address,
+		BuildID:     buildID,
+		ServerName:  cfg.ServerName,
+		CA:          strings.Join(cfg.TLSCAPEMChain, ""),
+		Certificate: string(certificatePEM),
+		Key:         string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}, nil
+}
+
+// ParseBundle reads the bundle from its JSON object, for example from the file written by the guest configuration.
+func ParseBundle(data []byte) (*Bundle, error) {
+	bundle := &Bundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("failed parsing bundle: %v", err)
+	}
+	return bundle, bundle.Validate()
+}
+
+// Validate returns ErrIncompleteBundle if the bundle has no address or an incomplete TLS material.
+func (b *Bundle) Validate() error {
+	if b.Address == "" {
+		return fmt.Errorf("%w: no address", ErrIncompleteBundle)
+	}
+	if (b.Certificate == "") != (b.Key == "") {
+		return fmt.Errorf("%w: client certificate and key must be given together", ErrIncompleteBundle)
+	}
+	return nil
+}
+
+// TLSConfig returns the TLS configuration of the guest, nil if the bundle has no CA.
+func (b *Bundle) TLSConfig() (*tls.Config, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	if b.CA == "" {
+		return nil, nil
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(b.CA)) {
+		return nil, fmt.Errorf("%w: no CA certificates", ErrIncompleteBundle)
+	}
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: b.ServerName}
+	if b.Certificate != "" {
+		certificate, err := tls.X509KeyPair([]byte(b.Certificate), []byte(b.Key))
+		if err != nil {
+			return nil, fmt.Errorf("failed loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// ClientConfig returns the configuration of the guest client connecting with the bundle.
+func (b *Bundle) ClientConfig() (*rootfs.GRPCClientConfig, error) {
+	tlsConfig, err := b.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &rootfs.GRPCClientConfig{
+		HostPort:  b.Address,
+		BuildID:   b.BuildID,
+		TLSConfig: tlsConfig,
+	}, nil
+}
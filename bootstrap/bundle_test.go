@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleConnectsGuest(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &rootfs.GRPCServiceConfig{
+		ServerName:        "test-grpc-server",
+		BindHostPort:      "127.0.0.1:0",
+		EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+	}
+	testServer := rootfs.NewTestServer(t, logger.Named("grpc-server"), grpcConfig, &rootfs.WorkContext{
+		BuildID:            "build-1",
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+	})
+	testServer.Start()
+	select {
+	case startErr := <-testServer.FailedNotify():
+		t.Fatal("expected the GRPC server to start but it failed", startErr)
+	case <-testServer.ReadyNotify():
+	}
+	defer testServer.Stop()
+
+	bundle, err := NewBundle(grpcConfig, grpcConfig.BindHostPort, "build-1")
+	assert.Nil(t, err)
+	assert.Contains(t, bundle.CA, "BEGIN CERTIFICATE")
+	assert.Contains(t, bundle.Key, "BEGIN PRIVATE KEY")
+
+	// the bundle survives the round trip through the metadata service:
+	document, err := bundle.MMDS()
+	assert.Nil(t, err)
+	parsed, err := ParseMMDS(document)
+	assert.Nil(t, err)
+	assert.Equal(t, bundle, parsed)
+
+	clientConfig, err := parsed.ClientConfig()
+	assert.Nil(t, err)
+	client, err := rootfs.NewClient(logger.Named("grpc-client"), clientConfig)
+	assert.Nil(t, err)
+	assert.Nil(t, client.Commands())
+	assert.Equal(t, commands.RunWithDefaults("true"), client.NextCommand())
+	assert.Nil(t, client.Success())
+}
+
+func TestBundleIncomplete(t *testing.T) {
+	_, err := NewBundle(&rootfs.GRPCServiceConfig{}, "127.0.0.1:5000", "")
+	assert.True(t, errors.Is(err, ErrIncompleteBundle))
+
+	_, err = (&Bundle{}).ClientConfig()
+	assert.True(t, errors.Is(err, ErrIncompleteBundle))
+
+	_, err = (&Bundle{Address: "127.0.0.1:5000", Certificate: "cert"}).ClientConfig()
+	assert.True(t, errors.Is(err, ErrIncompleteBundle))
+
+	clientConfig, err := (&Bundle{Address: "127.0.0.1:5000"}).ClientConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, clientConfig.TLSConfig)
+}
@@ -0,0 +1,152 @@
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultGuestBundlePath is the default path the guest configuration writes the bundle to.
+	DefaultGuestBundlePath = "/etc/firebuild/bootstrap.json"
+	// DefaultGuestUnitName is the default name of the systemd unit of the Ignition configuration.
+	DefaultGuestUnitName = "firebuild-guest.service"
+	// IgnitionVersion is the version of the Ignition configuration specification of IgnitionConfig.
+	IgnitionVersion = "3.3.0"
+)
+
+// ErrNoGuestCommand is returned when the guest options have no client command.
+var ErrNoGuestCommand = errors.New("no guest client command")
+
+// GuestOptions configures the guest configurations for the guests booted without Firecracker,
+// for example QEMU or cloud VMs, which cannot read the bundle from the metadata service.
+type GuestOptions struct {
+	// BundlePath is the path the bundle is written to, DefaultGuestBundlePath when empty.
+	// The client reads it with ParseBundle.
+	BundlePath string
+	// Command is the client invocation executed after the bundle was written,
+	// for example []string{"/usr/bin/vminit", "--bootstrap", "/etc/firebuild/bootstrap.json"}.
+	Command []string
+	// UnitName is the name of the systemd unit running the command in the Ignition configuration,
+	// DefaultGuestUnitName when empty.
+	UnitName string
+}
+
+func (o *GuestOptions) withDefaults() (*GuestOptions, error) {
+	if o == nil || len(o.Command) == 0 {
+		return nil, ErrNoGuestCommand
+	}
+	options := *o
+	if options.BundlePath == "" {
+		options.BundlePath = DefaultGuestBundlePath
+	}
+	if options.UnitName == "" {
+		options.UnitName = DefaultGuestUnitName
+	}
+	return &options, nil
+}
+
+// CloudInitUserData returns the cloud-init user data writing the bundle readable by root only
+// and running the client command once on the first boot. The cloud config is written as JSON,
+// which cloud-init reads as YAML.
+func CloudInitUserData(bundle *Bundle, options *GuestOptions) ([]byte, error) {
+	options, err := options.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	bundleBytes, err := marshalGuestBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	cloudConfig := map[string]interface{}{
+		"write_files": []map[string]string{{
+			"path":        options.BundlePath,
+			"owner":       "root:root",
+			"permissions": "0600",
+			"encoding":    "b64",
+			"content":     base64.StdEncoding.EncodeToString(bundleBytes),
+		}},
+		"runcmd": [][]string{options.Command},
+	}
+	return marshalGuestConfig("#cloud-config\n", cloudConfig)
+}
+
+// IgnitionConfig returns the Ignition configuration writing the bundle readable by root only
+// and enabling a systemd unit running the client command once the network is online.
+func IgnitionConfig(bundle *Bundle, options *GuestOptions) ([]byte, error) {
+	options, err := options.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	bundleBytes, err := marshalGuestBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	unit := strings.Join([]string{
+		"[Unit]",
+		"Description=firebuild guest client",
+		"Wants=network-online.target",
+		"After=network-online.target",
+		"ConditionPathExists=" + options.BundlePath,
+		"",
+		"[Service]",
+		"Type=oneshot",
+		"ExecStart=" + systemdCommandLine(options.Command),
+		"",
+		"[Install]",
+		"WantedBy=multi-user.target",
+		"",
+	}, "\n")
+	ignitionConfig := map[string]interface{}{
+		"ignition": map[string]string{"version": IgnitionVersion},
+		"storage": map[string]interface{}{
+			"files": []map[string]interface{}{{
+				"path":      options.BundlePath,
+				"mode":      0600,
+				"overwrite": true,
+				"contents": map[string]string{
+					"source": "data:;base64," + base64.StdEncoding.EncodeToString(bundleBytes),
+				},
+			}},
+		},
+		"systemd": map[string]interface{}{
+			"units": []map[string]interface{}{{
+				"name":     options.UnitName,
+				"enabled":  true,
+				"contents": unit,
+			}},
+		},
+	}
+	return marshalGuestConfig("", ignitionConfig)
+}
+
+func marshalGuestBundle(bundle *Bundle) ([]byte, error) {
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(bundle)
+}
+
+func marshalGuestConfig(header string, config interface{}) ([]byte, error) {
+	buffer := bytes.NewBufferString(header)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config); err != nil {
+		return nil, fmt.Errorf("failed encoding guest configuration: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// systemdCommandLine quotes the arguments for ExecStart, escaping the specifiers and the variables.
+func systemdCommandLine(command []string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$", "\n", `\n`)
+	quoted := []string{}
+	for _, arg := range command {
+		quoted = append(quoted, `"`+replacer.Replace(arg)+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudInitUserData(t *testing.T) {
+	bundle := &Bundle{Address: "10.0.0.1:5000", BuildID: "build-1", CA: "ca"}
+	userData, err := CloudInitUserData(bundle, &GuestOptions{Command: []string{"/usr/bin/vminit", "--bootstrap", DefaultGuestBundlePath}})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(userData), "#cloud-config\n"))
+
+	cloudConfig := struct {
+		WriteFiles []map[string]string `json:"write_files"`
+		RunCmd     [][]string          `json:"runcmd"`
+	}{}
+	assert.Nil(t, json.Unmarshal(userData[len("#cloud-config\n"):], &cloudConfig))
+	assert.Equal(t, 1, len(cloudConfig.WriteFiles))
+	assert.Equal(t, DefaultGuestBundlePath, cloudConfig.WriteFiles[0]["path"])
+	assert.Equal(t, "0600", cloudConfig.WriteFiles[0]["permissions"])
+	assert.Equal(t, [][]string{{"/usr/bin/vminit", "--bootstrap", DefaultGuestBundlePath}}, cloudConfig.RunCmd)
+
+	bundleBytes, err := base64.StdEncoding.DecodeString(cloudConfig.WriteFiles[0]["content"])
+	assert.Nil(t, err)
+	parsed, err := ParseBundle(bundleBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, bundle, parsed)
+}
+
+func TestIgnitionConfig(t *testing.T) {
+	bundle := &Bundle{Address: "10.0.0.1:5000", Token: "token"}
+	config, err := IgnitionConfig(bundle, &GuestOptions{
+		BundlePath: "/var/lib/firebuild/bundle.json",
+		Command:    []string{"/usr/bin/vminit", "--label", `100% "quoted" $HOME`},
+	})
+	assert.Nil(t, err)
+
+	ignitionConfig := struct {
+		Ignition struct {
+			Version string `json:"version"`
+		} `json:"ignition"`
+		Storage struct {
+			Files []struct {
+				Path     string `json:"path"`
+				Mode     int    `json:"mode"`
+				Contents struct {
+					Source string `json:"source"`
+				} `json:"contents"`
+			} `json:"files"`
+		} `json:"storage"`
+		Systemd struct {
+			Units []struct {
+				Name     string `json:"name"`
+				Enabled  bool   `json:"enabled"`
+				Contents string `json:"contents"`
+			} `json:"units"`
+		} `json:"systemd"`
+	}{}
+	assert.Nil(t, json.Unmarshal(config, &ignitionConfig))
+	assert.Equal(t, IgnitionVersion, ignitionConfig.Ignition.Version)
+	assert.Equal(t, 1, len(ignitionConfig.Storage.Files))
+	assert.Equal(t, "/var/lib/firebuild/bundle.json", ignitionConfig.Storage.Files[0].Path)
+	assert.Equal(t, 0600, ignitionConfig.Storage.Files[0].Mode)
+
+	bundleBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ignitionConfig.Storage.Files[0].Contents.Source, "data:;base64,"))
+	assert.Nil(t, err)
+	parsed, err := ParseBundle(bundleBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, bundle, parsed)
+
+	assert.Equal(t, 1, len(ignitionConfig.Systemd.Units))
+	assert.Equal(t, DefaultGuestUnitName, ignitionConfig.Systemd.Units[0].Name)
+	assert.True(t, ignitionConfig.Systemd.Units[0].Enabled)
+	assert.Contains(t, ignitionConfig.Systemd.Units[0].Contents, `ExecStart="/usr/bin/vminit" "--label" "100%% \"quoted\" $$HOME"`)
+	assert.Contains(t, ignitionConfig.Systemd.Units[0].Contents, "ConditionPathExists=/var/lib/firebuild/bundle.json")
+}
+
+func TestGuestConfigRequiresCommand(t *testing.T) {
+	_, err := CloudInitUserData(&Bundle{Address: "10.0.0.1:5000"}, nil)
+	assert.True(t, errors.Is(err, ErrNoGuestCommand))
+	_, err = IgnitionConfig(&Bundle{Address: "10.0.0.1:5000"}, &GuestOptions{})
+	assert.True(t, errors.Is(err, ErrNoGuestCommand))
+	_, err = IgnitionConfig(&Bundle{}, &GuestOptions{Command: []string{"vminit"}})
+	assert.True(t, errors.Is(err, ErrIncompleteBundle))
+}
@@ -0,0 +1,192 @@
+package bootstrap
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// KernelArgPrefix prefixes the names of the kernel command line parameters of the bundle.
+	KernelArgPrefix = "firebuild."
+	// DefaultMaxKernelCommandLine is the default maximum length of the kernel command line,
+	// the size of the command line buffer of the x86_64 Linux kernel.
+	DefaultMaxKernelCommandLine = 2048
+)
+
+// The kernel command line parameters of the bundle.
+const (
+	KernelArgAddress    = KernelArgPrefix + "addr"
+	KernelArgBuildID    = KernelArgPrefix + "build"
+	KernelArgServerName = KernelArgPrefix + "server-name"
+	KernelArgToken      = KernelArgPrefix + "token"
+	KernelArgCA         = KernelArgPrefix + "ca"
+	KernelArgCert       = KernelArgPrefix + "cert"
+	KernelArgKey        = KernelArgPrefix + "key"
+	// KernelArgMMDS tells the guest to read the TLS material from the metadata service.
+	KernelArgMMDS = KernelArgPrefix + "mmds"
+)
+
+// ErrKernelCommandLineTooLong is returned when the parameters do not fit the command line
+// even with the TLS material delivered through the metadata service.
+var ErrKernelCommandLineTooLong = errors.New("kernel command line too long")
+
+// KernelArgsOptions configures KernelArgs.
+type KernelArgsOptions struct {
+	// CommandLine is the rest of the kernel command line the parameters are appended to,
+	// for example console=ttyS0 reboot=k panic=1, it counts against MaxLength.
+	CommandLine string
+	// MaxLength is the maximum length of the complete kernel command line,
+	// DefaultMaxKernelCommandLine when zero.
+	MaxLength int
+	// ForceMMDS delivers the TLS material through the metadata service even when it fits the command line.
+	ForceMMDS bool
+}
+
+// KernelArgs returns the kernel command line parameters of the bundle, without the rest of the command line.
+// The PEM encoded TLS material is base64 encoded and values containing spaces are quoted.
+// When the parameters with the TLS material do not fit MaxLength, the TLS material is left out,
+// the firebuild.mmds=1 parameter is added and viaMMDS is true: the bundle must be published
+// with MMDSPublisher and the guest reads the TLS material from the metadata service.
+func KernelArgs(bundle *Bundle, options *KernelArgsOptions) (args string, viaMMDS bool, err error) {
+	if err := bundle.Validate(); err != nil {
+		return "", false, err
+	}
+	if options == nil {
+		options = &KernelArgsOptions{}
+	}
+	maxLength := options.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxKernelCommandLine
+	}
+
+	params := []string{}
+	for _, param := range []struct{ name, value string }{
+		{KernelArgAddress, bundle.Address},
+		{KernelArgBuildID, bundle.BuildID},
+		{KernelArgServerName, bundle.ServerName},
+		{KernelArgToken, bundle.Token},
+	} {
+		if param.value == "" {
+			continue
+		}
+		formatted, err := formatKernelArg(param.name, param.value)
+		if err != nil {
+			return "", false, err
+		}
+		params = append(params, formatted)
+	}
+	tlsParams := []string{}
+	for _, param := range []struct{ name, value string }{
+		{KernelArgCA, bundle.CA},
+		{KernelArgCert, bundle.Certificate},
+		{KernelArgKey, bundle.Key},
+	} {
+		if param.value == "" {
+			continue
+		}
+		tlsParams = append(tlsParams, param.name+"="+base64.RawURLEncoding.EncodeToString([]byte(param.value)))
+	}
+
+	if !options.ForceMMDS || len(tlsParams) == 0 {
+		args = strings.Join(append(params, tlsParams...), " ")
+		if kernelCommandLineLength(options.CommandLine, args) <= maxLength {
+			return args, false, nil
+		}
+		if len(tlsParams) == 0 {
+			return "", false, fmt.Errorf("%w: %d bytes allowed", ErrKernelCommandLineTooLong, maxLength)
+		}
+	}
+	args = strings.Join(append(params, KernelArgMMDS+"=1"), " ")
+	if kernelCommandLineLength(options.CommandLine, args) > maxLength {
+		return "", false, fmt.Errorf("%w: %d bytes allowed", ErrKernelCommandLineTooLong, maxLength)
+	}
+	return args, true, nil
+}
+
+// ParseKernelArgs reads the bundle from a kernel command line, for example the contents of /proc/cmdline.
+// When viaMMDS is true, the guest reads the TLS material from the metadata service.
+func ParseKernelArgs(commandLine string) (bundle *Bundle, viaMMDS bool, err error) {
+	bundle = &Bundle{}
+	for _, field := range splitKernelCommandLine(commandLine) {
+		name, value := field, ""
+		if index := strings.Index(field, "="); index > -1 {
+			name, value = field[:index], field[index+1:]
+		}
+		if !strings.HasPrefix(name, KernelArgPrefix) {
+			continue
+		}
+		switch name {
+		case KernelArgAddress:
+			bundle.Address = value
+		case KernelArgBuildID:
+			bundle.BuildID = value
+		case KernelArgServerName:
+			bundle.ServerName = value
+		case KernelArgToken:
+			bundle.Token = value
+		case KernelArgCA, KernelArgCert, KernelArgKey:
+			decoded, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed decoding %s: %v", name, err)
+			}
+			switch name {
+			case KernelArgCA:
+				bundle.CA = string(decoded)
+			case KernelArgCert:
+				bundle.Certificate = string(decoded)
+			default:
+				bundle.Key = string(decoded)
+			}
+		case KernelArgMMDS:
+			viaMMDS = value == "1"
+		}
+	}
+	return bundle, viaMMDS, bundle.Validate()
+}
+
+// formatKernelArg quotes a value containing spaces, the kernel has no escape for quotes and control characters.
+func formatKernelArg(name, value string) (string, error) {
+	for _, r := range value {
+		if r == '"' || r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("value of %s contains a character not allowed in the kernel command line: %q", name, r)
+		}
+	}
+	if strings.Contains(value, " ") {
+		return name + `="` + value + `"`, nil
+	}
+	return name + "=" + value, nil
+}
+
+// splitKernelCommandLine splits the command line on spaces outside of quotes and removes the quotes,
+// the way the kernel parses its parameters.
+func splitKernelCommandLine(commandLine string) []string {
+	fields := []string{}
+	current := strings.Builder{}
+	quoted := false
+	for _, r := range strings.TrimSpace(commandLine) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+func kernelCommandLineLength(commandLine, args string) int {
+	if commandLine == "" {
+		return len(args)
+	}
+	return len(commandLine) + 1 + len(args)
+}
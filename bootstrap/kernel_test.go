@@ -0,0 +1,52 @@
+package bootstrap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelArgs(t *testing.T) {
+	bundle := &Bundle{
+		Address:    "192.168.127.1:5000",
+		BuildID:    "build-1",
+		ServerName: "test-grpc-server",
+		Token:      "a token",
+		CA:         "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+	}
+	args, viaMMDS, err := KernelArgs(bundle, nil)
+	assert.Nil(t, err)
+	assert.False(t, viaMMDS)
+	assert.True(t, strings.HasPrefix(args, `firebuild.addr=192.168.127.1:5000 firebuild.build=build-1 firebuild.server-name=test-grpc-server firebuild.token="a token" firebuild.ca=`))
+
+	parsed, viaMMDS, err := ParseKernelArgs("console=ttyS0 reboot=k " + args + " panic=1\n")
+	assert.Nil(t, err)
+	assert.False(t, viaMMDS)
+	assert.Equal(t, bundle, parsed)
+
+	// the TLS material does not fit, it goes through the metadata service:
+	bundle.Certificate = strings.Repeat("c", 1024)
+	bundle.Key = strings.Repeat("k", 1024)
+	args, viaMMDS, err = KernelArgs(bundle, &KernelArgsOptions{CommandLine: "console=ttyS0 reboot=k panic=1"})
+	assert.Nil(t, err)
+	assert.True(t, viaMMDS)
+	assert.Equal(t, `firebuild.addr=192.168.127.1:5000 firebuild.build=build-1 firebuild.server-name=test-grpc-server firebuild.token="a token" firebuild.mmds=1`, args)
+
+	parsed, viaMMDS, err = ParseKernelArgs(args)
+	assert.Nil(t, err)
+	assert.True(t, viaMMDS)
+	assert.Equal(t, "a token", parsed.Token)
+	assert.Equal(t, "", parsed.CA)
+
+	_, viaMMDS, err = KernelArgs(&Bundle{Address: "192.168.127.1:5000", CA: "ca"}, &KernelArgsOptions{ForceMMDS: true})
+	assert.Nil(t, err)
+	assert.True(t, viaMMDS)
+
+	_, _, err = KernelArgs(bundle, &KernelArgsOptions{MaxLength: 64})
+	assert.True(t, errors.Is(err, ErrKernelCommandLineTooLong))
+
+	_, _, err = KernelArgs(&Bundle{Address: "192.168.127.1:5000", Token: `a"token`}, nil)
+	assert.NotNil(t, err)
+}
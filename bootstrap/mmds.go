@@ -0,0 +1,107 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// MMDSKey is the key of the bundle in the metadata of the Firecracker microVM metadata service (MMDS).
+// The guest reads the bundle from http://169.254.169.254/firebuild with the Accept: application/json header.
+const MMDSKey = "firebuild"
+
+// MMDS returns the JSON document of the metadata service with the bundle under MMDSKey.
+func (b *Bundle) MMDS() ([]byte, error) {
+	return json.Marshal(map[string]*Bundle{MMDSKey: b})
+}
+
+// ParseMMDS reads the bundle from the JSON document of the metadata service,
+// or from the JSON object under MMDSKey as returned to the guest.
+func ParseMMDS(data []byte) (*Bundle, error) {
+	document := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed parsing metadata: %v", err)
+	}
+	if nested, ok := document[MMDSKey]; ok {
+		data = nested
+	}
+	return ParseBundle(data)
+}
+
+// MMDSPublisher publishes the bundle to the metadata service through the API socket of a Firecracker process.
+type MMDSPublisher struct {
+	// SocketPath is the path of the Firecracker API socket.
+	SocketPath string
+	// Client optionally replaces the HTTP client connecting to the socket, for tests.
+	Client *http.Client
+}
+
+// Publish replaces the metadata of the microVM with the bundle, call before the guest boots.
+func (p *MMDSPublisher) Publish(ctx context.Context, bundle *Bundle) error {
+	return p.send(ctx, http.MethodPut, bundle)
+}
+
+// Update patches the bundle in the metadata of a running microVM,
+// for example after rotating the token or the client certificate.
+func (p *MMDSPublisher) Update(ctx context.Context, bundle *Bundle) error {
+	return p.send(ctx, http.MethodPatch, bundle)
+}
+
+// Watch publishes every bundle received from the channel with Update until the channel
+// is closed or the context is done, returning the first error.
+func (p *MMDSPublisher) Watch(ctx context.Context, bundles <-chan *Bundle) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case bundle, ok := <-bundles:
+			if !ok {
+				return nil
+			}
+			if err := p.Update(ctx, bundle); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *MMDSPublisher) send(ctx context.Context, method string, bundle *Bundle) error {
+	if err := bundle.Validate(); err != nil {
+		return err
+	}
+	body, err := bundle.MMDS()
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, "http://localhost/mmds", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := p.client().Do(request)
+	if err != nil {
+		return fmt.Errorf("failed publishing bundle: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("failed publishing bundle: %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func (p *MMDSPublisher) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	socketPath := p.SocketPath
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+}
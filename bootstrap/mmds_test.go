@@ -0,0 +1,88 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mmdsRequest struct {
+	method string
+	path   string
+	body   map[string]*Bundle
+}
+
+func TestMMDSPublisher(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "firecracker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	assert.Nil(t, err)
+
+	m := &sync.Mutex{}
+	requests := []mmdsRequest{}
+	apiServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := mmdsRequest{method: r.Method, path: r.URL.Path}
+		if err := json.NewDecoder(r.Body).Decode(&request.body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m.Lock()
+		requests = append(requests, request)
+		m.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	apiServer.Listener = listener
+	apiServer.Start()
+	defer apiServer.Close()
+
+	publisher := &MMDSPublisher{SocketPath: socketPath}
+	bundle := &Bundle{Address: "192.168.127.1:5000", BuildID: "build-1", Token: "token-1"}
+	assert.Nil(t, publisher.Publish(context.Background(), bundle))
+
+	rotated := make(chan *Bundle, 1)
+	rotated <- &Bundle{Address: "192.168.127.1:5000", BuildID: "build-1", Token: "token-2"}
+	close(rotated)
+	assert.Nil(t, publisher.Watch(context.Background(), rotated))
+
+	m.Lock()
+	defer m.Unlock()
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, http.MethodPut, requests[0].method)
+	assert.Equal(t, "/mmds", requests[0].path)
+	assert.Equal(t, "token-1", requests[0].body[MMDSKey].Token)
+	assert.Equal(t, http.MethodPatch, requests[1].method)
+	assert.Equal(t, "token-2", requests[1].body[MMDSKey].Token)
+
+	// an incomplete bundle is not published:
+	assert.NotNil(t, publisher.Update(context.Background(), &Bundle{}))
+	assert.Equal(t, 2, len(requests))
+}
+
+func TestMMDSPublisherFails(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"fault_message": "MMDS not configured"}`))
+	}))
+	defer apiServer.Close()
+
+	publisher := &MMDSPublisher{Client: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", apiServer.Listener.Addr().String())
+		},
+	}}}
+	err := publisher.Publish(context.Background(), &Bundle{Address: "192.168.127.1:5000"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "MMDS not configured")
+}
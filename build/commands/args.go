@@ -0,0 +1,173 @@
+package commands
+
+import "strings"
+
+// SubstituteArgs returns the commands with the ARG values substituted into the RUN, COPY, ADD, WORKDIR and USER commands
+// and into the ENV values, like a Dockerfile builder does:
+//
+//   - an ARG applies to the commands following it, until the next FROM;
+//   - an ARG preceding the first FROM is global, it applies in a stage when the stage declares it again,
+//     the value of the global ARG is then the default of the stage ARG;
+//   - the value of an ARG is the override of the same name, the default of the ARG or an empty string;
+//   - an ENV of the same name as an ARG takes precedence, the following references are left to the environment.
+//
+// Without a FROM, the commands are a single stage and the ARGs apply to the commands following them.
+// The $NAME, ${NAME}, ${NAME:-word} and ${NAME:+word} references of declared ARGs are substituted,
+// other references and escaped \$ references are left as they are for the shell of the command.
+// Overrides of undeclared ARGs are ignored. The returned list has the commands at the same indexes.
+func SubstituteArgs(cmds []VMInitSerializableCommand, overrides map[string]string) []VMInitSerializableCommand {
+	result := make([]VMInitSerializableCommand, 0, len(cmds))
+	globals := map[string]string{}
+	scope := map[string]string{}
+	inStage := false
+	for _, cmd := range cmds {
+		switch tcmd := cmd.(type) {
+		case Arg:
+			value := argValue(tcmd, overrides, globals, inStage)
+			scope[tcmd.Name] = value
+			if !inStage {
+				globals[tcmd.Name] = value
+			}
+		case From:
+			inStage = true
+			scope = map[string]string{}
+		case Add:
+			tcmd.Source = expandArgs(tcmd.Source, scope)
+			tcmd.Target = expandArgs(tcmd.Target, scope)
+			cmd = tcmd
+		case Copy:
+			tcmd.Source = expandArgs(tcmd.Source, scope)
+			tcmd.Target = expandArgs(tcmd.Target, scope)
+			cmd = tcmd
+		case Env:
+			tcmd.Value = expandArgs(tcmd.Value, scope)
+			delete(scope, tcmd.Name)
+			cmd = tcmd
+		case Run:
+			tcmd.Command = expandArgs(tcmd.Command, scope)
+			cmd = tcmd
+		case User:
+			tcmd.Value = expandArgs(tcmd.Value, scope)
+			cmd = tcmd
+		case Workdir:
+			tcmd.Value = expandArgs(tcmd.Value, scope)
+			cmd = tcmd
+		}
+		result = append(result, cmd)
+	}
+	return result
+}
+
+// ArgValues returns the values of the ARGs declared by the commands, resolved from the overrides
+// and the defaults like SubstituteArgs does. When several commands declare the same ARG,
+// the value of the last declaration is returned.
+func ArgValues(cmds []VMInitSerializableCommand, overrides map[string]string) map[string]string {
+	result := map[string]string{}
+	globals := map[string]string{}
+	inStage := false
+	for _, cmd := range cmds {
+		switch tcmd := cmd.(type) {
+		case Arg:
+			value := argValue(tcmd, overrides, globals, inStage)
+			result[tcmd.Name] = value
+			if !inStage {
+				globals[tcmd.Name] = value
+			}
+		case From:
+			inStage = true
+		}
+	}
+	return result
+}
+
+// argValue returns the value of the ARG: the override, the default, the value of the global ARG
+// of the same name in a stage or an empty string.
+func argValue(arg Arg, overrides, globals map[string]string, inStage bool) string {
+	if value, ok := overrides[arg.Name]; ok {
+		return value
+	}
+	if arg.HasDefault {
+		return arg.Default
+	}
+	if inStage {
+		return globals[arg.Name]
+	}
+	return ""
+}
+
+// expandArgs substitutes the references of the values in the input, see SubstituteArgs.
+func expandArgs(input string, values map[string]string) string {
+	if len(values) == 0 || !strings.Contains(input, "$") {
+		return input
+	}
+	var builder strings.Builder
+	for i := 0; i < len(input); i++ {
+		switch {
+		case input[i] == '\\' && i+1 < len(input) && input[i+1] == '$':
+			builder.WriteString(`\$`)
+			i = i + 1
+			continue
+		case input[i] != '$' || i+1 == len(input):
+			builder.WriteByte(input[i])
+			continue
+		}
+		if input[i+1] == '{' {
+			closing := strings.IndexByte(input[i:], '}')
+			if closing < 0 {
+				builder.WriteString(input[i:])
+				break
+			}
+			if expanded, ok := expandArgsBraced(input[i+2:i+closing], values); ok {
+				builder.WriteString(expanded)
+			} else {
+				builder.WriteString(input[i : i+closing+1])
+			}
+			i = i + closing
+			continue
+		}
+		end := i + 1
+		for end < len(input) && isArgNameByte(input[end], end == i+1) {
+			end = end + 1
+		}
+		if value, ok := values[input[i+1:end]]; ok {
+			builder.WriteString(value)
+		} else {
+			builder.WriteString(input[i:end])
+		}
+		i = end - 1
+	}
+	return builder.String()
+}
+
+// expandArgsBraced returns the value of the body of a ${...} reference,
+// false when the reference is not a reference of a value.
+func expandArgsBraced(body string, values map[string]string) (string, bool) {
+	name, modifier, word := body, "", ""
+	if index := strings.Index(body, ":"); index >= 0 && index+1 < len(body) {
+		name, modifier, word = body[:index], body[index:index+2], body[index+2:]
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", false
+	}
+	switch modifier {
+	case "":
+		return value, true
+	case ":-":
+		if value == "" {
+			return word, true
+		}
+		return value, true
+	case ":+":
+		if value != "" {
+			return word, true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// isArgNameByte returns true for the bytes of an ARG name, a name does not start with a digit.
+func isArgNameByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
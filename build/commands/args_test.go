@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseArg(t *testing.T, input string) Arg {
+	arg, err := NewRawArg(input)
+	if err != nil {
+		t.Fatal("expected the ARG to parse", err)
+	}
+	return arg
+}
+
+func TestSubstituteArgs(t *testing.T) {
+	cmds := []VMInitSerializableCommand{
+		mustParseArg(t, "VERSION=1.0"),
+		mustParseArg(t, "GLOBAL=global"),
+		From{OriginalCommand: "FROM alpine:3.13", BaseImage: "alpine:3.13"},
+		mustParseArg(t, "VERSION"),
+		mustParseArg(t, "TARGET=/opt/app"),
+		mustParseArg(t, "EMPTY"),
+		RunWithDefaults(`echo $VERSION ${VERSION} $GLOBAL \$VERSION $HOME ${EMPTY:-fallback} ${VERSION:+set} ${HOME:-home}`),
+		Copy{OriginalCommand: "COPY app-${VERSION} ${TARGET}/", Source: "app-${VERSION}", Target: "${TARGET}/"},
+		Env{OriginalCommand: "ENV TARGET=$TARGET/bin", Name: "TARGET", Value: "$TARGET/bin"},
+		RunWithDefaults("ls $TARGET"),
+		From{OriginalCommand: "FROM alpine:3.13", BaseImage: "alpine:3.13"},
+		RunWithDefaults("echo $VERSION"),
+	}
+	substituted := SubstituteArgs(cmds, map[string]string{"VERSION": "2.0", "UNDECLARED": "ignored"})
+	assert.Len(t, substituted, len(cmds), "expected the commands at the same indexes")
+
+	assert.Equal(t, `echo 2.0 2.0 $GLOBAL \$VERSION $HOME fallback set ${HOME:-home}`, substituted[6].(Run).Command,
+		"expected the override, the global ARG not declared in the stage and the other references left alone")
+	assert.Equal(t, "app-2.0", substituted[7].(Copy).Source)
+	assert.Equal(t, "/opt/app/", substituted[7].(Copy).Target)
+	assert.Equal(t, "/opt/app/bin", substituted[8].(Env).Value)
+	assert.Equal(t, "ls $TARGET", substituted[9].(Run).Command, "expected the ENV to take precedence")
+	assert.Equal(t, "echo $VERSION", substituted[11].(Run).Command, "expected the ARGs not to apply in another stage")
+	assert.Equal(t, "echo $VERSION ${VERSION} $GLOBAL \\$VERSION $HOME ${EMPTY:-fallback} ${VERSION:+set} ${HOME:-home}",
+		cmds[6].(Run).Command, "expected the input commands not to change")
+
+	// without a FROM, the commands are a single stage:
+	substituted = SubstituteArgs([]VMInitSerializableCommand{
+		mustParseArg(t, "VERSION=1.0"),
+		mustParseArg(t, "UID=1000"),
+		RunWithDefaults("echo ${VERSION}"),
+		Workdir{OriginalCommand: "WORKDIR /opt/app-$VERSION", Value: "/opt/app-$VERSION"},
+		User{OriginalCommand: "USER ${UID}:${UID}", Value: "${UID}:${UID}"},
+	}, nil)
+	assert.Equal(t, "echo 1.0", substituted[2].(Run).Command)
+	assert.Equal(t, "/opt/app-1.0", substituted[3].(Workdir).Value)
+	assert.Equal(t, "1000:1000", substituted[4].(User).Value)
+
+	_, err := NewRawArg("=value")
+	assert.NotNil(t, err)
+}
+
+func TestArgValues(t *testing.T) {
+	cmds := []VMInitSerializableCommand{
+		mustParseArg(t, "VERSION=1.0"),
+		mustParseArg(t, "GLOBAL=global"),
+		From{OriginalCommand: "FROM alpine:3.13", BaseImage: "alpine:3.13"},
+		mustParseArg(t, "GLOBAL"),
+		mustParseArg(t, "TARGET=/opt/app"),
+		mustParseArg(t, "EMPTY"),
+	}
+	assert.Equal(t, map[string]string{
+		"VERSION": "2.0",
+		"GLOBAL":  "global",
+		"TARGET":  "/opt/app",
+		"EMPTY":   "",
+	}, ArgValues(cmds, map[string]string{"VERSION": "2.0", "UNDECLARED": "ignored"}))
+}
@@ -16,15 +16,18 @@ type DockerfileSerializable interface {
 }
 
 // Add represents the ADD instruction.
+// Env optionally overrides the environment the executor adds the resource with, see EffectiveEnv.
 type Add struct {
 	VMInitSerializableCommand `json:"-" mapstructure:"-"`
-	OriginalCommand           string  `json:"OriginalCommand" mapstructure:"OriginalCommand"`
-	OriginalSource            string  `json:"OriginalSource" mapstructure:"OriginalSource"`
-	Source                    string  `json:"Source" mapstructure:"Source"`
-	Target                    string  `json:"Target" mapstructure:"Target"`
-	Workdir                   Workdir `json:"Workdir" mapstructure:"Workdir"`
-	User                      User    `json:"User" mapstructure:"User"`
-	UserFromLocalChown        *User   `json:"UserFromLocalChown" mapstructure:"UserFromLocalChown"`
+	OriginalCommand           string            `json:"OriginalCommand" mapstructure:"OriginalCommand"`
+	OriginalSource            string            `json:"OriginalSource" mapstructure:"OriginalSource"`
+	Source                    string            `json:"Source" mapstructure:"Source"`
+	Target                    string            `json:"Target" mapstructure:"Target"`
+	Workdir                   Workdir           `json:"Workdir" mapstructure:"Workdir"`
+	User                      User              `json:"User" mapstructure:"User"`
+	UserFromLocalChown        *User             `json:"UserFromLocalChown" mapstructure:"UserFromLocalChown"`
+	When                      string            `json:"When,omitempty" mapstructure:"When"`
+	Env                       map[string]string `json:"Env,omitempty" mapstructure:"Env"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -32,11 +35,13 @@ func (cmd Add) GetOriginal() string {
 	return cmd.OriginalCommand
 }
 
-// Arg represents the ARG instruction.
+// Arg represents the ARG instruction. See SubstituteArgs for the substitution of the values into the commands.
 type Arg struct {
 	OriginalCommand string `json:"OriginalCommand" mapstructure:"OriginalCommand"`
-	k, v            string
-	hadv            bool
+	Name            string `json:"Name" mapstructure:"Name"`
+	// Default is the value of the ARG given in the Dockerfile, valid when HasDefault is true.
+	Default    string `json:"Default" mapstructure:"Default"`
+	HasDefault bool   `json:"HasDefault" mapstructure:"HasDefault"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -46,8 +51,9 @@ func (cmd Arg) GetOriginal() string {
 
 // NewRawArg returns a new parsed ARG from the raw input.
 func NewRawArg(input string) (Arg, error) {
+	input = strings.TrimSpace(input)
 	parts := strings.Split(input, "=")
-	if len(parts) == 0 {
+	if parts[0] == "" {
 		return Arg{}, fmt.Errorf("arg: missing name")
 	}
 	v, hadv := func(input []string) (string, bool) {
@@ -57,26 +63,27 @@ func NewRawArg(input string) (Arg, error) {
 		return "", false
 	}(parts)
 	return Arg{
-		k:    parts[0],
-		v:    v,
-		hadv: hadv,
+		OriginalCommand: fmt.Sprintf("ARG %s", input),
+		Name:            parts[0],
+		Default:         v,
+		HasDefault:      hadv,
 	}, nil
 }
 
 // Key returns the ARG key.
 func (cmd Arg) Key() string {
-	return cmd.k
+	return cmd.Name
 }
 
 // Value returns the ARG value and  a boolean indicating if value was defined in the Dockerfile.
 func (cmd Arg) Value() (string, bool) {
-	return cmd.v, cmd.hadv
+	return cmd.Default, cmd.HasDefault
 }
 
 // Cmd represents the CMD instruction.
 type Cmd struct {
 	OriginalCommand string   `json:"OriginalCommand" mapstructure:"OriginalCommand"`
-	Values          []string `json:"values" mapstructure:"values"`
+	Values          []string `json:"Values" mapstructure:"Values"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -85,16 +92,19 @@ func (cmd Cmd) GetOriginal() string {
 }
 
 // Copy represents the COPY instruction.
+// Env optionally overrides the environment the executor copies the resource with, see EffectiveEnv.
 type Copy struct {
 	VMInitSerializableCommand `json:"-" mapstructure:"-"`
-	OriginalCommand           string  `json:"OriginalCommand" mapstructure:"OriginalCommand"`
-	OriginalSource            string  `json:"OriginalSource" mapstructure:"OriginalSource"`
-	Source                    string  `json:"Source" mapstructure:"Source"`
-	Stage                     string  `json:"Stage" mapstructure:"Stage"`
-	Target                    string  `json:"Target" mapstructure:"Target"`
-	Workdir                   Workdir `json:"Workdir" mapstructure:"Workdir"`
-	User                      User    `json:"User" mapstructure:"User"`
-	UserFromLocalChown        *User   `json:"UserFromLocalChown" mapstructure:"UserFromLocalChown"`
+	OriginalCommand           string            `json:"OriginalCommand" mapstructure:"OriginalCommand"`
+	OriginalSource            string            `json:"OriginalSource" mapstructure:"OriginalSource"`
+	Source                    string            `json:"Source" mapstructure:"Source"`
+	Stage                     string            `json:"Stage" mapstructure:"Stage"`
+	Target                    string            `json:"Target" mapstructure:"Target"`
+	Workdir                   Workdir           `json:"Workdir" mapstructure:"Workdir"`
+	User                      User              `json:"User" mapstructure:"User"`
+	UserFromLocalChown        *User             `json:"UserFromLocalChown" mapstructure:"UserFromLocalChown"`
+	When                      string            `json:"When,omitempty" mapstructure:"When"`
+	Env                       map[string]string `json:"Env,omitempty" mapstructure:"Env"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -102,6 +112,21 @@ func (cmd Copy) GetOriginal() string {
 	return cmd.OriginalCommand
 }
 
+// Delete represents the removal of paths copied in earlier steps, declared without running shell commands.
+// Relative paths are resolved against the workdir.
+type Delete struct {
+	VMInitSerializableCommand `json:"-" mapstructure:"-"`
+	OriginalCommand           string   `json:"OriginalCommand" mapstructure:"OriginalCommand"`
+	Paths                     []string `json:"Paths" mapstructure:"Paths"`
+	Workdir                   Workdir  `json:"Workdir" mapstructure:"Workdir"`
+	When                      string   `json:"When,omitempty" mapstructure:"When"`
+}
+
+// GetOriginal returns the original string command the command was parsed from.
+func (cmd Delete) GetOriginal() string {
+	return cmd.OriginalCommand
+}
+
 // Entrypoint represents the ENTRYPOINT instruction.
 type Entrypoint struct {
 	OriginalCommand string            `json:"OriginalCommand" mapstructure:"OriginalCommand"`
@@ -129,6 +154,44 @@ func (cmd Env) GetOriginal() string {
 	return cmd.OriginalCommand
 }
 
+// NewRawEnv returns the parsed ENV commands of the raw input of an ENV instruction, one command per value.
+// The input is either a list of name=value pairs, the values optionally double quoted, or the legacy
+// form of a single name followed by the value. Every command carries the original ENV instruction.
+func NewRawEnv(input string) ([]Env, error) {
+	input = strings.TrimSpace(input)
+	original := fmt.Sprintf("ENV %s", input)
+	if input == "" {
+		return nil, fmt.Errorf("env: missing name")
+	}
+	if name := strings.Fields(input)[0]; !strings.Contains(name, "=") {
+		value := strings.TrimSpace(strings.TrimPrefix(input, name))
+		return []Env{{OriginalCommand: original, Name: name, Value: value}}, nil
+	}
+	envs := []Env{}
+	for input != "" {
+		equals := strings.Index(input, "=")
+		if equals <= 0 || strings.ContainsAny(input[:equals], " \t") {
+			return nil, fmt.Errorf("env: expected name=value at '%s'", input)
+		}
+		name, rest := input[:equals], input[equals+1:]
+		value := ""
+		if strings.HasPrefix(rest, "\"") {
+			closing := strings.Index(rest[1:], "\"")
+			if closing < 0 {
+				return nil, fmt.Errorf("env: unterminated value of '%s'", name)
+			}
+			value, rest = rest[1:closing+1], rest[closing+2:]
+		} else if end := strings.IndexAny(rest, " \t"); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		envs = append(envs, Env{OriginalCommand: original, Name: name, Value: value})
+		input = strings.TrimSpace(rest)
+	}
+	return envs, nil
+}
+
 // Expose represents the EXPOSE instruction.
 type Expose struct {
 	OriginalCommand string `json:"OriginalCommand" mapstructure:"OriginalCommand"`
@@ -167,6 +230,7 @@ type From struct {
 	OriginalCommand string `json:"OriginalCommand" mapstructure:"OriginalCommand"`
 	BaseImage       string `json:"BaseImage" mapstructure:"BaseImage"`
 	StageName       string `json:"StageName" mapstructure:"StageName"`
+	Platform        string `json:"Platform" mapstructure:"Platform"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -201,15 +265,23 @@ func (cmd Label) GetOriginal() string {
 }
 
 // Run represents the RUN instruction.
+// Needs optionally lists the resource paths the command requires to be present before it executes,
+// for example the binary it executes, so that these resources can be streamed before any other.
+// When optionally restricts the command to the guests with facts matching the guard expression, see Guard.
+// Env overrides the environment of the stage the command executes in, see EffectiveEnv.
+// Retry optionally defines when the executor runs the failed command again.
 type Run struct {
 	VMInitSerializableCommand `json:"-" mapstructure:"-"`
 	OriginalCommand           string            `json:"OriginalCommand" mapstructure:"OriginalCommand"`
 	Args                      map[string]string `json:"Args" mapstructure:"Args"`
 	Command                   string            `json:"Command" mapstructure:"Command"`
 	Env                       map[string]string `json:"Env" mapstructure:"Env"`
+	Needs                     []string          `json:"Needs" mapstructure:"Needs"`
 	Shell                     Shell             `json:"Shell" mapstructure:"Shell"`
 	Workdir                   Workdir           `json:"Workdir" mapstructure:"Workdir"`
 	User                      User              `json:"User" mapstructure:"User"`
+	When                      string            `json:"When,omitempty" mapstructure:"When"`
+	Retry                     *RetryPolicy      `json:"Retry,omitempty" mapstructure:"Retry"`
 }
 
 // GetOriginal returns the original string command the command was parsed from.
@@ -273,6 +345,15 @@ func DefaultWorkdir() Workdir {
 	return Workdir{Value: "/"}
 }
 
+// DeleteWithDefaults returns a Delete for given paths with defaults.
+func DeleteWithDefaults(paths ...string) Delete {
+	return Delete{
+		OriginalCommand: fmt.Sprintf("DELETE %s", strings.Join(paths, " ")),
+		Paths:           paths,
+		Workdir:         DefaultWorkdir(),
+	}
+}
+
 // RunWithDefaults returns a Run for a given command with defaults.
 func RunWithDefaults(command string) Run {
 	return Run{
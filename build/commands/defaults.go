@@ -0,0 +1,42 @@
+package commands
+
+// ApplyDefaults returns the command with the user and the workdir set to the defaults when empty,
+// for example to the USER and WORKDIR of a non-root base image. Commands without a user or a workdir
+// are returned as given.
+func ApplyDefaults(cmd VMInitSerializableCommand, user User, workdir Workdir) VMInitSerializableCommand {
+	switch tcmd := cmd.(type) {
+	case Add:
+		tcmd.User, tcmd.Workdir = userOrDefault(tcmd.User, user), workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	case Copy:
+		tcmd.User, tcmd.Workdir = userOrDefault(tcmd.User, user), workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	case Delete:
+		tcmd.Workdir = workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	case Entrypoint:
+		tcmd.User, tcmd.Workdir = userOrDefault(tcmd.User, user), workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	case Run:
+		tcmd.User, tcmd.Workdir = userOrDefault(tcmd.User, user), workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	case Volume:
+		tcmd.User, tcmd.Workdir = userOrDefault(tcmd.User, user), workdirOrDefault(tcmd.Workdir, workdir)
+		return tcmd
+	}
+	return cmd
+}
+
+func userOrDefault(user, defaultUser User) User {
+	if user.Value == "" {
+		return defaultUser
+	}
+	return user
+}
+
+func workdirOrDefault(workdir, defaultWorkdir Workdir) Workdir {
+	if workdir.Value == "" {
+		return defaultWorkdir
+	}
+	return workdir
+}
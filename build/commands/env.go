@@ -0,0 +1,46 @@
+package commands
+
+// EnvOf returns the environment of a command, nil for a command without an environment.
+func EnvOf(cmd VMInitSerializableCommand) map[string]string {
+	switch tcmd := cmd.(type) {
+	case Add:
+		return tcmd.Env
+	case Copy:
+		return tcmd.Env
+	case Entrypoint:
+		return tcmd.Env
+	case Run:
+		return tcmd.Env
+	}
+	return nil
+}
+
+// EffectiveEnv returns the environment the command at the index executes with. The values inherit
+// like in a Dockerfile: the build environment, for example the ENV values in effect when the build starts,
+// is overridden by the ENV commands of the stage of the command preceding the command,
+// which are overridden by the environment of the command.
+func EffectiveEnv(buildEnv map[string]string, cmds []VMInitSerializableCommand, index int) map[string]string {
+	env := map[string]string{}
+	for name, value := range buildEnv {
+		env[name] = value
+	}
+	if index < 0 || index >= len(cmds) {
+		return env
+	}
+	stageStart := 0
+	for i := index - 1; i >= 0; i-- {
+		if _, ok := cmds[i].(From); ok {
+			stageStart = i + 1
+			break
+		}
+	}
+	for _, cmd := range cmds[stageStart:index] {
+		if envCmd, ok := cmd.(Env); ok {
+			env[envCmd.Name] = envCmd.Value
+		}
+	}
+	for name, value := range EnvOf(cmds[index]) {
+		env[name] = value
+	}
+	return env
+}
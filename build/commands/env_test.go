@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandEnvInheritance(t *testing.T) {
+	run := RunWithDefaults("make")
+	run.Env = map[string]string{"GOOS": "linux"}
+	copyCmd := Copy{OriginalCommand: "COPY app /app", Source: "app", Target: "/app", Env: map[string]string{"MODE": "copy"}}
+	cmds := []VMInitSerializableCommand{
+		From{OriginalCommand: "FROM golang:1.16 as builder", BaseImage: "golang:1.16", StageName: "builder"},
+		Env{OriginalCommand: "ENV MODE=stage", Name: "MODE", Value: "stage"},
+		Env{OriginalCommand: "ENV GOOS=darwin", Name: "GOOS", Value: "darwin"},
+		run,
+		From{OriginalCommand: "FROM alpine:3.13", BaseImage: "alpine:3.13"},
+		copyCmd,
+	}
+	buildEnv := map[string]string{"MODE": "build", "PATH": "/usr/bin"}
+
+	assert.Equal(t, map[string]string{"MODE": "stage", "GOOS": "linux", "PATH": "/usr/bin"}, EffectiveEnv(buildEnv, cmds, 3),
+		"expected the command env over the stage env over the build env")
+	assert.Equal(t, map[string]string{"MODE": "copy", "PATH": "/usr/bin"}, EffectiveEnv(buildEnv, cmds, 5),
+		"expected the env of another stage not to apply")
+	assert.Equal(t, buildEnv, EffectiveEnv(buildEnv, cmds, 6))
+	assert.Equal(t, "build", buildEnv["MODE"], "expected the build env not to change")
+
+	data, err := Serialize(copyCmd)
+	assert.Nil(t, err)
+	deserialized, err := Deserialize(data)
+	assert.Nil(t, err)
+	assert.Equal(t, copyCmd.Env, EnvOf(deserialized))
+}
+
+func TestParseEnvCommands(t *testing.T) {
+	envs, err := NewRawEnv(`A=1 B="two words" C=`)
+	assert.Nil(t, err)
+	assert.Equal(t, []Env{
+		{OriginalCommand: `ENV A=1 B="two words" C=`, Name: "A", Value: "1"},
+		{OriginalCommand: `ENV A=1 B="two words" C=`, Name: "B", Value: "two words"},
+		{OriginalCommand: `ENV A=1 B="two words" C=`, Name: "C", Value: ""},
+	}, envs)
+
+	envs, err = NewRawEnv("PATH /usr/local/bin:/usr/bin")
+	assert.Nil(t, err)
+	assert.Equal(t, []Env{{OriginalCommand: "ENV PATH /usr/local/bin:/usr/bin", Name: "PATH", Value: "/usr/local/bin:/usr/bin"}}, envs,
+		"expected the legacy form to hold a single value")
+
+	for _, input := range []string{"", "A=1 =2", `A="unterminated`} {
+		_, err := NewRawEnv(input)
+		assert.NotNil(t, err, input)
+	}
+
+	// the parsed commands survive the serialization of the Commands RPC and apply to the following commands:
+	cmds := []VMInitSerializableCommand{}
+	envs, err = NewRawEnv(`MODE=release NAME="my app"`)
+	assert.Nil(t, err)
+	for _, env := range envs {
+		data, err := Serialize(env)
+		assert.Nil(t, err)
+		deserialized, err := Deserialize(data)
+		assert.Nil(t, err)
+		assert.Equal(t, env, deserialized)
+		cmds = append(cmds, deserialized)
+	}
+	cmds = append(cmds, RunWithDefaults("make"))
+	assert.Equal(t, map[string]string{"MODE": "release", "NAME": "my app"}, EffectiveEnv(nil, cmds, 2))
+}
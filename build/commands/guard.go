@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Names of the guest facts the guards of the commands usually test.
+const (
+	// FactArch is the architecture of the guest, for example amd64 or arm64.
+	FactArch = "arch"
+	// FactOS is the operating system of the guest, for example linux.
+	FactOS = "os"
+	// FactDistro is the ID of the distribution of the guest, for example alpine or debian.
+	FactDistro = "distro"
+)
+
+// ErrInvalidGuard is returned when a guard expression does not parse.
+var ErrInvalidGuard = errors.New("invalid guard")
+
+// Facts contains the facts the guest reports about itself, keyed by the fact name, for example FactArch.
+type Facts map[string]string
+
+// Guard is a parsed guard expression of a command.
+//
+// An expression compares facts with values, for example arch == arm64 or distro != "alpine".
+// The comparisons combine with && and ||, && binds tighter. A fact the guest does not report is empty.
+// A command with a guard executes only on the guests with facts matching the guard.
+type Guard struct {
+	// the alternatives of the conditions all of which must match:
+	alternatives [][]guardCondition
+}
+
+type guardCondition struct {
+	fact     string
+	value    string
+	negative bool
+}
+
+// ParseGuard parses a guard expression, an empty expression matches every guest.
+func ParseGuard(expression string) (*Guard, error) {
+	guard := &Guard{}
+	if strings.TrimSpace(expression) == "" {
+		return guard, nil
+	}
+	tokens, err := guardTokens(expression)
+	if err != nil {
+		return nil, err
+	}
+	conditions := []guardCondition{}
+	for len(tokens) > 0 {
+		if len(tokens) < 3 || !isGuardOperand(tokens[0]) || (tokens[1] != "==" && tokens[1] != "!=") || !isGuardOperand(tokens[2]) {
+			return nil, fmt.Errorf("%w: expected fact == value or fact != value in '%s'", ErrInvalidGuard, expression)
+		}
+		conditions = append(conditions, guardCondition{
+			fact:     tokens[0],
+			value:    strings.Trim(tokens[2], `"`),
+			negative: tokens[1] == "!=",
+		})
+		tokens = tokens[3:]
+		if len(tokens) == 0 {
+			break
+		}
+		switch tokens[0] {
+		case "&&":
+		case "||":
+			guard.alternatives = append(guard.alternatives, conditions)
+			conditions = []guardCondition{}
+		default:
+			return nil, fmt.Errorf("%w: expected && or || in '%s', got '%s'", ErrInvalidGuard, expression, tokens[0])
+		}
+		if len(tokens) == 1 {
+			return nil, fmt.Errorf("%w: '%s' ends with an operator", ErrInvalidGuard, expression)
+		}
+		tokens = tokens[1:]
+	}
+	guard.alternatives = append(guard.alternatives, conditions)
+	return guard, nil
+}
+
+// Matches returns true if the facts match the guard.
+func (g *Guard) Matches(facts Facts) bool {
+	if len(g.alternatives) == 0 {
+		return true
+	}
+	for _, conditions := range g.alternatives {
+		matches := true
+		for _, condition := range conditions {
+			if (facts[condition.fact] == condition.value) == condition.negative {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// GuardOf returns the guard expression of a command, empty for a command without a guard.
+func GuardOf(cmd VMInitSerializableCommand) string {
+	switch tcmd := cmd.(type) {
+	case Add:
+		return tcmd.When
+	case Copy:
+		return tcmd.When
+	case Delete:
+		return tcmd.When
+	case Run:
+		return tcmd.When
+	}
+	return ""
+}
+
+// guardTokens splits a guard expression into operands, quoted values and operators.
+func guardTokens(expression string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i = i + 1
+		case strings.HasPrefix(expression[i:], "==") || strings.HasPrefix(expression[i:], "!=") ||
+			strings.HasPrefix(expression[i:], "&&") || strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, expression[i:i+2])
+			i = i + 2
+		case c == '"':
+			end := strings.IndexByte(expression[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quote in '%s'", ErrInvalidGuard, expression)
+			}
+			tokens = append(tokens, expression[i:i+end+2])
+			i = i + end + 2
+		case isGuardWordByte(c):
+			start := i
+			for i < len(expression) && isGuardWordByte(expression[i]) {
+				i = i + 1
+			}
+			tokens = append(tokens, expression[start:i])
+		default:
+			return nil, fmt.Errorf("%w: unexpected '%c' in '%s'", ErrInvalidGuard, c, expression)
+		}
+	}
+	return tokens, nil
+}
+
+func isGuardOperand(token string) bool {
+	return token != "==" && token != "!=" && token != "&&" && token != "||"
+}
+
+func isGuardWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '/' || c == ':'
+}
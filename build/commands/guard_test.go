@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardExpressions(t *testing.T) {
+	arm64Alpine := Facts{FactArch: "arm64", FactDistro: "alpine"}
+	amd64Debian := Facts{FactArch: "amd64", FactDistro: "debian"}
+	for expression, expected := range map[string][2]bool{
+		"":                                      {true, true},
+		"arch == arm64":                         {true, false},
+		`distro != "alpine"`:                    {false, true},
+		"arch == arm64 && distro == debian":     {false, false},
+		"arch == arm64 || distro == debian":     {true, true},
+		"arch==amd64&&distro==debian||os==none": {false, true},
+		`version == ""`:                         {true, true},
+	} {
+		guard, err := ParseGuard(expression)
+		assert.Nil(t, err, expression)
+		assert.Equal(t, expected[0], guard.Matches(arm64Alpine), expression)
+		assert.Equal(t, expected[1], guard.Matches(amd64Debian), expression)
+	}
+	for _, expression := range []string{"arch", "arch ==", "arch == arm64 &&", "arch == arm64 distro == alpine", `arch == "arm64`, "== arm64", "version == ''"} {
+		_, err := ParseGuard(expression)
+		assert.True(t, errors.Is(err, ErrInvalidGuard), "expected %s to be invalid", expression)
+	}
+}
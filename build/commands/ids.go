@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// IDLength is the length of a command ID.
+const IDLength = 16
+
+// ID returns the deterministic ID of the command at the index of a command list.
+// The same command at the same position of the list always has the same ID,
+// so the telemetry of the builds of the same Dockerfile can be joined per instruction.
+func ID(index int, cmd VMInitSerializableCommand) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n", index)
+	// struct fields serialize in declaration order and map keys sorted, the serialization is stable:
+	if data, err := json.Marshal(cmd); err == nil {
+		hash.Write(data)
+	} else {
+		fmt.Fprintf(hash, "%T %+v", cmd, cmd)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:IDLength]
+}
+
+// IDs returns the IDs of the commands of a list, by index.
+func IDs(cmds []VMInitSerializableCommand) []string {
+	result := make([]string, 0, len(cmds))
+	for index, cmd := range cmds {
+		result = append(result, ID(index, cmd))
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandIDsDeterministic(t *testing.T) {
+	cmds := []VMInitSerializableCommand{
+		RunWithDefaults("apk add curl"),
+		RunWithDefaults("apk add curl"),
+		RunWithDefaults("apk add git"),
+	}
+	ids := IDs(cmds)
+	assert.Equal(t, 3, len(ids))
+	assert.Equal(t, ids, IDs([]VMInitSerializableCommand{
+		RunWithDefaults("apk add curl"),
+		RunWithDefaults("apk add curl"),
+		RunWithDefaults("apk add git"),
+	}), "expected the same commands to have the same IDs")
+	for _, id := range ids {
+		assert.Equal(t, IDLength, len(id))
+	}
+	assert.NotEqual(t, ids[0], ids[1], "expected the same command at another index to have another ID")
+	assert.NotEqual(t, ids[1], ids[2])
+	assert.NotEqual(t, ids[2], ID(2, RunWithDefaults("apk add vim")))
+}
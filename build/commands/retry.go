@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy defines when the executor runs a failed RUN command again,
+// for example after a transient failure of a package mirror.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the command runs, including the first run.
+	Attempts int `json:"Attempts" mapstructure:"Attempts"`
+	// ExitCodes optionally lists the exit codes of the failures to retry.
+	ExitCodes []int `json:"ExitCodes" mapstructure:"ExitCodes"`
+	// StderrPatterns optionally lists the regular expressions of the stderr lines of the failures to retry.
+	// The failures are retried regardless of the exit code and the stderr when there are no exit codes and no patterns.
+	StderrPatterns []string `json:"StderrPatterns" mapstructure:"StderrPatterns"`
+	// DelayMillis is the number of milliseconds to wait before running the command again.
+	DelayMillis int `json:"DelayMillis" mapstructure:"DelayMillis"`
+}
+
+// Delay returns the duration to wait before running the command again.
+func (p *RetryPolicy) Delay() time.Duration {
+	return time.Duration(p.DelayMillis) * time.Millisecond
+}
+
+// Validate returns an error if a stderr pattern is not a valid regular expression.
+func (p *RetryPolicy) Validate() error {
+	_, err := p.stderrPatterns()
+	return err
+}
+
+// ShouldRetry returns true if the command should run again after the attempt, counted from 1,
+// failed with the exit code and the stderr lines.
+func (p *RetryPolicy) ShouldRetry(attempt, exitCode int, stderr []string) bool {
+	if p == nil || attempt >= p.Attempts {
+		return false
+	}
+	patterns, err := p.stderrPatterns()
+	if err != nil {
+		return false
+	}
+	if len(p.ExitCodes) == 0 && len(patterns) == 0 {
+		return true
+	}
+	for _, code := range p.ExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	for _, pattern := range patterns {
+		for _, line := range stderr {
+			if pattern.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) stderrPatterns() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(p.StderrPatterns))
+	for _, expression := range p.StderrPatterns {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry stderr pattern '%s': %v", expression, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
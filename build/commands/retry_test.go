@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 3, ExitCodes: []int{100}, StderrPatterns: []string{"^E: Failed to fetch"}}
+	assert.Nil(t, policy.Validate())
+	assert.True(t, policy.ShouldRetry(1, 100, nil))
+	assert.True(t, policy.ShouldRetry(2, 1, []string{"Reading package lists...", "E: Failed to fetch http://deb.debian.org"}))
+	assert.False(t, policy.ShouldRetry(1, 1, []string{"E: Unable to locate package"}))
+	assert.False(t, policy.ShouldRetry(3, 100, nil), "expected no retry after the last attempt")
+
+	anyFailure := &RetryPolicy{Attempts: 2}
+	assert.True(t, anyFailure.ShouldRetry(1, 1, nil))
+	assert.False(t, (*RetryPolicy)(nil).ShouldRetry(1, 1, nil))
+	assert.NotNil(t, (&RetryPolicy{StderrPatterns: []string{"("}}).Validate())
+
+	run := RunWithDefaults("apt-get update")
+	run.Retry = policy
+	data, err := Serialize(run)
+	assert.Nil(t, err)
+	deserialized, err := Deserialize(data)
+	assert.Nil(t, err)
+	assert.Equal(t, run, deserialized)
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFileName returns the name of the published JSON Schema file of a schema version.
+func SchemaFileName(version int) string {
+	return fmt.Sprintf("commands.v%d.schema.json", version)
+}
+
+// JSONSchema generates the JSON Schema of a serialized command of the current schema version.
+// A serialized command validates against exactly one of the commands, selected by the instruction
+// its OriginalCommand starts with. Additional properties are allowed so that a consumer of an older
+// schema version accepts the properties added by later versions.
+func JSONSchema() ([]byte, error) {
+	definitions := map[string]interface{}{}
+	kinds := make([]string, 0, len(commandKinds))
+	for kind := range commandKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	oneOf := []interface{}{}
+	for _, kind := range kinds {
+		commandType := reflect.TypeOf(commandKinds[kind]()).Elem()
+		oneOf = append(oneOf, map[string]interface{}{
+			"title": kind,
+			"allOf": []interface{}{
+				schemaOf(commandType, definitions),
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"OriginalCommand": map[string]interface{}{"pattern": fmt.Sprintf("^%s\\b", kind)},
+						SchemaVersionKey:  map[string]interface{}{"const": SchemaVersion},
+					},
+					"required": []string{SchemaVersionKey},
+				},
+			},
+		})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         fmt.Sprintf("https://github.com/combust-labs/firebuild-shared/build/commands/%s", SchemaFileName(SchemaVersion)),
+		"title":       fmt.Sprintf("firebuild serialized command, schema version %d", SchemaVersion),
+		"oneOf":       oneOf,
+		"definitions": definitions,
+	}, "", "  ")
+}
+
+// schemaOf returns the schema of a type, structs are added to the definitions and referenced.
+func schemaOf(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return map[string]interface{}{"anyOf": []interface{}{schemaOf(t.Elem(), definitions), map[string]interface{}{"type": "null"}}}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		// nil slices serialize as null:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaOf(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": schemaOf(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			// reserve the name before recursing into the fields:
+			definitions[t.Name()] = nil
+			properties := map[string]interface{}{}
+			required := []string{}
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				tagParts := strings.Split(field.Tag.Get("json"), ",")
+				name := tagParts[0]
+				if field.PkgPath != "" || name == "-" {
+					continue
+				}
+				if name == "" {
+					name = field.Name
+				}
+				properties[name] = schemaOf(field.Type, definitions)
+				// omitted empty properties are optional:
+				if len(tagParts) < 2 || tagParts[1] != "omitempty" {
+					required = append(required, name)
+				}
+			}
+			definitions[t.Name()] = map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			}
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
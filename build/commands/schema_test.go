@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run with FIREBUILD_UPDATE_SCHEMA=1 to write the schema after changing the commands.
+func TestCommandsJSONSchemaUpToDate(t *testing.T) {
+	generated, err := JSONSchema()
+	assert.Nil(t, err)
+	schemaPath := SchemaFileName(SchemaVersion)
+	if os.Getenv("FIREBUILD_UPDATE_SCHEMA") == "1" {
+		assert.Nil(t, ioutil.WriteFile(schemaPath, append(generated, '\n'), 0644))
+	}
+	published, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		t.Fatal("expected the published schema, got error", err)
+	}
+	assert.Equal(t, string(generated)+"\n", string(published),
+		"the commands changed, bump SchemaVersion when not backwards compatible and regenerate the schema with FIREBUILD_UPDATE_SCHEMA=1")
+}
+
+func TestCommandsJSONSchemaRequiresSerializedProperties(t *testing.T) {
+	generated, err := JSONSchema()
+	assert.Nil(t, err)
+	schema := struct {
+		Definitions map[string]struct {
+			Required []string `json:"required"`
+		} `json:"definitions"`
+	}{}
+	assert.Nil(t, json.Unmarshal(generated, &schema))
+
+	for name, cmd := range map[string]VMInitSerializableCommand{
+		"Add":    Add{OriginalCommand: "ADD a b"},
+		"Delete": DeleteWithDefaults("/tmp"),
+		"Run":    RunWithDefaults("true"),
+	} {
+		data, err := Serialize(cmd)
+		assert.Nil(t, err)
+		raw := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(data, &raw))
+		for _, property := range schema.Definitions[name].Required {
+			assert.Contains(t, raw, property, "%s serializes %s", name, property)
+		}
+	}
+}
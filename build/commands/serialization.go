@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// SchemaVersionKey is the property of a serialized command holding the schema version.
+const SchemaVersionKey = "SchemaVersion"
+
+// Schema versions of the serialized commands:
+//
+//   - 1: commands serialized before the schema was versioned, without the SchemaVersionKey property;
+//     CMD values were serialized as "values".
+//   - 2: commands carry the SchemaVersionKey property; CMD values are serialized as "Values",
+//     like the values of ENTRYPOINT and VOLUME.
+//
+// Deserialize migrates a command of an older version to the current version.
+// A command of a version newer than SchemaVersion is rejected.
+const SchemaVersion = 2
+
+var (
+	// ErrUnsupportedSchemaVersion is returned when a command was serialized with a newer schema version.
+	ErrUnsupportedSchemaVersion = errors.New("unsupported command schema version")
+	// ErrUnknownCommand is returned when a serialized command is not a known command.
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// commandKinds maps the instruction of the original command to the command type.
+var commandKinds = map[string]func() interface{}{
+	"ADD":        func() interface{} { return &Add{} },
+	"ARG":        func() interface{} { return &Arg{} },
+	"CMD":        func() interface{} { return &Cmd{} },
+	"COPY":       func() interface{} { return &Copy{} },
+	"DELETE":     func() interface{} { return &Delete{} },
+	"ENTRYPOINT": func() interface{} { return &Entrypoint{} },
+	"ENV":        func() interface{} { return &Env{} },
+	"EXPOSE":     func() interface{} { return &Expose{} },
+	"FROM":       func() interface{} { return &From{} },
+	"LABEL":      func() interface{} { return &Label{} },
+	"RUN":        func() interface{} { return &Run{} },
+	"SHELL":      func() interface{} { return &Shell{} },
+	"USER":       func() interface{} { return &User{} },
+	"VOLUME":     func() interface{} { return &Volume{} },
+	"WORKDIR":    func() interface{} { return &Workdir{} },
+}
+
+// migrations upgrade a raw command of the version keyed by to the next version.
+var migrations = map[int]func(kind string, raw map[string]interface{}){
+	1: func(kind string, raw map[string]interface{}) {
+		if kind == "CMD" {
+			if values, ok := raw["values"]; ok {
+				raw["Values"] = values
+				delete(raw, "values")
+			}
+		}
+	},
+}
+
+// Serialize serializes a command as JSON stamped with the current schema version.
+func Serialize(cmd VMInitSerializableCommand) ([]byte, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("command does not serialize as an object: %v", err)
+	}
+	raw[SchemaVersionKey] = SchemaVersion
+	return json.Marshal(raw)
+}
+
+// Deserialize deserializes a command serialized with any schema version up to SchemaVersion,
+// migrating the commands of older versions. Returns the command value, for example Run.
+func Deserialize(data []byte) (VMInitSerializableCommand, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return DeserializeRaw(raw)
+}
+
+// DeserializeRaw deserializes a command decoded from JSON into a map, see Deserialize.
+func DeserializeRaw(raw map[string]interface{}) (VMInitSerializableCommand, error) {
+	version, err := schemaVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	kind := commandKind(raw)
+	newCommand, ok := commandKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownCommand, kind)
+	}
+	for ; version < SchemaVersion; version++ {
+		if migration, ok := migrations[version]; ok {
+			migration(kind, raw)
+		}
+	}
+	delete(raw, SchemaVersionKey)
+	command := newCommand()
+	if err := mapstructure.Decode(raw, command); err != nil {
+		return nil, fmt.Errorf("found %s but did not deserialize: %v", kind, err)
+	}
+	// return the value, not the pointer:
+	return reflect.ValueOf(command).Elem().Interface(), nil
+}
+
+// schemaVersion returns the schema version of a raw command, 1 when not stamped.
+func schemaVersion(raw map[string]interface{}) (int, error) {
+	value, ok := raw[SchemaVersionKey]
+	if !ok {
+		return 1, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number < 1 || number != float64(int(number)) {
+		return 0, fmt.Errorf("%w: invalid schema version '%v'", ErrUnsupportedSchemaVersion, value)
+	}
+	if int(number) > SchemaVersion {
+		return 0, fmt.Errorf("%w: command serialized with schema version %d, this build supports versions up to %d, upgrade the deserializing side",
+			ErrUnsupportedSchemaVersion, int(number), SchemaVersion)
+	}
+	return int(number), nil
+}
+
+// commandKind returns the instruction of the original command of a raw command.
+func commandKind(raw map[string]interface{}) string {
+	original, _ := raw["OriginalCommand"].(string)
+	fields := strings.Fields(original)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandSchemaVersions(t *testing.T) {
+	run := RunWithDefaults("make install")
+	run.Needs = []string{"Makefile"}
+	data, err := Serialize(run)
+	assert.Nil(t, err)
+	raw := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, float64(SchemaVersion), raw[SchemaVersionKey])
+	deserialized, err := Deserialize(data)
+	assert.Nil(t, err)
+	assert.Equal(t, run, deserialized)
+
+	// version 1 commands are not stamped and serialize CMD values as values:
+	deserialized, err = Deserialize([]byte(`{"OriginalCommand":"CMD [\"serve\"]","values":["serve"]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, Cmd{OriginalCommand: `CMD ["serve"]`, Values: []string{"serve"}}, deserialized)
+
+	_, err = Deserialize([]byte(`{"OriginalCommand":"RUN true","SchemaVersion":99}`))
+	assert.True(t, errors.Is(err, ErrUnsupportedSchemaVersion))
+	assert.Contains(t, err.Error(), "upgrade")
+
+	_, err = Deserialize([]byte(`{"OriginalCommand":"HEALTHCHECK NONE"}`))
+	assert.True(t, errors.Is(err, ErrUnknownCommand))
+}
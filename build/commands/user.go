@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidUser is returned when a USER value does not parse.
+	ErrInvalidUser = errors.New("invalid user")
+	// ErrUnknownUser is returned when a user or group name is not found in the passwd or group file.
+	ErrUnknownUser = errors.New("unknown user or group")
+)
+
+// maxID is the largest valid user or group ID, 2^32-1 is reserved.
+const maxID = 1<<32 - 2
+
+// UserSpec is a parsed USER value of the form user[:group], the user and the group are names or numeric IDs.
+type UserSpec struct {
+	// User is the user name or the numeric user ID as written.
+	User string
+	// Group is the group name or the numeric group ID as written, empty without a group.
+	Group string
+}
+
+// ParseUserSpec parses and validates a USER value, for example 1000:1000, app or app:staff.
+// The whitespace around the user and the group is ignored.
+func ParseUserSpec(value string) (UserSpec, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 2 {
+		return UserSpec{}, fmt.Errorf("%w: '%s' has more than one ':'", ErrInvalidUser, value)
+	}
+	spec := UserSpec{User: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		spec.Group = strings.TrimSpace(parts[1])
+		if spec.Group == "" {
+			return UserSpec{}, fmt.Errorf("%w: '%s' has an empty group", ErrInvalidUser, value)
+		}
+	}
+	if spec.User == "" {
+		return UserSpec{}, fmt.Errorf("%w: '%s' has an empty user", ErrInvalidUser, value)
+	}
+	for _, part := range []string{spec.User, spec.Group} {
+		if err := validateUserPart(part); err != nil {
+			return UserSpec{}, fmt.Errorf("%w: '%s': %v", ErrInvalidUser, value, err)
+		}
+	}
+	return spec, nil
+}
+
+// Parse parses and validates the value of the USER command, see ParseUserSpec.
+func (cmd User) Parse() (UserSpec, error) {
+	return ParseUserSpec(cmd.Value)
+}
+
+// String returns the spec in the user[:group] form, numeric IDs as written.
+func (s UserSpec) String() string {
+	if s.Group == "" {
+		return s.User
+	}
+	return s.User + ":" + s.Group
+}
+
+// UID returns the numeric user ID, false if the user is a name.
+func (s UserSpec) UID() (uint32, bool) {
+	return numericID(s.User)
+}
+
+// GID returns the numeric group ID, false if the group is a name or there is no group.
+func (s UserSpec) GID() (uint32, bool) {
+	return numericID(s.Group)
+}
+
+// Lookup resolves the spec to numeric IDs with the contents of a passwd and a group file, for example
+// of the root file system of a guest. Like Docker, without a group the primary group of the user applies,
+// the root group for a numeric user not found in the passwd file. Returns ErrUnknownUser if a name is not found.
+func (s UserSpec) Lookup(passwd, group io.Reader) (uint32, uint32, error) {
+	users, err := readIDFile(passwd, 3)
+	if err != nil {
+		return 0, 0, err
+	}
+	uid, gid := uint32(0), uint32(0)
+	if numeric, ok := s.UID(); ok {
+		uid = numeric
+		for _, fields := range users {
+			if fields[2] == s.User {
+				gid, _ = numericID(fields[3])
+				break
+			}
+		}
+	} else {
+		found := false
+		for _, fields := range users {
+			if fields[0] == s.User {
+				uid, _ = numericID(fields[2])
+				gid, _ = numericID(fields[3])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, 0, fmt.Errorf("%w: user '%s'", ErrUnknownUser, s.User)
+		}
+	}
+	if s.Group == "" {
+		return uid, gid, nil
+	}
+	if numeric, ok := s.GID(); ok {
+		return uid, numeric, nil
+	}
+	groups, err := readIDFile(group, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, fields := range groups {
+		if fields[0] == s.Group {
+			gid, _ = numericID(fields[2])
+			return uid, gid, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: group '%s'", ErrUnknownUser, s.Group)
+}
+
+// LookupIn resolves the spec to numeric IDs with the etc/passwd and etc/group files under a root directory,
+// see Lookup. A missing file is treated as empty.
+func (s UserSpec) LookupIn(rootDir string) (uint32, uint32, error) {
+	passwd, err := openOptional(filepath.Join(rootDir, "etc", "passwd"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer passwd.Close()
+	group, err := openOptional(filepath.Join(rootDir, "etc", "group"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer group.Close()
+	return s.Lookup(passwd, group)
+}
+
+func validateUserPart(part string) error {
+	if part == "" {
+		return nil
+	}
+	if part[0] >= '0' && part[0] <= '9' {
+		if _, ok := numericID(part); !ok {
+			return fmt.Errorf("'%s' is not a valid numeric ID", part)
+		}
+		return nil
+	}
+	for _, c := range part {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '$') {
+			return fmt.Errorf("'%s' is not a valid name", part)
+		}
+	}
+	return nil
+}
+
+// numericID parses a numeric user or group ID, IDs do not fit an int on 32-bit platforms.
+func numericID(value string) (uint32, bool) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id > maxID {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// readIDFile returns the fields of the entries of a passwd or group file
+// with more fields than the minimum, the name and the fields up to the IDs.
+func readIDFile(reader io.Reader, minFields int) ([][]string, error) {
+	entries := [][]string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) <= minFields {
+			continue
+		}
+		entries = append(entries, fields)
+	}
+	return entries, scanner.Err()
+}
+
+func openOptional(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
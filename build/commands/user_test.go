@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPasswd = `root:x:0:0:root:/root:/bin/sh
+# a comment
+app:x:1000:1001:app:/home/app:/bin/sh
+nobody:x:65534:65534:nobody:/:/sbin/nologin
+`
+
+const testGroup = `root:x:0:
+staff:x:50:app
+app:x:1001:
+`
+
+func TestUserSpecParse(t *testing.T) {
+	for value, expected := range map[string]UserSpec{
+		"1000:1000":    {User: "1000", Group: "1000"},
+		"app":          {User: "app"},
+		" app : staff": {User: "app", Group: "staff"},
+		"www-data":     {User: "www-data"},
+	} {
+		spec, err := ParseUserSpec(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, spec, value)
+	}
+	for _, value := range []string{"", ":1000", "1000:", "a:b:c", "app user", "4294967295", "12ab"} {
+		_, err := ParseUserSpec(value)
+		assert.True(t, errors.Is(err, ErrInvalidUser), "expected %s to be invalid", value)
+	}
+
+	spec, err := User{Value: "1000:1000"}.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, "1000:1000", spec.String())
+	uid, ok := spec.UID()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1000), uid)
+	_, ok = UserSpec{User: "app"}.GID()
+	assert.False(t, ok)
+}
+
+func TestUserSpecLookup(t *testing.T) {
+	lookup := func(value string) (uint32, uint32, error) {
+		spec, err := ParseUserSpec(value)
+		assert.Nil(t, err)
+		return spec.Lookup(strings.NewReader(testPasswd), strings.NewReader(testGroup))
+	}
+	for value, expected := range map[string][2]uint32{
+		"app":        {1000, 1001},
+		"app:staff":  {1000, 50},
+		"1000":       {1000, 1001},
+		"1000:1000":  {1000, 1000},
+		"4242":       {4242, 0},
+		"nobody:app": {65534, 1001},
+		"4294967294": {4294967294, 0},
+	} {
+		uid, gid, err := lookup(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, [2]uint32{uid, gid}, value)
+	}
+	_, _, err := lookup("missing")
+	assert.True(t, errors.Is(err, ErrUnknownUser))
+	_, _, err = lookup("app:missing")
+	assert.True(t, errors.Is(err, ErrUnknownUser))
+
+	rootDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(rootDir, "etc"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(rootDir, "etc", "passwd"), []byte(testPasswd), 0644))
+	uid, gid, err := UserSpec{User: "app"}.LookupIn(rootDir)
+	assert.Nil(t, err)
+	assert.Equal(t, [2]uint32{1000, 1001}, [2]uint32{uid, gid})
+	_, _, err = UserSpec{User: "app", Group: "staff"}.LookupIn(rootDir)
+	assert.True(t, errors.Is(err, ErrUnknownUser), "expected a missing group file to be empty")
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrWorkdirEscape is returned when a path climbs above the root directory.
+var ErrWorkdirEscape = errors.New("path escapes the root directory")
+
+// Clean returns the workdir with a clean absolute value, a relative value is relative to the root directory.
+// Returns ErrWorkdirEscape if the value climbs above the root directory.
+func (cmd Workdir) Clean() (Workdir, error) {
+	value, err := joinUnderRoot("/", cmd.Value)
+	if err != nil {
+		return cmd, err
+	}
+	return Workdir{OriginalCommand: cmd.OriginalCommand, Value: value}, nil
+}
+
+// Join returns the workdir in effect after the next WORKDIR command: the next workdir when absolute,
+// otherwise the next workdir relative to this workdir, like consecutive WORKDIR instructions of a Dockerfile.
+// Returns ErrWorkdirEscape if the result climbs above the root directory.
+func (cmd Workdir) Join(next Workdir) (Workdir, error) {
+	base, err := cmd.Clean()
+	if err != nil {
+		return cmd, err
+	}
+	value, err := joinUnderRoot(base.Value, next.Value)
+	if err != nil {
+		return cmd, err
+	}
+	return Workdir{OriginalCommand: next.OriginalCommand, Value: value}, nil
+}
+
+// Resolve returns the clean absolute path of a target path relative to the workdir, an absolute target
+// is only cleaned. Returns ErrWorkdirEscape if the path climbs above the root directory.
+func (cmd Workdir) Resolve(target string) (string, error) {
+	base, err := cmd.Clean()
+	if err != nil {
+		return "", err
+	}
+	return joinUnderRoot(base.Value, target)
+}
+
+// EffectiveWorkdirs returns the workdir in effect for every command of a sequence, by index.
+// Every stage starts in DefaultWorkdir, the WORKDIR commands change the workdir of the commands after them.
+// Returns ErrWorkdirEscape if a WORKDIR command climbs above the root directory.
+func EffectiveWorkdirs(cmds []VMInitSerializableCommand) ([]Workdir, error) {
+	result := make([]Workdir, 0, len(cmds))
+	current := DefaultWorkdir()
+	for index, cmd := range cmds {
+		switch tcmd := cmd.(type) {
+		case From:
+			current = DefaultWorkdir()
+		case Workdir:
+			next, err := current.Join(tcmd)
+			if err != nil {
+				return nil, fmt.Errorf("command %d: %w", index, err)
+			}
+			current = next
+		}
+		result = append(result, current)
+	}
+	return result, nil
+}
+
+// joinUnderRoot joins the absolute base with a relative path, or cleans an absolute path,
+// and fails if the result climbs above the root directory instead of clamping it at the root.
+func joinUnderRoot(base, target string) (string, error) {
+	if path.IsAbs(target) {
+		base = "/"
+	}
+	depth := 0
+	for _, segment := range strings.Split(base, "/") {
+		if segment != "" && segment != "." {
+			depth = depth + 1
+		}
+	}
+	for _, segment := range strings.Split(target, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if depth == 0 {
+				return "", fmt.Errorf("%w: '%s'", ErrWorkdirEscape, target)
+			}
+			depth = depth - 1
+		default:
+			depth = depth + 1
+		}
+	}
+	return path.Join(base, target), nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkdirJoinAndResolve(t *testing.T) {
+	workdir, err := Workdir{Value: "app/./src/"}.Clean()
+	assert.Nil(t, err)
+	assert.Equal(t, "/app/src", workdir.Value)
+
+	joined, err := workdir.Join(Workdir{Value: "../bin"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/app/bin", joined.Value)
+	joined, err = workdir.Join(Workdir{Value: "/opt//tools"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/opt/tools", joined.Value)
+	_, err = workdir.Join(Workdir{Value: "../../.."})
+	assert.True(t, errors.Is(err, ErrWorkdirEscape))
+	_, err = Workdir{Value: "/.."}.Clean()
+	assert.True(t, errors.Is(err, ErrWorkdirEscape))
+
+	resolved, err := workdir.Resolve("main.go")
+	assert.Nil(t, err)
+	assert.Equal(t, "/app/src/main.go", resolved)
+	resolved, err = workdir.Resolve("/etc/../etc/hosts")
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/hosts", resolved)
+	resolved, err = workdir.Resolve("../..")
+	assert.Nil(t, err)
+	assert.Equal(t, "/", resolved)
+	_, err = workdir.Resolve("../../../etc/passwd")
+	assert.True(t, errors.Is(err, ErrWorkdirEscape))
+	_, err = DefaultWorkdir().Resolve("/../etc")
+	assert.True(t, errors.Is(err, ErrWorkdirEscape))
+}
+
+func TestEffectiveWorkdirs(t *testing.T) {
+	cmds := []VMInitSerializableCommand{
+		RunWithDefaults("true"),
+		Workdir{Value: "/app"},
+		Workdir{Value: "src"},
+		RunWithDefaults("make"),
+		From{BaseImage: "alpine:3.13"},
+		RunWithDefaults("true"),
+	}
+	workdirs, err := EffectiveWorkdirs(cmds)
+	assert.Nil(t, err)
+	values := []string{}
+	for _, workdir := range workdirs {
+		values = append(values, workdir.Value)
+	}
+	assert.Equal(t, []string{"/", "/app", "/app/src", "/app/src", "/", "/"}, values)
+
+	_, err = EffectiveWorkdirs([]VMInitSerializableCommand{Workdir{Value: ".."}})
+	assert.True(t, errors.Is(err, ErrWorkdirEscape))
+}
@@ -0,0 +1,382 @@
+// Package graph represents the executable commands of a build as a directed acyclic graph
+// of the dependencies between the commands.
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// ErrCycle is returned when a dependency would make the graph cyclic.
+var ErrCycle = errors.New("dependency cycle")
+
+// DependencyKind is the reason a command depends on another command.
+type DependencyKind int
+
+const (
+	// DependencyOrder is the dependency of a command on an earlier command of the same stage
+	// which may change the file system the command runs on.
+	DependencyOrder DependencyKind = iota
+	// DependencyResource is the dependency of a RUN command on the ADD or COPY command of a resource it needs.
+	DependencyResource
+	// DependencyStage is the dependency of a command on the commands of the stage it copies from
+	// or the stage its stage is based on.
+	DependencyStage
+	// DependencyExplicit is a dependency added with Graph.Depend.
+	DependencyExplicit
+)
+
+func (k DependencyKind) String() string {
+	switch k {
+	case DependencyOrder:
+		return "order"
+	case DependencyResource:
+		return "resource"
+	case DependencyStage:
+		return "stage"
+	case DependencyExplicit:
+		return "explicit"
+	}
+	return fmt.Sprintf("DependencyKind(%d)", int(k))
+}
+
+// Dependency is an edge of the graph, the command depends on the command at the index.
+type Dependency struct {
+	Index int
+	Kind  DependencyKind
+}
+
+// Node is a command of the graph.
+type Node struct {
+	// Index is the position of the command in the linear list of commands the graph was built from.
+	Index   int
+	Command commands.VMInitSerializableCommand
+	// Stage is the name of the stage the command belongs to, the index of the FROM command
+	// for a stage without a name and empty for the commands before the first FROM command.
+	Stage     string
+	DependsOn []Dependency
+}
+
+// Graph contains the commands and the dependencies between them.
+type Graph struct {
+	nodes []*Node
+}
+
+// New builds the graph of the commands in the order of the linear list.
+//
+// The dependencies are conservative: a RUN or DELETE command depends on the previous RUN or DELETE command
+// of its stage and on every ADD or COPY command since, unless the RUN command lists the resources it needs,
+// then it depends only on the ADD or COPY commands of these resources and the other ADD or COPY commands
+// carry over to the next RUN or DELETE command. An ADD or COPY command depends
+// on the previous RUN or DELETE command of its stage and on the ADD or COPY commands since with an overlapping target.
+// A COPY command from a stage depends on the commands of the stage. Every command depends on the FROM command
+// of its stage and a FROM command based on an earlier stage depends on the commands of that stage.
+// The remaining commands, for example ENV or LABEL, are applied by the commands using them
+// and depend only on the FROM command.
+func New(cmds []commands.VMInitSerializableCommand) *Graph {
+	g := &Graph{nodes: make([]*Node, 0, len(cmds))}
+
+	stages := map[string][]int{} // the indexes of the commands of a stage
+	stage, from, barrier := "", -1, -1
+	copies := []int{} // ADD and COPY commands since the barrier
+
+	for index, cmd := range cmds {
+		node := &Node{Index: index, Command: cmd}
+		g.nodes = append(g.nodes, node)
+
+		if tcmd, ok := cmd.(commands.From); ok {
+			stage = tcmd.StageName
+			if stage == "" {
+				stage = fmt.Sprintf("%d", index)
+			}
+			node.Stage = stage
+			for _, stageIndex := range stages[tcmd.BaseImage] {
+				node.depend(stageIndex, DependencyStage)
+			}
+			stages[stage] = []int{index}
+			from, barrier, copies = index, -1, []int{}
+			continue
+		}
+
+		node.Stage = stage
+		stages[stage] = append(stages[stage], index)
+		if from > -1 {
+			node.depend(from, DependencyOrder)
+		}
+
+		switch tcmd := cmd.(type) {
+		case commands.Run:
+			if barrier > -1 {
+				node.depend(barrier, DependencyOrder)
+			}
+			if len(tcmd.Needs) == 0 {
+				for _, copyIndex := range copies {
+					node.depend(copyIndex, DependencyOrder)
+				}
+				copies = []int{}
+			} else {
+				// the commands not needed remain dependencies of the next RUN or DELETE command:
+				unneeded := []int{}
+				for _, copyIndex := range copies {
+					if providesAny(cmds[copyIndex], tcmd.Needs) {
+						node.depend(copyIndex, DependencyResource)
+					} else {
+						unneeded = append(unneeded, copyIndex)
+					}
+				}
+				copies = unneeded
+			}
+			barrier = index
+		case commands.Delete:
+			if barrier > -1 {
+				node.depend(barrier, DependencyOrder)
+			}
+			for _, copyIndex := range copies {
+				node.depend(copyIndex, DependencyOrder)
+			}
+			barrier, copies = index, []int{}
+		case commands.Add, commands.Copy:
+			if barrier > -1 {
+				node.depend(barrier, DependencyOrder)
+			}
+			for _, copyIndex := range copies {
+				if targetsOverlap(cmds[copyIndex], cmd) {
+					node.depend(copyIndex, DependencyOrder)
+				}
+			}
+			if copyCmd, ok := cmd.(commands.Copy); ok && copyCmd.Stage != "" {
+				for _, stageIndex := range stages[copyCmd.Stage] {
+					node.depend(stageIndex, DependencyStage)
+				}
+			}
+			copies = append(copies, index)
+		}
+	}
+	return g
+}
+
+// Len returns the number of commands.
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Node returns the command at the index of the linear list, nil for an index out of range.
+func (g *Graph) Node(index int) *Node {
+	if index < 0 || index >= len(g.nodes) {
+		return nil
+	}
+	return g.nodes[index]
+}
+
+// Depend makes the command at the index depend on another command.
+// Returns ErrCycle if the other command depends on the command, directly or not.
+func (g *Graph) Depend(index, on int) error {
+	node, other := g.Node(index), g.Node(on)
+	if node == nil || other == nil {
+		return fmt.Errorf("command index out of range: %d -> %d", index, on)
+	}
+	if index == on || g.reaches(on, index) {
+		return fmt.Errorf("%w: %d -> %d", ErrCycle, index, on)
+	}
+	node.depend(on, DependencyExplicit)
+	return nil
+}
+
+// Dependents returns the indexes of the commands depending on the command at the index, directly or not,
+// in ascending order. These are the commands invalidated by a change of the command.
+func (g *Graph) Dependents(index int) []int {
+	dependents := map[int][]int{}
+	for _, node := range g.nodes {
+		for _, dependency := range node.DependsOn {
+			dependents[dependency.Index] = append(dependents[dependency.Index], node.Index)
+		}
+	}
+	visited := map[int]struct{}{}
+	queue := []int{index}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if _, ok := visited[dependent]; !ok {
+				visited[dependent] = struct{}{}
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	result := make([]int, 0, len(visited))
+	for dependent := range visited {
+		result = append(result, dependent)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// Levels returns the indexes of the commands grouped so that a command depends only on the commands
+// of the earlier groups. The commands of a group are independent and can execute in parallel.
+func (g *Graph) Levels() [][]int {
+	levels, levelOf := [][]int{}, map[int]int{}
+	for _, index := range g.order() {
+		level := 0
+		for _, dependency := range g.nodes[index].DependsOn {
+			if levelOf[dependency.Index]+1 > level {
+				level = levelOf[dependency.Index] + 1
+			}
+		}
+		levelOf[index] = level
+		for len(levels) <= level {
+			levels = append(levels, []int{})
+		}
+		levels[level] = append(levels[level], index)
+	}
+	for _, level := range levels {
+		sort.Ints(level)
+	}
+	return levels
+}
+
+// Linear serializes the graph to the linear list of commands executed one after another.
+// The commands keep their order unless an explicit dependency requires a command to move after another.
+func (g *Graph) Linear() []commands.VMInitSerializableCommand {
+	result := make([]commands.VMInitSerializableCommand, 0, len(g.nodes))
+	for _, index := range g.order() {
+		result = append(result, g.nodes[index].Command)
+	}
+	return result
+}
+
+// Groups returns the parallel groups of the commands of Linear, in the same order. The consecutive commands
+// of the same group other than 0 do not depend on each other and can execute concurrently,
+// a command of group 0 executes on its own.
+func (g *Graph) Groups() []int {
+	order := g.order()
+	groups := make([]int, len(order))
+	group, run := 0, map[int]int{} // the commands of the current run by index, with their position
+	closeRun := func() {
+		if len(run) > 1 {
+			group = group + 1
+			for _, position := range run {
+				groups[position] = group
+			}
+		}
+		run = map[int]int{}
+	}
+	for position, index := range order {
+		for _, dependency := range g.nodes[index].DependsOn {
+			if _, ok := run[dependency.Index]; ok {
+				closeRun()
+				break
+			}
+		}
+		run[index] = position
+	}
+	closeRun()
+	return groups
+}
+
+// order returns the indexes of the commands in topological order, the lowest ready index first.
+func (g *Graph) order() []int {
+	pending := make([]int, len(g.nodes))
+	dependents := map[int][]int{}
+	for _, node := range g.nodes {
+		pending[node.Index] = len(node.DependsOn)
+		for _, dependency := range node.DependsOn {
+			dependents[dependency.Index] = append(dependents[dependency.Index], node.Index)
+		}
+	}
+	ready := []int{}
+	for index, count := range pending {
+		if count == 0 {
+			ready = append(ready, index)
+		}
+	}
+	result := make([]int, 0, len(g.nodes))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		current := ready[0]
+		ready = ready[1:]
+		result = append(result, current)
+		for _, dependent := range dependents[current] {
+			pending[dependent] = pending[dependent] - 1
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	return result
+}
+
+// reaches returns true if the command at the index depends on the other command, directly or not.
+func (g *Graph) reaches(index, other int) bool {
+	visited := map[int]struct{}{}
+	queue := []int{index}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependency := range g.nodes[current].DependsOn {
+			if dependency.Index == other {
+				return true
+			}
+			if _, ok := visited[dependency.Index]; !ok {
+				visited[dependency.Index] = struct{}{}
+				queue = append(queue, dependency.Index)
+			}
+		}
+	}
+	return false
+}
+
+func (n *Node) depend(index int, kind DependencyKind) {
+	for _, dependency := range n.DependsOn {
+		if dependency.Index == index {
+			return
+		}
+	}
+	n.DependsOn = append(n.DependsOn, Dependency{Index: index, Kind: kind})
+}
+
+// providesAny returns true if the command is an ADD or COPY command of any of the resources.
+func providesAny(cmd commands.VMInitSerializableCommand, resourcePaths []string) bool {
+	source := ""
+	switch tcmd := cmd.(type) {
+	case commands.Add:
+		source = tcmd.Source
+	case commands.Copy:
+		source = tcmd.Source
+	}
+	for _, resourcePath := range resourcePaths {
+		if resourcePath == source {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsOverlap returns true if the targets of the ADD or COPY commands are the same path
+// or one contains the other.
+func targetsOverlap(cmd, other commands.VMInitSerializableCommand) bool {
+	target, otherTarget := targetOf(cmd), targetOf(other)
+	return target == otherTarget ||
+		strings.HasPrefix(target, strings.TrimSuffix(otherTarget, "/")+"/") ||
+		strings.HasPrefix(otherTarget, strings.TrimSuffix(target, "/")+"/")
+}
+
+// targetOf returns the absolute target of an ADD or COPY command.
+func targetOf(cmd commands.VMInitSerializableCommand) string {
+	target, workdir := "", commands.Workdir{}
+	switch tcmd := cmd.(type) {
+	case commands.Add:
+		target, workdir = tcmd.Target, tcmd.Workdir
+	case commands.Copy:
+		target, workdir = tcmd.Target, tcmd.Workdir
+	}
+	resolved, err := workdir.Resolve(target)
+	if err != nil {
+		// a target above the root overlaps with everything:
+		return "/"
+	}
+	return resolved
+}
@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCopy(source, target, stage string) commands.Copy {
+	return commands.Copy{
+		OriginalCommand: "COPY " + source + " " + target,
+		Source:          source,
+		Stage:           stage,
+		Target:          target,
+		Workdir:         commands.DefaultWorkdir(),
+		User:            commands.DefaultUser(),
+	}
+}
+
+func testNeeds(command string, needs ...string) commands.Run {
+	run := commands.RunWithDefaults(command)
+	run.Needs = needs
+	return run
+}
+
+func dependencies(node *Node) map[int]DependencyKind {
+	result := map[int]DependencyKind{}
+	for _, dependency := range node.DependsOn {
+		result[dependency.Index] = dependency.Kind
+	}
+	return result
+}
+
+func TestGraphDependencies(t *testing.T) {
+	cmds := []commands.VMInitSerializableCommand{
+		commands.From{BaseImage: "golang:1.16", StageName: "builder"},    // 0
+		testCopy("go.mod", "/src/go.mod", ""),                            // 1
+		testCopy("main.go", "/src/main.go", ""),                          // 2
+		testNeeds("go build", "main.go"),                                 // 3
+		commands.Env{Name: "CGO_ENABLED", Value: "0"},                    // 4
+		commands.From{BaseImage: "alpine:3.13"},                          // 5
+		testCopy("/src/app", "/usr/bin/app", "builder"),                  // 6
+		testCopy("etc", "/etc/app", ""),                                  // 7
+		testCopy("etc/app.conf", "/etc/app/app.conf", ""),                // 8
+		commands.RunWithDefaults("chmod +x /usr/bin/app"),                // 9
+		commands.DeleteWithDefaults("/etc/app/unused.conf"),              // 10
+		commands.Label{Key: "org.opencontainers.image.title", Value: ""}, // 11
+	}
+	g := New(cmds)
+	assert.Equal(t, len(cmds), g.Len())
+
+	assert.Equal(t, map[int]DependencyKind{}, dependencies(g.Node(0)))
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder}, dependencies(g.Node(1)))
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder}, dependencies(g.Node(2)), "expected copies of distinct targets to be independent")
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder, 2: DependencyResource}, dependencies(g.Node(3)), "expected a RUN with needs to depend only on the needed resources")
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder}, dependencies(g.Node(4)))
+	assert.Equal(t, map[int]DependencyKind{}, dependencies(g.Node(5)))
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder, 0: DependencyStage, 1: DependencyStage, 2: DependencyStage, 3: DependencyStage, 4: DependencyStage}, dependencies(g.Node(6)))
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder}, dependencies(g.Node(7)))
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder, 7: DependencyOrder}, dependencies(g.Node(8)), "expected overlapping targets to keep their order")
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder, 6: DependencyOrder, 7: DependencyOrder, 8: DependencyOrder}, dependencies(g.Node(9)))
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder, 9: DependencyOrder}, dependencies(g.Node(10)))
+	assert.Equal(t, map[int]DependencyKind{5: DependencyOrder}, dependencies(g.Node(11)))
+	assert.Equal(t, "builder", g.Node(4).Stage)
+	assert.Equal(t, "5", g.Node(11).Stage)
+	assert.Nil(t, g.Node(12))
+
+	assert.Equal(t, cmds, g.Linear(), "expected the inferred dependencies to keep the order")
+	assert.Equal(t, [][]int{{0, 5}, {1, 2, 4, 7, 11}, {3, 8}, {6}, {9}, {10}}, g.Levels())
+	assert.Equal(t, []int{0, 1, 1, 2, 2, 2, 3, 3, 0, 0, 4, 4}, g.Groups())
+	assert.Equal(t, []int{6, 9, 10}, g.Dependents(4))
+	assert.Equal(t, []int{3, 6, 9, 10}, g.Dependents(2))
+}
+
+func TestGraphUnneededCopiesCarryOver(t *testing.T) {
+	cmds := []commands.VMInitSerializableCommand{
+		commands.From{BaseImage: "alpine:3.13"},    // 0
+		testCopy("a", "/a", ""),                    // 1
+		testCopy("b", "/b", ""),                    // 2
+		testNeeds("run a", "a"),                    // 3
+		commands.RunWithDefaults("run everything"), // 4
+	}
+	g := New(cmds)
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder, 1: DependencyResource}, dependencies(g.Node(3)))
+	assert.Equal(t, map[int]DependencyKind{0: DependencyOrder, 2: DependencyOrder, 3: DependencyOrder}, dependencies(g.Node(4)),
+		"expected the copy not needed by the RUN with needs to remain a dependency of the next RUN")
+	assert.Equal(t, []int{4}, g.Dependents(2), "expected a change of the copy to invalidate the last RUN")
+}
+
+func TestGraphExplicitDependencies(t *testing.T) {
+	cmds := []commands.VMInitSerializableCommand{
+		testCopy("a", "/a", ""),
+		testCopy("b", "/b", ""),
+		testNeeds("run a", "a"),
+	}
+	g := New(cmds)
+	assert.Nil(t, g.Depend(0, 1))
+	assert.Equal(t, []commands.VMInitSerializableCommand{cmds[1], cmds[0], cmds[2]}, g.Linear())
+	assert.Equal(t, [][]int{{1}, {0}, {2}}, g.Levels())
+	assert.Equal(t, []int{0, 0, 0}, g.Groups())
+
+	assert.True(t, errors.Is(g.Depend(1, 2), ErrCycle))
+	assert.True(t, errors.Is(g.Depend(1, 1), ErrCycle))
+	assert.NotNil(t, g.Depend(1, 3))
+	assert.Equal(t, DependencyExplicit, g.Node(0).DependsOn[0].Kind)
+}
+
+func TestGraphWithoutStages(t *testing.T) {
+	cmds := []commands.VMInitSerializableCommand{
+		commands.RunWithDefaults("apk add curl"),
+		commands.RunWithDefaults("apk add git"),
+	}
+	g := New(cmds)
+	assert.Equal(t, "", g.Node(0).Stage)
+	assert.Equal(t, [][]int{{0}, {1}}, g.Levels())
+	assert.Equal(t, cmds, g.Linear())
+	assert.Equal(t, 0, New(nil).Len())
+	assert.Equal(t, []commands.VMInitSerializableCommand{}, New(nil).Linear())
+}
@@ -0,0 +1,145 @@
+package resources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DockerIgnoreFileName is the name of the file containing the context exclusion patterns.
+const DockerIgnoreFileName = ".dockerignore"
+
+type ignorePattern struct {
+	exclusion bool
+	regexp    *regexp.Regexp
+}
+
+// IgnoreMatcher matches context relative paths against .dockerignore patterns.
+// The last matching pattern wins, patterns starting with ! re-include previously excluded paths.
+type IgnoreMatcher struct {
+	patterns      []*ignorePattern
+	hasExclusions bool
+}
+
+// NewIgnoreMatcher creates a matcher from .dockerignore style patterns.
+func NewIgnoreMatcher(patterns []string) (*IgnoreMatcher, error) {
+	matcher := &IgnoreMatcher{patterns: []*ignorePattern{}}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		exclusion := false
+		if strings.HasPrefix(pattern, "!") {
+			exclusion = true
+			pattern = strings.TrimSpace(pattern[1:])
+		}
+		pattern = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(pattern)), "/")
+		compiled, err := regexp.Compile(ignorePatternToRegexp(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern '%s', reason: %v", pattern, err)
+		}
+		if exclusion {
+			matcher.hasExclusions = true
+		}
+		matcher.patterns = append(matcher.patterns, &ignorePattern{exclusion: exclusion, regexp: compiled})
+	}
+	return matcher, nil
+}
+
+// ReadIgnoreMatcher reads the patterns from a reader, one pattern per line.
+func ReadIgnoreMatcher(reader io.Reader) (*IgnoreMatcher, error) {
+	patterns := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewIgnoreMatcher(patterns)
+}
+
+// LoadDockerIgnore loads the .dockerignore file of a context directory.
+// Returns a matcher excluding nothing if the file does not exist.
+func LoadDockerIgnore(contextDir string) (*IgnoreMatcher, error) {
+	file, err := os.Open(filepath.Join(contextDir, DockerIgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewIgnoreMatcher([]string{})
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return ReadIgnoreMatcher(file)
+}
+
+// HasExclusions returns true if any of the patterns re-includes paths with !.
+// When true, an ignored directory may still contain included entries.
+func (m *IgnoreMatcher) HasExclusions() bool {
+	return m.hasExclusions
+}
+
+// Ignored returns true if the context relative path is excluded by the patterns.
+func (m *IgnoreMatcher) Ignored(relativePath string) bool {
+	relativePath = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(relativePath)), "/")
+	ignored := false
+	for _, pattern := range m.patterns {
+		if pattern.regexp.MatchString(relativePath) {
+			ignored = !pattern.exclusion
+		}
+	}
+	return ignored
+}
+
+// ignorePatternToRegexp converts a pattern to a regular expression matching the path
+// and everything below the path.
+func ignorePatternToRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// **/ matches zero or more directories
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i = i + end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(/.*)?$")
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package resources
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// MemoryScheme is the scheme of the sources served by the generators registered with RegisterGenerator.
+const MemoryScheme = "memory"
+
+// Generator writes the contents of a resource, it is called for every read of the contents.
+type Generator func(w io.Writer) error
+
+// SizedResource identifies a file resource knowing the size of its contents without reading them.
+type SizedResource interface {
+	// Size returns the size of the contents, false if the size is not known.
+	Size() (int64, bool)
+}
+
+// SizeOf returns the size of the contents of a resolved resource, false if the resource does not know its size.
+func SizeOf(resource ResolvedResource) (int64, bool) {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(SizedResource)
+		return ok
+	}); found != nil {
+		return found.(SizedResource).Size()
+	}
+	return 0, false
+}
+
+type memoryResolvedResource struct {
+	ResolvedResource
+	size func() (int64, bool)
+}
+
+func (mrr *memoryResolvedResource) Size() (int64, bool) {
+	return mrr.size()
+}
+
+func (mrr *memoryResolvedResource) Unwrap() ResolvedResource {
+	return mrr.ResolvedResource
+}
+
+func newMemoryResource(name string, contents func() (io.ReadCloser, error), size func() (int64, bool), mode fs.FileMode, targetPath string) ResolvedResource {
+	return &memoryResolvedResource{
+		ResolvedResource: NewResolvedFileResourceWithPath(contents, mode, name, targetPath,
+			commands.Workdir{}, commands.User{}, MemoryScheme+"://"+name),
+		size: size,
+	}
+}
+
+// FromBytes creates a file resource serving a copy of the data under the name, the source path of the resource.
+// The resource has no workdir and user, the defaults of the work context apply.
+func FromBytes(name string, data []byte, mode fs.FileMode, targetPath string) ResolvedResource {
+	copied := append([]byte{}, data...)
+	return newMemoryResource(name, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(copied)), nil
+	}, func() (int64, bool) {
+		return int64(len(copied)), true
+	}, mode, targetPath)
+}
+
+// FromString creates a file resource serving the string under the name, see FromBytes.
+func FromString(name, data string, mode fs.FileMode, targetPath string) ResolvedResource {
+	return FromBytes(name, []byte(data), mode, targetPath)
+}
+
+// FromSupplier creates a file resource serving the data of the supplier under the name, see FromBytes.
+// The supplier is called once, when the contents or the size are needed for the first time,
+// and the data or the error are kept for the subsequent reads.
+func FromSupplier(name string, supplier func() ([]byte, error), mode fs.FileMode, targetPath string) ResolvedResource {
+	once := &sync.Once{}
+	var data []byte
+	var supplierErr error
+	supply := func() ([]byte, error) {
+		once.Do(func() {
+			data, supplierErr = supplier()
+		})
+		return data, supplierErr
+	}
+	return newMemoryResource(name, func() (io.ReadCloser, error) {
+		supplied, err := supply()
+		if err != nil {
+			return nil, fmt.Errorf("resource failed: could not supply '%s', reason: %w", name, err)
+		}
+		return ioutil.NopCloser(bytes.NewReader(supplied)), nil
+	}, func() (int64, bool) {
+		supplied, err := supply()
+		return int64(len(supplied)), err == nil
+	}, mode, targetPath)
+}
+
+// FromGenerator creates a file resource streaming what the generator writes under the name, see FromBytes.
+// Every read of the contents runs the generator in a goroutine writing to the returned reader,
+// an error returned by the generator is returned from the read. Closing the reader early fails
+// the writes of the generator. The size is not known.
+func FromGenerator(name string, generator Generator, mode fs.FileMode, targetPath string) ResolvedResource {
+	return newMemoryResource(name, func() (io.ReadCloser, error) {
+		return generate(generator), nil
+	}, func() (int64, bool) {
+		return 0, false
+	}, mode, targetPath)
+}
+
+func generate(generator Generator) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(generator(writer))
+	}()
+	return reader
+}
+
+var (
+	generatorsLock = &sync.Mutex{}
+	generators     = map[string]Generator{}
+)
+
+// RegisterGenerator registers a generator under a name, replacing a generator registered under the same name,
+// so that ADD and COPY resolve memory://name to a resource streaming what the generator writes.
+func RegisterGenerator(name string, generator Generator) {
+	generatorsLock.Lock()
+	defer generatorsLock.Unlock()
+	generators[name] = generator
+}
+
+// resolveMemory resolves a memory source to a resource streaming the registered generator.
+func resolveMemory(request SchemeRequest) ([]ResolvedResource, error) {
+	name := request.URI[len(MemoryScheme+"://"):]
+	generatorsLock.Lock()
+	generator, ok := generators[name]
+	generatorsLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no generator registered under '%s'", name)
+	}
+	return []ResolvedResource{&memoryResolvedResource{
+		ResolvedResource: NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			return generate(generator), nil
+		}, fs.FileMode(0644), request.URI, request.TargetPath, request.TargetWorkdir, request.TargetUser, request.URI),
+		size: func() (int64, bool) {
+			return 0, false
+		},
+	}}, nil
+}
@@ -0,0 +1,110 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustReadContents(t *testing.T, resource ResolvedResource) string {
+	reader, err := resource.Contents()
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer reader.Close()
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	return string(contents)
+}
+
+func TestMemoryResourcesReadRepeatedly(t *testing.T) {
+	data := []byte("from bytes")
+	fromBytes := FromBytes("bytes", data, 0600, "/etc/bytes")
+	// the data is copied:
+	data[0] = 'F'
+
+	supplied := 0
+	fromSupplier := FromSupplier("supplier", func() ([]byte, error) {
+		supplied = supplied + 1
+		return []byte("from supplier"), nil
+	}, 0644, "/etc/supplier")
+
+	generated := 0
+	fromGenerator := FromGenerator("generator", func(w io.Writer) error {
+		generated = generated + 1
+		_, err := fmt.Fprintf(w, "generated %d", generated)
+		return err
+	}, 0644, "/etc/generator")
+
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, "from bytes", mustReadContents(t, fromBytes))
+		assert.Equal(t, "from supplier", mustReadContents(t, FromString("string", "from supplier", 0644, "/etc/string")))
+		assert.Equal(t, "from supplier", mustReadContents(t, fromSupplier))
+		assert.Equal(t, fmt.Sprintf("generated %d", i), mustReadContents(t, fromGenerator))
+	}
+	assert.Equal(t, 1, supplied)
+
+	size, ok := SizeOf(fromBytes)
+	assert.True(t, ok)
+	assert.Equal(t, int64(len("from bytes")), size)
+	size, ok = SizeOf(NewRetargetedResource("/opt/supplier", fromSupplier))
+	assert.True(t, ok)
+	assert.Equal(t, int64(len("from supplier")), size)
+	_, ok = SizeOf(fromGenerator)
+	assert.False(t, ok)
+
+	assert.Equal(t, "bytes", fromBytes.SourcePath())
+	assert.Equal(t, "/etc/bytes", fromBytes.TargetPath())
+	assert.Equal(t, "memory://bytes", fromBytes.ResolvedURIOrPath())
+	assert.Equal(t, "", fromBytes.TargetUser().Value)
+}
+
+func TestMemoryResourceErrors(t *testing.T) {
+	supplierErr := errors.New("supplier failed")
+	fromSupplier := FromSupplier("supplier", func() ([]byte, error) {
+		return nil, supplierErr
+	}, 0644, "/etc/supplier")
+	_, err := fromSupplier.Contents()
+	assert.True(t, errors.Is(err, supplierErr))
+	_, ok := SizeOf(fromSupplier)
+	assert.False(t, ok)
+
+	generatorErr := errors.New("generator failed")
+	reader, err := FromGenerator("generator", func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return generatorErr
+	}, 0644, "/etc/generator").Contents()
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.True(t, errors.Is(err, generatorErr))
+}
+
+func TestRegisteredGeneratorResolves(t *testing.T) {
+	RegisterGenerator("motd", func(w io.Writer) error {
+		_, err := w.Write([]byte("welcome"))
+		return err
+	})
+	resolved, err := NewDefaultResolver().ResolveAdd(commands.Add{
+		OriginalSource: "/context/Dockerfile",
+		Source:         "memory://motd",
+		Target:         "/etc/motd",
+		Workdir:        commands.DefaultWorkdir(),
+		User:           commands.DefaultUser(),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resolved))
+	assert.Equal(t, "welcome", mustReadContents(t, resolved[0]))
+	assert.Equal(t, "welcome", mustReadContents(t, resolved[0]))
+
+	_, err = NewDefaultResolver().ResolveAdd(commands.Add{
+		OriginalSource: "/context/Dockerfile",
+		Source:         "memory://unregistered",
+		Target:         "/etc/motd",
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,125 @@
+package resources
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// SchemeRequest is the source of an ADD or COPY command handed to the handler of its scheme.
+type SchemeRequest struct {
+	// URI is the source of the command including the scheme, for example oci://registry/image:tag.
+	URI string
+	// Scheme is the scheme of the URI without ://.
+	Scheme string
+	// OriginalSource is the location of the Dockerfile the command comes from.
+	OriginalSource string
+	// TargetPath is the target of the command.
+	TargetPath string
+	// TargetWorkdir is the workdir of the command.
+	TargetWorkdir commands.Workdir
+	// TargetUser is the user the resources are owned by.
+	TargetUser commands.User
+}
+
+// SchemeHandler resolves the resources of a source with the scheme the handler is registered under.
+// The handler may defer fetching the contents until ResolvedResource.Contents is called.
+type SchemeHandler func(request SchemeRequest) ([]ResolvedResource, error)
+
+var (
+	schemesLock    = &sync.Mutex{}
+	schemeHandlers = map[string]SchemeHandler{
+		"http":       resolveHTTP,
+		"https":      resolveHTTP,
+		MemoryScheme: resolveMemory,
+	}
+)
+
+// RegisterScheme registers a handler resolving the sources starting with the scheme followed by ://,
+// replacing a handler registered under the same scheme. Packages providing new source types,
+// for example oci:// or vault://, register their handlers in init so that the default resolver
+// resolves their sources without changes to this package.
+func RegisterScheme(scheme string, handler SchemeHandler) {
+	schemesLock.Lock()
+	defer schemesLock.Unlock()
+	schemeHandlers[scheme] = handler
+}
+
+// Schemes returns the sorted schemes with a registered handler.
+func Schemes() []string {
+	schemesLock.Lock()
+	defer schemesLock.Unlock()
+	schemes := []string{}
+	for scheme := range schemeHandlers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// schemeOf returns the scheme of a source, empty for a source without a scheme.
+// A scheme starts with a letter followed by letters, digits, +, - or . so that
+// a local path containing :// is not mistaken for a source with a scheme.
+func schemeOf(source string) string {
+	index := strings.Index(source, "://")
+	if index < 1 {
+		return ""
+	}
+	for i, r := range source[0:index] {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isLetter && (i == 0 || !((r >= '0' && r <= '9') || r == '+' || r == '-' || r == '.')) {
+			return ""
+		}
+	}
+	return source[0:index]
+}
+
+// resolveScheme resolves a source with a scheme with its registered handler.
+func resolveScheme(scheme string, request SchemeRequest) ([]ResolvedResource, error) {
+	schemesLock.Lock()
+	handler, ok := schemeHandlers[scheme]
+	schemesLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("resource failed: no handler registered for scheme '%s' of '%s'", scheme, request.URI)
+	}
+	request.Scheme = scheme
+	resolved, err := handler(request)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: %s handler failed for '%s', reason: %w", scheme, request.URI, err)
+	}
+	return resolved, nil
+}
+
+// resolveHTTP resolves a http and https source, the contents are fetched on read.
+func resolveHTTP(request SchemeRequest) ([]ResolvedResource, error) {
+	return []ResolvedResource{newHTTPResource(request.URI, request)}, nil
+}
+
+// newHTTPResource returns a resource fetching the contents of the URI of the request on read,
+// a response without a 2xx status fails the read.
+func newHTTPResource(sourcePath string, request SchemeRequest) ResolvedResource {
+	httpContentSupplier := func() (io.ReadCloser, error) {
+		httpResponse, err := http.Get(request.URI)
+		if err != nil {
+			return nil, err
+		}
+		if httpResponse.StatusCode/100 != 2 {
+			httpResponse.Body.Close()
+			return nil, fmt.Errorf("http resource failed: could not GET resource '%s', reason: %s", request.URI, httpResponse.Status)
+		}
+		return httpResponse.Body, nil
+	}
+	return &defaultResolvedResource{contentsReader: httpContentSupplier,
+		resolved:      request.URI,
+		targetMode:    fs.FileMode(0644),
+		sourcePath:    sourcePath,
+		targetPath:    request.TargetPath,
+		targetWorkdir: request.TargetWorkdir,
+		targetUser:    request.TargetUser}
+}
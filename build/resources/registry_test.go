@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisteredSchemeResolves(t *testing.T) {
+	requests := []SchemeRequest{}
+	RegisterScheme("test-scheme", func(request SchemeRequest) ([]ResolvedResource, error) {
+		requests = append(requests, request)
+		return []ResolvedResource{NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("from the handler")), nil
+		}, 0600, request.URI, request.TargetPath, request.TargetWorkdir, request.TargetUser, request.URI)}, nil
+	})
+	assert.Contains(t, Schemes(), "test-scheme")
+	assert.Contains(t, Schemes(), "https")
+
+	resolved, err := NewDefaultResolver().ResolveAdd(commands.Add{
+		OriginalSource: "/context/Dockerfile",
+		Source:         "test-scheme://bucket/file",
+		Target:         "/etc/file",
+		Workdir:        commands.DefaultWorkdir(),
+		User:           commands.DefaultUser(),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resolved))
+	assert.Equal(t, "/etc/file", resolved[0].TargetPath())
+	assert.Equal(t, []SchemeRequest{{
+		URI:            "test-scheme://bucket/file",
+		Scheme:         "test-scheme",
+		OriginalSource: "/context/Dockerfile",
+		TargetPath:     "/etc/file",
+		TargetWorkdir:  commands.DefaultWorkdir(),
+		TargetUser:     commands.DefaultUser(),
+	}}, requests)
+
+	reader, err := resolved[0].Contents()
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "from the handler", string(contents))
+
+	_, err = NewDefaultResolver().ResolveCopy(commands.Copy{
+		OriginalSource: "/context/Dockerfile",
+		Source:         "unregistered://bucket/file",
+		Target:         "/etc/file",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSchemeOf(t *testing.T) {
+	assert.Equal(t, "https", schemeOf("https://example.com/file"))
+	assert.Equal(t, "oci+layout", schemeOf("oci+layout://image"))
+	assert.Equal(t, "", schemeOf("dir/a://b"))
+	assert.Equal(t, "", schemeOf("://b"))
+	assert.Equal(t, "", schemeOf("relative/path"))
+}
+
+func TestHTTPResourceStatus(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/context/file" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("contents"))
+	}))
+	defer httpServer.Close()
+
+	resolve := func(originalSource, source string) ([]ResolvedResource, error) {
+		return NewDefaultResolver().ResolveAdd(commands.Add{
+			OriginalSource: originalSource,
+			Source:         source,
+			Target:         "/etc/file",
+			Workdir:        commands.DefaultWorkdir(),
+			User:           commands.DefaultUser(),
+		})
+	}
+	read := func(resource ResolvedResource) (string, error) {
+		reader, err := resource.Contents()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		contents, err := ioutil.ReadAll(reader)
+		return string(contents), err
+	}
+
+	// an absolute URL:
+	resolved, err := resolve("/context/Dockerfile", httpServer.URL+"/context/file")
+	assert.Nil(t, err)
+	contents, err := read(resolved[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "contents", contents)
+	resolved, err = resolve("/context/Dockerfile", httpServer.URL+"/context/missing")
+	assert.Nil(t, err)
+	_, err = read(resolved[0])
+	assert.NotNil(t, err, "expected a read of a missing URL to fail")
+
+	// a path relative to a Dockerfile fetched over http:
+	resolved, err = resolve(httpServer.URL+"/context/Dockerfile", "file")
+	assert.Nil(t, err)
+	assert.Equal(t, "file", resolved[0].SourcePath())
+	contents, err = read(resolved[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "contents", contents)
+	_, err = resolve(httpServer.URL+"/context/Dockerfile", "missing")
+	assert.NotNil(t, err, "expected a missing relative resource to fail")
+}
@@ -2,12 +2,14 @@ package resources
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -16,6 +18,9 @@ import (
 
 // ResolvedResource contains the data and the metadata of the resolved resource.
 type ResolvedResource interface {
+	// Contents returns a new reader of the contents from the start. The server reads the contents
+	// of a resource many times, for retransmissions, resumed transfers and multiple guests,
+	// resources which can be read only once implement ReusableResource.
 	Contents() (io.ReadCloser, error)
 	IsDir() bool
 	ResolvedURIOrPath() string
@@ -70,6 +75,291 @@ func (drr *defaultResolvedResource) TargetUser() commands.User {
 	return drr.targetUser
 }
 
+// PlatformAware identifies a resolved resource targeting a specific platform.
+// Resources not implementing this interface apply to every platform.
+type PlatformAware interface {
+	// Platform returns the platform of the resource, for example linux/arm64.
+	// An empty value means the resource applies to every platform.
+	Platform() string
+}
+
+// WrappedResource identifies a resolved resource adding capabilities to another resolved resource.
+type WrappedResource interface {
+	// Unwrap returns the wrapped resource.
+	Unwrap() ResolvedResource
+}
+
+// find walks the chain of wrapped resources and returns the first resource matching the predicate.
+func find(resource ResolvedResource, predicate func(ResolvedResource) bool) ResolvedResource {
+	for resource != nil {
+		if predicate(resource) {
+			return resource
+		}
+		wrapped, ok := resource.(WrappedResource)
+		if !ok {
+			return nil
+		}
+		resource = wrapped.Unwrap()
+	}
+	return nil
+}
+
+// PlatformOf returns the platform of a resolved resource or an empty string
+// if the resource is not platform specific.
+func PlatformOf(resource ResolvedResource) string {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(PlatformAware)
+		return ok
+	}); found != nil {
+		return found.(PlatformAware).Platform()
+	}
+	return ""
+}
+
+// PlatformMatches returns true if a resource platform is applicable to the wanted platform.
+// An empty value on either side matches everything. A platform without an OS is an architecture
+// with an optional variant, so arm64 matches linux/arm64 and arm/v7 matches linux/arm/v7.
+// The OS is compared only when both sides contain it and the variant only when both sides contain it,
+// so linux/arm64/v8 matches linux/arm64. The aliases x86_64 and aarch64 are amd64 and arm64.
+func PlatformMatches(resourcePlatform, wantedPlatform string) bool {
+	if resourcePlatform == "" || wantedPlatform == "" {
+		return true
+	}
+	resourceOS, resourceArch, resourceVariant := parsePlatform(resourcePlatform)
+	wantedOS, wantedArch, wantedVariant := parsePlatform(wantedPlatform)
+	if resourceOS != "" && wantedOS != "" && resourceOS != wantedOS {
+		return false
+	}
+	if resourceArch != wantedArch {
+		return false
+	}
+	return resourceVariant == "" || wantedVariant == "" || resourceVariant == wantedVariant
+}
+
+// platformArchitectures lists the known architectures, a platform of two parts starting with one
+// is an architecture and a variant.
+var platformArchitectures = map[string]struct{}{
+	"386": {}, "amd64": {}, "arm": {}, "arm64": {}, "loong64": {}, "mips": {}, "mipsle": {}, "mips64": {},
+	"mips64le": {}, "ppc64": {}, "ppc64le": {}, "riscv64": {}, "s390x": {}, "wasm": {},
+}
+
+// platformArchitectureAliases maps the architecture names reported by uname to the names used in platforms.
+var platformArchitectureAliases = map[string]string{
+	"aarch64": "arm64",
+	"x86_64":  "amd64",
+}
+
+// parsePlatform splits a platform into the lower case OS, architecture and variant,
+// a platform without a slash is an architecture and arm/v7 is an architecture and a variant.
+// The architecture aliases are normalized, so x86_64 is amd64.
+func parsePlatform(platform string) (string, string, string) {
+	normalize := func(arch string) string {
+		if alias, ok := platformArchitectureAliases[arch]; ok {
+			return alias
+		}
+		return arch
+	}
+	parts := strings.SplitN(strings.ToLower(platform), "/", 3)
+	switch len(parts) {
+	case 1:
+		return "", normalize(parts[0]), ""
+	case 2:
+		if arch := normalize(parts[0]); isPlatformArchitecture(arch) {
+			return "", arch, parts[1]
+		}
+		return parts[0], normalize(parts[1]), ""
+	}
+	return parts[0], normalize(parts[1]), parts[2]
+}
+
+func isPlatformArchitecture(arch string) bool {
+	_, ok := platformArchitectures[arch]
+	return ok
+}
+
+type platformResolvedResource struct {
+	ResolvedResource
+	platform string
+}
+
+func (prr *platformResolvedResource) Platform() string {
+	return prr.platform
+}
+
+func (prr *platformResolvedResource) Unwrap() ResolvedResource {
+	return prr.ResolvedResource
+}
+
+// NewPlatformVariant wraps a resolved resource as a variant for a given platform.
+// Multiple variants for different platforms can be registered under the same source path,
+// the server streams only the variants matching the platform of the work context.
+func NewPlatformVariant(platform string, resource ResolvedResource) ResolvedResource {
+	return &platformResolvedResource{ResolvedResource: resource, platform: platform}
+}
+
+// FilteredResource identifies a directory resource serving only a subset of its entries.
+type FilteredResource interface {
+	// Excluded returns true if the entry under the path relative to the resource root must not be served.
+	Excluded(relativePath string) bool
+	// DescendExcluded returns true if an excluded directory may contain entries which are not excluded.
+	DescendExcluded() bool
+}
+
+type filteredResolvedResource struct {
+	ResolvedResource
+	matcher    *IgnoreMatcher
+	pathPrefix string
+}
+
+func (frr *filteredResolvedResource) Excluded(relativePath string) bool {
+	return frr.matcher.Ignored(filepath.Join(frr.pathPrefix, relativePath))
+}
+
+func (frr *filteredResolvedResource) DescendExcluded() bool {
+	return frr.matcher.HasExclusions()
+}
+
+func (frr *filteredResolvedResource) Unwrap() ResolvedResource {
+	return frr.ResolvedResource
+}
+
+// FilterOf returns the filter of a resolved resource or nil if the resource is not filtered.
+func FilterOf(resource ResolvedResource) FilteredResource {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(FilteredResource)
+		return ok
+	}); found != nil {
+		return found.(FilteredResource)
+	}
+	return nil
+}
+
+// NewIgnoreFilteredResource wraps a directory resource so that entries ignored by the matcher are not served.
+// The path prefix is the path of the resource relative to the root the matcher patterns apply to.
+func NewIgnoreFilteredResource(matcher *IgnoreMatcher, pathPrefix string, resource ResolvedResource) ResolvedResource {
+	return &filteredResolvedResource{ResolvedResource: resource, matcher: matcher, pathPrefix: pathPrefix}
+}
+
+// RenamingResource identifies a directory resource serving some of its entries under different target names.
+type RenamingResource interface {
+	// RenamedTarget returns the target path relative to the resource target for an entry
+	// under the path relative to the resource root, and true if the entry is renamed.
+	RenamedTarget(relativePath string) (string, bool)
+}
+
+type renamingResolvedResource struct {
+	ResolvedResource
+	renames map[string]string
+}
+
+func (rrr *renamingResolvedResource) RenamedTarget(relativePath string) (string, bool) {
+	renamed, ok := rrr.renames[relativePath]
+	return renamed, ok
+}
+
+func (rrr *renamingResolvedResource) Unwrap() ResolvedResource {
+	return rrr.ResolvedResource
+}
+
+// NewRenamingResource wraps a directory resource so that entries are served under different target names.
+// The renames map paths relative to the resource root to target paths relative to the resource target.
+func NewRenamingResource(renames map[string]string, resource ResolvedResource) ResolvedResource {
+	return &renamingResolvedResource{ResolvedResource: resource, renames: renames}
+}
+
+// subRenamingResource serves the renames of a parent directory for a directory under the relative path,
+// the renamed targets are relative to the target of the sub directory.
+type subRenamingResource struct {
+	ResolvedResource
+	renames        RenamingResource
+	relativePath   string
+	relativeTarget string
+}
+
+func (srr *subRenamingResource) RenamedTarget(relativePath string) (string, bool) {
+	renamed, ok := srr.renames.RenamedTarget(path.Join(srr.relativePath, relativePath))
+	if !ok {
+		return "", false
+	}
+	relativeTarget, err := filepath.Rel(filepath.FromSlash(srr.relativeTarget), filepath.FromSlash(renamed))
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(relativeTarget), true
+}
+
+func (srr *subRenamingResource) Unwrap() ResolvedResource {
+	return srr.ResolvedResource
+}
+
+// RenamesOf returns the renames of a resolved resource or nil if the resource does not rename entries.
+func RenamesOf(resource ResolvedResource) RenamingResource {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(RenamingResource)
+		return ok
+	}); found != nil {
+		return found.(RenamingResource)
+	}
+	return nil
+}
+
+type retargetedResolvedResource struct {
+	ResolvedResource
+	targetPath string
+}
+
+func (rrr *retargetedResolvedResource) TargetPath() string {
+	return rrr.targetPath
+}
+
+func (rrr *retargetedResolvedResource) Unwrap() ResolvedResource {
+	return rrr.ResolvedResource
+}
+
+// NewRetargetedResource wraps a resource so that it is served under a different target path.
+func NewRetargetedResource(targetPath string, resource ResolvedResource) ResolvedResource {
+	return &retargetedResolvedResource{ResolvedResource: resource, targetPath: targetPath}
+}
+
+// SensitiveResource identifies a resource with secret contents, the contents must not be
+// written to disk, cached or included in digests which outlive the build.
+type SensitiveResource interface {
+	// Sensitive returns true if the contents are secret.
+	Sensitive() bool
+}
+
+// IsSensitive returns true if the resource or a resource it wraps has secret contents.
+func IsSensitive(resource ResolvedResource) bool {
+	return find(resource, func(r ResolvedResource) bool {
+		sensitive, ok := r.(SensitiveResource)
+		return ok && sensitive.Sensitive()
+	}) != nil
+}
+
+// Lease keeps the contents of resources readable, for example the token reading secrets from a store.
+type Lease interface {
+	// KeepAlive renews the lease until the context is done.
+	KeepAlive(ctx context.Context) error
+}
+
+// LeasedResource identifies a resource with contents readable only while a lease is renewed.
+// The server renews the leases of the resolved resources for the lifetime of the build,
+// resources sharing a lease return the same comparable value so that the lease is renewed once.
+type LeasedResource interface {
+	Lease() Lease
+}
+
+// LeaseOf returns the lease of the resource or a resource it wraps, nil if the resource has no lease.
+func LeaseOf(resource ResolvedResource) Lease {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(LeasedResource)
+		return ok
+	}); found != nil {
+		return found.(LeasedResource).Lease()
+	}
+	return nil
+}
+
 // -- Resource resolver:
 
 // Resolver resolves ADD and COPY dependencies.
@@ -127,52 +417,30 @@ func (dr *defaultResolver) resolveResources(originalSource, resourcePath, target
 		if err != nil {
 			return nil, err
 		}
-		defer httpResponse.Body.Close()
-		if httpResponse.StatusCode%100 != 2 {
-			return nil, fmt.Errorf("http resource failed: could not HEAD resource '%s', reason: %+v", newPath, err)
-		}
-
-		httpContentSupplier := func() (io.ReadCloser, error) {
-			// we have the temp file:
-			httpResponse, err := http.Get(newPath)
-			if err != nil {
-				return nil, err
-			}
-			return httpResponse.Body, nil
-			/*
-				bodyBytes, err := ioutil.ReadAll(httpResponse.Body)
-				if err != nil {
-					return nil, fmt.Errorf("http resource failed: could not GET resource '%s', reason: %+v", newPath, err)
-				}
-			*/
+		httpResponse.Body.Close()
+		if httpResponse.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("http resource failed: could not HEAD resource '%s', reason: %s", newPath, httpResponse.Status)
 		}
-
-		return append(resources, &defaultResolvedResource{contentsReader: httpContentSupplier,
-			resolved:      newPath,
-			targetMode:    fs.FileMode(0644),
-			sourcePath:    resourcePath,
-			targetPath:    targetPath,
-			targetWorkdir: targetWorkdir,
-			targetUser:    targetUser}), nil
+		return append(resources, newHTTPResource(resourcePath, SchemeRequest{
+			URI:            newPath,
+			Scheme:         schemeOf(newPath),
+			OriginalSource: originalSource,
+			TargetPath:     targetPath,
+			TargetWorkdir:  targetWorkdir,
+			TargetUser:     targetUser,
+		})), nil
 	}
 
-	// this here handles ADD / COPY (we don't distinguish) for a http source:
-	if strings.HasPrefix(resourcePath, "http://") || strings.HasPrefix(resourcePath, "https://") {
-		httpContentSupplier := func() (io.ReadCloser, error) {
-			// we have the temp file:
-			httpResponse, err := http.Get(resourcePath)
-			if err != nil {
-				return nil, err
-			}
-			return httpResponse.Body, nil
-		}
-		return append(resources, &defaultResolvedResource{contentsReader: httpContentSupplier,
-			resolved:      resourcePath,
-			targetMode:    fs.FileMode(0644),
-			sourcePath:    resourcePath,
-			targetPath:    targetPath,
-			targetWorkdir: targetWorkdir,
-			targetUser:    targetUser}), nil
+	// this here handles ADD / COPY (we don't distinguish) for a source with a scheme,
+	// http, https and memory are built in, other schemes are registered with RegisterScheme:
+	if scheme := schemeOf(resourcePath); scheme != "" {
+		return resolveScheme(scheme, SchemeRequest{
+			URI:            resourcePath,
+			OriginalSource: originalSource,
+			TargetPath:     targetPath,
+			TargetWorkdir:  targetWorkdir,
+			TargetUser:     targetUser,
+		})
 	}
 
 	newPath := filepath.Join(filepath.Dir(originalSource), resourcePath)
@@ -200,7 +468,7 @@ func (dr *defaultResolver) resolveResources(originalSource, resourcePath, target
 			resources = append(resources, &defaultResolvedResource{contentsReader: func() (io.ReadCloser, error) {
 				file, err := os.Open(newPath)
 				if err != nil {
-					return nil, fmt.Errorf("resource failed: could not read file resource '%s', reason:  %+v", newPath, err)
+					return nil, fmt.Errorf("resource failed: could not read file resource '%s', reason: %w", newPath, err)
 				}
 				return file, nil
 			},
@@ -247,3 +515,77 @@ func NewResolvedDirectoryResourceWithPath(mode fs.FileMode, resolvedPath, source
 		targetWorkdir: workdir,
 		targetUser:    user}
 }
+
+// NewSubResource returns a resource for an entry under a path relative to a resolved directory resource.
+// The relative path must stay within the directory, also after resolving symbolic links.
+// Entries excluded by a filtered resource are not resolvable.
+func NewSubResource(resource ResolvedResource, relativePath string) (ResolvedResource, error) {
+	if !resource.IsDir() {
+		return nil, fmt.Errorf("resource failed: '%s' is not a directory", resource.SourcePath())
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(relativePath))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("resource failed: '%s' not in the context of '%s'", relativePath, resource.SourcePath())
+	}
+	if filtered := FilterOf(resource); filtered != nil && filtered.Excluded(cleaned) {
+		return nil, fmt.Errorf("resource failed: '%s' excluded from '%s'", relativePath, resource.SourcePath())
+	}
+
+	root, err := filepath.EvalSymlinks(resource.ResolvedURIOrPath())
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: resolved '%s', reason: %v", resource.ResolvedURIOrPath(), err)
+	}
+	fullPath, err := filepath.EvalSymlinks(filepath.Join(root, cleaned))
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: resolved '%s', reason: %v", relativePath, err)
+	}
+	if fullPath != root && !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("resource failed: resolved '%s' not in the context of '%s'", fullPath, root)
+	}
+
+	statResult, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: resolved '%s', reason: %v", fullPath, err)
+	}
+
+	sourcePath := filepath.Join(resource.SourcePath(), cleaned)
+	relativeTarget := filepath.ToSlash(cleaned)
+	renames := RenamesOf(resource)
+	if renames != nil {
+		if renamed, ok := renames.RenamedTarget(relativeTarget); ok {
+			relativeTarget = renamed
+		}
+	}
+	targetPath := filepath.Join(resource.TargetPath(), relativeTarget)
+
+	var subResource ResolvedResource
+	if statResult.IsDir() {
+		subResource = NewResolvedDirectoryResourceWithPath(statResult.Mode().Perm(),
+			fullPath, sourcePath, targetPath,
+			resource.TargetWorkdir(),
+			resource.TargetUser())
+		if filtered, ok := FilterOf(resource).(*filteredResolvedResource); ok {
+			subResource = NewIgnoreFilteredResource(filtered.matcher, filepath.Join(filtered.pathPrefix, cleaned), subResource)
+		}
+		if renames != nil {
+			subResource = &subRenamingResource{ResolvedResource: subResource, renames: renames,
+				relativePath: filepath.ToSlash(cleaned), relativeTarget: relativeTarget}
+		}
+	} else {
+		subResource = NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			file, err := os.Open(fullPath)
+			if err != nil {
+				return nil, fmt.Errorf("resource failed: could not read file resource '%s', reason:  %+v", fullPath, err)
+			}
+			return file, nil
+		}, statResult.Mode().Perm(), sourcePath, targetPath,
+			resource.TargetWorkdir(),
+			resource.TargetUser(),
+			fullPath)
+	}
+
+	if platform := PlatformOf(resource); platform != "" {
+		subResource = NewPlatformVariant(platform, subResource)
+	}
+	return subResource, nil
+}
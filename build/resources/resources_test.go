@@ -0,0 +1,88 @@
+package resources
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformMatches(t *testing.T) {
+	for _, tc := range []struct {
+		resource, wanted string
+		matches          bool
+	}{
+		{"", "linux/arm64", true},
+		{"linux/arm64", "", true},
+		{"linux/arm64", "LINUX/ARM64", true},
+		{"arm64", "linux/arm64", true},
+		{"linux/arm64/v8", "linux/arm64", true},
+		{"linux/arm64", "linux/arm64/v8", true},
+		{"linux/arm/v7", "linux/arm/v7", true},
+		{"linux/arm/v6", "linux/arm/v7", false},
+		{"linux/arm64", "linux/amd64", false},
+		{"windows/amd64", "linux/amd64", false},
+		{"amd64", "arm64", false},
+		{"arm/v7", "linux/arm/v7", true},
+		{"arm/v6", "linux/arm/v7", false},
+		{"linux/arm/v7", "arm/v7", true},
+		{"arm64/v8", "linux/arm64", true},
+		{"x86_64", "linux/amd64", true},
+		{"linux/x86_64", "amd64", true},
+		{"aarch64", "linux/arm64", true},
+		{"linux/aarch64", "linux/arm64/v8", true},
+		{"aarch64/v8", "linux/arm64/v8", true},
+		{"x86_64", "linux/arm64", false},
+	} {
+		assert.Equal(t, tc.matches, PlatformMatches(tc.resource, tc.wanted), tc.resource+" for "+tc.wanted)
+	}
+}
+
+func TestSubResourceRenames(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"sub/a", "sub/inner/b", "renamed/c"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), fs.ModePerm))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(root, name), []byte(name), 0644))
+	}
+	resource := NewRenamingResource(map[string]string{
+		"sub/a":       "sub/a~1",
+		"sub/inner/b": "sub/inner/b~1",
+		"renamed":     "renamed~1",
+		"renamed/c":   "renamed~1/c",
+	}, NewResolvedDirectoryResourceWithPath(fs.ModePerm, root, "context", "/target", commands.DefaultWorkdir(), commands.DefaultUser()))
+
+	sub, err := NewSubResource(resource, "sub")
+	assert.Nil(t, err)
+	assert.Equal(t, "/target/sub", sub.TargetPath())
+	if assert.NotNil(t, RenamesOf(sub)) {
+		renamed, ok := RenamesOf(sub).RenamedTarget("a")
+		assert.True(t, ok)
+		assert.Equal(t, "a~1", renamed)
+		_, ok = RenamesOf(sub).RenamedTarget("inner")
+		assert.False(t, ok)
+	}
+
+	// the renames are re-rooted again for a directory under the sub directory:
+	inner, err := NewSubResource(sub, "inner")
+	assert.Nil(t, err)
+	assert.Equal(t, "/target/sub/inner", inner.TargetPath())
+	renamed, ok := RenamesOf(inner).RenamedTarget("b")
+	assert.True(t, ok)
+	assert.Equal(t, "b~1", renamed)
+
+	// the entries of a renamed directory are relative to the renamed target:
+	renamedDir, err := NewSubResource(resource, "renamed")
+	assert.Nil(t, err)
+	assert.Equal(t, "/target/renamed~1", renamedDir.TargetPath())
+	renamed, ok = RenamesOf(renamedDir).RenamedTarget("c")
+	assert.True(t, ok)
+	assert.Equal(t, "c", renamed)
+
+	file, err := NewSubResource(resource, "sub/a")
+	assert.Nil(t, err)
+	assert.Equal(t, "/target/sub/a~1", file.TargetPath())
+}
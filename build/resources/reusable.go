@@ -0,0 +1,64 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// ErrContentsConsumed is returned when the contents of a resource which can be read only once are read again.
+var ErrContentsConsumed = errors.New("resource contents already consumed")
+
+// ReusableResource identifies a resource declaring whether its contents can be read more than once.
+// Resources not implementing it are reusable. The server keeps the contents of the resources
+// which are not reusable in the scratch directory of the build on the first read.
+type ReusableResource interface {
+	// Reusable returns true if Contents can be called more than once.
+	Reusable() bool
+}
+
+// IsReusable returns true if the contents of the resource can be read more than once,
+// the outermost resource implementing ReusableResource decides.
+func IsReusable(resource ResolvedResource) bool {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(ReusableResource)
+		return ok
+	}); found != nil {
+		return found.(ReusableResource).Reusable()
+	}
+	return true
+}
+
+type oneShotResolvedResource struct {
+	ResolvedResource
+}
+
+func (osrr *oneShotResolvedResource) Reusable() bool {
+	return false
+}
+
+func (osrr *oneShotResolvedResource) Unwrap() ResolvedResource {
+	return osrr.ResolvedResource
+}
+
+// NewOneShotResource creates a file resource serving a reader which can be read only once,
+// for example the body of a response or the output of a process. Reading the contents again
+// fails with ErrContentsConsumed. The resolved path is optional.
+func NewOneShotResource(reader io.ReadCloser, mode fs.FileMode, sourcePath, targetPath string, workdir commands.Workdir, user commands.User, path string) ResolvedResource {
+	m := &sync.Mutex{}
+	consumed := false
+	return &oneShotResolvedResource{
+		ResolvedResource: NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			m.Lock()
+			defer m.Unlock()
+			if consumed {
+				return nil, ErrContentsConsumed
+			}
+			consumed = true
+			return reader, nil
+		}, mode, sourcePath, targetPath, workdir, user, path),
+	}
+}
@@ -0,0 +1,78 @@
+package resources
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// TemplateData is the data a template resource is rendered with when it is streamed.
+type TemplateData struct {
+	// BuildID is the ID of the build.
+	BuildID string
+	// Platform is the platform the build targets.
+	Platform string
+	// Args contains the ARG values in effect when the build starts.
+	Args map[string]string
+	// Env contains the ENV values in effect when the build starts.
+	Env map[string]string
+	// Facts contains the facts of the guest requesting the resource, empty when the client sent none.
+	Facts commands.Facts
+}
+
+// TemplateResource identifies a file resource rendered from a Go text/template when it is streamed.
+type TemplateResource interface {
+	// Render returns the contents rendered with the data.
+	Render(data TemplateData) (io.ReadCloser, error)
+}
+
+type templateResolvedResource struct {
+	ResolvedResource
+	template *template.Template
+}
+
+func (trr *templateResolvedResource) Render(data TemplateData) (io.ReadCloser, error) {
+	buffer := &bytes.Buffer{}
+	if err := trr.template.Execute(buffer, data); err != nil {
+		return nil, fmt.Errorf("resource failed: could not render template '%s', reason: %v", trr.SourcePath(), err)
+	}
+	return ioutil.NopCloser(buffer), nil
+}
+
+func (trr *templateResolvedResource) Unwrap() ResolvedResource {
+	return trr.ResolvedResource
+}
+
+// NewTemplateResource creates a file resource rendered from the text of a Go text/template with
+// the TemplateData of the build and the guest, for example {{ .Env.HOME }} or {{ .Facts.arch }}.
+// A missing key fails the rendering. Contents returns the text of the template as given,
+// the server streams the rendered contents.
+func NewTemplateResource(text string, mode fs.FileMode, sourcePath, targetPath string, workdir commands.Workdir, user commands.User) (ResolvedResource, error) {
+	parsed, err := template.New(sourcePath).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: could not parse template '%s', reason: %v", sourcePath, err)
+	}
+	return &templateResolvedResource{
+		ResolvedResource: NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(text)), nil
+		}, mode, sourcePath, targetPath, workdir, user, ""),
+		template: parsed,
+	}, nil
+}
+
+// TemplateOf returns the template of a resolved resource or nil if the resource is not rendered from a template.
+func TemplateOf(resource ResolvedResource) TemplateResource {
+	if found := find(resource, func(r ResolvedResource) bool {
+		_, ok := r.(TemplateResource)
+		return ok
+	}); found != nil {
+		return found.(TemplateResource)
+	}
+	return nil
+}
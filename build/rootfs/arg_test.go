@@ -0,0 +1,87 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseArg(t *testing.T, input string) commands.Arg {
+	arg, err := commands.NewRawArg(input)
+	if err != nil {
+		t.Fatal("expected the ARG to parse", err)
+	}
+	return arg
+}
+
+func TestServerSubstitutesBuildArgs(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	arg := mustParseArg(t, "VERSION=1.0")
+	serverCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			arg,
+			commands.RunWithDefaults("install --version ${VERSION}"),
+		},
+		ResourcesResolved: make(Resources),
+		Args:              map[string]string{"VERSION": "2.0"},
+	}
+	fingerprint, err := serverCtx.Fingerprint()
+	assert.Nil(t, err)
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, serverCtx)
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	data, err := commands.Serialize(arg)
+	assert.Nil(t, err)
+	deserialized, err := commands.Deserialize(data)
+	assert.Nil(t, err)
+	assert.Equal(t, arg, deserialized, "expected the ARG to survive the serialization")
+
+	assert.Nil(t, testClient.Commands())
+	group := testClient.NextCommandGroup()
+	if assert.Len(t, group, 1, "expected the client not to process the ARG") {
+		assert.Equal(t, 1, group[0].Index)
+		assert.Equal(t, "install --version 2.0", group[0].Command.(commands.Run).Command)
+	}
+	assert.Equal(t, commands.IDs(commands.SubstituteArgs(serverCtx.ExecutableCommands, serverCtx.Args)), serverCtx.CommandIDs,
+		"expected the IDs of the substituted commands")
+	assert.Equal(t, "install --version ${VERSION}", serverCtx.ExecutableCommands[1].(commands.Run).Command,
+		"expected the commands of the work context not to change")
+	startedFingerprint, err := serverCtx.Fingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, startedFingerprint)
+
+	environment, err := testClient.Environment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"VERSION": "2.0"}, environment, "expected the overridden ARG value")
+}
+
+func TestEnvironmentResolvesArgs(t *testing.T) {
+	serverCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			mustParseArg(t, "VERSION=1.0"),
+			mustParseArg(t, "TARGET=/opt/app"),
+			mustParseArg(t, "EMPTY"),
+		},
+		Args: map[string]string{"TARGET": "/srv/app", "UNDECLARED": "value"},
+		Env:  map[string]string{"EMPTY": "env"},
+	}
+	assert.Equal(t, map[string]string{
+		"VERSION":    "1.0",
+		"TARGET":     "/srv/app",
+		"UNDECLARED": "value",
+		"EMPTY":      "env",
+	}, serverCtx.Environment(), "expected the defaults, the overrides and the ENV values")
+}
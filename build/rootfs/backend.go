@@ -0,0 +1,207 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Backend is the transport independent build lifecycle served to the guests.
+// Transports translate their protocol to the backend calls, the backend takes care of the commands,
+// the resources, the logs, the events, the summary and the hooks of the build.
+type Backend interface {
+	EventProvider
+	// Commands returns the serialized executable commands of the build.
+	Commands(ctx context.Context) ([]string, error)
+	// ServeResource streams the resources served for a request by calling the send function with every chunk.
+	ServeResource(ctx context.Context, req *proto.ResourceRequest, send func(*proto.ResourceChunk) error) error
+	// ReceiveLogs accepts the output lines of the build written to a stream.
+	ReceiveLogs(ctx context.Context, stream LogStream, lines []string) error
+	// Complete finishes the build, successfully when the error is nil, otherwise aborted with the error.
+	// Returns true if the aborted guest is asked to serve a debug session.
+	Complete(ctx context.Context, buildErr error) (bool, error)
+	// Cancel tells the guest watching the build to stop the build immediately.
+	Cancel(reason error)
+	// Emit emits an event to the event consumer.
+	Emit(message interface{})
+	// Stats returns the current resource usage of the backend.
+	Stats() ServerStats
+	// Stop stops the backend, every following call fails with ErrServerStopped.
+	Stop()
+	// SubscribeLogs returns a channel receiving the stdout and stderr lines of the build
+	// and a function ending the subscription.
+	SubscribeLogs() (<-chan LogLine, func())
+	// Summary returns the summary of the build.
+	Summary() BuildSummary
+	// Wait blocks until the goroutines of the backend have exited, call after Stop.
+	Wait()
+}
+
+// NewBackend creates the backend serving a work context.
+func NewBackend(logger hclog.Logger, serverCtx *WorkContext, cfg *GRPCServiceConfig) Backend {
+	return &serverBackend{impl: newServerImpl(logger, serverCtx, cfg.WithDefaultsApplied()).(*serverImpl)}
+}
+
+// serverBackend exposes the lifecycle implemented by the gRPC service without gRPC.
+type serverBackend struct {
+	impl *serverImpl
+}
+
+func (b *serverBackend) Commands(ctx context.Context) ([]string, error) {
+	response, err := b.impl.Commands(ctx, &proto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return response.Command, nil
+}
+
+func (b *serverBackend) ServeResource(ctx context.Context, req *proto.ResourceRequest, send func(*proto.ResourceChunk) error) error {
+	return b.impl.Resource(req, &funcResourceServer{ctx: ctx, send: send})
+}
+
+func (b *serverBackend) ReceiveLogs(ctx context.Context, stream LogStream, lines []string) error {
+	switch stream {
+	case LogStreamStdout:
+		_, err := b.impl.StdOut(ctx, &proto.LogMessage{Line: lines})
+		return err
+	case LogStreamStderr:
+		_, err := b.impl.StdErr(ctx, &proto.LogMessage{Line: lines})
+		return err
+	}
+	return fmt.Errorf("%w: unknown log stream %d", ErrInvalidArgument, stream)
+}
+
+func (b *serverBackend) Complete(ctx context.Context, buildErr error) (bool, error) {
+	if buildErr == nil {
+		_, err := b.impl.Success(ctx, &proto.Empty{})
+		return false, err
+	}
+	response, err := b.impl.Abort(ctx, &proto.AbortRequest{Error: buildErr.Error()})
+	if err != nil {
+		return false, err
+	}
+	return response.Debug, nil
+}
+
+func (b *serverBackend) Cancel(reason error)                     { b.impl.Cancel(reason) }
+func (b *serverBackend) Emit(message interface{})                { b.impl.Emit(message) }
+func (b *serverBackend) OnMessage() <-chan interface{}           { return b.impl.OnMessage() }
+func (b *serverBackend) Subscribe() (<-chan interface{}, func()) { return b.impl.Subscribe() }
+func (b *serverBackend) Stats() ServerStats                      { return b.impl.Stats() }
+func (b *serverBackend) Stop()                                   { b.impl.Stop() }
+func (b *serverBackend) SubscribeLogs() (<-chan LogLine, func()) { return b.impl.SubscribeLogs() }
+func (b *serverBackend) Summary() BuildSummary                   { return b.impl.Summary() }
+func (b *serverBackend) Wait()                                   { b.impl.Wait() }
+
+// funcResourceServer adapts a send function to the resource stream of the gRPC service.
+type funcResourceServer struct {
+	grpc.ServerStream
+	ctx  context.Context
+	send func(*proto.ResourceChunk) error
+}
+
+func (s *funcResourceServer) Context() context.Context              { return s.ctx }
+func (s *funcResourceServer) Send(chunk *proto.ResourceChunk) error { return s.send(chunk) }
+func (s *funcResourceServer) SendHeader(metadata.MD) error          { return nil }
+func (s *funcResourceServer) SetHeader(metadata.MD) error           { return nil }
+func (s *funcResourceServer) SetTrailer(metadata.MD)                {}
+
+// TransportListenFunc creates the listener a server accepts the connections of the guests on.
+// The TLS configuration is the configuration of the server.
+type TransportListenFunc func(address string, tlsConfig *tls.Config) (net.Listener, error)
+
+// TransportDialFunc connects a client to the server at the address.
+// The TLS configuration is the configuration of the client.
+type TransportDialFunc func(ctx context.Context, address string, tlsConfig *tls.Config) (net.Conn, error)
+
+func listenTCP(address string, _ *tls.Config) (net.Listener, error) {
+	return net.Listen("tcp", address)
+}
+
+// TransportFactory creates a server serving the build over a transport.
+type TransportFactory func(cfg *GRPCServiceConfig, logger hclog.Logger) ServerProvider
+
+// DefaultTransport is the scheme of the gRPC transport, used when the bind address has no scheme.
+const DefaultTransport = "grpc"
+
+var (
+	transportsLock   = &sync.Mutex{}
+	transports       = map[string]TransportFactory{DefaultTransport: New}
+	transportDialers = map[string]TransportDialFunc{}
+)
+
+// RegisterTransport registers a transport factory under a scheme, replacing a transport registered
+// under the same scheme. Servers are created for the transport with NewWithTransport when
+// the bind address starts with the scheme followed by ://.
+func RegisterTransport(scheme string, factory TransportFactory) {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	transports[scheme] = factory
+}
+
+// RegisterTransportDialer registers the dial function clients connect with when the host and port
+// of the client configuration start with the scheme followed by ://.
+func RegisterTransportDialer(scheme string, dial TransportDialFunc) {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	transportDialers[scheme] = dial
+}
+
+// transportDialer returns the dial function for the scheme of the address and the address without the scheme.
+// The dial function is nil for addresses without a scheme and for the gRPC transport.
+func transportDialer(address string) (TransportDialFunc, string, error) {
+	index := strings.Index(address, "://")
+	if index < 0 {
+		return nil, address, nil
+	}
+	scheme := address[0:index]
+	if scheme == DefaultTransport {
+		return nil, address[index+3:], nil
+	}
+	transportsLock.Lock()
+	dial, ok := transportDialers[scheme]
+	transportsLock.Unlock()
+	if !ok {
+		return nil, address, fmt.Errorf("%w: no dialer for transport '%s'", ErrInvalidArgument, scheme)
+	}
+	return dial, address[index+3:], nil
+}
+
+// Transports returns the sorted schemes of the registered transports.
+func Transports() []string {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	schemes := []string{}
+	for scheme := range transports {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewWithTransport creates a server for the transport selected by the scheme of the bind address,
+// for example quic://127.0.0.1:0. The scheme is removed from the bind address handed to the transport.
+// A bind address without a scheme selects the gRPC transport.
+func NewWithTransport(cfg *GRPCServiceConfig, logger hclog.Logger) (ServerProvider, error) {
+	scheme := DefaultTransport
+	if index := strings.Index(cfg.BindHostPort, "://"); index > -1 {
+		scheme = cfg.BindHostPort[0:index]
+		cfg.BindHostPort = cfg.BindHostPort[index+3:]
+	}
+	transportsLock.Lock()
+	factory, ok := transports[scheme]
+	transportsLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown transport '%s'", ErrInvalidArgument, scheme)
+	}
+	return factory(cfg, logger), nil
+}
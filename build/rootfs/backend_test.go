@@ -0,0 +1,110 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackendServesWithoutTransport(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	backend := NewBackend(logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("contents"))), nil
+				}, fs.FileMode(0644), "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+			},
+		},
+	}, &GRPCServiceConfig{})
+	defer backend.Stop()
+
+	chanSucceeded := make(chan struct{})
+	go func() {
+		for message := range backend.OnMessage() {
+			if _, ok := message.(*ClientMsgSuccess); ok {
+				close(chanSucceeded)
+				return
+			}
+		}
+	}()
+
+	serialized, err := backend.Commands(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(serialized))
+
+	contents := []byte{}
+	assert.Nil(t, backend.ServeResource(context.Background(), &proto.ResourceRequest{Path: "file"}, func(chunk *proto.ResourceChunk) error {
+		if payload := chunk.GetChunk(); payload != nil {
+			contents = append(contents, payload.Chunk...)
+		}
+		return nil
+	}))
+	assert.Equal(t, "contents", string(contents))
+	assert.True(t, errors.Is(backend.ServeResource(context.Background(), &proto.ResourceRequest{Path: "missing"},
+		func(*proto.ResourceChunk) error { return nil }), ErrResourceNotFound))
+
+	assert.Nil(t, backend.ReceiveLogs(context.Background(), LogStreamStdout, []string{"line"}))
+	debug, err := backend.Complete(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.False(t, debug)
+	<-chanSucceeded
+
+	summary := backend.Summary()
+	assert.True(t, summary.Success)
+	assert.Equal(t, 1, summary.StdoutLines)
+	assert.Equal(t, int64(len("contents")), summary.BytesServed)
+}
+
+func TestTransportRegistry(t *testing.T) {
+	created := []string{}
+	RegisterTransport("test", func(cfg *GRPCServiceConfig, logger hclog.Logger) ServerProvider {
+		created = append(created, cfg.BindHostPort)
+		return New(cfg, logger)
+	})
+	assert.Contains(t, Transports(), "test")
+	assert.Contains(t, Transports(), DefaultTransport)
+
+	_, err := NewWithTransport(&GRPCServiceConfig{BindHostPort: "test://127.0.0.1:0"}, hclog.Default())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"127.0.0.1:0"}, created)
+
+	srv, err := NewWithTransport(&GRPCServiceConfig{BindHostPort: "127.0.0.1:0"}, hclog.Default())
+	assert.Nil(t, err)
+	_, ok := srv.(*grpcSvc)
+	assert.True(t, ok)
+
+	_, err = NewWithTransport(&GRPCServiceConfig{BindHostPort: "unknown://127.0.0.1:0"}, hclog.Default())
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+
+	dial, hostPort, err := transportDialer("127.0.0.1:5000")
+	assert.Nil(t, err)
+	assert.Nil(t, dial)
+	assert.Equal(t, "127.0.0.1:5000", hostPort)
+	_, _, err = transportDialer("test://127.0.0.1:5000")
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	RegisterTransportDialer("test", func(ctx context.Context, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	})
+	dial, hostPort, err = transportDialer("test://127.0.0.1:5000")
+	assert.Nil(t, err)
+	assert.NotNil(t, dial)
+	assert.Equal(t, "127.0.0.1:5000", hostPort)
+}
@@ -0,0 +1,132 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+func TestDirectoryResourceBatchedWalk(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for i := 0; i < 50; i++ {
+		MustPutTestResource(t, filepath.Join(tempDir, fmt.Sprintf("small/%02d", i)), []byte(fmt.Sprintf("small %d", i)))
+	}
+	MustPutTestResource(t, filepath.Join(tempDir, "large"), bytes.Repeat([]byte("large"), 100))
+
+	resource := resources.NewResolvedDirectoryResourceWithPath(0755, tempDir, "src", "/dst",
+		commands.DefaultWorkdir(), commands.DefaultUser())
+	chanChunks := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+		BatchFileSize:  32,
+		SafeBufferSize: 1024,
+		Sorted:         true,
+	}, resource).WalkResource()
+
+	sequence := &frameSequence{}
+	batches, batched, chunked := 0, map[string][]byte{}, map[string][]byte{}
+	headers := map[string]string{}
+	for {
+		chunk := <-chanChunks
+		if chunk == nil {
+			break
+		}
+		assert.Nil(t, sequence.next(chunk))
+		switch tchunk := chunk.GetPayload().(type) {
+		case *proto.ResourceChunk_Header:
+			headers[tchunk.Header.Id] = tchunk.Header.TargetPath
+		case *proto.ResourceChunk_Chunk:
+			chunked[headers[tchunk.Chunk.Id]] = append(chunked[headers[tchunk.Chunk.Id]], tchunk.Chunk.Chunk...)
+		case *proto.ResourceChunk_Batch:
+			batches = batches + 1
+			size := 0
+			for _, entry := range tchunk.Batch.Entries {
+				size = size + gproto.Size(entry)
+				assert.Equal(t, int64(len(entry.Contents)), entry.Header.Size)
+				batched[entry.Header.TargetPath] = entry.Contents
+			}
+			// a batch fits in the buffer:
+			assert.LessOrEqual(t, size, 1024)
+		case *proto.ResourceChunk_Error:
+			t.Fatal("expected no error", tchunk.Error.Message)
+		}
+	}
+
+	assert.Greater(t, batches, 1, "expected the small files to be split in batches fitting the buffer")
+	assert.Len(t, batched, 50)
+	assert.Equal(t, []byte("small 7"), batched["/dst/small/07"])
+	assert.Equal(t, map[string][]byte{"/dst/large": bytes.Repeat([]byte("large"), 100)}, chunked)
+}
+
+func TestClientWriteResourcesBatched(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+	assert.Nil(t, os.Chmod(filepath.Join(sourceDir, "a/file-00"), 0700))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{MaxMsgSize: 4096}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer testServer.Stop()
+	served := make(chan *ControlMsgResourceServed, 1)
+	go func() {
+		for {
+			select {
+			case event := <-testServer.OnMessage():
+				if tevent, ok := event.(*ControlMsgResourceServed); ok {
+					served <- tevent
+				}
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	capabilities, err := testClient.Capabilities()
+	assert.Nil(t, err)
+	assert.True(t, capabilities.HasFeature(FeatureBatch))
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, &WriteOptions{
+		BatchFileSize: 256,
+		Multiplex:     4,
+	}))
+	for name, contents := range expected {
+		written, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir", name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, contents, written, name)
+		}
+	}
+	info, err := os.Stat(filepath.Join(rootDir, "opt/dir/a/file-00"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, fs.FileMode(0700), info.Mode().Perm())
+	}
+
+	// every file and directory is counted, batched or not:
+	event := <-served
+	assert.Equal(t, len(expected)+5, event.Resources)
+}
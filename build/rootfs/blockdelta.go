@@ -0,0 +1,463 @@
+package rootfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultDeltaBlockSize is the default block size of block delta transfers.
+	DefaultDeltaBlockSize = 64 * 1024
+	// MaxDeltaBlockSize is the largest block size of block delta transfers accepted by the server.
+	MaxDeltaBlockSize = 4 * 1024 * 1024
+)
+
+// BlockDeltaOptions configures a block delta transfer.
+type BlockDeltaOptions struct {
+	// BlockSize is the size of the blocks of the existing file matched against the new contents.
+	// Default is DefaultDeltaBlockSize.
+	BlockSize int
+}
+
+// WithDefaultsApplied applies default configuration values to unconfigured properties.
+func (o *BlockDeltaOptions) WithDefaultsApplied() *BlockDeltaOptions {
+	if o.BlockSize == 0 {
+		o.BlockSize = DefaultDeltaBlockSize
+	}
+	return o
+}
+
+// DeltaTransferStats describes a finished block delta transfer.
+type DeltaTransferStats struct {
+	// Size is the size of the written file.
+	Size int64
+	// LiteralBytes is the number of content bytes transferred from the server.
+	LiteralBytes int64
+	// CopiedBytes is the number of bytes reused from the existing file.
+	CopiedBytes int64
+}
+
+// rollingChecksum is the rsync weak checksum of a window of bytes, updated in constant time when the window slides.
+type rollingChecksum struct {
+	a, b uint32
+	size uint32
+}
+
+func newRollingChecksum(window []byte) rollingChecksum {
+	r := rollingChecksum{size: uint32(len(window))}
+	for i, c := range window {
+		r.a = r.a + uint32(c)
+		r.b = r.b + uint32(len(window)-i)*uint32(c)
+	}
+	return r
+}
+
+// roll slides the window by one byte.
+func (r *rollingChecksum) roll(out, in byte) {
+	r.a = r.a - uint32(out) + uint32(in)
+	r.b = r.b - r.size*uint32(out) + r.a
+}
+
+func (r rollingChecksum) sum() uint32 {
+	return (r.a & 0xffff) | (r.b&0xffff)<<16
+}
+
+// blockSignatures computes the signatures of the full blocks of the contents, a trailing partial block is not signed.
+func blockSignatures(reader io.Reader, blockSize int) ([]*proto.BlockDeltaRequest_Signature, error) {
+	signatures := []*proto.BlockDeltaRequest_Signature{}
+	buffer := make([]byte, blockSize)
+	for {
+		_, err := io.ReadFull(reader, buffer)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return signatures, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		strong := sha256.Sum256(buffer)
+		signatures = append(signatures, &proto.BlockDeltaRequest_Signature{
+			Weak:   newRollingChecksum(buffer).sum(),
+			Strong: strong[:],
+		})
+	}
+}
+
+// blockDeltaResult describes the delta computed for new contents.
+type blockDeltaResult struct {
+	digest       []byte
+	literalBytes int64
+	size         int64
+}
+
+// computeBlockDelta matches the new contents against the signed blocks at every byte offset and emits
+// copy instructions for matched blocks and literal data of at most maxLiteral bytes for everything else.
+func computeBlockDelta(reader io.Reader, blockSize int, blocks []*proto.BlockDeltaRequest_Signature,
+	maxLiteral int, emit func(*proto.BlockDeltaFrame) error) (*blockDeltaResult, error) {
+
+	weakIndex := map[uint32][]int{}
+	for idx, block := range blocks {
+		weakIndex[block.Weak] = append(weakIndex[block.Weak], idx)
+	}
+
+	result := &blockDeltaResult{}
+	digest := sha256.New()
+	bufferedReader := bufio.NewReader(io.TeeReader(reader, digest))
+
+	// data holds the pending literal bytes followed by the current window:
+	data := make([]byte, 0, blockSize+maxLiteral)
+	emitLiteral := func(n int) error {
+		payload := make([]byte, n)
+		copy(payload, data[0:n])
+		data = append(data[:0], data[n:]...)
+		checksum := sha256.Sum256(payload)
+		result.literalBytes = result.literalBytes + int64(n)
+		result.size = result.size + int64(n)
+		return emit(&proto.BlockDeltaFrame{
+			Payload: &proto.BlockDeltaFrame_Literal_{
+				Literal: &proto.BlockDeltaFrame_Literal{Data: payload, Checksum: checksum[:]},
+			},
+		})
+	}
+	fillWindow := func() (bool, error) {
+		for len(data) < blockSize {
+			c, err := bufferedReader.ReadByte()
+			if err == io.EOF {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			data = append(data, c)
+		}
+		return true, nil
+	}
+
+	full, err := fillWindow()
+	if err != nil {
+		return nil, err
+	}
+	var checksum rollingChecksum
+	if full {
+		checksum = newRollingChecksum(data)
+	}
+
+	for full && len(blocks) > 0 {
+		window := data[len(data)-blockSize:]
+		if candidates, ok := weakIndex[checksum.sum()]; ok {
+			strong := sha256.Sum256(window)
+			matched := -1
+			for _, idx := range candidates {
+				if bytes.Equal(blocks[idx].Strong, strong[:]) {
+					matched = idx
+					break
+				}
+			}
+			if matched > -1 {
+				if pending := len(data) - blockSize; pending > 0 {
+					if err := emitLiteral(pending); err != nil {
+						return nil, err
+					}
+				}
+				if err := emit(&proto.BlockDeltaFrame{
+					Payload: &proto.BlockDeltaFrame_Copy_{
+						Copy: &proto.BlockDeltaFrame_Copy{Index: int64(matched)},
+					},
+				}); err != nil {
+					return nil, err
+				}
+				result.size = result.size + int64(blockSize)
+				data = data[:0]
+				if full, err = fillWindow(); err != nil {
+					return nil, err
+				}
+				if full {
+					checksum = newRollingChecksum(data)
+				}
+				continue
+			}
+		}
+		c, err := bufferedReader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		out := data[len(data)-blockSize]
+		data = append(data, c)
+		checksum.roll(out, c)
+		if pending := len(data) - blockSize; pending >= maxLiteral {
+			if err := emitLiteral(pending); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// everything not matched is sent as literal data:
+	for {
+		for len(data) < maxLiteral {
+			c, err := bufferedReader.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, c)
+		}
+		if len(data) == 0 {
+			break
+		}
+		if err := emitLiteral(minInt(len(data), maxLiteral)); err != nil {
+			return nil, err
+		}
+	}
+
+	result.digest = digest.Sum(nil)
+	return result, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ResourceBlockDelta streams the contents of the first file resource for the path as a delta against
+// the blocks of a file the client already has. Blocks found in the new contents at any offset are sent
+// as references to the existing blocks, everything else is sent as literal data.
+func (impl *serverImpl) ResourceBlockDelta(req *proto.BlockDeltaRequest, stream proto.RootfsServer_ResourceBlockDeltaServer) error {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	if req.BlockSize <= 0 || req.BlockSize > MaxDeltaBlockSize {
+		return withResourcePath(fmt.Errorf("%w: block size %d not in range 1 to %d", ErrInvalidArgument, req.BlockSize, MaxDeltaBlockSize), req.Path, req.Stage)
+	}
+
+	var resource resources.ResolvedResource
+	if ress, ok := impl.lookupResources(req.Path); ok {
+		for _, candidate := range ress {
+			if resources.PlatformMatches(resources.PlatformOf(candidate), impl.serverCtx.Platform) {
+				resource = candidate
+				break
+			}
+		}
+	}
+	if resource == nil {
+		return withResourcePath(fmt.Errorf("%w: '%s/%s'", ErrResourceNotFound, req.Stage, req.Path), req.Path, req.Stage)
+	}
+	if err := refuseSensitive(resource, req.Path, req.Stage); err != nil {
+		return err
+	}
+	if resource.IsDir() {
+		return withResourcePath(fmt.Errorf("%w: block delta not supported for directory resources", ErrInvalidArgument), req.Path, req.Stage)
+	}
+
+	// by using this safe value, we leave space for other fields of the payload
+	bufferSize := impl.serviceConfig.SafeClientMaxRecvMsgSize()
+	acquired, err := impl.chunkBudget.acquire(stream.Context(), int64(bufferSize))
+	if err != nil {
+		return err
+	}
+	defer impl.chunkBudget.release(acquired)
+	if int(acquired) < bufferSize {
+		bufferSize = int(acquired)
+	}
+
+	reader, err := impl.resourceContents(stream.Context(), resource)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	header, err := fileResourceHeader(resource, uuid.Must(uuid.NewV4()).String())
+	if err != nil {
+		return withResourcePath(err, req.Path, req.Stage)
+	}
+	impl.serverCtx.applyHeaderDefaults(header)
+	if err := stream.Send(&proto.BlockDeltaFrame{Payload: &proto.BlockDeltaFrame_Header{Header: header}}); err != nil {
+		impl.resourceLogger.Error("Failed sending header", "reason", err)
+		return err
+	}
+
+	result, err := computeBlockDelta(reader, int(req.BlockSize), req.Blocks, bufferSize, stream.Send)
+	if err != nil {
+		return withResourcePath(err, req.Path, req.Stage)
+	}
+	impl.countServed(1, int(result.literalBytes))
+
+	if err := stream.Send(&proto.BlockDeltaFrame{
+		Payload: &proto.BlockDeltaFrame_End_{
+			End: &proto.BlockDeltaFrame_End{Digest: result.digest, Size: result.size},
+		},
+	}); err != nil {
+		impl.resourceLogger.Error("Failed sending end", "reason", err)
+		return err
+	}
+
+	impl.emit(&ControlMsgResourceServed{
+		Path:      req.Path,
+		Stage:     req.Stage,
+		Resources: 1,
+		Bytes:     result.literalBytes,
+	})
+	return nil
+}
+
+// WriteResourceBlockDelta writes the contents of the first file resource for the path to a file, transferring
+// only the parts of the contents not found in the blocks of the existing file. Without an existing file,
+// all contents are transferred. The new contents are written to a temporary file, verified against the digest
+// sent by the server and renamed to the file. Returns the transfer statistics.
+func (c *defaultClient) WriteResourceBlockDelta(ctx context.Context, path, filePath string, opts *BlockDeltaOptions) (DeltaTransferStats, error) {
+	if opts == nil {
+		opts = &BlockDeltaOptions{}
+	}
+	opts = opts.WithDefaultsApplied()
+	stats := DeltaTransferStats{}
+
+	signatures := []*proto.BlockDeltaRequest_Signature{}
+	existing, err := os.Open(filePath)
+	if err == nil {
+		defer existing.Close()
+		if signatures, err = blockSignatures(existing, opts.BlockSize); err != nil {
+			return stats, errors.Wrapf(err, "failed computing block signatures of '%s'", filePath)
+		}
+	} else if !os.IsNotExist(err) {
+		return stats, errors.Wrapf(err, "failed opening '%s'", filePath)
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	deltaClient, err := c.underlying.ResourceBlockDelta(streamCtx, &proto.BlockDeltaRequest{
+		Path:      path,
+		BlockSize: int32(opts.BlockSize),
+		Blocks:    signatures,
+	})
+	if err != nil {
+		return stats, fromStatusError(err)
+	}
+	response, err := deltaClient.Recv()
+	if err != nil {
+		return stats, errors.Wrap(fromStatusError(err), "failed reading header")
+	}
+	protoHeader := response.GetHeader()
+	if protoHeader == nil {
+		return stats, errors.Wrap(ErrProtocolMismatch, "expected resource header")
+	}
+	header, err := decodeResourceHeader(protoHeader)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return stats, errors.Wrapf(err, "failed creating parent directory of '%s'", filePath)
+	}
+	file, err := ioutil.TempFile(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-")
+	if err != nil {
+		return stats, errors.Wrapf(err, "failed creating file '%s'", filePath)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	synced := c.syncer.track(file)
+	digest := sha256.New()
+	block := make([]byte, opts.BlockSize)
+	write := func(data []byte) error {
+		if _, err := file.Write(data); err != nil {
+			return errors.Wrapf(err, "failed writing file '%s'", filePath)
+		}
+		digest.Write(data)
+		stats.Size = stats.Size + int64(len(data))
+		return synced.wrote(len(data))
+	}
+
+	for {
+		response, err := deltaClient.Recv()
+		if err == io.EOF {
+			return stats, errors.Wrap(io.ErrUnexpectedEOF, "block delta ended without the end frame")
+		}
+		if err != nil {
+			return stats, errors.Wrap(fromStatusError(err), "failed reading block delta")
+		}
+		switch tresponse := response.GetPayload().(type) {
+		case *proto.BlockDeltaFrame_Copy_:
+			idx := tresponse.Copy.Index
+			if idx < 0 || idx >= int64(len(signatures)) {
+				return stats, errors.Wrapf(ErrProtocolMismatch, "copy of unknown block %d", idx)
+			}
+			if _, err := existing.ReadAt(block, idx*int64(opts.BlockSize)); err != nil {
+				return stats, errors.Wrapf(err, "failed reading block %d of '%s'", idx, filePath)
+			}
+			if err := write(block); err != nil {
+				return stats, err
+			}
+			stats.CopiedBytes = stats.CopiedBytes + int64(len(block))
+		case *proto.BlockDeltaFrame_Literal_:
+			checksum := sha256.Sum256(tresponse.Literal.Data)
+			if !bytes.Equal(checksum[:], tresponse.Literal.Checksum) {
+				return stats, errors.Wrapf(ErrChecksumMismatch, "literal data of '%s'", path)
+			}
+			if err := write(tresponse.Literal.Data); err != nil {
+				return stats, err
+			}
+			stats.LiteralBytes = stats.LiteralBytes + int64(len(tresponse.Literal.Data))
+		case *proto.BlockDeltaFrame_End_:
+			if err := verifyBlockDeltaEnd(tresponse.End, digest, stats.Size); err != nil {
+				return stats, err
+			}
+			if err := synced.finish(filePath); err != nil {
+				return stats, err
+			}
+			if err := file.Close(); err != nil {
+				return stats, errors.Wrapf(err, "failed closing file '%s'", filePath)
+			}
+			committed = true
+			if err := applyModeAndOwner(file.Name(), header, false); err != nil {
+				os.Remove(file.Name())
+				return stats, err
+			}
+			if err := os.Rename(file.Name(), filePath); err != nil {
+				os.Remove(file.Name())
+				return stats, errors.Wrapf(err, "failed moving file '%s' into place", filePath)
+			}
+			c.verifyWritten(path, []writtenEntry{{filePath: filePath, header: header}}, false)
+			return stats, nil
+		default:
+			return stats, errors.Wrap(ErrProtocolMismatch, "unexpected block delta frame")
+		}
+	}
+}
+
+func verifyBlockDeltaEnd(end *proto.BlockDeltaFrame_End, digest hash.Hash, size int64) error {
+	if end.Size != size {
+		return errors.Wrapf(ErrChecksumMismatch, "reconstructed %d bytes, expected %d", size, end.Size)
+	}
+	if !bytes.Equal(end.Digest, digest.Sum(nil)) {
+		return errors.Wrap(ErrChecksumMismatch, "reconstructed contents digest")
+	}
+	return nil
+}
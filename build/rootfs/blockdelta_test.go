@@ -0,0 +1,126 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingChecksum(t *testing.T) {
+	data := make([]byte, 1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	blockSize := 64
+	checksum := newRollingChecksum(data[0:blockSize])
+	for offset := 1; offset+blockSize <= len(data); offset++ {
+		checksum.roll(data[offset-1], data[offset+blockSize-1])
+		assert.Equal(t, newRollingChecksum(data[offset:offset+blockSize]).sum(), checksum.sum(), offset)
+	}
+}
+
+func TestComputeBlockDelta(t *testing.T) {
+	oldContent := make([]byte, 10*1024+100)
+	rand.New(rand.NewSource(2)).Read(oldContent)
+	blocks, err := blockSignatures(bytes.NewReader(oldContent), 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(blocks))
+
+	// insert bytes at the start and change a byte in the middle:
+	newContent := append([]byte("inserted"), oldContent...)
+	newContent[5000] = newContent[5000] + 1
+
+	frames := []*proto.BlockDeltaFrame{}
+	result, err := computeBlockDelta(bytes.NewReader(newContent), 1024, blocks, 512, func(frame *proto.BlockDeltaFrame) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(newContent)), result.size)
+
+	reconstructed := []byte{}
+	copies := 0
+	for _, frame := range frames {
+		switch tframe := frame.GetPayload().(type) {
+		case *proto.BlockDeltaFrame_Copy_:
+			copies = copies + 1
+			reconstructed = append(reconstructed, oldContent[tframe.Copy.Index*1024:(tframe.Copy.Index+1)*1024]...)
+		case *proto.BlockDeltaFrame_Literal_:
+			assert.True(t, len(tframe.Literal.Data) <= 512)
+			reconstructed = append(reconstructed, tframe.Literal.Data...)
+		}
+	}
+	assert.Equal(t, newContent, reconstructed)
+	// only the changed block does not match:
+	assert.Equal(t, 9, copies)
+	assert.Equal(t, int64(len("inserted")+1024+100), result.literalBytes)
+}
+
+func TestClientWriteResourceBlockDelta(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	oldContent := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(3)).Read(oldContent)
+	newContent := append(append([]byte{}, oldContent[0:500*1024]...), []byte("a few changed bytes")...)
+	newContent = append(newContent, oldContent[500*1024+10:]...)
+
+	m := &sync.Mutex{}
+	served := oldContent
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"seed": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					m.Lock()
+					defer m.Unlock()
+					return ioutil.NopCloser(bytes.NewReader(served)), nil
+				}, fs.FileMode(0640), "seed", "/var/lib/seed.db", commands.DefaultWorkdir(), commands.DefaultUser(), "seed"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	filePath := filepath.Join(tempDir, "seed.db")
+	opts := &BlockDeltaOptions{BlockSize: 4096}
+
+	// without an existing file, all contents are transferred:
+	stats, err := testClient.WriteResourceBlockDelta(context.Background(), "seed", filePath, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, DeltaTransferStats{Size: int64(len(oldContent)), LiteralBytes: int64(len(oldContent))}, stats)
+
+	m.Lock()
+	served = newContent
+	m.Unlock()
+
+	stats, err = testClient.WriteResourceBlockDelta(context.Background(), "seed", filePath, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(newContent)), stats.Size)
+	assert.True(t, stats.LiteralBytes < 3*4096, stats.LiteralBytes)
+	assert.Equal(t, stats.Size, stats.LiteralBytes+stats.CopiedBytes)
+
+	written, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, newContent, written)
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0640), info.Mode().Perm())
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
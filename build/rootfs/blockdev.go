@@ -0,0 +1,120 @@
+package rootfs
+
+import (
+	"context"
+	"io"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBlockDeviceAlignment is the default alignment of direct writes to block devices.
+	DefaultBlockDeviceAlignment = 4096
+	// DefaultBlockDeviceBufferSize is the default size of a single write to block devices.
+	DefaultBlockDeviceBufferSize = 1024 * 1024
+)
+
+// BlockDeviceWriteOptions configures writing a resource to a block device.
+type BlockDeviceWriteOptions struct {
+	// Alignment of the buffer address, the write size and the write offset required for direct I/O.
+	// Default is DefaultBlockDeviceAlignment.
+	Alignment int
+	// BufferSize is the size of a single write, rounded up to the alignment.
+	// Default is DefaultBlockDeviceBufferSize.
+	BufferSize int
+	// DisableDirectIO writes through the page cache.
+	DisableDirectIO bool
+}
+
+// WithDefaultsApplied applies default configuration values to unconfigured properties.
+func (o *BlockDeviceWriteOptions) WithDefaultsApplied() *BlockDeviceWriteOptions {
+	if o.Alignment == 0 {
+		o.Alignment = DefaultBlockDeviceAlignment
+	}
+	if o.BufferSize == 0 {
+		o.BufferSize = DefaultBlockDeviceBufferSize
+	}
+	if o.BufferSize%o.Alignment != 0 {
+		o.BufferSize = (o.BufferSize/o.Alignment + 1) * o.Alignment
+	}
+	return o
+}
+
+// WriteResourceToBlockDevice streams the first resource served for the path straight to a block device
+// or an existing file, starting at offset zero. Where supported, the device is opened for direct I/O
+// so that the contents bypass the page cache; the writes are aligned to the configured alignment.
+// A trailing partial block is written without direct I/O. The device is synced before returning.
+// Returns the number of bytes written.
+func (c *defaultClient) WriteResourceToBlockDevice(ctx context.Context, path, devicePath string, opts *BlockDeviceWriteOptions) (int64, error) {
+	if opts == nil {
+		opts = &BlockDeviceWriteOptions{}
+	}
+	opts = opts.WithDefaultsApplied()
+
+	reader, _, err := c.OpenResource(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	device, direct, err := openBlockDevice(devicePath, !opts.DisableDirectIO)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed opening block device '%s'", devicePath)
+	}
+	defer device.Close()
+
+	buffer := alignedBuffer(opts.BufferSize, opts.Alignment)
+	written := int64(0)
+	for {
+		readBytes, readErr := io.ReadFull(reader, buffer)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return written, readErr
+		}
+		if readBytes == len(buffer) {
+			n, err := device.Write(buffer)
+			written = written + int64(n)
+			if err != nil {
+				return written, errors.Wrap(err, "failed writing to block device")
+			}
+			continue
+		}
+		// the last, partial buffer:
+		alignedBytes := readBytes - readBytes%opts.Alignment
+		if alignedBytes > 0 {
+			n, err := device.Write(buffer[0:alignedBytes])
+			written = written + int64(n)
+			if err != nil {
+				return written, errors.Wrap(err, "failed writing to block device")
+			}
+		}
+		if alignedBytes < readBytes {
+			if direct {
+				if err := disableDirectIO(device); err != nil {
+					return written, errors.Wrap(err, "failed disabling direct I/O for the trailing block")
+				}
+			}
+			n, err := device.Write(buffer[alignedBytes:readBytes])
+			written = written + int64(n)
+			if err != nil {
+				return written, errors.Wrap(err, "failed writing to block device")
+			}
+		}
+		break
+	}
+
+	if err := device.Sync(); err != nil {
+		return written, errors.Wrap(err, "failed syncing block device")
+	}
+	return written, nil
+}
+
+// alignedBuffer returns a buffer of the size with the address aligned to the alignment.
+func alignedBuffer(size, alignment int) []byte {
+	buffer := make([]byte, size+alignment)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buffer[0])) % uintptr(alignment)); remainder != 0 {
+		offset = alignment - remainder
+	}
+	return buffer[offset : offset+size]
+}
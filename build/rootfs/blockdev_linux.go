@@ -0,0 +1,35 @@
+package rootfs
+
+import (
+	"os"
+	"syscall"
+)
+
+// openBlockDevice opens a device for writing, with direct I/O if requested and supported
+// by the underlying file system. Returns true if the device was opened with direct I/O.
+func openBlockDevice(devicePath string, direct bool) (*os.File, bool, error) {
+	if direct {
+		file, err := os.OpenFile(devicePath, os.O_WRONLY|syscall.O_DIRECT, 0)
+		if err == nil {
+			return file, true, nil
+		}
+		if pathErr, ok := err.(*os.PathError); !ok || pathErr.Err != syscall.EINVAL {
+			return nil, false, err
+		}
+		// the file system does not support direct I/O, for example tmpfs
+	}
+	file, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	return file, false, err
+}
+
+// disableDirectIO clears the direct I/O flag of an open file.
+func disableDirectIO(file *os.File) error {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_GETFL, 0)
+	if errno != 0 {
+		return errno
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_SETFL, flags&^syscall.O_DIRECT); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package rootfs
+
+import "os"
+
+// openBlockDevice opens a device for writing, direct I/O is supported on Linux only.
+func openBlockDevice(devicePath string, _ bool) (*os.File, bool, error) {
+	file, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	return file, false, err
+}
+
+// disableDirectIO is a no-op where direct I/O is not supported.
+func disableDirectIO(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package rootfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWriteResourceToBlockDevice(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// not a multiple of the alignment:
+	imageContent := getLargeFileContent(t, 3*1024*1024+1234)
+	MustPutTestResource(t, filepath.Join(tempDir, "rootfs.img"), imageContent)
+	devicePath := filepath.Join(tempDir, "device")
+	MustPutTestResource(t, devicePath, []byte{})
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"rootfs.img": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return os.Open(filepath.Join(tempDir, "rootfs.img"))
+				}, 0644, "rootfs.img", "/rootfs.img", commands.DefaultWorkdir(), commands.DefaultUser(), filepath.Join(tempDir, "rootfs.img")),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	written, err := testClient.WriteResourceToBlockDevice(context.Background(), "rootfs.img", devicePath, &BlockDeviceWriteOptions{
+		BufferSize: 64 * 1024,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(imageContent)), written)
+
+	deviceContent, err := ioutil.ReadFile(devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, imageContent, deviceContent)
+
+	_, err = testClient.WriteResourceToBlockDevice(context.Background(), "rootfs.img", filepath.Join(tempDir, "missing"), nil)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestAlignedBuffer(t *testing.T) {
+	for _, alignment := range []int{512, 4096} {
+		buffer := alignedBuffer(8192, alignment)
+		assert.Equal(t, 8192, len(buffer))
+		assert.Equal(t, uintptr(0), uintptr(unsafe.Pointer(&buffer[0]))%uintptr(alignment))
+	}
+}
@@ -0,0 +1,96 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GuestIDMetadataKey is the gRPC metadata key a client sends its guest ID in.
+const GuestIDMetadataKey = "firebuild-guest-id"
+
+// GuestSummary contains the outcome of a single guest of a broadcast build.
+type GuestSummary struct {
+	// FinishedAt is the time the guest finished, zero if the guest is in progress.
+	FinishedAt time.Time
+	// CommandsFinished is the number of commands the guest acknowledged as finished.
+	CommandsFinished int
+	// Success is true if the guest finished successfully.
+	Success bool
+	// Error contains the abort error if the guest aborted.
+	Error error
+}
+
+// guestIDFromContext returns the guest ID the client sent with the request, empty if none.
+func guestIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(GuestIDMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// broadcasting returns true if the work context is served to multiple guests.
+func (impl *serverImpl) broadcasting() bool {
+	return impl.serviceConfig.BroadcastGuests > 1
+}
+
+// guestCommandFinished counts a finished command of the guest of a broadcast build, the lock must be held.
+func (impl *serverImpl) guestCommandFinished(guestID string) {
+	guest := impl.summary.Guests[guestID]
+	guest.CommandsFinished = guest.CommandsFinished + 1
+	impl.summary.Guests[guestID] = guest
+}
+
+// guestFinished records the outcome of the guest of a broadcast build, the lock must be held.
+// Returns true when every guest has finished and the error of the build, nil if every guest succeeded.
+func (impl *serverImpl) guestFinished(guestID string, guestErr error) (bool, error) {
+	guest := impl.summary.Guests[guestID]
+	guest.FinishedAt = time.Now()
+	guest.Success = guestErr == nil
+	guest.Error = guestErr
+	impl.summary.Guests[guestID] = guest
+
+	finished, aborted := 0, 0
+	var firstErr error
+	for _, guest := range impl.summary.Guests {
+		if guest.FinishedAt.IsZero() {
+			continue
+		}
+		finished = finished + 1
+		if guest.Error != nil {
+			aborted = aborted + 1
+			if firstErr == nil {
+				firstErr = guest.Error
+			}
+		}
+	}
+	if finished < impl.serviceConfig.BroadcastGuests {
+		return false, nil
+	}
+	impl.summary.FinishedAt = time.Now()
+	if aborted == 0 {
+		impl.summary.Success = true
+		return true, nil
+	}
+	impl.summary.Error = fmt.Errorf("%d of %d guests aborted, first error: %v", aborted, finished, firstErr)
+	return true, impl.summary.Error
+}
+
+func guestIDUnaryClientInterceptor(guestID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, GuestIDMetadataKey, guestID), method, req, reply, cc, opts...)
+	}
+}
+
+func guestIDStreamClientInterceptor(guestID string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, GuestIDMetadataKey, guestID), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,79 @@
+package rootfs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerBroadcastToGuests(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	chanAbort := make(chan BuildSummary, 1)
+	grpcConfig := &GRPCServiceConfig{BroadcastGuests: 3}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: make(Resources),
+		OnAfterAbort: func(summary BuildSummary) error {
+			chanAbort <- summary
+			return nil
+		},
+	})
+	defer srv.Stop()
+
+	chanEvents := make(chan interface{}, 10)
+	go func() {
+		for message := range srv.OnMessage() {
+			switch message.(type) {
+			case *ClientMsgGuestFinished, *ClientMsgAborted, *ClientMsgSuccess:
+				chanEvents <- message
+			}
+		}
+	}()
+
+	guests := map[string]ClientProvider{}
+	for _, guestID := range []string{"guest-1", "guest-2", "guest-3"} {
+		guestClient, err := NewClient(logger.Named(guestID), &GRPCClientConfig{
+			GuestID:   guestID,
+			HostPort:  grpcConfig.BindHostPort,
+			TLSConfig: grpcConfig.TLSConfigClient,
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, guestClient.Commands())
+		run, ok := guestClient.NextCommand().(commands.Run)
+		if assert.True(t, ok) {
+			assert.Equal(t, "true", run.Command)
+		}
+		assert.Nil(t, guestClient.CommandStarted(0))
+		assert.Nil(t, guestClient.CommandFinished(0, nil))
+		guests[guestID] = guestClient
+	}
+
+	assert.Nil(t, guests["guest-1"].Success())
+	assert.Nil(t, guests["guest-2"].Abort(fmt.Errorf("guest failed")))
+	assert.Equal(t, &ClientMsgGuestFinished{GuestID: "guest-1"}, <-chanEvents)
+	assert.Equal(t, &ClientMsgGuestFinished{GuestID: "guest-2", Error: fmt.Errorf("guest failed")}, <-chanEvents)
+	// the build is in progress until every guest finishes:
+	assert.True(t, srv.Summary().FinishedAt.IsZero())
+
+	assert.Nil(t, guests["guest-3"].Success())
+	assert.Equal(t, &ClientMsgGuestFinished{GuestID: "guest-3"}, <-chanEvents)
+	aborted, ok := (<-chanEvents).(*ClientMsgAborted)
+	if assert.True(t, ok) {
+		assert.Contains(t, aborted.Error.Error(), "1 of 3 guests aborted")
+	}
+
+	summary := <-chanAbort
+	assert.False(t, summary.Success)
+	assert.Equal(t, 3, len(summary.Guests))
+	assert.True(t, summary.Guests["guest-1"].Success)
+	assert.Equal(t, "guest failed", summary.Guests["guest-2"].Error.Error())
+	assert.Equal(t, 1, summary.Guests["guest-3"].CommandsFinished)
+	assert.Equal(t, 3, summary.CommandsFinished)
+}
@@ -0,0 +1,65 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+)
+
+// chunkBudget limits the number of bytes buffered for chunks across all active resource streams.
+// A budget with zero limit tracks the usage but never blocks.
+type chunkBudget struct {
+	m       sync.Mutex
+	limit   int64
+	used    int64
+	streams int
+	waiters []chan struct{}
+}
+
+func newChunkBudget(limit int64) *chunkBudget {
+	return &chunkBudget{limit: limit}
+}
+
+// acquire blocks until n bytes are available or the context is done.
+// A request larger than the limit waits for the whole budget.
+// Returns the number of bytes acquired, which must be released.
+func (b *chunkBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if b.limit > 0 && n > b.limit {
+		n = b.limit
+	}
+	for {
+		b.m.Lock()
+		if b.limit == 0 || b.used+n <= b.limit {
+			b.used = b.used + n
+			b.streams = b.streams + 1
+			b.m.Unlock()
+			return n, nil
+		}
+		chanWait := make(chan struct{})
+		b.waiters = append(b.waiters, chanWait)
+		b.m.Unlock()
+
+		select {
+		case <-chanWait:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// release returns n acquired bytes to the budget and wakes up waiting streams.
+func (b *chunkBudget) release(n int64) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.used = b.used - n
+	b.streams = b.streams - 1
+	for _, chanWait := range b.waiters {
+		close(chanWait)
+	}
+	b.waiters = nil
+}
+
+func (b *chunkBudget) usage() (int64, int) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.used, b.streams
+}
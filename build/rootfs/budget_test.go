@@ -0,0 +1,94 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/utilstest"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkBudgetBlocksUntilReleased(t *testing.T) {
+	budget := newChunkBudget(100)
+
+	first, err := budget.acquire(context.Background(), 60)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), first)
+
+	chanAcquired := make(chan int64)
+	go func() {
+		// larger than the limit, waits for the whole budget:
+		acquired, _ := budget.acquire(context.Background(), 1000)
+		chanAcquired <- acquired
+	}()
+
+	select {
+	case <-chanAcquired:
+		t.Fatal("expected the second acquire to wait")
+	case <-time.After(time.Millisecond * 50):
+	}
+	used, streams := budget.usage()
+	assert.Equal(t, int64(60), used)
+	assert.Equal(t, 1, streams)
+
+	budget.release(first)
+	assert.Equal(t, int64(100), <-chanAcquired)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err = budget.acquire(ctx, 1)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestServerStreamsWithinChunkBudget(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	contents := getLargeFileContent(t, 64*1024)
+	ress := Resources{}
+	for _, name := range []string{"a", "b", "c"} {
+		ress[name] = []resources.ResolvedResource{
+			resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(contents)), nil
+			}, fs.FileMode(0644), name, "/etc/"+name, commands.DefaultWorkdir(), commands.DefaultUser(), name),
+		}
+	}
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{MaxBufferedChunkBytes: 1024}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  ress,
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	wg := &sync.WaitGroup{}
+	for name := range ress {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			MustReadResources(t, testClient, name, contents)
+		}(name)
+	}
+	wg.Wait()
+
+	utilstest.MustEventuallyWithDefaults(t, func() error {
+		if stats := srv.Stats(); stats.ActiveResourceStreams > 0 || stats.BufferedChunkBytes > 0 {
+			return fmt.Errorf("expected no active streams, got: %+v", stats)
+		}
+		return nil
+	})
+	assert.Equal(t, int64(1024), srv.Stats().BufferedChunkBytesLimit)
+	assert.Equal(t, int64(3*64*1024), srv.Summary().BytesServed)
+}
@@ -0,0 +1,173 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// Authentication modes reported by the server.
+const (
+	// AuthModeMutualTLS means the server verifies client certificates.
+	AuthModeMutualTLS = "mtls"
+	// AuthModeRoles means the server resolves the role of a client from the client certificate.
+	AuthModeRoles = "roles"
+	// AuthModeTLS means the server serves TLS without verifying client certificates.
+	AuthModeTLS = "tls"
+)
+
+// ChecksumSHA256 is the algorithm of the resource chunk checksums.
+const ChecksumSHA256 = "sha256"
+
+// Compressions of the resource chunks.
+const (
+	// CompressionGzip means the resource chunks of at least GRPCServiceConfig.CompressionThreshold bytes are gzipped
+	// for the clients accepting gzip.
+	CompressionGzip = "gzip"
+	// CompressionIdentity means the resource chunks are served uncompressed.
+	CompressionIdentity = "identity"
+)
+
+// Features reported by the server.
+const (
+	FeatureBatch         = "batch"
+	FeatureBlockDelta    = "block-delta"
+	FeatureBroadcast     = "broadcast"
+	FeatureConnect       = "connect"
+	FeatureDebugOnAbort  = "debug-on-abort"
+	FeatureFlush         = "flush"
+	FeatureManifest      = "manifest"
+	FeatureMultiplex     = "multiplex"
+	FeaturePortForward   = "port-forward"
+	FeatureProgress      = "progress"
+	FeatureReconnect     = "reconnect"
+	FeatureResourceDelta = "resource-delta"
+	FeatureSecret        = "secret"
+	FeatureSpool         = "spool"
+	FeatureStatus        = "status"
+	FeatureWatch         = "watch"
+	FeatureWatchLogs     = "watch-logs"
+)
+
+// Capabilities describes the protocol features enabled on the server,
+// a client checks them before relying on a feature an older or differently configured server may not provide.
+type Capabilities struct {
+	AuthModes          []string
+	ChecksumAlgorithms []string
+	Compression        []string
+	Features           []string
+	// MaxMsgSize is the maximum message size in bytes the server receives.
+	MaxMsgSize int64
+	// MaxChunkSize is the maximum size in bytes of a served resource chunk.
+	MaxChunkSize int64
+	// Resume is true when resources can be requested from an offset.
+	Resume bool
+}
+
+// HasFeature returns true if the server reported the feature.
+func (c *Capabilities) HasFeature(feature string) bool {
+	return containsString(c.Features, feature)
+}
+
+// HasAuthMode returns true if the server reported the authentication mode.
+func (c *Capabilities) HasAuthMode(mode string) bool {
+	return containsString(c.AuthModes, mode)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func capabilitiesFromProto(response *proto.CapabilitiesResponse) *Capabilities {
+	return &Capabilities{
+		AuthModes:          response.AuthModes,
+		ChecksumAlgorithms: response.ChecksumAlgorithms,
+		Compression:        response.Compression,
+		Features:           response.Features,
+		MaxMsgSize:         response.MaxMsgSize,
+		MaxChunkSize:       response.MaxChunkSize,
+		Resume:             response.Resume,
+	}
+}
+
+// capabilities lists the features enabled by the service configuration and the work context.
+func (impl *serverImpl) capabilities() *proto.CapabilitiesResponse {
+	response := &proto.CapabilitiesResponse{
+		AuthModes:          []string{},
+		ChecksumAlgorithms: []string{ChecksumSHA256},
+		Compression:        []string{CompressionIdentity},
+		Features: []string{
+			FeatureBatch,
+			FeatureBlockDelta,
+			FeatureFlush,
+			FeatureManifest,
+			FeatureMultiplex,
+			FeaturePortForward,
+			FeatureProgress,
+			FeatureReconnect,
+			FeatureResourceDelta,
+			FeatureSecret,
+			FeatureStatus,
+			FeatureWatch,
+			FeatureWatchLogs,
+		},
+		MaxMsgSize:   int64(impl.serviceConfig.MaxMsgSize),
+		MaxChunkSize: int64(impl.serviceConfig.SafeClientMaxRecvMsgSize()),
+		Resume:       true,
+	}
+	if impl.serviceConfig.CompressionThreshold > 0 {
+		response.Compression = append(response.Compression, CompressionGzip)
+	}
+
+	// without a server TLS config, the server uses the embedded CA and requires client certificates:
+	tlsConfig := impl.serviceConfig.TLSConfigServer
+	if tlsConfig == nil || tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert || tlsConfig.ClientAuth == tls.VerifyClientCertIfGiven {
+		response.AuthModes = append(response.AuthModes, AuthModeMutualTLS)
+	} else {
+		response.AuthModes = append(response.AuthModes, AuthModeTLS)
+	}
+	if impl.serviceConfig.ClientRoleResolver != nil || (tlsConfig == nil && impl.serviceConfig.ObserverClients > 0) {
+		response.AuthModes = append(response.AuthModes, AuthModeRoles)
+	}
+
+	if impl.broadcasting() {
+		response.Features = append(response.Features, FeatureBroadcast)
+	}
+	if impl.serviceConfig.ConnectProtocol {
+		response.Features = append(response.Features, FeatureConnect)
+	}
+	if impl.serviceConfig.DebugOnAbort {
+		response.Features = append(response.Features, FeatureDebugOnAbort)
+	}
+	if impl.serverCtx.Spool != nil {
+		response.Features = append(response.Features, FeatureSpool)
+	}
+	return response
+}
+
+func (impl *serverImpl) Capabilities(ctx context.Context, _ *proto.Empty) (*proto.CapabilitiesResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.CapabilitiesResponse{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+	return impl.capabilities(), nil
+}
+
+// Capabilities requests the protocol features enabled on the server.
+// Servers without capability discovery return an error wrapping ErrProtocolMismatch.
+func (c *defaultClient) Capabilities() (*Capabilities, error) {
+	response, err := c.underlying.Capabilities(context.Background(), &proto.Empty{})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	return capabilitiesFromProto(response), nil
+}
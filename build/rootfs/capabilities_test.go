@@ -0,0 +1,50 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCapabilities(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	}
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, buildCtx)
+	capabilities, err := testClient.Capabilities()
+	srv.Stop()
+	if assert.Nil(t, err) {
+		assert.True(t, capabilities.HasAuthMode(AuthModeMutualTLS))
+		assert.False(t, capabilities.HasAuthMode(AuthModeRoles))
+		assert.Equal(t, []string{ChecksumSHA256}, capabilities.ChecksumAlgorithms)
+		assert.Equal(t, []string{CompressionIdentity}, capabilities.Compression)
+		assert.True(t, capabilities.HasFeature(FeatureResourceDelta))
+		assert.False(t, capabilities.HasFeature(FeatureConnect))
+		assert.False(t, capabilities.HasFeature(FeatureDebugOnAbort))
+		assert.Equal(t, int64(DefaultMaxMsgSize), capabilities.MaxMsgSize)
+		assert.True(t, capabilities.MaxChunkSize < capabilities.MaxMsgSize)
+		assert.True(t, capabilities.Resume)
+	}
+
+	grpcConfig := &GRPCServiceConfig{ConnectProtocol: true, DebugOnAbort: true, ObserverClients: 1}
+	srv, _ = mustStartServerAndClient(t, logger, grpcConfig, buildCtx)
+	defer srv.Stop()
+	observerClient, err := NewClient(logger.Named("observer"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigObserverClients[0],
+	})
+	assert.Nil(t, err)
+	capabilities, err = observerClient.Capabilities()
+	if assert.Nil(t, err) {
+		assert.True(t, capabilities.HasAuthMode(AuthModeRoles))
+		assert.True(t, capabilities.HasFeature(FeatureConnect))
+		assert.True(t, capabilities.HasFeature(FeatureDebugOnAbort))
+	}
+}
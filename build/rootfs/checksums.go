@@ -0,0 +1,95 @@
+package rootfs
+
+import (
+	"crypto/sha256"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// ChecksumCache caches the checksums of the chunks of local files streamed by the server
+// so that serving the same files repeatedly, for example to multiple guests, skips hashing them again.
+// The chunks are keyed by the path, the modification time and the size of the file and the offset
+// and the length of the chunk. The checksums of a file are dropped when the file changes.
+// A cache may be shared by multiple servers.
+type ChecksumCache struct {
+	m     sync.Mutex
+	files map[string]*cachedChecksums
+
+	hits   int64
+	misses int64
+}
+
+type cachedChecksums struct {
+	modTime   time.Time
+	size      int64
+	checksums map[chunkRange][]byte
+}
+
+type chunkRange struct {
+	offset int64
+	length int
+}
+
+// NewChecksumCache creates an empty checksum cache.
+func NewChecksumCache() *ChecksumCache {
+	return &ChecksumCache{files: map[string]*cachedChecksums{}}
+}
+
+// Invalidate drops the cached checksums of a file.
+func (c *ChecksumCache) Invalidate(filePath string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.files, filePath)
+}
+
+// Stats returns the number of checksums served from the cache and the number of checksums computed.
+func (c *ChecksumCache) Stats() (hits, misses int64) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.hits, c.misses
+}
+
+// checksum returns the checksum of the chunk of the file at the offset, computing and caching it when not cached.
+// A nil cache or a nil file info always computes the checksum.
+func (c *ChecksumCache) checksum(filePath string, info fs.FileInfo, offset int64, payload []byte) []byte {
+	if c == nil || info == nil {
+		hash := sha256.Sum256(payload)
+		return hash[:]
+	}
+	key := chunkRange{offset: offset, length: len(payload)}
+
+	c.m.Lock()
+	cached, ok := c.files[filePath]
+	if ok && (!cached.modTime.Equal(info.ModTime()) || cached.size != info.Size()) {
+		// the file changed since the checksums were cached:
+		delete(c.files, filePath)
+		ok = false
+	}
+	if ok {
+		if checksum, found := cached.checksums[key]; found {
+			c.hits = c.hits + 1
+			c.m.Unlock()
+			return checksum
+		}
+	}
+	c.misses = c.misses + 1
+	c.m.Unlock()
+
+	hash := sha256.Sum256(payload)
+	checksum := hash[:]
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	cached, ok = c.files[filePath]
+	if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+		cached = &cachedChecksums{
+			modTime:   info.ModTime(),
+			size:      info.Size(),
+			checksums: map[chunkRange][]byte{},
+		}
+		c.files[filePath] = cached
+	}
+	cached.checksums[key] = checksum
+	return checksum
+}
@@ -0,0 +1,95 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumCacheInvalidatesChangedFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filePath := filepath.Join(tempDir, "file")
+	MustPutTestResource(t, filePath, []byte("original"))
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+
+	cache := NewChecksumCache()
+	expected := sha256.Sum256([]byte("original"))
+	assert.Equal(t, expected[:], cache.checksum(filePath, info, 0, []byte("original")))
+	// a cached checksum is returned as long as the file does not change:
+	assert.Equal(t, expected[:], cache.checksum(filePath, info, 0, []byte("original")))
+	hits, misses := cache.Stats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(1), misses)
+
+	MustPutTestResource(t, filePath, []byte("modified"))
+	assert.Nil(t, os.Chtimes(filePath, time.Now(), info.ModTime().Add(time.Second)))
+	changedInfo, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	expected = sha256.Sum256([]byte("modified"))
+	assert.Equal(t, expected[:], cache.checksum(filePath, changedInfo, 0, []byte("modified")))
+	hits, misses = cache.Stats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(2), misses)
+
+	cache.Invalidate(filePath)
+	cache.checksum(filePath, changedInfo, 0, []byte("modified"))
+	_, misses = cache.Stats()
+	assert.Equal(t, int64(3), misses)
+
+	// without a cache or a local file, the checksum is computed:
+	var nilCache *ChecksumCache
+	assert.Equal(t, expected[:], nilCache.checksum(filePath, changedInfo, 0, []byte("modified")))
+	assert.Equal(t, expected[:], cache.checksum("", nil, 0, []byte("modified")))
+}
+
+func TestServerCachesChunkChecksums(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "a"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "sub/b"), []byte("b"))
+
+	cache := NewChecksumCache()
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+		ChecksumCache: cache,
+	})
+	defer cleanupFunc()
+
+	for i := 0; i < 2; i++ {
+		rootDir := filepath.Join(tempDir, "root", string(rune('0'+i)))
+		assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, nil))
+		contents, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir/sub/b"))
+		assert.Nil(t, err)
+		assert.Equal(t, "b", string(contents))
+	}
+	hits, misses := cache.Stats()
+	assert.Equal(t, int64(2), hits)
+	assert.Equal(t, int64(2), misses)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
@@ -3,53 +3,183 @@ package rootfs
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"io/ioutil"
-	"strings"
+	"os/exec"
+	"time"
 
 	"github.com/combust-labs/firebuild-shared/build/commands"
 	"github.com/combust-labs/firebuild-shared/grpc/proto"
 	"github.com/gofrs/uuid"
 	"github.com/hashicorp/go-hclog"
-	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 )
 
 // ClientProvider defines a GRPC client behaviour.
 type ClientProvider interface {
 	// Abort aborts the client with error.
 	Abort(error) error
-	// Commands requests the processable commands from the server.
+	// Capabilities requests the protocol features enabled on the server.
+	Capabilities() (*Capabilities, error)
+	// CommandFinished acknowledges that the command at the index finished, with an optional error.
+	CommandFinished(index int, err error) error
+	// CommandRetried acknowledges that the command at the index failed with the error and runs again.
+	CommandRetried(index int, err error) error
+	// CommandStarted acknowledges that the command at the index started executing.
+	CommandStarted(index int) error
+	// Commands requests the processable commands from the server. The commands with a guard
+	// not matching the facts of the guest are skipped and acknowledged as skipped.
 	Commands() error
+	// Debug serves an interactive debug session for the host by proxying
+	// the input and output of a pty. Blocks until the host ends the session.
+	// Available only after Abort, when DebugRequested() returns true.
+	Debug(io.ReadWriter) error
+	// DebugRequested returns true if the server asked the aborted client to serve a debug session.
+	DebugRequested() bool
+	// Environment requests the ARG and ENV values in effect as of build start.
+	Environment() (map[string]string, error)
+	// FetchResources writes the resources served for the paths under a root directory, fetching multiple resources
+	// at the same time in the order of their priorities.
+	FetchResources(ctx context.Context, paths []string, rootDir string, opts *FetchOptions) error
+	// FetchResourceAsTar streams all resources served for a path as a tar stream to the writer.
+	FetchResourceAsTar(ctx context.Context, path string, w io.Writer) error
+	// Flush tells the server that all stdout and stderr lines were sent and returns the numbers
+	// of lines the server acknowledges as received. Call before Success.
+	Flush() (*FlushResult, error)
+	// FS returns a file system of all resources served by the server, keyed by the target paths.
+	// Directories are listed from the manifest, file contents are streamed when read.
+	FS(ctx context.Context) (fs.FS, error)
+	// Manifest lists the resources served for a path without the contents,
+	// directory resources are listed entry by entry. An empty path lists all resources.
+	Manifest(ctx context.Context, path string) ([]ManifestEntry, error)
 	// NextCommand returns the next command to process, Commands() must be called first.
 	NextCommand() commands.VMInitSerializableCommand
+	// NextCommandGroup returns the next commands to process with their indexes, Commands() must be called first.
+	// The commands of a parallel group are returned together, they may run concurrently and be acknowledged
+	// in any order. Returns an empty slice when there are no more commands.
+	NextCommandGroup() []IndexedCommand
+	// OpenResource opens the first resource streamed for a path and returns a reader
+	// of its contents and the resource header. The reader must be closed.
+	OpenResource(ctx context.Context, path string) (io.ReadCloser, ResourceHeader, error)
 	// Ping sends a ping message to the server, if the response ID does not match, returns an error.
 	Ping() error
+	// PortForward requests that a host address is proxied into the VM.
+	// The protocol is tcp or udp, the guest port is the port the client would like to use.
+	PortForward(protocol, hostAddress string, guestPort int) (*PortForward, error)
+	// PortForwardClose tells the server that a port forward is no longer needed.
+	PortForwardClose(id string) error
+	// RunAndStream runs the command and sends its stdout and stderr lines to the server
+	// until the command exits. The command must not have its Stdout and Stderr set.
+	RunAndStream(ctx context.Context, cmd *exec.Cmd) error
+	// RunAndStreamWithRetry runs the command created by newCmd like RunAndStream, running it again
+	// while the retry policy retries the failure. Returns the number of attempts and the error of the last attempt.
+	RunAndStreamWithRetry(ctx context.Context, index int, policy *commands.RetryPolicy, newCmd func() *exec.Cmd) (int, error)
+	// ReportProgress reports the progress of writing a resource to the server, see WriteOptions.ProgressInterval.
+	ReportProgress(progress ResourceProgress) error
+	// Resume presents the progress of a reconnecting client, the index of the last completed command and the offsets
+	// of the partially written resources, and returns where the client and the server agreed to continue the build.
+	Resume(lastCompletedIndex int, resourceOffsets map[string]int64) (*ResumePoint, error)
 	// Resource loads the resource identified by a path from the server.
 	Resource(string) (chan interface{}, error)
+	// Secret reads the contents of a secret resource into memory, secrets are not served as streamed resources.
+	Secret(ctx context.Context, path string) ([]byte, ResourceHeader, error)
+	// Status requests the progress of the build from the server.
+	Status() (*BuildStatus, error)
 	// StdErr sends stderr lines to the server.
 	StdErr([]string) error
 	// StdOut sends stdout lines to the server.
 	StdOut([]string) error
 	// Success finishes the client with success.
 	Success() error
+	// Warning reports warnings about a written resource to the server.
+	Warning(path string, warnings []string) error
+	// WatchCancel holds a watch stream open and returns a channel which receives
+	// the reason when the server cancels the build. The channel is closed when the watch ends.
+	WatchCancel() (<-chan error, error)
+	// WatchLogs holds a log stream open and returns a channel which receives
+	// the stdout and stderr lines of the build. The channel is closed when the stream ends.
+	WatchLogs() (<-chan LogLine, error)
+	// WriteResourceBlockDelta writes the first file resource served for a path to a file, transferring only
+	// the contents not found in the blocks of the existing file.
+	WriteResourceBlockDelta(ctx context.Context, path, filePath string, opts *BlockDeltaOptions) (DeltaTransferStats, error)
+	// WriteResourceToBlockDevice streams the first resource served for a path straight to a block device,
+	// bypassing the page cache where supported. Returns the number of bytes written.
+	WriteResourceToBlockDevice(ctx context.Context, path, devicePath string, opts *BlockDeviceWriteOptions) (int64, error)
+	// WriteResourceToFile writes the first resource served for a path to a file, resuming an interrupted write
+	// verified by the journal kept next to the file. Returns the size of the file.
+	WriteResourceToFile(ctx context.Context, path, filePath string) (int64, error)
+	// WriteResources writes all resources served for a path under a root directory at their target paths,
+	// applies the modes explicitly and reports mismatches found when verifying the written entries.
+	WriteResources(ctx context.Context, path, rootDir string, opts *WriteOptions) error
+}
+
+// IndexedCommand is a command to process with its index in the commands of the server,
+// the index the command is acknowledged with.
+type IndexedCommand struct {
+	Index   int
+	Command commands.VMInitSerializableCommand
+	// Group is the parallel group of the command, 0 if the command must run on its own.
+	Group int
+	// Env is the environment of the command over the ENV values of its stage preceding the command.
+	// The executor applies it over the build environment returned by Environment, see commands.EffectiveEnv.
+	Env map[string]string
+}
+
+// PortForward describes a port forward accepted by the server.
+type PortForward struct {
+	// ID identifies the port forward.
+	ID string
+	// GuestAddress is the address the client reaches the host address at.
+	GuestAddress string
 }
 
 // GRPCClientConfig is the client configuration.
 type GRPCClientConfig struct {
-	// HostPort to connect to.
+	// HostPort to connect to. A host and port prefixed with a scheme followed by ://
+	// connects with the dialer registered for the scheme with RegisterTransportDialer.
 	HostPort string
+	// Dialer optionally connects the client to the server, for example through an SSH server
+	// with NewSSHDialer. Takes precedence over the dialer registered for the scheme of HostPort.
+	Dialer TransportDialFunc
+	// WireVersion is the wire version of the service the client speaks, default DefaultWireVersion.
+	// The client falls back to an older version when the server does not serve the version.
+	WireVersion string
+	// BuildID is the ID of the build the client executes, sent with every request.
+	// The server rejects the requests with ErrBuildMismatch when it serves another build.
+	// The client does not send the build ID when empty.
+	BuildID string
+	// GuestID identifies the guest to a server broadcasting the build to multiple guests.
+	GuestID string
 	// TLSConfig is the optional TLS configuration to use when connecting to the server.
 	TLSConfig *tls.Config
 	// MaxRecvMsgSize is the maximum message size the client can safely handle.
 	MaxRecvMsgSize int
+	// FsyncPolicy defines when the files written by the client are synced.
+	// Default is FsyncPolicyPerFile.
+	FsyncPolicy FsyncPolicy
+	// FsyncIntervalBytes is the number of bytes written between syncs with FsyncPolicyPerNBytes.
+	// Default is DefaultFsyncIntervalBytes.
+	FsyncIntervalBytes int64
+	// LogLevels contains the optional levels of the loggers of the client subsystems, for example LogSubsystemClientFetch.
+	LogLevels LogLevels
+	// LogMode defines how the stdout and stderr lines are processed before they are sent to the server.
+	// Default is LogModeRaw.
+	LogMode LogMode
+	// Facts are the facts of the guest the guards of the commands are evaluated against,
+	// sent to the server to render the template resources with.
+	// Default is DefaultFacts.
+	Facts commands.Facts
+	// MaxLogLineBytes is the maximum length of a stdout or stderr line sent to the server, longer lines
+	// are truncated and end with a truncation marker. Lines sent at once are split in as many requests
+	// as needed to fit in a message. Zero applies DefaultMaxLogLineBytes, a negative value disables the limit.
+	MaxLogLineBytes int
+	// Interval of the keepalive pings the client sends on an idle connection, at least ten seconds.
+	// The server must permit the pings: a server without GRPCServiceConfig.KeepaliveInterval of at most
+	// twice this interval closes a connection pinging more often than every five minutes. Zero sends no pings.
+	KeepaliveInterval time.Duration
 }
 
 // WithDefaultsApplied applies default configuration values to unconfigured properties.
@@ -57,75 +187,209 @@ func (c *GRPCClientConfig) WithDefaultsApplied() *GRPCClientConfig {
 	if c.MaxRecvMsgSize == 0 {
 		c.MaxRecvMsgSize = DefaultMaxMsgSize
 	}
+	if c.MaxLogLineBytes == 0 {
+		c.MaxLogLineBytes = DefaultMaxLogLineBytes
+	}
+	if c.FsyncIntervalBytes == 0 {
+		c.FsyncIntervalBytes = DefaultFsyncIntervalBytes
+	}
+	if c.WireVersion == "" {
+		c.WireVersion = DefaultWireVersion
+	}
+	if c.Facts == nil {
+		c.Facts = DefaultFacts()
+	}
 	return c
 }
 
-// NewClient returns a new default client provider implementation.
+// NewClient returns a new default client provider implementation
+// calling the server over a connection of its own.
 func NewClient(logger hclog.Logger, cfg *GRPCClientConfig) (ClientProvider, error) {
-	cfg = cfg.WithDefaultsApplied()
-	grpcConn, err := grpc.Dial(cfg.HostPort,
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize)),
-		grpc.WithTransportCredentials(credentials.NewTLS(cfg.TLSConfig)))
-
+	conn, err := DialConn(logger, cfg)
 	if err != nil {
 		return nil, err
 	}
-
-	return &defaultClient{logger: logger, underlying: proto.NewRootfsServerClient(grpcConn)}, nil
+	return conn.newClient(logger, ""), nil
 }
 
 type defaultClient struct {
+	buildID         string
+	debugRequested  bool
+	facts           commands.Facts
 	logger          hclog.Logger
-	fetchedCommands []commands.VMInitSerializableCommand
+	fetchLogger     hclog.Logger
+	fetchedCommands []IndexedCommand
+	logMode         LogMode
+	maxLogLineBytes int
+	maxLogMsgBytes  int
+	resumeIndex     int
+	sentLines       *sentLogLines
+	syncer          *fileSyncer
 	underlying      proto.RootfsServerClient
 }
 
 // Abort aborts the client with error.
 func (c *defaultClient) Abort(input error) error {
-	_, err := c.underlying.Abort(context.Background(), &proto.AbortRequest{Error: input.Error()})
+	response, err := c.underlying.Abort(context.Background(), &proto.AbortRequest{Error: input.Error()})
+	if err != nil {
+		return err
+	}
+	c.debugRequested = response.Debug
+	return nil
+}
+
+// CommandFinished acknowledges that the command at the index finished, with an optional error.
+func (c *defaultClient) CommandFinished(index int, input error) error {
+	req := &proto.CommandAck{Index: int32(index), Phase: proto.CommandAck_FINISHED}
+	if input != nil {
+		req.Error = input.Error()
+	}
+	_, err := c.underlying.Ack(context.Background(), req)
+	return err
+}
+
+// CommandRetried acknowledges that the command at the index failed with the error and runs again.
+func (c *defaultClient) CommandRetried(index int, input error) error {
+	req := &proto.CommandAck{Index: int32(index), Phase: proto.CommandAck_RETRIED}
+	if input != nil {
+		req.Error = input.Error()
+	}
+	_, err := c.underlying.Ack(context.Background(), req)
+	return err
+}
+
+// CommandStarted acknowledges that the command at the index started executing.
+func (c *defaultClient) CommandStarted(index int) error {
+	_, err := c.underlying.Ack(context.Background(), &proto.CommandAck{Index: int32(index), Phase: proto.CommandAck_STARTED})
+	return err
+}
+
+// commandSkipped acknowledges that the command at the index does not execute on the guest.
+func (c *defaultClient) commandSkipped(index int) error {
+	_, err := c.underlying.Ack(context.Background(), &proto.CommandAck{Index: int32(index), Phase: proto.CommandAck_SKIPPED})
+	if errors.Is(err, ErrUnauthorized) {
+		// observers only read the commands:
+		return nil
+	}
 	return err
 }
 
 // Commands requests the processable commands from the server.
 func (c *defaultClient) Commands() error {
-	c.fetchedCommands = []commands.VMInitSerializableCommand{}
+	c.fetchedCommands = []IndexedCommand{}
 	response, err := c.underlying.Commands(context.Background(), &proto.Empty{})
 	if err != nil {
 		return err
 	}
-	for _, cmd := range response.Command {
-		rawItem := map[string]interface{}{}
-		if err := json.Unmarshal([]byte(cmd), &rawItem); err != nil {
-			return err
+	// unknown commands stay nil, the indexes must match the indexes of the server:
+	deserialized := make([]commands.VMInitSerializableCommand, len(response.Command))
+	for index, cmd := range response.Command {
+		command, err := commands.Deserialize([]byte(cmd))
+		if err != nil {
+			if errors.Is(err, commands.ErrUnknownCommand) {
+				c.logger.Warn("unexpected command received from grpc", "command", cmd)
+				continue
+			}
+			return errors.Wrap(ErrProtocolMismatch, err.Error())
 		}
-
-		if originalCommandString, ok := rawItem["OriginalCommand"]; ok {
-			if strings.HasPrefix(fmt.Sprintf("%s", originalCommandString), "ADD") {
-				command := commands.Add{}
-				if err := mapstructure.Decode(rawItem, &command); err != nil {
-					return errors.Wrap(err, "found ADD but did not deserialize")
-				}
-				c.fetchedCommands = append(c.fetchedCommands, command)
-			} else if strings.HasPrefix(fmt.Sprintf("%s", originalCommandString), "COPY") {
-				command := commands.Copy{}
-				if err := mapstructure.Decode(rawItem, &command); err != nil {
-					return errors.Wrap(err, "found COPY but did not deserialize")
-				}
-				c.fetchedCommands = append(c.fetchedCommands, command)
-			} else if strings.HasPrefix(fmt.Sprintf("%s", originalCommandString), "RUN") {
-				command := commands.Run{}
-				if err := mapstructure.Decode(rawItem, &command); err != nil {
-					return errors.Wrap(err, "found RUN but did not deserialize")
+		deserialized[index] = command
+	}
+	for index, command := range deserialized {
+		if index < c.resumeIndex {
+			// completed before the client reconnected:
+			continue
+		}
+		switch command.(type) {
+		case commands.Add, commands.Copy, commands.Delete, commands.Run:
+			guard, err := commands.ParseGuard(commands.GuardOf(command))
+			if err != nil {
+				return errors.Wrap(ErrProtocolMismatch, err.Error())
+			}
+			if !guard.Matches(c.facts) {
+				if err := c.commandSkipped(index); err != nil {
+					return err
 				}
-				c.fetchedCommands = append(c.fetchedCommands, command)
-			} else {
-				c.logger.Warn("unexpected command received from grpc", "command", rawItem)
+				continue
 			}
+			indexed := IndexedCommand{Index: index, Command: command, Env: commands.EffectiveEnv(nil, deserialized, index)}
+			if len(response.Group) == len(response.Command) {
+				indexed.Group = int(response.Group[index])
+			}
+			c.fetchedCommands = append(c.fetchedCommands, indexed)
+		case commands.Arg:
+			// substituted into the commands by the server
+		case commands.Env, commands.From:
+			// applied to the environment of the commands of the stage
+		case nil:
+		default:
+			c.logger.Warn("unexpected command received from grpc", "command", response.Command[index])
 		}
 	}
 	return nil
 }
 
+// Debug serves an interactive debug session for the host by proxying
+// the input and output of a pty. Blocks until the host ends the session.
+func (c *defaultClient) Debug(pty io.ReadWriter) error {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	stream, err := c.underlying.Debug(ctx)
+	if err != nil {
+		return err
+	}
+
+	// pty output to the host:
+	go func() {
+		buffer := make([]byte, 32*1024)
+		for {
+			readBytes, err := pty.Read(buffer)
+			if readBytes > 0 {
+				data := make([]byte, readBytes)
+				copy(data, buffer[0:readBytes])
+				if sendErr := stream.Send(&proto.DebugFrame{Data: data}); sendErr != nil {
+					return
+				}
+			}
+			if err != nil {
+				stream.CloseSend()
+				return
+			}
+		}
+	}()
+
+	// host input to the pty:
+	for {
+		frame, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "debug session failed")
+		}
+		if _, err := pty.Write(frame.Data); err != nil {
+			return errors.Wrap(err, "failed writing to pty")
+		}
+	}
+}
+
+// DebugRequested returns true if the server asked the aborted client to serve a debug session.
+func (c *defaultClient) DebugRequested() bool {
+	return c.debugRequested
+}
+
+// Environment requests the ARG and ENV values in effect as of build start.
+func (c *defaultClient) Environment() (map[string]string, error) {
+	response, err := c.underlying.Environment(context.Background(), &proto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	if response.Env == nil {
+		return map[string]string{}, nil
+	}
+	return response.Env, nil
+}
+
 // NextCommand returns the next command to process, Commands() must be called first.
 func (c *defaultClient) NextCommand() commands.VMInitSerializableCommand {
 	if len(c.fetchedCommands) == 0 {
@@ -133,10 +397,26 @@ func (c *defaultClient) NextCommand() commands.VMInitSerializableCommand {
 	}
 	result := c.fetchedCommands[0]
 	if len(c.fetchedCommands) == 1 {
-		c.fetchedCommands = []commands.VMInitSerializableCommand{}
+		c.fetchedCommands = []IndexedCommand{}
 	} else {
 		c.fetchedCommands = c.fetchedCommands[1:]
 	}
+	return result.Command
+}
+
+// NextCommandGroup returns the next commands to process with their indexes, the commands of a parallel group together.
+func (c *defaultClient) NextCommandGroup() []IndexedCommand {
+	if len(c.fetchedCommands) == 0 {
+		return []IndexedCommand{}
+	}
+	count := 1
+	if group := c.fetchedCommands[0].Group; group != 0 {
+		for count < len(c.fetchedCommands) && c.fetchedCommands[count].Group == group {
+			count = count + 1
+		}
+	}
+	result := c.fetchedCommands[:count]
+	c.fetchedCommands = c.fetchedCommands[count:]
 	return result
 }
 
@@ -148,17 +428,36 @@ func (c *defaultClient) Ping() error {
 		return err
 	}
 	if response.Id != pingID {
-		return fmt.Errorf("ping response invalid")
+		return errors.Wrap(ErrProtocolMismatch, "ping response invalid")
 	}
 	return nil
 }
 
+// PortForward requests that a host address is proxied into the VM.
+func (c *defaultClient) PortForward(protocol, hostAddress string, guestPort int) (*PortForward, error) {
+	response, err := c.underlying.PortForward(context.Background(), &proto.PortForwardRequest{
+		Protocol:    protocol,
+		HostAddress: hostAddress,
+		GuestPort:   int32(guestPort),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PortForward{ID: response.Id, GuestAddress: response.GuestAddress}, nil
+}
+
+// PortForwardClose tells the server that a port forward is no longer needed.
+func (c *defaultClient) PortForwardClose(id string) error {
+	_, err := c.underlying.PortForwardClose(context.Background(), &proto.PortForwardCloseRequest{Id: id})
+	return err
+}
+
 // Resource loads the resource identified by a path from the server.
 func (c *defaultClient) Resource(input string) (chan interface{}, error) {
 
 	chanResources := make(chan interface{})
 
-	resourceClient, err := c.underlying.Resource(context.Background(), &proto.ResourceRequest{Path: input})
+	resourceClient, err := c.underlying.Resource(context.Background(), &proto.ResourceRequest{Path: input, AcceptCompression: acceptedCompression})
 	if err != nil {
 		return nil, err
 	}
@@ -166,19 +465,24 @@ func (c *defaultClient) Resource(input string) (chan interface{}, error) {
 	go func() {
 
 		var currentResource *grpcResolvedResource
+		sequence := &frameSequence{}
 
 	out:
 		for {
 			response, err := resourceClient.Recv()
 
-			if response == nil {
+			if err == io.EOF {
 				resourceClient.CloseSend()
 				break
 			}
 
-			// yes, err check after response check
 			if err != nil {
-				chanResources <- errors.Wrap(err, "failed reading chunk")
+				chanResources <- errors.Wrap(fromStatusError(err), "failed reading chunk")
+				break out
+			}
+
+			if err := sequence.next(response); err != nil {
+				chanResources <- err
 				break out
 			}
 
@@ -186,22 +490,31 @@ func (c *defaultClient) Resource(input string) (chan interface{}, error) {
 			case *proto.ResourceChunk_Eof:
 				chanResources <- currentResource
 			case *proto.ResourceChunk_Chunk:
-				hash := sha256.Sum256(tresponse.Chunk.Chunk)
-				if string(hash[:]) != string(tresponse.Chunk.Checksum) {
-					chanResources <- errors.Wrap(err, "chunk checksum did not match")
+				contents, err := chunkContents(tresponse.Chunk)
+				if err != nil {
+					chanResources <- err
 					break out
 				}
-				currentResource.contents.Grow(len(tresponse.Chunk.Chunk))
-				currentResource.contents.Write(tresponse.Chunk.Chunk)
+				currentResource.contents.Grow(len(contents))
+				currentResource.contents.Write(contents)
+			case *proto.ResourceChunk_Error:
+				chanResources <- fmt.Errorf("server failed streaming resource: %s", tresponse.Error.Message)
+				break out
 			case *proto.ResourceChunk_Header:
+				header, err := decodeResourceHeader(tresponse.Header)
+				if err != nil {
+					chanResources <- err
+					break out
+				}
 				currentResource = &grpcResolvedResource{
 					contents:      bytes.NewBuffer([]byte{}),
-					isDir:         tresponse.Header.IsDir,
-					sourcePath:    tresponse.Header.SourcePath,
-					targetMode:    fs.FileMode(tresponse.Header.FileMode),
-					targetPath:    tresponse.Header.TargetPath,
-					targetUser:    tresponse.Header.TargetUser,
-					targetWorkdir: tresponse.Header.TargetWorkdir,
+					isDir:         header.IsDir,
+					platform:      header.Platform,
+					sourcePath:    header.SourcePath,
+					targetMode:    header.TargetMode,
+					targetPath:    header.TargetPath,
+					targetUser:    header.TargetUser,
+					targetWorkdir: header.TargetWorkdir,
 				}
 			}
 		}
@@ -215,28 +528,120 @@ func (c *defaultClient) Resource(input string) (chan interface{}, error) {
 
 // StdErr sends stderr lines to the server.
 func (c *defaultClient) StdErr(input []string) error {
-	_, err := c.underlying.StdErr(context.Background(), &proto.LogMessage{Line: input})
-	return err
+	for _, batch := range c.logBatches(input) {
+		if _, err := c.underlying.StdErr(context.Background(), &proto.LogMessage{Line: batch}); err != nil {
+			return err
+		}
+		c.sentLines.add(LogStreamStderr, len(batch))
+	}
+	return nil
 }
 
 // StdOut sends stdout lines to the server.
 func (c *defaultClient) StdOut(input []string) error {
-	_, err := c.underlying.StdOut(context.Background(), &proto.LogMessage{Line: input})
-	return err
+	for _, batch := range c.logBatches(input) {
+		if _, err := c.underlying.StdOut(context.Background(), &proto.LogMessage{Line: batch}); err != nil {
+			return err
+		}
+		c.sentLines.add(LogStreamStdout, len(batch))
+	}
+	return nil
+}
+
+// logBatches returns the lines processed for sending, split in batches fitting in a message.
+func (c *defaultClient) logBatches(input []string) [][]string {
+	lines, truncatedLines, truncatedBytes := truncateLogLines(normalizeLogLines(c.logMode, input), c.maxLogLineBytes)
+	if truncatedLines > 0 {
+		c.logger.Warn("log lines truncated", "lines", truncatedLines, "bytes", truncatedBytes)
+	}
+	return batchLogLines(lines, c.maxLogMsgBytes)
 }
 
 // Success finishes the client with success.
+// With FsyncPolicyAtSuccess, the files written by the client are synced before the success is reported.
 func (c *defaultClient) Success() error {
+	if err := c.syncer.syncPending(); err != nil {
+		return err
+	}
 	_, err := c.underlying.Success(context.Background(), &proto.Empty{})
 	return err
 }
 
+// Warning reports warnings about a written resource to the server.
+func (c *defaultClient) Warning(path string, warnings []string) error {
+	_, err := c.underlying.Warning(context.Background(), &proto.WarningMessage{Path: path, Warning: warnings})
+	return err
+}
+
+// WatchCancel holds a watch stream open and returns a channel which receives
+// the reason when the server cancels the build. The channel is closed when the watch ends.
+func (c *defaultClient) WatchCancel() (<-chan error, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	watchClient, err := c.underlying.Watch(ctx, &proto.Empty{})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	chanCancel := make(chan error, 1)
+	go func() {
+		// the stream ends when the watch returns:
+		defer cancel()
+		defer close(chanCancel)
+		for {
+			event, err := watchClient.Recv()
+			if err != nil {
+				return
+			}
+			if cancel := event.GetCancel(); cancel != nil {
+				chanCancel <- fmt.Errorf("cancelled by server: %s", cancel.Reason)
+				return
+			}
+		}
+	}()
+	return chanCancel, nil
+}
+
+// WatchLogs holds a log stream open and returns a channel which receives
+// the stdout and stderr lines of the build. The channel is closed when the stream ends.
+func (c *defaultClient) WatchLogs() (<-chan LogLine, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	watchClient, err := c.underlying.WatchLogs(ctx, &proto.Empty{})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// lines sent after the header are delivered:
+	if _, err := watchClient.Header(); err != nil {
+		cancel()
+		return nil, fromStatusError(err)
+	}
+	chanLines := make(chan LogLine)
+	go func() {
+		// the stream ends when the watch returns:
+		defer cancel()
+		defer close(chanLines)
+		for {
+			line, err := watchClient.Recv()
+			if err != nil {
+				return
+			}
+			stream := LogStreamStdout
+			if line.Stream == proto.LogLine_STDERR {
+				stream = LogStreamStderr
+			}
+			chanLines <- LogLine{Stream: stream, Line: line.Line, Time: time.Unix(0, line.Timestamp), CommandID: line.CommandId}
+		}
+	}()
+	return chanLines, nil
+}
+
 // --
 // test resolved resource
 
 type grpcResolvedResource struct {
 	contents      *bytes.Buffer
 	isDir         bool
+	platform      string
 	sourcePath    string
 	targetMode    fs.FileMode
 	targetPath    string
@@ -252,6 +657,10 @@ func (r *grpcResolvedResource) IsDir() bool {
 	return r.isDir
 }
 
+func (r *grpcResolvedResource) Platform() string {
+	return r.platform
+}
+
 func (r *grpcResolvedResource) ResolvedURIOrPath() string {
 	return fmt.Sprintf("grpc://%s", r.sourcePath)
 }
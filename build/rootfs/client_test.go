@@ -33,6 +33,8 @@ func TestClientHandlesStoppedServer(t *testing.T) {
 	// test client:
 	assert.NotNil(t, testClient.Abort(fmt.Errorf("")))
 	assert.NotNil(t, testClient.Commands())
+	_, envErr := testClient.Environment()
+	assert.NotNil(t, envErr)
 	assert.NotNil(t, testClient.Ping())
 	_, resourceErr := testClient.Resource("irrelevant")
 	assert.NotNil(t, resourceErr)
@@ -41,6 +43,146 @@ func TestClientHandlesStoppedServer(t *testing.T) {
 	assert.NotNil(t, testClient.Success())
 }
 
+func TestClientReceivesEnvironment(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+		Args:               map[string]string{"VERSION": "1.0.0", "SHARED": "arg"},
+		Env:                map[string]string{"PATH": "/usr/bin:/bin", "SHARED": "env"},
+	}
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	environment, err := testClient.Environment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"VERSION": "1.0.0",
+		"PATH":    "/usr/bin:/bin",
+		"SHARED":  "env",
+	}, environment)
+
+	assert.Nil(t, testClient.Success())
+
+	<-testServer.FinishedNotify()
+}
+
+func TestClientPortForward(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	}
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	portForward, err := testClient.PortForward("tcp", "127.0.0.1:3142", 3142)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:3142", portForward.GuestAddress)
+	assert.Nil(t, testClient.PortForwardClose(portForward.ID))
+	assert.NotNil(t, testClient.PortForwardClose(portForward.ID))
+
+	assert.Nil(t, testClient.Success())
+
+	<-testServer.FinishedNotify()
+	assert.Equal(t, []string{"127.0.0.1:3142"}, testServer.PortForwards())
+}
+
+func TestClientReceivesCancellation(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	}
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	chanCancel, err := testClient.WatchCancel()
+	assert.Nil(t, err)
+
+	testServer.Cancel(fmt.Errorf("job cancelled"))
+
+	cancelErr := <-chanCancel
+	assert.NotNil(t, cancelErr)
+	assert.Contains(t, cancelErr.Error(), "job cancelled")
+
+	assert.Nil(t, testClient.Abort(cancelErr))
+
+	<-testServer.FinishedNotify()
+}
+
+func TestClientResourcesResolvedFromDirectory(t *testing.T) {
+
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, ".dockerignore"), []byte("b.txt\n**/*.log\n"))
+	MustPutTestResource(t, filepath.Join(tempDir, "a.txt"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(tempDir, "b.txt"), []byte("b"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/c.txt"), []byte("c"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/ignored.log"), []byte("ignored"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Copy{
+			OriginalCommand: "COPY *.txt /app/",
+			OriginalSource:  "*.txt",
+			Source:          "*.txt",
+			Target:          "/app/",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Copy{
+			OriginalCommand: "COPY sub /app/sub",
+			OriginalSource:  "sub",
+			Source:          "sub",
+			Target:          "/app/sub",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+
+	resolved, err := ResolveFromDirectory(tempDir, executableCommands)
+	assert.Nil(t, err)
+
+	_, err = ResolveFromDirectory(tempDir, []commands.VMInitSerializableCommand{
+		commands.Copy{OriginalCommand: "COPY ../etc/passwd /", Source: "../etc/passwd", Target: "/"},
+	})
+	assert.NotNil(t, err, "expected resolution outside of the context to fail")
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	buildCtx := &WorkContext{
+		ExecutableCommands: executableCommands,
+		ResourcesResolved:  resolved,
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Commands())
+
+	MustBeCopyCommand(t, testClient, []byte("a"))
+	// the directory itself and the only file not ignored:
+	MustBeCopyCommand(t, testClient, []byte{}, []byte("c"))
+
+	// individual entries of the directory resource:
+	MustReadResources(t, testClient, "sub/c.txt", []byte("c"))
+	for _, invalidPath := range []string{"sub/ignored.log", "sub/../b.txt", "sub/missing"} {
+		resourceChannel, err := testClient.Resource(invalidPath)
+		assert.Nil(t, err)
+		_, isErr := (<-resourceChannel).(error)
+		assert.True(t, isErr, "expected an error for '%s'", invalidPath)
+	}
+
+	assert.Nil(t, testClient.Success())
+
+	<-testServer.FinishedNotify()
+}
+
 func TestClientHandlesLargeFiles(t *testing.T) {
 
 	tempDir, err := ioutil.TempDir("", "")
@@ -173,3 +315,59 @@ func getLargeFileContent(t *testing.T, n int64) []byte {
 	}
 	return bs
 }
+
+func TestClientReceivesPlatformVariants(t *testing.T) {
+
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amd64Content := []byte("amd64 binary")
+	arm64Content := []byte("arm64 binary")
+
+	variant := func(platform string, contents []byte) resources.ResolvedResource {
+		return resources.NewPlatformVariant(platform,
+			resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(contents)), nil
+			},
+				fs.FileMode(0755),
+				"binary",
+				"/usr/bin/binary",
+				commands.Workdir{Value: tempDir},
+				commands.DefaultUser(),
+				filepath.Join(tempDir, "binary")))
+	}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.Copy{
+				OriginalCommand: "COPY binary /usr/bin/binary",
+				OriginalSource:  "binary",
+				Source:          "binary",
+				Target:          "/usr/bin/binary",
+				User:            commands.DefaultUser(),
+				Workdir:         commands.Workdir{Value: tempDir},
+			},
+		},
+		ResourcesResolved: Resources{
+			"binary": []resources.ResolvedResource{
+				variant("linux/amd64", amd64Content),
+				variant("linux/arm64", arm64Content),
+			},
+		},
+		Platform: "linux/arm64",
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Commands())
+
+	MustBeCopyCommand(t, testClient, arm64Content)
+
+	assert.Nil(t, testClient.Success())
+
+	<-testServer.FinishedNotify()
+}
@@ -0,0 +1,166 @@
+package rootfs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CollisionPolicy defines how target path collisions detected during resolution are handled.
+// Two target paths collide when they differ but are equal after Unicode NFC normalization
+// and case folding, for example a context created on macOS with NFD names.
+type CollisionPolicy int
+
+const (
+	// CollisionPolicyIgnore does not detect collisions.
+	CollisionPolicyIgnore CollisionPolicy = iota
+	// CollisionPolicyError fails the resolution on the first collision.
+	CollisionPolicyError
+	// CollisionPolicyWarn logs a warning for every collision.
+	CollisionPolicyWarn
+	// CollisionPolicyRename serves colliding entries under a new, unique target name.
+	CollisionPolicyRename
+)
+
+// TargetCollision describes a target path colliding with a previously resolved target path.
+type TargetCollision struct {
+	// Existing is the target path resolved first.
+	Existing string
+	// Colliding is the target path colliding with the existing one.
+	Colliding string
+}
+
+func (c TargetCollision) Error() string {
+	return fmt.Sprintf("target path '%s' collides with '%s'", c.Colliding, c.Existing)
+}
+
+// DetectCollisions checks the target paths of resolved resources for collisions and applies the policy.
+// Directory resources are walked to check every entry. Resources are checked in the order of their keys.
+// With CollisionPolicyRename, the returned resources serve colliding entries under renamed targets.
+func DetectCollisions(ress Resources, policy CollisionPolicy, logger hclog.Logger) (Resources, error) {
+	if policy == CollisionPolicyIgnore {
+		return ress, nil
+	}
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	detector := &collisionDetector{seen: map[string]string{}}
+
+	keys := []string{}
+	for key := range ress {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := Resources{}
+	for _, key := range keys {
+		result[key] = []resources.ResolvedResource{}
+		for _, resource := range ress[key] {
+			if !resource.IsDir() {
+				renamed, collision := detector.check(resource.TargetPath())
+				if collision != nil {
+					switch policy {
+					case CollisionPolicyError:
+						return nil, collision
+					case CollisionPolicyWarn:
+						logger.Warn("target path collision", "source", resource.SourcePath(), "reason", collision)
+					case CollisionPolicyRename:
+						logger.Warn("target path collision, renaming", "source", resource.SourcePath(), "renamed", renamed, "reason", collision)
+						resource = resources.NewRetargetedResource(renamed, resource)
+					}
+				}
+				result[key] = append(result[key], resource)
+				continue
+			}
+
+			renames := map[string]string{}
+			filter := resources.FilterOf(resource)
+			walkErr := filepath.WalkDir(resource.ResolvedURIOrPath(), func(entryPath string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				relativePath, err := filepath.Rel(resource.ResolvedURIOrPath(), entryPath)
+				if err != nil {
+					return err
+				}
+				relativePath = filepath.ToSlash(relativePath)
+				if relativePath == "." {
+					relativePath = ""
+				}
+				if relativePath != "" && filter != nil && filter.Excluded(relativePath) {
+					if d.IsDir() && !filter.DescendExcluded() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				// entries of renamed directories are served under the renamed parent:
+				targetRelativePath := relativePath
+				if parent := path.Dir(relativePath); parent != "." {
+					if renamedParent, ok := renames[parent]; ok {
+						targetRelativePath = path.Join(renamedParent, path.Base(relativePath))
+					}
+				}
+				renamed, collision := detector.check(path.Join(resource.TargetPath(), targetRelativePath))
+				if collision != nil {
+					switch policy {
+					case CollisionPolicyError:
+						return collision
+					case CollisionPolicyWarn:
+						logger.Warn("target path collision", "source", entryPath, "reason", collision)
+					case CollisionPolicyRename:
+						logger.Warn("target path collision, renaming", "source", entryPath, "renamed", renamed, "reason", collision)
+						targetRelativePath = strings.TrimPrefix(strings.TrimPrefix(renamed, resource.TargetPath()), "/")
+					}
+				}
+				if targetRelativePath != relativePath {
+					renames[relativePath] = targetRelativePath
+				}
+				return nil
+			})
+			if walkErr != nil {
+				return nil, walkErr
+			}
+			if len(renames) > 0 {
+				resource = resources.NewRenamingResource(renames, resource)
+			}
+			result[key] = append(result[key], resource)
+		}
+	}
+	return result, nil
+}
+
+type collisionDetector struct {
+	seen map[string]string
+}
+
+// check registers a target path and returns a collision if an equivalent but different path was registered before.
+// For a collision, returns a unique renamed target path which does not collide.
+func (d *collisionDetector) check(targetPath string) (string, *TargetCollision) {
+	key := collisionKey(targetPath)
+	existing, ok := d.seen[key]
+	if !ok || existing == targetPath {
+		d.seen[key] = targetPath
+		return targetPath, nil
+	}
+	extension := path.Ext(targetPath)
+	base := strings.TrimSuffix(targetPath, extension)
+	for i := 1; ; i++ {
+		renamed := fmt.Sprintf("%s~%d%s", base, i, extension)
+		if _, taken := d.seen[collisionKey(renamed)]; !taken {
+			d.seen[collisionKey(renamed)] = renamed
+			return renamed, &TargetCollision{Existing: existing, Colliding: targetPath}
+		}
+	}
+}
+
+func collisionKey(targetPath string) string {
+	return strings.ToLower(norm.NFC.String(targetPath))
+}
@@ -0,0 +1,58 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDetectsCollisions(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// NFD and NFC forms of the same name and names differing only in case:
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/cafe\u0301.txt"), []byte("nfd"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/caf\u00e9.txt"), []byte("nfc"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/Docs/a"), []byte("upper"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/docs/b"), []byte("lower"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Copy{
+			OriginalCommand: "COPY sub /app",
+			OriginalSource:  "sub",
+			Source:          "sub",
+			Target:          "/app",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+
+	_, err = ResolveFromDirectory(tempDir, executableCommands)
+	assert.Nil(t, err, "expected collisions to be ignored by default")
+
+	_, err = ResolveFromDirectoryWithOptions(tempDir, executableCommands, &ResolveOptions{CollisionPolicy: CollisionPolicyError})
+	_, isCollision := err.(*TargetCollision)
+	assert.True(t, isCollision, "expected a collision error, got: %v", err)
+
+	resolved, err := ResolveFromDirectoryWithOptions(tempDir, executableCommands, &ResolveOptions{CollisionPolicy: CollisionPolicyRename})
+	assert.Nil(t, err)
+	walked := mustWalkTargetPaths(t, NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+		SafeBufferSize: 1024,
+		Sorted:         true,
+	}, resolved["sub"][0]))
+
+	assert.Equal(t, []string{
+		"/app",
+		"/app/Docs",
+		"/app/Docs/a",
+		"/app/cafe\u0301.txt",
+		"/app/caf\u00e9~1.txt",
+		"/app/docs~1",
+		"/app/docs~1/b",
+	}, walked)
+}
@@ -0,0 +1,38 @@
+package rootfs
+
+import "github.com/combust-labs/firebuild-shared/build/commands"
+
+// ensureCommandIDs derives the IDs of the executable commands of the work context,
+// unless the work context has an ID for every command.
+func ensureCommandIDs(serverCtx *WorkContext) {
+	if len(serverCtx.CommandIDs) != len(serverCtx.ExecutableCommands) {
+		serverCtx.CommandIDs = commands.IDs(serverCtx.substitutedCommands())
+	}
+}
+
+// commandID returns the ID of the command at the index, empty for an index out of range.
+func (impl *serverImpl) commandID(index int) string {
+	if index < 0 || index >= len(impl.serverCtx.CommandIDs) {
+		return ""
+	}
+	return impl.serverCtx.CommandIDs[index]
+}
+
+// runningCommandID returns the ID of the command the client started and has not finished,
+// empty if none or if the client runs several commands of a parallel group.
+func (impl *serverImpl) runningCommandID() string {
+	impl.m.Lock()
+	defer impl.m.Unlock()
+	return impl.runningCommandIDLocked()
+}
+
+// runningCommandIDLocked returns the ID of the running command, the lock must be held.
+func (impl *serverImpl) runningCommandIDLocked() string {
+	if len(impl.runningCommands) != 1 {
+		return ""
+	}
+	for index := range impl.runningCommands {
+		return impl.commandID(index)
+	}
+	return ""
+}
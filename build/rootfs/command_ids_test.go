@@ -0,0 +1,95 @@
+package rootfs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandIDsCorrelation(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	serverCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+			commands.RunWithDefaults("false"),
+		},
+		ResourcesResolved: make(Resources),
+	}
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, serverCtx)
+	defer srv.Stop()
+
+	ids := commands.IDs(serverCtx.ExecutableCommands)
+	assert.Equal(t, ids, serverCtx.CommandIDs)
+
+	chanMessages := make(chan interface{}, 16)
+	go func() {
+		for message := range srv.OnMessage() {
+			chanMessages <- message
+		}
+	}()
+	chanLines, unsubscribe := srv.SubscribeLogs()
+	defer unsubscribe()
+
+	assert.Nil(t, testClient.StdOut([]string{"before"}))
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.StdOut([]string{"first"}))
+	assert.Nil(t, testClient.CommandFinished(0, nil))
+	assert.Nil(t, testClient.CommandStarted(1))
+	assert.Nil(t, testClient.StdErr([]string{"second"}))
+	assert.Nil(t, testClient.Abort(fmt.Errorf("command failed")))
+
+	assert.Equal(t, &ClientMsgStdout{Lines: []string{"before"}}, <-chanMessages)
+	assert.Equal(t, &ClientMsgCommandStarted{Index: 0, CommandID: ids[0]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgStdout{Lines: []string{"first"}, CommandID: ids[0]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgCommandFinished{Index: 0, CommandID: ids[0], Attempts: 1}, <-chanMessages)
+	assert.Equal(t, &ClientMsgCommandStarted{Index: 1, CommandID: ids[1]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgStderr{Lines: []string{"second"}, CommandID: ids[1]}, <-chanMessages)
+	aborted, ok := (<-chanMessages).(*ClientMsgAborted)
+	assert.True(t, ok)
+	assert.Equal(t, ids[1], aborted.CommandID)
+
+	for _, expected := range []string{"", ids[0], ids[1]} {
+		line := <-chanLines
+		assert.Equal(t, expected, line.CommandID)
+	}
+}
+
+func TestCommandIDsGiven(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{ObserverClients: 1}
+	serverCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		CommandIDs:        []string{"step-1"},
+		ResourcesResolved: make(Resources),
+	}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, serverCtx)
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	observerClient, err := NewClient(logger.Named("grpc-observer"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigObserverClients[0],
+	})
+	assert.Nil(t, err)
+	chanRemote, err := observerClient.WatchLogs()
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.StdOut([]string{"output"}))
+
+	line := <-chanRemote
+	assert.Equal(t, "output", line.Line)
+	assert.Equal(t, "step-1", line.CommandID)
+}
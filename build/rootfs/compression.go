@@ -0,0 +1,97 @@
+package rootfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+// acceptedCompression is sent by the client with every resource request.
+var acceptedCompression = []string{CompressionGzip}
+
+// gzipWriters are reused across chunks, a gzip writer allocates several hundred kilobytes.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return writer
+	},
+}
+
+// compressionThreshold returns the minimum size of a chunk compressed for a client accepting the compressions,
+// zero when the chunks are sent uncompressed.
+func (impl *serverImpl) compressionThreshold(accepted []string) int {
+	if !containsString(accepted, CompressionGzip) {
+		return 0
+	}
+	return impl.serviceConfig.CompressionThreshold
+}
+
+// compressContents gzips the chunk of the contents in place when the chunk has at least threshold bytes
+// and the compressed chunk is smaller. The checksum remains the checksum of the uncompressed chunk.
+// Contents already compressed, for example a chunk stored compressed by the spool, are left as they are.
+func compressContents(contents *proto.ResourceChunk_ResourceContents, threshold int) {
+	if threshold <= 0 || len(contents.Chunk) < threshold || contents.Compression != "" {
+		return
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, len(contents.Chunk)/2))
+	writer := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(writer)
+	writer.Reset(buffer)
+	if _, err := writer.Write(contents.Chunk); err != nil {
+		return
+	}
+	if err := writer.Close(); err != nil {
+		return
+	}
+	if buffer.Len() >= len(contents.Chunk) {
+		// incompressible, for example an archive:
+		return
+	}
+	contents.Size = int64(len(contents.Chunk))
+	contents.Chunk = buffer.Bytes()
+	contents.Compression = CompressionGzip
+}
+
+// contentsSize returns the uncompressed size of the chunk of the contents.
+func contentsSize(contents *proto.ResourceChunk_ResourceContents) int {
+	if contents.Compression == CompressionGzip {
+		return int(contents.Size)
+	}
+	return len(contents.Chunk)
+}
+
+// chunkContents returns the uncompressed chunk of the contents received by the client
+// after verifying the checksum of the chunk.
+func chunkContents(contents *proto.ResourceChunk_ResourceContents) ([]byte, error) {
+	data := contents.Chunk
+	switch contents.Compression {
+	case "", CompressionIdentity:
+	case CompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(contents.Chunk))
+		if err != nil {
+			return nil, errors.Wrapf(ErrProtocolMismatch, "chunk of resource '%s' not gzipped: %v", contents.Id, err)
+		}
+		// a chunk never decompresses to more than its declared size:
+		data, err = ioutil.ReadAll(io.LimitReader(reader, contents.Size+1))
+		if err != nil {
+			return nil, errors.Wrapf(ErrProtocolMismatch, "failed decompressing chunk of resource '%s': %v", contents.Id, err)
+		}
+		if int64(len(data)) != contents.Size {
+			return nil, errors.Wrapf(ErrProtocolMismatch, "chunk of resource '%s' decompressed to %d bytes, expected %d",
+				contents.Id, len(data), contents.Size)
+		}
+	default:
+		return nil, errors.Wrapf(ErrProtocolMismatch, "chunk of resource '%s' has unsupported compression '%s'", contents.Id, contents.Compression)
+	}
+	hash := sha256.Sum256(data)
+	if string(hash[:]) != string(contents.Checksum) {
+		return nil, errors.Wrapf(ErrChecksumMismatch, "chunk of resource '%s'", contents.Id)
+	}
+	return data, nil
+}
@@ -0,0 +1,251 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustContents(t *testing.T, data []byte) *proto.ResourceChunk_ResourceContents {
+	hash := sha256.Sum256(data)
+	return &proto.ResourceChunk_ResourceContents{Chunk: data, Checksum: hash[:], Id: "id"}
+}
+
+func TestChunkCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible "), 1024)
+
+	contents := mustContents(t, data)
+	compressContents(contents, len(data))
+	assert.Equal(t, CompressionGzip, contents.Compression)
+	assert.Equal(t, int64(len(data)), contents.Size)
+	assert.Less(t, len(contents.Chunk), len(data))
+	assert.Equal(t, len(data), contentsSize(contents))
+	decompressed, err := chunkContents(contents)
+	assert.Nil(t, err)
+	assert.Equal(t, data, decompressed)
+
+	below := mustContents(t, data)
+	compressContents(below, len(data)+1)
+	assert.Empty(t, below.Compression, "expected a chunk below the threshold uncompressed")
+	disabled := mustContents(t, data)
+	compressContents(disabled, 0)
+	assert.Empty(t, disabled.Compression, "expected no compression without a threshold")
+
+	random := make([]byte, 4096)
+	_, err = rand.Read(random)
+	assert.Nil(t, err)
+	incompressible := mustContents(t, random)
+	compressContents(incompressible, 1)
+	assert.Empty(t, incompressible.Compression, "expected an incompressible chunk uncompressed")
+	assert.Equal(t, random, incompressible.Chunk)
+
+	// a chunk decompressing to a different size than declared is rejected:
+	oversized := mustContents(t, data)
+	compressContents(oversized, 1)
+	oversized.Size = oversized.Size - 1
+	_, err = chunkContents(oversized)
+	assert.True(t, errors.Is(err, ErrProtocolMismatch))
+
+	unknown := mustContents(t, data)
+	unknown.Compression = "zstd"
+	_, err = chunkContents(unknown)
+	assert.True(t, errors.Is(err, ErrProtocolMismatch))
+
+	corrupted := mustContents(t, data)
+	compressContents(corrupted, 1)
+	corrupted.Checksum[0] = corrupted.Checksum[0] + 1
+	_, err = chunkContents(corrupted)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "expected the checksum of the decompressed chunk verified")
+}
+
+func TestServerCompressesChunks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+	total := int64(0)
+	for _, contents := range expected {
+		total = total + int64(len(contents))
+	}
+	fileContents := bytes.Repeat([]byte("compressible file "), 64*1024)
+	MustPutTestResource(t, filepath.Join(tempDir, "file"), fileContents)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{CompressionThreshold: 256}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return os.Open(filepath.Join(tempDir, "file"))
+				}, 0644, "file", "/opt/file", commands.DefaultWorkdir(), commands.DefaultUser(), filepath.Join(tempDir, "file")),
+			},
+		},
+	})
+	defer testServer.Stop()
+	chanServed := make(chan *ControlMsgResourceServed, 16)
+	go func() {
+		for {
+			select {
+			case event := <-testServer.OnMessage():
+				if tevent, ok := event.(*ControlMsgResourceServed); ok {
+					chanServed <- tevent
+				}
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	capabilities, err := testClient.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{CompressionIdentity, CompressionGzip}, capabilities.Compression)
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, &WriteOptions{Multiplex: 4}))
+	for name, contents := range expected {
+		written, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir", name))
+		assert.Nil(t, err)
+		assert.Equal(t, contents, written, "expected the decompressed contents of "+name)
+	}
+	select {
+	case served := <-chanServed:
+		assert.Equal(t, total, served.Bytes, "expected the uncompressed bytes counted")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the resource served")
+	}
+
+	reader, _, err := testClient.OpenResource(context.Background(), "file")
+	assert.Nil(t, err)
+	read, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, fileContents, read)
+
+	// the walker compresses only when asked to:
+	for _, threshold := range []int{0, 256} {
+		chanChunks := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			CompressionThreshold: threshold,
+			SafeBufferSize:       4096,
+		}, resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+			commands.DefaultWorkdir(), commands.DefaultUser())).WalkResource()
+		compressed := 0
+		for frame := range chanChunks {
+			if frame == nil {
+				break
+			}
+			if contents := frame.GetChunk(); contents != nil && contents.Compression == CompressionGzip {
+				compressed = compressed + 1
+			}
+		}
+		if threshold == 0 {
+			assert.Equal(t, 0, compressed)
+		} else {
+			assert.Greater(t, compressed, 0)
+		}
+	}
+}
+
+func TestServerSendsSpooledChunksCompressed(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	fileContents := bytes.Repeat([]byte("compressible file "), 16*1024)
+	MustPutTestResource(t, filepath.Join(tempDir, "dir/file"), fileContents)
+	spool, err := NewSpool(&SpoolConfig{ChunkSize: 64 * 1024, Compress: true})
+	assert.Nil(t, err)
+	defer spool.Remove()
+
+	// the walker sends the stored chunks only to a client accepting them, without compressing again:
+	for _, accepted := range [][]string{nil, {CompressionGzip}} {
+		chanChunks := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			AcceptCompression: accepted,
+			SafeBufferSize:    128 * 1024,
+			Spool:             spool,
+		}, resources.NewResolvedDirectoryResourceWithPath(0755, filepath.Join(tempDir, "dir"), "dir", "/opt/dir",
+			commands.DefaultWorkdir(), commands.DefaultUser())).WalkResource()
+		received := []byte{}
+		for frame := range chanChunks {
+			if frame == nil {
+				break
+			}
+			contents := frame.GetChunk()
+			if contents == nil {
+				continue
+			}
+			if accepted == nil {
+				assert.Empty(t, contents.Compression)
+			} else {
+				assert.Equal(t, CompressionGzip, contents.Compression, "expected the stored chunk")
+				assert.LessOrEqual(t, contents.Size, int64(64*1024), "expected the uncompressed size of the stored chunk")
+				assert.Less(t, int64(len(contents.Chunk)), contents.Size)
+			}
+			chunk, err := chunkContents(contents)
+			assert.Nil(t, err)
+			received = append(received, chunk...)
+		}
+		assert.Equal(t, fileContents, received)
+	}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return os.Open(filepath.Join(tempDir, "dir/file"))
+				}, 0644, "file", "/opt/file", commands.DefaultWorkdir(), commands.DefaultUser(), filepath.Join(tempDir, "dir/file")),
+			},
+		},
+		Spool: spool,
+	})
+	defer testServer.Stop()
+	chanServed := make(chan *ControlMsgResourceServed, 16)
+	go func() {
+		for {
+			select {
+			case event := <-testServer.OnMessage():
+				if tevent, ok := event.(*ControlMsgResourceServed); ok {
+					chanServed <- tevent
+				}
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	reader, _, err := testClient.OpenResource(context.Background(), "file")
+	assert.Nil(t, err)
+	read, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, fileContents, read)
+	select {
+	case served := <-chanServed:
+		assert.Equal(t, int64(len(fileContents)), served.Bytes, "expected the uncompressed bytes counted")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the resource served")
+	}
+}
@@ -0,0 +1,327 @@
+package rootfs
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
+)
+
+// ErrConnClosed is returned when a client calls the server over a closed connection.
+var ErrConnClosed = errors.New("connection closed")
+
+// Conn is a single connection to the server shared by the subsystems of a guest,
+// for example the command executor, the resource fetcher, the log forwarder and the heartbeat.
+// Every subsystem calls the server with its own client obtained from Client.
+// Close closes the connection once the calls in flight have finished.
+type Conn struct {
+	m      sync.Mutex
+	closed bool
+	active int
+	idle   chan struct{}
+
+	cfg      *GRPCClientConfig
+	logger   hclog.Logger
+	grpcConn *grpc.ClientConn
+	stats    *connStats
+}
+
+// ConnStats contains the call counters of a connection,
+// the counters of the channelz channel data of the connection.
+type ConnStats struct {
+	// State is the connectivity state of the connection, for example READY.
+	State string
+	// CallsStarted is the number of calls started on the connection.
+	CallsStarted int64
+	// CallsSucceeded is the number of calls finished with OK.
+	CallsSucceeded int64
+	// CallsFailed is the number of calls finished with an error.
+	CallsFailed int64
+	// LastCallStartedAt is the time the last call started, zero if no call started.
+	LastCallStartedAt time.Time
+	// Subsystems contains the counters of the calls of every subsystem, keyed by the subsystem name.
+	Subsystems map[string]SubsystemStats
+}
+
+// SubsystemStats contains the call counters of a single subsystem sharing a connection.
+type SubsystemStats struct {
+	// CallsStarted is the number of calls the subsystem started.
+	CallsStarted int64
+	// CallsSucceeded is the number of calls of the subsystem finished with OK.
+	CallsSucceeded int64
+	// CallsFailed is the number of calls of the subsystem finished with an error.
+	CallsFailed int64
+	// BytesSent is the number of payload bytes the subsystem sent.
+	BytesSent int64
+	// BytesReceived is the number of payload bytes the subsystem received.
+	BytesReceived int64
+}
+
+// DialConn connects to the server. The connection is established lazily, on the first call.
+func DialConn(logger hclog.Logger, cfg *GRPCClientConfig) (*Conn, error) {
+	cfg = cfg.WithDefaultsApplied()
+	if err := validateWireVersion(cfg.WireVersion); err != nil {
+		return nil, err
+	}
+	dial, hostPort, err := transportDialer(cfg.HostPort)
+	if err != nil && cfg.Dialer == nil {
+		return nil, err
+	}
+	if cfg.Dialer != nil {
+		dial = cfg.Dialer
+	}
+	connStats := &connStats{subsystems: map[string]SubsystemStats{}}
+	dialOptions := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize)),
+		grpc.WithUnaryInterceptor(statusErrorUnaryClientInterceptor),
+		grpc.WithTransportCredentials(credentials.NewTLS(cfg.TLSConfig)),
+		grpc.WithStatsHandler(connStats),
+	}
+	if cfg.KeepaliveInterval > 0 {
+		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.KeepaliveInterval,
+			Timeout:             cfg.KeepaliveInterval,
+			PermitWithoutStream: true,
+		}))
+	}
+	if cfg.BuildID != "" {
+		dialOptions = append(dialOptions,
+			grpc.WithChainUnaryInterceptor(buildIDUnaryClientInterceptor(cfg.BuildID)),
+			grpc.WithChainStreamInterceptor(buildIDStreamClientInterceptor(cfg.BuildID)))
+	}
+	if cfg.GuestID != "" {
+		dialOptions = append(dialOptions,
+			grpc.WithChainUnaryInterceptor(guestIDUnaryClientInterceptor(cfg.GuestID)),
+			grpc.WithChainStreamInterceptor(guestIDStreamClientInterceptor(cfg.GuestID)))
+	}
+	if len(cfg.Facts) > 0 {
+		dialOptions = append(dialOptions,
+			grpc.WithChainStreamInterceptor(factsStreamClientInterceptor(cfg.Facts)))
+	}
+	negotiator := newWireVersionNegotiator(cfg.WireVersion)
+	dialOptions = append(dialOptions,
+		grpc.WithChainUnaryInterceptor(negotiator.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(negotiator.streamInterceptor))
+	if dial != nil {
+		dialOptions = append(dialOptions, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dial(ctx, address, cfg.TLSConfig)
+		}))
+	}
+	grpcConn, err := grpc.Dial(hostPort, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		cfg:      cfg,
+		logger:   logger,
+		grpcConn: grpcConn,
+		stats:    connStats,
+	}, nil
+}
+
+// Client returns a client of a subsystem calling the server over the connection.
+// The calls of the client are counted under the subsystem name in Stats.
+func (c *Conn) Client(subsystem string) ClientProvider {
+	return c.newClient(c.logger.Named(subsystem), subsystem)
+}
+
+func (c *Conn) newClient(logger hclog.Logger, subsystem string) *defaultClient {
+	return &defaultClient{
+		buildID:         c.cfg.BuildID,
+		facts:           c.cfg.Facts,
+		logger:          logger,
+		fetchLogger:     c.cfg.LogLevels.logger(logger.Named("fetch"), LogSubsystemClientFetch),
+		logMode:         c.cfg.LogMode,
+		maxLogLineBytes: c.cfg.MaxLogLineBytes,
+		maxLogMsgBytes:  int(float32(c.cfg.MaxRecvMsgSize) * 0.9),
+		sentLines:       &sentLogLines{},
+		syncer:          newFileSyncer(c.cfg.FsyncPolicy, c.cfg.FsyncIntervalBytes),
+		underlying:      proto.NewRootfsServerClient(&subsystemConn{conn: c, subsystem: subsystem}),
+	}
+}
+
+// ClientConn returns the underlying gRPC connection, for example to call other services served on the same port.
+// Calls made on the underlying connection directly are not awaited by Close.
+func (c *Conn) ClientConn() *grpc.ClientConn {
+	return c.grpcConn
+}
+
+// Stats returns the call counters of the connection.
+func (c *Conn) Stats() ConnStats {
+	result := c.stats.snapshot()
+	result.State = c.grpcConn.GetState().String()
+	return result
+}
+
+// Close rejects new calls with ErrConnClosed, waits until the calls in flight have finished
+// and closes the connection. When the context is done first, the calls in flight are cancelled
+// and the context error is returned.
+func (c *Conn) Close(ctx context.Context) error {
+	c.m.Lock()
+	if c.closed {
+		c.m.Unlock()
+		return nil
+	}
+	c.closed = true
+	idle := make(chan struct{})
+	if c.active == 0 {
+		close(idle)
+	} else {
+		c.idle = idle
+	}
+	c.m.Unlock()
+
+	var waitErr error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		c.logger.Warn("connection closed with calls in flight", "reason", ctx.Err())
+		waitErr = ctx.Err()
+	}
+	if err := c.grpcConn.Close(); err != nil {
+		return err
+	}
+	return waitErr
+}
+
+func (c *Conn) acquire() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.closed {
+		return ErrConnClosed
+	}
+	c.active = c.active + 1
+	return nil
+}
+
+func (c *Conn) release() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.active = c.active - 1
+	if c.active == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
+
+type subsystemKey struct{}
+
+// subsystemConn calls the server over the shared connection on behalf of a subsystem.
+type subsystemConn struct {
+	conn      *Conn
+	subsystem string
+}
+
+func (s *subsystemConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	if err := s.conn.acquire(); err != nil {
+		return err
+	}
+	defer s.conn.release()
+	return s.conn.grpcConn.Invoke(context.WithValue(ctx, subsystemKey{}, s.subsystem), method, args, reply, opts...)
+}
+
+func (s *subsystemConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := s.conn.acquire(); err != nil {
+		return nil, err
+	}
+	stream, err := s.conn.grpcConn.NewStream(context.WithValue(ctx, subsystemKey{}, s.subsystem), desc, method, opts...)
+	if err != nil {
+		s.conn.release()
+		return nil, err
+	}
+	releasing := &releasingClientStream{ClientStream: stream, release: s.conn.release}
+	go func() {
+		// a stream abandoned by the caller ends with its context:
+		<-stream.Context().Done()
+		releasing.once.Do(releasing.release)
+	}()
+	return releasing, nil
+}
+
+// releasingClientStream releases the connection when the stream ends: when receiving fails
+// or the context of the stream is done.
+type releasingClientStream struct {
+	grpc.ClientStream
+	once    sync.Once
+	release func()
+}
+
+func (s *releasingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(s.release)
+	}
+	return err
+}
+
+// connStats counts the calls of a connection.
+type connStats struct {
+	m                 sync.Mutex
+	callsStarted      int64
+	callsSucceeded    int64
+	callsFailed       int64
+	lastCallStartedAt time.Time
+	subsystems        map[string]SubsystemStats
+}
+
+func (s *connStats) snapshot() ConnStats {
+	s.m.Lock()
+	defer s.m.Unlock()
+	result := ConnStats{
+		CallsStarted:      s.callsStarted,
+		CallsSucceeded:    s.callsSucceeded,
+		CallsFailed:       s.callsFailed,
+		LastCallStartedAt: s.lastCallStartedAt,
+		Subsystems:        map[string]SubsystemStats{},
+	}
+	for subsystem, subsystemStats := range s.subsystems {
+		result.Subsystems[subsystem] = subsystemStats
+	}
+	return result
+}
+
+func (s *connStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s *connStats) HandleRPC(ctx context.Context, rpcStats stats.RPCStats) {
+	subsystem, _ := ctx.Value(subsystemKey{}).(string)
+	s.m.Lock()
+	defer s.m.Unlock()
+	subsystemStats := s.subsystems[subsystem]
+	switch event := rpcStats.(type) {
+	case *stats.Begin:
+		s.callsStarted = s.callsStarted + 1
+		s.lastCallStartedAt = event.BeginTime
+		subsystemStats.CallsStarted = subsystemStats.CallsStarted + 1
+	case *stats.End:
+		if event.Error == nil {
+			s.callsSucceeded = s.callsSucceeded + 1
+			subsystemStats.CallsSucceeded = subsystemStats.CallsSucceeded + 1
+		} else {
+			s.callsFailed = s.callsFailed + 1
+			subsystemStats.CallsFailed = subsystemStats.CallsFailed + 1
+		}
+	case *stats.OutPayload:
+		subsystemStats.BytesSent = subsystemStats.BytesSent + int64(event.Length)
+	case *stats.InPayload:
+		subsystemStats.BytesReceived = subsystemStats.BytesReceived + int64(event.Length)
+	default:
+		return
+	}
+	s.subsystems[subsystem] = subsystemStats
+}
+
+func (s *connStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *connStats) HandleConn(context.Context, stats.ConnStats) {}
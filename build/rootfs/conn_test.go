@@ -0,0 +1,140 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnSharedBySubsystems(t *testing.T) {
+	logger := hclog.Default()
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	conn, err := DialConn(logger, &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	executor := conn.Client("executor")
+	logs := conn.Client("logs")
+	assert.Nil(t, executor.Commands())
+	assert.Nil(t, executor.Ping())
+	assert.Nil(t, logs.StdOut([]string{"line"}))
+
+	stats := conn.Stats()
+	assert.Equal(t, "READY", stats.State)
+	assert.Equal(t, int64(3), stats.CallsStarted)
+	assert.Equal(t, int64(3), stats.CallsSucceeded)
+	assert.Equal(t, int64(2), stats.Subsystems["executor"].CallsSucceeded)
+	assert.Equal(t, int64(1), stats.Subsystems["logs"].CallsSucceeded)
+	assert.True(t, stats.Subsystems["logs"].BytesSent > 0)
+
+	// a watch in flight holds the connection open until the deadline:
+	_, err = logs.WatchLogs()
+	assert.Nil(t, err)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFunc()
+	assert.Equal(t, context.DeadlineExceeded, conn.Close(ctx))
+
+	pingErr := executor.Ping()
+	assert.True(t, errors.Is(pingErr, ErrConnClosed), "expected closed connection, got: %v", pingErr)
+	assert.Nil(t, conn.Close(context.Background()))
+}
+
+func TestConnCloseWaitsForCalls(t *testing.T) {
+	logger := hclog.Default()
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	conn, err := DialConn(logger, &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	chanLines, err := conn.Client("logs").WatchLogs()
+	assert.Nil(t, err)
+	chanClosed := make(chan error, 1)
+	go func() {
+		chanClosed <- conn.Close(context.Background())
+	}()
+
+	// the watch ends when the server stops, the connection closes after it:
+	srv.Stop()
+	for range chanLines {
+	}
+	select {
+	case err := <-chanClosed:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the connection to close when the watch ended")
+	}
+}
+
+func TestConnReleasesEndedWatch(t *testing.T) {
+	logger := hclog.Default()
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	conn, err := DialConn(logger, &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	chanCancel, err := conn.Client("executor").WatchCancel()
+	assert.Nil(t, err)
+	// the watch is in place once the server serves it:
+	time.Sleep(100 * time.Millisecond)
+	srv.Cancel(errors.New("cancelled"))
+	select {
+	case cancelErr := <-chanCancel:
+		assert.NotNil(t, cancelErr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the build cancelled")
+	}
+
+	// the watch returned without receiving again, the connection is released all the same:
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	assert.Nil(t, conn.Close(ctx))
+}
@@ -0,0 +1,369 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// connectEndStreamFlag marks the envelope ending a Connect stream.
+	connectEndStreamFlag = 0x02
+	// connectMaxRequestBytes limits the size of a Connect request.
+	connectMaxRequestBytes = 4 * 1024 * 1024
+)
+
+// connectHandler serves the unary and the server streaming RPCs of the service with the Connect protocol,
+// encoded as JSON or as binary protobuf. Client and bidirectional streaming RPCs are not available.
+// The calls pass the same interceptors and stats handler as the gRPC calls.
+type connectHandler struct {
+	svc               proto.RootfsServerServer
+	unaryInterceptor  grpc.UnaryServerInterceptor
+	streamInterceptor grpc.StreamServerInterceptor
+	statsHandler      stats.Handler
+	unary             map[string]grpc.MethodDesc
+	streams           map[string]grpc.StreamDesc
+}
+
+func newConnectHandler(svc proto.RootfsServerServer, unaryInterceptors []grpc.UnaryServerInterceptor,
+	streamInterceptors []grpc.StreamServerInterceptor, statsHandler stats.Handler, wireVersions []string) *connectHandler {
+	h := &connectHandler{
+		svc:               svc,
+		unaryInterceptor:  chainUnaryInterceptors(unaryInterceptors),
+		streamInterceptor: chainStreamInterceptors(streamInterceptors),
+		statsHandler:      statsHandler,
+		unary:             map[string]grpc.MethodDesc{},
+		streams:           map[string]grpc.StreamDesc{},
+	}
+	for _, version := range wireVersions {
+		serviceDesc := wireServiceDesc(version)
+		prefix := "/" + serviceDesc.ServiceName + "/"
+		for _, desc := range serviceDesc.Methods {
+			h.unary[prefix+desc.MethodName] = desc
+		}
+		for _, desc := range serviceDesc.Streams {
+			if desc.ServerStreams && !desc.ClientStreams {
+				h.streams[prefix+desc.StreamName] = desc
+			}
+		}
+	}
+	return h
+}
+
+// isGRPCRequest returns true if the request uses the gRPC protocol rather than the Connect protocol.
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+func (h *connectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeConnectError(w, status.Errorf(codes.Unimplemented, "method %s not supported", r.Method))
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if index := strings.Index(contentType, ";"); index > -1 {
+		contentType = contentType[0:index]
+	}
+	ctx := connectContext(r)
+	if desc, ok := h.unary[r.URL.Path]; ok {
+		codec, ok := connectCodecs[contentType]
+		if !ok {
+			writeConnectError(w, status.Errorf(codes.Unimplemented, "content type %s not supported", contentType))
+			return
+		}
+		h.serveUnary(ctx, w, r, desc, contentType, codec)
+		return
+	}
+	if desc, ok := h.streams[r.URL.Path]; ok {
+		codec, ok := connectCodecs[strings.Replace(contentType, "/connect+", "/", 1)]
+		if !ok || !strings.HasPrefix(contentType, "application/connect+") {
+			writeConnectError(w, status.Errorf(codes.Unimplemented, "content type %s not supported", contentType))
+			return
+		}
+		h.serveStream(ctx, w, r, desc, contentType, codec)
+		return
+	}
+	writeConnectError(w, status.Errorf(codes.Unimplemented, "method %s not available", r.URL.Path))
+}
+
+func (h *connectHandler) serveUnary(ctx context.Context, w http.ResponseWriter, r *http.Request,
+	desc grpc.MethodDesc, contentType string, codec connectCodec) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, connectMaxRequestBytes))
+	if err != nil {
+		writeConnectError(w, status.Errorf(codes.InvalidArgument, "failed reading request: %v", err))
+		return
+	}
+	ctx, beginTime := h.beginRPC(ctx, r.URL.Path)
+	decode := func(message interface{}) error {
+		if err := codec.unmarshal(body, message.(protobuf.Message)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed decoding request: %v", err)
+		}
+		h.handleRPC(ctx, &stats.InPayload{Payload: message, Data: body, Length: len(body), RecvTime: time.Now()})
+		return nil
+	}
+	response, err := desc.Handler(h.svc, ctx, decode, h.unaryInterceptor)
+	if err != nil {
+		h.endRPC(ctx, beginTime, err)
+		writeConnectError(w, err)
+		return
+	}
+	payload, err := codec.marshal(response.(protobuf.Message))
+	if err != nil {
+		err = status.Errorf(codes.Internal, "failed encoding response: %v", err)
+		h.endRPC(ctx, beginTime, err)
+		writeConnectError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+	h.handleRPC(ctx, &stats.OutPayload{Payload: response, Data: payload, Length: len(payload), SentTime: time.Now()})
+	h.endRPC(ctx, beginTime, nil)
+}
+
+func (h *connectHandler) serveStream(ctx context.Context, w http.ResponseWriter, r *http.Request,
+	desc grpc.StreamDesc, contentType string, codec connectCodec) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r.Body, header); err != nil {
+		writeConnectError(w, status.Errorf(codes.InvalidArgument, "failed reading request envelope: %v", err))
+		return
+	}
+	size := binary.BigEndian.Uint32(header[1:5])
+	if size > connectMaxRequestBytes {
+		writeConnectError(w, status.Errorf(codes.ResourceExhausted, "request of %d bytes too large", size))
+		return
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r.Body, body); err != nil {
+		writeConnectError(w, status.Errorf(codes.InvalidArgument, "failed reading request: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	ctx, beginTime := h.beginRPC(ctx, r.URL.Path)
+	stream := &connectServerStream{ctx: ctx, codec: codec, request: body, handler: h, w: w}
+	info := &grpc.StreamServerInfo{
+		FullMethod:     r.URL.Path,
+		IsServerStream: true,
+	}
+	err := h.streamInterceptor(h.svc, stream, info, desc.Handler)
+	h.endRPC(ctx, beginTime, err)
+	end := map[string]interface{}{}
+	if err != nil {
+		end["error"] = connectErrorBody(err)
+	}
+	endPayload, _ := json.Marshal(end)
+	stream.writeEnvelope(connectEndStreamFlag, endPayload)
+}
+
+// beginRPC tags the context of a call for the stats handler and reports the begin of the call.
+func (h *connectHandler) beginRPC(ctx context.Context, fullMethod string) (context.Context, time.Time) {
+	beginTime := time.Now()
+	if h.statsHandler == nil {
+		return ctx, beginTime
+	}
+	ctx = h.statsHandler.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: fullMethod})
+	h.statsHandler.HandleRPC(ctx, &stats.Begin{BeginTime: beginTime})
+	return ctx, beginTime
+}
+
+// handleRPC reports the stats of a call to the stats handler.
+func (h *connectHandler) handleRPC(ctx context.Context, rpcStats stats.RPCStats) {
+	if h.statsHandler != nil {
+		h.statsHandler.HandleRPC(ctx, rpcStats)
+	}
+}
+
+// endRPC reports the end of a call to the stats handler.
+func (h *connectHandler) endRPC(ctx context.Context, beginTime time.Time, err error) {
+	h.handleRPC(ctx, &stats.End{BeginTime: beginTime, EndTime: time.Now(), Error: err})
+}
+
+// chainUnaryInterceptors returns an interceptor calling the interceptors in order, the first is the outermost.
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+// chainStreamInterceptors returns an interceptor calling the interceptors in order, the first is the outermost.
+func chainStreamInterceptors(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return chained(srv, ss)
+	}
+}
+
+// connectContext creates the context of a Connect request carrying the request headers
+// as the incoming metadata and the TLS state of the connection as the peer.
+func connectContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	p := &peer.Peer{Addr: connectAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+type connectAddr string
+
+func (a connectAddr) Network() string { return "tcp" }
+func (a connectAddr) String() string  { return string(a) }
+
+// connectServerStream is the server stream of a Connect streaming request with a single request message.
+type connectServerStream struct {
+	ctx      context.Context
+	codec    connectCodec
+	request  []byte
+	received bool
+	handler  *connectHandler
+	w        http.ResponseWriter
+}
+
+func (s *connectServerStream) Context() context.Context     { return s.ctx }
+func (s *connectServerStream) SendHeader(metadata.MD) error { return nil }
+func (s *connectServerStream) SetHeader(metadata.MD) error  { return nil }
+func (s *connectServerStream) SetTrailer(metadata.MD)       {}
+
+func (s *connectServerStream) RecvMsg(message interface{}) error {
+	if s.received {
+		return io.EOF
+	}
+	s.received = true
+	if err := s.codec.unmarshal(s.request, message.(protobuf.Message)); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed decoding request: %v", err)
+	}
+	s.handler.handleRPC(s.ctx, &stats.InPayload{Payload: message, Data: s.request, Length: len(s.request), RecvTime: time.Now()})
+	return nil
+}
+
+func (s *connectServerStream) SendMsg(message interface{}) error {
+	payload, err := s.codec.marshal(message.(protobuf.Message))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed encoding response: %v", err)
+	}
+	if err := s.writeEnvelope(0, payload); err != nil {
+		return err
+	}
+	s.handler.handleRPC(s.ctx, &stats.OutPayload{Payload: message, Data: payload, Length: len(payload), SentTime: time.Now()})
+	return nil
+}
+
+func (s *connectServerStream) writeEnvelope(flags byte, payload []byte) error {
+	envelope := make([]byte, 5, 5+len(payload))
+	envelope[0] = flags
+	binary.BigEndian.PutUint32(envelope[1:5], uint32(len(payload)))
+	if _, err := s.w.Write(append(envelope, payload...)); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// connectCodec encodes the messages of a Connect content type.
+type connectCodec struct {
+	marshal   func(protobuf.Message) ([]byte, error)
+	unmarshal func([]byte, protobuf.Message) error
+}
+
+var connectCodecs = map[string]connectCodec{
+	"application/json": {
+		marshal: protojson.Marshal,
+		unmarshal: func(data []byte, message protobuf.Message) error {
+			if len(bytes.TrimSpace(data)) == 0 {
+				return nil
+			}
+			return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, message)
+		},
+	},
+	"application/proto": {
+		marshal:   protobuf.Marshal,
+		unmarshal: protobuf.Unmarshal,
+	},
+}
+
+// connectCodes maps the gRPC codes to the Connect error codes and HTTP statuses.
+var connectCodes = map[codes.Code]struct {
+	name       string
+	httpStatus int
+}{
+	codes.Canceled:           {"canceled", 499},
+	codes.Unknown:            {"unknown", http.StatusInternalServerError},
+	codes.InvalidArgument:    {"invalid_argument", http.StatusBadRequest},
+	codes.DeadlineExceeded:   {"deadline_exceeded", http.StatusGatewayTimeout},
+	codes.NotFound:           {"not_found", http.StatusNotFound},
+	codes.AlreadyExists:      {"already_exists", http.StatusConflict},
+	codes.PermissionDenied:   {"permission_denied", http.StatusForbidden},
+	codes.ResourceExhausted:  {"resource_exhausted", http.StatusTooManyRequests},
+	codes.FailedPrecondition: {"failed_precondition", http.StatusBadRequest},
+	codes.Aborted:            {"aborted", http.StatusConflict},
+	codes.OutOfRange:         {"out_of_range", http.StatusBadRequest},
+	codes.Unimplemented:      {"unimplemented", http.StatusNotImplemented},
+	codes.Internal:           {"internal", http.StatusInternalServerError},
+	codes.Unavailable:        {"unavailable", http.StatusServiceUnavailable},
+	codes.DataLoss:           {"data_loss", http.StatusInternalServerError},
+	codes.Unauthenticated:    {"unauthenticated", http.StatusUnauthorized},
+}
+
+func connectErrorBody(err error) map[string]interface{} {
+	s := status.Convert(err)
+	code, ok := connectCodes[s.Code()]
+	if !ok {
+		code = connectCodes[codes.Unknown]
+	}
+	return map[string]interface{}{"code": code.name, "message": s.Message()}
+}
+
+// writeConnectError writes the error of a unary Connect request.
+func writeConnectError(w http.ResponseWriter, err error) {
+	code, ok := connectCodes[status.Code(err)]
+	if !ok {
+		code = connectCodes[codes.Unknown]
+	}
+	payload, marshalErr := json.Marshal(connectErrorBody(err))
+	if marshalErr != nil {
+		payload = []byte(fmt.Sprintf(`{"code":"%s"}`, code.name))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.httpStatus)
+	w.Write(payload)
+}
@@ -0,0 +1,172 @@
+package rootfs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestServerConnectProtocol(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	recorder := NewRPCStatsRecorder()
+	grpcConfig := &GRPCServiceConfig{ConnectProtocol: true, StatsHandler: recorder}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("contents"))), nil
+				}, fs.FileMode(0644), "file", "/opt/file", commands.DefaultWorkdir(), commands.DefaultUser(), ""),
+			},
+		},
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: grpcConfig.TLSConfigClient.Clone()},
+	}
+	url := fmt.Sprintf("https://%s/proto.RootfsServer/", grpcConfig.BindHostPort)
+
+	// unary:
+	response, err := httpClient.Post(url+"Commands", "application/json", bytes.NewReader([]byte("{}")))
+	if assert.Nil(t, err) {
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		body := map[string][]string{}
+		assert.Nil(t, json.NewDecoder(response.Body).Decode(&body))
+		assert.Equal(t, 1, len(body["command"]))
+	}
+
+	response, err = httpClient.Post(url+"Manifest", "application/json", bytes.NewReader([]byte(`{"path":"missing"}`)))
+	if assert.Nil(t, err) {
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		body := map[string]string{}
+		assert.Nil(t, json.NewDecoder(response.Body).Decode(&body))
+		assert.Equal(t, "not_found", body["code"])
+	}
+
+	// server streaming:
+	messages, end := mustConnectStream(t, httpClient, url+"Resource", `{"path":"file"}`)
+	assert.Nil(t, end["error"])
+	if assert.Equal(t, 3, len(messages)) {
+		assert.Contains(t, messages[0], "header")
+		assert.Contains(t, messages[1], "chunk")
+		assert.Contains(t, messages[2], "eof")
+	}
+
+	messages, end = mustConnectStream(t, httpClient, url+"Resource", `{"path":"missing"}`)
+	assert.Equal(t, 0, len(messages))
+	if assert.NotNil(t, end["error"]) {
+		assert.Equal(t, "not_found", end["error"].(map[string]interface{})["code"])
+	}
+
+	// the Connect calls pass the stats handler of the server:
+	snapshot := recorder.Snapshot()
+	assert.Equal(t, RPCStats{Calls: 1, MessagesReceived: 1, MessagesSent: 1,
+		BytesReceived: snapshot["Commands"].BytesReceived, BytesSent: snapshot["Commands"].BytesSent}, snapshot["Commands"])
+	assert.Equal(t, 1, snapshot["Manifest"].Errors)
+	assert.Equal(t, 2, snapshot["Resource"].Calls)
+	assert.Equal(t, 1, snapshot["Resource"].Errors)
+	assert.Equal(t, 3, snapshot["Resource"].MessagesSent)
+
+	// gRPC is served on the same listener:
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.Success())
+}
+
+func TestServerConnectPlaintext(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{ConnectProtocol: true, ConnectPlaintextBindHostPort: "127.0.0.1:0"}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	url := fmt.Sprintf("http://%s/proto.RootfsServer/", grpcConfig.ConnectPlaintextBindHostPort)
+	h2cClient := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+	for _, httpClient := range []*http.Client{http.DefaultClient, h2cClient} {
+		response, err := httpClient.Post(url+"Commands", "application/json", bytes.NewReader([]byte("{}")))
+		if assert.Nil(t, err) {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			response.Body.Close()
+		}
+		// plaintext clients are unverified observers:
+		response, err = httpClient.Post(url+"StdOut", "application/json", bytes.NewReader([]byte(`{"line":["line"]}`)))
+		if assert.Nil(t, err) {
+			assert.Equal(t, http.StatusForbidden, response.StatusCode)
+			response.Body.Close()
+		}
+	}
+
+	// TLS is served as before:
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.Success())
+}
+
+func mustConnectStream(t *testing.T, httpClient *http.Client, url, request string) ([]map[string]interface{}, map[string]interface{}) {
+	envelope := make([]byte, 5)
+	binary.BigEndian.PutUint32(envelope[1:], uint32(len(request)))
+	response, err := httpClient.Post(url, "application/connect+json", bytes.NewReader(append(envelope, []byte(request)...)))
+	if err != nil {
+		t.Fatal("expected a streaming response, got error", err)
+	}
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	messages := []map[string]interface{}{}
+	for {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(response.Body, header); err != nil {
+			t.Fatal("expected an envelope, got error", err)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(response.Body, payload); err != nil {
+			t.Fatal("expected an envelope payload, got error", err)
+		}
+		message := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(payload, &message))
+		if header[0]&connectEndStreamFlag != 0 {
+			return messages, message
+		}
+		messages = append(messages, message)
+	}
+}
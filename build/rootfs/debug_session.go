@@ -0,0 +1,103 @@
+package rootfs
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// DebugSession is a bidirectional byte stream to a pty proxy implemented by the guest.
+// Reading returns the guest terminal output, writing sends input to the guest terminal.
+// Closing the session ends the Debug RPC and lets the guest shut down.
+type DebugSession interface {
+	io.ReadWriteCloser
+	// Done returns a channel closed when the session has ended.
+	Done() <-chan struct{}
+}
+
+type grpcDebugSession struct {
+	m      sync.Mutex
+	stream proto.RootfsServer_DebugServer
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	closeOnce sync.Once
+	chanDone  chan struct{}
+}
+
+func newGRPCDebugSession(stream proto.RootfsServer_DebugServer) *grpcDebugSession {
+	reader, writer := io.Pipe()
+	return &grpcDebugSession{
+		stream:   stream,
+		reader:   reader,
+		writer:   writer,
+		chanDone: make(chan struct{}),
+	}
+}
+
+// Read reads the guest terminal output.
+func (s *grpcDebugSession) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// Write sends the input to the guest terminal.
+func (s *grpcDebugSession) Write(p []byte) (int, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	select {
+	case <-s.chanDone:
+		return 0, fmt.Errorf("debug session closed")
+	default:
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	if err := s.stream.Send(&proto.DebugFrame{Data: data}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the debug session.
+func (s *grpcDebugSession) Close() error {
+	s.closeWithError(nil)
+	return nil
+}
+
+// Done returns a channel closed when the session has ended.
+func (s *grpcDebugSession) Done() <-chan struct{} {
+	return s.chanDone
+}
+
+// awaitWrite waits for a write in progress, the stream must not be sent to once the Debug handler returned.
+// Writes after the session has ended fail without sending.
+func (s *grpcDebugSession) awaitWrite() {
+	s.m.Lock()
+	defer s.m.Unlock()
+}
+
+func (s *grpcDebugSession) closeWithError(err error) {
+	s.closeOnce.Do(func() {
+		s.writer.CloseWithError(err)
+		close(s.chanDone)
+	})
+}
+
+// receive pumps the guest terminal output into the session reader until the stream ends.
+func (s *grpcDebugSession) receive() {
+	for {
+		frame, err := s.stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			s.closeWithError(err)
+			return
+		}
+		if _, err := s.writer.Write(frame.Data); err != nil {
+			s.closeWithError(err)
+			return
+		}
+	}
+}
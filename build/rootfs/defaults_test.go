@@ -0,0 +1,62 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkContextDefaults(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	explicit := commands.RunWithDefaults("id")
+	cmds := []commands.VMInitSerializableCommand{
+		commands.Run{OriginalCommand: "RUN make", Command: "make"},
+		explicit,
+		commands.Delete{OriginalCommand: "DELETE build", Paths: []string{"build"}},
+	}
+	workContext := &WorkContext{
+		ExecutableCommands: cmds,
+		ResourcesResolved:  make(Resources),
+		DefaultUser:        commands.User{Value: "1000:1000"},
+		DefaultWorkdir:     commands.Workdir{Value: "/home/app"},
+	}
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, workContext)
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	assert.Nil(t, testClient.Commands())
+	assert.Equal(t, commands.Run{OriginalCommand: "RUN make", Command: "make",
+		User: commands.User{Value: "1000:1000"}, Workdir: commands.Workdir{Value: "/home/app"}}, testClient.NextCommand(),
+		"expected the defaults of the work context")
+	assert.Equal(t, explicit, testClient.NextCommand(), "expected the explicit user and workdir to remain")
+	assert.Equal(t, commands.Delete{OriginalCommand: "DELETE build", Paths: []string{"build"},
+		Workdir: commands.Workdir{Value: "/home/app"}}, testClient.NextCommand())
+	assert.Nil(t, testClient.NextCommand())
+	assert.Equal(t, cmds, workContext.ExecutableCommands, "expected the commands of the work context not to change")
+
+	env := commands.Env{OriginalCommand: "ENV A=b", Name: "A", Value: "b"}
+	assert.Equal(t, env, commands.ApplyDefaults(env, workContext.DefaultUser, workContext.DefaultWorkdir))
+
+	header := &proto.ResourceChunk_ResourceHeader{}
+	workContext.applyHeaderDefaults(header)
+	assert.Equal(t, "1000:1000", header.TargetUser)
+	assert.Equal(t, "/home/app", header.TargetWorkdir)
+	header = &proto.ResourceChunk_ResourceHeader{TargetUser: "0:0", TargetWorkdir: "/"}
+	workContext.applyHeaderDefaults(header)
+	assert.Equal(t, "0:0", header.TargetUser)
+
+	header = &proto.ResourceChunk_ResourceHeader{}
+	(&WorkContext{}).applyHeaderDefaults(header)
+	assert.Equal(t, commands.DefaultUser().Value, header.TargetUser)
+	assert.Equal(t, commands.DefaultWorkdir().Value, header.TargetWorkdir)
+}
@@ -0,0 +1,34 @@
+package rootfs
+
+import (
+	"os"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/pkg/errors"
+)
+
+// ApplyDelete removes the paths of a Delete command under the root directory. Relative paths are resolved
+// against the workdir of the command. With an overlay layer, the paths are deleted from the layer with
+// whiteouts and the root directory is not modified. Paths which do not exist are ignored.
+// Deleting the root is not allowed.
+func ApplyDelete(cmd commands.Delete, rootDir string, layer *OverlayLayer) error {
+	for _, deletedPath := range cmd.Paths {
+		targetPath, err := cmd.Workdir.Resolve(deletedPath)
+		if err != nil {
+			return errors.Wrapf(ErrInvalidArgument, "can't delete '%s': %v", deletedPath, err)
+		}
+		if targetPath == "/" {
+			return errors.Wrapf(ErrInvalidArgument, "can't delete the root via '%s'", deletedPath)
+		}
+		if layer != nil {
+			if err := layer.Whiteout(targetPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.RemoveAll(targetPathUnder(rootDir, targetPath)); err != nil {
+			return errors.Wrapf(err, "failed deleting '%s'", targetPath)
+		}
+	}
+	return nil
+}
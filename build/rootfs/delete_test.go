@@ -0,0 +1,58 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteCommand(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	rootDir := filepath.Join(tempDir, "root")
+	MustPutTestResource(t, filepath.Join(rootDir, "etc/keep"), []byte("keep"))
+	MustPutTestResource(t, filepath.Join(rootDir, "etc/remove"), []byte("remove"))
+	MustPutTestResource(t, filepath.Join(rootDir, "opt/app/cache/file"), []byte("cache"))
+
+	deleteCommand := commands.DeleteWithDefaults("/etc/remove", "cache", "/does/not/exist")
+	deleteCommand.Workdir = commands.Workdir{Value: "/opt/app"}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{deleteCommand},
+		ResourcesResolved:  Resources{},
+	})
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Commands())
+	received, ok := testClient.NextCommand().(commands.Delete)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, deleteCommand, received)
+
+	assert.Nil(t, ApplyDelete(received, rootDir, nil))
+	assert.FileExists(t, filepath.Join(rootDir, "etc/keep"))
+	assert.NoFileExists(t, filepath.Join(rootDir, "etc/remove"))
+	assert.NoDirExists(t, filepath.Join(rootDir, "opt/app/cache"))
+
+	// with a layer, deletions become whiteouts:
+	upperDir := filepath.Join(tempDir, "upper")
+	assert.Nil(t, ApplyDelete(received, rootDir, &OverlayLayer{UpperDir: upperDir, WhiteoutFormat: WhiteoutFormatAUFS}))
+	assert.FileExists(t, filepath.Join(upperDir, "etc/.wh.remove"))
+	assert.FileExists(t, filepath.Join(upperDir, "opt/app/.wh.cache"))
+
+	assert.NotNil(t, ApplyDelete(commands.DeleteWithDefaults("../.."), rootDir, nil))
+	assert.DirExists(t, rootDir)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
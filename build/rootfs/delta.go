@@ -0,0 +1,197 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// ResourceDelta streams the resources for the path the client does not have yet. The client lists
+// the entries it has with their digests, only new entries and files with a different digest are sent.
+// Existing entries under the target path of a served resource which are no longer served are sent
+// as deletions after all resources.
+func (impl *serverImpl) ResourceDelta(req *proto.ResourceDeltaRequest, stream proto.RootfsServer_ResourceDeltaServer) error {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	ress, ok := impl.lookupResources(req.Path)
+	if !ok {
+		return withResourcePath(fmt.Errorf("%w: '%s/%s'", ErrResourceNotFound, req.Stage, req.Path), req.Path, req.Stage)
+	}
+
+	existing := map[string]string{}
+	for _, entry := range req.Existing {
+		existing[path.Clean("/"+entry.TargetPath)] = entry.Digest
+	}
+
+	servedResources, servedBytes := 0, int64(0)
+	roots := []string{}
+	current := map[string]struct{}{}
+	resourceReq := &proto.ResourceRequest{Path: req.Path, Stage: req.Stage, Multiplex: req.Multiplex, BatchFileSize: req.BatchFileSize,
+		AcceptCompression: req.AcceptCompression}
+
+	for _, resource := range ress {
+		if !resources.PlatformMatches(resources.PlatformOf(resource), impl.serverCtx.Platform) {
+			continue
+		}
+		if err := refuseSensitive(resource, req.Path, req.Stage); err != nil {
+			return err
+		}
+		roots = append(roots, path.Clean("/"+resource.TargetPath()))
+
+		var include func(targetPath, filePath string, isDir bool) bool
+		if resource.IsDir() {
+			include = func(targetPath, filePath string, isDir bool) bool {
+				targetPath = path.Clean("/" + targetPath)
+				current[targetPath] = struct{}{}
+				digest, ok := existing[targetPath]
+				if !ok {
+					return true
+				}
+				if isDir {
+					return false
+				}
+				fileDigest, err := fileDigest(filePath)
+				return err != nil || fileDigest != digest
+			}
+		} else {
+			targetPath := path.Clean("/" + resource.TargetPath())
+			current[targetPath] = struct{}{}
+			if digest, ok := existing[targetPath]; ok {
+				if contentsDigest, err := impl.resourceDigest(stream.Context(), resource); err == nil && contentsDigest == digest {
+					continue
+				}
+			}
+		}
+
+		resourcesCount, bytesCount, err := impl.sendResource(resourceReq, resource, stream, include)
+		servedResources = servedResources + resourcesCount
+		servedBytes = servedBytes + bytesCount
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, deleted := range deletedTargets(existing, current, roots) {
+		if err := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+			Payload: &proto.ResourceChunk_Delete{
+				Delete: &proto.ResourceChunk_ResourceDelete{TargetPath: deleted},
+			},
+		}); err != nil {
+			impl.resourceLogger.Error("Failed sending delete", "reason", err)
+			return err
+		}
+	}
+
+	impl.emit(&ControlMsgResourceServed{
+		Path:      req.Path,
+		Stage:     req.Stage,
+		Resources: servedResources,
+		Bytes:     servedBytes,
+	})
+	return nil
+}
+
+// deletedTargets returns the existing target paths under any of the roots which are not current, sorted.
+func deletedTargets(existing map[string]string, current map[string]struct{}, roots []string) []string {
+	deleted := []string{}
+	for targetPath := range existing {
+		if _, ok := current[targetPath]; ok {
+			continue
+		}
+		for _, root := range roots {
+			if targetPath == root || strings.HasPrefix(targetPath, strings.TrimSuffix(root, "/")+"/") {
+				deleted = append(deleted, targetPath)
+				break
+			}
+		}
+	}
+	sort.Strings(deleted)
+	return deleted
+}
+
+// fileDigest returns the hex encoded SHA-256 digest of the contents of a local file.
+func fileDigest(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return readerDigest(file)
+}
+
+// resourceDigest returns the hex encoded SHA-256 digest of the contents of a file resource.
+func (impl *serverImpl) resourceDigest(ctx context.Context, resource resources.ResolvedResource) (string, error) {
+	// the digest of a spilled resource is captured on the first read:
+	if spilled, ok := resource.(*spilledResource); ok {
+		if digest, ok := spilled.Digest(); ok {
+			return digest, nil
+		}
+	}
+	reader, err := impl.resourceContents(ctx, resource)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	return readerDigest(reader)
+}
+
+func readerDigest(reader io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ExistingDigests lists the entries under the target path of a root directory as a map of target paths
+// to hex encoded SHA-256 digests of the contents, directories have an empty digest.
+// The result is suitable for WriteOptions.Existing. Returns an empty map if the target path does not exist.
+func ExistingDigests(rootDir, targetPath string) (map[string]string, error) {
+	digests := map[string]string{}
+	rootPath := filepath.Clean(rootDir)
+	walkRoot := targetPathUnder(rootDir, targetPath)
+	if _, err := os.Lstat(walkRoot); os.IsNotExist(err) {
+		return digests, nil
+	}
+	err := filepath.WalkDir(walkRoot, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(rootPath, filePath)
+		if err != nil {
+			return err
+		}
+		entryTargetPath := path.Clean("/" + filepath.ToSlash(relativePath))
+		if d.IsDir() {
+			digests[entryTargetPath] = ""
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		digest, err := fileDigest(filePath)
+		if err != nil {
+			return err
+		}
+		digests[entryTargetPath] = digest
+		return nil
+	})
+	return digests, err
+}
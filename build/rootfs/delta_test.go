@@ -0,0 +1,80 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWriteResourcesDelta(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "removed"), []byte("removed"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "changed"), []byte("original"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "sub/unchanged"), []byte("unchanged"))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, nil))
+	// an entry the guest has but the server does not serve:
+	MustPutTestResource(t, filepath.Join(rootDir, "opt/dir/local"), []byte("local"))
+	// an entry outside of the served resource is left alone:
+	MustPutTestResource(t, filepath.Join(rootDir, "opt/other"), []byte("other"))
+
+	unchangedBefore, err := os.Stat(filepath.Join(rootDir, "opt/dir/sub/unchanged"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.Remove(filepath.Join(sourceDir, "removed")))
+	MustPutTestResource(t, filepath.Join(sourceDir, "changed"), []byte("changed"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "added"), []byte("added"))
+
+	existing, err := ExistingDigests(rootDir, "/opt/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, "", existing["/opt/dir/sub"])
+	assert.Contains(t, existing, "/opt/dir/sub/unchanged")
+
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, &WriteOptions{Existing: existing}))
+
+	for entryPath, expected := range map[string]string{
+		"opt/dir/added":         "added",
+		"opt/dir/changed":       "changed",
+		"opt/dir/sub/unchanged": "unchanged",
+		"opt/other":             "other",
+	} {
+		contents, err := ioutil.ReadFile(filepath.Join(rootDir, entryPath))
+		assert.Nil(t, err, entryPath)
+		assert.Equal(t, expected, string(contents), entryPath)
+	}
+	assert.NoFileExists(t, filepath.Join(rootDir, "opt/dir/removed"))
+	assert.NoFileExists(t, filepath.Join(rootDir, "opt/dir/local"))
+
+	// the unchanged file was not transferred again:
+	unchangedAfter, err := os.Stat(filepath.Join(rootDir, "opt/dir/sub/unchanged"))
+	assert.Nil(t, err)
+	assert.True(t, os.SameFile(unchangedBefore, unchangedAfter))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
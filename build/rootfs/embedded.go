@@ -0,0 +1,134 @@
+package rootfs
+
+import (
+	"context"
+	"net"
+
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewEmbedded returns a server serving the build on an externally managed grpc.Server,
+// so an application serving other services on one gRPC endpoint does not need a second port.
+// The service is registered on the grpc.Server in every configured wire version immediately,
+// before the application starts serving. Calls made before Start fail as unavailable.
+//
+// The listener is the listener the application serves the grpc.Server on and reports the bind address,
+// the server neither serves nor closes it. Stop ends the build but leaves the grpc.Server running.
+// The grpc.Server owns the transport security: the TLS settings of the configuration are not used,
+// no embedded CA is created and the Connect protocol is not served.
+func NewEmbedded(cfg *GRPCServiceConfig, logger hclog.Logger, grpcServer *grpc.Server, listener net.Listener) ServerProvider {
+	cfg = cfg.WithDefaultsApplied()
+	s := &grpcSvc{
+		config:      cfg,
+		logger:      cfg.LogLevels.logger(logger, LogSubsystemServerLifecycle),
+		baseLogger:  logger,
+		srv:         grpcServer,
+		embedded:    true,
+		roles:       newClientRoles(cfg.ClientRoleResolver, cfg.AllowUnverifiedExecutor),
+		statusErrs:  &statusErrors{sessionID: uuid.Must(uuid.NewV4()).String()},
+		session:     newBuildSession(""),
+		routines:    newRoutineGroup(),
+		chanFailed:  make(chan error, 1),
+		chanReady:   make(chan struct{}),
+		chanStopped: make(chan struct{}),
+	}
+	if listener != nil {
+		s.config.BindHostPort = listener.Addr().String()
+	}
+	for _, version := range s.config.WireVersions {
+		if validateWireVersion(version) == nil {
+			grpcServer.RegisterService(s.embeddedServiceDesc(wireServiceDesc(version)), nil)
+		}
+	}
+	return s
+}
+
+// startEmbedded starts the build served by the externally managed grpc.Server, the lock must be held.
+func (s *grpcSvc) startEmbedded(serverCtx *WorkContext) {
+	s.scratchDir = serverCtx.ScratchDir
+	s.session.setBuildID(serverCtx.BuildID)
+	s.svc = newServerImpl(s.baseLogger.Named("grpc-impl").With("build-id", serverCtx.BuildID), serverCtx, s.config)
+	s.running = true
+	s.logger.Info("embedded service running")
+	close(s.chanReady)
+	if s.config.BuildTimeout > 0 {
+		s.routines.goTracked(func() { s.enforceBuildTimeout(s.config.BuildTimeout) })
+	}
+}
+
+// currentService returns the service of the started build, nil before Start.
+func (s *grpcSvc) currentService() serverImplInterface {
+	s.Lock()
+	defer s.Unlock()
+	return s.svc
+}
+
+var errEmbeddedNotStarted = status.Error(codes.Unavailable, "server not started")
+
+// embeddedServiceDesc returns a service descriptor with the handlers of the descriptor calling the service
+// of the started build through the interceptors of the server. The interceptors of the grpc.Server
+// run before the interceptors of the server.
+func (s *grpcSvc) embeddedServiceDesc(desc *grpc.ServiceDesc) *grpc.ServiceDesc {
+	embedded := *desc
+	embedded.HandlerType = nil
+	embedded.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, method := range desc.Methods {
+		handler := method.Handler
+		embedded.Methods = append(embedded.Methods, grpc.MethodDesc{
+			MethodName: method.MethodName,
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				svc := s.currentService()
+				if svc == nil {
+					return nil, errEmbeddedNotStarted
+				}
+				return handler(svc, ctx, dec, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+					serverHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+						return s.routines.unaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+							return s.statusErrs.unaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+								return s.session.unaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+									return s.roles.unaryInterceptor(ctx, req, info, handler)
+								})
+							})
+						})
+					}
+					if interceptor == nil {
+						return serverHandler(ctx, req)
+					}
+					return interceptor(ctx, req, info, serverHandler)
+				})
+			},
+		})
+	}
+	embedded.Streams = make([]grpc.StreamDesc, 0, len(desc.Streams))
+	for _, stream := range desc.Streams {
+		handler := stream.Handler
+		info := &grpc.StreamServerInfo{
+			FullMethod:     "/" + desc.ServiceName + "/" + stream.StreamName,
+			IsClientStream: stream.ClientStreams,
+			IsServerStream: stream.ServerStreams,
+		}
+		embedded.Streams = append(embedded.Streams, grpc.StreamDesc{
+			StreamName:    stream.StreamName,
+			ClientStreams: stream.ClientStreams,
+			ServerStreams: stream.ServerStreams,
+			Handler: func(_ interface{}, ss grpc.ServerStream) error {
+				svc := s.currentService()
+				if svc == nil {
+					return errEmbeddedNotStarted
+				}
+				return s.routines.streamInterceptor(svc, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+					return s.statusErrs.streamInterceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+						return s.session.streamInterceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+							return s.roles.streamInterceptor(srv, ss, info, handler)
+						})
+					})
+				})
+			},
+		})
+	}
+	return &embedded
+}
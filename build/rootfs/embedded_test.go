@@ -0,0 +1,64 @@
+package rootfs
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestEmbeddedServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	grpcServer := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	srv := NewEmbedded(&GRPCServiceConfig{}, hclog.Default(), grpcServer, listener)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	err = conn.Invoke(context.Background(), "/"+proto.RootfsServer_ServiceDesc.ServiceName+"/Ping", &proto.PingRequest{Id: "ping"}, &proto.PingResponse{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	srv.Start(&WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	<-srv.ReadyNotify()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	for _, desc := range proto.RootfsServerServiceDescs() {
+		response := &proto.PingResponse{}
+		assert.Nil(t, conn.Invoke(context.Background(), "/"+desc.ServiceName+"/Ping", &proto.PingRequest{Id: "ping"}, response))
+		assert.Equal(t, "ping", response.Id)
+		assert.Nil(t, conn.Invoke(context.Background(), "/"+desc.ServiceName+"/Commands", &proto.Empty{}, &proto.CommandsResponse{}))
+	}
+
+	srv.Stop()
+	srv.Wait()
+
+	// the application server keeps serving after the build stops, the requests of the build are rejected:
+	for i := 0; i < 2; i++ {
+		err = conn.Invoke(context.Background(), "/"+proto.RootfsServer_ServiceDesc.ServiceName+"/Ping", &proto.PingRequest{Id: "ping"}, &proto.PingResponse{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	healthResponse, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, healthResponse.Status)
+}
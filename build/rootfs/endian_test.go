@@ -0,0 +1,49 @@
+package rootfs
+
+import (
+	"encoding/hex"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+// The tests in this file pin the bytes exchanged between the host and the guest,
+// so that a server and a client built for architectures of a different byte order,
+// for example an amd64 host and an arm64 or s390x guest, agree on them.
+
+func TestChunkChecksumsByteOrder(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03, 0x04, 0xfe, 0xff}
+	expected := "2f90f563ff30b27d9aab5c20451515e74a9a9508f6404b5b368557533231fa3c"
+	assert.Equal(t, expected, hex.EncodeToString(NewChecksumCache().checksum("", nil, 0, payload)))
+	assert.Equal(t, expected, hex.EncodeToString((*ChecksumCache)(nil).checksum("", nil, 0, payload)))
+}
+
+func TestFileModeEncodingByteOrder(t *testing.T) {
+	header := &proto.ResourceChunk_ResourceHeader{FileMode: 0755, Size: 1<<33 + 1, HasSize: true}
+	headerBytes, err := gproto.MarshalOptions{Deterministic: true}.Marshal(header)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x18, 0xed, 0x03, 0x50, 0x81, 0x80, 0x80, 0x80, 0x20, 0x58, 0x01}, headerBytes)
+
+	decoded := &proto.ResourceChunk_ResourceHeader{}
+	assert.Nil(t, gproto.Unmarshal(headerBytes, decoded))
+	assert.Equal(t, fs.FileMode(0755), fs.FileMode(decoded.FileMode))
+	assert.Equal(t, int64(1<<33+1), decoded.Size)
+
+	entry := &proto.ManifestEntry{FileMode: 0644, Size: 1 << 40}
+	entryBytes, err := gproto.MarshalOptions{Deterministic: true}.Marshal(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x18, 0xa4, 0x03, 0x28, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20}, entryBytes)
+}
+
+func TestConnectEnvelopeByteOrder(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	stream := &connectServerStream{w: recorder}
+	assert.Nil(t, stream.writeEnvelope(0x02, make([]byte, 0x010203)))
+	// the length of the payload is big endian on every architecture:
+	assert.Equal(t, []byte{0x02, 0x00, 0x01, 0x02, 0x03}, recorder.Body.Bytes()[0:5])
+	assert.Equal(t, 5+0x010203, recorder.Body.Len())
+}
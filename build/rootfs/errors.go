@@ -0,0 +1,273 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+var (
+	// ErrAlreadyCompleted is returned when a client finishes a build with Success or Abort which has already finished.
+	ErrAlreadyCompleted = errors.New("already completed")
+	// ErrBuildMismatch is returned when a client requests a build other than the build the server serves.
+	ErrBuildMismatch = errors.New("build mismatch")
+	// ErrChecksumMismatch is returned when the checksum of a received resource chunk does not match the chunk.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrInvalidArgument is returned when a request contains an invalid value.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrProtocolMismatch is returned when a message does not conform to the protocol the receiver implements,
+	// for example when the client and the server are built from incompatible versions.
+	ErrProtocolMismatch = errors.New("protocol mismatch")
+	// ErrResourceExhausted is returned when a request exceeds a configured limit.
+	ErrResourceExhausted = errors.New("resource exhausted")
+	// ErrResourceNotFound is returned when the server has no resource for a requested path.
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrServerStopped is returned when the server no longer serves requests.
+	ErrServerStopped = errors.New("server stopped")
+	// ErrUnauthorized is returned when a client is not allowed to call an RPC.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// StatusCode returns the gRPC status code an error is transferred with.
+// Errors not wrapping any of the package errors map to codes.Unknown.
+func StatusCode(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, ErrAlreadyCompleted):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrBuildMismatch):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrChecksumMismatch):
+		return codes.DataLoss
+	case errors.Is(err, ErrInvalidArgument):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrProtocolMismatch):
+		return codes.Unimplemented
+	case errors.Is(err, ErrResourceExhausted):
+		return codes.ResourceExhausted
+	case errors.Is(err, ErrResourceNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrServerStopped):
+		return codes.Unavailable
+	case errors.Is(err, ErrUnauthorized):
+		return codes.PermissionDenied
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	return codes.Unknown
+}
+
+// toStatusError converts an error returned by an RPC handler to a gRPC status error
+// carrying the status code of the error and the error details.
+func toStatusError(err error, sessionID string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+	details := &ErrorDetails{Reason: errorReason(err), SessionID: sessionID}
+	var resourceErr *resourcePathError
+	if errors.As(err, &resourceErr) {
+		details.Path = resourceErr.path
+		details.Stage = resourceErr.stage
+	}
+	s := status.New(StatusCode(err), err.Error())
+	withDetails, detailsErr := s.WithDetails(details.toProto()...)
+	if detailsErr != nil {
+		return s.Err()
+	}
+	return withDetails.Err()
+}
+
+// fromStatusError converts a gRPC status error received by the client to an error
+// wrapping the package error matching the status code, if any.
+// The result retains the gRPC status.
+func fromStatusError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	var sentinel error
+	switch s.Code() {
+	case codes.AlreadyExists:
+		sentinel = ErrAlreadyCompleted
+	case codes.DataLoss:
+		sentinel = ErrChecksumMismatch
+	case codes.FailedPrecondition:
+		sentinel = ErrBuildMismatch
+	case codes.InvalidArgument:
+		sentinel = ErrInvalidArgument
+	case codes.ResourceExhausted:
+		sentinel = ErrResourceExhausted
+	case codes.Unavailable:
+		sentinel = ErrServerStopped
+	case codes.Unimplemented:
+		sentinel = ErrProtocolMismatch
+	case codes.NotFound:
+		sentinel = ErrResourceNotFound
+	case codes.PermissionDenied, codes.Unauthenticated:
+		sentinel = ErrUnauthorized
+	default:
+		return err
+	}
+	return &statusError{sentinel: sentinel, status: s}
+}
+
+type statusError struct {
+	sentinel error
+	status   *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.status.Err().Error()
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *statusError) Unwrap() error {
+	return e.sentinel
+}
+
+// statusErrors converts errors returned by RPC handlers to gRPC status errors.
+type statusErrors struct {
+	sessionID string
+}
+
+func (e *statusErrors) unaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	response, err := handler(ctx, req)
+	return response, toStatusError(err, e.sessionID)
+}
+
+func (e *statusErrors) streamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, ss), e.sessionID)
+}
+
+func statusErrorUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return fromStatusError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// ErrorDetails contains the details of an error returned by the server.
+type ErrorDetails struct {
+	// Reason identifies the class of the failure, for example RESOURCE_NOT_FOUND.
+	Reason string
+	// Path is the resource path the error relates to, empty if not related to a resource.
+	Path string
+	// Stage is the stage of the resource path the error relates to.
+	Stage string
+	// SessionID identifies the server session which returned the error.
+	SessionID string
+}
+
+// ErrorDomain is the domain of the error details returned by the server.
+const ErrorDomain = "firebuild.combust-labs"
+
+// ErrorDetailsOf returns the details of an error returned by the server.
+// Returns false if the error carries no details.
+func ErrorDetailsOf(err error) (*ErrorDetails, bool) {
+	var rpcErr *statusError
+	if errors.As(err, &rpcErr) {
+		return errorDetailsFromStatus(rpcErr.status)
+	}
+	if s, ok := status.FromError(err); ok && err != nil {
+		return errorDetailsFromStatus(s)
+	}
+	var resourceErr *resourcePathError
+	if errors.As(err, &resourceErr) {
+		return &ErrorDetails{Reason: errorReason(err), Path: resourceErr.path, Stage: resourceErr.stage}, true
+	}
+	return nil, false
+}
+
+func errorDetailsFromStatus(s *status.Status) (*ErrorDetails, bool) {
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return &ErrorDetails{
+				Reason:    info.Reason,
+				Path:      info.Metadata["path"],
+				Stage:     info.Metadata["stage"],
+				SessionID: info.Metadata["sessionId"],
+			}, true
+		}
+	}
+	return nil, false
+}
+
+func (d *ErrorDetails) toProto() []protoiface.MessageV1 {
+	metadata := map[string]string{}
+	if d.SessionID != "" {
+		metadata["sessionId"] = d.SessionID
+	}
+	result := []protoiface.MessageV1{}
+	if d.Path != "" {
+		metadata["path"] = d.Path
+		metadata["stage"] = d.Stage
+		result = append(result, &errdetails.ResourceInfo{
+			ResourceType: "resource",
+			ResourceName: d.Path,
+		})
+	}
+	return append([]protoiface.MessageV1{&errdetails.ErrorInfo{
+		Reason:   d.Reason,
+		Domain:   ErrorDomain,
+		Metadata: metadata,
+	}}, result...)
+}
+
+// errorReason returns the reason identifying the class of an error.
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, ErrAlreadyCompleted):
+		return "ALREADY_COMPLETED"
+	case errors.Is(err, ErrBuildMismatch):
+		return "BUILD_MISMATCH"
+	case errors.Is(err, ErrChecksumMismatch):
+		return "CHECKSUM_MISMATCH"
+	case errors.Is(err, ErrInvalidArgument):
+		return "INVALID_ARGUMENT"
+	case errors.Is(err, ErrProtocolMismatch):
+		return "PROTOCOL_MISMATCH"
+	case errors.Is(err, ErrResourceExhausted):
+		return "RESOURCE_EXHAUSTED"
+	case errors.Is(err, ErrResourceNotFound):
+		return "RESOURCE_NOT_FOUND"
+	case errors.Is(err, ErrServerStopped):
+		return "SERVER_STOPPED"
+	case errors.Is(err, ErrUnauthorized):
+		return "UNAUTHORIZED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// resourcePathError relates an error to a requested resource path.
+type resourcePathError struct {
+	err   error
+	path  string
+	stage string
+}
+
+func withResourcePath(err error, path, stage string) error {
+	return &resourcePathError{err: err, path: path, stage: stage}
+}
+
+func (e *resourcePathError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resourcePathError) Unwrap() error {
+	return e.err
+}
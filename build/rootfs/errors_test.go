@@ -0,0 +1,59 @@
+package rootfs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorsStatusCodeRoundTrip(t *testing.T) {
+	for _, sentinel := range []error{ErrAlreadyCompleted, ErrBuildMismatch, ErrChecksumMismatch, ErrInvalidArgument, ErrProtocolMismatch,
+		ErrResourceExhausted, ErrResourceNotFound, ErrServerStopped, ErrUnauthorized} {
+		wrapped := fmt.Errorf("%w: details", sentinel)
+		assert.NotEqual(t, codes.Unknown, StatusCode(wrapped))
+
+		received := fromStatusError(toStatusError(withResourcePath(wrapped, "path", "stage"), "session"))
+		assert.True(t, errors.Is(received, sentinel), "expected '%v' to be '%v'", received, sentinel)
+		assert.Equal(t, StatusCode(wrapped), status.Code(received))
+
+		details, ok := ErrorDetailsOf(received)
+		assert.True(t, ok)
+		assert.Equal(t, errorReason(sentinel), details.Reason)
+		assert.Equal(t, "path", details.Path)
+		assert.Equal(t, "stage", details.Stage)
+		assert.Equal(t, "session", details.SessionID)
+	}
+	assert.Equal(t, codes.OK, StatusCode(nil))
+	assert.Equal(t, codes.Unknown, StatusCode(fmt.Errorf("other")))
+}
+
+func TestClientResourceNotFound(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	resourceChannel, err := testClient.Resource("missing")
+	assert.Nil(t, err)
+	resourceErr, isErr := (<-resourceChannel).(error)
+	assert.True(t, isErr)
+	assert.True(t, errors.Is(resourceErr, ErrResourceNotFound), "expected not found, got: %v", resourceErr)
+	assert.Equal(t, codes.NotFound, StatusCode(resourceErr))
+	details, ok := ErrorDetailsOf(resourceErr)
+	assert.True(t, ok)
+	assert.Equal(t, "RESOURCE_NOT_FOUND", details.Reason)
+	assert.Equal(t, "missing", details.Path)
+	assert.NotEmpty(t, details.SessionID)
+
+	assert.True(t, errors.Is(testClient.CommandStarted(1), ErrInvalidArgument))
+}
@@ -0,0 +1,177 @@
+package rootfs
+
+import "sync"
+
+// DefaultEventSubscriberBufferSize is the number of events buffered for every event subscriber.
+const DefaultEventSubscriberBufferSize = 1024
+
+// broadcaster delivers published values to any number of subscribers, eventBroadcaster and logBroadcaster
+// wrap it for their channel types. Values published with offer never block the publisher: when the buffer
+// of a subscriber is full, the value is dropped for that subscriber. A value published with deliver is not dropped:
+// the publisher waits until every subscriber received it, ended the subscription or the broadcaster closed.
+type broadcaster struct {
+	// publishing orders the published values, a delivered value is received before any later value:
+	publishing  sync.Mutex
+	m           sync.Mutex
+	closed      bool
+	nextID      int
+	subscribers map[int]*subscription
+}
+
+// subscription is a subscriber of a broadcaster.
+type subscription struct {
+	// send sends a value to the channel of the subscriber, without blocking when cancel is nil,
+	// otherwise until cancel is closed. Returns false when the value was not sent.
+	send func(value interface{}, cancel <-chan struct{}) bool
+	// closeChannel closes the channel of the subscriber.
+	closeChannel func()
+	// ended is closed when the subscription ends, a delivery then stops waiting for the subscriber:
+	ended chan struct{}
+	// delivering counts the deliveries in progress, the channel is closed only once they returned:
+	delivering sync.WaitGroup
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subscribers: map[int]*subscription{}}
+}
+
+// subscribe adds a subscriber and returns the function ending the subscription.
+// The channel of the subscriber is closed when the subscription ends or the broadcaster closes.
+func (b *broadcaster) subscribe(send func(value interface{}, cancel <-chan struct{}) bool, closeChannel func()) func() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.closed {
+		closeChannel()
+		return func() {}
+	}
+	id := b.nextID
+	b.nextID = b.nextID + 1
+	b.subscribers[id] = &subscription{send: send, closeChannel: closeChannel, ended: make(chan struct{})}
+	return func() {
+		b.m.Lock()
+		s, ok := b.subscribers[id]
+		delete(b.subscribers, id)
+		b.m.Unlock()
+		if ok {
+			s.end()
+		}
+	}
+}
+
+// end stops the deliveries to the subscription and closes the channel of the subscriber.
+func (s *subscription) end() {
+	close(s.ended)
+	s.delivering.Wait()
+	s.closeChannel()
+}
+
+// offer publishes the values, a subscriber with a full buffer misses them.
+func (b *broadcaster) offer(values ...interface{}) {
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+	b.m.Lock()
+	defer b.m.Unlock()
+	for _, value := range values {
+		for _, s := range b.subscribers {
+			s.send(value, nil)
+		}
+	}
+}
+
+// deliver publishes the value, waiting for the subscribers with a full buffer.
+func (b *broadcaster) deliver(value interface{}) {
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+	b.m.Lock()
+	subscriptions := make([]*subscription, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		s.delivering.Add(1)
+		subscriptions = append(subscriptions, s)
+	}
+	b.m.Unlock()
+	for _, s := range subscriptions {
+		s.send(value, s.ended)
+		s.delivering.Done()
+	}
+}
+
+// close ends every subscription, later subscriptions end immediately.
+func (b *broadcaster) close() {
+	b.m.Lock()
+	if b.closed {
+		b.m.Unlock()
+		return
+	}
+	b.closed = true
+	subscriptions := b.subscribers
+	b.subscribers = map[int]*subscription{}
+	b.m.Unlock()
+	for _, s := range subscriptions {
+		s.end()
+	}
+}
+
+// eventBroadcaster delivers copies of the server events to any number of subscribers.
+// A subscriber not keeping up misses events, except the events ending the build, see isFinalEvent.
+type eventBroadcaster struct {
+	*broadcaster
+}
+
+func newEventBroadcaster() *eventBroadcaster {
+	return &eventBroadcaster{broadcaster: newBroadcaster()}
+}
+
+// subscribe returns a channel receiving published events and a function ending the subscription.
+// The channel is closed when the subscription ends or the broadcaster closes.
+func (b *eventBroadcaster) subscribe() (<-chan interface{}, func()) {
+	chanEvents := make(chan interface{}, DefaultEventSubscriberBufferSize)
+	return chanEvents, b.broadcaster.subscribe(func(value interface{}, cancel <-chan struct{}) bool {
+		if cancel == nil {
+			select {
+			case chanEvents <- value:
+				return true
+			default:
+				return false
+			}
+		}
+		select {
+		case chanEvents <- value:
+			return true
+		case <-cancel:
+			return false
+		}
+	}, func() { close(chanEvents) })
+}
+
+func (b *eventBroadcaster) publish(event interface{}) {
+	if isFinalEvent(event) {
+		b.deliver(event)
+		return
+	}
+	b.offer(event)
+}
+
+// isFinalEvent returns true for the events ending the build, a subscriber always receives them.
+func isFinalEvent(event interface{}) bool {
+	switch event.(type) {
+	case *ClientMsgAborted, *ClientMsgSuccess, *ControlMsgBuildTimeout:
+		return true
+	}
+	return false
+}
+
+// emit publishes the event to the subscribers and sends it to the OnMessage consumer.
+// After the server stopped, the event is dropped rather than blocking the handler.
+func (impl *serverImpl) emit(event interface{}) {
+	impl.events.publish(event)
+	select {
+	case impl.chanMessages <- event:
+	case <-impl.chanStopped:
+	}
+}
+
+// Subscribe returns a channel receiving a copy of every event emitted via OnMessage
+// and a function ending the subscription.
+func (impl *serverImpl) Subscribe() (<-chan interface{}, func()) {
+	return impl.events.subscribe()
+}
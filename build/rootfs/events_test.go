@@ -0,0 +1,107 @@
+package rootfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerEventSubscribers(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+		ResourcesResolved:  make(Resources),
+	})
+
+	first, _ := srv.Subscribe()
+	second, _ := srv.Subscribe()
+	ended, unsubscribe := srv.Subscribe()
+	unsubscribe()
+	_, ok := <-ended
+	assert.False(t, ok, "expected the channel of an ended subscription to be closed")
+
+	received := []interface{}{}
+	chanDone := make(chan struct{})
+	go func() {
+		defer close(chanDone)
+		for message := range srv.OnMessage() {
+			received = append(received, message)
+			if _, ok := message.(*ClientMsgSuccess); ok {
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.Ping())
+	assert.Nil(t, testClient.StdOut([]string{"line"}))
+	assert.Nil(t, testClient.Success())
+	<-chanDone
+
+	expected := []interface{}{
+		&ControlMsgPingSent{},
+		&ClientMsgStdout{Lines: []string{"line"}},
+		&ClientMsgSuccess{},
+	}
+	assert.Equal(t, expected, received)
+	for _, subscriber := range []<-chan interface{}{first, second} {
+		for _, event := range expected {
+			assert.Equal(t, event, <-subscriber, "expected every subscriber to receive every event")
+		}
+	}
+
+	srv.Stop()
+	_, ok = <-first
+	assert.False(t, ok, "expected the subscriptions to end when the server stops")
+	late, _ := srv.Subscribe()
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestEventBroadcasterDeliversFinalEvents(t *testing.T) {
+	broadcaster := newEventBroadcaster()
+	subscriber, unsubscribe := broadcaster.subscribe()
+	defer unsubscribe()
+
+	// fill the buffer, the subscriber misses the overflowing events but not the final one:
+	for i := 0; i < DefaultEventSubscriberBufferSize+10; i++ {
+		broadcaster.publish(&ControlMsgPingSent{})
+	}
+	chanPublished := make(chan struct{})
+	go func() {
+		defer close(chanPublished)
+		broadcaster.publish(&ClientMsgSuccess{})
+	}()
+	select {
+	case <-chanPublished:
+		t.Fatal("expected the final event to wait for the subscriber")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for i := 0; i < DefaultEventSubscriberBufferSize; i++ {
+		assert.Equal(t, &ControlMsgPingSent{}, <-subscriber)
+	}
+	assert.Equal(t, &ClientMsgSuccess{}, <-subscriber, "expected the final event delivered")
+	<-chanPublished
+
+	// a subscriber not draining does not block the broadcaster forever:
+	stalled, _ := broadcaster.subscribe()
+	for i := 0; i < DefaultEventSubscriberBufferSize; i++ {
+		broadcaster.publish(&ControlMsgPingSent{})
+	}
+	chanPublished = make(chan struct{})
+	go func() {
+		defer close(chanPublished)
+		broadcaster.publish(&ClientMsgAborted{})
+	}()
+	for i := 0; i < DefaultEventSubscriberBufferSize; i++ {
+		<-subscriber
+	}
+	broadcaster.close()
+	<-chanPublished
+	for range stalled {
+	}
+}
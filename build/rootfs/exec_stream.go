@@ -0,0 +1,233 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+const (
+	// DefaultStreamBatchLines is the maximum number of lines RunAndStream sends in a single request.
+	DefaultStreamBatchLines = 100
+	// DefaultStreamBatchBytes is the maximum number of line bytes RunAndStream buffers before sending them.
+	DefaultStreamBatchBytes = 256 * 1024
+	// DefaultStreamFlushInterval is the maximum duration RunAndStream buffers a line before sending it.
+	DefaultStreamFlushInterval = 100 * time.Millisecond
+)
+
+// RunAndStream runs the command and sends its stdout and stderr lines to the server until the command exits.
+// The lines are sent in batches. A carriage return not followed by a line feed rewrites the line,
+// only the final state of a line rewritten by a progress bar is sent. Lines longer than GRPCClientConfig.MaxLogLineBytes
+// are truncated without buffering more than the limit. The command must not have its Stdout and Stderr set.
+// When the context is done, the command is killed.
+// Returns the context error, the error of the command or the first error sending the lines, in that order.
+func (c *defaultClient) RunAndStream(ctx context.Context, cmd *exec.Cmd) error {
+	return c.runAndStream(ctx, cmd, func(string) {})
+}
+
+// RunAndStreamWithRetry runs the command created by newCmd like RunAndStream until it succeeds or the retry policy
+// of the command at the index does not retry the failure, acknowledging every retried failure with CommandRetried.
+// A nil policy runs the command once. The context error is not retried.
+// Returns the number of attempts and the error of the last attempt.
+func (c *defaultClient) RunAndStreamWithRetry(ctx context.Context, index int, policy *commands.RetryPolicy, newCmd func() *exec.Cmd) (int, error) {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		stderrLines := []string{}
+		err := c.runAndStream(ctx, newCmd(), func(line string) {
+			stderrLines = append(stderrLines, line)
+		})
+		if err == nil || ctx.Err() != nil {
+			return attempt, err
+		}
+		exitCode := -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		if !policy.ShouldRetry(attempt, exitCode, stderrLines) {
+			return attempt, err
+		}
+		if ackErr := c.CommandRetried(index, err); ackErr != nil {
+			return attempt, ackErr
+		}
+		select {
+		case <-time.After(policy.Delay()):
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		}
+	}
+}
+
+// runAndStream runs the command like RunAndStream, passing every stderr line to the observer.
+func (c *defaultClient) runAndStream(ctx context.Context, cmd *exec.Cmd, stderrObserver func(string)) error {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return fmt.Errorf("%w: command stdout and stderr must not be set", ErrInvalidArgument)
+	}
+	stdout := newLineBatcher(c.StdOut)
+	stderr := newLineBatcher(c.StdErr)
+	stdoutWriter := newLineWriter(c.maxLogLineBytes, stdout.add)
+	stderrWriter := newLineWriter(c.maxLogLineBytes, func(line string) {
+		stderrObserver(line)
+		stderr.add(line)
+	})
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	chanFlushDone := make(chan struct{})
+	defer close(chanFlushDone)
+	go func() {
+		ticker := time.NewTicker(DefaultStreamFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stdout.flush()
+				stderr.flush()
+			case <-chanFlushDone:
+				return
+			}
+		}
+	}()
+
+	chanWait := make(chan error, 1)
+	go func() {
+		chanWait <- cmd.Wait()
+	}()
+
+	var waitErr error
+	select {
+	case waitErr = <-chanWait:
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-chanWait
+		waitErr = ctx.Err()
+	}
+
+	stdoutWriter.close()
+	stderrWriter.close()
+	stdoutErr := stdout.flush()
+	stderrErr := stderr.flush()
+
+	if waitErr != nil {
+		return waitErr
+	}
+	if stdoutErr != nil {
+		return stdoutErr
+	}
+	return stderrErr
+}
+
+// lineWriter splits the written bytes into lines.
+type lineWriter struct {
+	m            sync.Mutex
+	emit         func(string)
+	line         []byte
+	maxLineBytes int
+	truncated    int
+	carriage     bool
+}
+
+func newLineWriter(maxLineBytes int, emit func(string)) *lineWriter {
+	return &lineWriter{emit: emit, maxLineBytes: maxLineBytes}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	for _, b := range p {
+		if w.carriage {
+			w.carriage = false
+			if b != '\n' {
+				// a carriage return not followed by a line feed rewrites the line:
+				w.line = w.line[:0]
+				w.truncated = 0
+			}
+		}
+		switch b {
+		case '\n':
+			w.emitLine()
+		case '\r':
+			w.carriage = true
+		default:
+			if w.maxLineBytes <= 0 || len(w.line) < w.maxLineBytes {
+				w.line = append(w.line, b)
+			} else {
+				w.truncated = w.truncated + 1
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// close emits the unterminated last line, if any.
+func (w *lineWriter) close() {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if len(w.line) > 0 || w.truncated > 0 {
+		w.emitLine()
+	}
+	w.carriage = false
+}
+
+func (w *lineWriter) emitLine() {
+	line := string(w.line)
+	if w.truncated > 0 {
+		line, _ = truncatedLogLine(line, len(line)+w.truncated, w.maxLineBytes)
+	}
+	w.emit(line)
+	w.line = w.line[:0]
+	w.truncated = 0
+}
+
+// lineBatcher sends lines in batches.
+type lineBatcher struct {
+	m        sync.Mutex
+	send     func([]string) error
+	lines    []string
+	bytes    int
+	firstErr error
+}
+
+func newLineBatcher(send func([]string) error) *lineBatcher {
+	return &lineBatcher{send: send}
+}
+
+func (b *lineBatcher) add(line string) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.lines = append(b.lines, line)
+	b.bytes = b.bytes + len(line)
+	if len(b.lines) >= DefaultStreamBatchLines || b.bytes >= DefaultStreamBatchBytes {
+		b.flushLocked()
+	}
+}
+
+// flush sends the buffered lines and returns the first error sending the lines so far.
+func (b *lineBatcher) flush() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.flushLocked()
+	return b.firstErr
+}
+
+func (b *lineBatcher) flushLocked() {
+	if len(b.lines) == 0 {
+		return
+	}
+	if err := b.send(b.lines); err != nil && b.firstErr == nil {
+		b.firstErr = err
+	}
+	b.lines = nil
+	b.bytes = 0
+}
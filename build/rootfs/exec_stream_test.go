@@ -0,0 +1,59 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/utilstest"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := []string{}
+	writer := newLineWriter(30, func(line string) {
+		lines = append(lines, line)
+	})
+	writer.Write([]byte("first\nsec"))
+	writer.Write([]byte("ond\r\n10%\r50%\r100%\n"))
+	writer.Write([]byte("a very long line that goes on and on\nlast"))
+	writer.close()
+	assert.Equal(t, []string{"first", "second", "100%", "a very... [30 bytes truncated]", "last"}, lines)
+}
+
+func TestRunAndStream(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, hclog.Default(), &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer cleanupFunc()
+
+	cmd := exec.Command("sh", "-c", `printf 'out 1\nout 2\n'; printf 'err 1\n' >&2; printf 'progress 1\rprogress 2\n'`)
+	assert.Nil(t, testClient.RunAndStream(context.Background(), cmd))
+	utilstest.MustEventuallyWithDefaults(t, func() error {
+		if strings.Join(testServer.ReceivedStdout(), "|") != "out 1|out 2|progress 2" {
+			return errors.New("stdout not received yet")
+		}
+		if strings.Join(testServer.ReceivedStderr(), "|") != "err 1" {
+			return errors.New("stderr not received yet")
+		}
+		return nil
+	})
+
+	// the error of the command is returned:
+	assert.NotNil(t, testClient.RunAndStream(context.Background(), exec.Command("sh", "-c", "exit 3")))
+
+	// the command is killed when the context is done:
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFunc()
+	assert.Equal(t, context.DeadlineExceeded, testClient.RunAndStream(ctx, exec.Command("sh", "-c", "exec sleep 10")))
+}
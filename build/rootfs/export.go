@@ -0,0 +1,82 @@
+package rootfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// workContextExport is the serialized form of a work context.
+type workContextExport struct {
+	SchemaVersion      int                   `json:"SchemaVersion"`
+	Commands           []json.RawMessage     `json:"Commands"`
+	Platform           string                `json:"Platform,omitempty"`
+	Args               map[string]string     `json:"Args,omitempty"`
+	Env                map[string]string     `json:"Env,omitempty"`
+	CommandTimeouts    map[int]time.Duration `json:"CommandTimeouts,omitempty"`
+	ResourcePriorities map[string]int        `json:"ResourcePriorities,omitempty"`
+}
+
+// Export serializes the commands and the settings of the work context as JSON stamped with
+// the schema version of the commands, commands.SchemaVersion.
+// Resolved resources, the checksum cache, the spool, the scratch directory and the hooks are not exported,
+// the importing side resolves the resources of the commands again.
+func (ctx *WorkContext) Export() ([]byte, error) {
+	export := &workContextExport{
+		SchemaVersion:      commands.SchemaVersion,
+		Commands:           []json.RawMessage{},
+		Platform:           ctx.Platform,
+		Args:               ctx.Args,
+		Env:                ctx.Env,
+		CommandTimeouts:    ctx.CommandTimeouts,
+		ResourcePriorities: ctx.ResourcePriorities,
+	}
+	for index, cmd := range ctx.ExecutableCommands {
+		commandBytes, err := commands.Serialize(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed serializing command %d: %v", index, err)
+		}
+		export.Commands = append(export.Commands, commandBytes)
+	}
+	return json.Marshal(export)
+}
+
+// ImportWorkContext deserializes a work context exported with any schema version up to commands.SchemaVersion,
+// migrating the commands of older versions. The imported work context has no resolved resources.
+func ImportWorkContext(data []byte) (*WorkContext, error) {
+	export := &workContextExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return nil, fmt.Errorf("%w: work context export not readable: %v", ErrInvalidArgument, err)
+	}
+	if export.SchemaVersion > commands.SchemaVersion {
+		return nil, fmt.Errorf("%w: work context exported with schema version %d, this build supports versions up to %d, upgrade the importing side",
+			commands.ErrUnsupportedSchemaVersion, export.SchemaVersion, commands.SchemaVersion)
+	}
+	ctx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+		Platform:           export.Platform,
+		Args:               export.Args,
+		Env:                export.Env,
+		CommandTimeouts:    export.CommandTimeouts,
+		ResourcePriorities: export.ResourcePriorities,
+	}
+	for index, commandBytes := range export.Commands {
+		raw := map[string]interface{}{}
+		if err := json.Unmarshal(commandBytes, &raw); err != nil {
+			return nil, fmt.Errorf("%w: command %d not readable: %v", ErrInvalidArgument, index, err)
+		}
+		// a command without its own version has the version of the export:
+		if _, ok := raw[commands.SchemaVersionKey]; !ok && export.SchemaVersion > 0 {
+			raw[commands.SchemaVersionKey] = float64(export.SchemaVersion)
+		}
+		cmd, err := commands.DeserializeRaw(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed importing command %d: %w", index, err)
+		}
+		ctx.ExecutableCommands = append(ctx.ExecutableCommands, cmd)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,46 @@
+package rootfs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkContextExportImport(t *testing.T) {
+	workContext := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.Add{
+				OriginalCommand: "ADD source /opt/target",
+				OriginalSource:  "/context/Dockerfile",
+				Source:          "source",
+				Target:          "/opt/target",
+				Workdir:         commands.DefaultWorkdir(),
+				User:            commands.DefaultUser(),
+			},
+			commands.RunWithDefaults("make install"),
+			commands.DeleteWithDefaults("/opt/target"),
+		},
+		Platform:           "linux/arm64",
+		Env:                map[string]string{"GOOS": "linux"},
+		CommandTimeouts:    map[int]time.Duration{1: time.Minute},
+		ResourcePriorities: map[string]int{"source": 0},
+	}
+	data, err := workContext.Export()
+	assert.Nil(t, err)
+
+	imported, err := ImportWorkContext(data)
+	assert.Nil(t, err)
+	assert.Equal(t, workContext.ExecutableCommands, imported.ExecutableCommands)
+	assert.Equal(t, workContext.Platform, imported.Platform)
+	assert.Equal(t, workContext.Env, imported.Env)
+	assert.Equal(t, workContext.CommandTimeouts, imported.CommandTimeouts)
+	assert.Equal(t, workContext.ResourcePriorities, imported.ResourcePriorities)
+	assert.Equal(t, 0, len(imported.ResourcesResolved))
+
+	_, err = ImportWorkContext([]byte(strings.Replace(string(data), `"SchemaVersion":2`, `"SchemaVersion":3`, 1)))
+	assert.True(t, errors.Is(err, commands.ErrUnsupportedSchemaVersion))
+}
@@ -0,0 +1,42 @@
+package rootfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// osReleasePath is the file identifying the distribution of the guest.
+const osReleasePath = "/etc/os-release"
+
+// DefaultFacts returns the facts of the guest the client runs on: the architecture, the operating system
+// and, when the guest has an /etc/os-release file, the ID of the distribution.
+func DefaultFacts() commands.Facts {
+	facts := commands.Facts{
+		commands.FactArch: runtime.GOARCH,
+		commands.FactOS:   runtime.GOOS,
+	}
+	if file, err := os.Open(osReleasePath); err == nil {
+		defer file.Close()
+		if distro := osReleaseID(file); distro != "" {
+			facts[commands.FactDistro] = distro
+		}
+	}
+	return facts
+}
+
+// osReleaseID returns the ID of the distribution from the contents of an os-release file, empty if not found.
+func osReleaseID(reader io.Reader) string {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "ID=") {
+			return strings.Trim(strings.TrimPrefix(line, "ID="), `"'`)
+		}
+	}
+	return ""
+}
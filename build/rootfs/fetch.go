@@ -0,0 +1,97 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFetchConcurrency is the default number of resources fetched at the same time.
+const DefaultFetchConcurrency = 4
+
+// FetchOptions configures fetching multiple resources.
+type FetchOptions struct {
+	// Concurrency is the number of resources fetched at the same time, DefaultFetchConcurrency when zero.
+	Concurrency int
+	// Priorities optionally maps the resource paths to their priorities, lower values are fetched first.
+	// When nil, the priorities listed in the manifest of all resources are used.
+	Priorities map[string]int
+	// WriteOptions configures writing every fetched resource.
+	WriteOptions *WriteOptions
+}
+
+// WithDefaultsApplied returns the options with the defaults applied to unset values.
+func (opts *FetchOptions) WithDefaultsApplied() *FetchOptions {
+	result := &FetchOptions{}
+	if opts != nil {
+		*result = *opts
+	}
+	if result.Concurrency <= 0 {
+		result.Concurrency = DefaultFetchConcurrency
+	}
+	return result
+}
+
+// FetchResources writes the resources served for the paths under the root directory, fetching multiple resources
+// at the same time. The resources are started in the order of their priorities so the resources needed first
+// are available as early as possible. The first failure cancels the remaining fetches.
+func (c *defaultClient) FetchResources(ctx context.Context, paths []string, rootDir string, opts *FetchOptions) error {
+	opts = opts.WithDefaultsApplied()
+
+	priorities := opts.Priorities
+	if priorities == nil {
+		entries, err := c.Manifest(ctx, "")
+		if err != nil {
+			return errors.Wrap(err, "failed listing resource priorities")
+		}
+		priorities = map[string]int{}
+		for _, entry := range entries {
+			priorities[entry.Path] = entry.Priority
+		}
+	}
+
+	ordered := make([]string, len(paths))
+	copy(ordered, paths)
+	sortByPriority(ordered, priorities)
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+
+	chanPaths := make(chan string)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < minInt(opts.Concurrency, len(ordered)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for resourcePath := range chanPaths {
+				if err := c.WriteResources(ctx, resourcePath, rootDir, opts.WriteOptions); err != nil {
+					errOnce.Do(func() {
+						firstErr = errors.Wrapf(err, "failed fetching resource '%s'", resourcePath)
+						cancelFunc()
+					})
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, resourcePath := range ordered {
+		select {
+		case chanPaths <- resourcePath:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(chanPaths)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
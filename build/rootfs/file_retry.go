@@ -0,0 +1,149 @@
+package rootfs
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultFileRetryBackoff is the default wait before the first retry of a file operation.
+	DefaultFileRetryBackoff = 50 * time.Millisecond
+	// DefaultFileRetryMaxBackoff is the default maximum wait between the retries of a file operation.
+	DefaultFileRetryMaxBackoff = 2 * time.Second
+)
+
+// FileRetryPolicy configures the retries of opening and reading the served files on transient
+// file system errors, for example ESTALE of an NFS mount or EIO of an overlay file system.
+// A read is retried by opening the file again and continuing at the offset of the failed read.
+type FileRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of an open or a read, including the first attempt.
+	// Zero or one disables the retries.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for every subsequent retry.
+	// Default is DefaultFileRetryBackoff.
+	Backoff time.Duration
+	// MaxBackoff is the maximum wait between the retries. Default is DefaultFileRetryMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// isTransientFileError returns true if the file operation may succeed when retried.
+func isTransientFileError(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EIO)
+}
+
+// fileRetries counts the retried and the failed file operations of a server.
+type fileRetries struct {
+	retried int64
+	failed  int64
+}
+
+// fileRetrier opens and reads files retrying the transient errors, a nil retrier does not retry.
+type fileRetrier struct {
+	policy FileRetryPolicy
+	counts *fileRetries
+	// onRetry is called before every retry.
+	onRetry func(name string, attempt int, err error)
+}
+
+func newFileRetrier(policy FileRetryPolicy, counts *fileRetries, onRetry func(name string, attempt int, err error)) *fileRetrier {
+	if policy.MaxAttempts <= 1 {
+		return nil
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = DefaultFileRetryBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultFileRetryMaxBackoff
+	}
+	return &fileRetrier{policy: policy, counts: counts, onRetry: onRetry}
+}
+
+// retry calls the operation until it succeeds, fails with an error which is not transient
+// or the attempts are exhausted.
+func (r *fileRetrier) retry(name string, operation func() error) error {
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || !isTransientFileError(err) {
+			return err
+		}
+		if attempt >= r.policy.MaxAttempts {
+			atomic.AddInt64(&r.counts.failed, 1)
+			return err
+		}
+		atomic.AddInt64(&r.counts.retried, 1)
+		if r.onRetry != nil {
+			r.onRetry(name, attempt, err)
+		}
+		time.Sleep(backoff)
+		backoff = backoff * 2
+		if backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+// open opens the named contents with the open function, the returned reader retries the failed reads
+// by opening the contents again at the offset of the failed read.
+func (r *fileRetrier) open(name string, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if r == nil {
+		return open()
+	}
+	var reader io.ReadCloser
+	if err := r.retry(name, func() error {
+		opened, err := open()
+		reader = opened
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return &retryingReader{retrier: r, name: name, open: open, current: reader}, nil
+}
+
+type retryingReader struct {
+	retrier *fileRetrier
+	name    string
+	open    func() (io.ReadCloser, error)
+	current io.ReadCloser
+	offset  int64
+}
+
+func (rr *retryingReader) Read(p []byte) (int, error) {
+	var n int
+	err := rr.retrier.retry(rr.name, func() error {
+		if rr.current == nil {
+			reopened, err := rr.open()
+			if err != nil {
+				return err
+			}
+			if err := skipContents(reopened, rr.offset); err != nil {
+				reopened.Close()
+				return err
+			}
+			rr.current = reopened
+		}
+		read, err := rr.current.Read(p)
+		n = read
+		rr.offset = rr.offset + int64(read)
+		if err != nil && isTransientFileError(err) {
+			// the next attempt opens the contents again at the offset:
+			rr.current.Close()
+			rr.current = nil
+			if read > 0 {
+				return nil
+			}
+		}
+		return err
+	})
+	return n, err
+}
+
+func (rr *retryingReader) Close() error {
+	if rr.current == nil {
+		return nil
+	}
+	return rr.current.Close()
+}
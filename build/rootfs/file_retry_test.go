@@ -0,0 +1,210 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyReader fails with the error once after reading the given number of bytes.
+type flakyReader struct {
+	io.Reader
+	failAfter int
+	err       error
+	read      int
+}
+
+func (r *flakyReader) Read(p []byte) (int, error) {
+	if r.err != nil && r.read >= r.failAfter {
+		err := r.err
+		r.err = nil
+		return 0, err
+	}
+	if r.err != nil && len(p) > r.failAfter-r.read {
+		p = p[0 : r.failAfter-r.read]
+	}
+	n, err := r.Reader.Read(p)
+	r.read = r.read + n
+	return n, err
+}
+
+func (r *flakyReader) Close() error {
+	return nil
+}
+
+func TestFileRetrierRetriesTransientErrors(t *testing.T) {
+	counts := &fileRetries{}
+	retrier := newFileRetrier(FileRetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}, counts, nil)
+
+	opens := 0
+	reader, err := retrier.open("file", func() (io.ReadCloser, error) {
+		opens = opens + 1
+		switch opens {
+		case 1:
+			return nil, &fs.PathError{Op: "open", Path: "file", Err: syscall.ESTALE}
+		case 2:
+			return &flakyReader{Reader: strings.NewReader("transient contents"), failAfter: 9, err: syscall.EIO}, nil
+		default:
+			return ioutil.NopCloser(strings.NewReader("transient contents")), nil
+		}
+	})
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "transient contents", string(contents))
+	assert.Equal(t, 3, opens)
+	assert.Equal(t, int64(2), counts.retried)
+	assert.Equal(t, int64(0), counts.failed)
+
+	// errors which are not transient are not retried:
+	permissionErr := &fs.PathError{Op: "open", Path: "file", Err: syscall.EACCES}
+	_, err = retrier.open("file", func() (io.ReadCloser, error) {
+		return nil, permissionErr
+	})
+	assert.Equal(t, permissionErr, err)
+
+	// the attempts are bounded:
+	_, err = retrier.open("file", func() (io.ReadCloser, error) {
+		return nil, fmt.Errorf("resource failed: %w", syscall.ESTALE)
+	})
+	assert.True(t, errors.Is(err, syscall.ESTALE))
+	assert.Equal(t, int64(4), counts.retried)
+	assert.Equal(t, int64(1), counts.failed)
+
+	// no policy, no retries:
+	assert.Nil(t, newFileRetrier(FileRetryPolicy{}, counts, nil))
+}
+
+func TestServerRetriesTransientFileErrors(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	opens := 0
+	flaky := resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+		opens = opens + 1
+		if opens == 1 {
+			return nil, &fs.PathError{Op: "open", Path: "/nfs/file", Err: syscall.ESTALE}
+		}
+		return ioutil.NopCloser(strings.NewReader("served after a retry")), nil
+	}, 0644, "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "/nfs/file")
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{
+		FileRetry: FileRetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  Resources{"file": []resources.ResolvedResource{flaky}},
+	})
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	reader, _, err := testClient.OpenResource(context.Background(), "file")
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	reader.Close()
+	assert.Equal(t, "served after a retry", string(contents))
+	assert.Equal(t, int64(1), srv.Stats().FileRetries)
+	assert.Equal(t, int64(0), srv.Stats().FileRetryFailures)
+}
+
+// failingReader reads the contents and then fails with the error on every read.
+type failingReader struct {
+	contents []byte
+	err      error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.contents) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.contents)
+	r.contents = r.contents[n:]
+	return n, nil
+}
+
+// eofReader returns the last bytes together with io.EOF.
+type eofReader struct {
+	contents []byte
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n := copy(p, r.contents)
+	r.contents = r.contents[n:]
+	if len(r.contents) == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestServerFailsPersistentFileErrors(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	failing := resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(&failingReader{contents: []byte("partial"),
+			err: &fs.PathError{Op: "read", Path: "/nfs/file", Err: syscall.EIO}}), nil
+	}, 0644, "failing", "/etc/failing", commands.DefaultWorkdir(), commands.DefaultUser(), "/nfs/failing")
+	eof := resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(&eofReader{contents: []byte("read with EOF")}), nil
+	}, 0644, "eof", "/etc/eof", commands.DefaultWorkdir(), commands.DefaultUser(), "eof")
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{
+		FileRetry: FileRetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  Resources{"failing": []resources.ResolvedResource{failing}, "eof": []resources.ResolvedResource{eof}},
+	})
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	chanRead := make(chan error, 1)
+	go func() {
+		reader, _, err := testClient.OpenResource(context.Background(), "failing")
+		if err == nil {
+			_, err = ioutil.ReadAll(reader)
+			reader.Close()
+		}
+		chanRead <- err
+	}()
+	select {
+	case err := <-chanRead:
+		assert.NotNil(t, err, "expected the read error reported to the client")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to end on a persistent read error")
+	}
+
+	reader, _, err := testClient.OpenResource(context.Background(), "eof")
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	reader.Close()
+	assert.Equal(t, "read with EOF", string(contents), "expected the bytes read with io.EOF served")
+}
@@ -0,0 +1,136 @@
+package rootfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// Fingerprint returns the hex encoded SHA-256 digest identifying the build independently of the build ID:
+// the commands as sent to the client in order, the command groups, the platform, the ARG and ENV values
+// and the resources by path with their targets, modes, owners and contents. Directory resources
+// are walked in sorted order. Equal work contexts have equal fingerprints, the contents are streamed
+// so that large resources are not buffered. The hooks, the caches, the timeouts and the contents
+// of the sensitive resources are not included.
+func (ctx *WorkContext) Fingerprint() (string, error) {
+	digest := sha256.New()
+
+	writeFingerprintField(digest, "commands", strconv.Itoa(len(ctx.ExecutableCommands)))
+	for _, cmd := range ctx.substitutedCommands() {
+		commandBytes, err := commands.Serialize(commands.ApplyDefaults(cmd, ctx.defaultUser(), ctx.defaultWorkdir()))
+		if err != nil {
+			return "", err
+		}
+		writeFingerprintField(digest, "command", string(commandBytes))
+	}
+	if len(ctx.CommandGroups) == len(ctx.ExecutableCommands) {
+		for _, group := range ctx.CommandGroups {
+			writeFingerprintField(digest, "group", strconv.Itoa(group))
+		}
+	}
+	writeFingerprintField(digest, "platform", ctx.Platform)
+	writeFingerprintValues(digest, "arg", ctx.Args)
+	writeFingerprintValues(digest, "env", ctx.Env)
+
+	paths := []string{}
+	for resourcePath := range ctx.ResourcesResolved {
+		paths = append(paths, resourcePath)
+	}
+	sort.Strings(paths)
+	for _, resourcePath := range paths {
+		writeFingerprintField(digest, "resources", resourcePath)
+		for _, resource := range ctx.ResourcesResolved[resourcePath] {
+			if err := ctx.fingerprintResource(digest, resource); err != nil {
+				return "", withResourcePath(err, resourcePath, "")
+			}
+		}
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// computeFingerprint computes the fingerprint of the work context for the summary.
+func (impl *serverImpl) computeFingerprint() {
+	fingerprint, err := impl.serverCtx.Fingerprint()
+	if err != nil {
+		impl.logger.Warn("build fingerprint not computed", "reason", err)
+		return
+	}
+	impl.m.Lock()
+	impl.summary.Fingerprint = fingerprint
+	impl.m.Unlock()
+	impl.logger.Info("build fingerprint computed", "fingerprint", fingerprint)
+}
+
+// fingerprintResource writes a resource to the digest the way the server streams it to the client.
+func (ctx *WorkContext) fingerprintResource(digest hash.Hash, resource resources.ResolvedResource) error {
+	writeFingerprintField(digest, "platform", resources.PlatformOf(resource))
+	writeFingerprintField(digest, "source", resource.SourcePath())
+	writeFingerprintField(digest, "target", resource.TargetPath())
+	writeFingerprintField(digest, "mode", strconv.FormatUint(uint64(resource.TargetMode()), 8))
+	writeFingerprintField(digest, "user", encodeHeaderUser(resource.TargetUser()))
+	writeFingerprintField(digest, "workdir", resource.TargetWorkdir().Value)
+
+	if resources.IsSensitive(resource) {
+		// the secret is identified by its source only, so that it is not read for the fingerprint:
+		return nil
+	}
+	if !resource.IsDir() {
+		reader, err := resource.Contents()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		writeFingerprintField(digest, "file", "")
+		_, err = io.Copy(digest, reader)
+		return err
+	}
+
+	outputChannel := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+		SafeBufferSize: DefaultMaxMsgSize,
+		Sorted:         true,
+	}, resource).WalkResource()
+	for {
+		payload := <-outputChannel
+		if payload == nil {
+			return nil
+		}
+		switch tpayload := payload.GetPayload().(type) {
+		case *proto.ResourceChunk_Header:
+			writeFingerprintField(digest, "entry", tpayload.Header.TargetPath)
+			writeFingerprintField(digest, "mode", strconv.FormatInt(tpayload.Header.FileMode, 8))
+			writeFingerprintField(digest, "dir", strconv.FormatBool(tpayload.Header.IsDir))
+		case *proto.ResourceChunk_Chunk:
+			digest.Write(tpayload.Chunk.Chunk)
+		case *proto.ResourceChunk_Error:
+			go drainWalk(outputChannel, nil)
+			return fmt.Errorf("failed walking directory resource: %s", tpayload.Error.Message)
+		}
+	}
+}
+
+// writeFingerprintField writes a named value prefixed with its length, so that adjacent values cannot collide.
+func writeFingerprintField(digest hash.Hash, name, value string) {
+	fmt.Fprintf(digest, "%s:%d:%s;", name, len(value), value)
+}
+
+// writeFingerprintValues writes the values in the order of their names.
+func writeFingerprintValues(digest hash.Hash, name string, values map[string]string) {
+	names := []string{}
+	for valueName := range values {
+		names = append(names, valueName)
+	}
+	sort.Strings(names)
+	writeFingerprintField(digest, name+"s", strconv.Itoa(len(names)))
+	for _, valueName := range names {
+		writeFingerprintField(digest, name, valueName)
+		writeFingerprintField(digest, name+"-value", values[valueName])
+	}
+}
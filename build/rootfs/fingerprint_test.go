@@ -0,0 +1,103 @@
+package rootfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkContextFingerprint(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "a"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "sub/b"), []byte("b"))
+
+	newWorkContext := func(buildID, contents string, args map[string]string) *WorkContext {
+		return &WorkContext{
+			BuildID:            buildID,
+			ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("make")},
+			Args:               args,
+			ResourcesResolved: Resources{
+				"file": []resources.ResolvedResource{
+					resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+						return ioutil.NopCloser(bytes.NewReader([]byte(contents))), nil
+					}, fs.FileMode(0644), "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+				},
+				"dir": []resources.ResolvedResource{
+					resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+						commands.DefaultWorkdir(), commands.DefaultUser()),
+				},
+			},
+		}
+	}
+
+	fingerprint, err := newWorkContext("first", "contents", map[string]string{"A": "1", "B": "2"}).Fingerprint()
+	assert.Nil(t, err)
+	assert.Len(t, fingerprint, 64)
+
+	sameFingerprint, err := newWorkContext("second", "contents", map[string]string{"B": "2", "A": "1"}).Fingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, sameFingerprint, "expected the build ID and the order of the values not to matter")
+
+	otherContents, err := newWorkContext("first", "other contents", map[string]string{"A": "1", "B": "2"}).Fingerprint()
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint, otherContents)
+
+	otherArgs, err := newWorkContext("first", "contents", map[string]string{"A": "1", "B": "3"}).Fingerprint()
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint, otherArgs)
+
+	otherCommands := newWorkContext("first", "contents", map[string]string{"A": "1", "B": "2"})
+	otherCommands.ExecutableCommands = []commands.VMInitSerializableCommand{commands.RunWithDefaults("make install")}
+	otherCommandsFingerprint, err := otherCommands.Fingerprint()
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint, otherCommandsFingerprint)
+
+	MustPutTestResource(t, filepath.Join(sourceDir, "sub/b"), []byte("changed"))
+	otherDirectory, err := newWorkContext("first", "contents", map[string]string{"A": "1", "B": "2"}).Fingerprint()
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint, otherDirectory)
+
+	missing := newWorkContext("first", "contents", nil)
+	missing.ResourcesResolved["missing"] = []resources.ResolvedResource{
+		resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			return os.Open(filepath.Join(tempDir, "missing"))
+		}, fs.FileMode(0644), "missing", "/etc/missing", commands.DefaultWorkdir(), commands.DefaultUser(), "missing"),
+	}
+	_, err = missing.Fingerprint()
+	assert.NotNil(t, err)
+}
+
+func TestServerSummaryFingerprint(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	workContext := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+		ResourcesResolved:  make(Resources),
+	}
+	expected, err := workContext.Fingerprint()
+	assert.Nil(t, err)
+
+	srv, _ := mustStartServerAndClient(t, logger, &GRPCServiceConfig{ComputeFingerprint: true}, workContext)
+	defer srv.Stop()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for srv.Summary().Fingerprint == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.Equal(t, expected, srv.Summary().Fingerprint)
+}
@@ -0,0 +1,90 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// FlushResult is the acknowledgement of the stdout and stderr lines the server received from the client.
+type FlushResult struct {
+	// StdoutLines is the number of stdout lines the server received during the build.
+	StdoutLines int
+	// StderrLines is the number of stderr lines the server received during the build.
+	StderrLines int
+	// Complete is true if the server received at least the lines the client sent.
+	Complete bool
+}
+
+// sentLogLines counts the stdout and stderr lines a client sent successfully.
+type sentLogLines struct {
+	m      sync.Mutex
+	stdout int
+	stderr int
+}
+
+func (s *sentLogLines) add(stream LogStream, lines int) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if stream == LogStreamStderr {
+		s.stderr = s.stderr + lines
+		return
+	}
+	s.stdout = s.stdout + lines
+}
+
+func (s *sentLogLines) counts() (int, int) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.stdout, s.stderr
+}
+
+// Flush tells the server that the client has sent all of its stdout and stderr lines and returns
+// the numbers of lines the server acknowledges as received. Call after the last lines were sent
+// and before Success, so that no output of the end of the build is lost to the shutdown of the server.
+// The lines are acknowledged after they were emitted to the consumer of the server events.
+func (c *defaultClient) Flush() (*FlushResult, error) {
+	stdoutLines, stderrLines := c.sentLines.counts()
+	response, err := c.underlying.Flush(context.Background(), &proto.FlushRequest{
+		StdoutLines: int64(stdoutLines),
+		StderrLines: int64(stderrLines),
+	})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	result := &FlushResult{
+		StdoutLines: int(response.StdoutLines),
+		StderrLines: int(response.StderrLines),
+		Complete:    response.Complete,
+	}
+	if !result.Complete {
+		c.logger.Warn("server did not receive all log lines",
+			"stdout-sent", stdoutLines, "stdout-received", result.StdoutLines,
+			"stderr-sent", stderrLines, "stderr-received", result.StderrLines)
+	}
+	return result, nil
+}
+
+func (impl *serverImpl) Flush(ctx context.Context, req *proto.FlushRequest) (*proto.FlushResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.FlushResponse{}, ErrServerStopped
+	}
+	response := &proto.FlushResponse{
+		StdoutLines: int64(impl.summary.StdoutLines),
+		StderrLines: int64(impl.summary.StderrLines),
+	}
+	impl.m.Unlock()
+
+	response.Complete = response.StdoutLines >= req.StdoutLines && response.StderrLines >= req.StderrLines
+	impl.logsLogger.Debug("logs flushed", "stdout-lines", response.StdoutLines, "stderr-lines", response.StderrLines, "complete", response.Complete)
+	impl.emit(&ClientMsgLogsFlushed{
+		StdoutLines: int(response.StdoutLines),
+		StderrLines: int(response.StderrLines),
+		Complete:    response.Complete,
+	})
+	return response, nil
+}
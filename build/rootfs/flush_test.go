@@ -0,0 +1,62 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientFlushAcknowledgesLogs(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanFlushed := make(chan *ClientMsgLogsFlushed, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			if tmessage, ok := message.(*ClientMsgLogsFlushed); ok {
+				chanFlushed <- tmessage
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.StdOut([]string{"first", "second"}))
+	assert.Nil(t, testClient.StdErr([]string{"error"}))
+	result, err := testClient.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, &FlushResult{StdoutLines: 2, StderrLines: 1, Complete: true}, result)
+	assert.Equal(t, &ClientMsgLogsFlushed{StdoutLines: 2, StderrLines: 1, Complete: true}, <-chanFlushed)
+
+	capabilities, err := testClient.Capabilities()
+	assert.Nil(t, err)
+	assert.True(t, capabilities.HasFeature(FeatureFlush))
+}
+
+func TestClientFlushReportsIncompleteLogs(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	// lines counted as sent but never received by the server:
+	testClient.(*defaultClient).sentLines.add(LogStreamStdout, 3)
+	result, err := testClient.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, &FlushResult{StdoutLines: 0, StderrLines: 0, Complete: false}, result)
+}
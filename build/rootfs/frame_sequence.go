@@ -0,0 +1,94 @@
+package rootfs
+
+import (
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+// frameSequence validates the order of the frames of a resource stream received by the client.
+// Every resource starts with a header, the chunks and the end of the resource carry the ID
+// of that header. Without a limit, a resource ends before the next one starts. With a limit,
+// up to limit resources of a multiplexed stream may be open at the same time. A stream violating
+// the order is rejected with ErrProtocolMismatch.
+type frameSequence struct {
+	// limit is the maximum number of open resources, zero or one for a stream which is not multiplexed.
+	limit int
+	// active is the ID of the resource started by the last header.
+	active string
+	// open holds the IDs of the resources between their header and their end.
+	open map[string]struct{}
+	// frames counts the validated frames, for the errors.
+	frames int
+}
+
+// begin starts the sequence with the header of a resource received outside of the sequence.
+func (s *frameSequence) begin(id string) {
+	s.active, s.open, s.frames = id, map[string]struct{}{id: {}}, 1
+}
+
+// next validates the next frame of the stream. Error frames are accepted anywhere,
+// they end the stream.
+func (s *frameSequence) next(frame *proto.ResourceChunk) error {
+	if s.open == nil {
+		s.open = map[string]struct{}{}
+	}
+	s.frames = s.frames + 1
+	switch tframe := frame.GetPayload().(type) {
+	case *proto.ResourceChunk_Header:
+		if _, ok := s.open[tframe.Header.Id]; ok {
+			return errors.Wrapf(ErrProtocolMismatch, "frame %d: repeated header of resource '%s'", s.frames, tframe.Header.Id)
+		}
+		if len(s.open) > 0 && s.limit <= 1 {
+			return errors.Wrapf(ErrProtocolMismatch, "frame %d: header of resource '%s' before the end of resource '%s'",
+				s.frames, tframe.Header.Id, s.active)
+		}
+		if len(s.open) > 0 && len(s.open) >= s.limit {
+			return errors.Wrapf(ErrProtocolMismatch, "frame %d: header of resource '%s' exceeds %d multiplexed resources",
+				s.frames, tframe.Header.Id, s.limit)
+		}
+		s.active = tframe.Header.Id
+		s.open[tframe.Header.Id] = struct{}{}
+	case *proto.ResourceChunk_Chunk:
+		if err := s.expect("chunk", tframe.Chunk.Id); err != nil {
+			return err
+		}
+	case *proto.ResourceChunk_Eof:
+		if err := s.expect("end", tframe.Eof.Id); err != nil {
+			return err
+		}
+		delete(s.open, tframe.Eof.Id)
+	case *proto.ResourceChunk_Batch:
+		if len(s.open) > 0 && s.limit <= 1 {
+			return errors.Wrapf(ErrProtocolMismatch, "frame %d: batch before the end of resource '%s'", s.frames, s.active)
+		}
+		for _, entry := range tframe.Batch.Entries {
+			if entry.Header == nil {
+				return errors.Wrapf(ErrProtocolMismatch, "frame %d: batched resource without a header", s.frames)
+			}
+			if _, ok := s.open[entry.Header.Id]; ok {
+				return errors.Wrapf(ErrProtocolMismatch, "frame %d: batched resource '%s' is open", s.frames, entry.Header.Id)
+			}
+		}
+	case *proto.ResourceChunk_Delete:
+		if len(s.open) > 0 {
+			return errors.Wrapf(ErrProtocolMismatch, "frame %d: delete of '%s' before the end of resource '%s'",
+				s.frames, tframe.Delete.TargetPath, s.active)
+		}
+	}
+	return nil
+}
+
+// expect validates that a chunk or an end frame belongs to an open resource.
+func (s *frameSequence) expect(kind, id string) error {
+	if _, ok := s.open[id]; ok {
+		return nil
+	}
+	if len(s.open) == 0 {
+		return errors.Wrapf(ErrProtocolMismatch, "frame %d: %s of resource '%s' without a header", s.frames, kind, id)
+	}
+	if s.limit <= 1 {
+		return errors.Wrapf(ErrProtocolMismatch, "frame %d: %s of resource '%s' interleaved with resource '%s'",
+			s.frames, kind, id, s.active)
+	}
+	return errors.Wrapf(ErrProtocolMismatch, "frame %d: %s of resource '%s' which is not open", s.frames, kind, id)
+}
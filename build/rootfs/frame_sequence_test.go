@@ -0,0 +1,120 @@
+package rootfs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameSequence(t *testing.T) {
+	header := func(id string) *proto.ResourceChunk {
+		return &proto.ResourceChunk{Payload: &proto.ResourceChunk_Header{Header: &proto.ResourceChunk_ResourceHeader{Id: id}}}
+	}
+	chunk := func(id string) *proto.ResourceChunk {
+		return &proto.ResourceChunk{Payload: &proto.ResourceChunk_Chunk{Chunk: &proto.ResourceChunk_ResourceContents{Id: id}}}
+	}
+	eof := func(id string) *proto.ResourceChunk {
+		return &proto.ResourceChunk{Payload: &proto.ResourceChunk_Eof{Eof: &proto.ResourceChunk_ResourceEof{Id: id}}}
+	}
+	deleted := &proto.ResourceChunk{Payload: &proto.ResourceChunk_Delete{Delete: &proto.ResourceChunk_ResourceDelete{TargetPath: "/etc/old"}}}
+	failed := &proto.ResourceChunk{Payload: &proto.ResourceChunk_Error{Error: &proto.ResourceChunk_ResourceError{Message: "failed"}}}
+
+	for _, tc := range []struct {
+		name    string
+		frames  []*proto.ResourceChunk
+		invalid int
+		message string
+	}{
+		{name: "ordered", frames: []*proto.ResourceChunk{header("a"), chunk("a"), chunk("a"), eof("a"), deleted, header("b"), eof("b")}, invalid: -1},
+		{name: "error", frames: []*proto.ResourceChunk{header("a"), chunk("a"), failed}, invalid: -1},
+		{name: "chunk without header", frames: []*proto.ResourceChunk{chunk("a")}, invalid: 0,
+			message: "frame 1: chunk of resource 'a' without a header"},
+		{name: "chunk after end", frames: []*proto.ResourceChunk{header("a"), eof("a"), chunk("a")}, invalid: 2,
+			message: "frame 3: chunk of resource 'a' without a header"},
+		{name: "interleaved chunk", frames: []*proto.ResourceChunk{header("a"), chunk("a"), chunk("b")}, invalid: 2,
+			message: "frame 3: chunk of resource 'b' interleaved with resource 'a'"},
+		{name: "interleaved end", frames: []*proto.ResourceChunk{header("a"), eof("b")}, invalid: 1,
+			message: "frame 2: end of resource 'b' interleaved with resource 'a'"},
+		{name: "header before end", frames: []*proto.ResourceChunk{header("a"), chunk("a"), header("b")}, invalid: 2,
+			message: "frame 3: header of resource 'b' before the end of resource 'a'"},
+		{name: "delete before end", frames: []*proto.ResourceChunk{header("a"), deleted}, invalid: 1,
+			message: "frame 2: delete of '/etc/old' before the end of resource 'a'"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sequence := &frameSequence{}
+			for index, frame := range tc.frames {
+				err := sequence.next(frame)
+				if index != tc.invalid {
+					assert.Nil(t, err, "frame %d", index)
+					continue
+				}
+				assert.True(t, errors.Is(err, ErrProtocolMismatch))
+				assert.Contains(t, err.Error(), tc.message)
+				break
+			}
+		})
+	}
+
+	t.Run("multiplexed", func(t *testing.T) {
+		sequence := &frameSequence{limit: 2}
+		for _, frame := range []*proto.ResourceChunk{header("a"), header("b"), chunk("a"), chunk("b"), eof("b"), header("c"), eof("a"), eof("c"), deleted} {
+			assert.Nil(t, sequence.next(frame))
+		}
+		for _, tc := range []struct {
+			frames  []*proto.ResourceChunk
+			message string
+		}{
+			{frames: []*proto.ResourceChunk{header("a"), header("b"), header("c")}, message: "frame 3: header of resource 'c' exceeds 2 multiplexed resources"},
+			{frames: []*proto.ResourceChunk{header("a"), header("a")}, message: "frame 2: repeated header of resource 'a'"},
+			{frames: []*proto.ResourceChunk{header("a"), chunk("b")}, message: "frame 2: chunk of resource 'b' which is not open"},
+		} {
+			sequence := &frameSequence{limit: 2}
+			var err error
+			for _, frame := range tc.frames {
+				if err = sequence.next(frame); err != nil {
+					break
+				}
+			}
+			assert.True(t, errors.Is(err, ErrProtocolMismatch))
+			assert.Contains(t, err.Error(), tc.message)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		batch := func(ids ...string) *proto.ResourceChunk {
+			entries := []*proto.ResourceChunk_ResourceBatch_Entry{}
+			for _, id := range ids {
+				entries = append(entries, &proto.ResourceChunk_ResourceBatch_Entry{Header: &proto.ResourceChunk_ResourceHeader{Id: id}})
+			}
+			return &proto.ResourceChunk{Payload: &proto.ResourceChunk_Batch{Batch: &proto.ResourceChunk_ResourceBatch{Entries: entries}}}
+		}
+		sequence := &frameSequence{}
+		for _, frame := range []*proto.ResourceChunk{header("a"), eof("a"), batch("b", "c"), header("d"), eof("d")} {
+			assert.Nil(t, sequence.next(frame))
+		}
+		sequence = &frameSequence{}
+		assert.Nil(t, sequence.next(header("a")))
+		err := sequence.next(batch("b"))
+		assert.True(t, errors.Is(err, ErrProtocolMismatch))
+		assert.Contains(t, err.Error(), "frame 2: batch before the end of resource 'a'")
+
+		// multiplexed, a batch may arrive while other resources are open:
+		sequence = &frameSequence{limit: 2}
+		assert.Nil(t, sequence.next(header("a")))
+		assert.Nil(t, sequence.next(batch("b")))
+		err = sequence.next(batch("a"))
+		assert.True(t, errors.Is(err, ErrProtocolMismatch))
+		assert.Contains(t, err.Error(), "frame 3: batched resource 'a' is open")
+	})
+
+	t.Run("begin", func(t *testing.T) {
+		sequence := &frameSequence{}
+		sequence.begin("a")
+		assert.Nil(t, sequence.next(chunk("a")))
+		err := sequence.next(chunk("b"))
+		assert.True(t, errors.Is(err, ErrProtocolMismatch))
+		assert.Contains(t, err.Error(), "frame 3: chunk of resource 'b' interleaved with resource 'a'")
+	})
+}
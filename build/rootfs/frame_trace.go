@@ -0,0 +1,150 @@
+package rootfs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// MaxFrameTraces is the number of the most recent frame traces kept by the server, see GRPCServiceConfig.FrameTraceSampling.
+const MaxFrameTraces = 256
+
+// FrameTrace is the trace of a sampled frame of a resource stream sent by the server.
+type FrameTrace struct {
+	// Path is the path of the resource requested by the client.
+	Path string
+	// Kind is the kind of the frame: header, chunk, eof, batch, delete or error.
+	Kind string
+	// Size is the encoded size of the frame in bytes.
+	Size int
+	// QueueTime is the time between the frame being produced and the stream starting to send it,
+	// for example while the frames of other multiplexed files are sent.
+	QueueTime time.Duration
+	// Latency is the time the stream took to send the frame, including the wait for the flow control window of the client.
+	Latency time.Duration
+	// SentAt is when the stream finished sending the frame.
+	SentAt time.Time
+}
+
+// frameTracer samples one in every frames of the resource streams of the server.
+// The producer of a frame marks the frame queued, the sampled frames are traced when sent.
+// A nil tracer traces nothing.
+type frameTracer struct {
+	every int64
+	// produced counts the produced frames, pending the sampled frames not sent yet, both atomic:
+	produced int64
+	pending  int64
+
+	m      sync.Mutex
+	queued map[*proto.ResourceChunk]time.Time
+	traces []FrameTrace
+	next   int
+	traced int64
+}
+
+// newFrameTracer returns a tracer sampling one in every frames, nil when every is not positive.
+func newFrameTracer(every int) *frameTracer {
+	if every <= 0 {
+		return nil
+	}
+	return &frameTracer{every: int64(every), queued: map[*proto.ResourceChunk]time.Time{}}
+}
+
+// queue marks the frame produced, the frame is sampled when it is one in every frames.
+func (t *frameTracer) queue(frame *proto.ResourceChunk) {
+	if t == nil || atomic.AddInt64(&t.produced, 1)%t.every != 0 {
+		return
+	}
+	t.m.Lock()
+	t.queued[frame] = time.Now()
+	t.m.Unlock()
+	atomic.AddInt64(&t.pending, 1)
+}
+
+// send sends the frame with the send function and traces the frame when it was sampled.
+func (t *frameTracer) send(resourcePath string, frame *proto.ResourceChunk, send func(*proto.ResourceChunk) error) error {
+	if t == nil || atomic.LoadInt64(&t.pending) == 0 {
+		return send(frame)
+	}
+	t.m.Lock()
+	queuedAt, sampled := t.queued[frame]
+	delete(t.queued, frame)
+	t.m.Unlock()
+	if !sampled {
+		return send(frame)
+	}
+	atomic.AddInt64(&t.pending, -1)
+	sendingAt := time.Now()
+	err := send(frame)
+	sentAt := time.Now()
+	t.record(FrameTrace{
+		Path:      resourcePath,
+		Kind:      frameKind(frame),
+		Size:      protobuf.Size(frame),
+		QueueTime: sendingAt.Sub(queuedAt),
+		Latency:   sentAt.Sub(sendingAt),
+		SentAt:    sentAt,
+	})
+	return err
+}
+
+// forget drops the sampled frames which will not be sent, for example of a walk drained after an error.
+func (t *frameTracer) forget(frame *proto.ResourceChunk) {
+	if t == nil || atomic.LoadInt64(&t.pending) == 0 {
+		return
+	}
+	t.m.Lock()
+	if _, ok := t.queued[frame]; ok {
+		delete(t.queued, frame)
+		atomic.AddInt64(&t.pending, -1)
+	}
+	t.m.Unlock()
+}
+
+// record keeps the trace, replacing the oldest trace once MaxFrameTraces are kept.
+func (t *frameTracer) record(trace FrameTrace) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.traced = t.traced + 1
+	if len(t.traces) < MaxFrameTraces {
+		t.traces = append(t.traces, trace)
+		return
+	}
+	t.traces[t.next] = trace
+	t.next = (t.next + 1) % MaxFrameTraces
+}
+
+// snapshot returns the kept traces, oldest first, and the number of frames traced so far.
+func (t *frameTracer) snapshot() ([]FrameTrace, int64) {
+	if t == nil {
+		return nil, 0
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	traces := make([]FrameTrace, 0, len(t.traces))
+	traces = append(traces, t.traces[t.next:]...)
+	traces = append(traces, t.traces[:t.next]...)
+	return traces, t.traced
+}
+
+// frameKind returns the kind of a frame for the traces.
+func frameKind(frame *proto.ResourceChunk) string {
+	switch frame.GetPayload().(type) {
+	case *proto.ResourceChunk_Header:
+		return "header"
+	case *proto.ResourceChunk_Chunk:
+		return "chunk"
+	case *proto.ResourceChunk_Eof:
+		return "eof"
+	case *proto.ResourceChunk_Batch:
+		return "batch"
+	case *proto.ResourceChunk_Delete:
+		return "delete"
+	case *proto.ResourceChunk_Error:
+		return "error"
+	}
+	return "unknown"
+}
@@ -0,0 +1,118 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameTracerSamples(t *testing.T) {
+	var nilTracer *frameTracer
+	frame := &proto.ResourceChunk{Payload: &proto.ResourceChunk_Eof{Eof: &proto.ResourceChunk_ResourceEof{Id: "id"}}}
+	nilTracer.queue(frame)
+	assert.Nil(t, nilTracer.send("path", frame, func(*proto.ResourceChunk) error { return nil }))
+	traces, traced := nilTracer.snapshot()
+	assert.Empty(t, traces)
+	assert.Equal(t, int64(0), traced)
+	assert.Nil(t, newFrameTracer(0), "expected no tracer without sampling")
+
+	tracer := newFrameTracer(3)
+	sendErr := errors.New("send failed")
+	for i := 0; i < 3*MaxFrameTraces+4; i++ {
+		frame := &proto.ResourceChunk{Payload: &proto.ResourceChunk_Chunk{Chunk: &proto.ResourceChunk_ResourceContents{Chunk: []byte("data"), Id: "id"}}}
+		tracer.queue(frame)
+		err := tracer.send("path", frame, func(*proto.ResourceChunk) error {
+			if i == 2 {
+				return sendErr
+			}
+			return nil
+		})
+		if i == 2 {
+			assert.Equal(t, sendErr, err, "expected the error of a traced send")
+		}
+	}
+	traces, traced = tracer.snapshot()
+	assert.Equal(t, int64(MaxFrameTraces+1), traced)
+	assert.Len(t, traces, MaxFrameTraces, "expected the oldest trace replaced")
+	for i, trace := range traces {
+		assert.Equal(t, "path", trace.Path)
+		assert.Equal(t, "chunk", trace.Kind)
+		assert.Greater(t, trace.Size, 4)
+		if i > 0 {
+			assert.False(t, trace.SentAt.Before(traces[i-1].SentAt), "expected the oldest trace first")
+		}
+	}
+
+	// a sampled frame dropped by a drained walk is not kept:
+	frame = &proto.ResourceChunk{Payload: &proto.ResourceChunk_Eof{Eof: &proto.ResourceChunk_ResourceEof{Id: "id"}}}
+	for i := 0; i < 3; i++ {
+		tracer.queue(frame)
+	}
+	tracer.forget(frame)
+	assert.Empty(t, tracer.queued)
+	assert.Equal(t, int64(0), tracer.pending)
+}
+
+func TestServerTracesSampledFrames(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{FrameTraceSampling: 2}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer testServer.Stop()
+	go func() {
+		for {
+			select {
+			case <-testServer.OnMessage():
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", filepath.Join(tempDir, "root"), &WriteOptions{Multiplex: 4}))
+
+	// every file and directory has a header and an end frame, every file of the tree has contents:
+	frames := 2*(len(expected)+5) + len(expected)
+	// the last frame may be traced after the client received it:
+	deadline := time.Now().Add(time.Second * 5)
+	for testServer.Stats().FramesTraced < int64(frames/2) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	stats := testServer.Stats()
+	assert.Equal(t, int64(frames/2), stats.FramesTraced)
+	assert.Len(t, stats.FrameTraces, frames/2)
+	kinds := map[string]int{}
+	for _, trace := range stats.FrameTraces {
+		assert.Equal(t, "dir", trace.Path)
+		assert.Greater(t, trace.Size, 0)
+		assert.GreaterOrEqual(t, int64(trace.QueueTime), int64(0))
+		kinds[trace.Kind] = kinds[trace.Kind] + 1
+	}
+	assert.Greater(t, kinds["header"], 0)
+	assert.Greater(t, kinds["chunk"], 0)
+	assert.Greater(t, kinds["eof"], 0)
+}
@@ -0,0 +1,107 @@
+package rootfs
+
+import (
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FsyncPolicy defines when the client syncs the files it writes resources to.
+// Block device writes are always synced when complete.
+type FsyncPolicy int
+
+const (
+	// FsyncPolicyPerFile syncs every file when it is completely written.
+	FsyncPolicyPerFile FsyncPolicy = iota
+	// FsyncPolicyNever leaves syncing to the operating system.
+	FsyncPolicyNever
+	// FsyncPolicyPerNBytes syncs a file every time the configured number of bytes has been written to it
+	// and when it is completely written.
+	FsyncPolicyPerNBytes
+	// FsyncPolicyAtSuccess syncs all written files when the client reports success.
+	FsyncPolicyAtSuccess
+)
+
+// DefaultFsyncIntervalBytes is the default number of bytes written between syncs with FsyncPolicyPerNBytes.
+const DefaultFsyncIntervalBytes = int64(64 * 1024 * 1024)
+
+type fileSyncer struct {
+	sync.Mutex
+	intervalBytes int64
+	pending       []string
+	policy        FsyncPolicy
+}
+
+func newFileSyncer(policy FsyncPolicy, intervalBytes int64) *fileSyncer {
+	return &fileSyncer{intervalBytes: intervalBytes, pending: []string{}, policy: policy}
+}
+
+// track returns a tracker of writes to the file applying the policy.
+func (s *fileSyncer) track(file *os.File) *syncedFile {
+	return &syncedFile{file: file, syncer: s}
+}
+
+// syncPending syncs the files written with FsyncPolicyAtSuccess.
+func (s *fileSyncer) syncPending() error {
+	s.Lock()
+	pending := s.pending
+	s.pending = []string{}
+	s.Unlock()
+	for _, filePath := range pending {
+		if err := syncFile(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncFile(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "failed opening '%s' for sync", filePath)
+	}
+	defer file.Close()
+	if err := file.Sync(); err != nil {
+		return errors.Wrapf(err, "failed syncing '%s'", filePath)
+	}
+	return nil
+}
+
+type syncedFile struct {
+	file     *os.File
+	syncer   *fileSyncer
+	unsynced int64
+}
+
+// wrote records bytes written to the file, syncs the file when the policy requires it.
+func (f *syncedFile) wrote(n int) error {
+	f.unsynced = f.unsynced + int64(n)
+	if f.syncer.policy == FsyncPolicyPerNBytes && f.unsynced >= f.syncer.intervalBytes {
+		return f.sync()
+	}
+	return nil
+}
+
+// finish syncs the completely written file when the policy requires it.
+// The final path is the path the file is synced at with FsyncPolicyAtSuccess, files written
+// under a temporary name are renamed before the success.
+func (f *syncedFile) finish(finalPath string) error {
+	switch f.syncer.policy {
+	case FsyncPolicyPerFile, FsyncPolicyPerNBytes:
+		return f.sync()
+	case FsyncPolicyAtSuccess:
+		f.syncer.Lock()
+		f.syncer.pending = append(f.syncer.pending, finalPath)
+		f.syncer.Unlock()
+	}
+	return nil
+}
+
+func (f *syncedFile) sync() error {
+	f.unsynced = 0
+	if err := f.file.Sync(); err != nil {
+		return errors.Wrapf(err, "failed syncing '%s'", f.file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSyncerPolicies(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	file, err := os.Create(filepath.Join(tempDir, "file"))
+	assert.Nil(t, err)
+	defer file.Close()
+
+	perNBytes := newFileSyncer(FsyncPolicyPerNBytes, 10).track(file)
+	assert.Nil(t, perNBytes.wrote(6))
+	assert.Equal(t, int64(6), perNBytes.unsynced)
+	assert.Nil(t, perNBytes.wrote(6))
+	assert.Equal(t, int64(0), perNBytes.unsynced)
+
+	never := newFileSyncer(FsyncPolicyNever, 10)
+	assert.Nil(t, never.track(file).finish(file.Name()))
+	assert.Empty(t, never.pending)
+
+	atSuccess := newFileSyncer(FsyncPolicyAtSuccess, 10)
+	assert.Nil(t, atSuccess.track(file).finish(file.Name()))
+	assert.Equal(t, []string{file.Name()}, atSuccess.pending)
+	assert.Nil(t, atSuccess.syncPending())
+	assert.Empty(t, atSuccess.pending)
+
+	// a file removed before success can't be synced:
+	assert.Nil(t, atSuccess.track(file).finish(file.Name()))
+	assert.Nil(t, os.Remove(file.Name()))
+	assert.NotNil(t, atSuccess.syncPending())
+}
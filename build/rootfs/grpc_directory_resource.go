@@ -2,18 +2,20 @@ package rootfs
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"fmt"
 	"io"
 	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
+	"sort"
 
 	"github.com/combust-labs/firebuild-shared/build/commands"
 	"github.com/combust-labs/firebuild-shared/build/resources"
 	"github.com/combust-labs/firebuild-shared/grpc/proto"
 	"github.com/gofrs/uuid"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 // GRPCReadingDirectoryResource identifies a gRPC walkable directory resource.
@@ -25,120 +27,585 @@ type GRPCReadingDirectoryResource interface {
 // This special resource type walks an underlying directory and produces resource entries for every directory and a file within
 // the underlying directory. In a sense, it behaves similar to an SCP client but operates via gRPC.
 func NewGRPCDirectoryResource(safeBufferSize int, resource resources.ResolvedResource) GRPCReadingDirectoryResource {
+	return NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{SafeBufferSize: safeBufferSize}, resource)
+}
+
+// UnreadableFilePolicy defines how a directory walk handles an entry which cannot be read:
+// a file which cannot be opened or a directory which cannot be listed.
+type UnreadableFilePolicy int
+
+const (
+	// UnreadableFileFail sends an error frame for the entry and stops the walk. This is the default.
+	UnreadableFileFail UnreadableFilePolicy = iota
+	// UnreadableFileWarn skips the entry and reports it to DirectoryWalkOptions.OnUnreadable.
+	UnreadableFileWarn
+	// UnreadableFileSkip skips the entry silently.
+	UnreadableFileSkip
+)
+
+// DirectoryWalkOptions configures how a gRPC directory resource walks the underlying directory.
+type DirectoryWalkOptions struct {
+	// AcceptCompression lists the compressions the receiving client accepts. When it includes CompressionGzip,
+	// the chunks the spool stores compressed are sent as stored, see SpoolConfig.Compress.
+	AcceptCompression []string
+	// ChecksumCache optionally caches the checksums of the chunks of the files.
+	ChecksumCache *ChecksumCache
+	// CompressionThreshold, when greater than zero, is the minimum size of a file chunk compressed with CompressionGzip.
+	// A chunk is sent compressed only when compression makes it smaller, the checksum is of the uncompressed chunk.
+	CompressionThreshold int
+	// SafeBufferSize is the maximum size of a single chunk payload.
+	SafeBufferSize int
+	// Spool optionally serves the contents of the files from a spool.
+	Spool *Spool
+	// Sorted guarantees that the entries of every directory are emitted in lexicographical
+	// byte order of their names and that every directory is emitted before any of its children,
+	// regardless of the order the underlying file system returns the entries in.
+	Sorted bool
+	// BatchFileSize, when greater than zero, is the maximum size of a file sent whole together with its header
+	// in a batch frame, see proto.ResourceChunk_ResourceBatch. A batch holds at most SafeBufferSize bytes,
+	// larger files are sent in chunks.
+	BatchFileSize int64
+	// Multiplex is the maximum number of files of the same directory streamed concurrently,
+	// the frames of the files interleave and are told apart by their IDs. Every file ends before
+	// a file of a different directory or a directory starts. Zero or one streams the files one by one.
+	// Multiplexed files are not emitted in the Sorted order.
+	Multiplex int
+	// Include, when set, is called for every entry not excluded by the resource filter with the target path,
+	// the local file path and the type of the entry. Entries for which it returns false are not emitted,
+	// directories are descended regardless.
+	Include func(targetPath, filePath string, isDir bool) bool
+	// UnreadableFiles defines how entries which cannot be read are handled, default is UnreadableFileFail.
+	// A file which fails while its contents are streamed always fails the walk.
+	UnreadableFiles UnreadableFilePolicy
+	// OnUnreadable, when set, is called with the local path and the error of every entry skipped
+	// with the UnreadableFileWarn policy.
+	OnUnreadable func(filePath string, err error)
+
+	retrier *fileRetrier
+	tracer  *frameTracer
+}
+
+// NewGRPCDirectoryResourceWithOptions creates a resolved walkable gRPC directory resource with walk options.
+func NewGRPCDirectoryResourceWithOptions(opts *DirectoryWalkOptions, resource resources.ResolvedResource) GRPCReadingDirectoryResource {
 	return &grpcDirectoryResource{contentsReader: func() (io.ReadCloser, error) {
 		return ioutil.NopCloser(bytes.NewReader([]byte{})), nil
 	},
+		acceptGzip:     containsString(opts.AcceptCompression, CompressionGzip),
+		batchFileSize:  opts.BatchFileSize,
+		checksums:      opts.ChecksumCache,
+		compression:    opts.CompressionThreshold,
+		filter:         resources.FilterOf(resource),
+		include:        opts.Include,
 		isDir:          true,
+		multiplex:      opts.Multiplex,
+		onUnreadable:   opts.OnUnreadable,
+		platform:       resources.PlatformOf(resource),
+		renames:        resources.RenamesOf(resource),
 		resolved:       resource.ResolvedURIOrPath(),
-		safeBufferSize: safeBufferSize,
+		retrier:        opts.retrier,
+		safeBufferSize: opts.SafeBufferSize,
+		sorted:         opts.Sorted,
+		spool:          opts.Spool,
 		targetMode:     resource.TargetMode(),
 		sourcePath:     resource.SourcePath(),
 		targetPath:     resource.TargetPath(),
 		targetWorkdir:  resource.TargetWorkdir(),
 		targetUser:     resource.TargetUser(),
+		tracer:         opts.tracer,
+		unreadable:     opts.UnreadableFiles,
 	}
 }
 
 type grpcDirectoryResource struct {
+	acceptGzip     bool
+	batchFileSize  int64
+	checksums      *ChecksumCache
+	compression    int
 	contentsReader func() (io.ReadCloser, error)
+	filter         resources.FilteredResource
+	include        func(targetPath, filePath string, isDir bool) bool
 	isDir          bool
+	multiplex      int
+	onUnreadable   func(filePath string, err error)
+	platform       string
+	renames        resources.RenamingResource
 	resolved       string
+	retrier        *fileRetrier
 	safeBufferSize int
+	sorted         bool
+	spool          *Spool
 	targetMode     fs.FileMode
 	sourcePath     string
 	targetPath     string
 	targetWorkdir  commands.Workdir
 	targetUser     commands.User
+	tracer         *frameTracer
+	unreadable     UnreadableFilePolicy
 }
 
 func (drr *grpcDirectoryResource) WalkResource() chan *proto.ResourceChunk {
 	chanChunks := make(chan *proto.ResourceChunk)
 	go func() {
-		filepath.WalkDir(drr.resolved, func(path string, d fs.DirEntry, err error) error {
+		walkFunc := filepath.WalkDir
+		if drr.sorted {
+			walkFunc = walkDirSorted
+		}
+		var mux *walkMultiplexer
+		if drr.multiplex > 1 {
+			mux = newWalkMultiplexer(drr.multiplex)
+		}
+		var batch *fileBatch
+		if drr.batchFileSize > 0 {
+			batch = &fileBatch{limit: drr.safeBufferSize, tracer: drr.tracer}
+		}
+		walkErr := walkFunc(drr.resolved, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if d == nil {
+					// the walked directory itself is not accessible:
+					drr.sendError(chanChunks, "", path, err)
+					return err
+				}
+				// the directory was emitted but cannot be listed:
+				return drr.handleUnreadable(chanChunks, "", path, err)
+			}
 
-			finfo, err := d.Info()
+			remainingPath, err := filepath.Rel(drr.resolved, path)
 			if err != nil {
 				return err
 			}
+			remainingPath = slashPath(remainingPath, os.PathSeparator)
+			if remainingPath == "." {
+				remainingPath = ""
+			}
+
+			if remainingPath != "" && drr.filter != nil && drr.filter.Excluded(remainingPath) {
+				if d.IsDir() && !drr.filter.DescendExcluded() {
+					return fs.SkipDir
+				}
+				return nil
+			}
 
-			remainingPath := strings.TrimPrefix(strings.TrimPrefix(path, drr.resolved), "/")
+			if drr.include != nil && !drr.include(drr.targetPathOf(remainingPath), path, d.IsDir()) {
+				return nil
+			}
 
 			resourceUUID := uuid.Must(uuid.NewV4()).String()
 
+			finfo, err := d.Info()
+			if err != nil {
+				if handleErr := drr.handleUnreadable(chanChunks, resourceUUID, path, err); handleErr != nil || !d.IsDir() {
+					return handleErr
+				}
+				return fs.SkipDir
+			}
+
+			header, err := drr.header(remainingPath, finfo, d.IsDir(), resourceUUID)
+			if err != nil {
+				drr.sendError(chanChunks, resourceUUID, path, err)
+				return err
+			}
+
 			if d.IsDir() {
-				chanChunks <- &proto.ResourceChunk{
-					Payload: &proto.ResourceChunk_Header{
-						Header: &proto.ResourceChunk_ResourceHeader{
-							SourcePath:    filepath.Join(drr.sourcePath, remainingPath),
-							TargetPath:    filepath.Join(drr.targetPath, remainingPath),
-							FileMode:      int64(finfo.Mode().Perm()),
-							IsDir:         true,
-							TargetUser:    drr.targetUser.Value,
-							TargetWorkdir: drr.targetWorkdir.Value,
-							Id:            resourceUUID,
-						},
-					},
+				batch.flush(chanChunks)
+				if mux != nil {
+					// the files of the previous directory end before the next directory starts:
+					if err := mux.barrier(); err != nil {
+						return err
+					}
 				}
-				chanChunks <- &proto.ResourceChunk{
+				drr.emit(chanChunks, header)
+				drr.emit(chanChunks, &proto.ResourceChunk{
 					Payload: &proto.ResourceChunk_Eof{
 						Eof: &proto.ResourceChunk_ResourceEof{
 							Id: resourceUUID,
 						},
 					},
-				}
+				})
 				return nil
 			}
 
 			// it's a file:
 
-			chanChunks <- &proto.ResourceChunk{
-				Payload: &proto.ResourceChunk_Header{
-					Header: &proto.ResourceChunk_ResourceHeader{
-						SourcePath:    filepath.Join(drr.sourcePath, remainingPath),
-						TargetPath:    filepath.Join(drr.targetPath, remainingPath),
-						FileMode:      int64(finfo.Mode().Perm()),
-						IsDir:         false,
-						TargetUser:    drr.targetUser.Value,
-						TargetWorkdir: drr.targetWorkdir.Value,
-						Id:            resourceUUID,
+			if batch != nil && finfo.Size() <= drr.batchFileSize {
+				return drr.batchFile(chanChunks, batch, header, path, finfo, resourceUUID)
+			}
+			if mux != nil {
+				return mux.dispatch(filepath.Dir(path), func() error {
+					return drr.streamFile(chanChunks, header, path, finfo, resourceUUID)
+				})
+			}
+			return drr.streamFile(chanChunks, header, path, finfo, resourceUUID)
+		})
+		if walkErr == nil {
+			batch.flush(chanChunks)
+		}
+		if mux != nil {
+			// the error of a failed file was sent by the file:
+			mux.barrier()
+		}
+		chanChunks <- nil
+	}()
+	return chanChunks
+}
+
+// batchFile reads a small file whole and adds it with its header to the batch, the batch is sent when full.
+// A file which grew beyond the batch file size since listed is streamed instead.
+func (drr *grpcDirectoryResource) batchFile(chanChunks chan *proto.ResourceChunk, batch *fileBatch, header *proto.ResourceChunk, filePath string, finfo fs.FileInfo, id string) error {
+	var contents []byte
+	if drr.spool != nil {
+		var openErr error
+		spooled, err := drr.spool.spooled(filePath, finfo, func() (io.ReadCloser, error) {
+			reader, err := drr.retrier.open(filePath, func() (io.ReadCloser, error) {
+				return os.Open(filePath)
+			})
+			openErr = err
+			return reader, err
+		})
+		if err != nil && openErr != nil {
+			return drr.handleUnreadable(chanChunks, id, filePath, err)
+		}
+		if err == nil {
+			err = spooled.each(0, drr.safeBufferSize, func(payload, _ []byte) error {
+				contents = append(contents, payload...)
+				return nil
+			})
+		}
+		if err != nil {
+			drr.sendError(chanChunks, id, filePath, err)
+			return err
+		}
+	} else {
+		reader, err := drr.retrier.open(filePath, func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		})
+		if err != nil {
+			return drr.handleUnreadable(chanChunks, id, filePath, err)
+		}
+		contents, err = ioutil.ReadAll(io.LimitReader(reader, drr.batchFileSize+1))
+		reader.Close()
+		if err != nil {
+			drr.sendError(chanChunks, id, filePath, err)
+			return err
+		}
+	}
+	if int64(len(contents)) > drr.batchFileSize {
+		return drr.streamFile(chanChunks, header, filePath, finfo, id)
+	}
+	entryHeader := header.GetHeader()
+	entryHeader.Size = int64(len(contents))
+	batch.add(chanChunks, &proto.ResourceChunk_ResourceBatch_Entry{
+		Header:   entryHeader,
+		Contents: contents,
+		Checksum: drr.checksums.checksum(filePath, finfo, 0, contents),
+	})
+	return nil
+}
+
+// fileBatch collects small files with their contents for a batch frame.
+type fileBatch struct {
+	// limit is the maximum encoded size of the entries of a batch.
+	limit   int
+	size    int
+	entries []*proto.ResourceChunk_ResourceBatch_Entry
+	tracer  *frameTracer
+}
+
+// add adds an entry to the batch, sends the batch first when the entry does not fit.
+func (b *fileBatch) add(chanChunks chan *proto.ResourceChunk, entry *proto.ResourceChunk_ResourceBatch_Entry) {
+	entrySize := protobuf.Size(entry)
+	if len(b.entries) > 0 && b.size+entrySize > b.limit {
+		b.flush(chanChunks)
+	}
+	b.entries = append(b.entries, entry)
+	b.size = b.size + entrySize
+}
+
+// flush sends the collected entries, if any.
+func (b *fileBatch) flush(chanChunks chan *proto.ResourceChunk) {
+	if b == nil || len(b.entries) == 0 {
+		return
+	}
+	frame := &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Batch{
+			Batch: &proto.ResourceChunk_ResourceBatch{
+				Entries: b.entries,
+			},
+		},
+	}
+	b.tracer.queue(frame)
+	chanChunks <- frame
+	b.entries, b.size = nil, 0
+}
+
+// streamFile emits the header, the chunks and the end of a file.
+func (drr *grpcDirectoryResource) streamFile(chanChunks chan *proto.ResourceChunk, header *proto.ResourceChunk, filePath string, finfo fs.FileInfo, id string) error {
+	if drr.spool != nil {
+		return drr.walkSpooled(chanChunks, header, filePath, finfo, id)
+	}
+
+	reader, err := drr.retrier.open(filePath, func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	})
+	if err != nil {
+		return drr.handleUnreadable(chanChunks, id, filePath, err)
+	}
+	defer reader.Close()
+
+	// the header is sent only once the file is open so that a skipped file leaves no trace:
+	drr.emit(chanChunks, header)
+
+	buffer := make([]byte, drr.safeBufferSize)
+	offset := int64(0)
+	for {
+		readBytes, err := reader.Read(buffer)
+		if readBytes > 0 {
+			// the buffer is reused for the next read while the chunk is sent:
+			payload := make([]byte, readBytes)
+			copy(payload, buffer[0:readBytes])
+			checksum := drr.checksums.checksum(filePath, finfo, offset, payload)
+			offset = offset + int64(readBytes)
+			drr.emit(chanChunks, &proto.ResourceChunk{
+				Payload: &proto.ResourceChunk_Chunk{
+					Chunk: &proto.ResourceChunk_ResourceContents{
+						Chunk:    payload,
+						Checksum: checksum,
+						Id:       id,
 					},
 				},
-			}
+			})
+		}
+		if err == io.EOF {
+			drr.emit(chanChunks, &proto.ResourceChunk{
+				Payload: &proto.ResourceChunk_Eof{
+					Eof: &proto.ResourceChunk_ResourceEof{
+						Id: id,
+					},
+				},
+			})
+			return nil
+		}
+		if err != nil {
+			// the header and possibly some contents were sent, the file cannot be skipped any longer:
+			drr.sendError(chanChunks, id, filePath, err)
+			return err
+		}
+	}
+}
 
-			buffer := make([]byte, drr.safeBufferSize)
+// emit hands a frame over to the stream, the tracer of the stream samples the frame.
+// File chunks are compressed here so that multiplexed files compress concurrently.
+func (drr *grpcDirectoryResource) emit(chanChunks chan *proto.ResourceChunk, frame *proto.ResourceChunk) {
+	if contents := frame.GetChunk(); contents != nil {
+		compressContents(contents, drr.compression)
+	}
+	drr.tracer.queue(frame)
+	chanChunks <- frame
+}
 
-			reader, err := os.Open(path)
-			defer reader.Close()
+// handleUnreadable applies the unreadable file policy to an entry which cannot be read.
+// The entry is skipped unless the policy is UnreadableFileFail, then an error frame is sent
+// and the error is returned to stop the walk.
+func (drr *grpcDirectoryResource) handleUnreadable(chanChunks chan *proto.ResourceChunk, id, filePath string, err error) error {
+	switch drr.unreadable {
+	case UnreadableFileWarn:
+		if drr.onUnreadable != nil {
+			drr.onUnreadable(filePath, err)
+		}
+		return nil
+	case UnreadableFileSkip:
+		return nil
+	}
+	drr.sendError(chanChunks, id, filePath, err)
+	return err
+}
 
-			for {
-				readBytes, err := reader.Read(buffer)
-				if readBytes == 0 && err == io.EOF {
-					chanChunks <- &proto.ResourceChunk{
-						Payload: &proto.ResourceChunk_Eof{
-							Eof: &proto.ResourceChunk_ResourceEof{
-								Id: resourceUUID,
-							},
-						},
-					}
-					break
-				} else {
-					payload := buffer[0:readBytes]
-					hash := sha256.Sum256(payload)
-					chanChunks <- &proto.ResourceChunk{
-						Payload: &proto.ResourceChunk_Chunk{
-							Chunk: &proto.ResourceChunk_ResourceContents{
-								Chunk:    payload,
-								Checksum: hash[:],
-								Id:       resourceUUID,
-							},
-						},
-					}
-				}
-			}
+// sendError sends the error frame of an entry which cannot be streamed.
+func (drr *grpcDirectoryResource) sendError(chanChunks chan *proto.ResourceChunk, id, filePath string, err error) {
+	drr.emit(chanChunks, &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Error{
+			Error: &proto.ResourceChunk_ResourceError{
+				Id:      id,
+				Message: fmt.Sprintf("resource '%s' not streamable: %v", filePath, err),
+			},
+		},
+	})
+}
 
+// walkSpooled emits the header and the chunks of a file from the spool, spooling the file first if needed.
+// A file which cannot be opened for spooling is handled by the unreadable file policy.
+func (drr *grpcDirectoryResource) walkSpooled(chanChunks chan *proto.ResourceChunk, header *proto.ResourceChunk, filePath string, finfo fs.FileInfo, id string) error {
+	var openErr error
+	spooled, err := drr.spool.spooled(filePath, finfo, func() (io.ReadCloser, error) {
+		reader, err := drr.retrier.open(filePath, func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		})
+		openErr = err
+		return reader, err
+	})
+	if err != nil && openErr != nil {
+		return drr.handleUnreadable(chanChunks, id, filePath, err)
+	}
+	drr.emit(chanChunks, header)
+	if err == nil {
+		err = spooled.eachContents(0, drr.safeBufferSize, drr.acceptGzip, func(contents *proto.ResourceChunk_ResourceContents) error {
+			contents.Id = id
+			drr.emit(chanChunks, &proto.ResourceChunk{
+				Payload: &proto.ResourceChunk_Chunk{
+					Chunk: contents,
+				},
+			})
 			return nil
 		})
-		chanChunks <- nil
-	}()
-	return chanChunks
+	}
+	if err != nil {
+		drr.sendError(chanChunks, id, filePath, err)
+		return err
+	}
+	drr.emit(chanChunks, &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Eof{
+			Eof: &proto.ResourceChunk_ResourceEof{
+				Id: id,
+			},
+		},
+	})
+	return nil
+}
+
+// header creates the resource header for an entry under the path relative to the walked directory.
+// For files, the header carries the size of the file as of the walk.
+func (drr *grpcDirectoryResource) header(remainingPath string, finfo fs.FileInfo, isDir bool, id string) (*proto.ResourceChunk, error) {
+	sourcePath, targetPath, escaped, err := encodeHeaderPaths(path.Join(NormalizeContextPath(drr.sourcePath), remainingPath),
+		drr.targetPathOf(remainingPath))
+	if err != nil {
+		return nil, err
+	}
+	size := int64(0)
+	if !isDir {
+		size = finfo.Size()
+	}
+	return &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Header{
+			Header: &proto.ResourceChunk_ResourceHeader{
+				SourcePath:    sourcePath,
+				TargetPath:    targetPath,
+				FileMode:      int64(hostFileMode(finfo, isDir)),
+				IsDir:         isDir,
+				TargetUser:    encodeHeaderUser(drr.targetUser),
+				TargetWorkdir: drr.targetWorkdir.Value,
+				Id:            id,
+				Platform:      drr.platform,
+				EscapedPaths:  escaped,
+				Size:          size,
+				HasSize:       !isDir,
+			},
+		},
+	}, nil
+}
+
+// targetPathOf returns the target path of an entry under the path relative to the walked directory.
+func (drr *grpcDirectoryResource) targetPathOf(remainingPath string) string {
+	if drr.renames != nil {
+		if renamed, ok := drr.renames.RenamedTarget(remainingPath); ok {
+			return path.Join(drr.targetPath, renamed)
+		}
+	}
+	return path.Join(drr.targetPath, remainingPath)
 }
+
+// manifest lists the entries of the walked directory without reading the contents.
+// The path of every entry is the entry path relative to the walked directory joined with the base path.
+func (drr *grpcDirectoryResource) manifest(basePath string) ([]*proto.ManifestEntry, error) {
+	entries := []*proto.ManifestEntry{}
+	err := walkDirSorted(drr.resolved, func(entryPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		remainingPath, err := filepath.Rel(drr.resolved, entryPath)
+		if err != nil {
+			return err
+		}
+		remainingPath = slashPath(remainingPath, os.PathSeparator)
+		if remainingPath == "." {
+			remainingPath = ""
+		}
+		if remainingPath != "" && drr.filter != nil && drr.filter.Excluded(remainingPath) {
+			if d.IsDir() && !drr.filter.DescendExcluded() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := &proto.ManifestEntry{
+			Path:       path.Join(basePath, remainingPath),
+			TargetPath: drr.targetPathOf(remainingPath),
+			FileMode:   int64(hostFileMode(finfo, d.IsDir())),
+			IsDir:      d.IsDir(),
+			Platform:   drr.platform,
+		}
+		if !d.IsDir() {
+			entry.Size = finfo.Size()
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// walkDirSorted walks the file tree rooted at root like filepath.WalkDir but sorts the entries
+// of every directory explicitly by the bytes of their names and calls the function for a directory
+// before calling it for any of its children.
+func walkDirSorted(root string, fn fs.WalkDirFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirSortedEntry(root, &statDirEntry{info: info}, fn)
+	}
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkDirSortedEntry(path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		err = fn(path, d, err)
+		if err != nil {
+			if err == fs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for _, entry := range entries {
+		if err := walkDirSortedEntry(filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+type statDirEntry struct {
+	info fs.FileInfo
+}
+
+func (d *statDirEntry) Name() string               { return d.info.Name() }
+func (d *statDirEntry) IsDir() bool                { return d.info.IsDir() }
+func (d *statDirEntry) Type() fs.FileMode          { return d.info.Mode().Type() }
+func (d *statDirEntry) Info() (fs.FileInfo, error) { return d.info, nil }
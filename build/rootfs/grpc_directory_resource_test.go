@@ -0,0 +1,123 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectoryResourceSortedWalk(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, path := range []string{"b/z", "b/a", "a", "B", "c/d/e"} {
+		MustPutTestResource(t, filepath.Join(tempDir, path), []byte(path))
+	}
+
+	resource := resources.NewResolvedDirectoryResourceWithPath(0755, tempDir, "src", "/dst",
+		commands.DefaultWorkdir(), commands.DefaultUser())
+	walked := mustWalkTargetPaths(t, NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+		SafeBufferSize: 1024,
+		Sorted:         true,
+	}, resource))
+
+	assert.Equal(t, []string{
+		"/dst",
+		"/dst/B",
+		"/dst/a",
+		"/dst/b",
+		"/dst/b/a",
+		"/dst/b/z",
+		"/dst/c",
+		"/dst/c/d",
+		"/dst/c/d/e",
+	}, walked)
+}
+
+func TestDirectoryResourceUnreadableFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "a"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(tempDir, "c"), []byte("c"))
+	// a dangling link cannot be opened, regardless of the user running the tests:
+	assert.Nil(t, os.Symlink(filepath.Join(tempDir, "missing"), filepath.Join(tempDir, "b")))
+
+	resource := resources.NewResolvedDirectoryResourceWithPath(0755, tempDir, "src", "/dst",
+		commands.DefaultWorkdir(), commands.DefaultUser())
+
+	t.Run("fail", func(t *testing.T) {
+		chanChunks := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			SafeBufferSize: 1024,
+			Sorted:         true,
+		}, resource).WalkResource()
+		walked := []string{}
+		var walkErr *proto.ResourceChunk_ResourceError
+		for {
+			chunk := <-chanChunks
+			if chunk == nil {
+				break
+			}
+			if header := chunk.GetHeader(); header != nil {
+				walked = append(walked, header.TargetPath)
+			}
+			if chunk.GetError() != nil {
+				walkErr = chunk.GetError()
+			}
+		}
+		assert.Equal(t, []string{"/dst", "/dst/a"}, walked)
+		if assert.NotNil(t, walkErr, "expected an error frame for the unreadable file") {
+			assert.Contains(t, walkErr.Message, filepath.Join(tempDir, "b"))
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		skipped := []string{}
+		walked := mustWalkTargetPaths(t, NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			SafeBufferSize:  1024,
+			Sorted:          true,
+			UnreadableFiles: UnreadableFileWarn,
+			OnUnreadable: func(filePath string, err error) {
+				assert.NotNil(t, err)
+				skipped = append(skipped, filePath)
+			},
+		}, resource))
+		assert.Equal(t, []string{"/dst", "/dst/a", "/dst/c"}, walked)
+		assert.Equal(t, []string{filepath.Join(tempDir, "b")}, skipped)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		walked := mustWalkTargetPaths(t, NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			SafeBufferSize:  1024,
+			Sorted:          true,
+			UnreadableFiles: UnreadableFileSkip,
+			OnUnreadable: func(filePath string, err error) {
+				t.Fatal("expected skipped entries not to be reported", filePath)
+			},
+		}, resource))
+		assert.Equal(t, []string{"/dst", "/dst/a", "/dst/c"}, walked)
+	})
+}
+
+func mustWalkTargetPaths(t *testing.T, resource GRPCReadingDirectoryResource) []string {
+	walked := []string{}
+	chanChunks := resource.WalkResource()
+	for {
+		chunk := <-chanChunks
+		if chunk == nil {
+			break
+		}
+		if header, ok := chunk.GetPayload().(*proto.ResourceChunk_Header); ok {
+			walked = append(walked, header.Header.TargetPath)
+		}
+	}
+	return walked
+}
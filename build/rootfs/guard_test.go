@@ -0,0 +1,60 @@
+package rootfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardOSReleaseID(t *testing.T) {
+	assert.Equal(t, "alpine", osReleaseID(strings.NewReader("NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.13.5\n")))
+	assert.Equal(t, "debian", osReleaseID(strings.NewReader("ID=\"debian\"\n")))
+	assert.Equal(t, "", osReleaseID(strings.NewReader("NAME=unknown\n")))
+	assert.NotEmpty(t, DefaultFacts()[commands.FactArch])
+}
+
+func TestGuardedCommandsSkipped(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	armOnly := commands.RunWithDefaults("apk add arm-tools")
+	armOnly.When = "arch == arm64"
+	nonAlpine := commands.RunWithDefaults("apt-get install curl")
+	nonAlpine.When = "distro != alpine"
+	cmds := []commands.VMInitSerializableCommand{armOnly, nonAlpine, commands.RunWithDefaults("true")}
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: cmds,
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanMessages := make(chan interface{}, 16)
+	go func() {
+		for message := range srv.OnMessage() {
+			chanMessages <- message
+		}
+	}()
+
+	guestClient, err := NewClient(logger.Named("grpc-guest"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		Facts:     commands.Facts{commands.FactArch: "arm64", commands.FactDistro: "alpine"},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, guestClient.Commands())
+
+	assert.Equal(t, &ControlMsgCommandsRequested{}, <-chanMessages)
+	skipped, ok := (<-chanMessages).(*ClientMsgCommandSkipped)
+	assert.True(t, ok)
+	assert.Equal(t, 1, skipped.Index)
+	assert.Equal(t, 1, srv.Summary().CommandsSkipped)
+
+	assert.Equal(t, []IndexedCommand{{Index: 0, Command: armOnly, Env: map[string]string{}}}, guestClient.NextCommandGroup())
+	assert.Equal(t, []IndexedCommand{{Index: 2, Command: cmds[2], Env: map[string]string{}}}, guestClient.NextCommandGroup())
+	assert.Nil(t, guestClient.NextCommand())
+}
@@ -0,0 +1,117 @@
+package rootfs
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+const (
+	// MaxPathComponentLength is the maximum length in bytes of a single path component in a resource header.
+	MaxPathComponentLength = 255
+	// MaxPathLength is the maximum length in bytes of a path in a resource header.
+	MaxPathLength = 4096
+)
+
+// NormalizeContextPath converts a path relative to the build context to a clean forward-slash path.
+// Backslashes are treated as separators so that paths originating from Windows contexts
+// resolve to the same resources on every host.
+func NormalizeContextPath(input string) string {
+	if input == "" {
+		return input
+	}
+	return path.Clean(strings.ReplaceAll(input, "\\", "/"))
+}
+
+// EncodeHeaderPath converts a host path to a forward-slash path suitable for a resource header.
+// Protobuf strings must be valid UTF-8, paths containing invalid UTF-8 sequences or literal backslashes
+// are escaped: every such byte and every % is encoded as %XX and the returned boolean is true.
+// Returns an error if the path or any of its components exceed the maximum lengths.
+func EncodeHeaderPath(input string) (string, bool, error) {
+	input = filepath.ToSlash(input)
+	if len(input) > MaxPathLength {
+		return "", false, fmt.Errorf("path length %d exceeds maximum of %d bytes", len(input), MaxPathLength)
+	}
+	for _, component := range strings.Split(input, "/") {
+		if len(component) > MaxPathComponentLength {
+			return "", false, fmt.Errorf("path component length %d exceeds maximum of %d bytes", len(component), MaxPathComponentLength)
+		}
+	}
+	if utf8.ValidString(input) && !strings.Contains(input, "\\") {
+		return input, false, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if (r == utf8.RuneError && size == 1) || r == '%' || r == '\\' {
+			sb.WriteString(fmt.Sprintf("%%%02X", input[i]))
+		} else {
+			sb.WriteString(input[i : i+size])
+		}
+		i = i + size
+	}
+	return sb.String(), true, nil
+}
+
+// DecodeHeaderPath reverses EncodeHeaderPath for a path received in a resource header.
+func DecodeHeaderPath(input string, escaped bool) (string, error) {
+	if !escaped {
+		return input, nil
+	}
+	decoded := make([]byte, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		if input[i] != '%' {
+			decoded = append(decoded, input[i])
+			continue
+		}
+		if i+2 >= len(input) {
+			return "", fmt.Errorf("invalid escape sequence at %d in '%s'", i, input)
+		}
+		b, err := strconv.ParseUint(input[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence at %d in '%s'", i, input)
+		}
+		decoded = append(decoded, byte(b))
+		i = i + 2
+	}
+	return string(decoded), nil
+}
+
+// encodeHeaderPaths encodes the source and the target path of a resource header.
+// If any of the paths requires escaping, both paths are escaped so that a single flag describes the header.
+func encodeHeaderPaths(sourcePath, targetPath string) (string, string, bool, error) {
+	encodedSourcePath, sourceEscaped, err := EncodeHeaderPath(NormalizeContextPath(sourcePath))
+	if err != nil {
+		return "", "", false, err
+	}
+	encodedTargetPath, targetEscaped, err := EncodeHeaderPath(targetPath)
+	if err != nil {
+		return "", "", false, err
+	}
+	if sourceEscaped == targetEscaped {
+		return encodedSourcePath, encodedTargetPath, sourceEscaped, nil
+	}
+	if !sourceEscaped {
+		encodedSourcePath = strings.ReplaceAll(encodedSourcePath, "%", "%25")
+	}
+	if !targetEscaped {
+		encodedTargetPath = strings.ReplaceAll(encodedTargetPath, "%", "%25")
+	}
+	return encodedSourcePath, encodedTargetPath, true, nil
+}
+
+// encodeHeaderUser returns the user of a resource header in the user[:group] form of the USER value,
+// numeric IDs as written, for example 1000:1000. A value which does not parse is sent as is
+// and fails on the client resolving the owner.
+func encodeHeaderUser(user commands.User) string {
+	spec, err := user.Parse()
+	if err != nil {
+		return user.Value
+	}
+	return spec.String()
+}
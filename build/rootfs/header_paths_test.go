@@ -0,0 +1,66 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderPathEncoding(t *testing.T) {
+	for _, input := range []string{
+		"/etc/passwd",
+		"/app/100% done",
+		"/app/caf\xe9",
+		"/app/back\\slash",
+		"/app/zażółć gęślą jaźń",
+	} {
+		encoded, escaped, err := EncodeHeaderPath(input)
+		assert.Nil(t, err)
+		decoded, err := DecodeHeaderPath(encoded, escaped)
+		assert.Nil(t, err)
+		assert.Equal(t, input, decoded)
+	}
+
+	encoded, escaped, err := EncodeHeaderPath("/app/caf\xe9 100%")
+	assert.Nil(t, err)
+	assert.True(t, escaped)
+	assert.Equal(t, "/app/caf%E9 100%25", encoded)
+
+	_, _, err = EncodeHeaderPath("/app/" + strings.Repeat("a", MaxPathComponentLength+1))
+	assert.NotNil(t, err)
+	_, _, err = EncodeHeaderPath(strings.Repeat("/a", MaxPathLength))
+	assert.NotNil(t, err)
+
+	_, err = DecodeHeaderPath("/app/%zz", true)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "dir/sub/file", NormalizeContextPath("dir\\sub\\file"))
+	assert.Equal(t, "dir/file", NormalizeContextPath("./dir//file"))
+}
+
+func TestHeaderUserEncoding(t *testing.T) {
+	assert.Equal(t, "1000:1000", encodeHeaderUser(commands.User{Value: " 1000 : 1000 "}))
+	assert.Equal(t, "a:b:c", encodeHeaderUser(commands.User{Value: "a:b:c"}), "expected an invalid user as given")
+}
+
+func TestDirectoryResourceEscapesNonUTF8Names(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "caf\xe9"), []byte("latin-1"), 0644); err != nil {
+		t.Skip("file system does not support non UTF-8 names", err)
+	}
+
+	resource := resources.NewResolvedDirectoryResourceWithPath(0755, tempDir, "src", "/dst",
+		commands.DefaultWorkdir(), commands.DefaultUser())
+	walked := mustWalkTargetPaths(t, NewGRPCDirectoryResource(1024, resource))
+
+	assert.Equal(t, []string{"/dst", "/dst/caf%E9"}, walked)
+}
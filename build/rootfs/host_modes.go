@@ -0,0 +1,41 @@
+package rootfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// windowsExecutableExtensions are the extensions of the files walked on a Windows host
+// which are executable in the guest, Windows has no executable permission.
+var windowsExecutableExtensions = map[string]struct{}{
+	".bat": {},
+	".cmd": {},
+	".com": {},
+	".exe": {},
+	".ps1": {},
+	".sh":  {},
+}
+
+// windowsFileMode maps the mode of an entry walked on a Windows host to the permissions in the guest.
+// The mode reported on Windows reflects only the read-only attribute: directories and executables
+// are mapped to 0755, other files to 0644, read-only entries lose the write permissions.
+func windowsFileMode(mode fs.FileMode, name string, isDir bool) fs.FileMode {
+	perm := fs.FileMode(0644)
+	if _, ok := windowsExecutableExtensions[strings.ToLower(path.Ext(name))]; ok || isDir {
+		perm = 0755
+	}
+	if mode.Perm()&0200 == 0 {
+		perm = perm &^ 0222
+	}
+	return perm
+}
+
+// slashPath returns the path with the separators of the host replaced by slashes,
+// like filepath.ToSlash but for any separator.
+func slashPath(hostPath string, separator rune) string {
+	if separator == '/' {
+		return hostPath
+	}
+	return strings.ReplaceAll(hostPath, string(separator), "/")
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package rootfs
+
+import "io/fs"
+
+// hostFileMode returns the permissions in the guest of an entry walked on the host,
+// the permissions of the entry on Linux and macOS hosts.
+func hostFileMode(info fs.FileInfo, _ bool) fs.FileMode {
+	return info.Mode().Perm()
+}
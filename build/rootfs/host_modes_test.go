@@ -0,0 +1,54 @@
+package rootfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowsFileModes(t *testing.T) {
+	// the modes as reported on a Windows host, reflecting only the read-only attribute:
+	windowsFS := fstest.MapFS{
+		"app":                 &fstest.MapFile{Mode: fs.ModeDir | 0777},
+		"app/bin":             &fstest.MapFile{Mode: fs.ModeDir | 0777},
+		"app/readme.txt":      &fstest.MapFile{Mode: 0666},
+		"app/locked.txt":      &fstest.MapFile{Mode: 0444},
+		"app/bin/setup.EXE":   &fstest.MapFile{Mode: 0666},
+		"app/bin/run.sh":      &fstest.MapFile{Mode: 0666},
+		"app/bin/locked.cmd":  &fstest.MapFile{Mode: 0444},
+		"app/readonly":        &fstest.MapFile{Mode: fs.ModeDir | 0555},
+		"app/readonly/a.conf": &fstest.MapFile{Mode: 0666},
+	}
+
+	modes := map[string]fs.FileMode{}
+	assert.Nil(t, fs.WalkDir(windowsFS, "app", func(entryPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modes[entryPath] = windowsFileMode(info.Mode(), info.Name(), d.IsDir())
+		return nil
+	}))
+	assert.Equal(t, map[string]fs.FileMode{
+		"app":                 0755,
+		"app/bin":             0755,
+		"app/bin/locked.cmd":  0555,
+		"app/bin/run.sh":      0755,
+		"app/bin/setup.EXE":   0755,
+		"app/locked.txt":      0444,
+		"app/readme.txt":      0644,
+		"app/readonly":        0555,
+		"app/readonly/a.conf": 0644,
+	}, modes)
+}
+
+func TestSlashPath(t *testing.T) {
+	assert.Equal(t, "sub/dir/file", slashPath(`sub\dir\file`, '\\'))
+	assert.Equal(t, `sub\dir/file`, slashPath(`sub\dir/file`, '/'))
+	assert.Equal(t, "", slashPath("", '\\'))
+}
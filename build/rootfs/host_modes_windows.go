@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package rootfs
+
+import "io/fs"
+
+// hostFileMode returns the permissions in the guest of an entry walked on the host.
+func hostFileMode(info fs.FileInfo, isDir bool) fs.FileMode {
+	return windowsFileMode(info.Mode(), info.Name(), isDir)
+}
@@ -3,81 +3,408 @@ package rootfs
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
 	"github.com/combust-labs/firebuild-shared/grpc/proto"
 	"github.com/gofrs/uuid"
 	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/metadata"
 )
 
 // EventProvider provides the event subsriptions to the server executor.
 // When client event occurs, a corresponding event will be sent via one of the channels.
 type EventProvider interface {
+	// OnMessage returns the channel of the events of the build. The channel must be consumed, the server waits
+	// until every event is received. Every event is received once: multiple goroutines reading the channel
+	// split the events between them, use Subscribe for an independent stream of the events.
 	OnMessage() <-chan interface{}
+	// Subscribe returns a channel receiving a copy of every event sent via OnMessage and a function ending
+	// the subscription. Any number of subscribers receive the events independently of each other and
+	// of the OnMessage consumer, a subscriber not keeping up misses events
+	// except the events ending the build, which wait for the subscriber. The channel is closed when the server stops.
+	Subscribe() (<-chan interface{}, func())
 }
 
 type serverImplInterface interface {
 	proto.RootfsServerServer
 	EventProvider
+	Cancel(error)
+	Emit(interface{})
 	Stop()
+	Stats() ServerStats
+	SubscribeLogs() (<-chan LogLine, func())
+	Summary() BuildSummary
+	Wait()
 }
 
 type serverImpl struct {
 	m       *sync.Mutex
+	aborted bool
 	stopped bool
 
-	logger        hclog.Logger
-	serviceConfig *GRPCServiceConfig
-	serverCtx     *WorkContext
+	logger         hclog.Logger
+	logsLogger     hclog.Logger
+	resourceLogger hclog.Logger
+	serviceConfig  *GRPCServiceConfig
+	serverCtx      *WorkContext
 
+	commandsRequested bool
+	commandAcks       map[int]proto.CommandAck_Phase
+	commandTimers     map[int]*time.Timer
+	runningCommands   map[int]struct{}
+	commandRetries    map[int]int
+	lastAckedIndex    int
+	summary           BuildSummary
+
+	bytesTotal     int64
+	bytesTotalOnce sync.Once
+	journaledBytes int64
+
+	portForwards map[string]struct{}
+	logs         *logBroadcaster
+	events       *eventBroadcaster
+	chunkBudget  *chunkBudget
+	fileRetries  *fileRetries
+	fileRetrier  *fileRetrier
+	frameTracer  *frameTracer
+	routines     *routineGroup
+
+	cancelReason error
+	chanCancel   chan struct{}
 	chanMessages chan interface{}
+	chanStopped  chan struct{}
 }
 
 func newServerImpl(logger hclog.Logger, serverCtx *WorkContext, serviceConfig *GRPCServiceConfig) serverImplInterface {
-	return &serverImpl{
-		m:             &sync.Mutex{},
-		logger:        logger,
-		serviceConfig: serviceConfig,
-		serverCtx:     serverCtx,
-		chanMessages:  make(chan interface{}),
+	impl := &serverImpl{
+		m:               &sync.Mutex{},
+		logger:          serviceConfig.LogLevels.logger(logger, LogSubsystemServerLifecycle),
+		logsLogger:      serviceConfig.LogLevels.logger(logger.Named("logs"), LogSubsystemServerLogs),
+		resourceLogger:  serviceConfig.LogLevels.logger(logger.Named("resource"), LogSubsystemServerResource),
+		serviceConfig:   serviceConfig,
+		serverCtx:       serverCtx,
+		commandAcks:     map[int]proto.CommandAck_Phase{},
+		commandTimers:   map[int]*time.Timer{},
+		runningCommands: map[int]struct{}{},
+		commandRetries:  map[int]int{},
+		lastAckedIndex:  -1,
+		summary:         newBuildSummary(serverCtx, serviceConfig),
+		portForwards:    map[string]struct{}{},
+		logs:            newLogBroadcaster(),
+		events:          newEventBroadcaster(),
+		chunkBudget:     newChunkBudget(serviceConfig.MaxBufferedChunkBytes),
+		fileRetries:     &fileRetries{},
+		frameTracer:     newFrameTracer(serviceConfig.FrameTraceSampling),
+		routines:        newRoutineGroup(),
+		chanCancel:      make(chan struct{}),
+		chanMessages:    make(chan interface{}),
+		chanStopped:     make(chan struct{}),
 	}
+	impl.fileRetrier = newFileRetrier(serviceConfig.FileRetry, impl.fileRetries, func(name string, attempt int, err error) {
+		impl.resourceLogger.Warn("retrying file operation", "name", name, "attempt", attempt, "reason", err)
+	})
+	impl.restoreJournal()
+	if serviceConfig.ComputeFingerprint {
+		impl.routines.goTracked(impl.computeFingerprint)
+	}
+	impl.keepLeasesAlive()
+	return impl
 }
 
-func (impl *serverImpl) Abort(ctx context.Context, req *proto.AbortRequest) (*proto.Empty, error) {
+func (impl *serverImpl) Abort(ctx context.Context, req *proto.AbortRequest) (*proto.AbortResponse, error) {
 	// handle stopped server
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.Empty{}, fmt.Errorf("stopped")
+		return &proto.AbortResponse{}, ErrServerStopped
+	}
+	if impl.completedLocked(ctx) {
+		defer impl.m.Unlock()
+		return &proto.AbortResponse{}, ErrAlreadyCompleted
 	}
+	impl.aborted = true
+	commandID := impl.runningCommandIDLocked()
+	abortErr := errors.New(impl.serviceConfig.Redactor.Redact(req.Error))
+	if impl.broadcasting() {
+		guestID := guestIDFromContext(ctx)
+		finished, buildErr := impl.guestFinished(guestID, abortErr)
+		impl.m.Unlock()
+		impl.emit(&ClientMsgGuestFinished{GuestID: guestID, Error: abortErr})
+		if finished {
+			impl.emit(&ClientMsgAborted{Error: buildErr, CommandID: commandID})
+			impl.runHook("after-abort", impl.serverCtx.OnAfterAbort)
+		}
+		return &proto.AbortResponse{Debug: impl.serviceConfig.DebugOnAbort}, nil
+	}
+	impl.summary.FinishedAt = time.Now()
+	impl.summary.Error = abortErr
+	impl.journalLocked()
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ClientMsgAborted{Error: errors.New(req.Error)}
+	impl.emit(&ClientMsgAborted{Error: abortErr, CommandID: commandID})
+	impl.runHook("after-abort", impl.serverCtx.OnAfterAbort)
+	return &proto.AbortResponse{Debug: impl.serviceConfig.DebugOnAbort}, nil
+}
+
+func (impl *serverImpl) Ack(ctx context.Context, req *proto.CommandAck) (*proto.Empty, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, ErrServerStopped
+	}
+	index := int(req.Index)
+	if index < 0 || index >= len(impl.serverCtx.ExecutableCommands) {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, fmt.Errorf("%w: command index out of range: %d", ErrInvalidArgument, index)
+	}
+	impl.lastAckedIndex = index
+	impl.commandAcks[index] = req.Phase
+	switch req.Phase {
+	case proto.CommandAck_STARTED:
+		impl.runningCommands[index] = struct{}{}
+		if timeout := impl.commandTimeout(index); timeout > 0 {
+			if timer, ok := impl.commandTimers[index]; ok {
+				timer.Stop()
+			}
+			impl.commandTimers[index] = time.AfterFunc(timeout, func() {
+				impl.commandTimedOut(index, timeout)
+			})
+		}
+	case proto.CommandAck_FINISHED:
+		delete(impl.runningCommands, index)
+		if timer, ok := impl.commandTimers[index]; ok {
+			timer.Stop()
+			delete(impl.commandTimers, index)
+		}
+		impl.summary.CommandsFinished = impl.summary.CommandsFinished + 1
+		if impl.broadcasting() {
+			impl.guestCommandFinished(guestIDFromContext(ctx))
+		}
+	case proto.CommandAck_SKIPPED:
+		impl.summary.CommandsSkipped = impl.summary.CommandsSkipped + 1
+	case proto.CommandAck_RETRIED:
+		impl.commandRetries[index] = impl.commandRetries[index] + 1
+		impl.summary.CommandRetries = impl.summary.CommandRetries + 1
+	}
+	attempts := impl.commandRetries[index] + 1
+	impl.journalLocked()
+	impl.m.Unlock()
+
+	var commandErr error
+	if req.Error != "" {
+		commandErr = errors.New(impl.serviceConfig.Redactor.Redact(req.Error))
+	}
+
+	switch req.Phase {
+	case proto.CommandAck_STARTED:
+		impl.emit(&ClientMsgCommandStarted{Index: index, CommandID: impl.commandID(index)})
+	case proto.CommandAck_SKIPPED:
+		impl.emit(&ClientMsgCommandSkipped{Index: index, CommandID: impl.commandID(index)})
+	case proto.CommandAck_RETRIED:
+		impl.emit(&ClientMsgCommandRetried{Index: index, CommandID: impl.commandID(index), Attempt: attempts - 1, Error: commandErr})
+	default:
+		impl.emit(&ClientMsgCommandFinished{Index: index, CommandID: impl.commandID(index), Error: commandErr, Attempts: attempts})
+	}
 	return &proto.Empty{}, nil
 }
 
+// commandTimeout returns the timeout of a command, must be called under lock.
+func (impl *serverImpl) commandTimeout(index int) time.Duration {
+	if timeout, ok := impl.serverCtx.CommandTimeouts[index]; ok {
+		return timeout
+	}
+	return impl.serviceConfig.DefaultCommandTimeout
+}
+
+func (impl *serverImpl) commandTimedOut(index int, timeout time.Duration) {
+	impl.m.Lock()
+	if _, ok := impl.commandTimers[index]; !ok || impl.stopped {
+		impl.m.Unlock()
+		return
+	}
+	delete(impl.commandTimers, index)
+	timeoutErr := fmt.Errorf("command %d did not finish within %v", index, timeout)
+	impl.summary.FinishedAt = time.Now()
+	impl.summary.Error = timeoutErr
+	impl.m.Unlock()
+
+	impl.logger.Warn("command timeout exceeded, cancelling", "index", index, "command-id", impl.commandID(index), "timeout", timeout)
+	impl.Cancel(timeoutErr)
+	impl.Emit(&ControlMsgCommandTimeout{Index: index, CommandID: impl.commandID(index), Timeout: timeout, Summary: impl.Summary()})
+}
+
+func (impl *serverImpl) Cancel(reason error) {
+	impl.m.Lock()
+	defer impl.m.Unlock()
+	if impl.cancelReason != nil {
+		return
+	}
+	if reason == nil {
+		reason = errors.New("cancelled")
+	}
+	impl.cancelReason = reason
+	close(impl.chanCancel)
+}
+
 func (impl *serverImpl) Commands(ctx context.Context, _ *proto.Empty) (*proto.CommandsResponse, error) {
 	// handle stopped server
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.CommandsResponse{Command: []string{}}, fmt.Errorf("stopped")
+		return &proto.CommandsResponse{Command: []string{}}, ErrServerStopped
+	}
+	// observers only read the commands, the build starts with the executor:
+	firstRequest := false
+	if clientRoleFromContext(ctx) == ClientRoleExecutor {
+		firstRequest = !impl.commandsRequested
+		impl.commandsRequested = true
+	}
+	if firstRequest {
+		impl.journalLocked()
 	}
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ControlMsgCommandsRequested{}
+	if firstRequest && impl.serverCtx.OnBeforeCommands != nil {
+		if err := impl.serverCtx.OnBeforeCommands(impl.Summary()); err != nil {
+			impl.logger.Error("before commands hook failed", "reason", err)
+			return &proto.CommandsResponse{Command: []string{}}, err
+		}
+	}
+
+	impl.emit(&ControlMsgCommandsRequested{})
 	response := &proto.CommandsResponse{Command: []string{}}
-	for _, cmd := range impl.serverCtx.ExecutableCommands {
-		commandBytes, err := json.Marshal(cmd)
+	for _, cmd := range impl.serverCtx.substitutedCommands() {
+		commandBytes, err := commands.Serialize(commands.ApplyDefaults(cmd, impl.serverCtx.defaultUser(), impl.serverCtx.defaultWorkdir()))
 		if err != nil {
 			return response, err
 		}
 		response.Command = append(response.Command, string(commandBytes))
 	}
+	if len(impl.serverCtx.CommandGroups) == len(impl.serverCtx.ExecutableCommands) {
+		for _, group := range impl.serverCtx.CommandGroups {
+			response.Group = append(response.Group, int32(group))
+		}
+	}
+	return response, nil
+}
+
+func (impl *serverImpl) Debug(stream proto.RootfsServer_DebugServer) error {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return ErrServerStopped
+	}
+	if !impl.serviceConfig.DebugOnAbort || !impl.aborted {
+		defer impl.m.Unlock()
+		return fmt.Errorf("debug session available only after abort with debug enabled")
+	}
+	impl.m.Unlock()
+
+	session := newGRPCDebugSession(stream)
+	impl.routines.goTracked(session.receive)
+
+	impl.emit(&ClientMsgDebugSession{Session: session})
+
+	select {
+	case <-session.Done():
+	case <-impl.chanStopped:
+		session.Close()
+	}
+	session.awaitWrite()
+
+	return nil
+}
+
+func (impl *serverImpl) Emit(message interface{}) {
+	impl.emit(message)
+}
+
+func (impl *serverImpl) Environment(ctx context.Context, _ *proto.Empty) (*proto.EnvironmentResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.EnvironmentResponse{Env: map[string]string{}}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	impl.emit(&ControlMsgEnvironmentRequested{})
+	return &proto.EnvironmentResponse{Env: impl.serverCtx.Environment()}, nil
+}
+
+func (impl *serverImpl) Manifest(ctx context.Context, req *proto.ManifestRequest) (*proto.ManifestResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.ManifestResponse{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	priorities := impl.serverCtx.Priorities()
+	keys := []string{req.Path}
+	if req.Path == "" {
+		keys = []string{}
+		for key := range impl.serverCtx.ResourcesResolved {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sortByPriority(keys, priorities)
+	}
+
+	response := &proto.ManifestResponse{Entries: []*proto.ManifestEntry{}}
+	for _, key := range keys {
+		ress, ok := impl.lookupResources(key)
+		if !ok {
+			return response, withResourcePath(fmt.Errorf("%w: '%s/%s'", ErrResourceNotFound, req.Stage, key), key, req.Stage)
+		}
+		for _, resource := range ress {
+			if !resources.PlatformMatches(resources.PlatformOf(resource), impl.serverCtx.Platform) {
+				continue
+			}
+			if resource.IsDir() {
+				entries, err := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{}, resource).(*grpcDirectoryResource).manifest(key)
+				if err != nil {
+					return response, withResourcePath(fmt.Errorf("failed listing directory resource: %v", err), key, req.Stage)
+				}
+				for _, entry := range entries {
+					entry.Priority = int32(priorityOf(priorities, key))
+				}
+				response.Entries = append(response.Entries, entries...)
+				continue
+			}
+			entry := &proto.ManifestEntry{
+				Path:       key,
+				TargetPath: resource.TargetPath(),
+				FileMode:   int64(resource.TargetMode()),
+				Platform:   resources.PlatformOf(resource),
+				Priority:   int32(priorityOf(priorities, key)),
+			}
+			// the size is known only for local files and the resources knowing their size:
+			if size, ok := resources.SizeOf(resource); ok {
+				entry.Size = size
+			} else if statResult, err := os.Stat(resource.ResolvedURIOrPath()); err == nil && statResult.Mode().IsRegular() {
+				entry.Size = statResult.Size()
+			}
+			response.Entries = append(response.Entries, entry)
+		}
+	}
 	return response, nil
 }
 
@@ -86,128 +413,515 @@ func (impl *serverImpl) Ping(ctx context.Context, req *proto.PingRequest) (*prot
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.PingResponse{Id: ""}, fmt.Errorf("stopped")
+		return &proto.PingResponse{Id: ""}, ErrServerStopped
 	}
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ControlMsgPingSent{}
+	impl.emit(&ControlMsgPingSent{})
 	return &proto.PingResponse{Id: req.Id}, nil
 }
 
+func (impl *serverImpl) PortForward(ctx context.Context, req *proto.PortForwardRequest) (*proto.PortForwardResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.PortForwardResponse{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	message := &ControlMsgPortForwardRequested{
+		ID:          uuid.Must(uuid.NewV4()).String(),
+		Protocol:    req.Protocol,
+		HostAddress: req.HostAddress,
+		GuestPort:   int(req.GuestPort),
+		chanReply:   make(chan portForwardReply, 1),
+	}
+	impl.emit(message)
+
+	select {
+	case reply := <-message.chanReply:
+		if reply.err != nil {
+			return &proto.PortForwardResponse{}, reply.err
+		}
+		impl.m.Lock()
+		impl.portForwards[message.ID] = struct{}{}
+		impl.m.Unlock()
+		return &proto.PortForwardResponse{Id: message.ID, GuestAddress: reply.guestAddress}, nil
+	case <-time.After(time.Millisecond * time.Duration(impl.serviceConfig.PortForwardTimeoutMillis)):
+		return &proto.PortForwardResponse{}, fmt.Errorf("%w: port forward not accepted within timeout", context.DeadlineExceeded)
+	case <-ctx.Done():
+		return &proto.PortForwardResponse{}, ctx.Err()
+	case <-impl.chanStopped:
+		return &proto.PortForwardResponse{}, ErrServerStopped
+	}
+}
+
+func (impl *serverImpl) PortForwardClose(ctx context.Context, req *proto.PortForwardCloseRequest) (*proto.Empty, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, ErrServerStopped
+	}
+	if _, ok := impl.portForwards[req.Id]; !ok {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, fmt.Errorf("%w: unknown port forward: '%s'", ErrInvalidArgument, req.Id)
+	}
+	delete(impl.portForwards, req.Id)
+	impl.m.Unlock()
+
+	impl.emit(&ControlMsgPortForwardClosed{ID: req.Id})
+	return &proto.Empty{}, nil
+}
+
 func (impl *serverImpl) Resource(req *proto.ResourceRequest, stream proto.RootfsServer_ResourceServer) error {
 	// handle stopped server
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return fmt.Errorf("stopped")
+		return ErrServerStopped
 	}
 	impl.m.Unlock()
 
-	if ress, ok := impl.serverCtx.ResourcesResolved[req.Path]; ok {
+	if req.Offset < 0 {
+		return withResourcePath(fmt.Errorf("%w: negative offset %d", ErrInvalidArgument, req.Offset), req.Path, req.Stage)
+	}
+
+	servedResources, servedBytes := 0, int64(0)
+	matched := false
+
+	if ress, ok := impl.lookupResources(req.Path); ok {
 		for _, resource := range ress {
 
-			reader, err := resource.Contents()
+			if !resources.PlatformMatches(resources.PlatformOf(resource), impl.serverCtx.Platform) {
+				impl.resourceLogger.Debug("skipping resource for different platform",
+					"resource", resource.TargetPath(),
+					"platform", resources.PlatformOf(resource))
+				continue
+			}
+			if req.TargetPath != "" && resource.TargetPath() != req.TargetPath {
+				continue
+			}
+			if err := refuseSensitive(resource, req.Path, req.Stage); err != nil {
+				return err
+			}
+			matched = true
+
+			resourcesCount, bytesCount, err := impl.sendResource(req, resource, stream, nil)
+			servedResources = servedResources + resourcesCount
+			servedBytes = servedBytes + bytesCount
 			if err != nil {
 				return err
 			}
+		}
 
-			impl.logger.Debug("sending resource data", "resource", resource.TargetPath())
+	} else {
+		return withResourcePath(fmt.Errorf("%w: '%s/%s'", ErrResourceNotFound, req.Stage, req.Path), req.Path, req.Stage)
+	}
 
-			if resource.IsDir() {
-				// by using this safe value, we leave space for other fields of the payload
-				grpcDirResource := NewGRPCDirectoryResource(impl.serviceConfig.SafeClientMaxRecvMsgSize(), resource)
-				outputChannel := grpcDirResource.WalkResource()
-				for {
-					payload := <-outputChannel
-					if payload == nil {
-						break
-					}
-					sendErr := stream.Send(payload)
-					if sendErr != nil {
-						// TODO: requires server abort
-						impl.logger.Error("failed sending walk directory packet", "reason", sendErr)
-						return sendErr
-					}
+	if req.TargetPath != "" && !matched {
+		return withResourcePath(fmt.Errorf("%w: '%s/%s' with target '%s'", ErrResourceNotFound, req.Stage, req.Path, req.TargetPath), req.Path, req.Stage)
+	}
+
+	impl.emit(&ControlMsgResourceServed{
+		Path:      req.Path,
+		Stage:     req.Stage,
+		Resources: servedResources,
+		Bytes:     servedBytes,
+	})
+	return nil
+}
+
+// sendResource streams a single resolved resource, returns the number of resources and content bytes sent.
+// The optional include function selects the entries of a directory resource to send.
+func (impl *serverImpl) sendResource(req *proto.ResourceRequest, resource resources.ResolvedResource, stream proto.RootfsServer_ResourceServer,
+	include func(targetPath, filePath string, isDir bool) bool) (int, int64, error) {
+	servedResources, servedBytes := 0, int64(0)
+
+	// by using this safe value, we leave space for other fields of the payload
+	bufferSize := impl.serviceConfig.SafeClientMaxRecvMsgSize()
+	multiplex := 1
+	if resource.IsDir() {
+		multiplex = resourceMultiplex(req.Multiplex)
+	}
+	acquired, err := impl.chunkBudget.acquire(stream.Context(), int64(bufferSize*multiplex))
+	if err != nil {
+		return servedResources, servedBytes, err
+	}
+	defer impl.chunkBudget.release(acquired)
+	if int(acquired) < bufferSize*multiplex {
+		// the budget allows fewer files in flight:
+		multiplex = int(acquired) / bufferSize
+		if multiplex < 1 {
+			multiplex = 1
+			bufferSize = int(acquired)
+		}
+	}
+
+	if resource.IsDir() && req.Offset > 0 {
+		return servedResources, servedBytes, withResourcePath(fmt.Errorf("%w: offset not supported for directory resources", ErrInvalidArgument), req.Path, req.Stage)
+	}
+
+	reader, err := impl.resourceContents(stream.Context(), resource)
+	if err != nil {
+		return servedResources, servedBytes, err
+	}
+	defer reader.Close()
+
+	impl.resourceLogger.Debug("sending resource data", "resource", resource.TargetPath())
+
+	if resource.IsDir() {
+		batchFileSize := req.BatchFileSize
+		if batchFileSize > int64(bufferSize/2) {
+			// a batched file leaves room for the header and the other files of the batch:
+			batchFileSize = int64(bufferSize / 2)
+		}
+		grpcDirResource := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+			BatchFileSize:        batchFileSize,
+			ChecksumCache:        impl.serverCtx.ChecksumCache,
+			AcceptCompression:    req.AcceptCompression,
+			CompressionThreshold: impl.compressionThreshold(req.AcceptCompression),
+			Include:              include,
+			Multiplex:            multiplex,
+			SafeBufferSize:       bufferSize,
+			Spool:                impl.serverCtx.Spool,
+			Sorted:               impl.serviceConfig.SortedDirectoryWalk,
+			UnreadableFiles:      impl.serviceConfig.UnreadableFiles,
+			OnUnreadable: func(filePath string, err error) {
+				impl.resourceLogger.Warn("skipping unreadable directory resource entry", "resource", resource.TargetPath(), "path", filePath, "reason", err)
+				impl.emit(&ControlMsgResourceEntrySkipped{Path: req.Path, Stage: req.Stage, FilePath: filePath, Reason: err})
+			},
+			retrier: impl.fileRetrier,
+			tracer:  impl.frameTracer,
+		}, resource)
+		outputChannel := grpcDirResource.WalkResource()
+		for {
+			payload := <-outputChannel
+			if payload == nil {
+				break
+			}
+			if walkErr := payload.GetError(); walkErr != nil {
+				if sendErr := impl.frameTracer.send(req.Path, payload, stream.Send); sendErr != nil {
+					impl.resourceLogger.Error("failed sending walk directory error", "reason", sendErr)
+				}
+				impl.routines.goTracked(func() { drainWalk(outputChannel, impl.frameTracer) })
+				return servedResources, servedBytes, withResourcePath(fmt.Errorf("failed walking directory resource: %s", walkErr.Message), req.Path, req.Stage)
+			}
+			switch tpayload := payload.GetPayload().(type) {
+			case *proto.ResourceChunk_Header:
+				impl.serverCtx.applyHeaderDefaults(tpayload.Header)
+				impl.countServed(1, 0)
+				servedResources = servedResources + 1
+			case *proto.ResourceChunk_Chunk:
+				impl.countServed(0, contentsSize(tpayload.Chunk))
+				servedBytes = servedBytes + int64(contentsSize(tpayload.Chunk))
+			case *proto.ResourceChunk_Batch:
+				for _, entry := range tpayload.Batch.Entries {
+					impl.serverCtx.applyHeaderDefaults(entry.Header)
+					impl.countServed(1, len(entry.Contents))
+					servedResources = servedResources + 1
+					servedBytes = servedBytes + int64(len(entry.Contents))
 				}
-				continue
 			}
+			sendErr := impl.frameTracer.send(req.Path, payload, stream.Send)
+			if sendErr != nil {
+				// TODO: requires server abort
+				impl.resourceLogger.Error("failed sending walk directory packet", "reason", sendErr)
+				impl.routines.goTracked(func() { drainWalk(outputChannel, impl.frameTracer) })
+				return servedResources, servedBytes, sendErr
+			}
+		}
+		return servedResources, servedBytes, nil
+	}
+
+	resourceUUID := uuid.Must(uuid.NewV4()).String()
+	header, err := fileResourceHeader(resource, resourceUUID)
+	if err != nil {
+		return servedResources, servedBytes, withResourcePath(err, req.Path, req.Stage)
+	}
+	impl.serverCtx.applyHeaderDefaults(header)
+	header.Offset = req.Offset
+	if impl.serverCtx.Spool != nil && spoolable(resource) {
+		return impl.sendSpooled(req, resource, reader, header, bufferSize, stream)
+	}
+	// the digest of the contents is computed only for a client verifying the contents:
+	var digest hash.Hash
+	if req.ContentsDigest || req.PrefixDigest != "" {
+		digest = sha256.New()
+	}
+	if err := skipVerifiedPrefix(reader, req.Offset, req.PrefixDigest, digest); err != nil {
+		return servedResources, servedBytes, withResourcePath(err, req.Path, req.Stage)
+	}
+	sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Header{
+			Header: header,
+		},
+	})
+	if sendErr != nil {
+		// TODO: requires server abort
+		impl.resourceLogger.Error("Failed sending header", "reason", sendErr)
+		return servedResources, servedBytes, sendErr
+	}
+	impl.countServed(1, 0)
+	servedResources = servedResources + 1
+
+	// only the checksums of local files are cached:
+	var localInfo fs.FileInfo
+	if impl.serverCtx.ChecksumCache != nil {
+		localInfo, _ = localFileInfo(resource.ResolvedURIOrPath())
+	}
+	offset := req.Offset
+	compression := impl.compressionThreshold(req.AcceptCompression)
+
+	buffer := make([]byte, bufferSize)
 
-			resourceUUID := uuid.Must(uuid.NewV4()).String()
-			sendErr := stream.Send(&proto.ResourceChunk{
-				Payload: &proto.ResourceChunk_Header{
-					Header: &proto.ResourceChunk_ResourceHeader{
-						SourcePath:    resource.SourcePath(),
-						TargetPath:    resource.TargetPath(),
-						FileMode:      int64(resource.TargetMode()),
-						IsDir:         resource.IsDir(),
-						TargetUser:    resource.TargetUser().Value,
-						TargetWorkdir: resource.TargetWorkdir().Value,
-						Id:            resourceUUID,
-					},
+	for {
+		readBytes, err := reader.Read(buffer)
+		if readBytes > 0 {
+			payload := buffer[0:readBytes]
+			if digest != nil {
+				digest.Write(payload)
+			}
+			checksum := impl.serverCtx.ChecksumCache.checksum(resource.ResolvedURIOrPath(), localInfo, offset, payload)
+			offset = offset + int64(readBytes)
+			contents := &proto.ResourceChunk_ResourceContents{
+				Chunk:    payload,
+				Checksum: checksum,
+				Id:       resourceUUID,
+			}
+			compressContents(contents, compression)
+			sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+				Payload: &proto.ResourceChunk_Chunk{
+					Chunk: contents,
 				},
 			})
 			if sendErr != nil {
 				// TODO: requires server abort
-				impl.logger.Error("Failed sending header", "reason", sendErr)
-				return sendErr
-			}
-
-			// by using this safe value, we leave space for other fields of the payload
-			buffer := make([]byte, impl.serviceConfig.SafeClientMaxRecvMsgSize())
-
-			for {
-				readBytes, err := reader.Read(buffer)
-				if readBytes == 0 && err == io.EOF {
-					sendErr := stream.Send(&proto.ResourceChunk{
-						Payload: &proto.ResourceChunk_Eof{
-							Eof: &proto.ResourceChunk_ResourceEof{
-								Id: resourceUUID,
-							},
-						},
-					})
-					if sendErr != nil {
-						// TODO: requires server abort
-						impl.logger.Error("Failed sending eof", "reason", sendErr)
-						return sendErr
-					}
-					break
-				} else {
-					payload := buffer[0:readBytes]
-					hash := sha256.Sum256(payload)
-					sendErr := stream.Send(&proto.ResourceChunk{
-						Payload: &proto.ResourceChunk_Chunk{
-							Chunk: &proto.ResourceChunk_ResourceContents{
-								Chunk:    payload,
-								Checksum: hash[:],
-								Id:       resourceUUID,
-							},
-						},
-					})
-					if sendErr != nil {
-						// TODO: requires server abort
-						impl.logger.Error("Failed sending chunk", "reason", sendErr)
-						return sendErr
-					}
-				}
+				impl.resourceLogger.Error("Failed sending chunk", "reason", sendErr)
+				return servedResources, servedBytes, sendErr
+			}
+			impl.countServed(0, readBytes)
+			servedBytes = servedBytes + int64(readBytes)
+		}
+		if err == io.EOF {
+			eof := &proto.ResourceChunk_ResourceEof{Id: resourceUUID}
+			if digest != nil {
+				eof.Digest = hex.EncodeToString(digest.Sum(nil))
+			}
+			sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+				Payload: &proto.ResourceChunk_Eof{
+					Eof: eof,
+				},
+			})
+			if sendErr != nil {
+				// TODO: requires server abort
+				impl.resourceLogger.Error("Failed sending eof", "reason", sendErr)
+				return servedResources, servedBytes, sendErr
 			}
+			break
 		}
+		if err != nil {
+			// the header and possibly some contents were sent, the client must not take the resource as complete:
+			impl.resourceLogger.Error("Failed reading resource", "reason", err)
+			return servedResources, servedBytes, withResourcePath(err, req.Path, req.Stage)
+		}
+	}
+	return servedResources, servedBytes, nil
+}
 
-	} else {
-		return fmt.Errorf("not found: '%s/%s'", req.Stage, req.Path)
+// sendSpooled streams a file resource from the spool of the work context, spooling the contents first if needed.
+func (impl *serverImpl) sendSpooled(req *proto.ResourceRequest, resource resources.ResolvedResource, reader io.ReadCloser,
+	header *proto.ResourceChunk_ResourceHeader, bufferSize int, stream proto.RootfsServer_ResourceServer) (int, int64, error) {
+	servedBytes := int64(0)
+
+	info, _ := localFileInfo(resource.ResolvedURIOrPath())
+	spooled, err := impl.serverCtx.Spool.spooled(resource.ResolvedURIOrPath(), info, func() (io.ReadCloser, error) {
+		return reader, nil
+	})
+	if err != nil {
+		return 0, servedBytes, withResourcePath(err, req.Path, req.Stage)
+	}
+	if req.Offset > spooled.size {
+		return 0, servedBytes, withResourcePath(fmt.Errorf("%w: offset %d beyond the end of the resource", ErrInvalidArgument, req.Offset), req.Path, req.Stage)
+	}
+	if req.PrefixDigest != "" {
+		if err := spooled.verifyPrefix(req.Offset, req.PrefixDigest); err != nil {
+			return 0, servedBytes, withResourcePath(err, req.Path, req.Stage)
+		}
+	}
+	if sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{Payload: &proto.ResourceChunk_Header{Header: header}}); sendErr != nil {
+		impl.resourceLogger.Error("Failed sending header", "reason", sendErr)
+		return 0, servedBytes, sendErr
+	}
+	impl.countServed(1, 0)
+
+	compression := impl.compressionThreshold(req.AcceptCompression)
+	// the chunks the spool stores compressed are sent as stored:
+	acceptGzip := containsString(req.AcceptCompression, CompressionGzip)
+	if err := spooled.eachContents(req.Offset, bufferSize, acceptGzip, func(contents *proto.ResourceChunk_ResourceContents) error {
+		contents.Id = header.Id
+		compressContents(contents, compression)
+		if sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+			Payload: &proto.ResourceChunk_Chunk{
+				Chunk: contents,
+			},
+		}); sendErr != nil {
+			impl.resourceLogger.Error("Failed sending chunk", "reason", sendErr)
+			return sendErr
+		}
+		impl.countServed(0, contentsSize(contents))
+		servedBytes = servedBytes + int64(contentsSize(contents))
+		return nil
+	}); err != nil {
+		return 1, servedBytes, err
+	}
+
+	eof := &proto.ResourceChunk_ResourceEof{Id: header.Id}
+	if req.ContentsDigest || req.PrefixDigest != "" {
+		eof.Digest = spooled.digest
+	}
+	if sendErr := impl.sendFrame(req.Path, stream, &proto.ResourceChunk{
+		Payload: &proto.ResourceChunk_Eof{
+			Eof: eof,
+		},
+	}); sendErr != nil {
+		impl.resourceLogger.Error("Failed sending eof", "reason", sendErr)
+		return 1, servedBytes, sendErr
+	}
+	return 1, servedBytes, nil
+}
+
+// fileResourceHeader creates the header of a file resource.
+func fileResourceHeader(resource resources.ResolvedResource, id string) (*proto.ResourceChunk_ResourceHeader, error) {
+	sourcePath, targetPath, escaped, err := encodeHeaderPaths(resource.SourcePath(), resource.TargetPath())
+	if err != nil {
+		return nil, fmt.Errorf("resource '%s' not streamable: %v", resource.SourcePath(), err)
+	}
+	return &proto.ResourceChunk_ResourceHeader{
+		SourcePath:    sourcePath,
+		TargetPath:    targetPath,
+		FileMode:      int64(resource.TargetMode()),
+		IsDir:         resource.IsDir(),
+		TargetUser:    encodeHeaderUser(resource.TargetUser()),
+		TargetWorkdir: resource.TargetWorkdir().Value,
+		Id:            id,
+		Platform:      resources.PlatformOf(resource),
+		EscapedPaths:  escaped,
+	}, nil
+}
+
+// skipVerifiedPrefix advances the contents reader by the offset. With a digest, the skipped prefix is written
+// to the digest and, with a prefix digest, verified against the hex encoded prefix digest. A prefix which
+// does not match is ErrInvalidArgument, the client has a prefix of different contents.
+func skipVerifiedPrefix(reader io.Reader, offset int64, prefixDigest string, digest hash.Hash) error {
+	if digest == nil {
+		return skipContents(reader, offset)
+	}
+	skipped, err := io.CopyN(digest, reader, offset)
+	if err == io.EOF {
+		return fmt.Errorf("%w: offset %d beyond the end of the resource of %d bytes", ErrInvalidArgument, offset, skipped)
+	}
+	if err != nil {
+		return err
+	}
+	if prefixDigest != "" && hex.EncodeToString(digest.Sum(nil)) != prefixDigest {
+		return fmt.Errorf("%w: prefix of %d bytes does not match the resource", ErrInvalidArgument, offset)
 	}
 	return nil
 }
 
+// skipContents advances the contents reader by the offset, seeking where the reader supports it.
+func skipContents(reader io.Reader, offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if offset > end {
+			return fmt.Errorf("%w: offset %d beyond the end of the resource", ErrInvalidArgument, offset)
+		}
+		_, err = seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+	skipped, err := io.CopyN(ioutil.Discard, reader, offset)
+	if err == io.EOF {
+		return fmt.Errorf("%w: offset %d beyond the end of the resource of %d bytes", ErrInvalidArgument, offset, skipped)
+	}
+	return err
+}
+
+// drainWalk consumes the remaining chunks of an abandoned directory walk so the walker can finish.
+func drainWalk(outputChannel chan *proto.ResourceChunk, tracer *frameTracer) {
+	for {
+		payload := <-outputChannel
+		if payload == nil {
+			return
+		}
+		tracer.forget(payload)
+	}
+}
+
+// sendFrame sends a frame of a resource stream produced by the sending routine, the frame is sampled for tracing.
+func (impl *serverImpl) sendFrame(resourcePath string, stream proto.RootfsServer_ResourceServer, frame *proto.ResourceChunk) error {
+	impl.frameTracer.queue(frame)
+	return impl.frameTracer.send(resourcePath, frame, stream.Send)
+}
+
+// lookupResources returns the resources resolved under the path. If there are no resources
+// under the exact path, the path may address an entry within a resolved directory resource.
+func (impl *serverImpl) lookupResources(path string) ([]resources.ResolvedResource, bool) {
+	if ress, ok := impl.serverCtx.ResourcesResolved[path]; ok {
+		return ress, true
+	}
+	longestPrefix := ""
+	for key := range impl.serverCtx.ResourcesResolved {
+		prefix := strings.TrimSuffix(key, "/")
+		if strings.HasPrefix(path, prefix+"/") && len(prefix) > len(longestPrefix) {
+			longestPrefix = key
+		}
+	}
+	if longestPrefix == "" {
+		return nil, false
+	}
+	relativePath := strings.TrimPrefix(path, strings.TrimSuffix(longestPrefix, "/")+"/")
+	subResources := []resources.ResolvedResource{}
+	for _, resource := range impl.serverCtx.ResourcesResolved[longestPrefix] {
+		if !resource.IsDir() {
+			continue
+		}
+		subResource, err := resources.NewSubResource(resource, relativePath)
+		if err != nil {
+			impl.resourceLogger.Debug("sub resource not resolved", "path", path, "reason", err)
+			continue
+		}
+		subResources = append(subResources, subResource)
+	}
+	return subResources, len(subResources) > 0
+}
+
 func (impl *serverImpl) StdErr(ctx context.Context, req *proto.LogMessage) (*proto.Empty, error) {
 	// handle stopped server
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.Empty{}, fmt.Errorf("stopped")
+		return &proto.Empty{}, ErrServerStopped
 	}
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ClientMsgStderr{Lines: req.Line}
+	lines := impl.limitLogLines(impl.serviceConfig.Redactor.redactLines(normalizeLogLines(impl.serviceConfig.LogMode, req.Line)))
+
+	impl.m.Lock()
+	impl.summary.StderrLines = impl.summary.StderrLines + len(req.Line)
+	impl.journalLocked()
+	impl.m.Unlock()
+
+	commandID := impl.runningCommandID()
+	impl.logs.publish(LogStreamStderr, commandID, lines)
+	impl.emit(&ClientMsgStderr{Lines: lines, CommandID: commandID})
 	return &proto.Empty{}, nil
 }
 
@@ -216,14 +930,36 @@ func (impl *serverImpl) StdOut(ctx context.Context, req *proto.LogMessage) (*pro
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.Empty{}, fmt.Errorf("stopped")
+		return &proto.Empty{}, ErrServerStopped
 	}
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ClientMsgStdout{Lines: req.Line}
+	lines := impl.limitLogLines(impl.serviceConfig.Redactor.redactLines(normalizeLogLines(impl.serviceConfig.LogMode, req.Line)))
+
+	impl.m.Lock()
+	impl.summary.StdoutLines = impl.summary.StdoutLines + len(req.Line)
+	impl.journalLocked()
+	impl.m.Unlock()
+
+	commandID := impl.runningCommandID()
+	impl.logs.publish(LogStreamStdout, commandID, lines)
+	impl.emit(&ClientMsgStdout{Lines: lines, CommandID: commandID})
 	return &proto.Empty{}, nil
 }
 
+// limitLogLines truncates the lines to the maximum line length and counts the truncated lines.
+func (impl *serverImpl) limitLogLines(lines []string) []string {
+	result, truncatedLines, truncatedBytes := truncateLogLines(lines, impl.serviceConfig.MaxLogLineBytes)
+	if truncatedLines > 0 {
+		impl.m.Lock()
+		impl.summary.TruncatedLogLines = impl.summary.TruncatedLogLines + truncatedLines
+		impl.summary.TruncatedLogBytes = impl.summary.TruncatedLogBytes + truncatedBytes
+		impl.m.Unlock()
+		impl.logsLogger.Warn("log lines truncated", "lines", truncatedLines, "bytes", truncatedBytes)
+	}
+	return result
+}
+
 func (impl *serverImpl) Stop() {
 	impl.m.Lock()
 	if impl.stopped {
@@ -232,7 +968,22 @@ func (impl *serverImpl) Stop() {
 	}
 
 	impl.stopped = true
+	for index, timer := range impl.commandTimers {
+		timer.Stop()
+		delete(impl.commandTimers, index)
+	}
+	impl.journalLocked()
+	close(impl.chanStopped)
 	impl.m.Unlock()
+
+	impl.logs.close()
+	impl.events.close()
+}
+
+// SubscribeLogs returns a channel receiving the stdout and stderr lines sent by the client
+// and a function ending the subscription. The channel is closed when the server stops.
+func (impl *serverImpl) SubscribeLogs() (<-chan LogLine, func()) {
+	return impl.logs.subscribe()
 }
 
 func (impl *serverImpl) Success(ctx context.Context, _ *proto.Empty) (*proto.Empty, error) {
@@ -240,14 +991,173 @@ func (impl *serverImpl) Success(ctx context.Context, _ *proto.Empty) (*proto.Emp
 	impl.m.Lock()
 	if impl.stopped {
 		defer impl.m.Unlock()
-		return &proto.Empty{}, fmt.Errorf("stopped")
+		return &proto.Empty{}, ErrServerStopped
+	}
+	if impl.completedLocked(ctx) {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, ErrAlreadyCompleted
+	}
+	if impl.broadcasting() {
+		guestID := guestIDFromContext(ctx)
+		finished, buildErr := impl.guestFinished(guestID, nil)
+		impl.m.Unlock()
+		impl.emit(&ClientMsgGuestFinished{GuestID: guestID})
+		if !finished {
+			return &proto.Empty{}, nil
+		}
+		if buildErr != nil {
+			impl.emit(&ClientMsgAborted{Error: buildErr})
+			impl.runHook("after-abort", impl.serverCtx.OnAfterAbort)
+			return &proto.Empty{}, nil
+		}
+		impl.emit(&ClientMsgSuccess{})
+		impl.runHook("after-success", impl.serverCtx.OnAfterSuccess)
+		return &proto.Empty{}, nil
+	}
+	impl.summary.FinishedAt = time.Now()
+	impl.summary.Success = true
+	impl.journalLocked()
+	impl.m.Unlock()
+
+	impl.emit(&ClientMsgSuccess{})
+	impl.runHook("after-success", impl.serverCtx.OnAfterSuccess)
+	return &proto.Empty{}, nil
+}
+
+func (impl *serverImpl) Stats() ServerStats {
+	used, streams := impl.chunkBudget.usage()
+	stats := ServerStats{
+		BufferedChunkBytes:      used,
+		BufferedChunkBytesLimit: impl.serviceConfig.MaxBufferedChunkBytes,
+		ActiveResourceStreams:   streams,
+		FileRetries:             atomic.LoadInt64(&impl.fileRetries.retried),
+		FileRetryFailures:       atomic.LoadInt64(&impl.fileRetries.failed),
+	}
+	stats.FrameTraces, stats.FramesTraced = impl.frameTracer.snapshot()
+	return stats
+}
+
+func (impl *serverImpl) Summary() BuildSummary {
+	impl.m.Lock()
+	defer impl.m.Unlock()
+	summary := impl.summary
+	if impl.summary.Guests != nil {
+		summary.Guests = map[string]GuestSummary{}
+		for guestID, guest := range impl.summary.Guests {
+			summary.Guests[guestID] = guest
+		}
+	}
+	return summary
+}
+
+// completedLocked returns true if the client calling the RPC has finished with Success or Abort,
+// the guest of the client for a broadcast build. The lock must be held.
+func (impl *serverImpl) completedLocked(ctx context.Context) bool {
+	if impl.broadcasting() {
+		return !impl.summary.Guests[guestIDFromContext(ctx)].FinishedAt.IsZero()
+	}
+	return !impl.summary.FinishedAt.IsZero()
+}
+
+func (impl *serverImpl) countServed(resources int, bytes int) {
+	impl.m.Lock()
+	defer impl.m.Unlock()
+	impl.summary.ResourcesServed = impl.summary.ResourcesServed + resources
+	impl.summary.BytesServed = impl.summary.BytesServed + int64(bytes)
+	if resources > 0 || impl.summary.BytesServed-impl.journaledBytes >= journalBytesInterval {
+		impl.journalLocked()
+	}
+}
+
+func (impl *serverImpl) runHook(name string, hook BuildHook) {
+	if hook == nil {
+		return
+	}
+	if err := hook(impl.Summary()); err != nil {
+		impl.logger.Error("build hook failed", "hook", name, "reason", err)
+	}
+}
+
+func (impl *serverImpl) Warning(ctx context.Context, req *proto.WarningMessage) (*proto.Empty, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, ErrServerStopped
 	}
 	impl.m.Unlock()
 
-	impl.chanMessages <- &ClientMsgSuccess{}
+	impl.emit(&ClientMsgWarning{Path: req.Path, Warnings: req.Warning})
 	return &proto.Empty{}, nil
 }
 
+func (impl *serverImpl) Watch(_ *proto.Empty, stream proto.RootfsServer_WatchServer) error {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	select {
+	case <-impl.chanCancel:
+		impl.m.Lock()
+		reason := impl.cancelReason
+		impl.m.Unlock()
+		return stream.Send(&proto.WatchEvent{
+			Payload: &proto.WatchEvent_Cancel_{
+				Cancel: &proto.WatchEvent_Cancel{Reason: reason.Error()},
+			},
+		})
+	case <-impl.chanStopped:
+		return nil
+	case <-stream.Context().Done():
+		return nil
+	}
+}
+
+func (impl *serverImpl) WatchLogs(_ *proto.Empty, stream proto.RootfsServer_WatchLogsServer) error {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	chanLines, unsubscribe := impl.logs.subscribe()
+	defer unsubscribe()
+
+	// the header tells the client that the subscription is in place:
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case line, ok := <-chanLines:
+			if !ok {
+				return nil
+			}
+			protoStream := proto.LogLine_STDOUT
+			if line.Stream == LogStreamStderr {
+				protoStream = proto.LogLine_STDERR
+			}
+			if err := stream.Send(&proto.LogLine{
+				Stream:    protoStream,
+				Line:      line.Line,
+				Timestamp: line.Time.UnixNano(),
+				CommandId: line.CommandID,
+			}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
 func (impl *serverImpl) OnMessage() <-chan interface{} {
 	return impl.chanMessages
 }
@@ -0,0 +1,181 @@
+package rootfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// journalBytesInterval is the number of served resource bytes between journal updates.
+const journalBytesInterval = 1024 * 1024
+
+// serverJournal is the session state the server journals to GRPCServiceConfig.JournalPath,
+// so that a server started after the host process restarted can resume the session.
+type serverJournal struct {
+	BuildID           string `json:"buildId"`
+	CommandsCount     int    `json:"commandsCount"`
+	CommandsRequested bool   `json:"commandsRequested"`
+	// CommandAcks contains the phase of the last acknowledgement by command index.
+	CommandAcks     map[int]proto.CommandAck_Phase `json:"commandAcks"`
+	CommandRetries  map[int]int                    `json:"commandRetries"`
+	LastAckedIndex  int                            `json:"lastAckedIndex"`
+	StartedAt       time.Time                      `json:"startedAt"`
+	FinishedAt      time.Time                      `json:"finishedAt"`
+	ResourcesServed int                            `json:"resourcesServed"`
+	BytesServed     int64                          `json:"bytesServed"`
+	// StderrLines and StdoutLines are the offsets of the logs received from the client.
+	StderrLines int    `json:"stderrLines"`
+	StdoutLines int    `json:"stdoutLines"`
+	Success     bool   `json:"success"`
+	Aborted     bool   `json:"aborted"`
+	Error       string `json:"error,omitempty"`
+}
+
+// loadServerJournal reads the journal at the path, nil if there is no path or no journal.
+func loadServerJournal(journalPath string) (*serverJournal, error) {
+	if journalPath == "" {
+		return nil, nil
+	}
+	journalBytes, err := ioutil.ReadFile(journalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading journal '%s': %v", journalPath, err)
+	}
+	journal := &serverJournal{}
+	if err := json.Unmarshal(journalBytes, journal); err != nil {
+		return nil, fmt.Errorf("failed parsing journal '%s': %v", journalPath, err)
+	}
+	return journal, nil
+}
+
+// resumeBuildID takes the build ID of the journal for a work context without a build ID,
+// so that the reconnecting client is accepted by the session.
+func resumeBuildID(serverCtx *WorkContext, journalPath string) error {
+	journal, err := loadServerJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	if journal != nil && serverCtx.BuildID == "" {
+		serverCtx.BuildID = journal.BuildID
+	}
+	return nil
+}
+
+// restoreJournal restores the session state from the journal of the same build with the same number of commands.
+// A journal of another build is replaced by the journal of this build.
+// The commands running when the journal was written are not restored, the client acknowledges them again.
+func (impl *serverImpl) restoreJournal() {
+	journal, err := loadServerJournal(impl.serviceConfig.JournalPath)
+	if err != nil {
+		impl.logger.Warn("journal not restored", "reason", err)
+		return
+	}
+	if journal == nil {
+		return
+	}
+	if journal.BuildID != impl.serverCtx.BuildID || journal.CommandsCount != len(impl.serverCtx.ExecutableCommands) {
+		impl.logger.Warn("journal of another build not restored", "journal-build-id", journal.BuildID, "journal-commands", journal.CommandsCount)
+		return
+	}
+
+	impl.commandsRequested = journal.CommandsRequested
+	impl.lastAckedIndex = journal.LastAckedIndex
+	for index, phase := range journal.CommandAcks {
+		impl.commandAcks[index] = phase
+	}
+	for index, retries := range journal.CommandRetries {
+		impl.commandRetries[index] = retries
+	}
+	impl.aborted = journal.Aborted
+	impl.journaledBytes = journal.BytesServed
+
+	impl.summary.StartedAt = journal.StartedAt
+	impl.summary.FinishedAt = journal.FinishedAt
+	impl.summary.ResourcesServed = journal.ResourcesServed
+	impl.summary.BytesServed = journal.BytesServed
+	impl.summary.StderrLines = journal.StderrLines
+	impl.summary.StdoutLines = journal.StdoutLines
+	impl.summary.Success = journal.Success
+	if journal.Error != "" {
+		impl.summary.Error = errors.New(journal.Error)
+	}
+	for _, phase := range journal.CommandAcks {
+		switch phase {
+		case proto.CommandAck_FINISHED:
+			impl.summary.CommandsFinished = impl.summary.CommandsFinished + 1
+		case proto.CommandAck_SKIPPED:
+			impl.summary.CommandsSkipped = impl.summary.CommandsSkipped + 1
+		}
+	}
+	for _, retries := range journal.CommandRetries {
+		impl.summary.CommandRetries = impl.summary.CommandRetries + retries
+	}
+	impl.summary.Resumed = true
+	impl.logger.Info("session resumed from journal", "path", impl.serviceConfig.JournalPath,
+		"commands-finished", impl.summary.CommandsFinished, "last-acked-index", impl.lastAckedIndex)
+}
+
+// journalLocked writes the session state to the journal, the lock must be held.
+// The journal is replaced atomically, a failed write is logged and the build continues.
+func (impl *serverImpl) journalLocked() {
+	journalPath := impl.serviceConfig.JournalPath
+	if journalPath == "" || impl.broadcasting() {
+		return
+	}
+	journal := &serverJournal{
+		BuildID:           impl.serverCtx.BuildID,
+		CommandsCount:     len(impl.serverCtx.ExecutableCommands),
+		CommandsRequested: impl.commandsRequested,
+		CommandAcks:       impl.commandAcks,
+		CommandRetries:    impl.commandRetries,
+		LastAckedIndex:    impl.lastAckedIndex,
+		StartedAt:         impl.summary.StartedAt,
+		FinishedAt:        impl.summary.FinishedAt,
+		ResourcesServed:   impl.summary.ResourcesServed,
+		BytesServed:       impl.summary.BytesServed,
+		StderrLines:       impl.summary.StderrLines,
+		StdoutLines:       impl.summary.StdoutLines,
+		Success:           impl.summary.Success,
+		Aborted:           impl.aborted,
+	}
+	if impl.summary.Error != nil {
+		journal.Error = impl.summary.Error.Error()
+	}
+	impl.journaledBytes = impl.summary.BytesServed
+	if err := writeFileAtomic(journalPath, journal); err != nil {
+		impl.logger.Warn("failed writing journal", "path", journalPath, "reason", err)
+	}
+}
+
+// writeFileAtomic writes the value as JSON to a temporary file next to the path and renames it to the path.
+func writeFileAtomic(filePath string, value interface{}) error {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	tempFile, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(valueBytes); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), filePath)
+}
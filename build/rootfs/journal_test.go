@@ -0,0 +1,106 @@
+package rootfs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerJournalResume(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	journalPath := filepath.Join(t.TempDir(), "session.journal")
+	newWorkContext := func() *WorkContext {
+		return &WorkContext{
+			ExecutableCommands: []commands.VMInitSerializableCommand{
+				commands.RunWithDefaults("true"),
+				commands.RunWithDefaults("make"),
+			},
+			ResourcesResolved: make(Resources),
+		}
+	}
+	drain := func(srv ServerProvider) {
+		go func() {
+			for range srv.OnMessage() {
+			}
+		}()
+	}
+
+	firstCtx := newWorkContext()
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{JournalPath: journalPath}, firstCtx)
+	drain(srv)
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.StdOut([]string{"line 1", "line 2"}))
+	assert.Nil(t, testClient.CommandFinished(0, nil))
+	assert.Nil(t, testClient.CommandStarted(1))
+	assert.False(t, srv.Summary().Resumed)
+	// the host process goes away mid-build:
+	srv.Stop()
+
+	grpcConfig := &GRPCServiceConfig{JournalPath: journalPath}
+	secondCtx := newWorkContext()
+	srv, _ = mustStartServerAndClient(t, logger, grpcConfig, secondCtx)
+	defer srv.Stop()
+	drain(srv)
+
+	assert.Equal(t, firstCtx.BuildID, secondCtx.BuildID, "expected the build ID of the journal")
+	summary := srv.Summary()
+	assert.True(t, summary.Resumed)
+	assert.Equal(t, 1, summary.CommandsFinished)
+	assert.Equal(t, 2, summary.StdoutLines)
+
+	resumedClient, err := NewClient(logger.Named("grpc-client"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		BuildID:   firstCtx.BuildID,
+	})
+	assert.Nil(t, err)
+	status, err := resumedClient.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, BuildStateRunning, status.State)
+	assert.Equal(t, 1, status.LastAckedIndex)
+	assert.Equal(t, 1, status.CommandsRemaining())
+
+	assert.Nil(t, resumedClient.Commands())
+	assert.Nil(t, resumedClient.CommandStarted(1))
+	assert.Nil(t, resumedClient.CommandFinished(1, nil))
+	assert.Nil(t, resumedClient.Success())
+
+	journal, err := loadServerJournal(journalPath)
+	assert.Nil(t, err)
+	assert.True(t, journal.Success)
+	assert.Equal(t, 2, journal.CommandsCount)
+}
+
+func TestServerJournalOfAnotherBuild(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	journalPath := filepath.Join(t.TempDir(), "session.journal")
+	assert.Nil(t, ioutil.WriteFile(journalPath, []byte(`{"buildId":"other","commandsCount":5,"commandsRequested":true,"lastAckedIndex":4}`), 0644))
+
+	workContext := &WorkContext{
+		BuildID:            "this",
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+		ResourcesResolved:  make(Resources),
+	}
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{JournalPath: journalPath}, workContext)
+	defer srv.Stop()
+
+	assert.False(t, srv.Summary().Resumed)
+	status, err := testClient.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, BuildStatePending, status.State)
+	assert.Equal(t, -1, status.LastAckedIndex)
+
+	assert.Nil(t, ioutil.WriteFile(journalPath, []byte("not json"), 0644))
+	failing := New(&GRPCServiceConfig{JournalPath: journalPath, BindHostPort: "127.0.0.1:0"}, logger)
+	failing.Start(&WorkContext{ResourcesResolved: make(Resources)})
+	assert.NotNil(t, <-failing.FailedNotify(), "expected a corrupt journal to fail the start")
+}
@@ -0,0 +1,174 @@
+package rootfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ServerReady marks the server ready to serve client requests.
+// The server does not emit it via OnMessage, it is encoded by the embedding application
+// when ReadyNotify is closed.
+type ServerReady struct {
+	BindHostPort string
+}
+
+// JSONLinesEncoder writes server events as JSON lines, one JSON object per line.
+// Every object has the time and the type of the event, remaining properties depend on the type.
+// Events with multiple log lines are written as one object per log line.
+type JSONLinesEncoder struct {
+	m   sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewJSONLinesEncoder returns an encoder writing to the writer.
+func NewJSONLinesEncoder(w io.Writer) *JSONLinesEncoder {
+	return &JSONLinesEncoder{w: w, now: time.Now}
+}
+
+// Encode writes an event as JSON lines. Accepts every event emitted via OnMessage,
+// LogLine received from log subscriptions and ServerReady. Returns an error for unknown events.
+func (e *JSONLinesEncoder) Encode(event interface{}) error {
+	records, err := e.records(event)
+	if err != nil {
+		return err
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	for _, record := range records {
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(append(recordBytes, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *JSONLinesEncoder) records(event interface{}) ([]map[string]interface{}, error) {
+	now := e.now()
+	record := func(eventType string, properties map[string]interface{}) map[string]interface{} {
+		properties["time"] = now
+		properties["type"] = eventType
+		return properties
+	}
+	logRecords := func(eventType string, lines []string) []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, line := range lines {
+			result = append(result, record(eventType, map[string]interface{}{"line": line}))
+		}
+		return result
+	}
+
+	switch tevent := event.(type) {
+	case *ServerReady:
+		return []map[string]interface{}{record("ready", map[string]interface{}{"bindHostPort": tevent.BindHostPort})}, nil
+	case LogLine:
+		return []map[string]interface{}{{"time": tevent.Time, "type": tevent.Stream.String(), "line": tevent.Line}}, nil
+	case *ClientMsgAborted:
+		return []map[string]interface{}{record("aborted", map[string]interface{}{"error": errorString(tevent.Error)})}, nil
+	case *ClientMsgCommandFinished:
+		properties := map[string]interface{}{"index": tevent.Index}
+		if tevent.Error != nil {
+			properties["error"] = tevent.Error.Error()
+		}
+		return []map[string]interface{}{record("command-finished", properties)}, nil
+	case *ClientMsgCommandRetried:
+		return []map[string]interface{}{record("command-retried", map[string]interface{}{
+			"index":   tevent.Index,
+			"attempt": tevent.Attempt,
+			"error":   errorString(tevent.Error),
+		})}, nil
+	case *ClientMsgCommandSkipped:
+		return []map[string]interface{}{record("command-skipped", map[string]interface{}{"index": tevent.Index})}, nil
+	case *ClientMsgCommandStarted:
+		return []map[string]interface{}{record("command-started", map[string]interface{}{"index": tevent.Index})}, nil
+	case *ClientMsgDebugSession:
+		return []map[string]interface{}{record("debug-session", map[string]interface{}{})}, nil
+	case *ClientMsgGuestFinished:
+		return []map[string]interface{}{record("guest-finished", map[string]interface{}{"guestId": tevent.GuestID, "error": errorString(tevent.Error)})}, nil
+	case *ClientMsgLogsFlushed:
+		return []map[string]interface{}{record("logs-flushed", map[string]interface{}{
+			"stdoutLines": tevent.StdoutLines,
+			"stderrLines": tevent.StderrLines,
+			"complete":    tevent.Complete,
+		})}, nil
+	case *ClientMsgProgress:
+		return []map[string]interface{}{record("progress", map[string]interface{}{
+			"guestId":        tevent.GuestID,
+			"path":           tevent.Path,
+			"bytesWritten":   tevent.BytesWritten,
+			"bytesTotal":     tevent.BytesTotal,
+			"bytesPerSecond": tevent.BytesPerSecond,
+			"eta":            tevent.ETA.String(),
+			"done":           tevent.Done,
+		})}, nil
+	case *ClientMsgResumed:
+		return []map[string]interface{}{record("resumed", map[string]interface{}{
+			"nextIndex":       tevent.NextIndex,
+			"resourceOffsets": tevent.ResourceOffsets,
+		})}, nil
+	case *ClientMsgStderr:
+		return logRecords("stderr", tevent.Lines), nil
+	case *ClientMsgStdout:
+		return logRecords("stdout", tevent.Lines), nil
+	case *ClientMsgSuccess:
+		return []map[string]interface{}{record("success", map[string]interface{}{})}, nil
+	case *ClientMsgWarning:
+		result := []map[string]interface{}{}
+		for _, warning := range tevent.Warnings {
+			result = append(result, record("warning", map[string]interface{}{"path": tevent.Path, "warning": warning}))
+		}
+		return result, nil
+	case *ControlMsgBuildTimeout:
+		return []map[string]interface{}{record("build-timeout", map[string]interface{}{"timeout": tevent.Timeout.String()})}, nil
+	case *ControlMsgCommandsRequested:
+		return []map[string]interface{}{record("commands-requested", map[string]interface{}{})}, nil
+	case *ControlMsgCommandTimeout:
+		return []map[string]interface{}{record("command-timeout", map[string]interface{}{
+			"index":   tevent.Index,
+			"timeout": tevent.Timeout.String(),
+		})}, nil
+	case *ControlMsgEnvironmentRequested:
+		return []map[string]interface{}{record("environment-requested", map[string]interface{}{})}, nil
+	case *ControlMsgPingSent:
+		return []map[string]interface{}{record("ping", map[string]interface{}{})}, nil
+	case *ControlMsgPortForwardClosed:
+		return []map[string]interface{}{record("port-forward-closed", map[string]interface{}{"id": tevent.ID})}, nil
+	case *ControlMsgPortForwardRequested:
+		return []map[string]interface{}{record("port-forward-requested", map[string]interface{}{
+			"id":          tevent.ID,
+			"protocol":    tevent.Protocol,
+			"hostAddress": tevent.HostAddress,
+			"guestPort":   tevent.GuestPort,
+		})}, nil
+	case *ControlMsgResourceEntrySkipped:
+		return []map[string]interface{}{record("resource-entry-skipped", map[string]interface{}{
+			"path":     tevent.Path,
+			"stage":    tevent.Stage,
+			"filePath": tevent.FilePath,
+			"error":    errorString(tevent.Reason),
+		})}, nil
+	case *ControlMsgResourceServed:
+		return []map[string]interface{}{record("resource-served", map[string]interface{}{
+			"path":      tevent.Path,
+			"stage":     tevent.Stage,
+			"resources": tevent.Resources,
+			"bytes":     tevent.Bytes,
+		})}, nil
+	default:
+		return nil, fmt.Errorf("unknown event type %T", event)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,53 @@
+package rootfs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLinesEncoder(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+	encoder := NewJSONLinesEncoder(buffer)
+	encoder.now = func() time.Time {
+		return time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	for _, event := range []interface{}{
+		&ServerReady{BindHostPort: "127.0.0.1:1234"},
+		&ClientMsgCommandStarted{Index: 0},
+		&ClientMsgStdout{Lines: []string{"line 1", "line 2"}},
+		&ControlMsgResourceServed{Path: "etc/file", Resources: 1, Bytes: 10},
+		&ControlMsgResourceEntrySkipped{Path: "etc", FilePath: "/src/etc/secret", Reason: fmt.Errorf("permission denied")},
+		&ClientMsgCommandRetried{Index: 0, Attempt: 1, Error: fmt.Errorf("exit status 100")},
+		&ClientMsgCommandFinished{Index: 0, Error: fmt.Errorf("exit status 1")},
+		&ClientMsgCommandSkipped{Index: 1},
+		&ClientMsgResumed{NextIndex: 2, ResourceOffsets: map[string]int64{"etc/file": 4}},
+		&ClientMsgLogsFlushed{StdoutLines: 2, StderrLines: 1, Complete: true},
+		&ClientMsgProgress{Path: "etc", BytesWritten: 512, BytesTotal: 1024, BytesPerSecond: 256, ETA: 2 * time.Second},
+		&ClientMsgAborted{Error: fmt.Errorf("failed")},
+	} {
+		assert.Nil(t, encoder.Encode(event))
+	}
+	assert.NotNil(t, encoder.Encode(struct{}{}), "expected unknown event to fail")
+
+	assert.Equal(t, []string{
+		`{"bindHostPort":"127.0.0.1:1234","time":"2021-04-01T12:00:00Z","type":"ready"}`,
+		`{"index":0,"time":"2021-04-01T12:00:00Z","type":"command-started"}`,
+		`{"line":"line 1","time":"2021-04-01T12:00:00Z","type":"stdout"}`,
+		`{"line":"line 2","time":"2021-04-01T12:00:00Z","type":"stdout"}`,
+		`{"bytes":10,"path":"etc/file","resources":1,"stage":"","time":"2021-04-01T12:00:00Z","type":"resource-served"}`,
+		`{"error":"permission denied","filePath":"/src/etc/secret","path":"etc","stage":"","time":"2021-04-01T12:00:00Z","type":"resource-entry-skipped"}`,
+		`{"attempt":1,"error":"exit status 100","index":0,"time":"2021-04-01T12:00:00Z","type":"command-retried"}`,
+		`{"error":"exit status 1","index":0,"time":"2021-04-01T12:00:00Z","type":"command-finished"}`,
+		`{"index":1,"time":"2021-04-01T12:00:00Z","type":"command-skipped"}`,
+		`{"nextIndex":2,"resourceOffsets":{"etc/file":4},"time":"2021-04-01T12:00:00Z","type":"resumed"}`,
+		`{"complete":true,"stderrLines":1,"stdoutLines":2,"time":"2021-04-01T12:00:00Z","type":"logs-flushed"}`,
+		`{"bytesPerSecond":256,"bytesTotal":1024,"bytesWritten":512,"done":false,"eta":"2s","guestId":"","path":"etc","time":"2021-04-01T12:00:00Z","type":"progress"}`,
+		`{"error":"failed","time":"2021-04-01T12:00:00Z","type":"aborted"}`,
+	}, strings.Split(strings.TrimSpace(buffer.String()), "\n"))
+}
@@ -0,0 +1,142 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/pkg/errors"
+)
+
+// DefaultLazyFetchLookahead is the default number of commands following the executed command
+// whose resources are fetched in the background.
+const DefaultLazyFetchLookahead = 2
+
+// LazyFetchOptions configures fetching the resources on demand.
+type LazyFetchOptions struct {
+	// Concurrency is the number of resources fetched at the same time, DefaultFetchConcurrency when zero.
+	Concurrency int
+	// Lookahead is the number of commands following the executed command whose resources are fetched
+	// in the background, DefaultLazyFetchLookahead when zero. A negative value disables the background fetching.
+	Lookahead int
+	// WriteOptions configures writing every fetched resource.
+	WriteOptions *WriteOptions
+}
+
+// WithDefaultsApplied returns the options with the defaults applied to unset values.
+func (opts *LazyFetchOptions) WithDefaultsApplied() *LazyFetchOptions {
+	result := &LazyFetchOptions{}
+	if opts != nil {
+		*result = *opts
+	}
+	if result.Concurrency <= 0 {
+		result.Concurrency = DefaultFetchConcurrency
+	}
+	if result.Lookahead == 0 {
+		result.Lookahead = DefaultLazyFetchLookahead
+	} else if result.Lookahead < 0 {
+		result.Lookahead = 0
+	}
+	return result
+}
+
+// LazyFetcher fetches the resources of the commands just before the commands needing them execute.
+// While a command executes, the resources of the following commands are fetched in the background
+// so the transfer overlaps with the execution instead of preceding the whole build.
+type LazyFetcher struct {
+	client   ClientProvider
+	commands []commands.VMInitSerializableCommand
+	opts     *LazyFetchOptions
+	rootDir  string
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	chanSlots  chan struct{}
+	fetches    map[string]*lazyFetch
+	m          sync.Mutex
+	wg         sync.WaitGroup
+}
+
+type lazyFetch struct {
+	chanDone chan struct{}
+	err      error
+}
+
+// NewLazyFetcher creates a lazy fetcher writing the resources of the commands under the root directory.
+// The commands are the commands in execution order, the index of a command is its index in the commands.
+func NewLazyFetcher(client ClientProvider, cmds []commands.VMInitSerializableCommand, rootDir string, opts *LazyFetchOptions) *LazyFetcher {
+	opts = opts.WithDefaultsApplied()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	return &LazyFetcher{
+		client:     client,
+		commands:   cmds,
+		opts:       opts,
+		rootDir:    rootDir,
+		ctx:        ctx,
+		cancelFunc: cancelFunc,
+		chanSlots:  make(chan struct{}, opts.Concurrency),
+		fetches:    map[string]*lazyFetch{},
+	}
+}
+
+// BeforeCommand blocks until the resources the command at the index needs are written
+// and starts fetching the resources of the following commands in the background.
+// Returns the error of the first failed fetch of a needed resource.
+func (f *LazyFetcher) BeforeCommand(ctx context.Context, index int) error {
+	if index < 0 || index >= len(f.commands) {
+		return errors.Wrapf(ErrInvalidArgument, "command index %d out of range", index)
+	}
+	needed := []*lazyFetch{}
+	for _, resourcePath := range neededResources(f.commands[index]) {
+		needed = append(needed, f.fetch(resourcePath))
+	}
+	for ahead := index + 1; ahead <= index+f.opts.Lookahead && ahead < len(f.commands); ahead++ {
+		for _, resourcePath := range neededResources(f.commands[ahead]) {
+			f.fetch(resourcePath)
+		}
+	}
+	for _, fetch := range needed {
+		select {
+		case <-fetch.chanDone:
+			if fetch.err != nil {
+				return fetch.err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close cancels the fetches in progress and waits for them to finish.
+func (f *LazyFetcher) Close() {
+	f.cancelFunc()
+	f.wg.Wait()
+}
+
+// fetch starts fetching the resource unless the resource was already requested.
+func (f *LazyFetcher) fetch(resourcePath string) *lazyFetch {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if fetch, ok := f.fetches[resourcePath]; ok {
+		return fetch
+	}
+	fetch := &lazyFetch{chanDone: make(chan struct{})}
+	f.fetches[resourcePath] = fetch
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer close(fetch.chanDone)
+		select {
+		case f.chanSlots <- struct{}{}:
+		case <-f.ctx.Done():
+			fetch.err = f.ctx.Err()
+			return
+		}
+		defer func() { <-f.chanSlots }()
+		if err := f.client.WriteResources(f.ctx, resourcePath, f.rootDir, f.opts.WriteOptions); err != nil {
+			fetch.err = errors.Wrapf(err, "failed fetching resource '%s'", resourcePath)
+		}
+	}()
+	return fetch
+}
@@ -0,0 +1,157 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazyFetcherFetchesBeforeCommand(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "a.txt"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(tempDir, "bin/app"), []byte("app"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Run{
+			OriginalCommand: "RUN true",
+			Command:         "true",
+		},
+		commands.Copy{
+			OriginalCommand: "COPY a.txt /app/a.txt",
+			OriginalSource:  "a.txt",
+			Source:          "a.txt",
+			Target:          "/app/a.txt",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Run{
+			OriginalCommand: "RUN /app/bin/app",
+			Command:         "/app/bin/app",
+			Needs:           []string{"bin"},
+		},
+		commands.Copy{
+			OriginalCommand: "COPY bin /app/bin",
+			OriginalSource:  "bin",
+			Source:          "bin",
+			Target:          "/app/bin",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+	resolved, err := ResolveFromDirectory(tempDir, executableCommands)
+	assert.Nil(t, err)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: executableCommands,
+		ResourcesResolved:  resolved,
+	})
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Commands())
+
+	rootDir := filepath.Join(tempDir, "root")
+	fetcher := NewLazyFetcher(testClient, executableCommands, rootDir, &LazyFetchOptions{Lookahead: -1})
+	defer fetcher.Close()
+
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 0))
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 1))
+	contents, err := ioutil.ReadFile(filepath.Join(rootDir, "app/a.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a", string(contents))
+	// without the lookahead, the binary is not fetched before the command needing it:
+	_, err = os.Stat(filepath.Join(rootDir, "app/bin"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 2))
+	contents, err = ioutil.ReadFile(filepath.Join(rootDir, "app/bin/app"))
+	assert.Nil(t, err)
+	assert.Equal(t, "app", string(contents))
+	// already fetched:
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 3))
+
+	assert.True(t, errors.Is(fetcher.BeforeCommand(context.Background(), 4), ErrInvalidArgument))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestLazyFetcherFetchesAhead(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "a.txt"), []byte("a"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Run{
+			OriginalCommand: "RUN true",
+			Command:         "true",
+		},
+		commands.Copy{
+			OriginalCommand: "COPY a.txt /app/a.txt",
+			OriginalSource:  "a.txt",
+			Source:          "a.txt",
+			Target:          "/app/a.txt",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Copy{
+			OriginalCommand: "COPY missing /app/missing",
+			OriginalSource:  "missing",
+			Source:          "missing",
+			Target:          "/app/missing",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: executableCommands[0:2],
+		ResourcesResolved:  mustResolve(t, tempDir, executableCommands[0:2]),
+	})
+	defer cleanupFunc()
+
+	rootDir := filepath.Join(tempDir, "root")
+	fetcher := NewLazyFetcher(testClient, executableCommands, rootDir, nil)
+	defer fetcher.Close()
+
+	// the resource of the following command is fetched while the first command executes:
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 0))
+	assert.Nil(t, fetcher.fetch("a.txt").waitForTest())
+	contents, err := ioutil.ReadFile(filepath.Join(rootDir, "app/a.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a", string(contents))
+
+	assert.Nil(t, fetcher.BeforeCommand(context.Background(), 1))
+	assert.NotNil(t, fetcher.BeforeCommand(context.Background(), 2))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func (f *lazyFetch) waitForTest() error {
+	<-f.chanDone
+	return f.err
+}
+
+func mustResolve(t *testing.T, contextDir string, cmds []commands.VMInitSerializableCommand) Resources {
+	resolved, err := ResolveFromDirectory(contextDir, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
@@ -0,0 +1,98 @@
+package rootfs
+
+import "github.com/hashicorp/go-hclog"
+
+const (
+	// LogSubsystemServerLifecycle names the logger of the server start, stop, timeouts and hooks.
+	LogSubsystemServerLifecycle = "server.lifecycle"
+	// LogSubsystemServerResource names the logger of the server streaming the resources.
+	LogSubsystemServerResource = "server.resource"
+	// LogSubsystemServerLogs names the logger of the server receiving the stdout and stderr lines.
+	LogSubsystemServerLogs = "server.logs"
+	// LogSubsystemClientFetch names the logger of the client fetching and writing the resources.
+	LogSubsystemClientFetch = "client.fetch"
+)
+
+// LogLevels contains the levels of the loggers of the subsystems, keyed by the subsystem name,
+// for example LogSubsystemServerResource. A subsystem without a level logs at the level of the logger.
+// The level of the logger still applies: a subsystem can log less than the logger,
+// to log more the logger level must be lowered and the remaining subsystems raised.
+type LogLevels map[string]hclog.Level
+
+// logger returns the logger of the subsystem.
+func (l LogLevels) logger(logger hclog.Logger, subsystem string) hclog.Logger {
+	level, ok := l[subsystem]
+	if !ok || level == hclog.NoLevel {
+		return logger
+	}
+	return &leveledLogger{Logger: logger, level: level}
+}
+
+// leveledLogger drops the messages below its level before the underlying logger applies its own level.
+type leveledLogger struct {
+	hclog.Logger
+	level hclog.Level
+}
+
+func (l *leveledLogger) enabled(level hclog.Level) bool {
+	return level >= l.level
+}
+
+func (l *leveledLogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	if l.enabled(level) {
+		l.Logger.Log(level, msg, args...)
+	}
+}
+
+func (l *leveledLogger) Trace(msg string, args ...interface{}) {
+	if l.enabled(hclog.Trace) {
+		l.Logger.Trace(msg, args...)
+	}
+}
+
+func (l *leveledLogger) Debug(msg string, args ...interface{}) {
+	if l.enabled(hclog.Debug) {
+		l.Logger.Debug(msg, args...)
+	}
+}
+
+func (l *leveledLogger) Info(msg string, args ...interface{}) {
+	if l.enabled(hclog.Info) {
+		l.Logger.Info(msg, args...)
+	}
+}
+
+func (l *leveledLogger) Warn(msg string, args ...interface{}) {
+	if l.enabled(hclog.Warn) {
+		l.Logger.Warn(msg, args...)
+	}
+}
+
+func (l *leveledLogger) Error(msg string, args ...interface{}) {
+	if l.enabled(hclog.Error) {
+		l.Logger.Error(msg, args...)
+	}
+}
+
+func (l *leveledLogger) IsTrace() bool { return l.enabled(hclog.Trace) && l.Logger.IsTrace() }
+func (l *leveledLogger) IsDebug() bool { return l.enabled(hclog.Debug) && l.Logger.IsDebug() }
+func (l *leveledLogger) IsInfo() bool  { return l.enabled(hclog.Info) && l.Logger.IsInfo() }
+func (l *leveledLogger) IsWarn() bool  { return l.enabled(hclog.Warn) && l.Logger.IsWarn() }
+func (l *leveledLogger) IsError() bool { return l.enabled(hclog.Error) && l.Logger.IsError() }
+
+func (l *leveledLogger) With(args ...interface{}) hclog.Logger {
+	return &leveledLogger{Logger: l.Logger.With(args...), level: l.level}
+}
+
+func (l *leveledLogger) Named(name string) hclog.Logger {
+	return &leveledLogger{Logger: l.Logger.Named(name), level: l.level}
+}
+
+func (l *leveledLogger) ResetNamed(name string) hclog.Logger {
+	return &leveledLogger{Logger: l.Logger.ResetNamed(name), level: l.level}
+}
+
+// SetLevel changes the level of the subsystem, the level of the underlying logger is not changed.
+func (l *leveledLogger) SetLevel(level hclog.Level) {
+	l.level = level
+}
@@ -0,0 +1,74 @@
+package rootfs
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestLogLevels(t *testing.T) {
+	output := &syncBuffer{}
+	base := hclog.New(&hclog.LoggerOptions{Level: hclog.Debug, Output: output})
+	levels := LogLevels{LogSubsystemServerResource: hclog.Warn}
+
+	assert.Equal(t, base, levels.logger(base, LogSubsystemServerLogs))
+
+	resourceLogger := levels.logger(base.Named("resource"), LogSubsystemServerResource).With("build-id", "build")
+	resourceLogger.Debug("resource debug")
+	resourceLogger.Warn("resource warning")
+	assert.False(t, resourceLogger.IsDebug())
+	assert.True(t, resourceLogger.IsWarn())
+	base.Debug("base debug")
+
+	assert.NotContains(t, output.String(), "resource debug")
+	assert.Contains(t, output.String(), "resource warning")
+	assert.Contains(t, output.String(), "base debug")
+
+	resourceLogger.SetLevel(hclog.Debug)
+	resourceLogger.Debug("resource debug enabled")
+	assert.Contains(t, output.String(), "resource debug enabled")
+	assert.True(t, base.IsDebug())
+}
+
+func TestServerLogLevels(t *testing.T) {
+	output := &syncBuffer{}
+	logger := hclog.New(&hclog.LoggerOptions{Level: hclog.Debug, Output: output})
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{
+		LogLevels:       LogLevels{LogSubsystemServerLifecycle: hclog.Warn},
+		MaxLogLineBytes: 30,
+	}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	assert.Nil(t, testClient.StdOut([]string{"a line longer than the thirty bytes limit"}))
+	assert.NotContains(t, output.String(), "GRPC server running")
+	assert.Contains(t, output.String(), "grpc-impl.logs: log lines truncated")
+}
@@ -0,0 +1,73 @@
+package rootfs
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxLogLineBytes is the default maximum length of a stdout or stderr line, longer lines are truncated.
+const DefaultMaxLogLineBytes = 16 * 1024
+
+// logTruncationMarker returns the marker appended to a line truncated by the number of bytes.
+func logTruncationMarker(truncatedBytes int) string {
+	return fmt.Sprintf("... [%d bytes truncated]", truncatedBytes)
+}
+
+// truncateLogLine returns the line truncated to the maximum length, including the truncation marker,
+// and the number of bytes truncated.
+func truncateLogLine(line string, maxBytes int) (string, int) {
+	if maxBytes <= 0 || len(line) <= maxBytes {
+		return line, 0
+	}
+	return truncatedLogLine(line, len(line), maxBytes)
+}
+
+// truncatedLogLine returns the line of the total length, of which only the prefix is known,
+// truncated on a character boundary to the maximum length, including the truncation marker,
+// and the number of bytes truncated.
+func truncatedLogLine(prefix string, totalBytes, maxBytes int) (string, int) {
+	// the marker of the actual count is never longer than the marker of the total length:
+	cut := maxBytes - len(logTruncationMarker(totalBytes))
+	if cut > len(prefix) {
+		cut = len(prefix)
+	}
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && cut < len(prefix) && !utf8.RuneStart(prefix[cut]) {
+		cut = cut - 1
+	}
+	return prefix[:cut] + logTruncationMarker(totalBytes-cut), totalBytes - cut
+}
+
+// truncateLogLines returns the lines truncated to the maximum length,
+// the number of lines truncated and the number of bytes truncated.
+func truncateLogLines(lines []string, maxBytes int) ([]string, int, int64) {
+	result := make([]string, 0, len(lines))
+	truncatedLines, truncatedBytes := 0, int64(0)
+	for _, line := range lines {
+		truncatedLine, truncated := truncateLogLine(line, maxBytes)
+		if truncated > 0 {
+			truncatedLines = truncatedLines + 1
+			truncatedBytes = truncatedBytes + int64(truncated)
+		}
+		result = append(result, truncatedLine)
+	}
+	return result, truncatedLines, truncatedBytes
+}
+
+// batchLogLines splits the lines into batches not exceeding the maximum number of line bytes,
+// so a batch fits in a single message. A line longer than the maximum is sent in a batch of its own.
+func batchLogLines(lines []string, maxBytes int) [][]string {
+	batches := [][]string{}
+	batch, batchBytes := []string{}, 0
+	for _, line := range lines {
+		if len(batch) > 0 && batchBytes+len(line) > maxBytes {
+			batches = append(batches, batch)
+			batch, batchBytes = []string{}, 0
+		}
+		batch = append(batch, line)
+		batchBytes = batchBytes + len(line)
+	}
+	return append(batches, batch)
+}
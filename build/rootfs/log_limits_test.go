@@ -0,0 +1,106 @@
+package rootfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateLogLine(t *testing.T) {
+	line, truncated := truncateLogLine("short", 30)
+	assert.Equal(t, "short", line)
+	assert.Equal(t, 0, truncated)
+
+	line, truncated = truncateLogLine(strings.Repeat("x", 100), 30)
+	assert.Equal(t, "xxxxx... [95 bytes truncated]", line)
+	assert.Equal(t, 95, truncated)
+	assert.True(t, len(line) <= 30)
+
+	// truncation never splits a character:
+	line, _ = truncateLogLine("żółć żółć żółć żółć żółć żółć", 30)
+	assert.Equal(t, "żół... [47 bytes truncated]", line)
+
+	// a truncated line is not truncated again:
+	again, truncated := truncateLogLine(line, 30)
+	assert.Equal(t, line, again)
+	assert.Equal(t, 0, truncated)
+
+	line, truncated = truncateLogLine(strings.Repeat("x", 100), -1)
+	assert.Equal(t, 100, len(line))
+	assert.Equal(t, 0, truncated)
+}
+
+func TestBatchLogLines(t *testing.T) {
+	assert.Equal(t, [][]string{{"aaa", "bbb"}, {"cccccccc"}, {"d"}},
+		batchLogLines([]string{"aaa", "bbb", "cccccccc", "d"}, 6))
+	assert.Equal(t, [][]string{{}}, batchLogLines([]string{}, 6))
+}
+
+func TestServerTruncatesLogLines(t *testing.T) {
+	grpcConfig := &GRPCServiceConfig{MaxLogLineBytes: 64}
+	srv, _ := mustStartServerAndClient(t, hclog.Default(), grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanLines := make(chan []string, 10)
+	go func() {
+		for message := range srv.OnMessage() {
+			if stdout, ok := message.(*ClientMsgStdout); ok {
+				chanLines <- stdout.Lines
+			}
+		}
+	}()
+
+	// the client does not truncate, the server enforces its own limit:
+	testClient, err := NewClient(hclog.Default(), &GRPCClientConfig{
+		HostPort:        grpcConfig.BindHostPort,
+		TLSConfig:       grpcConfig.TLSConfigClient,
+		MaxLogLineBytes: -1,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, testClient.StdOut([]string{"short", strings.Repeat("x", 1024)}))
+	lines := <-chanLines
+	assert.Equal(t, "short", lines[0])
+	assert.True(t, len(lines[1]) <= 64)
+	assert.True(t, strings.HasSuffix(lines[1], "... [986 bytes truncated]"), "unexpected line: %s", lines[1])
+
+	summary := srv.Summary()
+	assert.Equal(t, 2, summary.StdoutLines)
+	assert.Equal(t, 1, summary.TruncatedLogLines)
+	assert.Equal(t, int64(986), summary.TruncatedLogBytes)
+}
+
+func TestClientSplitsLogLines(t *testing.T) {
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, hclog.Default(), grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanLines := make(chan []string, 10)
+	go func() {
+		for message := range srv.OnMessage() {
+			if stdout, ok := message.(*ClientMsgStdout); ok {
+				chanLines <- stdout.Lines
+			}
+		}
+	}()
+
+	testClient, err := NewClient(hclog.Default(), &GRPCClientConfig{
+		HostPort:       grpcConfig.BindHostPort,
+		TLSConfig:      grpcConfig.TLSConfigClient,
+		MaxRecvMsgSize: 1024,
+	})
+	assert.Nil(t, err)
+	line := strings.Repeat("x", 400)
+	assert.Nil(t, testClient.StdOut([]string{line, line, line, line, line}))
+	assert.Equal(t, []string{line, line}, <-chanLines)
+	assert.Equal(t, []string{line, line}, <-chanLines)
+	assert.Equal(t, []string{line}, <-chanLines)
+}
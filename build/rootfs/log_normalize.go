@@ -0,0 +1,42 @@
+package rootfs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LogMode defines how the stdout and stderr lines of the build are processed before they are stored.
+type LogMode int
+
+const (
+	// LogModeRaw keeps the lines as written by the build.
+	LogModeRaw LogMode = iota
+	// LogModeNormalized collapses a line rewritten with carriage returns, for example by a progress bar,
+	// to the final state of the line and strips ANSI escape sequences.
+	LogModeNormalized
+)
+
+// ansiEscape matches the CSI sequences, for example colors and cursor movements,
+// the OSC sequences, for example window titles, the character set designations and the remaining two character escape sequences.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]+[0-~]|\x1b[@-Z\\-_]`)
+
+// NormalizeLogLine returns the final state of a line rewritten with carriage returns without ANSI escape sequences.
+func NormalizeLogLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if index := strings.LastIndexByte(line, '\r'); index > -1 {
+		line = line[index+1:]
+	}
+	return ansiEscape.ReplaceAllString(line, "")
+}
+
+// normalizeLogLines returns the lines processed as defined by the mode.
+func normalizeLogLines(mode LogMode, lines []string) []string {
+	if mode != LogModeNormalized {
+		return lines
+	}
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, NormalizeLogLine(line))
+	}
+	return result
+}
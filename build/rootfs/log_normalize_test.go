@@ -0,0 +1,48 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLogLine(t *testing.T) {
+	for input, expected := range map[string]string{
+		"plain line":                           "plain line",
+		"10%\r50%\r100%":                       "100%",
+		"done\r":                               "done",
+		"\x1b[32mgreen\x1b[0m text":            "green text",
+		"\x1b[2K\r\x1b[1Gprogress [####] 100%": "progress [####] 100%",
+		"\x1b]0;title\x07output":               "output",
+		"\x1b(Bcharset":                        "charset",
+	} {
+		assert.Equal(t, expected, NormalizeLogLine(input), "input %q", input)
+	}
+}
+
+func TestServerNormalizesLogLines(t *testing.T) {
+	srv, testClient := mustStartServerAndClient(t, hclog.Default(), &GRPCServiceConfig{LogMode: LogModeNormalized}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanLines := make(chan []string, 2)
+	go func() {
+		for message := range srv.OnMessage() {
+			switch tmessage := message.(type) {
+			case *ClientMsgStdout:
+				chanLines <- tmessage.Lines
+			case *ClientMsgStderr:
+				chanLines <- tmessage.Lines
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.StdOut([]string{"\x1b[1mbold\x1b[0m", "1/3\r2/3\r3/3"}))
+	assert.Equal(t, []string{"bold", "3/3"}, <-chanLines)
+	assert.Nil(t, testClient.StdErr([]string{"\x1b[31merror\x1b[0m"}))
+	assert.Equal(t, []string{"error"}, <-chanLines)
+}
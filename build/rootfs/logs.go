@@ -0,0 +1,71 @@
+package rootfs
+
+import "time"
+
+// DefaultLogSubscriberBufferSize is the number of log lines buffered for every log subscriber.
+const DefaultLogSubscriberBufferSize = 1024
+
+// LogStream identifies the output stream a log line was written to.
+type LogStream int
+
+const (
+	// LogStreamStdout identifies the standard output.
+	LogStreamStdout LogStream = iota
+	// LogStreamStderr identifies the standard error.
+	LogStreamStderr
+)
+
+func (s LogStream) String() string {
+	switch s {
+	case LogStreamStdout:
+		return "stdout"
+	case LogStreamStderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// LogLine is a single line of the build output.
+type LogLine struct {
+	Stream LogStream
+	Line   string
+	Time   time.Time
+	// CommandID identifies the command the client was executing when it sent the line,
+	// empty if none or if the client was executing several commands of a parallel group.
+	CommandID string
+}
+
+// logBroadcaster delivers log lines to any number of subscribers.
+// Subscribers never block the publisher: when a subscriber buffer is full, lines are dropped for that subscriber.
+type logBroadcaster struct {
+	*broadcaster
+}
+
+func newLogBroadcaster() *logBroadcaster {
+	return &logBroadcaster{broadcaster: newBroadcaster()}
+}
+
+// subscribe returns a channel receiving published log lines and a function ending the subscription.
+// The channel is closed when the subscription ends or the broadcaster closes.
+func (b *logBroadcaster) subscribe() (<-chan LogLine, func()) {
+	chanLines := make(chan LogLine, DefaultLogSubscriberBufferSize)
+	return chanLines, b.broadcaster.subscribe(func(value interface{}, _ <-chan struct{}) bool {
+		// lines are only offered:
+		select {
+		case chanLines <- value.(LogLine):
+			return true
+		default:
+			return false
+		}
+	}, func() { close(chanLines) })
+}
+
+func (b *logBroadcaster) publish(stream LogStream, commandID string, lines []string) {
+	now := time.Now()
+	values := make([]interface{}, 0, len(lines))
+	for _, line := range lines {
+		values = append(values, LogLine{Stream: stream, Line: line, Time: now, CommandID: commandID})
+	}
+	b.offer(values...)
+}
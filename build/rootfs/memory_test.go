@@ -0,0 +1,153 @@
+package rootfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+// The peak memory test streams a large resource and is not part of the regular tests,
+// FIREBUILD_MEMORY_TEST_BYTES enables it with the size of the resource, run it with:
+//
+//   FIREBUILD_MEMORY_TEST_BYTES=268435456 go test -run TestLargeTransferPeakMemory ./build/rootfs
+
+// memoryTestChunkMultiple bounds the peak heap growth of the server and the client together
+// as a multiple of the chunk size, independently of the size of the resource.
+const memoryTestChunkMultiple = 24
+
+// synthReader generates a deterministic synthetic resource of a size without buffering it.
+type synthReader struct {
+	remaining int64
+	next      byte
+}
+
+func (r *synthReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	for i := range p {
+		p[i] = r.next
+		r.next = r.next + 31
+	}
+	r.remaining = r.remaining - int64(len(p))
+	return len(p), nil
+}
+
+// heapPeak samples the heap in use until stopped and reports the peak.
+type heapPeak struct {
+	m        sync.Mutex
+	peak     uint64
+	chanStop chan struct{}
+	chanDone chan struct{}
+}
+
+func startHeapPeak() *heapPeak {
+	h := &heapPeak{chanStop: make(chan struct{}), chanDone: make(chan struct{})}
+	go func() {
+		defer close(h.chanDone)
+		stats := &runtime.MemStats{}
+		for {
+			runtime.ReadMemStats(stats)
+			h.m.Lock()
+			if stats.HeapInuse > h.peak {
+				h.peak = stats.HeapInuse
+			}
+			h.m.Unlock()
+			select {
+			case <-h.chanStop:
+				return
+			case <-time.After(time.Millisecond * 5):
+			}
+		}
+	}()
+	return h
+}
+
+func (h *heapPeak) stop() uint64 {
+	close(h.chanStop)
+	<-h.chanDone
+	h.m.Lock()
+	defer h.m.Unlock()
+	return h.peak
+}
+
+// memoryTestBytes returns the size of the resource streamed by the peak memory test
+// and skips the test when FIREBUILD_MEMORY_TEST_BYTES is not set.
+func memoryTestBytes(t *testing.T) int64 {
+	value := os.Getenv("FIREBUILD_MEMORY_TEST_BYTES")
+	if value == "" {
+		t.Skip("large transfer skipped, set FIREBUILD_MEMORY_TEST_BYTES to run it")
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		t.Fatal("expected FIREBUILD_MEMORY_TEST_BYTES to be a number of bytes", err)
+	}
+	return size
+}
+
+func TestLargeTransferPeakMemory(t *testing.T) {
+	size := memoryTestBytes(t)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Info)
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"large": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(&synthReader{remaining: size}), nil
+				}, fs.FileMode(0644), "large", "/large", commands.DefaultWorkdir(), commands.DefaultUser(), "large"),
+			},
+		},
+	})
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+	chunkSize := grpcConfig.SafeClientMaxRecvMsgSize()
+
+	runtime.GC()
+	baseline := &runtime.MemStats{}
+	runtime.ReadMemStats(baseline)
+	peak := startHeapPeak()
+
+	reader, header, err := testClient.OpenResource(context.Background(), "large")
+	if !assert.Nil(t, err) {
+		peak.stop()
+		return
+	}
+	assert.Equal(t, "/large", header.TargetPath)
+	copied, err := io.Copy(ioutil.Discard, reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, size, copied)
+
+	peakHeap := peak.stop()
+	growth := int64(peakHeap) - int64(baseline.HeapInuse)
+	t.Log("streamed", size, "bytes, chunk size", chunkSize, "peak heap growth", growth)
+	assert.True(t, growth <= int64(memoryTestChunkMultiple*chunkSize),
+		"expected the peak heap growth of %d bytes to stay within %d chunks of %d bytes", growth, memoryTestChunkMultiple, chunkSize)
+}
@@ -0,0 +1,73 @@
+package rootfs
+
+import "sync"
+
+// MaxResourceMultiplex is the maximum number of files the server streams interleaved on one resource stream,
+// a client asking for more files is served this many.
+const MaxResourceMultiplex = 64
+
+// resourceMultiplex returns the number of files to stream interleaved for the multiplex requested by the client.
+func resourceMultiplex(requested int32) int {
+	if requested <= 1 {
+		return 1
+	}
+	if requested > MaxResourceMultiplex {
+		return MaxResourceMultiplex
+	}
+	return int(requested)
+}
+
+// walkMultiplexer streams up to limit files of the same directory concurrently,
+// the frames of the files interleave on the walk channel.
+type walkMultiplexer struct {
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	m      sync.Mutex
+	err    error
+	parent string
+}
+
+func newWalkMultiplexer(limit int) *walkMultiplexer {
+	return &walkMultiplexer{slots: make(chan struct{}, limit)}
+}
+
+// dispatch streams a file in a goroutine once fewer than limit files are in flight. The files in flight
+// of a different directory finish first. Returns the error of a file which failed, the file sent the error frame.
+func (w *walkMultiplexer) dispatch(parent string, stream func() error) error {
+	if parent != w.parent {
+		if err := w.barrier(); err != nil {
+			return err
+		}
+		w.parent = parent
+	}
+	w.slots <- struct{}{}
+	if err := w.failed(); err != nil {
+		<-w.slots
+		return err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.slots }()
+		if err := stream(); err != nil {
+			w.m.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.m.Unlock()
+		}
+	}()
+	return nil
+}
+
+// barrier waits until the files in flight have finished and returns the error of a file which failed.
+func (w *walkMultiplexer) barrier() error {
+	w.wg.Wait()
+	return w.failed()
+}
+
+func (w *walkMultiplexer) failed() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.err
+}
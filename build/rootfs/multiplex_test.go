@@ -0,0 +1,136 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustPutMultiplexedTree(t *testing.T, sourceDir string) map[string][]byte {
+	expected := map[string][]byte{}
+	for _, dir := range []string{"a", "b/c", "d"} {
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("%s/file-%02d", dir, i)
+			// files larger than a chunk interleave with their siblings:
+			contents := bytes.Repeat([]byte(name), 10+i*20)
+			MustPutTestResource(t, filepath.Join(sourceDir, name), contents)
+			expected[name] = contents
+		}
+	}
+	return expected
+}
+
+func TestDirectoryResourceMultiplexedWalk(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	expected := mustPutMultiplexedTree(t, tempDir)
+
+	resource := resources.NewResolvedDirectoryResourceWithPath(0755, tempDir, "src", "/dst",
+		commands.DefaultWorkdir(), commands.DefaultUser())
+	chanChunks := NewGRPCDirectoryResourceWithOptions(&DirectoryWalkOptions{
+		SafeBufferSize: 64,
+		Sorted:         true,
+		Multiplex:      4,
+	}, resource).WalkResource()
+
+	sequence := &frameSequence{limit: 4}
+	targets := map[string]string{}
+	contents := map[string][]byte{}
+	interleaved := false
+	lastID := ""
+	for {
+		chunk := <-chanChunks
+		if chunk == nil {
+			break
+		}
+		assert.Nil(t, sequence.next(chunk))
+		switch tchunk := chunk.GetPayload().(type) {
+		case *proto.ResourceChunk_Header:
+			if tchunk.Header.IsDir {
+				assert.Len(t, sequence.open, 1, "expected the files to end before a directory starts")
+				continue
+			}
+			targets[tchunk.Header.Id] = tchunk.Header.TargetPath
+		case *proto.ResourceChunk_Chunk:
+			if lastID != "" && lastID != tchunk.Chunk.Id {
+				if _, ok := sequence.open[lastID]; ok {
+					interleaved = true
+				}
+			}
+			lastID = tchunk.Chunk.Id
+			contents[tchunk.Chunk.Id] = append(contents[tchunk.Chunk.Id], tchunk.Chunk.Chunk...)
+		case *proto.ResourceChunk_Error:
+			t.Fatal("expected no error", tchunk.Error.Message)
+		}
+	}
+	assert.Len(t, sequence.open, 0)
+	assert.True(t, interleaved, "expected the chunks of the files to interleave")
+
+	assert.Equal(t, len(expected), len(targets))
+	for id, target := range targets {
+		rel, err := filepath.Rel("/dst", target)
+		assert.Nil(t, err)
+		assert.Equal(t, expected[path.Clean(rel)], contents[id], target)
+	}
+}
+
+func TestClientWriteResourcesMultiplexed(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{MaxMsgSize: 1024}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer testServer.Stop()
+	go func() {
+		for {
+			select {
+			case <-testServer.OnMessage():
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	capabilities, err := testClient.Capabilities()
+	assert.Nil(t, err)
+	assert.True(t, capabilities.HasFeature(FeatureMultiplex))
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, &WriteOptions{Multiplex: 8}))
+	for name, contents := range expected {
+		written, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir", name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, contents, written, name)
+		}
+	}
+	// no temporary files are left behind:
+	leftovers, err := filepath.Glob(filepath.Join(rootDir, "opt/dir/*/.*"))
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+}
@@ -0,0 +1,106 @@
+package rootfs
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// WhiteoutFormat defines how deletions are represented in an overlay layer.
+type WhiteoutFormat int
+
+const (
+	// WhiteoutFormatOverlay represents a deleted entry with a 0:0 character device and an opaque directory
+	// with the trusted.overlay.opaque extended attribute, as used by the Linux overlay file system.
+	// Requires Linux and the privileges to create device nodes and to set trusted extended attributes.
+	WhiteoutFormatOverlay WhiteoutFormat = iota
+	// WhiteoutFormatAUFS represents a deleted entry with an empty .wh.<name> file and an opaque directory
+	// with an empty .wh..wh..opq file, as used by OCI image layers.
+	WhiteoutFormatAUFS
+)
+
+const (
+	// AUFSWhiteoutPrefix is the name prefix of AUFS style whiteout files.
+	AUFSWhiteoutPrefix = ".wh."
+	// AUFSOpaqueMarker is the name of the AUFS style opaque directory marker file.
+	AUFSOpaqueMarker = ".wh..wh..opq"
+)
+
+// OverlayLayer is the upper directory of an overlay file system resources are written into instead of the live root.
+// Entries deleted from the layer are represented with whiteouts so that they are hidden in lower layers.
+// An entry written over a whiteout replaces the whiteout, a directory written over a whiteout is made opaque
+// so the contents of the deleted directory in lower layers do not reappear.
+type OverlayLayer struct {
+	// UpperDir is the directory the layer is written to.
+	UpperDir string
+	// WhiteoutFormat is the representation of deletions in the layer.
+	WhiteoutFormat WhiteoutFormat
+}
+
+// Whiteout deletes the target path from the layer, removing the entry from the upper directory
+// and hiding the entry in the lower layers.
+func (l *OverlayLayer) Whiteout(targetPath string) error {
+	filePath := targetPathUnder(l.UpperDir, targetPath)
+	if filePath == filepath.Clean(l.UpperDir) {
+		return errors.Wrap(ErrInvalidArgument, "the layer root can't be deleted")
+	}
+	if err := os.RemoveAll(filePath); err != nil {
+		return errors.Wrapf(err, "failed removing '%s' from the layer", targetPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.Wrapf(err, "failed creating parent directory of '%s' in the layer", targetPath)
+	}
+	switch l.WhiteoutFormat {
+	case WhiteoutFormatAUFS:
+		if err := writeEmptyFile(aufsWhiteoutPath(filePath)); err != nil {
+			return errors.Wrapf(err, "failed creating whiteout for '%s'", targetPath)
+		}
+	default:
+		if err := createOverlayWhiteout(filePath); err != nil {
+			return errors.Wrapf(err, "failed creating whiteout for '%s'", targetPath)
+		}
+	}
+	return nil
+}
+
+// replaceWhiteout removes a whiteout of the file path, if there is one.
+// Returns true if a whiteout has been removed.
+func (l *OverlayLayer) replaceWhiteout(filePath string) (bool, error) {
+	whiteoutPath := filePath
+	if l.WhiteoutFormat == WhiteoutFormatAUFS {
+		whiteoutPath = aufsWhiteoutPath(filePath)
+		if _, err := os.Lstat(whiteoutPath); err != nil {
+			return false, nil
+		}
+	} else {
+		info, err := os.Lstat(whiteoutPath)
+		if err != nil || !isOverlayWhiteout(info) {
+			return false, nil
+		}
+	}
+	if err := os.Remove(whiteoutPath); err != nil {
+		return false, errors.Wrapf(err, "failed removing whiteout '%s'", whiteoutPath)
+	}
+	return true, nil
+}
+
+// makeOpaque marks a directory of the layer opaque, hiding the contents of the directory in the lower layers.
+func (l *OverlayLayer) makeOpaque(dirPath string) error {
+	if l.WhiteoutFormat == WhiteoutFormatAUFS {
+		return writeEmptyFile(filepath.Join(dirPath, AUFSOpaqueMarker))
+	}
+	return setOverlayOpaque(dirPath)
+}
+
+func aufsWhiteoutPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), AUFSWhiteoutPrefix+filepath.Base(filePath))
+}
+
+func writeEmptyFile(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
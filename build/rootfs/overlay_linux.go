@@ -0,0 +1,25 @@
+package rootfs
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// createOverlayWhiteout creates an overlay whiteout, a 0:0 character device.
+func createOverlayWhiteout(filePath string) error {
+	return syscall.Mknod(filePath, syscall.S_IFCHR|0000, 0)
+}
+
+// isOverlayWhiteout returns true if the file is an overlay whiteout.
+func isOverlayWhiteout(info fs.FileInfo) bool {
+	if info.Mode()&fs.ModeCharDevice == 0 {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Rdev == 0
+}
+
+// setOverlayOpaque marks an overlay directory opaque.
+func setOverlayOpaque(dirPath string) error {
+	return syscall.Setxattr(dirPath, "trusted.overlay.opaque", []byte("y"), 0)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package rootfs
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// createOverlayWhiteout fails, overlay whiteouts are supported on Linux only.
+func createOverlayWhiteout(_ string) error {
+	return fmt.Errorf("%w: overlay whiteouts are supported on Linux only", ErrInvalidArgument)
+}
+
+// isOverlayWhiteout returns false, overlay whiteouts are supported on Linux only.
+func isOverlayWhiteout(_ fs.FileInfo) bool {
+	return false
+}
+
+// setOverlayOpaque fails, overlay opaque directories are supported on Linux only.
+func setOverlayOpaque(_ string) error {
+	return fmt.Errorf("%w: overlay opaque directories are supported on Linux only", ErrInvalidArgument)
+}
@@ -0,0 +1,92 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWriteResourcesToAUFSLayer(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "source/dir/file"), []byte("file"))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, filepath.Join(tempDir, "source/dir"), "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+			"config": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("config"))), nil
+				}, fs.FileMode(0644), "config", "/etc/config", commands.DefaultWorkdir(), commands.DefaultUser(), "config"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	upperDir := filepath.Join(tempDir, "upper")
+	layer := &OverlayLayer{UpperDir: upperDir, WhiteoutFormat: WhiteoutFormatAUFS}
+	rootDir := filepath.Join(tempDir, "root")
+
+	// a directory deleted in the layer and written again is opaque:
+	assert.Nil(t, layer.Whiteout("/opt/dir"))
+	assert.FileExists(t, filepath.Join(upperDir, "opt/.wh.dir"))
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, &WriteOptions{Layer: layer}))
+	assert.NoFileExists(t, filepath.Join(upperDir, "opt/.wh.dir"))
+	assert.FileExists(t, filepath.Join(upperDir, "opt/dir", AUFSOpaqueMarker))
+	assert.FileExists(t, filepath.Join(upperDir, "opt/dir/file"))
+
+	// a written file deleted in the layer is replaced by a whiteout:
+	assert.Nil(t, testClient.WriteResources(context.Background(), "config", rootDir, &WriteOptions{Layer: layer}))
+	assert.FileExists(t, filepath.Join(upperDir, "etc/config"))
+	assert.Nil(t, layer.Whiteout("/etc/config"))
+	assert.NoFileExists(t, filepath.Join(upperDir, "etc/config"))
+	assert.FileExists(t, filepath.Join(upperDir, "etc/.wh.config"))
+
+	assert.NotNil(t, layer.Whiteout("/"))
+
+	// the live root is not written to:
+	_, statErr := os.Stat(rootDir)
+	assert.True(t, os.IsNotExist(statErr))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestOverlayLayerWhiteouts(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	layer := &OverlayLayer{UpperDir: tempDir, WhiteoutFormat: WhiteoutFormatOverlay}
+	if err := layer.Whiteout("/etc/file"); err != nil {
+		t.Skip("overlay whiteouts not supported in this environment:", err)
+	}
+	info, err := os.Lstat(filepath.Join(tempDir, "etc/file"))
+	assert.Nil(t, err)
+	assert.True(t, isOverlayWhiteout(info))
+
+	replaced, err := layer.replaceWhiteout(filepath.Join(tempDir, "etc/file"))
+	assert.Nil(t, err)
+	assert.True(t, replaced)
+	replaced, err = layer.replaceWhiteout(filepath.Join(tempDir, "etc/file"))
+	assert.Nil(t, err)
+	assert.False(t, replaced)
+}
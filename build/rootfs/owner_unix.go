@@ -0,0 +1,18 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package rootfs
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the numeric owner of a file, false if not available.
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,95 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelCommandGroups(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	cmds := []commands.VMInitSerializableCommand{
+		commands.RunWithDefaults("apk add curl"),
+		commands.Env{OriginalCommand: "ENV A=1", Name: "A", Value: "1"},
+		commands.RunWithDefaults("curl -o /tmp/a https://example.com/a"),
+		commands.RunWithDefaults("curl -o /tmp/b https://example.com/b"),
+		commands.RunWithDefaults("make"),
+	}
+	serverCtx := &WorkContext{
+		ExecutableCommands: cmds,
+		CommandGroups:      []int{0, 1, 1, 1, 0},
+		ResourcesResolved:  make(Resources),
+	}
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, serverCtx)
+	defer srv.Stop()
+
+	chanMessages := make(chan interface{}, 32)
+	go func() {
+		for message := range srv.OnMessage() {
+			chanMessages <- message
+		}
+	}()
+
+	assert.Nil(t, testClient.Commands())
+	<-chanMessages // commands requested
+
+	group := testClient.NextCommandGroup()
+	assert.Equal(t, []IndexedCommand{{Index: 0, Command: cmds[0], Env: map[string]string{}}}, group)
+
+	group = testClient.NextCommandGroup()
+	assert.Equal(t, []IndexedCommand{
+		{Index: 2, Command: cmds[2], Group: 1, Env: map[string]string{"A": "1"}},
+		{Index: 3, Command: cmds[3], Group: 1, Env: map[string]string{"A": "1"}},
+	}, group,
+		"expected the commands of a group together, without the commands the client does not process")
+
+	// out of order acknowledgements:
+	assert.Nil(t, testClient.CommandStarted(3))
+	assert.Nil(t, testClient.CommandStarted(2))
+	assert.Nil(t, testClient.StdOut([]string{"interleaved"}))
+	assert.Nil(t, testClient.CommandFinished(3, nil))
+	assert.Nil(t, testClient.StdOut([]string{"only 2"}))
+	assert.Nil(t, testClient.CommandFinished(2, nil))
+
+	assert.Equal(t, &ClientMsgCommandStarted{Index: 3, CommandID: serverCtx.CommandIDs[3]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgCommandStarted{Index: 2, CommandID: serverCtx.CommandIDs[2]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgStdout{Lines: []string{"interleaved"}}, <-chanMessages,
+		"expected no command ID while several commands run")
+	assert.Equal(t, &ClientMsgCommandFinished{Index: 3, CommandID: serverCtx.CommandIDs[3], Attempts: 1}, <-chanMessages)
+	assert.Equal(t, &ClientMsgStdout{Lines: []string{"only 2"}, CommandID: serverCtx.CommandIDs[2]}, <-chanMessages)
+	assert.Equal(t, &ClientMsgCommandFinished{Index: 2, CommandID: serverCtx.CommandIDs[2], Attempts: 1}, <-chanMessages)
+	assert.Equal(t, 2, srv.Summary().CommandsFinished)
+
+	group = testClient.NextCommandGroup()
+	assert.Equal(t, []IndexedCommand{{Index: 4, Command: cmds[4], Env: map[string]string{"A": "1"}}}, group)
+	assert.Equal(t, []IndexedCommand{}, testClient.NextCommandGroup())
+}
+
+func TestParallelCommandGroupsIgnoredWithoutAGroupForEveryCommand(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	cmds := []commands.VMInitSerializableCommand{
+		commands.RunWithDefaults("true"),
+		commands.RunWithDefaults("true"),
+	}
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: cmds,
+		CommandGroups:      []int{1},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	assert.Nil(t, testClient.Commands())
+	assert.Equal(t, []IndexedCommand{{Index: 0, Command: cmds[0], Env: map[string]string{}}}, testClient.NextCommandGroup())
+	assert.Equal(t, cmds[1], testClient.NextCommand())
+	assert.Equal(t, []IndexedCommand{}, testClient.NextCommandGroup())
+}
@@ -0,0 +1,150 @@
+package rootfs
+
+import (
+	"sort"
+	"time"
+)
+
+// Preset is a named set of transfer tuning values applied together to the server, the client
+// and the fetch options, because the individual values interact:
+//
+//   - the server sends chunks of 90% of its MaxMsgSize, the client must receive messages of the same size,
+//     so the preset sets the same message size on both sides;
+//   - every resource stream reserves a chunk per file in flight from MaxBufferedChunkBytes, a budget smaller
+//     than FetchConcurrency × Multiplex chunks makes the streams wait for each other;
+//   - batched files are at most half a chunk, a BatchFileSize above that is capped by the server;
+//   - a client keepalive is only accepted by a server with a keepalive of at most twice the client interval.
+//
+// A preset only sets the values which are not set yet, explicitly configured values take precedence.
+type Preset struct {
+	// Name identifies the preset, for example in a command line flag.
+	Name string
+	// MaxMsgSize is the maximum message size of the server and the client, the chunk size follows from it.
+	MaxMsgSize int
+	// MaxBufferedChunkBytes limits the chunk buffers of the server across all streams, zero means no limit.
+	MaxBufferedChunkBytes int64
+	// FetchConcurrency is the number of resources the client fetches at the same time.
+	FetchConcurrency int
+	// Multiplex is the number of files of a directory resource the client writes at the same time.
+	Multiplex int
+	// BatchFileSize is the maximum size of a file sent whole in a batch, zero sends every file in chunks.
+	BatchFileSize int64
+	// CompressionThreshold is the minimum size of a chunk the server gzips, zero sends uncompressed chunks.
+	CompressionThreshold int
+	// KeepaliveInterval is the keepalive interval of the server and the client, zero sends no pings.
+	KeepaliveInterval time.Duration
+}
+
+var (
+	// PresetLowMemoryGuest keeps the memory of a small guest and of the host serving many guests low:
+	// small messages, one resource and one file at a time and a tight chunk budget.
+	// Small files are batched so the small chunks do not multiply the per file overhead.
+	PresetLowMemoryGuest = Preset{
+		Name:                  "low-memory-guest",
+		MaxMsgSize:            512 * 1024,
+		MaxBufferedChunkBytes: 4 * 1024 * 1024,
+		FetchConcurrency:      1,
+		Multiplex:             1,
+		BatchFileSize:         16 * 1024,
+	}
+	// PresetFastLocalVsock favours throughput on a local, reliable transport such as vsock:
+	// large messages, many resources and files in flight and no chunk budget.
+	// No keepalive, the local connection does not go idle through a middlebox.
+	PresetFastLocalVsock = Preset{
+		Name:             "fast-local-vsock",
+		MaxMsgSize:       16 * 1024 * 1024,
+		FetchConcurrency: 8,
+		Multiplex:        16,
+		BatchFileSize:    256 * 1024,
+	}
+	// PresetWANRemoteBuilder suits a builder reached over a wide area network: messages of the default size,
+	// many files in flight to hide the latency, aggressive batching of small files, gzipped chunks
+	// and keepalive pings so that idle connections survive NAT and load balancer timeouts.
+	PresetWANRemoteBuilder = Preset{
+		Name:                  "wan-remote-builder",
+		MaxMsgSize:            DefaultMaxMsgSize,
+		MaxBufferedChunkBytes: 128 * 1024 * 1024,
+		FetchConcurrency:      4,
+		Multiplex:             8,
+		BatchFileSize:         512 * 1024,
+		CompressionThreshold:  64 * 1024,
+		KeepaliveInterval:     30 * time.Second,
+	}
+)
+
+var presets = map[string]Preset{
+	PresetFastLocalVsock.Name:   PresetFastLocalVsock,
+	PresetLowMemoryGuest.Name:   PresetLowMemoryGuest,
+	PresetWANRemoteBuilder.Name: PresetWANRemoteBuilder,
+}
+
+// PresetNames returns the sorted names of the presets.
+func PresetNames() []string {
+	names := []string{}
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetByName returns the preset with the name.
+func PresetByName(name string) (Preset, bool) {
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// ApplyServer sets the unset values of the server configuration.
+func (p Preset) ApplyServer(cfg *GRPCServiceConfig) *GRPCServiceConfig {
+	if cfg.MaxMsgSize == 0 {
+		cfg.MaxMsgSize = p.MaxMsgSize
+	}
+	if cfg.MaxBufferedChunkBytes == 0 {
+		cfg.MaxBufferedChunkBytes = p.MaxBufferedChunkBytes
+	}
+	if cfg.CompressionThreshold == 0 {
+		cfg.CompressionThreshold = p.CompressionThreshold
+	}
+	if cfg.KeepaliveInterval == 0 {
+		cfg.KeepaliveInterval = p.KeepaliveInterval
+	}
+	return cfg
+}
+
+// ApplyClient sets the unset values of the client configuration.
+func (p Preset) ApplyClient(cfg *GRPCClientConfig) *GRPCClientConfig {
+	if cfg.MaxRecvMsgSize == 0 {
+		cfg.MaxRecvMsgSize = p.MaxMsgSize
+	}
+	if cfg.KeepaliveInterval == 0 {
+		cfg.KeepaliveInterval = p.KeepaliveInterval
+	}
+	return cfg
+}
+
+// ApplyFetch sets the unset values of the fetch options and of their write options.
+// Nil options are created.
+func (p Preset) ApplyFetch(opts *FetchOptions) *FetchOptions {
+	if opts == nil {
+		opts = &FetchOptions{}
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = p.FetchConcurrency
+	}
+	opts.WriteOptions = p.ApplyWrite(opts.WriteOptions)
+	return opts
+}
+
+// ApplyWrite sets the unset values of the write options. Nil options are created.
+func (p Preset) ApplyWrite(opts *WriteOptions) *WriteOptions {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	if opts.Multiplex == 0 {
+		opts.Multiplex = p.Multiplex
+	}
+	if opts.BatchFileSize == 0 {
+		opts.BatchFileSize = p.BatchFileSize
+	}
+	return opts
+}
@@ -0,0 +1,98 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresetsAreCoherent(t *testing.T) {
+	assert.Equal(t, []string{"fast-local-vsock", "low-memory-guest", "wan-remote-builder"}, PresetNames())
+	for _, name := range PresetNames() {
+		preset, ok := PresetByName(name)
+		assert.True(t, ok)
+		assert.Equal(t, name, preset.Name)
+
+		chunkSize := (&GRPCServiceConfig{MaxMsgSize: preset.MaxMsgSize}).SafeClientMaxRecvMsgSize()
+		assert.LessOrEqual(t, preset.BatchFileSize, int64(chunkSize/2), "%s: batched files are capped by the server", name)
+		if preset.MaxBufferedChunkBytes > 0 {
+			assert.GreaterOrEqual(t, preset.MaxBufferedChunkBytes, int64(preset.FetchConcurrency*preset.Multiplex*chunkSize),
+				"%s: the chunk budget holds the files in flight", name)
+		}
+		assert.Equal(t, preset.MaxMsgSize, preset.ApplyClient(&GRPCClientConfig{}).MaxRecvMsgSize)
+	}
+	_, ok := PresetByName("unknown")
+	assert.False(t, ok)
+}
+
+func TestPresetKeepsExplicitValues(t *testing.T) {
+	serverConfig := PresetWANRemoteBuilder.ApplyServer(&GRPCServiceConfig{MaxMsgSize: 1024})
+	assert.Equal(t, 1024, serverConfig.MaxMsgSize)
+	assert.Equal(t, PresetWANRemoteBuilder.MaxBufferedChunkBytes, serverConfig.MaxBufferedChunkBytes)
+	assert.Equal(t, PresetWANRemoteBuilder.KeepaliveInterval, serverConfig.KeepaliveInterval)
+	assert.Equal(t, PresetWANRemoteBuilder.CompressionThreshold, serverConfig.CompressionThreshold)
+
+	fetchOpts := PresetWANRemoteBuilder.ApplyFetch(&FetchOptions{WriteOptions: &WriteOptions{Multiplex: 2}})
+	assert.Equal(t, PresetWANRemoteBuilder.FetchConcurrency, fetchOpts.Concurrency)
+	assert.Equal(t, 2, fetchOpts.WriteOptions.Multiplex)
+	assert.Equal(t, PresetWANRemoteBuilder.BatchFileSize, fetchOpts.WriteOptions.BatchFileSize)
+}
+
+func TestPresetServesResources(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	for _, preset := range []Preset{PresetLowMemoryGuest, PresetFastLocalVsock, PresetWANRemoteBuilder} {
+		t.Run(preset.Name, func(t *testing.T) {
+			grpcConfig := preset.ApplyServer(&GRPCServiceConfig{})
+			testServer, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+				ExecutableCommands: []commands.VMInitSerializableCommand{},
+				ResourcesResolved: Resources{
+					"dir": []resources.ResolvedResource{
+						resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+							commands.DefaultWorkdir(), commands.DefaultUser()),
+					},
+				},
+			})
+			defer testServer.Stop()
+			go func() {
+				for {
+					select {
+					case <-testServer.OnMessage():
+					case <-testServer.StoppedNotify():
+						return
+					}
+				}
+			}()
+
+			testClient, err := NewClient(logger.Named("grpc-client"), preset.ApplyClient(&GRPCClientConfig{
+				HostPort:  grpcConfig.BindHostPort,
+				TLSConfig: grpcConfig.TLSConfigClient,
+			}))
+			assert.Nil(t, err)
+
+			rootDir := filepath.Join(tempDir, preset.Name)
+			assert.Nil(t, testClient.FetchResources(context.Background(), []string{"dir"}, rootDir, preset.ApplyFetch(nil)))
+			for name, contents := range expected {
+				written, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir", name))
+				if assert.Nil(t, err, name) {
+					assert.Equal(t, contents, written, name)
+				}
+			}
+		})
+	}
+}
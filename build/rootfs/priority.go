@@ -0,0 +1,67 @@
+package rootfs
+
+import (
+	"sort"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+)
+
+// ResourcePriorities derives the order the resources are needed in from the commands, keyed by the resource path.
+// The priority of a resource is the index of the first command needing it: the ADD or COPY command of the resource
+// or a RUN command listing the resource in its needs. Lower values are needed first.
+func ResourcePriorities(cmds []commands.VMInitSerializableCommand) map[string]int {
+	priorities := map[string]int{}
+	need := func(resourcePath string, index int) {
+		if current, ok := priorities[resourcePath]; !ok || index < current {
+			priorities[resourcePath] = index
+		}
+	}
+	for index, cmd := range cmds {
+		for _, resourcePath := range neededResources(cmd) {
+			need(resourcePath, index)
+		}
+	}
+	return priorities
+}
+
+// neededResources returns the paths of the resources a command needs: the source of an ADD command
+// or a COPY command not copying from a stage and the needs of a RUN command.
+func neededResources(cmd commands.VMInitSerializableCommand) []string {
+	switch tcmd := cmd.(type) {
+	case commands.Add:
+		return []string{tcmd.Source}
+	case commands.Copy:
+		if tcmd.Stage == "" {
+			return []string{tcmd.Source}
+		}
+	case commands.Run:
+		return tcmd.Needs
+	}
+	return nil
+}
+
+// Priorities returns the priorities of the resources derived from the executable commands
+// and overridden by the explicitly configured resource priorities.
+func (ctx *WorkContext) Priorities() map[string]int {
+	priorities := ResourcePriorities(ctx.substitutedCommands())
+	for resourcePath, priority := range ctx.ResourcePriorities {
+		priorities[resourcePath] = priority
+	}
+	return priorities
+}
+
+// sortByPriority sorts the resource paths by priority, paths without a priority go last.
+// Paths of the same priority keep their order.
+func sortByPriority(resourcePaths []string, priorities map[string]int) {
+	sort.SliceStable(resourcePaths, func(i, j int) bool {
+		return priorityOf(priorities, resourcePaths[i]) < priorityOf(priorities, resourcePaths[j])
+	})
+}
+
+// priorityOf returns the priority of a resource path, the lowest priority if the path has no priority.
+func priorityOf(priorities map[string]int, resourcePath string) int {
+	if priority, ok := priorities[resourcePath]; ok {
+		return priority
+	}
+	return int(^uint32(0) >> 1)
+}
@@ -0,0 +1,106 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourcePriorities(t *testing.T) {
+	priorities := ResourcePriorities([]commands.VMInitSerializableCommand{
+		commands.Copy{Source: "config", Target: "/etc/config"},
+		commands.Copy{Source: "artifact", Stage: "builder", Target: "/usr/bin/artifact"},
+		commands.Run{Command: "/usr/bin/app", Needs: []string{"app"}},
+		commands.Add{Source: "app", Target: "/usr/bin/app"},
+		commands.Add{Source: "config", Target: "/etc/config.bak"},
+	})
+	assert.Equal(t, map[string]int{"config": 0, "app": 2}, priorities)
+
+	sorted := []string{"unknown", "app", "config", "other"}
+	sortByPriority(sorted, priorities)
+	assert.Equal(t, []string{"config", "app", "unknown", "other"}, sorted)
+}
+
+func TestClientFetchResourcesInPriorityOrder(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "a.txt"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(tempDir, "bin/app"), []byte("app"))
+	MustPutTestResource(t, filepath.Join(tempDir, "lib/dep"), []byte("dep"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Copy{
+			OriginalCommand: "COPY a.txt /app/a.txt",
+			OriginalSource:  "a.txt",
+			Source:          "a.txt",
+			Target:          "/app/a.txt",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Copy{
+			OriginalCommand: "COPY lib /app/lib",
+			OriginalSource:  "lib",
+			Source:          "lib",
+			Target:          "/app/lib",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Run{
+			OriginalCommand: "RUN /app/bin/app",
+			Command:         "/app/bin/app",
+			Needs:           []string{"bin"},
+		},
+		commands.Copy{
+			OriginalCommand: "COPY bin /app/bin",
+			OriginalSource:  "bin",
+			Source:          "bin",
+			Target:          "/app/bin",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+	resolved, err := ResolveFromDirectory(tempDir, executableCommands)
+	assert.Nil(t, err)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: executableCommands,
+		ResourcesResolved:  resolved,
+		// the binary is needed before anything else:
+		ResourcePriorities: map[string]int{"bin": -1},
+	})
+	defer cleanupFunc()
+
+	manifest, err := testClient.Manifest(context.Background(), "")
+	assert.Nil(t, err)
+	listed := []string{}
+	for _, entry := range manifest {
+		listed = append(listed, entry.Path)
+	}
+	assert.Equal(t, []string{"bin", "bin/app", "a.txt", "lib", "lib/dep"}, listed)
+	assert.Equal(t, -1, manifest[0].Priority)
+	assert.Equal(t, 1, manifest[3].Priority)
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.FetchResources(context.Background(), []string{"lib", "a.txt", "bin"}, rootDir, &FetchOptions{Concurrency: 2}))
+	for entryPath, expected := range map[string]string{"app/a.txt": "a", "app/bin/app": "app", "app/lib/dep": "dep"} {
+		contents, err := ioutil.ReadFile(filepath.Join(rootDir, entryPath))
+		if assert.Nil(t, err) {
+			assert.Equal(t, expected, string(contents))
+		}
+	}
+
+	assert.NotNil(t, testClient.FetchResources(context.Background(), []string{"missing"}, rootDir, nil))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
@@ -0,0 +1,174 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// progressReportTimeout bounds a progress report, a report not accepted in time is a failed report.
+const progressReportTimeout = 5 * time.Second
+
+// ResourceProgress is the progress of writing a resource reported by the client.
+type ResourceProgress struct {
+	// Path is the path of the resource.
+	Path string
+	// BytesWritten is the number of content bytes received so far.
+	BytesWritten int64
+	// BytesTotal is the number of content bytes of the resource, zero when unknown, see WriteOptions.ProgressInterval.
+	BytesTotal int64
+	// BytesPerSecond is the throughput achieved since the client started writing the resource.
+	BytesPerSecond int64
+	// ETA is the estimated time until the resource is written, zero when the total or the throughput is unknown.
+	ETA time.Duration
+	// Done is true in the last report of a resource written successfully.
+	Done bool
+}
+
+// ReportProgress reports the progress of writing a resource to the server.
+func (c *defaultClient) ReportProgress(progress ResourceProgress) error {
+	ctx, cancel := context.WithTimeout(context.Background(), progressReportTimeout)
+	defer cancel()
+	_, err := c.underlying.Progress(ctx, &proto.ProgressReport{
+		Path:           progress.Path,
+		BytesWritten:   progress.BytesWritten,
+		BytesTotal:     progress.BytesTotal,
+		BytesPerSecond: progress.BytesPerSecond,
+		EtaMillis:      progress.ETA.Milliseconds(),
+		Done:           progress.Done,
+	})
+	return fromStatusError(err)
+}
+
+// progressReporter reports the progress of writing a resource at an interval, see WriteOptions.ProgressInterval.
+// A nil reporter reports nothing.
+type progressReporter struct {
+	client  *defaultClient
+	path    string
+	started time.Time
+	// total and written are atomic, the bytes are counted by the receiving routine:
+	total   int64
+	written int64
+	// announced is true when the total is the sum of the sizes announced by the headers of the files received:
+	announced bool
+
+	stopOnce sync.Once
+	chanStop chan struct{}
+	chanDone chan struct{}
+	// failed is set when the server did not accept a report, no further reports are sent:
+	failed bool
+}
+
+// startProgress starts reporting the progress of the resource at the interval, nil when the interval is not positive.
+// The total is the size of the entries of the manifest of the resource, unknown when the server serves no manifest.
+// A delta transfers only some of the entries, the total of a delta is the sum of the sizes announced by the headers
+// of the files received so far.
+func (c *defaultClient) startProgress(ctx context.Context, path string, interval time.Duration, delta bool) *progressReporter {
+	if interval <= 0 {
+		return nil
+	}
+	r := &progressReporter{
+		client:    c,
+		path:      path,
+		started:   time.Now(),
+		announced: delta,
+		chanStop:  make(chan struct{}),
+		chanDone:  make(chan struct{}),
+	}
+	if !delta {
+		if entries, err := c.Manifest(ctx, path); err == nil {
+			for _, entry := range entries {
+				r.total = r.total + entry.Size
+			}
+		} else {
+			c.logger.Debug("progress reported without a total", "path", path, "reason", err)
+		}
+	}
+	go func() {
+		defer close(r.chanDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.report(false)
+			case <-r.chanStop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// announce counts the size of a file announced by its header towards the total of a delta.
+func (r *progressReporter) announce(header ResourceHeader) {
+	if r == nil || !r.announced || header.IsDir || header.Size < 0 {
+		return
+	}
+	atomic.AddInt64(&r.total, header.Size)
+}
+
+// add counts received content bytes.
+func (r *progressReporter) add(bytes int) {
+	if r == nil {
+		return
+	}
+	atomic.AddInt64(&r.written, int64(bytes))
+}
+
+// stop stops the reports, a resource written successfully is reported done.
+func (r *progressReporter) stop(done bool) {
+	if r == nil {
+		return
+	}
+	r.stopOnce.Do(func() {
+		close(r.chanStop)
+		<-r.chanDone
+		if done {
+			r.report(true)
+		}
+	})
+}
+
+// report sends the current progress, only from the reporting routine or after it stopped.
+func (r *progressReporter) report(done bool) {
+	if r.failed {
+		return
+	}
+	progress := ResourceProgress{Path: r.path, BytesWritten: atomic.LoadInt64(&r.written), BytesTotal: atomic.LoadInt64(&r.total), Done: done}
+	if elapsed := time.Since(r.started); elapsed > 0 {
+		progress.BytesPerSecond = int64(float64(progress.BytesWritten) / elapsed.Seconds())
+	}
+	if !done && progress.BytesTotal > progress.BytesWritten && progress.BytesPerSecond > 0 {
+		progress.ETA = time.Duration(float64(progress.BytesTotal-progress.BytesWritten) / float64(progress.BytesPerSecond) * float64(time.Second))
+	}
+	if err := r.client.ReportProgress(progress); err != nil {
+		// progress is best effort, for example a server without FeatureProgress:
+		r.client.logger.Debug("failed reporting progress, not reporting any further", "path", r.path, "reason", err)
+		r.failed = true
+	}
+}
+
+func (impl *serverImpl) Progress(ctx context.Context, req *proto.ProgressReport) (*proto.Empty, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.Empty{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	impl.emit(&ClientMsgProgress{
+		GuestID:        guestIDFromContext(ctx),
+		Path:           req.Path,
+		BytesWritten:   req.BytesWritten,
+		BytesTotal:     req.BytesTotal,
+		BytesPerSecond: req.BytesPerSecond,
+		ETA:            time.Duration(req.EtaMillis) * time.Millisecond,
+		Done:           req.Done,
+	})
+	return &proto.Empty{}, nil
+}
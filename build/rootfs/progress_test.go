@@ -0,0 +1,139 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientReportsWriteProgress(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	expected := mustPutMultiplexedTree(t, sourceDir)
+	total := int64(0)
+	for _, contents := range expected {
+		total = total + int64(len(contents))
+	}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer testServer.Stop()
+	chanProgress := make(chan *ClientMsgProgress, 1024)
+	go func() {
+		for {
+			select {
+			case event := <-testServer.OnMessage():
+				if tevent, ok := event.(*ClientMsgProgress); ok {
+					chanProgress <- tevent
+				}
+			case <-testServer.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	capabilities, err := testClient.Capabilities()
+	assert.Nil(t, err)
+	assert.True(t, capabilities.HasFeature(FeatureProgress))
+
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", filepath.Join(tempDir, "root"), &WriteOptions{
+		ProgressInterval: time.Millisecond,
+	}))
+
+	// the periodic reports precede the report of the written resource:
+	var last *ClientMsgProgress
+	for last == nil || !last.Done {
+		select {
+		case progress := <-chanProgress:
+			assert.Equal(t, "dir", progress.Path)
+			assert.Equal(t, total, progress.BytesTotal, "expected the total of the manifest")
+			assert.LessOrEqual(t, progress.BytesWritten, total)
+			last = progress
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the resource reported done")
+		}
+	}
+	assert.Equal(t, total, last.BytesWritten)
+	assert.Greater(t, last.BytesPerSecond, int64(0))
+	assert.Equal(t, time.Duration(0), last.ETA)
+
+	// a delta reports the total of the files transferred, not of the manifest:
+	changed := []byte("changed contents")
+	for name := range expected {
+		MustPutTestResource(t, filepath.Join(sourceDir, name), changed)
+		break
+	}
+	existing, err := ExistingDigests(filepath.Join(tempDir, "root"), "/opt/dir")
+	assert.Nil(t, err)
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", filepath.Join(tempDir, "root"), &WriteOptions{
+		Existing:         existing,
+		ProgressInterval: time.Millisecond,
+	}))
+	for last = nil; last == nil || !last.Done; {
+		select {
+		case progress := <-chanProgress:
+			assert.LessOrEqual(t, progress.BytesTotal, int64(len(changed)))
+			last = progress
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the delta reported done")
+		}
+	}
+	assert.Equal(t, int64(len(changed)), last.BytesTotal, "expected the total of the changed file")
+	assert.Equal(t, int64(len(changed)), last.BytesWritten)
+}
+
+func TestProgressReporterEstimatesCompletion(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	chanProgress := make(chan *ClientMsgProgress, 1)
+	go func() {
+		for {
+			select {
+			case event := <-srv.OnMessage():
+				if tevent, ok := event.(*ClientMsgProgress); ok {
+					chanProgress <- tevent
+				}
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	reporter := &progressReporter{client: testClient.(*defaultClient), path: "file", total: 4000, started: time.Now().Add(-time.Second)}
+	reporter.add(1000)
+	reporter.report(false)
+	progress := <-chanProgress
+	assert.Equal(t, int64(1000), progress.BytesWritten)
+	assert.InDelta(t, 1000, progress.BytesPerSecond, 100)
+	assert.InDelta(t, float64(3*time.Second), float64(progress.ETA), float64(500*time.Millisecond))
+	assert.False(t, progress.Done)
+
+	var nilReporter *progressReporter
+	nilReporter.add(1)
+	nilReporter.stop(true)
+}
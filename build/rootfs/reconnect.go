@@ -0,0 +1,145 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// ResumePoint is where a reconnecting client and the server agreed to continue the build.
+type ResumePoint struct {
+	// BuildID is the ID of the resumed build.
+	BuildID string
+	// NextIndex is the index of the first command to execute, the earlier commands are not returned
+	// by NextCommand and NextCommandGroup.
+	NextIndex int
+	// ResourceOffsets contain the offsets to continue the partially written resources from, keyed by the resource path.
+	// The resources the server does not serve or cannot continue from the offset of the client are not included
+	// and must be written from the start.
+	ResourceOffsets map[string]int64
+}
+
+// Resume presents the progress of a reconnecting client to the server so that both sides continue the build
+// rather than treating the reconnection as a new build. The last completed index is the index of the last command
+// the client finished or skipped, -1 if none, the resource offsets are the lengths of the partially written resources
+// keyed by the resource path. The client executed the commands, so the server takes over its progress.
+// When called after Commands, the commands before the agreed index are dropped from the fetched commands.
+func (c *defaultClient) Resume(lastCompletedIndex int, resourceOffsets map[string]int64) (*ResumePoint, error) {
+	response, err := c.underlying.Resume(context.Background(), &proto.ResumeRequest{
+		BuildId:            c.buildID,
+		LastCompletedIndex: int32(lastCompletedIndex),
+		ResourceOffsets:    resourceOffsets,
+	})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	point := &ResumePoint{
+		BuildID:         response.BuildId,
+		NextIndex:       int(response.NextIndex),
+		ResourceOffsets: response.ResourceOffsets,
+	}
+	if point.ResourceOffsets == nil {
+		point.ResourceOffsets = map[string]int64{}
+	}
+	c.resumeIndex = point.NextIndex
+	remaining := []IndexedCommand{}
+	for _, indexed := range c.fetchedCommands {
+		if indexed.Index >= c.resumeIndex {
+			remaining = append(remaining, indexed)
+		}
+	}
+	c.fetchedCommands = remaining
+	return point, nil
+}
+
+func (impl *serverImpl) Resume(ctx context.Context, req *proto.ResumeRequest) (*proto.ResumeResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.ResumeResponse{}, ErrServerStopped
+	}
+	if req.BuildId != "" && req.BuildId != impl.serverCtx.BuildID {
+		defer impl.m.Unlock()
+		return &proto.ResumeResponse{}, fmt.Errorf("%w: requested build %q, server serves build %q", ErrBuildMismatch, req.BuildId, impl.serverCtx.BuildID)
+	}
+	lastCompleted := int(req.LastCompletedIndex)
+	if lastCompleted < -1 || lastCompleted >= len(impl.serverCtx.ExecutableCommands) {
+		defer impl.m.Unlock()
+		return &proto.ResumeResponse{}, fmt.Errorf("%w: command index out of range: %d", ErrInvalidArgument, lastCompleted)
+	}
+	impl.resumeCommandsLocked(lastCompleted)
+	impl.journalLocked()
+	impl.m.Unlock()
+
+	response := &proto.ResumeResponse{
+		BuildId:         impl.serverCtx.BuildID,
+		NextIndex:       int32(lastCompleted + 1),
+		ResourceOffsets: map[string]int64{},
+	}
+	for path, offset := range req.ResourceOffsets {
+		if impl.resumableFrom(path, offset) {
+			response.ResourceOffsets[path] = offset
+		}
+	}
+	impl.logger.Info("client resumed", "next-index", response.NextIndex, "resources", len(response.ResourceOffsets))
+	impl.emit(&ClientMsgResumed{NextIndex: int(response.NextIndex), ResourceOffsets: response.ResourceOffsets})
+	return response, nil
+}
+
+// resumeCommandsLocked takes over the progress of the client, the lock must be held: the commands up to
+// the last completed index without a finished or skipped acknowledgement are finished, the acknowledgements
+// of the later commands are dropped because the client executes the commands again.
+func (impl *serverImpl) resumeCommandsLocked(lastCompleted int) {
+	for index := range impl.serverCtx.ExecutableCommands {
+		phase, acked := impl.commandAcks[index]
+		completed := acked && (phase == proto.CommandAck_FINISHED || phase == proto.CommandAck_SKIPPED)
+		switch {
+		case index <= lastCompleted && !completed:
+			impl.commandAcks[index] = proto.CommandAck_FINISHED
+			impl.summary.CommandsFinished = impl.summary.CommandsFinished + 1
+		case index > lastCompleted && acked:
+			delete(impl.commandAcks, index)
+			switch phase {
+			case proto.CommandAck_FINISHED:
+				impl.summary.CommandsFinished = impl.summary.CommandsFinished - 1
+			case proto.CommandAck_SKIPPED:
+				impl.summary.CommandsSkipped = impl.summary.CommandsSkipped - 1
+			}
+		}
+		if index > lastCompleted {
+			delete(impl.runningCommands, index)
+			if timer, ok := impl.commandTimers[index]; ok {
+				timer.Stop()
+				delete(impl.commandTimers, index)
+			}
+		}
+	}
+	impl.commandsRequested = true
+	impl.lastAckedIndex = lastCompleted
+}
+
+// resumableFrom returns true if the first resource served for the path can be continued from the offset.
+// The offset of a resource with contents of unknown size is accepted, the resource request validates it.
+func (impl *serverImpl) resumableFrom(path string, offset int64) bool {
+	if offset <= 0 {
+		return false
+	}
+	for _, resource := range impl.serverCtx.ResourcesResolved[path] {
+		if !resources.PlatformMatches(resources.PlatformOf(resource), impl.serverCtx.Platform) {
+			continue
+		}
+		if resource.IsDir() {
+			return false
+		}
+		statResult, err := os.Stat(resource.ResolvedURIOrPath())
+		if err != nil {
+			return true
+		}
+		return statResult.Mode().IsRegular() && offset <= statResult.Size()
+	}
+	return false
+}
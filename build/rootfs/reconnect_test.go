@@ -0,0 +1,88 @@
+package rootfs
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientReconnectResume(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	filePath := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("0123456789"), 0644))
+	resource := resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}, 0644, "file", "/file", commands.DefaultWorkdir(), commands.DefaultUser(), filePath)
+
+	cmds := []commands.VMInitSerializableCommand{
+		commands.RunWithDefaults("step 0"),
+		commands.RunWithDefaults("step 1"),
+		commands.RunWithDefaults("step 2"),
+		commands.RunWithDefaults("step 3"),
+	}
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		BuildID:            "reconnect",
+		ExecutableCommands: cmds,
+		ResourcesResolved:  Resources{"file": {resource}},
+	})
+	defer srv.Stop()
+	chanResumed := make(chan *ClientMsgResumed, 4)
+	go func() {
+		for message := range srv.OnMessage() {
+			if resumed, ok := message.(*ClientMsgResumed); ok {
+				chanResumed <- resumed
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.CommandFinished(0, nil))
+	assert.Nil(t, testClient.CommandStarted(1))
+	// the acknowledgement of the finished command 1 is lost with the connection
+
+	newClient := func(buildID string) ClientProvider {
+		client, err := NewClient(logger.Named("grpc-client"), &GRPCClientConfig{
+			HostPort:  grpcConfig.BindHostPort,
+			TLSConfig: grpcConfig.TLSConfigClient,
+			BuildID:   buildID,
+		})
+		assert.Nil(t, err)
+		return client
+	}
+
+	reconnected := newClient("reconnect")
+	assert.Nil(t, reconnected.Commands())
+	point, err := reconnected.Resume(1, map[string]int64{"file": 4, "unknown": 3})
+	assert.Nil(t, err)
+	assert.Equal(t, &ResumePoint{BuildID: "reconnect", NextIndex: 2, ResourceOffsets: map[string]int64{"file": 4}}, point)
+	assert.Equal(t, &ClientMsgResumed{NextIndex: 2, ResourceOffsets: map[string]int64{"file": 4}}, <-chanResumed)
+	assert.Equal(t, 2, srv.Summary().CommandsFinished, "expected the server to take over the progress of the client")
+	assert.Equal(t, cmds[2], reconnected.NextCommand())
+	assert.Nil(t, reconnected.Commands())
+	assert.Equal(t, cmds[2], reconnected.NextCommand(), "expected the completed commands to stay skipped")
+
+	// a guest which lost its progress starts over:
+	point, err = newClient("").Resume(-1, map[string]int64{"file": 11})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, point.NextIndex)
+	assert.Equal(t, map[string]int64{}, point.ResourceOffsets, "expected an offset beyond the resource not to resume")
+	<-chanResumed
+	assert.Equal(t, 0, srv.Summary().CommandsFinished)
+
+	_, err = newClient("").Resume(4, nil)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	_, err = newClient("another").Resume(0, nil)
+	assert.True(t, errors.Is(err, ErrBuildMismatch))
+}
@@ -0,0 +1,61 @@
+package rootfs
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedPlaceholder replaces the secrets found in the build output.
+const RedactedPlaceholder = "[REDACTED]"
+
+// Redactor replaces the secrets in the stdout and stderr lines and the abort error of the build,
+// so the secrets echoed by the build do not reach the events, the log subscribers or the summary.
+type Redactor struct {
+	values   *strings.Replacer
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a redactor replacing the exact secret values, for example the values of the secret store
+// the build uses, and the matches of the patterns, for example tokens of a known format. Empty values are ignored.
+func NewRedactor(values []string, patterns ...*regexp.Regexp) *Redactor {
+	sorted := []string{}
+	for _, value := range values {
+		if value != "" {
+			sorted = append(sorted, value)
+		}
+	}
+	// a value containing another value is replaced as a whole:
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	oldnew := make([]string, 0, len(sorted)*2)
+	for _, value := range sorted {
+		oldnew = append(oldnew, value, RedactedPlaceholder)
+	}
+	return &Redactor{values: strings.NewReplacer(oldnew...), patterns: patterns}
+}
+
+// Redact returns the line with every secret replaced by RedactedPlaceholder.
+func (r *Redactor) Redact(line string) string {
+	if r == nil {
+		return line
+	}
+	line = r.values.Replace(line)
+	for _, pattern := range r.patterns {
+		line = pattern.ReplaceAllString(line, RedactedPlaceholder)
+	}
+	return line
+}
+
+// redactLines returns the lines with every secret replaced, the lines as given without a redactor.
+func (r *Redactor) redactLines(lines []string) []string {
+	if r == nil {
+		return lines
+	}
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, r.Redact(line))
+	}
+	return result
+}
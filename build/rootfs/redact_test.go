@@ -0,0 +1,65 @@
+package rootfs
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor([]string{"s3cret", "", "s3cret-longer"}, regexp.MustCompile(`ghp_[A-Za-z0-9]+`))
+	assert.Equal(t, "password=[REDACTED]", redactor.Redact("password=s3cret"))
+	assert.Equal(t, "[REDACTED] and [REDACTED]", redactor.Redact("s3cret-longer and s3cret"))
+	assert.Equal(t, "token: [REDACTED]", redactor.Redact("token: ghp_abc123XYZ"))
+	assert.Equal(t, "nothing to hide", redactor.Redact("nothing to hide"))
+
+	var noRedactor *Redactor
+	assert.Equal(t, "s3cret", noRedactor.Redact("s3cret"))
+}
+
+func TestServerRedactsLogLines(t *testing.T) {
+	grpcConfig := &GRPCServiceConfig{
+		MaxLogLineBytes: 40,
+		Redactor:        NewRedactor([]string{"s3cret"}),
+	}
+	srv, testClient := mustStartServerAndClient(t, hclog.Default(), grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanLines := make(chan []string, 10)
+	chanAborted := make(chan error, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			switch tmessage := message.(type) {
+			case *ClientMsgStdout:
+				chanLines <- tmessage.Lines
+			case *ClientMsgStderr:
+				chanLines <- tmessage.Lines
+			case *ClientMsgAborted:
+				chanAborted <- tmessage.Error
+			}
+		}
+	}()
+	chanLogs, unsubscribe := srv.SubscribeLogs()
+	defer unsubscribe()
+
+	assert.Nil(t, testClient.StdOut([]string{"echo s3cret"}))
+	assert.Equal(t, []string{"echo [REDACTED]"}, <-chanLines)
+	assert.Equal(t, "echo [REDACTED]", (<-chanLogs).Line)
+
+	// the secret is redacted before the line is truncated:
+	assert.Nil(t, testClient.StdErr([]string{"01234567890123s3cret and a lot of trailing text"}))
+	lines := <-chanLines
+	assert.True(t, len(lines[0]) <= 40)
+	assert.NotContains(t, lines[0], "s3")
+
+	assert.Nil(t, testClient.Abort(errors.New("failed with s3cret")))
+	assert.Equal(t, "failed with [REDACTED]", (<-chanAborted).Error())
+	assert.Equal(t, "failed with [REDACTED]", srv.Summary().Error.Error())
+}
@@ -0,0 +1,46 @@
+package rootfs
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestRegisterRootfsServerWith(t *testing.T) {
+	impl := newServerImpl(hclog.Default(), &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	}, (&GRPCServiceConfig{}).WithDefaultsApplied())
+	defer impl.Stop()
+	go func() {
+		for range impl.OnMessage() {
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	grpcServer := grpc.NewServer()
+	proto.RegisterRootfsServerWith(grpcServer, impl)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	for _, desc := range proto.RootfsServerServiceDescs() {
+		response := &proto.PingResponse{}
+		assert.Nil(t, conn.Invoke(context.Background(), "/"+desc.ServiceName+"/Ping", &proto.PingRequest{Id: "ping"}, response))
+		assert.Equal(t, "ping", response.Id)
+	}
+
+	serviceDescriptor := proto.RootfsServerServiceDescriptor()
+	assert.Equal(t, len(proto.RootfsServer_ServiceDesc.Methods)+len(proto.RootfsServer_ServiceDesc.Streams), serviceDescriptor.Methods().Len())
+	assert.Equal(t, proto.FileDescriptor().Path(), serviceDescriptor.ParentFile().Path())
+}
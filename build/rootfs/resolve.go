@@ -0,0 +1,139 @@
+package rootfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+)
+
+// ResolveOptions configures resolution of resources from a context directory.
+type ResolveOptions struct {
+	// CollisionPolicy defines how target path collisions are handled, collisions are ignored by default.
+	CollisionPolicy CollisionPolicy
+	// Logger receives collision warnings, optional.
+	Logger hclog.Logger
+}
+
+// ResolveFromDirectory resolves the sources of ADD and COPY commands relative to a context directory.
+// Sources may contain glob patterns, entries matching the patterns of the context .dockerignore file
+// are not resolved. COPY commands with a stage are skipped, their resources do not come from the context.
+// The result is keyed by the command source and can be used as WorkContext.ResourcesResolved.
+func ResolveFromDirectory(contextDir string, cmds []commands.VMInitSerializableCommand) (Resources, error) {
+	return ResolveFromDirectoryWithOptions(contextDir, cmds, &ResolveOptions{})
+}
+
+// ResolveFromDirectoryWithOptions resolves the sources of ADD and COPY commands relative to a context directory
+// like ResolveFromDirectory and checks the resolved target paths for collisions according to the options.
+func ResolveFromDirectoryWithOptions(contextDir string, cmds []commands.VMInitSerializableCommand, opts *ResolveOptions) (Resources, error) {
+	absContextDir, err := filepath.Abs(contextDir)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: context directory '%s', reason: %v", contextDir, err)
+	}
+	matcher, err := resources.LoadDockerIgnore(absContextDir)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: could not load ignore file, reason: %v", err)
+	}
+
+	resolved := Resources{}
+	for _, cmd := range cmds {
+		var source, target string
+		var workdir commands.Workdir
+		var user commands.User
+		switch tcmd := cmd.(type) {
+		case commands.Add:
+			source, target, workdir, user = tcmd.Source, tcmd.Target, tcmd.Workdir, tcmd.User
+			if tcmd.UserFromLocalChown != nil {
+				user = *tcmd.UserFromLocalChown
+			}
+		case commands.Copy:
+			if tcmd.Stage != "" {
+				continue
+			}
+			source, target, workdir, user = tcmd.Source, tcmd.Target, tcmd.Workdir, tcmd.User
+			if tcmd.UserFromLocalChown != nil {
+				user = *tcmd.UserFromLocalChown
+			}
+		default:
+			continue
+		}
+		if _, ok := resolved[source]; ok {
+			continue
+		}
+		sourceResources, err := resolveDirectorySource(absContextDir, matcher, source, target, workdir, user)
+		if err != nil {
+			return nil, err
+		}
+		resolved[source] = sourceResources
+	}
+	return DetectCollisions(resolved, opts.CollisionPolicy, opts.Logger)
+}
+
+func resolveDirectorySource(contextDir string, matcher *resources.IgnoreMatcher, source, target string, workdir commands.Workdir, user commands.User) ([]resources.ResolvedResource, error) {
+	if source == "" {
+		return nil, fmt.Errorf("empty: '%s' not resolvable", source)
+	}
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		httpContentSupplier := func() (io.ReadCloser, error) {
+			httpResponse, err := http.Get(source)
+			if err != nil {
+				return nil, err
+			}
+			return httpResponse.Body, nil
+		}
+		return []resources.ResolvedResource{
+			resources.NewResolvedFileResourceWithPath(httpContentSupplier, fs.FileMode(0644), source, target, workdir, user, source),
+		}, nil
+	}
+
+	pattern := filepath.Join(contextDir, filepath.Clean("/"+source))
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("resource failed: filepath glob error for path '%s', reason: %v", source, err)
+	}
+
+	result := []resources.ResolvedResource{}
+	for _, match := range matches {
+		relativePath, err := filepath.Rel(contextDir, match)
+		if err != nil || strings.HasPrefix(relativePath, "..") {
+			return nil, fmt.Errorf("resource failed: resolved '%s' not in the context of '%s'", match, contextDir)
+		}
+		if relativePath != "." && matcher.Ignored(relativePath) {
+			continue
+		}
+		statResult, err := os.Stat(match)
+		if err != nil {
+			return nil, fmt.Errorf("resource failed: resolved '%s', reason: %v", match, err)
+		}
+		if statResult.IsDir() {
+			result = append(result, resources.NewIgnoreFilteredResource(matcher, relativePath,
+				resources.NewResolvedDirectoryResourceWithPath(statResult.Mode().Perm(), match, source, target, workdir, user)))
+			continue
+		}
+		filePath := match
+		fileTarget := target
+		if len(matches) > 1 || strings.HasSuffix(target, "/") {
+			fileTarget = filepath.Join(target, filepath.Base(match))
+		}
+		result = append(result, resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+			file, err := os.Open(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("resource failed: could not read file resource '%s', reason: %v", filePath, err)
+			}
+			return file, nil
+		}, statResult.Mode().Perm(), source, fileTarget, workdir, user, filePath))
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("resource failed: '%s' did not resolve any resources in '%s'", source, contextDir)
+	}
+	return result, nil
+}
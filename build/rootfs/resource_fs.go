@@ -0,0 +1,217 @@
+package rootfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// ManifestEntry describes a resource served by the server without its contents.
+type ManifestEntry struct {
+	// Path is the resource path to request the entry with.
+	Path string
+	// TargetPath is the path the entry is written to.
+	TargetPath string
+	// TargetMode is the file mode of the entry.
+	TargetMode fs.FileMode
+	// IsDir is true for directories.
+	IsDir bool
+	// Size is the size of a file entry, zero when the server does not know the size upfront.
+	Size int64
+	// Platform is the platform of the entry, empty for platform independent entries.
+	Platform string
+	// Priority is the priority of the resource the entry belongs to, lower values are needed first.
+	Priority int
+}
+
+// Manifest lists the resources served for a path without the contents.
+// Listing all resources, the resources are ordered by priority.
+func (c *defaultClient) Manifest(ctx context.Context, path string) ([]ManifestEntry, error) {
+	response, err := c.underlying.Manifest(ctx, &proto.ManifestRequest{Path: path})
+	if err != nil {
+		return nil, err
+	}
+	result := []ManifestEntry{}
+	for _, entry := range response.Entries {
+		result = append(result, ManifestEntry{
+			Path:       entry.Path,
+			TargetPath: entry.TargetPath,
+			TargetMode: fs.FileMode(entry.FileMode),
+			IsDir:      entry.IsDir,
+			Size:       entry.Size,
+			Platform:   entry.Platform,
+			Priority:   int(entry.Priority),
+		})
+	}
+	return result, nil
+}
+
+// FS returns a file system of all resources served by the server, keyed by the target paths.
+func (c *defaultClient) FS(ctx context.Context) (fs.FS, error) {
+	entries, err := c.Manifest(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	resourceFS := &resourceFS{
+		ctx:    ctx,
+		client: c,
+		nodes: map[string]*resourceFSNode{
+			".": {name: ".", entry: ManifestEntry{TargetMode: 0755, IsDir: true}, children: map[string]struct{}{}},
+		},
+	}
+	for _, entry := range entries {
+		resourceFS.add(entry)
+	}
+	return resourceFS, nil
+}
+
+type resourceFSNode struct {
+	name     string
+	entry    ManifestEntry
+	children map[string]struct{}
+}
+
+func (n *resourceFSNode) info() fs.FileInfo {
+	return &resourceFileInfo{name: path.Base(n.name), entry: n.entry}
+}
+
+// resourceFS is an fs.FS of the resources served by the server.
+type resourceFS struct {
+	ctx    context.Context
+	client *defaultClient
+	nodes  map[string]*resourceFSNode
+}
+
+// add adds an entry under its target path, creating missing parent directories.
+// An entry with the same target path as a previous entry replaces the previous one.
+func (rfs *resourceFS) add(entry ManifestEntry) {
+	name := strings.TrimPrefix(path.Clean("/"+entry.TargetPath), "/")
+	if name == "" {
+		return
+	}
+	node, ok := rfs.nodes[name]
+	if !ok {
+		node = &resourceFSNode{name: name, children: map[string]struct{}{}}
+		rfs.nodes[name] = node
+	}
+	node.entry = entry
+	for child := name; child != "."; {
+		parent := path.Dir(child)
+		parentNode, ok := rfs.nodes[parent]
+		if !ok {
+			parentNode = &resourceFSNode{name: parent, entry: ManifestEntry{TargetMode: 0755, IsDir: true}, children: map[string]struct{}{}}
+			rfs.nodes[parent] = parentNode
+		}
+		parentNode.children[path.Base(child)] = struct{}{}
+		child = parent
+	}
+}
+
+// Open opens a file or a directory. File contents are streamed from the server when first read.
+func (rfs *resourceFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	node, ok := rfs.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.entry.IsDir {
+		return &resourceFSDir{fs: rfs, node: node}, nil
+	}
+	return &resourceFSFile{fs: rfs, node: node}, nil
+}
+
+type resourceFSFile struct {
+	fs     *resourceFS
+	node   *resourceFSNode
+	reader io.ReadCloser
+}
+
+func (f *resourceFSFile) Stat() (fs.FileInfo, error) {
+	return f.node.info(), nil
+}
+
+func (f *resourceFSFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		reader, _, err := f.fs.client.openResource(f.fs.ctx, &proto.ResourceRequest{
+			Path:       f.node.entry.Path,
+			TargetPath: f.node.entry.TargetPath,
+		})
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: err}
+		}
+		f.reader = reader
+	}
+	return f.reader.Read(p)
+}
+
+func (f *resourceFSFile) Close() error {
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+	return nil
+}
+
+type resourceFSDir struct {
+	fs     *resourceFS
+	node   *resourceFSNode
+	offset int
+}
+
+func (d *resourceFSDir) Stat() (fs.FileInfo, error) {
+	return d.node.info(), nil
+}
+
+func (d *resourceFSDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: fs.ErrInvalid}
+}
+
+func (d *resourceFSDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the directory entries sorted by name.
+func (d *resourceFSDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := []string{}
+	for name := range d.node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = names[d.offset:]
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	d.offset = d.offset + len(names)
+	result := []fs.DirEntry{}
+	for _, name := range names {
+		result = append(result, fs.FileInfoToDirEntry(d.fs.nodes[path.Join(d.node.name, name)].info()))
+	}
+	return result, nil
+}
+
+type resourceFileInfo struct {
+	name  string
+	entry ManifestEntry
+}
+
+func (i *resourceFileInfo) Name() string       { return i.name }
+func (i *resourceFileInfo) Size() int64        { return i.entry.Size }
+func (i *resourceFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *resourceFileInfo) IsDir() bool        { return i.entry.IsDir }
+func (i *resourceFileInfo) Sys() interface{}   { return nil }
+func (i *resourceFileInfo) Mode() fs.FileMode {
+	if i.entry.IsDir {
+		return i.entry.TargetMode.Perm() | fs.ModeDir
+	}
+	return i.entry.TargetMode.Perm()
+}
@@ -0,0 +1,78 @@
+package rootfs
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientResourceFS(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	MustPutTestResource(t, filepath.Join(tempDir, "a.txt"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(tempDir, "b.txt"), []byte("b"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/c.txt"), []byte("c"))
+	MustPutTestResource(t, filepath.Join(tempDir, "sub/nested/d.txt"), []byte("d"))
+
+	executableCommands := []commands.VMInitSerializableCommand{
+		commands.Copy{
+			OriginalCommand: "COPY *.txt /app/",
+			OriginalSource:  "*.txt",
+			Source:          "*.txt",
+			Target:          "/app/",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+		commands.Copy{
+			OriginalCommand: "COPY sub /app/sub",
+			OriginalSource:  "sub",
+			Source:          "sub",
+			Target:          "/app/sub",
+			User:            commands.DefaultUser(),
+			Workdir:         commands.DefaultWorkdir(),
+		},
+	}
+	resolved, err := ResolveFromDirectory(tempDir, executableCommands)
+	assert.Nil(t, err)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: executableCommands,
+		ResourcesResolved:  resolved,
+	})
+	defer cleanupFunc()
+
+	manifest, err := testClient.Manifest(context.Background(), "sub")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(manifest))
+
+	resourceFS, err := testClient.FS(context.Background())
+	assert.Nil(t, err)
+
+	assert.Nil(t, fstest.TestFS(resourceFS, "app/a.txt", "app/b.txt", "app/sub/c.txt", "app/sub/nested/d.txt"))
+
+	contents, err := fs.ReadFile(resourceFS, "app/b.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("b"), contents)
+
+	walked := []string{}
+	assert.Nil(t, fs.WalkDir(resourceFS, "app/sub", func(path string, d fs.DirEntry, err error) error {
+		walked = append(walked, path)
+		return err
+	}))
+	assert.Equal(t, []string{"app/sub", "app/sub/c.txt", "app/sub/nested", "app/sub/nested/d.txt"}, walked)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
@@ -0,0 +1,148 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+// ResourceHeader describes a resource streamed by the server.
+type ResourceHeader struct {
+	ID            string
+	SourcePath    string
+	TargetPath    string
+	TargetMode    fs.FileMode
+	IsDir         bool
+	TargetUser    string
+	TargetWorkdir string
+	Platform      string
+	// Size is the size of the contents announced by the server, -1 if unknown.
+	Size int64
+	// Offset is the offset into the contents the server started streaming at.
+	Offset int64
+}
+
+func decodeResourceHeader(header *proto.ResourceChunk_ResourceHeader) (ResourceHeader, error) {
+	sourcePath, err := DecodeHeaderPath(header.SourcePath, header.EscapedPaths)
+	if err != nil {
+		return ResourceHeader{}, errors.Wrapf(ErrProtocolMismatch, "invalid header source path: %v", err)
+	}
+	targetPath, err := DecodeHeaderPath(header.TargetPath, header.EscapedPaths)
+	if err != nil {
+		return ResourceHeader{}, errors.Wrapf(ErrProtocolMismatch, "invalid header target path: %v", err)
+	}
+	size := int64(-1)
+	if header.HasSize {
+		size = header.Size
+	}
+	return ResourceHeader{
+		ID:            header.Id,
+		SourcePath:    sourcePath,
+		TargetPath:    targetPath,
+		TargetMode:    fs.FileMode(header.FileMode),
+		IsDir:         header.IsDir,
+		TargetUser:    header.TargetUser,
+		TargetWorkdir: header.TargetWorkdir,
+		Platform:      header.Platform,
+		Size:          size,
+		Offset:        header.Offset,
+	}, nil
+}
+
+// OpenResource opens the first resource streamed for the path and returns a reader of its contents.
+// The contents are read from the stream as the reader is consumed, every chunk is verified against its checksum.
+// When the path resolves to multiple resources, the reader ends with the first resource.
+// The reader must be closed, closing the reader ends the stream.
+func (c *defaultClient) OpenResource(ctx context.Context, path string) (io.ReadCloser, ResourceHeader, error) {
+	return c.openResource(ctx, &proto.ResourceRequest{Path: path})
+}
+
+func (c *defaultClient) openResource(ctx context.Context, req *proto.ResourceRequest) (io.ReadCloser, ResourceHeader, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	req.AcceptCompression = acceptedCompression
+	resourceClient, err := c.underlying.Resource(streamCtx, req)
+	if err != nil {
+		cancel()
+		return nil, ResourceHeader{}, fromStatusError(err)
+	}
+	reader := &resourceReader{cancel: cancel, stream: resourceClient}
+	response, err := resourceClient.Recv()
+	if err != nil {
+		cancel()
+		return nil, ResourceHeader{}, errors.Wrap(fromStatusError(err), "failed reading header")
+	}
+	switch tresponse := response.GetPayload().(type) {
+	case *proto.ResourceChunk_Header:
+		header, err := decodeResourceHeader(tresponse.Header)
+		if err != nil {
+			cancel()
+			return nil, ResourceHeader{}, err
+		}
+		reader.id = header.ID
+		reader.sequence.begin(header.ID)
+		return reader, header, nil
+	case *proto.ResourceChunk_Error:
+		cancel()
+		return nil, ResourceHeader{}, fmt.Errorf("server failed streaming resource: %s", tresponse.Error.Message)
+	default:
+		cancel()
+		return nil, ResourceHeader{}, errors.Wrap(ErrProtocolMismatch, "expected resource header")
+	}
+}
+
+type resourceReader struct {
+	cancel   context.CancelFunc
+	stream   proto.RootfsServer_ResourceClient
+	id       string
+	sequence frameSequence
+	pending  []byte
+	err      error
+	// digest is the digest of the contents sent by the server with the end of the resource, if requested:
+	digest string
+}
+
+func (r *resourceReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.pending, r.err = r.next()
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next receives the next chunk of the resource, returns io.EOF when the resource ends.
+func (r *resourceReader) next() ([]byte, error) {
+	response, err := r.stream.Recv()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, errors.Wrap(fromStatusError(err), "failed reading chunk")
+	}
+	if err := r.sequence.next(response); err != nil {
+		return nil, err
+	}
+	switch tresponse := response.GetPayload().(type) {
+	case *proto.ResourceChunk_Chunk:
+		return chunkContents(tresponse.Chunk)
+	case *proto.ResourceChunk_Eof:
+		r.digest = tresponse.Eof.Digest
+		return nil, io.EOF
+	case *proto.ResourceChunk_Error:
+		return nil, fmt.Errorf("server failed streaming resource: %s", tresponse.Error.Message)
+	default:
+		return nil, errors.Wrap(ErrProtocolMismatch, "unexpected resource frame")
+	}
+}
+
+func (r *resourceReader) Close() error {
+	r.cancel()
+	return nil
+}
@@ -0,0 +1,53 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOpenResource(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	largeFileContent := getLargeFileContent(t, 10*1024*1024)
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"large-file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(largeFileContent)), nil
+				}, fs.FileMode(0755), "large-file", "/etc/large-file", commands.DefaultWorkdir(), commands.DefaultUser(), "large-file"),
+			},
+		},
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	reader, header, err := testClient.OpenResource(context.Background(), "large-file")
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/large-file", header.TargetPath)
+	assert.Equal(t, fs.FileMode(0755), header.TargetMode)
+	assert.False(t, header.IsDir)
+
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, largeFileContent, contents)
+
+	_, _, err = testClient.OpenResource(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrResourceNotFound), "expected not found, got: %v", err)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
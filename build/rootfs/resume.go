@@ -0,0 +1,181 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PartialFileSuffix is appended to the path of a file to get the path the file is written at until complete.
+	PartialFileSuffix = ".partial"
+	// ResumeJournalSuffix is appended to the path of a file to get the path of the journal of its partial file.
+	ResumeJournalSuffix = ".journal"
+	// resumeJournalInterval is the number of bytes written between journal updates.
+	resumeJournalInterval = 1024 * 1024
+)
+
+// resumeJournal records the progress of a file write so that an interrupted write can be resumed.
+type resumeJournal struct {
+	// Path is the resource path the file is written from.
+	Path string `json:"path"`
+	// BytesWritten is the length of the prefix of the file written so far.
+	BytesWritten int64 `json:"bytesWritten"`
+	// Digest is the hex encoded SHA-256 digest of the written prefix.
+	Digest string `json:"digest"`
+}
+
+// WriteResourceToFile writes the contents of the first resource served for the path to a file.
+// The contents are written to a partial file next to the file and the partial file is renamed
+// to the file once complete, the file path never holds partially written contents.
+// While the partial file is written, a journal with the number of bytes written and a digest of the written
+// prefix is kept next to the file. If a journal exists when the write starts, the existing prefix
+// of the partial file is verified against the journal and the server is asked to continue from the end of
+// the verified prefix. The server verifies the digest of the prefix against the resource, if the prefix cannot be
+// verified or the resource changed since, the file is written from the start. Before the partial file is renamed,
+// the digest of the whole file is verified against the digest of the resource sent by the server.
+// The journal is removed once the file is complete. The mode of the file is applied explicitly
+// and verified, a mismatch is reported to the server as a warning. The file is synced according to the configured
+// fsync policy. Returns the size of the file.
+func (c *defaultClient) WriteResourceToFile(ctx context.Context, path, filePath string) (int64, error) {
+	partialPath := filePath + PartialFileSuffix
+	journalPath := filePath + ResumeJournalSuffix
+	offset, digest := c.verifiedPrefix(path, partialPath, journalPath)
+
+	req := &proto.ResourceRequest{Path: path, Offset: offset, ContentsDigest: true}
+	if offset > 0 {
+		req.PrefixDigest = hex.EncodeToString(digest.Sum(nil))
+	}
+	reader, header, err := c.openResource(ctx, req)
+	if err != nil && offset > 0 && errors.Is(err, ErrInvalidArgument) {
+		// the resource changed since the prefix was written:
+		c.fetchLogger.Debug("resource not resumable, writing from the start", "path", path, "reason", err)
+		offset, digest = 0, sha256.New()
+		reader, header, err = c.openResource(ctx, &proto.ResourceRequest{Path: path, ContentsDigest: true})
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	if header.IsDir {
+		return 0, errors.Wrapf(ErrInvalidArgument, "resource '%s' is a directory", path)
+	}
+	if header.Offset != offset {
+		// the server does not support resuming, the contents start from the beginning:
+		offset, digest = 0, sha256.New()
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed opening file '%s'", partialPath)
+	}
+	defer file.Close()
+	if err := file.Truncate(offset); err != nil {
+		return 0, errors.Wrapf(err, "failed truncating file '%s'", partialPath)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, errors.Wrapf(err, "failed seeking file '%s'", partialPath)
+	}
+
+	synced := c.syncer.track(file)
+	journal := &resumeJournal{Path: path, BytesWritten: offset}
+	buffer := make([]byte, resumeJournalInterval)
+	for {
+		readBytes, readErr := io.ReadFull(reader, buffer)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return journal.BytesWritten, readErr
+		}
+		if readBytes > 0 {
+			if _, err := file.Write(buffer[0:readBytes]); err != nil {
+				return journal.BytesWritten, errors.Wrapf(err, "failed writing file '%s'", partialPath)
+			}
+			if err := synced.wrote(readBytes); err != nil {
+				return journal.BytesWritten, err
+			}
+			digest.Write(buffer[0:readBytes])
+			journal.BytesWritten = journal.BytesWritten + int64(readBytes)
+			journal.Digest = hex.EncodeToString(digest.Sum(nil))
+		}
+		if readErr != nil {
+			break
+		}
+		if err := writeResumeJournal(journalPath, journal); err != nil {
+			return journal.BytesWritten, err
+		}
+	}
+
+	if served, ok := reader.(*resourceReader); ok && served.digest != "" && served.digest != hex.EncodeToString(digest.Sum(nil)) {
+		// never move contents of a different resource into place:
+		file.Close()
+		os.Remove(partialPath)
+		os.Remove(journalPath)
+		return journal.BytesWritten, errors.Wrapf(ErrChecksumMismatch, "file '%s' does not match the digest of resource '%s'", filePath, path)
+	}
+	if err := synced.finish(filePath); err != nil {
+		return journal.BytesWritten, err
+	}
+	if err := file.Close(); err != nil {
+		return journal.BytesWritten, errors.Wrapf(err, "failed closing file '%s'", partialPath)
+	}
+	if err := applyModeAndOwner(partialPath, header, false); err != nil {
+		return journal.BytesWritten, err
+	}
+	if err := os.Rename(partialPath, filePath); err != nil {
+		return journal.BytesWritten, errors.Wrapf(err, "failed moving file '%s' into place", filePath)
+	}
+	if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+		return journal.BytesWritten, errors.Wrapf(err, "failed removing journal '%s'", journalPath)
+	}
+	c.verifyWritten(path, []writtenEntry{{filePath: filePath, header: header}}, false)
+	return journal.BytesWritten, nil
+}
+
+// verifiedPrefix returns the length of the prefix of the partial file matching the journal and the digest state
+// after the prefix. Returns zero and a fresh digest if there is no journal or the prefix does not match.
+func (c *defaultClient) verifiedPrefix(path, partialPath, journalPath string) (int64, hash.Hash) {
+	digest := sha256.New()
+	journalBytes, err := ioutil.ReadFile(journalPath)
+	if err != nil {
+		return 0, digest
+	}
+	journal := &resumeJournal{}
+	if err := json.Unmarshal(journalBytes, journal); err != nil || journal.Path != path || journal.BytesWritten <= 0 {
+		c.fetchLogger.Debug("ignoring journal", "journal", journalPath)
+		return 0, digest
+	}
+	file, err := os.Open(partialPath)
+	if err != nil {
+		return 0, digest
+	}
+	defer file.Close()
+	if _, err := io.CopyN(digest, file, journal.BytesWritten); err != nil {
+		c.fetchLogger.Debug("file shorter than the journal", "file", partialPath, "journal-bytes", journal.BytesWritten)
+		return 0, sha256.New()
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != journal.Digest {
+		c.fetchLogger.Debug("file prefix does not match the journal", "file", partialPath)
+		return 0, sha256.New()
+	}
+	return journal.BytesWritten, digest
+}
+
+func writeResumeJournal(journalPath string, journal *resumeJournal) error {
+	journalBytes, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	// write and rename so that a crash never leaves a partially written journal behind:
+	if err := ioutil.WriteFile(journalPath+".tmp", journalBytes, 0644); err != nil {
+		return errors.Wrapf(err, "failed writing journal '%s'", journalPath)
+	}
+	return os.Rename(journalPath+".tmp", journalPath)
+}
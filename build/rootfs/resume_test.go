@@ -0,0 +1,203 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+type seekRecordingReader struct {
+	*bytes.Reader
+	m       *sync.Mutex
+	offsets *[]int64
+}
+
+func (r *seekRecordingReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		r.m.Lock()
+		*r.offsets = append(*r.offsets, offset)
+		r.m.Unlock()
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func (r *seekRecordingReader) Close() error {
+	return nil
+}
+
+func TestClientWriteResourceToFileResumes(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	content := getLargeFileContent(t, 3*1024*1024)
+	served := content
+	m := &sync.Mutex{}
+	offsets := []int64{}
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"image": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					m.Lock()
+					defer m.Unlock()
+					return &seekRecordingReader{Reader: bytes.NewReader(served), m: m, offsets: &offsets}, nil
+				}, 0644, "image", "/image", commands.DefaultWorkdir(), commands.DefaultUser(), "image"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	filePath := filepath.Join(tempDir, "image")
+	journalPath := filePath + ResumeJournalSuffix
+
+	// a crash left a verified prefix followed by garbage:
+	prefix := content[0 : 1024*1024+17]
+	MustPutTestResource(t, filePath+PartialFileSuffix, append(append([]byte{}, prefix...), []byte("garbage")...))
+	digest := sha256.Sum256(prefix)
+	assert.Nil(t, writeResumeJournal(journalPath, &resumeJournal{
+		Path:         "image",
+		BytesWritten: int64(len(prefix)),
+		Digest:       hex.EncodeToString(digest[:]),
+	}))
+
+	size, err := testClient.WriteResourceToFile(context.Background(), "image", filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(content)), size)
+	written, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, written)
+	for _, leftover := range []string{journalPath, filePath + PartialFileSuffix} {
+		_, statErr := os.Stat(leftover)
+		assert.True(t, os.IsNotExist(statErr), leftover)
+	}
+
+	// a prefix not matching the journal is written from the start:
+	MustPutTestResource(t, filePath+PartialFileSuffix, []byte("corrupted prefix"))
+	assert.Nil(t, writeResumeJournal(journalPath, &resumeJournal{
+		Path:         "image",
+		BytesWritten: int64(len(prefix)),
+		Digest:       hex.EncodeToString(digest[:]),
+	}))
+	_, err = testClient.WriteResourceToFile(context.Background(), "image", filePath)
+	assert.Nil(t, err)
+	written, err = ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, written)
+
+	m.Lock()
+	assert.Empty(t, offsets, "expected the prefix read to verify it rather than skipped")
+	m.Unlock()
+
+	// a resource changed since the prefix was written is written from the start:
+	MustPutTestResource(t, filePath+PartialFileSuffix, prefix)
+	assert.Nil(t, writeResumeJournal(journalPath, &resumeJournal{
+		Path:         "image",
+		BytesWritten: int64(len(prefix)),
+		Digest:       hex.EncodeToString(digest[:]),
+	}))
+	changed := getLargeFileContent(t, int64(len(content)))
+	m.Lock()
+	served = changed
+	m.Unlock()
+	_, err = testClient.WriteResourceToFile(context.Background(), "image", filePath)
+	assert.Nil(t, err)
+	written, err = ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, changed, written, "expected the changed resource written whole")
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestServerResourceOffsetBeyondEnd(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("short"))), nil
+				}, 0644, "file", "/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	_, _, err := testClient.(*defaultClient).openResource(context.Background(), &proto.ResourceRequest{Path: "file", Offset: 10})
+	assert.Equal(t, codes.InvalidArgument, StatusCode(err))
+
+	reader, header, err := testClient.(*defaultClient).openResource(context.Background(), &proto.ResourceRequest{Path: "file", Offset: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), header.Offset)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ort"), contents)
+	reader.Close()
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestServerVerifiesResumedPrefix(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		spool, err := NewSpool(&SpoolConfig{ChunkSize: 2, Compress: compress})
+		assert.Nil(t, err)
+		for _, spooled := range []*Spool{nil, spool} {
+			logger := hclog.Default()
+			logger.SetLevel(hclog.Debug)
+			testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+				ExecutableCommands: []commands.VMInitSerializableCommand{},
+				ResourcesResolved: Resources{
+					"file": []resources.ResolvedResource{
+						resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+							return ioutil.NopCloser(bytes.NewReader([]byte("short"))), nil
+						}, 0644, "file", "/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+					},
+				},
+				Spool: spooled,
+			})
+
+			// the server refuses an offset after a prefix of other contents:
+			otherDigest := sha256.Sum256([]byte("xx"))
+			_, _, err = testClient.(*defaultClient).openResource(context.Background(), &proto.ResourceRequest{Path: "file", Offset: 2,
+				PrefixDigest: hex.EncodeToString(otherDigest[:])})
+			assert.Equal(t, codes.InvalidArgument, StatusCode(err), "expected a prefix of other contents refused")
+
+			prefixDigest := sha256.Sum256([]byte("sh"))
+			reader, _, err := testClient.(*defaultClient).openResource(context.Background(), &proto.ResourceRequest{Path: "file", Offset: 2,
+				PrefixDigest: hex.EncodeToString(prefixDigest[:]), ContentsDigest: true})
+			assert.Nil(t, err)
+			contents, err := ioutil.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("ort"), contents)
+			contentsDigest := sha256.Sum256([]byte("short"))
+			assert.Equal(t, hex.EncodeToString(contentsDigest[:]), reader.(*resourceReader).digest,
+				"expected the digest of the whole contents")
+			reader.Close()
+
+			assert.Nil(t, testClient.Success())
+			<-testServer.FinishedNotify()
+			cleanupFunc()
+		}
+		assert.Nil(t, spool.Remove())
+	}
+}
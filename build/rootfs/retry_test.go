@@ -0,0 +1,74 @@
+package rootfs
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAndStreamWithRetry(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("flaky")},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanMessages := make(chan interface{}, 64)
+	go func() {
+		for message := range srv.OnMessage() {
+			switch message.(type) {
+			case *ClientMsgCommandRetried, *ClientMsgCommandFinished:
+				chanMessages <- message
+			}
+		}
+	}()
+
+	// fails with a transient error twice, then succeeds:
+	counter := filepath.Join(t.TempDir(), "counter")
+	newCmd := func() *exec.Cmd {
+		return exec.Command("sh", "-c", `echo x >> `+counter+`; if [ $(wc -l < `+counter+`) -lt 3 ]; then echo "E: Failed to fetch" >&2; exit 100; fi`)
+	}
+	policy := &commands.RetryPolicy{Attempts: 5, StderrPatterns: []string{"Failed to fetch"}}
+
+	assert.Nil(t, testClient.CommandStarted(0))
+	attempts, err := testClient.RunAndStreamWithRetry(context.Background(), 0, policy, newCmd)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Nil(t, testClient.CommandFinished(0, err))
+
+	for _, expectedAttempt := range []int{1, 2} {
+		retried, ok := (<-chanMessages).(*ClientMsgCommandRetried)
+		assert.True(t, ok)
+		assert.Equal(t, expectedAttempt, retried.Attempt)
+		assert.Equal(t, "exit status 100", retried.Error.Error())
+	}
+	finished, ok := (<-chanMessages).(*ClientMsgCommandFinished)
+	assert.True(t, ok)
+	assert.Equal(t, 3, finished.Attempts)
+	assert.Equal(t, 2, srv.Summary().CommandRetries)
+
+	// a failure the policy does not retry:
+	attempts, err = testClient.RunAndStreamWithRetry(context.Background(), 0, policy, func() *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// without a policy:
+	attempts, err = testClient.RunAndStreamWithRetry(context.Background(), 0, nil, func() *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 100")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+}
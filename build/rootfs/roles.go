@@ -0,0 +1,148 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ClientRole identifies what a connected client is allowed to do.
+type ClientRole int
+
+const (
+	// ClientRoleExecutor is the role of the client executing the build.
+	// An executor may call every RPC.
+	ClientRoleExecutor ClientRole = iota
+	// ClientRoleObserver is the role of a helper client collaborating on the build.
+	// An observer may only read: commands, environment, resources, server events and build logs.
+	// An observer cannot report progress or output and cannot finish the build with Success or Abort.
+	ClientRoleObserver
+)
+
+func (r ClientRole) String() string {
+	switch r {
+	case ClientRoleExecutor:
+		return "executor"
+	case ClientRoleObserver:
+		return "observer"
+	default:
+		return "unknown"
+	}
+}
+
+// ClientRoleResolver resolves the role of a client from the verified client certificate.
+type ClientRoleResolver func(cert *x509.Certificate) ClientRole
+
+// observerMethods lists the RPCs an observer is allowed to call.
+var observerMethods = map[string]struct{}{
+	"/proto.RootfsServer/Capabilities":       {},
+	"/proto.RootfsServer/Commands":           {},
+	"/proto.RootfsServer/Environment":        {},
+	"/proto.RootfsServer/Manifest":           {},
+	"/proto.RootfsServer/Ping":               {},
+	"/proto.RootfsServer/Resource":           {},
+	"/proto.RootfsServer/ResourceBlockDelta": {},
+	"/proto.RootfsServer/ResourceDelta":      {},
+	"/proto.RootfsServer/Status":             {},
+	"/proto.RootfsServer/Watch":              {},
+	"/proto.RootfsServer/WatchLogs":          {},
+}
+
+type clientRoleContextKey struct{}
+
+// clientRoleFromContext returns the role of the client calling an RPC.
+func clientRoleFromContext(ctx context.Context) ClientRole {
+	if role, ok := ctx.Value(clientRoleContextKey{}).(ClientRole); ok {
+		return role
+	}
+	return ClientRoleExecutor
+}
+
+// clientRoles resolves client roles and authorizes RPCs according to the resolved role.
+type clientRoles struct {
+	m               sync.Mutex
+	observerSerials map[string]struct{}
+	resolver        ClientRoleResolver
+	unverified      ClientRole
+}
+
+// newClientRoles returns the client roles of the resolver, a client without a verified certificate
+// is an observer unless allowUnverifiedExecutor is true.
+func newClientRoles(resolver ClientRoleResolver, allowUnverifiedExecutor bool) *clientRoles {
+	unverified := ClientRoleObserver
+	if allowUnverifiedExecutor {
+		unverified = ClientRoleExecutor
+	}
+	return &clientRoles{observerSerials: map[string]struct{}{}, resolver: resolver, unverified: unverified}
+}
+
+// addObserver registers the serial number of an observer certificate issued by the embedded CA.
+func (r *clientRoles) addObserver(serial *big.Int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.observerSerials[serial.String()] = struct{}{}
+}
+
+// role returns the role of the client identified by the peer in the context.
+// A client without a verified certificate, for example over a transport without TLS, has the unverified role.
+func (r *clientRoles) role(ctx context.Context) ClientRole {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return r.unverified
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return r.unverified
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	if r.resolver != nil {
+		return r.resolver(cert)
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, ok := r.observerSerials[cert.SerialNumber.String()]; ok {
+		return ClientRoleObserver
+	}
+	return ClientRoleExecutor
+}
+
+func (r *clientRoles) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	role := r.role(ctx)
+	if role != ClientRoleExecutor {
+		if _, ok := observerMethods[canonicalMethod(fullMethod)]; !ok {
+			return ctx, fmt.Errorf("%w: %s not allowed for client role %s", ErrUnauthorized, fullMethod, role)
+		}
+	}
+	return context.WithValue(ctx, clientRoleContextKey{}, role), nil
+}
+
+func (r *clientRoles) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := r.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (r *clientRoles) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := r.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &roleServerStream{ServerStream: ss, ctx: ctx})
+}
+
+type roleServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *roleServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,113 @@
+package rootfs
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/stats"
+)
+
+// RPCStats contains the counters of the calls of an RPC.
+type RPCStats struct {
+	// Calls is the number of calls started.
+	Calls int
+	// Errors is the number of calls finished with an error.
+	Errors int
+	// MessagesReceived is the number of messages received by the server.
+	MessagesReceived int
+	// MessagesSent is the number of messages sent by the server.
+	MessagesSent int
+	// BytesReceived is the number of payload bytes received by the server.
+	BytesReceived int64
+	// BytesSent is the number of payload bytes sent by the server.
+	BytesSent int64
+}
+
+// RPCStatsSnapshot contains the counters of the calls keyed by the RPC name, for example Resource.
+// The calls of every wire version count under the same name.
+type RPCStatsSnapshot map[string]RPCStats
+
+// Total returns the counters of all calls.
+func (s RPCStatsSnapshot) Total() RPCStats {
+	total := RPCStats{}
+	for _, rpcStats := range s {
+		total.Calls = total.Calls + rpcStats.Calls
+		total.Errors = total.Errors + rpcStats.Errors
+		total.MessagesReceived = total.MessagesReceived + rpcStats.MessagesReceived
+		total.MessagesSent = total.MessagesSent + rpcStats.MessagesSent
+		total.BytesReceived = total.BytesReceived + rpcStats.BytesReceived
+		total.BytesSent = total.BytesSent + rpcStats.BytesSent
+	}
+	return total
+}
+
+// RPCStatsRecorder is a gRPC stats handler counting the calls of the server in memory,
+// set it as GRPCServiceConfig.StatsHandler.
+type RPCStatsRecorder struct {
+	m     sync.Mutex
+	stats RPCStatsSnapshot
+}
+
+// NewRPCStatsRecorder returns a new recorder without any calls counted.
+func NewRPCStatsRecorder() *RPCStatsRecorder {
+	return &RPCStatsRecorder{stats: RPCStatsSnapshot{}}
+}
+
+// Snapshot returns the counters of the calls so far.
+func (r *RPCStatsRecorder) Snapshot() RPCStatsSnapshot {
+	r.m.Lock()
+	defer r.m.Unlock()
+	result := RPCStatsSnapshot{}
+	for name, rpcStats := range r.stats {
+		result[name] = rpcStats
+	}
+	return result
+}
+
+type rpcNameKey struct{}
+
+// TagRPC implements stats.Handler.
+func (r *RPCStatsRecorder) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	name := info.FullMethodName
+	if index := strings.LastIndex(name, "/"); index > -1 {
+		name = name[index+1:]
+	}
+	return context.WithValue(ctx, rpcNameKey{}, name)
+}
+
+// HandleRPC implements stats.Handler.
+func (r *RPCStatsRecorder) HandleRPC(ctx context.Context, rpcStats stats.RPCStats) {
+	name, ok := ctx.Value(rpcNameKey{}).(string)
+	if !ok {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	counters := r.stats[name]
+	switch event := rpcStats.(type) {
+	case *stats.Begin:
+		counters.Calls = counters.Calls + 1
+	case *stats.End:
+		if event.Error != nil {
+			counters.Errors = counters.Errors + 1
+		}
+	case *stats.InPayload:
+		counters.MessagesReceived = counters.MessagesReceived + 1
+		counters.BytesReceived = counters.BytesReceived + int64(event.Length)
+	case *stats.OutPayload:
+		counters.MessagesSent = counters.MessagesSent + 1
+		counters.BytesSent = counters.BytesSent + int64(event.Length)
+	default:
+		return
+	}
+	r.stats[name] = counters
+}
+
+// TagConn implements stats.Handler.
+func (r *RPCStatsRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (r *RPCStatsRecorder) HandleConn(context.Context, stats.ConnStats) {}
@@ -0,0 +1,44 @@
+package rootfs
+
+import (
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestServerRPCStats(t *testing.T) {
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, hclog.Default(), &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Ping())
+	assert.Nil(t, testClient.Ping())
+	assert.Nil(t, testClient.StdOut([]string{"line"}))
+	assert.NotNil(t, testClient.CommandStarted(10))
+
+	chanLines, err := testClient.WatchLogs()
+	assert.Nil(t, err)
+
+	stats := testServer.RPCStats()
+	assert.Equal(t, 2, stats["Ping"].Calls)
+	assert.Equal(t, 2, stats["Ping"].MessagesReceived)
+	assert.Equal(t, 2, stats["Ping"].MessagesSent)
+	assert.Equal(t, 0, stats["Ping"].Errors)
+	assert.Equal(t, 1, stats["StdOut"].Calls)
+	assert.True(t, stats["StdOut"].BytesReceived > 0)
+	assert.Equal(t, 1, stats["Ack"].Calls)
+	assert.Equal(t, 1, stats["Ack"].Errors)
+	assert.Equal(t, 0, stats["Resource"].Calls)
+
+	total := stats.Total()
+	assert.Equal(t, 1, total.Errors)
+	assert.True(t, total.Calls >= 4)
+
+	testServer.Stop()
+	for range chanLines {
+	}
+}
@@ -0,0 +1,132 @@
+package rootfs
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ScratchDirConfig configures a scratch directory.
+type ScratchDirConfig struct {
+	// Location is the directory the scratch directory is created in.
+	// Default is the system temporary directory.
+	Location string
+	// MaxSizeBytes is the maximum number of bytes written to files created with Create.
+	// Zero means no limit.
+	MaxSizeBytes int64
+	// RetainOnFailure keeps the scratch directory when the build did not succeed,
+	// so that the contents can be inspected for debugging.
+	RetainOnFailure bool
+}
+
+// ScratchDir is a temporary directory for resources which need local storage
+// while the build is in progress, for example downloaded remote or extracted archive resources.
+// The server removes the scratch directory of the work context when it stops.
+type ScratchDir struct {
+	m        sync.Mutex
+	config   *ScratchDirConfig
+	path     string
+	removed  bool
+	usedSize int64
+}
+
+// NewScratchDir creates a new scratch directory.
+func NewScratchDir(cfg *ScratchDirConfig) (*ScratchDir, error) {
+	path, err := ioutil.TempDir(cfg.Location, "firebuild-scratch-")
+	if err != nil {
+		return nil, fmt.Errorf("scratch directory failed: could not create in '%s', reason: %v", cfg.Location, err)
+	}
+	return &ScratchDir{config: cfg, path: path}, nil
+}
+
+// Path returns the path of the scratch directory.
+func (d *ScratchDir) Path() string {
+	return d.path
+}
+
+// TempDir creates a new directory in the scratch directory.
+func (d *ScratchDir) TempDir(pattern string) (string, error) {
+	return ioutil.TempDir(d.path, pattern)
+}
+
+// Create creates a new file in the scratch directory. Writes to the file are accounted
+// against the maximum size of the scratch directory, a write exceeding the maximum size
+// fails with ErrResourceExhausted.
+func (d *ScratchDir) Create(pattern string) (*ScratchFile, error) {
+	file, err := ioutil.TempFile(d.path, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &ScratchFile{File: file, dir: d}, nil
+}
+
+// UsedSize returns the number of bytes written to files created with Create.
+func (d *ScratchDir) UsedSize() int64 {
+	d.m.Lock()
+	defer d.m.Unlock()
+	return d.usedSize
+}
+
+// Size returns the number of bytes of all regular files in the scratch directory.
+func (d *ScratchDir) Size() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(d.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size = size + info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// Cleanup removes the scratch directory. When the build failed and the scratch directory
+// is configured to be retained on failure, the directory is not removed and false is returned.
+func (d *ScratchDir) Cleanup(failed bool) (bool, error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.removed {
+		return true, nil
+	}
+	if failed && d.config.RetainOnFailure {
+		return false, nil
+	}
+	if err := os.RemoveAll(d.path); err != nil {
+		return false, err
+	}
+	d.removed = true
+	return true, nil
+}
+
+func (d *ScratchDir) reserve(n int64) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.config.MaxSizeBytes > 0 && d.usedSize+n > d.config.MaxSizeBytes {
+		return fmt.Errorf("%w: scratch directory maximum size of %d bytes exceeded", ErrResourceExhausted, d.config.MaxSizeBytes)
+	}
+	d.usedSize = d.usedSize + n
+	return nil
+}
+
+// ScratchFile is a file in the scratch directory.
+type ScratchFile struct {
+	*os.File
+	dir *ScratchDir
+}
+
+// Write writes to the file if the scratch directory maximum size allows.
+func (f *ScratchFile) Write(p []byte) (int, error) {
+	if err := f.dir.reserve(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
@@ -0,0 +1,65 @@
+package rootfs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScratchDirSizeAccounting(t *testing.T) {
+	location, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(location)
+
+	scratchDir, err := NewScratchDir(&ScratchDirConfig{Location: location, MaxSizeBytes: 10, RetainOnFailure: true})
+	assert.Nil(t, err)
+
+	file, err := scratchDir.Create("resource-")
+	assert.Nil(t, err)
+	_, err = file.Write([]byte("12345678"))
+	assert.Nil(t, err)
+	_, err = file.Write([]byte("123"))
+	assert.True(t, errors.Is(err, ErrResourceExhausted), "expected the maximum size to be enforced, got: %v", err)
+	assert.Nil(t, file.Close())
+
+	assert.Equal(t, int64(8), scratchDir.UsedSize())
+	size, err := scratchDir.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), size)
+
+	removed, err := scratchDir.Cleanup(true)
+	assert.Nil(t, err)
+	assert.False(t, removed, "expected the scratch directory to be retained on failure")
+	_, err = os.Stat(scratchDir.Path())
+	assert.Nil(t, err)
+}
+
+func TestScratchDirRemovedOnStop(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	scratchDir, err := NewScratchDir(&ScratchDirConfig{RetainOnFailure: true})
+	assert.Nil(t, err)
+	defer os.RemoveAll(scratchDir.Path())
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+		ScratchDir:         scratchDir,
+	})
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	assert.Nil(t, testClient.Success())
+	srv.Stop()
+
+	_, err = os.Stat(scratchDir.Path())
+	assert.True(t, os.IsNotExist(err), "expected the scratch directory to be removed after success")
+}
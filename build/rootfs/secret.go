@@ -0,0 +1,125 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Secret serves the contents of a sensitive resource in a single message. The contents are read
+// into memory, never spooled, and must fit in the message size the client receives.
+func (impl *serverImpl) Secret(ctx context.Context, req *proto.SecretRequest) (*proto.SecretResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.SecretResponse{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	ress, _ := impl.lookupResources(req.Path)
+	for _, resource := range ress {
+		if !resources.PlatformMatches(resources.PlatformOf(resource), impl.serverCtx.Platform) {
+			continue
+		}
+		if req.TargetPath != "" && resource.TargetPath() != req.TargetPath {
+			continue
+		}
+		if !resources.IsSensitive(resource) || resource.IsDir() {
+			return &proto.SecretResponse{}, withResourcePath(fmt.Errorf("%w: '%s/%s' is not a secret", ErrInvalidArgument, req.Stage, req.Path), req.Path, req.Stage)
+		}
+
+		reader, err := impl.resourceContents(ctx, resource)
+		if err != nil {
+			return &proto.SecretResponse{}, withResourcePath(err, req.Path, req.Stage)
+		}
+		maxSize := impl.serviceConfig.SafeClientMaxRecvMsgSize()
+		contents, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+		reader.Close()
+		if err != nil {
+			return &proto.SecretResponse{}, withResourcePath(err, req.Path, req.Stage)
+		}
+		if len(contents) > maxSize {
+			return &proto.SecretResponse{}, withResourcePath(fmt.Errorf("%w: secret larger than %d bytes", ErrInvalidArgument, maxSize), req.Path, req.Stage)
+		}
+
+		header, err := fileResourceHeader(resource, uuid.Must(uuid.NewV4()).String())
+		if err != nil {
+			return &proto.SecretResponse{}, withResourcePath(err, req.Path, req.Stage)
+		}
+		impl.serverCtx.applyHeaderDefaults(header)
+		header.Size = int64(len(contents))
+		header.HasSize = true
+
+		impl.countServed(1, len(contents))
+		impl.emit(&ControlMsgResourceServed{
+			Path:      req.Path,
+			Stage:     req.Stage,
+			Resources: 1,
+			Bytes:     int64(len(contents)),
+		})
+		return &proto.SecretResponse{Header: header, Contents: contents}, nil
+	}
+	return &proto.SecretResponse{}, withResourcePath(fmt.Errorf("%w: '%s/%s'", ErrResourceNotFound, req.Stage, req.Path), req.Path, req.Stage)
+}
+
+// refuseSensitive rejects a sensitive resource requested with a streaming RPC:
+// the client may spool and journal streamed contents, secrets are read with Secret.
+func refuseSensitive(resource resources.ResolvedResource, path, stage string) error {
+	if resources.IsSensitive(resource) {
+		return withResourcePath(fmt.Errorf("%w: '%s/%s' is a secret, read it with Secret", ErrInvalidArgument, stage, path), path, stage)
+	}
+	return nil
+}
+
+// keepLeasesAlive renews the leases of the resolved resources until the server stops.
+func (impl *serverImpl) keepLeasesAlive() {
+	leases := map[resources.Lease]struct{}{}
+	for _, resourceList := range impl.serverCtx.ResourcesResolved {
+		for _, resource := range resourceList {
+			if lease := resources.LeaseOf(resource); lease != nil {
+				leases[lease] = struct{}{}
+			}
+		}
+	}
+	for lease := range leases {
+		lease := lease
+		impl.routines.goTracked(func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-impl.chanStopped:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			if err := lease.KeepAlive(ctx); err != nil {
+				impl.resourceLogger.Error("failed renewing the lease of resources", "reason", err)
+			}
+		})
+	}
+}
+
+// Secret reads the contents of a secret resource into memory. The contents are never spooled
+// or journaled, the caller decides where they go, for example a tmpfs mount.
+func (c *defaultClient) Secret(ctx context.Context, path string) ([]byte, ResourceHeader, error) {
+	response, err := c.underlying.Secret(ctx, &proto.SecretRequest{Path: path})
+	if err != nil {
+		return nil, ResourceHeader{}, fromStatusError(err)
+	}
+	if response.Header == nil {
+		return nil, ResourceHeader{}, errors.Wrap(ErrProtocolMismatch, "expected secret header")
+	}
+	header, err := decodeResourceHeader(response.Header)
+	if err != nil {
+		return nil, ResourceHeader{}, err
+	}
+	return response.Contents, header, nil
+}
@@ -0,0 +1,107 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLease struct {
+	chanStarted chan struct{}
+	chanEnded   chan struct{}
+}
+
+func (l *testLease) KeepAlive(ctx context.Context) error {
+	close(l.chanStarted)
+	<-ctx.Done()
+	close(l.chanEnded)
+	return nil
+}
+
+type testSecretResource struct {
+	resources.ResolvedResource
+	lease resources.Lease
+}
+
+func (r *testSecretResource) Sensitive() bool {
+	return true
+}
+
+func (r *testSecretResource) Lease() resources.Lease {
+	return r.lease
+}
+
+func (r *testSecretResource) Unwrap() resources.ResolvedResource {
+	return r.ResolvedResource
+}
+
+func TestServerSecret(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	lease := &testLease{chanStarted: make(chan struct{}), chanEnded: make(chan struct{})}
+	newSecret := func(targetPath string) resources.ResolvedResource {
+		return &testSecretResource{
+			ResolvedResource: resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader([]byte("s3cr3t"))), nil
+			}, fs.FileMode(0400), "vault://app/database", targetPath, commands.DefaultWorkdir(), commands.DefaultUser(), "vault://app/database"),
+			lease: lease,
+		}
+	}
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"password":       []resources.ResolvedResource{newSecret("/run/secrets/password")},
+			"password-again": []resources.ResolvedResource{newSecret("/run/secrets/password-again")},
+			"plain": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("plain"))), nil
+				}, fs.FileMode(0644), "plain", "/etc/plain", commands.DefaultWorkdir(), commands.DefaultUser(), "plain"),
+			},
+		},
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	select {
+	case <-lease.chanStarted:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected the lease to be renewed while the build runs")
+	}
+
+	contents, header, err := testClient.Secret(context.Background(), "password")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("s3cr3t"), contents)
+	assert.Equal(t, "/run/secrets/password", header.TargetPath)
+	assert.Equal(t, fs.FileMode(0400), header.TargetMode)
+
+	// secrets are not streamed:
+	_, _, err = testClient.OpenResource(context.Background(), "password")
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "expected invalid argument, got: %v", err)
+	// streamed resources are not secrets:
+	_, _, err = testClient.Secret(context.Background(), "plain")
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "expected invalid argument, got: %v", err)
+	_, _, err = testClient.Secret(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrResourceNotFound), "expected not found, got: %v", err)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+	testServer.Stop()
+
+	select {
+	case <-lease.chanEnded:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected the lease renewal to end when the server stops")
+	}
+}
@@ -1,8 +1,12 @@
 package rootfs
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -10,9 +14,14 @@ import (
 	"github.com/combust-labs/firebuild-shared/build/commands"
 	"github.com/combust-labs/firebuild-shared/build/resources"
 	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/gofrs/uuid"
 	"github.com/hashicorp/go-hclog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
 )
 
 const (
@@ -20,6 +29,8 @@ const (
 	DefaultGracefulStopTimeoutMillis = 10000
 	// DefaultMaxRecvMsgSize is the default max recv msg size for the GRPC server.
 	DefaultMaxMsgSize = 4 * 1024 * 1024
+	// DefaultPortForwardTimeoutMillis is the default time to wait for the port forward decision.
+	DefaultPortForwardTimeoutMillis = 10000
 	// DefaultServerName is the default ServerName.
 	DefaultServerName = "localhost"
 )
@@ -28,17 +39,115 @@ const (
 type GRPCServiceConfig struct {
 	// Host and port to bind on
 	BindHostPort string
+	// When true, a client without a verified client certificate, for example of an embedded server
+	// on a transport without TLS, is an executor. By default such a client is an observer.
+	AllowUnverifiedExecutor bool
+	// Resolves the role of a client from the verified client certificate.
+	// Required to identify observers when TLSConfigServer is given,
+	// every client with a verified certificate is an executor when not set.
+	ClientRoleResolver ClientRoleResolver
+	// When true, the service is additionally served with the Connect protocol on the same listener,
+	// so tools without a gRPC client, for example a browser or curl, can query the server.
+	// The listener is then served by an HTTP server terminating TLS itself, HTTP/1.1 and HTTP/2 are accepted.
+	ConnectProtocol bool
+	// When set together with ConnectProtocol, the Connect protocol and gRPC are additionally served without TLS
+	// on this host and port, with HTTP/1.1 and HTTP/2 over cleartext (h2c), so local tools need no client certificate.
+	// Clients of this listener are unverified and observers unless AllowUnverifiedExecutor is set.
+	// The bound address is written back when the server is ready.
+	ConnectPlaintextBindHostPort string
+	// Number of guest executors the work context is broadcast to. Every guest receives the identical
+	// commands and resources and identifies itself with GRPCClientConfig.GuestID. The build finishes
+	// when every guest has finished and succeeds when every guest succeeded.
+	// Zero or one serves a single guest.
+	BroadcastGuests int
+	// Maximum duration of the build. When exceeded, the client is cancelled,
+	// a ControlMsgBuildTimeout event is emitted and the server stops.
+	// Zero means no timeout.
+	BuildTimeout time.Duration
+	// When true, the server computes the WorkContext.Fingerprint in the background when it starts
+	// and reports it in BuildSummary.Fingerprint. Computing the fingerprint reads every resource once.
+	ComputeFingerprint bool
+	// When greater than zero, the file chunks of the resources of at least CompressionThreshold bytes are gzipped
+	// for the clients accepting CompressionGzip, unless compression does not make a chunk smaller.
+	// Batched files are not compressed. Zero serves uncompressed chunks.
+	CompressionThreshold int
+	// When true, an aborted client is asked to keep the session open
+	// and serve an interactive debug session over the Debug RPC.
+	DebugOnAbort bool
+	// Default maximum duration of a single command, measured between the client
+	// acknowledging the start and the finish of the command. When exceeded,
+	// the client is cancelled and a ControlMsgCommandTimeout event is emitted.
+	// Zero means no timeout. WorkContext.CommandTimeouts take precedence.
+	DefaultCommandTimeout time.Duration
 	// When no TLSConfigServer is given, server uses an embedded CA.
 	// This property sets the RSA key size, default is 4096 bytes.
 	EmbeddedCAKeySize int
+	// Retries of opening and reading the served files on transient file system errors,
+	// for example of NFS backed build contexts. No retries by default.
+	FileRetry FileRetryPolicy
+	// When greater than zero, one in every FrameTraceSampling frames of the resource streams is traced:
+	// the size of the frame, the time it was queued before it was sent and the time it took to send.
+	// The traces are reported in ServerStats.FrameTraces. Zero traces no frames.
+	FrameTraceSampling int
 	// How long to wait for the GRPC server to shutdown
 	// before stopping forcefully.
 	GracefulStopTimeoutMillis int
+	// Interval of the keepalive pings the server sends on an idle connection. A connection not answering
+	// a ping within the interval is closed. Clients may ping as often as every half interval.
+	// Zero applies the gRPC defaults: no pings and clients pinging at most every five minutes.
+	KeepaliveInterval time.Duration
+	// Optional path of the file the server journals the session state to: the acknowledged commands,
+	// the served resources and the offsets of the received logs. When the journal exists at start
+	// and belongs to the build of the work context, the server resumes the session so that the client
+	// of a server which restarted mid-build can reconnect and continue. A work context without a build ID
+	// takes the build ID of the journal. Not used for broadcast builds.
+	JournalPath string
+	// Optional levels of the loggers of the server subsystems, for example LogSubsystemServerResource.
+	LogLevels LogLevels
+	// Defines how the stdout and stderr lines received from the client are processed
+	// before they are emitted, published to the log subscribers and counted. Default is LogModeRaw.
+	LogMode LogMode
+	// Maximum length of a stdout or stderr line received from the client, longer lines are truncated
+	// and end with a truncation marker. Zero applies DefaultMaxLogLineBytes, a negative value disables the limit.
+	MaxLogLineBytes int
+	// Maximum number of bytes buffered for chunks across all active resource streams.
+	// Every streamed resource reserves a chunk buffer, streams wait until the budget allows.
+	// Zero means no limit.
+	MaxBufferedChunkBytes int64
 	// MaxRecvMsgSize returns a ServerOption to set the max message size in bytes the server can receive.
 	// If this is not set, gRPC uses the default 4MB.
 	MaxMsgSize int
+	// How many observer client TLS configs to create with the embedded CA.
+	// Observers may read from the build but cannot finish it.
+	// Used only when TLSConfigServer is not given.
+	ObserverClients int
+	// How long to wait for the embedding application to accept or reject
+	// a port forward request before rejecting it.
+	PortForwardTimeoutMillis int
+	// Optionally replaces the secrets in the stdout and stderr lines, the command errors and the abort error
+	// received from the client, before they are emitted, published to the log subscribers or stored in the summary.
+	// Lines are redacted before they are truncated.
+	Redactor *Redactor
 	// Identifies the GRPC server. This setting is required when doing mTLS.
 	ServerName string
+	// Optionally receives the gRPC stats of the server, for example an RPCStatsRecorder counting the calls.
+	// Not used by a server embedded in an externally managed grpc.Server.
+	StatsHandler stats.Handler
+	// When true, directory resources are walked in an explicitly sorted order:
+	// entries of every directory in lexicographical order of their names,
+	// every directory before any of its children.
+	SortedDirectoryWalk bool
+	// How long the server keeps accepting the late stdout and stderr lines of the client after the build
+	// succeeded, for example the final log flush of the guest racing the Success call.
+	// Stop called within the window waits until the window has passed. Zero stops immediately.
+	SuccessLinger time.Duration
+	// Defines how files of directory resources which cannot be opened and directories which cannot be listed
+	// are handled. Default is UnreadableFileFail. With UnreadableFileWarn, every skipped entry is logged
+	// and a ControlMsgResourceEntrySkipped event is emitted.
+	UnreadableFiles UnreadableFilePolicy
+	// Wire versions of the service served by the server, all versions when empty.
+	// A host drops WireVersionV1Alpha once no guest speaks it any longer.
+	WireVersions []string
 	// Contains the GRPC server configuration.
 	// If not provided, a runtime, build only CA and TLS context will be created.
 	TLSConfigServer *tls.Config
@@ -47,6 +156,12 @@ type GRPCServiceConfig struct {
 	// The client config is obtained from auto-generated CA.
 	// If the TLSConfigServer was provided, the client config will be always nil.
 	TLSConfigClient *tls.Config
+	// TLSCAPEMChain contains the PEM encoded certificates of the auto-generated CA,
+	// for example to deliver to the guest out of band, only when TLSConfigServer was not given.
+	TLSCAPEMChain []string
+	// TLSConfigObserverClients contains the ObserverClients tls.Configs for observer clients,
+	// obtained from auto-generated CA only when TLSConfigServer was not given.
+	TLSConfigObserverClients []*tls.Config
 }
 
 // SafeClientMaxRecvMsgSize returns the maximum safe payload size to send by the client.
@@ -56,21 +171,32 @@ func (c *GRPCServiceConfig) SafeClientMaxRecvMsgSize() int {
 
 // WithDefaultsApplied applies default configuration values to unconfigured properties.
 func (c *GRPCServiceConfig) WithDefaultsApplied() *GRPCServiceConfig {
+	if c.MaxLogLineBytes == 0 {
+		c.MaxLogLineBytes = DefaultMaxLogLineBytes
+	}
 	if c.MaxMsgSize == 0 {
 		c.MaxMsgSize = DefaultMaxMsgSize
 	}
 	if c.GracefulStopTimeoutMillis == 0 {
 		c.GracefulStopTimeoutMillis = DefaultGracefulStopTimeoutMillis
 	}
+	if c.PortForwardTimeoutMillis == 0 {
+		c.PortForwardTimeoutMillis = DefaultPortForwardTimeoutMillis
+	}
 	if c.ServerName == "" {
 		c.ServerName = DefaultServerName
 	}
+	if len(c.WireVersions) == 0 {
+		c.WireVersions = WireVersions()
+	}
 	return c
 }
 
 // ServerProvider defines a GRPC server behaviour.
 type ServerProvider interface {
 	EventProvider
+	// Cancel tells the client watching the server to stop the build immediately.
+	Cancel(reason error)
 	// Starts the server with a given work context.
 	Start(serverCtx *WorkContext)
 	// Stops the server, if the server is started.
@@ -79,8 +205,18 @@ type ServerProvider interface {
 	ReadyNotify() <-chan struct{}
 	// FailedNotify returns a channel that will be contain the error if the server has failed to start.
 	FailedNotify() <-chan error
+	// Stats returns the current resource usage of the server.
+	Stats() ServerStats
 	// StoppedNotify returns a channel that will be closed when the server has stopped.
 	StoppedNotify() <-chan struct{}
+	// SubscribeLogs returns a channel receiving the stdout and stderr lines of the build
+	// and a function ending the subscription. Subscribers do not affect OnMessage consumers,
+	// a subscriber not keeping up misses lines. The channel is closed when the server stops.
+	SubscribeLogs() (<-chan LogLine, func())
+	// Summary returns the summary of the build served by the server.
+	Summary() BuildSummary
+	// Wait blocks until the internal goroutines of the server have exited, call after Stop.
+	Wait()
 }
 
 // Resources is a map of resolved resources the server handles for the client.
@@ -88,32 +224,155 @@ type Resources = map[string][]resources.ResolvedResource
 
 // WorkContext contains the information for the bootstrap work to execute.
 type WorkContext struct {
+	// BuildID identifies the build in the logs, the summary and the responses of the server.
+	// Clients configured with the ID of another build are rejected. Generated when empty.
+	BuildID string
+	// CommandIDs identify the ExecutableCommands by index in the events, the log lines and the logs of the server.
+	// Derived with commands.IDs when there is not an ID for every command.
+	CommandIDs []string
+	// CommandGroups optionally assign the ExecutableCommands by index to parallel groups. The consecutive commands
+	// of the same group other than 0 are independent, the client may run them concurrently and acknowledge them
+	// in any order. Ignored unless there is a group for every command, see graph.Graph.Groups.
+	CommandGroups      []int
 	ExecutableCommands []commands.VMInitSerializableCommand
 	ResourcesResolved  Resources
+	// Platform the build targets, for example linux/amd64 or linux/arm64.
+	// When set, only resources matching the platform are served to the client.
+	// An empty value serves all resources.
+	Platform string
+	// Args contains the ARG values in effect when the build starts, overriding the defaults of the ARG commands
+	// of the same name. The ARG values are substituted into the commands sent to the client, see commands.SubstituteArgs,
+	// ExecutableCommands remain as they are.
+	Args map[string]string
+	// Env contains the ENV values in effect when the build starts.
+	// ENV values take precedence over ARG values of the same name.
+	Env map[string]string
+	// DefaultUser is the user of the commands and the resources without a user, for example the USER
+	// of a non-root base image, applied when the commands and the resource headers are sent.
+	// Default is commands.DefaultUser().
+	DefaultUser commands.User
+	// DefaultWorkdir is the workdir of the commands and the resources without a workdir, for example the WORKDIR
+	// of the base image, applied when the commands and the resource headers are sent.
+	// Default is commands.DefaultWorkdir().
+	DefaultWorkdir commands.Workdir
+	// CommandTimeouts contains the maximum durations of individual commands
+	// keyed by the index of the command in ExecutableCommands.
+	CommandTimeouts map[int]time.Duration
+	// ResourcePriorities optionally overrides the priorities of the resources derived
+	// from the executable commands, keyed by the resource path. Lower values are needed first.
+	ResourcePriorities map[string]int
+	// ChecksumCache optionally caches the checksums of the chunks of the local files served,
+	// share it between the servers serving the same resources.
+	ChecksumCache *ChecksumCache
+	// Spool optionally serves the file contents from a spool, share it between the servers
+	// serving the same resources. The server does not remove the spool.
+	Spool *Spool
+	// ScratchDir is the optional temporary storage of resources resolved for the build.
+	// The server cleans it up when it stops.
+	ScratchDir *ScratchDir
+	// SpillRemoteResources keeps the contents of the remote file resources, for example http and https
+	// downloads, in the scratch directory on the first read, see NewSpilledResource. Retries, resumed
+	// transfers and other guests are served without downloading again.
+	SpillRemoteResources bool
+
+	// OnBeforeCommands is executed when the client requests the commands for the first time.
+	// An error returned from the hook fails the commands request.
+	OnBeforeCommands BuildHook
+	// OnAfterSuccess is executed after the client finishes successfully.
+	OnAfterSuccess BuildHook
+	// OnAfterAbort is executed after the client aborts.
+	OnAfterAbort BuildHook
+}
+
+// defaultUser returns the user of the commands and the resources without a user.
+func (ctx *WorkContext) defaultUser() commands.User {
+	if ctx.DefaultUser.Value == "" {
+		return commands.DefaultUser()
+	}
+	return ctx.DefaultUser
+}
+
+// defaultWorkdir returns the workdir of the commands and the resources without a workdir.
+func (ctx *WorkContext) defaultWorkdir() commands.Workdir {
+	if ctx.DefaultWorkdir.Value == "" {
+		return commands.DefaultWorkdir()
+	}
+	return ctx.DefaultWorkdir
+}
+
+// applyHeaderDefaults sets the user and the workdir of a resource header without them to the defaults.
+func (ctx *WorkContext) applyHeaderDefaults(header *proto.ResourceChunk_ResourceHeader) {
+	if header.TargetUser == "" {
+		header.TargetUser = encodeHeaderUser(ctx.defaultUser())
+	}
+	if header.TargetWorkdir == "" {
+		header.TargetWorkdir = ctx.defaultWorkdir().Value
+	}
+}
+
+// substitutedCommands returns the executable commands with the ARG values substituted, as sent to the client.
+func (ctx *WorkContext) substitutedCommands() []commands.VMInitSerializableCommand {
+	return commands.SubstituteArgs(ctx.ExecutableCommands, ctx.Args)
+}
+
+// Environment returns the accumulated ARG and ENV key-value map as of build start.
+// The ARG values are the values of Args and the resolved values of the ARG commands without one.
+func (ctx *WorkContext) Environment() map[string]string {
+	result := commands.ArgValues(ctx.ExecutableCommands, ctx.Args)
+	for k, v := range ctx.Args {
+		result[k] = v
+	}
+	for k, v := range ctx.Env {
+		result[k] = v
+	}
+	return result
 }
 
 type grpcSvc struct {
 	sync.Mutex
 
 	config *GRPCServiceConfig
-	logger hclog.Logger
-
-	srv *grpc.Server
-	svc serverImplInterface
+	// logger is the server.lifecycle logger, subsystem loggers are derived from the base logger:
+	logger     hclog.Logger
+	baseLogger hclog.Logger
+
+	srv     *grpc.Server
+	httpSrv *http.Server
+	svc     serverImplInterface
+	listen  TransportListenFunc
+
+	// embedded servers register the service on an externally managed grpc.Server:
+	embedded   bool
+	roles      *clientRoles
+	statusErrs *statusErrors
+	session    *buildSession
+	routines   *routineGroup
 
 	chanReady   chan struct{}
 	chanStopped chan struct{}
 	chanFailed  chan error
 
+	scratchDir *ScratchDir
+
 	wasStarted bool
 	running    bool
 }
 
 // New returns a new instance of the server.
 func New(cfg *GRPCServiceConfig, logger hclog.Logger) ServerProvider {
+	return NewWithListener(cfg, logger, listenTCP)
+}
+
+// NewWithListener returns a new instance of the server serving gRPC on the listener created by the listen function,
+// for example to serve gRPC over a transport other than TCP.
+func NewWithListener(cfg *GRPCServiceConfig, logger hclog.Logger, listen TransportListenFunc) ServerProvider {
+	cfg = cfg.WithDefaultsApplied()
 	return &grpcSvc{
-		config:      cfg.WithDefaultsApplied(),
-		logger:      logger,
+		config:      cfg,
+		listen:      listen,
+		logger:      cfg.LogLevels.logger(logger, LogSubsystemServerLifecycle),
+		baseLogger:  logger,
+		routines:    newRoutineGroup(),
 		chanFailed:  make(chan error, 1),
 		chanReady:   make(chan struct{}),
 		chanStopped: make(chan struct{}),
@@ -127,16 +386,53 @@ func (s *grpcSvc) Start(serverCtx *WorkContext) {
 
 	if !s.wasStarted {
 		s.wasStarted = true
-		listener, err := net.Listen("tcp", s.config.BindHostPort)
-		if err != nil {
+
+		for _, version := range s.config.WireVersions {
+			if err := validateWireVersion(version); err != nil {
+				s.chanFailed <- err
+				return
+			}
+		}
+
+		if err := resumeBuildID(serverCtx, s.config.JournalPath); err != nil {
+			s.chanFailed <- err
+			return
+		}
+		buildID := ensureBuildID(serverCtx)
+		ensureCommandIDs(serverCtx)
+		if err := ensureReusableResources(serverCtx); err != nil {
 			s.chanFailed <- err
 			return
 		}
 
+		if s.embedded {
+			s.startEmbedded(serverCtx)
+			return
+		}
+
+		roles := newClientRoles(s.config.ClientRoleResolver, s.config.AllowUnverifiedExecutor)
+		statusErrs := &statusErrors{sessionID: uuid.Must(uuid.NewV4()).String()}
+		session := newBuildSession(buildID)
+
+		// the gRPC and the Connect calls pass the same interceptors:
+		unaryInterceptors := []grpc.UnaryServerInterceptor{s.routines.unaryInterceptor, statusErrs.unaryInterceptor, session.unaryInterceptor, roles.unaryInterceptor}
+		streamInterceptors := []grpc.StreamServerInterceptor{s.routines.streamInterceptor, statusErrs.streamInterceptor, session.streamInterceptor, roles.streamInterceptor}
+
 		grpcServerOptions := []grpc.ServerOption{
 			grpc.MaxMsgSize(s.config.MaxMsgSize),
+			grpc.ChainUnaryInterceptor(unaryInterceptors...),
+			grpc.ChainStreamInterceptor(streamInterceptors...),
+		}
+		if s.config.StatsHandler != nil {
+			grpcServerOptions = append(grpcServerOptions, grpc.StatsHandler(s.config.StatsHandler))
+		}
+		if s.config.KeepaliveInterval > 0 {
+			grpcServerOptions = append(grpcServerOptions,
+				grpc.KeepaliveParams(keepalive.ServerParameters{Time: s.config.KeepaliveInterval, Timeout: s.config.KeepaliveInterval}),
+				grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: s.config.KeepaliveInterval / 2, PermitWithoutStream: true}))
 		}
 
+		var listenTLSConfig *tls.Config
 		if s.config.TLSConfigServer == nil {
 
 			// if there is no server TLS config, generate a new runtime CA
@@ -164,12 +460,38 @@ func (s *grpcSvc) Start(serverCtx *WorkContext) {
 				return
 			}
 
+			observerTLSConfigs := []*tls.Config{}
+			for i := 0; i < s.config.ObserverClients; i++ {
+				observerTLSConfig, err := embeddedCA.NewClientCertTLSConfig(s.config.ServerName)
+				if err != nil {
+					s.chanFailed <- err
+					return
+				}
+				observerCert, err := x509.ParseCertificate(observerTLSConfig.Certificates[0].Certificate[0])
+				if err != nil {
+					s.chanFailed <- err
+					return
+				}
+				roles.addObserver(observerCert.SerialNumber)
+				observerTLSConfigs = append(observerTLSConfigs, observerTLSConfig)
+			}
+
 			grpcServerOptions = append(grpcServerOptions, grpc.Creds(credentials.NewTLS(serverTLSConfig)))
+			listenTLSConfig = serverTLSConfig
 
+			s.config.TLSCAPEMChain = embeddedCA.CAPEMChain()
 			s.config.TLSConfigClient = clientTLSConfig
+			s.config.TLSConfigObserverClients = observerTLSConfigs
 
 		} else {
 			grpcServerOptions = append(grpcServerOptions, grpc.Creds(credentials.NewTLS(s.config.TLSConfigServer)))
+			listenTLSConfig = s.config.TLSConfigServer
+		}
+
+		listener, err := s.listen(s.config.BindHostPort, listenTLSConfig)
+		if err != nil {
+			s.chanFailed <- err
+			return
 		}
 
 		s.srv = grpc.NewServer(grpcServerOptions...)
@@ -225,18 +547,72 @@ func (s *grpcSvc) Start(serverCtx *WorkContext) {
 
 		s.logger.Info("Registering service with the GRPC server")
 
-		s.svc = newServerImpl(s.logger.Named("grpc-impl"), serverCtx, s.config)
+		s.scratchDir = serverCtx.ScratchDir
+		s.svc = newServerImpl(s.baseLogger.Named("grpc-impl").With("build-id", buildID), serverCtx, s.config)
 
-		proto.RegisterRootfsServerServer(s.srv, s.svc)
+		for _, version := range s.config.WireVersions {
+			s.srv.RegisterService(wireServiceDesc(version), s.svc)
+		}
+
+		serve := s.srv.Serve
+		if s.config.ConnectProtocol {
+			connect := newConnectHandler(s.svc, unaryInterceptors, streamInterceptors, s.config.StatsHandler, s.config.WireVersions)
+			s.httpSrv = &http.Server{
+				// h2c serves HTTP/2 on the plaintext listener, TLS connections negotiate HTTP/2 before the handler:
+				Handler: h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if isGRPCRequest(r) {
+						s.srv.ServeHTTP(w, r)
+						return
+					}
+					connect.ServeHTTP(w, r)
+				}), &http2.Server{}),
+				TLSConfig: listenTLSConfig.Clone(),
+			}
+			serve = func(listener net.Listener) error {
+				if err := s.httpSrv.ServeTLS(listener, "", ""); err != http.ErrServerClosed {
+					return err
+				}
+				return nil
+			}
+			if s.config.ConnectPlaintextBindHostPort != "" {
+				plaintextListener, err := net.Listen("tcp", s.config.ConnectPlaintextBindHostPort)
+				if err != nil {
+					listener.Close()
+					s.chanFailed <- err
+					return
+				}
+				s.config.ConnectPlaintextBindHostPort = plaintextListener.Addr().String()
+				serveTLS := serve
+				serve = func(listener net.Listener) error {
+					chanPlaintextErr := make(chan error, 1)
+					s.routines.goTracked(func() {
+						err := s.httpSrv.Serve(plaintextListener)
+						if err != http.ErrServerClosed {
+							// the listeners fail together:
+							s.httpSrv.Close()
+						}
+						chanPlaintextErr <- err
+					})
+					err := serveTLS(listener)
+					if err != nil {
+						s.httpSrv.Close()
+					}
+					if plaintextErr := <-chanPlaintextErr; err == nil && plaintextErr != http.ErrServerClosed {
+						err = plaintextErr
+					}
+					return err
+				}
+			}
+		}
 
 		chanErr := make(chan struct{})
-		go func() {
-			if err := s.srv.Serve(listener); err != nil {
+		s.routines.goTracked(func() {
+			if err := serve(listener); err != nil {
 				s.logger.Error("Failed to serve", "reason", "error")
 				s.chanFailed <- err
 				close(chanErr)
 			}
-		}()
+		})
 
 		select {
 		case <-chanErr:
@@ -245,6 +621,9 @@ func (s *grpcSvc) Start(serverCtx *WorkContext) {
 			s.running = true
 			s.config.BindHostPort = listener.Addr().String()
 			close(s.chanReady)
+			if s.config.BuildTimeout > 0 {
+				s.routines.goTracked(func() { s.enforceBuildTimeout(s.config.BuildTimeout) })
+			}
 		}
 
 	} else {
@@ -252,9 +631,41 @@ func (s *grpcSvc) Start(serverCtx *WorkContext) {
 	}
 }
 
+// Cancel tells the client watching the server to stop the build immediately.
+func (s *grpcSvc) Cancel(reason error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.running {
+		s.svc.Cancel(reason)
+	} else {
+		s.logger.Warn("server not running, nothing to cancel")
+	}
+}
+
+func (s *grpcSvc) enforceBuildTimeout(timeout time.Duration) {
+	select {
+	case <-s.chanStopped:
+		return
+	case <-time.After(timeout):
+	}
+	s.Lock()
+	svc := s.svc
+	s.Unlock()
+	// the build may have completed and the server is lingering after success:
+	if !svc.Summary().FinishedAt.IsZero() {
+		return
+	}
+	s.logger.Warn("build timeout exceeded, cancelling", "timeout", timeout)
+	svc.Cancel(fmt.Errorf("build timeout of %v exceeded", timeout))
+	svc.Emit(&ControlMsgBuildTimeout{Timeout: timeout, Summary: svc.Summary()})
+	s.Stop()
+}
+
 // Stop stops the server, if the server is started.
 func (s *grpcSvc) Stop() {
 
+	s.lingerAfterSuccess()
+
 	s.Lock()
 	defer s.Unlock()
 
@@ -263,22 +674,49 @@ func (s *grpcSvc) Stop() {
 		s.logger.Info("attempting graceful stop")
 		s.svc.Stop()
 
-		chanSignal := make(chan struct{})
-		go func() {
-			s.srv.GracefulStop()
-			close(chanSignal)
-		}()
-
-		select {
-		case <-chanSignal:
-			s.logger.Info("stopped gracefully")
-		case <-time.After(time.Millisecond * time.Duration(s.config.GracefulStopTimeoutMillis)):
-			s.logger.Warn("failed to stop gracefully within timeout, forceful stop")
-			s.srv.Stop()
+		// an embedded server leaves the externally managed grpc.Server running:
+		if !s.embedded {
+			gracefulStopTimeout := time.Millisecond * time.Duration(s.config.GracefulStopTimeoutMillis)
+			if s.httpSrv != nil {
+				ctx, cancelFunc := context.WithTimeout(context.Background(), gracefulStopTimeout)
+				if err := s.httpSrv.Shutdown(ctx); err != nil {
+					s.logger.Warn("failed to stop gracefully within timeout, forceful stop")
+					s.httpSrv.Close()
+				} else {
+					s.logger.Info("stopped gracefully")
+				}
+				cancelFunc()
+				s.srv.Stop()
+			} else {
+				chanSignal := make(chan struct{})
+				s.routines.goTracked(func() {
+					s.srv.GracefulStop()
+					close(chanSignal)
+				})
+
+				select {
+				case <-chanSignal:
+					s.logger.Info("stopped gracefully")
+				case <-time.After(gracefulStopTimeout):
+					s.logger.Warn("failed to stop gracefully within timeout, forceful stop")
+					s.srv.Stop()
+				}
+			}
 		}
 
 		s.logger.Info("stopped")
 
+		if s.scratchDir != nil {
+			removed, err := s.scratchDir.Cleanup(!s.svc.Summary().Success)
+			if err != nil {
+				s.logger.Error("failed removing scratch directory", "path", s.scratchDir.Path(), "reason", err)
+			} else if !removed {
+				s.logger.Info("scratch directory retained after failure", "path", s.scratchDir.Path())
+			}
+		}
+
+		// an embedded server keeps receiving requests through the interceptors, they are rejected from now on:
+		s.routines.close()
 		s.running = false
 		close(s.chanStopped)
 
@@ -288,6 +726,32 @@ func (s *grpcSvc) Stop() {
 
 }
 
+// lingerAfterSuccess waits until the SuccessLinger window after a successful build has passed.
+func (s *grpcSvc) lingerAfterSuccess() {
+	if s.config.SuccessLinger <= 0 {
+		return
+	}
+	s.Lock()
+	svc, running := s.svc, s.running
+	s.Unlock()
+	if !running {
+		return
+	}
+	summary := svc.Summary()
+	if !summary.Success {
+		return
+	}
+	remaining := s.config.SuccessLinger - time.Since(summary.FinishedAt)
+	if remaining <= 0 {
+		return
+	}
+	s.logger.Info("lingering after success", "remaining", remaining)
+	select {
+	case <-time.After(remaining):
+	case <-s.chanStopped:
+	}
+}
+
 func (s *grpcSvc) OnMessage() <-chan interface{} {
 	return s.svc.OnMessage()
 }
@@ -306,3 +770,48 @@ func (s *grpcSvc) FailedNotify() <-chan error {
 func (s *grpcSvc) StoppedNotify() <-chan struct{} {
 	return s.chanStopped
 }
+
+// Stats returns the current resource usage of the server.
+func (s *grpcSvc) Stats() ServerStats {
+	s.Lock()
+	defer s.Unlock()
+	if s.svc == nil {
+		return ServerStats{BufferedChunkBytesLimit: s.config.MaxBufferedChunkBytes}
+	}
+	return s.svc.Stats()
+}
+
+// Subscribe returns a channel receiving a copy of every event of the build and a function ending the subscription.
+func (s *grpcSvc) Subscribe() (<-chan interface{}, func()) {
+	s.Lock()
+	defer s.Unlock()
+	if s.svc == nil {
+		chanEvents := make(chan interface{})
+		close(chanEvents)
+		return chanEvents, func() {}
+	}
+	return s.svc.Subscribe()
+}
+
+// SubscribeLogs returns a channel receiving the stdout and stderr lines of the build
+// and a function ending the subscription.
+func (s *grpcSvc) SubscribeLogs() (<-chan LogLine, func()) {
+	s.Lock()
+	defer s.Unlock()
+	if s.svc == nil {
+		chanLines := make(chan LogLine)
+		close(chanLines)
+		return chanLines, func() {}
+	}
+	return s.svc.SubscribeLogs()
+}
+
+// Summary returns the summary of the build served by the server.
+func (s *grpcSvc) Summary() BuildSummary {
+	s.Lock()
+	defer s.Unlock()
+	if s.svc == nil {
+		return BuildSummary{}
+	}
+	return s.svc.Summary()
+}
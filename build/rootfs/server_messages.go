@@ -1,25 +1,194 @@
 package rootfs
 
+import "time"
+
 // ClientMsgAborted is emitted by the server when the client aborts with an error.
 type ClientMsgAborted struct {
 	Error error
+	// CommandID identifies the command the client was executing when it aborted,
+	// empty if none or if the client was executing several commands of a parallel group.
+	CommandID string
+}
+
+// ClientMsgCommandFinished is emitted by the server when the client finishes executing a command.
+type ClientMsgCommandFinished struct {
+	Index     int
+	CommandID string
+	Error     error
+	// Attempts is the number of times the command ran, more than 1 if the client retried the command.
+	Attempts int
+}
+
+// ClientMsgCommandRetried is emitted by the server when the client runs a failed command again.
+// Attempt is the number of the failed attempt, counted from 1.
+type ClientMsgCommandRetried struct {
+	Index     int
+	CommandID string
+	Attempt   int
+	Error     error
+}
+
+// ClientMsgCommandSkipped is emitted by the server when the client skips a command
+// because the guard of the command does not match the guest facts.
+type ClientMsgCommandSkipped struct {
+	Index     int
+	CommandID string
+}
+
+// ClientMsgCommandStarted is emitted by the server when the client starts executing a command.
+type ClientMsgCommandStarted struct {
+	Index     int
+	CommandID string
+}
+
+// ClientMsgDebugSession is emitted by the server when the client opens a debug session after an abort.
+// The session must be closed by the consumer when done.
+type ClientMsgDebugSession struct {
+	Session DebugSession
+}
+
+// ClientMsgLogsFlushed is emitted by the server when the client flushes its logs before finishing.
+// StdoutLines and StderrLines are the numbers of lines the server received during the build,
+// Complete is true if the server received at least the lines the client sent.
+type ClientMsgLogsFlushed struct {
+	StdoutLines int
+	StderrLines int
+	Complete    bool
+}
+
+// ClientMsgProgress is emitted by the server when the client reports the progress of writing a resource,
+// see WriteOptions.ProgressInterval. GuestID identifies the guest of a broadcast build, empty otherwise.
+// BytesTotal is zero when the client does not know the total, ETA is zero when the client cannot estimate it.
+type ClientMsgProgress struct {
+	GuestID        string
+	Path           string
+	BytesWritten   int64
+	BytesTotal     int64
+	BytesPerSecond int64
+	ETA            time.Duration
+	Done           bool
+}
+
+// ClientMsgResumed is emitted by the server when a reconnecting client and the server agree where to continue the build.
+// NextIndex is the index of the first command the client executes, ResourceOffsets contain the offsets
+// the client continues the partially written resources from, keyed by the resource path.
+type ClientMsgResumed struct {
+	NextIndex       int
+	ResourceOffsets map[string]int64
 }
 
 // ClientMsgStderr is emitted by the server when the client sends stderr contents.
 type ClientMsgStderr struct {
 	Lines []string
+	// CommandID identifies the command the client was executing when it sent the lines,
+	// empty if none or if the client was executing several commands of a parallel group.
+	CommandID string
 }
 
 // ClientMsgStdout is emitted by the server when the client sends stdout contents.
 type ClientMsgStdout struct {
 	Lines []string
+	// CommandID identifies the command the client was executing when it sent the lines,
+	// empty if none or if the client was executing several commands of a parallel group.
+	CommandID string
+}
+
+// ClientMsgGuestFinished is emitted by the server when a guest of a broadcast build finishes,
+// with the abort error if the guest aborted. ClientMsgSuccess or ClientMsgAborted follows
+// when every guest has finished.
+type ClientMsgGuestFinished struct {
+	GuestID string
+	Error   error
 }
 
 // ClientMsgSuccess is emitted by the server when the client finishes successfully.
 type ClientMsgSuccess struct{}
 
+// ClientMsgWarning is emitted by the server when the client reports warnings about a resource it has written,
+// for example a mode or an owner different from the requested one.
+type ClientMsgWarning struct {
+	Path     string
+	Warnings []string
+}
+
+// ControlMsgBuildTimeout is emitted by the server when the build timeout was exceeded.
+// The client is cancelled and the server stops after the event is consumed.
+type ControlMsgBuildTimeout struct {
+	Timeout time.Duration
+	Summary BuildSummary
+}
+
 // ControlMsgCommandsRequested is emitted by the server when the client requests the commands.
 type ControlMsgCommandsRequested struct{}
 
+// ControlMsgCommandTimeout is emitted by the server when a command did not finish within its timeout.
+// The client is cancelled before the event is emitted.
+type ControlMsgCommandTimeout struct {
+	Index     int
+	CommandID string
+	Timeout   time.Duration
+	Summary   BuildSummary
+}
+
+// ControlMsgEnvironmentRequested is emitted by the server when the client requests the environment.
+type ControlMsgEnvironmentRequested struct{}
+
+// ControlMsgPortForwardClosed is emitted by the server when the client no longer needs a port forward.
+type ControlMsgPortForwardClosed struct {
+	ID string
+}
+
+// ControlMsgPortForwardRequested is emitted by the server when the client requests
+// that a host address is proxied into the VM. The consumer must call Accept or Reject,
+// otherwise the request is rejected after the configured timeout.
+type ControlMsgPortForwardRequested struct {
+	ID          string
+	Protocol    string
+	HostAddress string
+	GuestPort   int
+
+	chanReply chan portForwardReply
+}
+
+// Accept accepts the port forward, the guest address is where the client reaches the host address.
+func (m *ControlMsgPortForwardRequested) Accept(guestAddress string) {
+	m.reply(portForwardReply{guestAddress: guestAddress})
+}
+
+// Reject rejects the port forward with a reason.
+func (m *ControlMsgPortForwardRequested) Reject(reason error) {
+	m.reply(portForwardReply{err: reason})
+}
+
+func (m *ControlMsgPortForwardRequested) reply(r portForwardReply) {
+	select {
+	case m.chanReply <- r:
+	default:
+	}
+}
+
+type portForwardReply struct {
+	guestAddress string
+	err          error
+}
+
+// ControlMsgResourceServed is emitted by the server when a resource request was served completely.
+// Resources counts every file and directory of directory resources separately.
+type ControlMsgResourceServed struct {
+	Path      string
+	Stage     string
+	Resources int
+	Bytes     int64
+}
+
+// ControlMsgResourceEntrySkipped is emitted by the server when an entry of a directory resource could not be read
+// and was skipped because of the UnreadableFileWarn policy. FilePath is the local path of the entry.
+type ControlMsgResourceEntrySkipped struct {
+	Path     string
+	Stage    string
+	FilePath string
+	Reason   error
+}
+
 // ControlMsgPingSent is emitted by the server when the client sends a ping request.
 type ControlMsgPingSent struct{}
@@ -1,13 +1,20 @@
 package rootfs
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/combust-labs/firebuild-shared/build/commands"
 	"github.com/combust-labs/firebuild-shared/utilstest"
 	"github.com/hashicorp/go-hclog"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type eventuallyFunc func() error
@@ -38,6 +45,278 @@ func TestServerNoContentOpsSuccess(t *testing.T) {
 	})
 }
 
+func TestServerBuildHooks(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	chanBefore := make(chan BuildSummary, 1)
+	chanSuccess := make(chan BuildSummary, 1)
+	chanAbort := make(chan BuildSummary, 1)
+
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: make(Resources),
+		OnBeforeCommands: func(summary BuildSummary) error {
+			chanBefore <- summary
+			return nil
+		},
+		OnAfterSuccess: func(summary BuildSummary) error {
+			chanSuccess <- summary
+			return nil
+		},
+		OnAfterAbort: func(summary BuildSummary) error {
+			chanAbort <- summary
+			return nil
+		},
+	}
+
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, buildCtx)
+	defer cleanupFunc()
+
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.StdOut([]string{"line 1", "line 2"}))
+	assert.Nil(t, testClient.Success())
+
+	<-testServer.FinishedNotify()
+
+	beforeSummary := <-chanBefore
+	assert.Equal(t, 1, beforeSummary.CommandsCount)
+	assert.False(t, beforeSummary.Success)
+
+	successSummary := <-chanSuccess
+	assert.True(t, successSummary.Success)
+	assert.Equal(t, 2, successSummary.StdoutLines)
+	assert.False(t, successSummary.FinishedAt.IsZero())
+
+	assert.Empty(t, chanBefore, "expected before commands hook to run once")
+	assert.Empty(t, chanAbort, "expected after abort hook not to run")
+}
+
+func TestServerDebugSessionOnAbort(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{DebugOnAbort: true}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanHostOutput := make(chan string, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			if tmessage, ok := message.(*ClientMsgDebugSession); ok {
+				tmessage.Session.Write([]byte("whoami\n"))
+				buffer := make([]byte, 64)
+				n, _ := tmessage.Session.Read(buffer)
+				chanHostOutput <- string(buffer[0:n])
+				tmessage.Session.Close()
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.Abort(fmt.Errorf("aborted")))
+	assert.True(t, testClient.DebugRequested())
+
+	// a fake pty answering every input line with a fixed output:
+	ptyInReader, ptyInWriter := io.Pipe()
+	ptyOutReader, ptyOutWriter := io.Pipe()
+	go func() {
+		buffer := make([]byte, 64)
+		for {
+			if _, err := ptyInReader.Read(buffer); err != nil {
+				ptyOutWriter.CloseWithError(err)
+				return
+			}
+			ptyOutWriter.Write([]byte("root\n"))
+		}
+	}()
+	defer ptyInWriter.Close()
+
+	assert.Nil(t, testClient.Debug(&struct {
+		io.Reader
+		io.Writer
+	}{ptyOutReader, ptyInWriter}))
+	assert.Equal(t, "root\n", <-chanHostOutput)
+}
+
+func TestServerBuildTimeout(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{BuildTimeout: time.Millisecond * 500}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanTimeout := make(chan *ControlMsgBuildTimeout, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			if tmessage, ok := message.(*ControlMsgBuildTimeout); ok {
+				chanTimeout <- tmessage
+				return
+			}
+		}
+	}()
+
+	chanCancel, err := testClient.WatchCancel()
+	assert.Nil(t, err)
+	assert.Nil(t, testClient.StdOut([]string{"still working"}))
+
+	assert.NotNil(t, <-chanCancel)
+	timeoutMessage := <-chanTimeout
+	assert.Equal(t, 1, timeoutMessage.Summary.StdoutLines)
+	assert.False(t, timeoutMessage.Summary.Success)
+
+	select {
+	case <-srv.StoppedNotify():
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected server to stop after build timeout")
+	}
+}
+
+func TestServerBuildTimeoutAfterSuccess(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{
+		BuildTimeout:  time.Millisecond * 300,
+		SuccessLinger: time.Second * 2,
+	}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+
+	chanTimeout := make(chan *ControlMsgBuildTimeout, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			if tmessage, ok := message.(*ControlMsgBuildTimeout); ok {
+				chanTimeout <- tmessage
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, testClient.Success())
+
+	select {
+	case <-chanTimeout:
+		t.Fatal("expected no build timeout after the build succeeded")
+	case <-srv.StoppedNotify():
+		t.Fatal("expected the server to keep lingering after success")
+	case <-time.After(time.Second):
+	}
+	assert.True(t, srv.Summary().Success)
+}
+
+func TestServerCommandTimeout(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{DefaultCommandTimeout: time.Second * 10}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+			commands.RunWithDefaults("sleep 3600"),
+		},
+		ResourcesResolved: make(Resources),
+		CommandTimeouts: map[int]time.Duration{
+			1: time.Millisecond * 300,
+		},
+	})
+	defer srv.Stop()
+
+	chanTimeout := make(chan *ControlMsgCommandTimeout, 1)
+	go func() {
+		for message := range srv.OnMessage() {
+			if tmessage, ok := message.(*ControlMsgCommandTimeout); ok {
+				chanTimeout <- tmessage
+				return
+			}
+		}
+	}()
+
+	chanCancel, err := testClient.WatchCancel()
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.CommandFinished(0, nil))
+	assert.Nil(t, testClient.CommandStarted(1))
+	assert.NotNil(t, testClient.CommandStarted(2), "expected out of range index to fail")
+
+	assert.NotNil(t, <-chanCancel)
+	timeoutMessage := <-chanTimeout
+	assert.Equal(t, 1, timeoutMessage.Index)
+	assert.Equal(t, 1, timeoutMessage.Summary.CommandsFinished)
+	assert.NotNil(t, timeoutMessage.Summary.Error)
+}
+
+func mustStartServerAndClient(t *testing.T, logger hclog.Logger, grpcConfig *GRPCServiceConfig, buildCtx *WorkContext) (ServerProvider, ClientProvider) {
+	grpcConfig.ServerName = "test-grpc-server"
+	grpcConfig.BindHostPort = "127.0.0.1:0"
+	grpcConfig.EmbeddedCAKeySize = 1024 // use this low for tests only! low value speeds up tests
+
+	srv := New(grpcConfig, logger.Named("grpc-server"))
+	srv.Start(buildCtx)
+	select {
+	case startErr := <-srv.FailedNotify():
+		t.Fatal("expected the GRPC server to start but it failed", startErr)
+	case <-srv.ReadyNotify():
+	}
+
+	testClient, clientErr := NewClient(logger.Named("grpc-client"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	if clientErr != nil {
+		srv.Stop()
+		t.Fatal("expected the GRPC client, got error", clientErr)
+	}
+	return srv, testClient
+}
+
+func TestServerCompletesOnce(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	afterSuccess := 0
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+		OnAfterSuccess: func(BuildSummary) error {
+			afterSuccess = afterSuccess + 1
+			return nil
+		},
+	})
+	defer srv.Stop()
+	chanMessages := make(chan interface{}, 8)
+	go func() {
+		for message := range srv.OnMessage() {
+			chanMessages <- message
+		}
+	}()
+
+	assert.Nil(t, testClient.Success())
+	err := testClient.Success()
+	assert.True(t, errors.Is(err, ErrAlreadyCompleted), "expected a repeated Success to fail, got", err)
+	err = testClient.Abort(fmt.Errorf("late abort"))
+	assert.True(t, errors.Is(err, ErrAlreadyCompleted), "expected an Abort after Success to fail, got", err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Nil(t, testClient.Ping())
+
+	assert.Equal(t, &ClientMsgSuccess{}, <-chanMessages)
+	assert.Equal(t, &ControlMsgPingSent{}, <-chanMessages, "expected no events of the repeated calls")
+	assert.Equal(t, 1, afterSuccess)
+	summary := srv.Summary()
+	assert.True(t, summary.Success)
+	assert.Nil(t, summary.Error)
+}
+
 func testWithStopType(t *testing.T, stopTrigger func(ClientProvider), eventuallyCond func(TestServer) eventuallyFunc) {
 	logger := hclog.Default()
 	logger.SetLevel(hclog.Debug)
@@ -72,3 +351,152 @@ func testWithStopType(t *testing.T, stopTrigger func(ClientProvider), eventually
 	assert.Equal(t, expectedStdoutLines, testServer.ReceivedStdout())
 
 }
+
+func TestServerObserverClient(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{ObserverClients: 1}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: make(Resources),
+	})
+	defer srv.Stop()
+
+	chanSucceeded := make(chan struct{})
+	go func() {
+		for message := range srv.OnMessage() {
+			if _, ok := message.(*ClientMsgSuccess); ok {
+				close(chanSucceeded)
+				return
+			}
+		}
+	}()
+
+	assert.Equal(t, 1, len(grpcConfig.TLSConfigObserverClients))
+	observerClient, err := NewClient(logger.Named("grpc-observer"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigObserverClients[0],
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, observerClient.Commands())
+	assert.Nil(t, observerClient.Ping())
+	assert.Equal(t, codes.PermissionDenied, status.Code(observerClient.StdOut([]string{"observer output"})))
+	assert.Equal(t, codes.PermissionDenied, status.Code(observerClient.Success()))
+	assert.True(t, errors.Is(observerClient.Success(), ErrUnauthorized))
+	assert.Equal(t, codes.PermissionDenied, status.Code(observerClient.Abort(fmt.Errorf("aborted"))))
+	assert.Equal(t, 0, srv.Summary().StdoutLines)
+
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.Success())
+	<-chanSucceeded
+}
+
+func TestClientRolesUnverifiedPeer(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{})
+	roles := newClientRoles(nil, false)
+	assert.Equal(t, ClientRoleObserver, roles.role(ctx), "expected a peer without a certificate to observe")
+	_, err := roles.authorize(ctx, "/proto.RootfsServer/Success")
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	_, err = roles.authorize(ctx, "/proto.RootfsServer/Commands")
+	assert.Nil(t, err)
+
+	roles = newClientRoles(nil, true)
+	assert.Equal(t, ClientRoleExecutor, roles.role(ctx))
+	_, err = roles.authorize(ctx, "/proto.RootfsServer/Success")
+	assert.Nil(t, err)
+}
+
+func TestServerLogSubscribers(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{ObserverClients: 1}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+
+	// the primary consumer:
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	chanLocal, unsubscribe := srv.SubscribeLogs()
+	defer unsubscribe()
+
+	observerClient, err := NewClient(logger.Named("grpc-observer"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigObserverClients[0],
+	})
+	assert.Nil(t, err)
+	chanRemote, err := observerClient.WatchLogs()
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.StdOut([]string{"out 1", "out 2"}))
+	assert.Nil(t, testClient.StdErr([]string{"err 1"}))
+
+	expected := []LogLine{
+		{Stream: LogStreamStdout, Line: "out 1"},
+		{Stream: LogStreamStdout, Line: "out 2"},
+		{Stream: LogStreamStderr, Line: "err 1"},
+	}
+	for _, chanLines := range []<-chan LogLine{chanLocal, chanRemote} {
+		for _, expectedLine := range expected {
+			line := <-chanLines
+			assert.Equal(t, expectedLine.Stream, line.Stream)
+			assert.Equal(t, expectedLine.Line, line.Line)
+			assert.False(t, line.Time.IsZero())
+		}
+	}
+
+	srv.Stop()
+	_, open := <-chanLocal
+	assert.False(t, open, "expected the subscription to close when the server stops")
+	_, open = <-chanRemote
+	assert.False(t, open, "expected the log stream to end when the server stops")
+}
+
+func TestServerSuccessLinger(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{
+		ServerName:        "test-grpc-server",
+		BindHostPort:      "127.0.0.1:0",
+		EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+		SuccessLinger:     time.Millisecond * 500,
+	}
+	testServer := NewTestServer(t, logger.Named("grpc-server"), grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	testServer.Start()
+	select {
+	case startErr := <-testServer.FailedNotify():
+		t.Fatal("expected the GRPC server to start but it failed", startErr)
+	case <-testServer.ReadyNotify():
+	}
+	defer testServer.Stop()
+
+	testClient, clientErr := NewClient(logger.Named("grpc-client"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, clientErr)
+
+	succeededAt := time.Now()
+	assert.Nil(t, testClient.Success())
+	assert.Nil(t, testClient.StdOut([]string{"late stdout"}))
+	assert.Nil(t, testClient.StdErr([]string{"late stderr"}))
+
+	<-testServer.FinishedNotify()
+	assert.True(t, time.Since(succeededAt) >= grpcConfig.SuccessLinger, "expected the server to linger after success")
+	assert.True(t, testServer.Succeeded())
+	assert.Equal(t, []string{"late stdout"}, testServer.ReceivedStdout())
+	assert.Equal(t, []string{"late stderr"}, testServer.ReceivedStderr())
+}
@@ -0,0 +1,92 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// BuildIDMetadataKey is the gRPC metadata key a client sends the build ID in.
+// The server returns the ID of the build it serves in the response header of the same key.
+const BuildIDMetadataKey = "firebuild-build-id"
+
+// buildIDFromContext returns the build ID the client sent with the request, empty if none.
+func buildIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(BuildIDMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ensureBuildID generates a build ID for the work context, unless the work context has one.
+func ensureBuildID(serverCtx *WorkContext) string {
+	if serverCtx.BuildID == "" {
+		serverCtx.BuildID = uuid.Must(uuid.NewV4()).String()
+	}
+	return serverCtx.BuildID
+}
+
+// buildSession rejects the requests of clients sending the ID of a build other than the build the server serves.
+// Requests without a build ID are accepted, clients not configured with a build ID do not send one.
+type buildSession struct {
+	m       sync.RWMutex
+	buildID string
+}
+
+func newBuildSession(buildID string) *buildSession {
+	return &buildSession{buildID: buildID}
+}
+
+func (s *buildSession) setBuildID(buildID string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.buildID = buildID
+}
+
+func (s *buildSession) validate(ctx context.Context) (string, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if requested := buildIDFromContext(ctx); requested != "" && requested != s.buildID {
+		return s.buildID, fmt.Errorf("%w: requested build %q, server serves build %q", ErrBuildMismatch, requested, s.buildID)
+	}
+	return s.buildID, nil
+}
+
+func (s *buildSession) unaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	buildID, err := s.validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the Connect protocol has no transport stream to set the header on:
+	grpc.SetHeader(ctx, metadata.Pairs(BuildIDMetadataKey, buildID))
+	return handler(ctx, req)
+}
+
+func (s *buildSession) streamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	buildID, err := s.validate(ss.Context())
+	if err != nil {
+		return err
+	}
+	ss.SetHeader(metadata.Pairs(BuildIDMetadataKey, buildID))
+	return handler(srv, ss)
+}
+
+func buildIDUnaryClientInterceptor(buildID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, BuildIDMetadataKey, buildID), method, req, reply, cc, opts...)
+	}
+}
+
+func buildIDStreamClientInterceptor(buildID string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, BuildIDMetadataKey, buildID), desc, cc, method, opts...)
+	}
+}
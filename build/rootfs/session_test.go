@@ -0,0 +1,63 @@
+package rootfs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerBuildID(t *testing.T) {
+	logger := hclog.Default()
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		BuildID:            "build-1",
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+	assert.Equal(t, "build-1", srv.Summary().BuildID)
+
+	// a client without a build ID is accepted:
+	assert.Nil(t, testClient.Ping())
+
+	matchingClient, err := NewClient(logger, &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		BuildID:   "build-1",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, matchingClient.Ping())
+	assert.Nil(t, matchingClient.Commands())
+
+	otherClient, err := NewClient(logger, &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		BuildID:   "build-2",
+	})
+	assert.Nil(t, err)
+	pingErr := otherClient.Ping()
+	assert.True(t, errors.Is(pingErr, ErrBuildMismatch), "expected build mismatch, got: %v", pingErr)
+	details, ok := ErrorDetailsOf(pingErr)
+	assert.True(t, ok)
+	assert.Equal(t, "BUILD_MISMATCH", details.Reason)
+	assert.NotNil(t, otherClient.Commands())
+}
+
+func TestServerGeneratesBuildID(t *testing.T) {
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	}
+	srv, _ := mustStartServerAndClient(t, hclog.Default(), &GRPCServiceConfig{}, buildCtx)
+	defer srv.Stop()
+	assert.NotEmpty(t, buildCtx.BuildID)
+	assert.Equal(t, buildCtx.BuildID, srv.Summary().BuildID)
+}
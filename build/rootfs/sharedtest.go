@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/combust-labs/firebuild-shared/build/commands"
@@ -17,6 +18,7 @@ import (
 // TestServer wraps an instance of a server and provides testing
 // utilities around it.
 type TestServer interface {
+	Cancel(error)
 	Start()
 	Stop()
 	FailedNotify() <-chan error
@@ -25,20 +27,30 @@ type TestServer interface {
 
 	Aborted() error
 	ClientRequestedCommands() bool
+	PortForwards() []string
 	ReceivedStderr() []string
 	ReceivedStdout() []string
+	ReceivedWarnings() []string
+	// RPCStats returns the counters of the calls the server handled so far.
+	RPCStats() RPCStatsSnapshot
 	Succeeded() bool
 }
 
 // NewTestServer starts a new test server provider.
+// The calls are counted with an RPCStatsRecorder, unless the configuration has a stats handler.
 func NewTestServer(t *testing.T, logger hclog.Logger, cfg *GRPCServiceConfig, ctx *WorkContext) TestServer {
+	recorder := NewRPCStatsRecorder()
+	if cfg.StatsHandler == nil {
+		cfg.StatsHandler = recorder
+	}
 	return &testGRPCServerProvider{
 		cfg:          cfg,
 		ctx:          ctx,
 		logger:       logger,
+		recorder:     recorder,
 		stdErrOutput: []string{},
 		stdOutOutput: []string{},
-		chanAborted:  make(chan struct{}),
+		warnings:     []string{},
 		chanFailed:   make(chan error, 1),
 		chanFinished: make(chan struct{}),
 		chanReady:    make(chan struct{}),
@@ -46,24 +58,26 @@ func NewTestServer(t *testing.T, logger hclog.Logger, cfg *GRPCServiceConfig, ct
 }
 
 type testGRPCServerProvider struct {
+	sync.Mutex
+
 	cfg *GRPCServiceConfig
 	ctx *WorkContext
 	srv ServerProvider
 
-	logger hclog.Logger
+	logger   hclog.Logger
+	recorder *RPCStatsRecorder
 
 	abortError              error
 	clientRequestedCommands bool
+	portForwards            []string
 	stdErrOutput            []string
 	stdOutOutput            []string
 	success                 bool
+	warnings                []string
 
-	chanAborted  chan struct{}
 	chanFailed   chan error
 	chanFinished chan struct{}
 	chanReady    chan struct{}
-
-	isAbortedClosed bool
 }
 
 // Start starts a testing server.
@@ -88,39 +102,51 @@ func (p *testGRPCServerProvider) Start() {
 				break out
 
 			case message := <-p.srv.OnMessage():
-				switch tmessage := message.(type) {
-				case *ClientMsgAborted:
-					p.abortError = tmessage.Error
-					close(p.chanAborted)
-				case *ClientMsgSuccess:
-					if p.success {
-						continue out
-					}
-					p.success = true
-					go func() {
-						p.srv.Stop()
-					}()
-				case *ClientMsgStderr:
-					p.stdErrOutput = append(p.stdErrOutput, tmessage.Lines...)
-				case *ClientMsgStdout:
-					p.stdOutOutput = append(p.stdOutOutput, tmessage.Lines...)
-				case *ControlMsgCommandsRequested:
-					p.clientRequestedCommands = true
-				}
-
-			case <-p.chanAborted:
-				if p.isAbortedClosed {
-					continue
-				}
-				p.isAbortedClosed = true
-				go func() {
-					p.srv.Stop()
-				}()
+				p.handleMessage(message)
 			}
 		}
 	}()
 }
 
+func (p *testGRPCServerProvider) handleMessage(message interface{}) {
+	p.Lock()
+	defer p.Unlock()
+	switch tmessage := message.(type) {
+	case *ClientMsgAborted:
+		// the server emits the message once, a repeated Abort or Success fails with ErrAlreadyCompleted:
+		p.abortError = tmessage.Error
+		go func() {
+			p.srv.Stop()
+		}()
+	case *ClientMsgSuccess:
+		p.success = true
+		go func() {
+			p.srv.Stop()
+		}()
+	case *ClientMsgStderr:
+		p.stdErrOutput = append(p.stdErrOutput, tmessage.Lines...)
+	case *ClientMsgStdout:
+		p.stdOutOutput = append(p.stdOutOutput, tmessage.Lines...)
+	case *ClientMsgWarning:
+		for _, warning := range tmessage.Warnings {
+			p.warnings = append(p.warnings, tmessage.Path+": "+warning)
+		}
+	case *ControlMsgCommandsRequested:
+		p.clientRequestedCommands = true
+	case *ControlMsgPortForwardRequested:
+		// the test server does not proxy, the host address is handed back as is
+		p.portForwards = append(p.portForwards, tmessage.HostAddress)
+		tmessage.Accept(tmessage.HostAddress)
+	}
+}
+
+// Cancel tells the client to stop the build.
+func (p *testGRPCServerProvider) Cancel(reason error) {
+	if p.srv != nil {
+		p.srv.Cancel(reason)
+	}
+}
+
 // Stop stops a testing server.
 func (p *testGRPCServerProvider) Stop() {
 	if p.srv != nil {
@@ -145,26 +171,55 @@ func (p *testGRPCServerProvider) ReadyNotify() <-chan struct{} {
 
 // Aborted returns the abort error, if client aborted.
 func (p *testGRPCServerProvider) Aborted() error {
+	p.Lock()
+	defer p.Unlock()
 	return p.abortError
 }
 
 // ClientRequestedCommands returns true is the client requested messages from the server at least once.
 func (p *testGRPCServerProvider) ClientRequestedCommands() bool {
+	p.Lock()
+	defer p.Unlock()
 	return p.clientRequestedCommands
 }
 
+// PortForwards returns host addresses of port forwards requested by the client.
+func (p *testGRPCServerProvider) PortForwards() []string {
+	p.Lock()
+	defer p.Unlock()
+	return p.portForwards
+}
+
 // ReceivedStderr returns stderr received from the client.
 func (p *testGRPCServerProvider) ReceivedStderr() []string {
+	p.Lock()
+	defer p.Unlock()
 	return p.stdErrOutput
 }
 
 // ReceivedStderr returns stdout received from the client.
 func (p *testGRPCServerProvider) ReceivedStdout() []string {
+	p.Lock()
+	defer p.Unlock()
 	return p.stdOutOutput
 }
 
+// ReceivedWarnings returns warnings received from the client, prefixed with the resource path.
+func (p *testGRPCServerProvider) ReceivedWarnings() []string {
+	p.Lock()
+	defer p.Unlock()
+	return p.warnings
+}
+
+// RPCStats returns the counters of the calls the server handled so far.
+func (p *testGRPCServerProvider) RPCStats() RPCStatsSnapshot {
+	return p.recorder.Snapshot()
+}
+
 // Succeeded returns true if the client finished successfully.
 func (p *testGRPCServerProvider) Succeeded() bool {
+	p.Lock()
+	defer p.Unlock()
 	return p.success
 }
 
@@ -0,0 +1,235 @@
+//go:build soak
+// +build soak
+
+package rootfs
+
+// The soak test streams random trees through new sessions until the duration has passed
+// and fails when the goroutines, the open file descriptors or the heap grow across sessions.
+// It is not part of the regular tests, run it with:
+//
+//   FIREBUILD_SOAK_DURATION=4h go test -tags soak -run TestSoak -timeout 0 ./build/rootfs
+//
+// or build the test binary with go test -c -tags soak and run it on the target host.
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// defaultSoakDuration is the duration of the soak test, FIREBUILD_SOAK_DURATION overrides it.
+	defaultSoakDuration = time.Minute
+	// soakWarmupSessions are the sessions run before the baseline is taken,
+	// so that lazily initialized state is not reported as a leak.
+	soakWarmupSessions = 5
+	// soakSampleInterval is how often the counts are logged.
+	soakSampleInterval = time.Minute
+	// soakGoroutineSlack and soakFDSlack are the growth over the baseline tolerated at the end of the test.
+	soakGoroutineSlack = 10
+	soakFDSlack        = 10
+	// soakHeapSlack is the heap growth over the baseline tolerated at the end of the test.
+	soakHeapSlack = 32 * 1024 * 1024
+)
+
+// soakCounts are the resource counts of the process between sessions.
+type soakCounts struct {
+	goroutines int
+	fds        int
+	heap       uint64
+}
+
+func (c soakCounts) String() string {
+	return fmt.Sprintf("goroutines=%d fds=%d heap=%d", c.goroutines, c.fds, c.heap)
+}
+
+// sampleSoakCounts collects the garbage and counts the goroutines, the open file descriptors and the heap in use.
+// The file descriptors are counted on systems with /proc only, -1 elsewhere.
+func sampleSoakCounts() soakCounts {
+	runtime.GC()
+	stats := &runtime.MemStats{}
+	runtime.ReadMemStats(stats)
+	fds := -1
+	if entries, err := ioutil.ReadDir("/proc/self/fd"); err == nil {
+		fds = len(entries)
+	}
+	return soakCounts{goroutines: runtime.NumGoroutine(), fds: fds, heap: stats.HeapInuse}
+}
+
+func soakDuration(t *testing.T) time.Duration {
+	if value := os.Getenv("FIREBUILD_SOAK_DURATION"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			t.Fatal("expected FIREBUILD_SOAK_DURATION to be a duration", err)
+		}
+		return duration
+	}
+	return defaultSoakDuration
+}
+
+func soakSeed(t *testing.T) int64 {
+	if value := os.Getenv("FIREBUILD_SOAK_SEED"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Fatal("expected FIREBUILD_SOAK_SEED to be a number", err)
+		}
+		return seed
+	}
+	return time.Now().UnixNano()
+}
+
+// putSoakTree writes a random tree of files and returns the digests of the files by the path relative to the directory.
+func putSoakTree(t *testing.T, random *rand.Rand, dir string) map[string][sha256.Size]byte {
+	digests := map[string][sha256.Size]byte{}
+	dirs := []string{"."}
+	for i := 0; i < 1+random.Intn(8); i++ {
+		dirs = append(dirs, filepath.Join(dirs[random.Intn(len(dirs))], fmt.Sprintf("dir-%d", i)))
+	}
+	for i := 0; i < 1+random.Intn(64); i++ {
+		name := filepath.Join(dirs[random.Intn(len(dirs))], fmt.Sprintf("file-%d", i))
+		size := random.Intn(4 * 1024)
+		if random.Intn(8) == 0 {
+			// a few files span multiple chunks:
+			size = random.Intn(2 * 1024 * 1024)
+		}
+		contents := make([]byte, size)
+		random.Read(contents)
+		MustPutTestResource(t, filepath.Join(dir, name), contents)
+		digests[filepath.ToSlash(name)] = sha256.Sum256(contents)
+	}
+	return digests
+}
+
+// runSoakSession runs a session streaming the tree under the source directory and verifies the written files.
+func runSoakSession(t *testing.T, logger hclog.Logger, random *rand.Rand, tempDir string) {
+	sourceDir, rootDir := filepath.Join(tempDir, "source"), filepath.Join(tempDir, "root")
+	defer os.RemoveAll(sourceDir)
+	defer os.RemoveAll(rootDir)
+	digests := putSoakTree(t, random, sourceDir)
+
+	grpcConfig := &GRPCServiceConfig{
+		ServerName:        "test-grpc-server",
+		BindHostPort:      "127.0.0.1:0",
+		EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+		BuildTimeout:      time.Minute,
+	}
+	srv := New(grpcConfig, logger.Named("grpc-server"))
+	srv.Start(&WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+		},
+	})
+	defer func() {
+		srv.Stop()
+		srv.Wait()
+	}()
+	select {
+	case startErr := <-srv.FailedNotify():
+		t.Fatal("expected the GRPC server to start but it failed", startErr)
+	case <-srv.ReadyNotify():
+	}
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	conn, err := DialConn(logger.Named("grpc-client"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close(context.Background())
+	testClient := conn.Client("soak")
+
+	// every session exercises another combination of the transfer options:
+	opts := &WriteOptions{}
+	if random.Intn(2) == 0 {
+		opts.Multiplex = 1 + random.Intn(8)
+	}
+	if random.Intn(2) == 0 {
+		opts.BatchFileSize = int64(random.Intn(64 * 1024))
+	}
+	assert.Nil(t, testClient.Commands())
+	if !assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, opts), "options: %+v", opts) {
+		return
+	}
+	written := 0
+	filepath.WalkDir(filepath.Join(rootDir, "opt/dir"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(filepath.Join(rootDir, "opt/dir"), path)
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		expected, ok := digests[filepath.ToSlash(rel)]
+		assert.True(t, ok, "unexpected file %s", rel)
+		assert.Equal(t, expected, sha256.Sum256(contents), "digest of %s, options: %+v", rel, opts)
+		written = written + 1
+		return nil
+	})
+	assert.Equal(t, len(digests), written)
+	assert.Nil(t, testClient.Success())
+}
+
+func TestSoak(t *testing.T) {
+	duration, seed := soakDuration(t), soakSeed(t)
+	t.Logf("soaking for %v with seed %d", duration, seed)
+	random := rand.New(rand.NewSource(seed))
+
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.NewNullLogger()
+
+	for i := 0; i < soakWarmupSessions; i++ {
+		runSoakSession(t, logger, random, tempDir)
+	}
+	baseline := sampleSoakCounts()
+	t.Logf("baseline: %v", baseline)
+
+	deadline := time.Now().Add(duration)
+	nextSample := time.Now().Add(soakSampleInterval)
+	sessions := 0
+	for time.Now().Before(deadline) && !t.Failed() {
+		runSoakSession(t, logger, random, tempDir)
+		sessions = sessions + 1
+		if time.Now().After(nextSample) {
+			t.Logf("after %d sessions: %v", sessions, sampleSoakCounts())
+			nextSample = time.Now().Add(soakSampleInterval)
+		}
+	}
+
+	final := sampleSoakCounts()
+	t.Logf("after %d sessions: %v", sessions, final)
+	assert.LessOrEqual(t, final.goroutines, baseline.goroutines+soakGoroutineSlack, "goroutines leaked")
+	if baseline.fds >= 0 {
+		assert.LessOrEqual(t, final.fds, baseline.fds+soakFDSlack, "file descriptors leaked")
+	}
+	assert.LessOrEqual(t, final.heap, baseline.heap+soakHeapSlack, "heap grew")
+}
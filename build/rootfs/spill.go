@@ -0,0 +1,232 @@
+package rootfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+)
+
+// ensureReusableResources wraps the file resources which are not reusable, and the remote file resources
+// when the work context spills them, so that the first read of their contents is kept in the scratch directory
+// and the subsequent reads are served from there. Sensitive and template resources are never wrapped.
+// A scratch directory is created for the work context when it has none, the server removes it when it stops.
+// The resources of the work context are replaced by a new map, the map of the caller is not modified.
+func ensureReusableResources(serverCtx *WorkContext) error {
+	spilled := func(resource resources.ResolvedResource) bool {
+		if resource.IsDir() || !spoolable(resource) {
+			return false
+		}
+		if _, ok := resource.(*spilledResource); ok {
+			return false
+		}
+		return !resources.IsReusable(resource) || (serverCtx.SpillRemoteResources && isRemoteResource(resource))
+	}
+	wrapped := Resources{}
+	needsWrapping := false
+	for resourcePath, resolved := range serverCtx.ResourcesResolved {
+		wrapped[resourcePath] = resolved
+		for _, resource := range resolved {
+			if spilled(resource) {
+				needsWrapping = true
+			}
+		}
+	}
+	if !needsWrapping {
+		return nil
+	}
+	if serverCtx.ScratchDir == nil {
+		scratchDir, err := NewScratchDir(&ScratchDirConfig{})
+		if err != nil {
+			return err
+		}
+		serverCtx.ScratchDir = scratchDir
+	}
+	for resourcePath, resolved := range wrapped {
+		replaced := make([]resources.ResolvedResource, 0, len(resolved))
+		for _, resource := range resolved {
+			if spilled(resource) {
+				resource = NewSpilledResource(resource, serverCtx.ScratchDir)
+			}
+			replaced = append(replaced, resource)
+		}
+		wrapped[resourcePath] = replaced
+	}
+	serverCtx.ResourcesResolved = wrapped
+	return nil
+}
+
+// isRemoteResource returns true if the contents of the resource are fetched from a URI other than memory://.
+func isRemoteResource(resource resources.ResolvedResource) bool {
+	uri := resource.ResolvedURIOrPath()
+	return strings.Contains(uri, "://") && !strings.HasPrefix(uri, resources.MemoryScheme+"://")
+}
+
+// NewSpilledResource wraps a file resource with contents which can be read only once or are expensive to read,
+// for example the body of a HTTP or S3 download. The first read streams the contents and writes them
+// to a file of the scratch directory, capturing the size and the SHA-256 digest. The subsequent reads,
+// for retries, resumed transfers and other guests, wait until the first read finished and read the file.
+// The rest of the contents is written to the file when the first reader is closed early.
+// A failed first read fails the subsequent reads. The file is removed with the scratch directory.
+func NewSpilledResource(resource resources.ResolvedResource, scratchDir *ScratchDir) resources.ResolvedResource {
+	return &spilledResource{ResolvedResource: resource, scratchDir: scratchDir, done: make(chan struct{})}
+}
+
+type spilledResource struct {
+	resources.ResolvedResource
+	scratchDir *ScratchDir
+
+	m       sync.Mutex
+	started bool
+	done    chan struct{}
+	path    string
+	size    int64
+	digest  string
+	err     error
+}
+
+func (r *spilledResource) Reusable() bool {
+	return true
+}
+
+func (r *spilledResource) Unwrap() resources.ResolvedResource {
+	return r.ResolvedResource
+}
+
+// Digest returns the hex encoded SHA-256 digest of the contents, false until the first read finished.
+func (r *spilledResource) Digest() (string, bool) {
+	if !r.finished() {
+		return "", false
+	}
+	return r.digest, true
+}
+
+// Size returns the size of the contents, false until the first read finished.
+func (r *spilledResource) Size() (int64, bool) {
+	if !r.finished() {
+		return 0, false
+	}
+	return r.size, true
+}
+
+func (r *spilledResource) finished() bool {
+	select {
+	case <-r.done:
+		return r.err == nil
+	default:
+		return false
+	}
+}
+
+func (r *spilledResource) Contents() (io.ReadCloser, error) {
+	r.m.Lock()
+	if !r.started {
+		r.started = true
+		defer r.m.Unlock()
+		source, err := r.ResolvedResource.Contents()
+		if err != nil {
+			r.finish(nil, err)
+			return nil, err
+		}
+		file, err := r.scratchDir.Create("resource-")
+		if err != nil {
+			source.Close()
+			r.finish(nil, err)
+			return nil, err
+		}
+		return &teeReader{resource: r, source: source, file: file, digest: sha256.New()}, nil
+	}
+	r.m.Unlock()
+	<-r.done
+	if r.err != nil {
+		return nil, r.err
+	}
+	return os.Open(r.path)
+}
+
+// finish records the outcome of the first read and releases the waiting reads.
+func (r *spilledResource) finish(tee *teeReader, err error) {
+	if err == nil {
+		r.path = tee.file.Name()
+		r.size = tee.size
+		r.digest = hex.EncodeToString(tee.digest.Sum(nil))
+	}
+	r.err = err
+	close(r.done)
+}
+
+// teeReader writes what it reads from the source to the file and the digest. The rest of the source
+// is written when the reader is closed before the end of the source.
+type teeReader struct {
+	resource *spilledResource
+	source   io.ReadCloser
+	file     *ScratchFile
+	digest   hash.Hash
+	size     int64
+	finished bool
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	if t.finished {
+		return 0, io.EOF
+	}
+	n, err := t.source.Read(p)
+	if n > 0 {
+		if writeErr := t.write(p[0:n]); writeErr != nil {
+			t.complete(writeErr)
+			return n, writeErr
+		}
+	}
+	if err == io.EOF {
+		t.complete(nil)
+	} else if err != nil {
+		t.complete(err)
+	}
+	return n, err
+}
+
+func (t *teeReader) Close() error {
+	if !t.finished {
+		_, err := io.Copy(writerFunc(t.write), t.source)
+		t.complete(err)
+	}
+	return nil
+}
+
+func (t *teeReader) write(p []byte) error {
+	if _, err := t.file.Write(p); err != nil {
+		return err
+	}
+	t.digest.Write(p)
+	t.size = t.size + int64(len(p))
+	return nil
+}
+
+func (t *teeReader) complete(err error) {
+	t.finished = true
+	t.source.Close()
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(t.file.Name())
+		t.resource.finish(t, err)
+		return
+	}
+	t.resource.finish(t, nil)
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) error
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	if err := f(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
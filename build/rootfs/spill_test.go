@@ -0,0 +1,189 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOneShotResourceServedRepeatedly(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	contents := strings.Repeat("one shot contents ", 1024*64)
+	oneShot := resources.NewOneShotResource(ioutil.NopCloser(strings.NewReader(contents)), 0644,
+		"one-shot", "/etc/one-shot", commands.DefaultWorkdir(), commands.DefaultUser(), "")
+	assert.False(t, resources.IsReusable(oneShot))
+
+	ress := Resources{"one-shot": []resources.ResolvedResource{oneShot}}
+	buildCtx := &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  ress,
+	}
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, buildCtx)
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	// the resources of the caller are not modified:
+	assert.Equal(t, oneShot, ress["one-shot"][0])
+	assert.NotNil(t, buildCtx.ScratchDir)
+	scratchPath := buildCtx.ScratchDir.Path()
+
+	otherGuest, err := NewClient(logger.Named("other-guest"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	for i, client := range []ClientProvider{testClient, otherGuest, testClient} {
+		rootDir := filepath.Join(tempDir, "root", string(rune('a'+i)))
+		assert.Nil(t, client.WriteResources(context.Background(), "one-shot", rootDir, nil))
+		written, err := ioutil.ReadFile(filepath.Join(rootDir, "etc/one-shot"))
+		if assert.Nil(t, err) {
+			assert.Equal(t, contents, string(written))
+		}
+	}
+	assert.Equal(t, int64(len(contents)), buildCtx.ScratchDir.UsedSize())
+
+	// the scratch directory created for the resources is removed on stop:
+	srv.Stop()
+	_, err = os.Stat(scratchPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOneShotResourceReadOnce(t *testing.T) {
+	oneShot := resources.NewOneShotResource(ioutil.NopCloser(strings.NewReader("contents")), 0644,
+		"one-shot", "/etc/one-shot", commands.DefaultWorkdir(), commands.DefaultUser(), "")
+	reader, err := oneShot.Contents()
+	assert.Nil(t, err)
+	reader.Close()
+	_, err = oneShot.Contents()
+	assert.True(t, errors.Is(err, resources.ErrContentsConsumed))
+
+	scratchDir, err := NewScratchDir(&ScratchDirConfig{})
+	assert.Nil(t, err)
+	defer scratchDir.Cleanup(false)
+
+	// the rest of the source is kept when the first read closes early:
+	wrapped := NewSpilledResource(resources.NewOneShotResource(ioutil.NopCloser(strings.NewReader("contents")), 0644,
+		"one-shot", "/etc/one-shot", commands.DefaultWorkdir(), commands.DefaultUser(), ""), scratchDir)
+	assert.True(t, resources.IsReusable(wrapped))
+	reader, err = wrapped.Contents()
+	assert.Nil(t, err)
+	partial := make([]byte, 3)
+	_, err = reader.Read(partial)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	for i := 0; i < 2; i++ {
+		reader, err = wrapped.Contents()
+		assert.Nil(t, err)
+		read, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Nil(t, reader.Close())
+		assert.Equal(t, "contents", string(read))
+	}
+}
+
+func TestRemoteResourceSpilledOnce(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	contents := strings.Repeat("remote contents ", 1024*64)
+	downloads := int32(0)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		w.Write([]byte(contents))
+	}))
+	defer httpServer.Close()
+
+	remote, err := resources.NewDefaultResolver().ResolveAdd(commands.Add{
+		OriginalSource: filepath.Join(tempDir, "Dockerfile"),
+		Source:         httpServer.URL + "/file",
+		Target:         "/opt/remote",
+		Workdir:        commands.DefaultWorkdir(),
+		User:           commands.DefaultUser(),
+	})
+	assert.Nil(t, err)
+
+	buildCtx := &WorkContext{
+		ExecutableCommands:   []commands.VMInitSerializableCommand{},
+		ResourcesResolved:    Resources{"remote": remote},
+		SpillRemoteResources: true,
+	}
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, buildCtx)
+	defer srv.Stop()
+	go func() {
+		for {
+			select {
+			case <-srv.OnMessage():
+			case <-srv.StoppedNotify():
+				return
+			}
+		}
+	}()
+
+	otherGuest, err := NewClient(logger.Named("other-guest"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+	for i, client := range []ClientProvider{testClient, otherGuest} {
+		rootDir := filepath.Join(tempDir, "root", string(rune('a'+i)))
+		assert.Nil(t, client.WriteResources(context.Background(), "remote", rootDir, nil))
+		written, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/remote"))
+		if assert.Nil(t, err) {
+			assert.Equal(t, contents, string(written))
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	spilled, ok := buildCtx.ResourcesResolved["remote"][0].(*spilledResource)
+	if assert.True(t, ok) {
+		digest := sha256.Sum256([]byte(contents))
+		capturedDigest, ok := spilled.Digest()
+		assert.True(t, ok)
+		assert.Equal(t, hex.EncodeToString(digest[:]), capturedDigest)
+		size, ok := resources.SizeOf(spilled)
+		assert.True(t, ok)
+		assert.Equal(t, int64(len(contents)), size)
+	}
+
+	// the manifest lists the size of the spilled resource without downloading again:
+	manifest, err := testClient.Manifest(context.Background(), "remote")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(manifest)) {
+		assert.Equal(t, int64(len(contents)), manifest[0].Size)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+}
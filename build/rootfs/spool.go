@@ -0,0 +1,369 @@
+package rootfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// errPrefixRead ends reading the spooled contents once a prefix is read.
+var errPrefixRead = errors.New("prefix read")
+
+// DefaultSpoolChunkSize is the default size of the chunks of a spooled file.
+const DefaultSpoolChunkSize = 1024 * 1024
+
+// SpoolConfig configures a spool.
+type SpoolConfig struct {
+	// Location is the directory the spool directory is created in.
+	// Default is the system temporary directory.
+	Location string
+	// ChunkSize is the size of the chunks files are spooled in, DefaultSpoolChunkSize when zero.
+	// Chunks larger than the chunks the server sends are split when served.
+	ChunkSize int
+	// Compress stores the spooled chunks gzip compressed. The chunks are sent as stored
+	// to the clients accepting CompressionGzip and decompressed for the other clients.
+	Compress bool
+}
+
+// Spool is a directory of file resources chunked and hashed once, for serving the same resources
+// to any number of guests by reading the spool instead of the resources. A file is spooled when served
+// for the first time or when prepared with Prepare and spooled again when the local file changes.
+// A spool may be shared by multiple servers.
+type Spool struct {
+	m       sync.Mutex
+	config  *SpoolConfig
+	path    string
+	removed bool
+	files   map[string]*spooledFile
+}
+
+type spooledFile struct {
+	chanReady chan struct{}
+	err       error
+
+	modTime  time.Time
+	size     int64
+	dataPath string
+	chunks   []spooledChunk
+	// digest is the hex encoded SHA-256 digest of the contents:
+	digest string
+}
+
+type spooledChunk struct {
+	compressed bool
+	offset     int64
+	stored     int
+	length     int
+	checksum   []byte
+}
+
+// NewSpool creates a new spool directory.
+func NewSpool(cfg *SpoolConfig) (*Spool, error) {
+	config := &SpoolConfig{}
+	if cfg != nil {
+		*config = *cfg
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultSpoolChunkSize
+	}
+	path, err := ioutil.TempDir(config.Location, "firebuild-spool-")
+	if err != nil {
+		return nil, fmt.Errorf("spool failed: could not create in '%s', reason: %v", config.Location, err)
+	}
+	return &Spool{config: config, path: path, files: map[string]*spooledFile{}}, nil
+}
+
+// Path returns the path of the spool directory.
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Prepare spools the file resources and the files of the directory resources ahead of serving them.
+// Template and sensitive resources are never spooled.
+func (s *Spool) Prepare(ress Resources) error {
+	for _, resolved := range ress {
+		for _, resource := range resolved {
+			if !spoolable(resource) {
+				continue
+			}
+			if !resource.IsDir() {
+				info, _ := localFileInfo(resource.ResolvedURIOrPath())
+				if _, err := s.spooled(resource.ResolvedURIOrPath(), info, resource.Contents); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := filepath.WalkDir(resource.ResolvedURIOrPath(), func(path string, d fs.DirEntry, err error) error {
+				if err != nil || !d.Type().IsRegular() {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				_, err = s.spooled(path, info, func() (io.ReadCloser, error) {
+					return os.Open(path)
+				})
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Remove removes the spool directory.
+func (s *Spool) Remove() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.removed {
+		return nil
+	}
+	if err := os.RemoveAll(s.path); err != nil {
+		return err
+	}
+	s.removed = true
+	s.files = map[string]*spooledFile{}
+	return nil
+}
+
+// spooled returns the spooled file of a resource key, spooling the contents when the key was not spooled yet
+// or the local file changed since. The file info is nil for contents not read from a local file.
+func (s *Spool) spooled(key string, info fs.FileInfo, open func() (io.ReadCloser, error)) (*spooledFile, error) {
+	s.m.Lock()
+	if s.removed {
+		s.m.Unlock()
+		return nil, fmt.Errorf("spool failed: spool '%s' removed", s.path)
+	}
+	file, ok := s.files[key]
+	if ok && info != nil && (!file.modTime.Equal(info.ModTime()) || file.size != info.Size()) {
+		// the file changed since spooled:
+		ok = false
+	}
+	if !ok {
+		file = &spooledFile{chanReady: make(chan struct{})}
+		if info != nil {
+			file.modTime, file.size = info.ModTime(), info.Size()
+		}
+		s.files[key] = file
+		s.m.Unlock()
+		file.err = s.spool(key, file, open)
+		if file.err != nil {
+			s.m.Lock()
+			if s.files[key] == file {
+				delete(s.files, key)
+			}
+			s.m.Unlock()
+		}
+		close(file.chanReady)
+	} else {
+		s.m.Unlock()
+		<-file.chanReady
+	}
+	return file, file.err
+}
+
+// spool chunks, hashes and optionally compresses the contents to a spool data file.
+func (s *Spool) spool(key string, file *spooledFile, open func() (io.ReadCloser, error)) error {
+	reader, err := open()
+	if err != nil {
+		return fmt.Errorf("spool failed: could not open '%s', reason: %v", key, err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.TempFile(s.path, spoolName(key)+"-")
+	if err != nil {
+		return fmt.Errorf("spool failed: could not create data file for '%s', reason: %v", key, err)
+	}
+	defer data.Close()
+
+	buffer := make([]byte, s.config.ChunkSize)
+	offset, size := int64(0), int64(0)
+	digest := sha256.New()
+	for {
+		readBytes, err := io.ReadFull(reader, buffer)
+		if readBytes > 0 {
+			payload := buffer[0:readBytes]
+			checksum := sha256.Sum256(payload)
+			digest.Write(payload)
+			stored := payload
+			if s.config.Compress {
+				compressed := &bytes.Buffer{}
+				writer := gzip.NewWriter(compressed)
+				if _, err := writer.Write(payload); err != nil {
+					return err
+				}
+				if err := writer.Close(); err != nil {
+					return err
+				}
+				stored = compressed.Bytes()
+			}
+			if _, err := data.Write(stored); err != nil {
+				return fmt.Errorf("spool failed: could not write data file for '%s', reason: %v", key, err)
+			}
+			file.chunks = append(file.chunks, spooledChunk{
+				compressed: s.config.Compress,
+				offset:     offset,
+				stored:     len(stored),
+				length:     readBytes,
+				checksum:   checksum[:],
+			})
+			offset = offset + int64(len(stored))
+			size = size + int64(readBytes)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("spool failed: could not read '%s', reason: %v", key, err)
+		}
+	}
+	file.size = size
+	file.digest = hex.EncodeToString(digest.Sum(nil))
+	file.dataPath = data.Name()
+	return nil
+}
+
+// each calls the function with the payload and the checksum of the spooled contents starting at the offset,
+// in payloads of at most the maximum size.
+func (f *spooledFile) each(offset int64, maxSize int, fn func(payload, checksum []byte) error) error {
+	return f.eachContents(offset, maxSize, false, func(contents *proto.ResourceChunk_ResourceContents) error {
+		return fn(contents.Chunk, contents.Checksum)
+	})
+}
+
+// verifyPrefix verifies the prefix of the contents of the length against the hex encoded prefix digest.
+// A prefix which does not match is ErrInvalidArgument.
+func (f *spooledFile) verifyPrefix(length int64, prefixDigest string) error {
+	digest := sha256.New()
+	remaining := length
+	if err := f.each(0, DefaultSpoolChunkSize, func(payload, _ []byte) error {
+		if int64(len(payload)) >= remaining {
+			digest.Write(payload[0:remaining])
+			return errPrefixRead
+		}
+		digest.Write(payload)
+		remaining = remaining - int64(len(payload))
+		return nil
+	}); err != nil && err != errPrefixRead {
+		return err
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != prefixDigest {
+		return fmt.Errorf("%w: prefix of %d bytes does not match the resource", ErrInvalidArgument, length)
+	}
+	return nil
+}
+
+// eachContents calls the function with the contents of the spooled chunks starting at the offset,
+// in chunks of at most the maximum size. When gzipped contents are accepted, a whole chunk stored compressed
+// is passed as stored with the CompressionGzip compression and the uncompressed size, otherwise uncompressed.
+func (f *spooledFile) eachContents(offset int64, maxSize int, acceptGzip bool, fn func(contents *proto.ResourceChunk_ResourceContents) error) error {
+	if offset > f.size {
+		return fmt.Errorf("%w: offset %d beyond the end of the resource", ErrInvalidArgument, offset)
+	}
+	data, err := os.Open(f.dataPath)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	position := int64(0)
+	for _, chunk := range f.chunks {
+		if position+int64(chunk.length) <= offset {
+			position = position + int64(chunk.length)
+			continue
+		}
+		start := 0
+		if offset > position {
+			start = int(offset - position)
+		}
+		position = position + int64(chunk.length)
+		if acceptGzip && chunk.compressed && start == 0 && chunk.stored <= maxSize && chunk.stored < chunk.length {
+			stored, err := f.readStored(data, chunk)
+			if err != nil {
+				return err
+			}
+			if err := fn(&proto.ResourceChunk_ResourceContents{Chunk: stored, Checksum: chunk.checksum,
+				Compression: CompressionGzip, Size: int64(chunk.length)}); err != nil {
+				return err
+			}
+			continue
+		}
+		payload, err := f.readChunk(data, chunk)
+		if err != nil {
+			return err
+		}
+		if start == 0 && len(payload) <= maxSize {
+			if err := fn(&proto.ResourceChunk_ResourceContents{Chunk: payload, Checksum: chunk.checksum}); err != nil {
+				return err
+			}
+			continue
+		}
+		for start < len(payload) {
+			end := minInt(start+maxSize, len(payload))
+			checksum := sha256.Sum256(payload[start:end])
+			if err := fn(&proto.ResourceChunk_ResourceContents{Chunk: payload[start:end], Checksum: checksum[:]}); err != nil {
+				return err
+			}
+			start = end
+		}
+	}
+	return nil
+}
+
+// readChunk returns the uncompressed contents of the chunk.
+func (f *spooledFile) readChunk(data *os.File, chunk spooledChunk) ([]byte, error) {
+	stored, err := f.readStored(data, chunk)
+	if err != nil {
+		return nil, err
+	}
+	if !chunk.compressed {
+		return stored, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, fmt.Errorf("spool failed: could not decompress data file '%s', reason: %v", f.dataPath, err)
+	}
+	payload := make([]byte, chunk.length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, fmt.Errorf("spool failed: could not decompress data file '%s', reason: %v", f.dataPath, err)
+	}
+	return payload, nil
+}
+
+// readStored returns the chunk as stored in the data file.
+func (f *spooledFile) readStored(data *os.File, chunk spooledChunk) ([]byte, error) {
+	stored := make([]byte, chunk.stored)
+	if _, err := data.ReadAt(stored, chunk.offset); err != nil {
+		return nil, fmt.Errorf("spool failed: could not read data file '%s', reason: %v", f.dataPath, err)
+	}
+	return stored, nil
+}
+
+// spoolName returns the name of the data file of a resource key.
+func spoolName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[0:8])
+}
+
+// localFileInfo returns the file info of a path if the path is a local regular file.
+func localFileInfo(path string) (fs.FileInfo, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, false
+	}
+	return info, true
+}
@@ -0,0 +1,114 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolServesChunksFromOffset(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		spool, err := NewSpool(&SpoolConfig{ChunkSize: 4, Compress: compress})
+		assert.Nil(t, err)
+
+		opened := 0
+		open := func() (io.ReadCloser, error) {
+			opened = opened + 1
+			return ioutil.NopCloser(bytes.NewReader([]byte("0123456789"))), nil
+		}
+		spooled, err := spool.spooled("key", nil, open)
+		assert.Nil(t, err)
+		_, err = spool.spooled("key", nil, open)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, opened, "contents are spooled once")
+
+		served := []string{}
+		assert.Nil(t, spooled.each(5, 2, func(payload, checksum []byte) error {
+			expected := sha256.Sum256(payload)
+			assert.Equal(t, expected[:], checksum)
+			served = append(served, string(payload))
+			return nil
+		}))
+		assert.Equal(t, []string{"56", "7", "89"}, served)
+
+		assert.NotNil(t, spooled.each(11, 2, func(payload, checksum []byte) error { return nil }))
+
+		assert.Nil(t, spool.Remove())
+		_, err = os.Stat(spool.Path())
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestServerServesFromSpool(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "dir/a"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "dir/sub/b"), []byte("b"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "file"), []byte("original contents"))
+
+	ress := Resources{
+		"dir": []resources.ResolvedResource{
+			resources.NewResolvedDirectoryResourceWithPath(0755, filepath.Join(sourceDir, "dir"), "dir", "/opt/dir",
+				commands.DefaultWorkdir(), commands.DefaultUser()),
+		},
+		"file": []resources.ResolvedResource{
+			resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+				return os.Open(filepath.Join(sourceDir, "file"))
+			}, fs.FileMode(0644), "file", "/opt/file", commands.DefaultWorkdir(), commands.DefaultUser(), filepath.Join(sourceDir, "file")),
+		},
+	}
+
+	spool, err := NewSpool(&SpoolConfig{Location: tempDir, ChunkSize: 4, Compress: true})
+	assert.Nil(t, err)
+	defer spool.Remove()
+	assert.Nil(t, spool.Prepare(ress))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  ress,
+		Spool:              spool,
+	})
+	defer cleanupFunc()
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, nil))
+	assert.Nil(t, testClient.WriteResources(context.Background(), "file", rootDir, nil))
+	for entryPath, expected := range map[string]string{
+		"opt/dir/a":     "a",
+		"opt/dir/sub/b": "b",
+		"opt/file":      "original contents",
+	} {
+		contents, err := ioutil.ReadFile(filepath.Join(rootDir, entryPath))
+		if assert.Nil(t, err) {
+			assert.Equal(t, expected, string(contents))
+		}
+	}
+
+	// a changed file is spooled again:
+	MustPutTestResource(t, filepath.Join(sourceDir, "file"), []byte("changed"))
+	assert.Nil(t, os.Chtimes(filepath.Join(sourceDir, "file"), time.Now(), time.Now().Add(time.Minute)))
+	assert.Nil(t, testClient.WriteResources(context.Background(), "file", rootDir, nil))
+	contents, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/file"))
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", string(contents))
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
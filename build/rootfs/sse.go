@@ -0,0 +1,144 @@
+package rootfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultSSEProgressInterval is the default interval of checking the build summary for progress.
+const DefaultSSEProgressInterval = time.Millisecond * 500
+
+// SSEHandlerOptions configures the server-sent events handler.
+type SSEHandlerOptions struct {
+	// ProgressInterval is the interval of checking the build summary for progress,
+	// a progress event is sent only when the summary changed.
+	ProgressInterval time.Duration
+}
+
+// WithDefaultsApplied applies default configuration values to unconfigured properties.
+func (o *SSEHandlerOptions) WithDefaultsApplied() *SSEHandlerOptions {
+	if o.ProgressInterval == 0 {
+		o.ProgressInterval = DefaultSSEProgressInterval
+	}
+	return o
+}
+
+// NewSSEHandler returns an http.Handler streaming the build output and progress
+// of a server as server-sent events:
+//   - stdout and stderr events for every log line, the data is {"line":"...","time":"..."},
+//   - progress events when the build summary changes, the data is the JSON progress,
+//   - a done event with the final progress when the server stops, the stream ends afterwards.
+//
+// The handler subscribes to logs and does not affect OnMessage consumers.
+func NewSSEHandler(provider ServerProvider) http.Handler {
+	return NewSSEHandlerWithOptions(provider, &SSEHandlerOptions{})
+}
+
+// NewSSEHandlerWithOptions returns a server-sent events http.Handler configured with options.
+func NewSSEHandlerWithOptions(provider ServerProvider, opts *SSEHandlerOptions) http.Handler {
+	return &sseHandler{provider: provider, opts: opts.WithDefaultsApplied()}
+}
+
+type sseHandler struct {
+	provider ServerProvider
+	opts     *SSEHandlerOptions
+}
+
+type sseLogLine struct {
+	Line string    `json:"line"`
+	Time time.Time `json:"time"`
+}
+
+type sseProgress struct {
+	CommandsCount    int    `json:"commandsCount"`
+	CommandsFinished int    `json:"commandsFinished"`
+	ResourcesServed  int    `json:"resourcesServed"`
+	BytesServed      int64  `json:"bytesServed"`
+	StderrLines      int    `json:"stderrLines"`
+	StdoutLines      int    `json:"stdoutLines"`
+	Finished         bool   `json:"finished"`
+	Success          bool   `json:"success"`
+	Error            string `json:"error,omitempty"`
+}
+
+func newSSEProgress(summary BuildSummary) sseProgress {
+	progress := sseProgress{
+		CommandsCount:    summary.CommandsCount,
+		CommandsFinished: summary.CommandsFinished,
+		ResourcesServed:  summary.ResourcesServed,
+		BytesServed:      summary.BytesServed,
+		StderrLines:      summary.StderrLines,
+		StdoutLines:      summary.StdoutLines,
+		Finished:         !summary.FinishedAt.IsZero(),
+		Success:          summary.Success,
+	}
+	if summary.Error != nil {
+		progress.Error = summary.Error.Error()
+	}
+	return progress
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	chanLines, unsubscribe := h.provider.SubscribeLogs()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.opts.ProgressInterval)
+	defer ticker.Stop()
+
+	lastProgress := newSSEProgress(h.provider.Summary())
+	if writeSSEEvent(w, "progress", lastProgress) != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case line, ok := <-chanLines:
+			if !ok {
+				writeSSEEvent(w, "done", newSSEProgress(h.provider.Summary()))
+				flusher.Flush()
+				return
+			}
+			if writeSSEEvent(w, line.Stream.String(), sseLogLine{Line: line.Line, Time: line.Time}) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			progress := newSSEProgress(h.provider.Summary())
+			if progress == lastProgress {
+				continue
+			}
+			lastProgress = progress
+			if writeSSEEvent(w, "progress", progress) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w io.Writer, event string, data interface{}) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, dataBytes)
+	return err
+}
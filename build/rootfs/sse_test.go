@@ -0,0 +1,67 @@
+package rootfs
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEHandlerStreamsLogsAndProgress(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	srv, testClient := mustStartServerAndClient(t, logger, &GRPCServiceConfig{}, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  make(Resources),
+	})
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	httpServer := httptest.NewServer(NewSSEHandlerWithOptions(srv, &SSEHandlerOptions{ProgressInterval: time.Millisecond * 10}))
+	defer httpServer.Close()
+
+	response, err := http.Get(httpServer.URL)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	assert.Nil(t, testClient.StdOut([]string{"out 1"}))
+	assert.Nil(t, testClient.StdErr([]string{"err 1"}))
+	assert.Nil(t, testClient.Success())
+	// the progress must be observed before the stream ends:
+	time.Sleep(time.Millisecond * 100)
+	srv.Stop()
+
+	events := map[string][]string{}
+	order := []string{}
+	scanner := bufio.NewScanner(response.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			event = strings.TrimPrefix(line, "event: ")
+			order = append(order, event)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			events[event] = append(events[event], strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	assert.Equal(t, "progress", order[0])
+	assert.Equal(t, "done", order[len(order)-1])
+	assert.Equal(t, 1, len(events["stdout"]))
+	assert.Contains(t, events["stdout"][0], `"line":"out 1"`)
+	assert.Equal(t, 1, len(events["stderr"]))
+	assert.Contains(t, events["stderr"][0], `"line":"err 1"`)
+	assert.Contains(t, events["done"][0], `"success":true`)
+	assert.Contains(t, events["progress"][len(events["progress"])-1], `"stdoutLines":1`)
+}
@@ -0,0 +1,80 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHDialerConfig configures connecting to the server through an SSH server,
+// for example the hypervisor host running the server.
+type SSHDialerConfig struct {
+	// Address is the host and port of the SSH server, port 22 when no port is given.
+	Address string
+	// ClientConfig contains the user, the authentication methods and the host key verification.
+	ClientConfig *ssh.ClientConfig
+}
+
+// NewSSHDialer returns a dial function connecting to the server through the SSH server, to be used
+// as GRPCClientConfig.Dialer. The server address is resolved by the SSH server, so the server may
+// bind to an address reachable only from the SSH server. The SSH connection is established on the first
+// dial and reused by the following dials, a broken SSH connection is established again.
+func NewSSHDialer(cfg *SSHDialerConfig) TransportDialFunc {
+	dialer := &sshDialer{config: cfg}
+	return dialer.dial
+}
+
+type sshDialer struct {
+	m      sync.Mutex
+	config *SSHDialerConfig
+	client *ssh.Client
+}
+
+func (d *sshDialer) dial(ctx context.Context, address string, _ *tls.Config) (net.Conn, error) {
+	client, err := d.sshClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.Dial("tcp", address)
+	if err == nil {
+		return conn, nil
+	}
+	// the SSH connection may have broken, establish it again:
+	d.m.Lock()
+	if d.client == client {
+		client.Close()
+		d.client = nil
+	}
+	d.m.Unlock()
+	if client, err = d.sshClient(ctx); err != nil {
+		return nil, err
+	}
+	return client.Dial("tcp", address)
+}
+
+func (d *sshDialer) sshClient(ctx context.Context) (*ssh.Client, error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.client != nil {
+		return d.client, nil
+	}
+	sshAddress := d.config.Address
+	if _, _, err := net.SplitHostPort(sshAddress); err != nil {
+		sshAddress = net.JoinHostPort(sshAddress, "22")
+	}
+	conn, err := (&net.Dialer{Timeout: d.config.ClientConfig.Timeout}).DialContext(ctx, "tcp", sshAddress)
+	if err != nil {
+		return nil, fmt.Errorf("ssh dial failed: could not connect to '%s', reason: %v", sshAddress, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, sshAddress, d.config.ClientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh dial failed: handshake with '%s' failed, reason: %v", sshAddress, err)
+	}
+	d.client = ssh.NewClient(sshConn, chans, reqs)
+	return d.client, nil
+}
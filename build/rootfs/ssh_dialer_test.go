@@ -0,0 +1,159 @@
+package rootfs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestClientDialsThroughSSH(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, _ := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+		},
+		ResourcesResolved: make(Resources),
+	})
+	defer srv.Stop()
+
+	chanSucceeded := make(chan struct{})
+	go func() {
+		for message := range srv.OnMessage() {
+			if _, ok := message.(*ClientMsgSuccess); ok {
+				close(chanSucceeded)
+				return
+			}
+		}
+	}()
+
+	sshAddress, forwarded, stopSSH := mustStartTestSSHServer(t, "builder", "secret")
+	defer stopSSH()
+
+	testClient, err := NewClient(logger.Named("grpc-client"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		Dialer: NewSSHDialer(&SSHDialerConfig{
+			Address: sshAddress,
+			ClientConfig: &ssh.ClientConfig{
+				User:            "builder",
+				Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			},
+		}),
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.Success())
+	<-chanSucceeded
+	assert.Equal(t, []string{grpcConfig.BindHostPort}, forwarded())
+}
+
+// mustStartTestSSHServer starts an SSH server forwarding direct-tcpip channels for the user with the password.
+// Returns the address of the server, a function returning the forwarded addresses and a function stopping the server.
+func mustStartTestSSHServer(t *testing.T, user, password string) (string, func() []string, func()) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal("expected SSH host key, got error", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal("expected SSH host key signer, got error", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, given []byte) (*ssh.Permissions, error) {
+			if meta.User() == user && string(given) == password {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("expected SSH listener, got error", err)
+	}
+	forwarded := &testForwards{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, config, forwarded)
+		}
+	}()
+	return listener.Addr().String(), forwarded.get, func() { listener.Close() }
+}
+
+type testForwards struct {
+	m         sync.Mutex
+	addresses []string
+}
+
+func (f *testForwards) add(address string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.addresses = append(f.addresses, address)
+}
+
+func (f *testForwards) get() []string {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return append([]string{}, f.addresses...)
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig, forwarded *testForwards) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		// host string, port uint32, origin host string, origin port uint32:
+		extra := newChannel.ExtraData()
+		hostLength := binary.BigEndian.Uint32(extra[0:4])
+		host := string(extra[4 : 4+hostLength])
+		port := binary.BigEndian.Uint32(extra[4+hostLength : 8+hostLength])
+		address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+		target, err := net.Dial("tcp", address)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+		forwarded.add(address)
+		go ssh.DiscardRequests(requests)
+		go func() {
+			io.Copy(channel, target)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(target, channel)
+			target.Close()
+		}()
+	}
+}
@@ -0,0 +1,20 @@
+package rootfs
+
+// ServerStats contains the current resource usage of the server.
+type ServerStats struct {
+	// BufferedChunkBytes is the number of bytes currently reserved for chunk buffers of active resource streams.
+	BufferedChunkBytes int64
+	// BufferedChunkBytesLimit is the configured limit of BufferedChunkBytes, zero means no limit.
+	BufferedChunkBytesLimit int64
+	// ActiveResourceStreams is the number of resources currently streamed.
+	ActiveResourceStreams int
+	// FileRetries is the number of file opens and reads retried after a transient error, see FileRetryPolicy.
+	FileRetries int64
+	// FileRetryFailures is the number of file opens and reads which failed after all attempts.
+	FileRetryFailures int64
+	// FrameTraces are the most recent traces of the sampled frames of the resource streams, oldest first,
+	// at most MaxFrameTraces. Empty unless GRPCServiceConfig.FrameTraceSampling is set.
+	FrameTraces []FrameTrace
+	// FramesTraced is the number of frames traced so far, including the traces no longer kept.
+	FramesTraced int64
+}
@@ -0,0 +1,165 @@
+package rootfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+)
+
+// BuildState is the state of the build reported by the Status RPC.
+type BuildState int
+
+const (
+	// BuildStatePending means the client has not requested the commands yet.
+	BuildStatePending BuildState = iota
+	// BuildStateRunning means the client requested the commands and has not finished.
+	BuildStateRunning
+	// BuildStateSucceeded means the client finished with success.
+	BuildStateSucceeded
+	// BuildStateAborted means the client aborted.
+	BuildStateAborted
+)
+
+func (s BuildState) String() string {
+	switch s {
+	case BuildStatePending:
+		return "pending"
+	case BuildStateRunning:
+		return "running"
+	case BuildStateSucceeded:
+		return "succeeded"
+	case BuildStateAborted:
+		return "aborted"
+	default:
+		return "unknown"
+	}
+}
+
+// BuildStatus is the progress of the build as seen by the server,
+// for example for a watchdog or a progress display in the guest.
+type BuildStatus struct {
+	State BuildState
+	// CommandsCount is the number of executable commands.
+	CommandsCount int
+	// CommandsFinished is the number of commands acknowledged as finished.
+	CommandsFinished int
+	// CommandsSkipped is the number of commands acknowledged as skipped.
+	CommandsSkipped int
+	// LastAckedIndex is the index of the command of the last acknowledgement, -1 before the first one.
+	LastAckedIndex int
+	// BytesTotal is the size of the local resource files served for the platform of the build,
+	// the contents of remote resources are not known in advance and not included.
+	BytesTotal int64
+	// BytesServed is the number of resource content bytes streamed to the clients.
+	BytesServed int64
+	// BytesRemaining is the number of bytes of BytesTotal not served yet.
+	BytesRemaining int64
+}
+
+// CommandsRemaining returns the number of commands neither finished nor skipped.
+func (s *BuildStatus) CommandsRemaining() int {
+	remaining := s.CommandsCount - s.CommandsFinished - s.CommandsSkipped
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func buildStatusFromProto(response *proto.StatusResponse) *BuildStatus {
+	return &BuildStatus{
+		State:            BuildState(response.State),
+		CommandsCount:    int(response.CommandsCount),
+		CommandsFinished: int(response.CommandsFinished),
+		CommandsSkipped:  int(response.CommandsSkipped),
+		LastAckedIndex:   int(response.LastAckedIndex),
+		BytesTotal:       response.BytesTotal,
+		BytesServed:      response.BytesServed,
+		BytesRemaining:   response.BytesRemaining,
+	}
+}
+
+func (impl *serverImpl) Status(ctx context.Context, _ *proto.Empty) (*proto.StatusResponse, error) {
+	// handle stopped server
+	impl.m.Lock()
+	if impl.stopped {
+		defer impl.m.Unlock()
+		return &proto.StatusResponse{}, ErrServerStopped
+	}
+	impl.m.Unlock()
+
+	// sizing the resources walks the file system, once and without holding the lock:
+	impl.bytesTotalOnce.Do(func() {
+		impl.bytesTotal = resourcesSize(impl.serverCtx.ResourcesResolved, impl.serverCtx.Platform)
+	})
+
+	impl.m.Lock()
+	defer impl.m.Unlock()
+	response := &proto.StatusResponse{
+		State:            proto.StatusResponse_RUNNING,
+		CommandsCount:    int32(impl.summary.CommandsCount),
+		CommandsFinished: int32(impl.summary.CommandsFinished),
+		CommandsSkipped:  int32(impl.summary.CommandsSkipped),
+		LastAckedIndex:   int32(impl.lastAckedIndex),
+		BytesTotal:       impl.bytesTotal,
+		BytesServed:      impl.summary.BytesServed,
+	}
+	switch {
+	case impl.aborted || impl.summary.Error != nil:
+		response.State = proto.StatusResponse_ABORTED
+	case impl.summary.Success:
+		response.State = proto.StatusResponse_SUCCEEDED
+	case !impl.commandsRequested:
+		response.State = proto.StatusResponse_PENDING
+	}
+	if remaining := response.BytesTotal - response.BytesServed; remaining > 0 {
+		response.BytesRemaining = remaining
+	}
+	return response, nil
+}
+
+// Status requests the progress of the build from the server.
+func (c *defaultClient) Status() (*BuildStatus, error) {
+	response, err := c.underlying.Status(context.Background(), &proto.Empty{})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	return buildStatusFromProto(response), nil
+}
+
+// resourcesSize returns the size of the local resource files matching the platform,
+// the files of directory resources included.
+func resourcesSize(resolved Resources, platform string) int64 {
+	size := int64(0)
+	for _, resourceList := range resolved {
+		for _, resource := range resourceList {
+			if !resources.PlatformMatches(resources.PlatformOf(resource), platform) {
+				continue
+			}
+			statResult, err := os.Stat(resource.ResolvedURIOrPath())
+			if err != nil {
+				continue
+			}
+			if statResult.Mode().IsRegular() {
+				size = size + statResult.Size()
+				continue
+			}
+			if !statResult.IsDir() {
+				continue
+			}
+			filepath.WalkDir(resource.ResolvedURIOrPath(), func(_ string, entry fs.DirEntry, err error) error {
+				if err != nil || !entry.Type().IsRegular() {
+					return nil
+				}
+				if info, infoErr := entry.Info(); infoErr == nil {
+					size = size + info.Size()
+				}
+				return nil
+			})
+		}
+	}
+	return size
+}
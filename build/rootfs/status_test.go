@@ -0,0 +1,92 @@
+package rootfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerStatus(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "status.txt")
+	contents := []byte("status of the build")
+	assert.Nil(t, ioutil.WriteFile(filePath, contents, 0644))
+	resource := resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}, 0644, "status.txt", "/status.txt", commands.DefaultWorkdir(), commands.DefaultUser(), filePath)
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.RunWithDefaults("true"),
+			commands.RunWithDefaults("false"),
+		},
+		ResourcesResolved: Resources{"status.txt": {resource}},
+	})
+	defer srv.Stop()
+	go func() {
+		for range srv.OnMessage() {
+		}
+	}()
+
+	status, err := testClient.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, &BuildStatus{
+		State:          BuildStatePending,
+		CommandsCount:  2,
+		LastAckedIndex: -1,
+		BytesTotal:     int64(len(contents)),
+		BytesRemaining: int64(len(contents)),
+	}, status)
+	assert.Equal(t, 2, status.CommandsRemaining())
+
+	assert.Nil(t, testClient.Commands())
+	assert.Nil(t, testClient.CommandStarted(0))
+	assert.Nil(t, testClient.CommandFinished(0, nil))
+	MustReadResources(t, testClient, "status.txt", contents)
+
+	status, err = testClient.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, BuildStateRunning, status.State)
+	assert.Equal(t, 1, status.CommandsFinished)
+	assert.Equal(t, 0, status.LastAckedIndex)
+	assert.Equal(t, 1, status.CommandsRemaining())
+	assert.Equal(t, int64(len(contents)), status.BytesServed)
+	assert.Equal(t, int64(0), status.BytesRemaining)
+
+	assert.Nil(t, testClient.Success())
+	status, err = testClient.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, BuildStateSucceeded, status.State)
+	assert.Equal(t, "succeeded", status.State.String())
+}
+
+func TestResourcesSize(t *testing.T) {
+	tempDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(tempDir, "dir", "nested"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(tempDir, "dir", "a"), []byte(strings.Repeat("a", 3)), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(tempDir, "dir", "nested", "b"), []byte(strings.Repeat("b", 5)), 0644))
+
+	newResource := func(path string) resources.ResolvedResource {
+		return resources.NewResolvedFileResourceWithPath(nil, 0644, path, path, commands.DefaultWorkdir(), commands.DefaultUser(), path)
+	}
+	resolved := Resources{
+		"dir":     {newResource(filepath.Join(tempDir, "dir"))},
+		"a":       {newResource(filepath.Join(tempDir, "dir", "a"))},
+		"missing": {newResource(filepath.Join(tempDir, "missing"))},
+		"arm64":   {resources.NewPlatformVariant("linux/arm64", newResource(filepath.Join(tempDir, "dir", "a")))},
+	}
+	assert.Equal(t, int64(14), resourcesSize(resolved, ""))
+	assert.Equal(t, int64(11), resourcesSize(resolved, "linux/amd64"))
+}
@@ -0,0 +1,69 @@
+package rootfs
+
+import "time"
+
+// BuildSummary contains the information about the progress and the outcome of a build.
+type BuildSummary struct {
+	// BuildID identifies the build.
+	BuildID string
+	// Fingerprint identifies the commands and the resources of the build, see WorkContext.Fingerprint.
+	// Empty unless GRPCServiceConfig.ComputeFingerprint is set, until computed or if the resources were not readable.
+	Fingerprint string
+	// StartedAt is the time the server started serving the work context.
+	StartedAt time.Time
+	// FinishedAt is the time the client finished, zero if the build is in progress.
+	FinishedAt time.Time
+	// CommandsCount is the number of executable commands in the work context.
+	CommandsCount int
+	// CommandsFinished is the number of commands the client acknowledged as finished.
+	CommandsFinished int
+	// CommandsSkipped is the number of commands the client skipped because their guard did not match the guest facts.
+	CommandsSkipped int
+	// CommandRetries is the number of times the client ran a failed command again.
+	CommandRetries int
+	// ResourcesServed is the number of resources streamed to the client,
+	// every file and directory of a directory resource counts separately.
+	ResourcesServed int
+	// BytesServed is the number of resource content bytes streamed to the client.
+	BytesServed int64
+	// StderrLines is the number of stderr lines received from the client.
+	StderrLines int
+	// StdoutLines is the number of stdout lines received from the client.
+	StdoutLines int
+	// TruncatedLogLines is the number of stdout and stderr lines truncated to the maximum line length.
+	TruncatedLogLines int
+	// TruncatedLogBytes is the number of bytes removed from the truncated lines.
+	TruncatedLogBytes int64
+	// Success is true if the client finished successfully.
+	Success bool
+	// Error contains the abort error if the client aborted.
+	Error error
+	// Resumed is true if the server restored the session state from the journal of the build.
+	Resumed bool
+	// Guests contains the outcomes of the guests of a broadcast build keyed by the guest ID,
+	// nil if the build is not broadcast.
+	Guests map[string]GuestSummary
+}
+
+func newBuildSummary(serverCtx *WorkContext, serviceConfig *GRPCServiceConfig) BuildSummary {
+	summary := BuildSummary{
+		BuildID:       serverCtx.BuildID,
+		StartedAt:     time.Now(),
+		CommandsCount: len(serverCtx.ExecutableCommands),
+	}
+	if serviceConfig.BroadcastGuests > 1 {
+		summary.Guests = map[string]GuestSummary{}
+	}
+	return summary
+}
+
+// Duration returns the build duration, the duration so far if the build is in progress.
+func (s BuildSummary) Duration() time.Duration {
+	if s.FinishedAt.IsZero() {
+		return time.Since(s.StartedAt)
+	}
+	return s.FinishedAt.Sub(s.StartedAt)
+}
+
+// BuildHook is a host-side callback executed around build phases.
+type BuildHook func(BuildSummary) error
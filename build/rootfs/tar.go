@@ -0,0 +1,111 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+// FetchResourceAsTar streams all resources served for a path as a tar stream to the writer.
+// Entries are named after the target paths relative to the root. Contents of files with a size
+// announced by the server are written through as chunks arrive, other files are buffered until complete.
+// The tar stream is finished when the server finishes streaming the resources.
+func (c *defaultClient) FetchResourceAsTar(ctx context.Context, path string, w io.Writer) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resourceClient, err := c.underlying.Resource(streamCtx, &proto.ResourceRequest{Path: path, AcceptCompression: acceptedCompression})
+	if err != nil {
+		return fromStatusError(err)
+	}
+
+	tarWriter := tar.NewWriter(w)
+	modTime := time.Now()
+
+	var current *ResourceHeader
+	var buffered *bytes.Buffer
+	sequence := &frameSequence{}
+
+	for {
+		response, err := resourceClient.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(fromStatusError(err), "failed reading chunk")
+		}
+		if err := sequence.next(response); err != nil {
+			return err
+		}
+		switch tresponse := response.GetPayload().(type) {
+		case *proto.ResourceChunk_Header:
+			header, err := decodeResourceHeader(tresponse.Header)
+			if err != nil {
+				return err
+			}
+			current = &header
+			buffered = nil
+			if header.IsDir || header.Size >= 0 {
+				if err := tarWriter.WriteHeader(tarHeader(header, header.Size, modTime)); err != nil {
+					return errors.Wrapf(err, "failed writing tar header for '%s'", header.TargetPath)
+				}
+			} else {
+				buffered = bytes.NewBuffer([]byte{})
+			}
+		case *proto.ResourceChunk_Chunk:
+			contents, err := chunkContents(tresponse.Chunk)
+			if err != nil {
+				return err
+			}
+			if buffered != nil {
+				buffered.Write(contents)
+				continue
+			}
+			if _, err := tarWriter.Write(contents); err != nil {
+				return errors.Wrapf(err, "failed writing tar contents of '%s'", current.TargetPath)
+			}
+		case *proto.ResourceChunk_Eof:
+			if buffered != nil {
+				if err := tarWriter.WriteHeader(tarHeader(*current, int64(buffered.Len()), modTime)); err != nil {
+					return errors.Wrapf(err, "failed writing tar header for '%s'", current.TargetPath)
+				}
+				if _, err := tarWriter.Write(buffered.Bytes()); err != nil {
+					return errors.Wrapf(err, "failed writing tar contents of '%s'", current.TargetPath)
+				}
+			}
+			current = nil
+			buffered = nil
+		case *proto.ResourceChunk_Error:
+			return fmt.Errorf("server failed streaming resource: %s", tresponse.Error.Message)
+		}
+	}
+	return tarWriter.Close()
+}
+
+func tarHeader(header ResourceHeader, size int64, modTime time.Time) *tar.Header {
+	name := strings.TrimPrefix(header.TargetPath, "/")
+	if header.IsDir {
+		if name == "" {
+			name = "."
+		}
+		return &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name + "/",
+			Mode:     int64(header.TargetMode.Perm()),
+			ModTime:  modTime,
+		}
+	}
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(header.TargetMode.Perm()),
+		Size:     size,
+		ModTime:  modTime,
+	}
+}
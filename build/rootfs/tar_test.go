@@ -0,0 +1,85 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientFetchResourceAsTar(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	largeFileContent := getLargeFileContent(t, 10*1024*1024)
+	MustPutTestResource(t, filepath.Join(tempDir, "dir/large-file"), largeFileContent)
+	MustPutTestResource(t, filepath.Join(tempDir, "dir/small-file"), []byte("small"))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, filepath.Join(tempDir, "dir"), "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+			// size not known upfront:
+			"stream": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader([]byte("streamed"))), nil
+				}, fs.FileMode(0600), "stream", "/opt/stream", commands.DefaultWorkdir(), commands.DefaultUser(), "stream"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	expected := []struct {
+		name     string
+		mode     int64
+		contents []byte
+	}{
+		{name: "opt/dir/", mode: 0755},
+		{name: "opt/dir/large-file", mode: 0755, contents: largeFileContent},
+		{name: "opt/dir/small-file", mode: 0755, contents: []byte("small")},
+		{name: "opt/stream", mode: 0600, contents: []byte("streamed")},
+	}
+
+	dirBuffer := bytes.NewBuffer([]byte{})
+	assert.Nil(t, testClient.FetchResourceAsTar(context.Background(), "dir", dirBuffer))
+	streamBuffer := bytes.NewBuffer([]byte{})
+	assert.Nil(t, testClient.FetchResourceAsTar(context.Background(), "stream", streamBuffer))
+
+	tarReader := tar.NewReader(dirBuffer)
+	for _, expectedEntry := range expected {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			tarReader = tar.NewReader(streamBuffer)
+			header, err = tarReader.Next()
+		}
+		if !assert.Nil(t, err) {
+			break
+		}
+		assert.Equal(t, expectedEntry.name, header.Name)
+		assert.Equal(t, expectedEntry.mode, header.Mode)
+		contents, err := ioutil.ReadAll(tarReader)
+		assert.Nil(t, err)
+		if expectedEntry.contents != nil {
+			assert.Equal(t, expectedEntry.contents, contents)
+		}
+	}
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
@@ -0,0 +1,71 @@
+package rootfs
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// FactsMetadataKey is the gRPC metadata key a client sends the facts of its guest in,
+// one name=value pair per value, so that template resources render with the facts.
+const FactsMetadataKey = "firebuild-facts"
+
+// factsFromContext returns the facts the client sent with the request, empty if none.
+func factsFromContext(ctx context.Context) commands.Facts {
+	facts := commands.Facts{}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return facts
+	}
+	for _, value := range md.Get(FactsMetadataKey) {
+		if index := strings.Index(value, "="); index > 0 {
+			facts[value[0:index]] = value[index+1:]
+		}
+	}
+	return facts
+}
+
+func factsStreamClientInterceptor(facts commands.Facts) grpc.StreamClientInterceptor {
+	pairs := []string{}
+	for name, value := range facts {
+		pairs = append(pairs, FactsMetadataKey, name+"="+value)
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, pairs...), desc, cc, method, opts...)
+	}
+}
+
+// resourceContents opens the contents of a file resource for the client of the request,
+// template resources are rendered with the work context and the facts of the guest.
+func (impl *serverImpl) resourceContents(ctx context.Context, resource resources.ResolvedResource) (io.ReadCloser, error) {
+	tmpl := resources.TemplateOf(resource)
+	if tmpl == nil {
+		return impl.fileRetrier.open(resource.ResolvedURIOrPath(), resource.Contents)
+	}
+	return tmpl.Render(resources.TemplateData{
+		BuildID:  impl.serverCtx.BuildID,
+		Platform: impl.serverCtx.Platform,
+		Args:     copyValues(impl.serverCtx.Args),
+		Env:      copyValues(impl.serverCtx.Env),
+		Facts:    factsFromContext(ctx),
+	})
+}
+
+// spoolable returns true if the contents of the resource may be spooled to disk: they are the same
+// for every client and not secret.
+func spoolable(resource resources.ResolvedResource) bool {
+	return resources.TemplateOf(resource) == nil && !resources.IsSensitive(resource)
+}
+
+func copyValues(values map[string]string) map[string]string {
+	copied := map[string]string{}
+	for name, value := range values {
+		copied[name] = value
+	}
+	return copied
+}
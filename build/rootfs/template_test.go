@@ -0,0 +1,86 @@
+package rootfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateResourceRendersPerGuest(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	rendered, err := resources.NewTemplateResource("{{ .Env.GREETING }} from {{ .Facts.arch }} in {{ .BuildID }}", 0644,
+		"config.tmpl", "/etc/config", commands.DefaultWorkdir(), commands.DefaultUser())
+	assert.Nil(t, err)
+	missingKey, err := resources.NewTemplateResource("{{ .Env.MISSING }}", 0644,
+		"missing.tmpl", "/etc/missing", commands.DefaultWorkdir(), commands.DefaultUser())
+	assert.Nil(t, err)
+	_, err = resources.NewTemplateResource("{{ .Env.GREETING", 0644,
+		"invalid.tmpl", "/etc/invalid", commands.DefaultWorkdir(), commands.DefaultUser())
+	assert.NotNil(t, err)
+
+	ress := Resources{
+		"config.tmpl":  []resources.ResolvedResource{rendered},
+		"missing.tmpl": []resources.ResolvedResource{missingKey},
+	}
+	spool, err := NewSpool(&SpoolConfig{Location: tempDir})
+	assert.Nil(t, err)
+	defer spool.Remove()
+	assert.Nil(t, spool.Prepare(ress))
+
+	grpcConfig := &GRPCServiceConfig{}
+	srv, testClient := mustStartServerAndClient(t, logger, grpcConfig, &WorkContext{
+		BuildID:            "build-1",
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  ress,
+		Env:                map[string]string{"GREETING": "hello"},
+		Spool:              spool,
+	})
+	defer srv.Stop()
+
+	read := func(client ClientProvider, path string) (string, error) {
+		reader, _, err := client.OpenResource(context.Background(), path)
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		contents, err := ioutil.ReadAll(reader)
+		return string(contents), err
+	}
+
+	contents, err := read(testClient, "config.tmpl")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello from "+runtime.GOARCH+" in build-1", contents)
+
+	otherGuest, err := NewClient(logger.Named("other-guest"), &GRPCClientConfig{
+		HostPort:  grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+		Facts:     commands.Facts{commands.FactArch: "riscv64"},
+	})
+	assert.Nil(t, err)
+	contents, err = read(otherGuest, "config.tmpl")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello from riscv64 in build-1", contents)
+
+	_, err = read(testClient, "missing.tmpl")
+	assert.NotNil(t, err)
+
+	// the template text identifies the resource outside of the stream:
+	reader, err := rendered.Contents()
+	assert.Nil(t, err)
+	text, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "{{ .Env.GREETING }} from {{ .Facts.arch }} in {{ .BuildID }}", string(text))
+}
@@ -0,0 +1,210 @@
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Wire versions of the RootfsServer service.
+//
+// Every wire version keeps the field numbers and the meaning of the fields of the versions before it,
+// new fields are only added and their zero values select the behaviour of the previous versions.
+// A server serves every wire version with the same handlers, a request of an older client omitting
+// the new fields is served with the previous behaviour.
+const (
+	// WireVersionV1Alpha is the original proto.RootfsServer service,
+	// spoken by the guests built before the service was versioned.
+	WireVersionV1Alpha = "v1alpha"
+	// WireVersionV1 is the firebuild.rootfs.v1.RootfsServer service.
+	WireVersionV1 = "v1"
+)
+
+// DefaultWireVersion is the wire version used by the client when not configured.
+const DefaultWireVersion = WireVersionV1
+
+var wireServiceNames = map[string]string{
+	WireVersionV1Alpha: proto.RootfsServer_ServiceDesc.ServiceName,
+	WireVersionV1:      proto.RootfsServerV1ServiceName,
+}
+
+// WireVersions returns all wire versions, oldest first.
+func WireVersions() []string {
+	return []string{WireVersionV1Alpha, WireVersionV1}
+}
+
+func validateWireVersion(version string) error {
+	if _, ok := wireServiceNames[version]; !ok {
+		return fmt.Errorf("%w: unknown wire version '%s', supported versions: %s",
+			ErrInvalidArgument, version, strings.Join(WireVersions(), ", "))
+	}
+	return nil
+}
+
+// wireServiceDesc returns the service descriptor of the wire version.
+// The handlers are shared by all versions because the versions are wire compatible.
+func wireServiceDesc(version string) *grpc.ServiceDesc {
+	if version == WireVersionV1 {
+		return &proto.RootfsServerV1_ServiceDesc
+	}
+	return &proto.RootfsServer_ServiceDesc
+}
+
+// wireMethod returns the full method name of a method of the v1alpha service in the wire version.
+func wireMethod(fullMethod, version string) string {
+	prefix := "/" + proto.RootfsServer_ServiceDesc.ServiceName + "/"
+	if !strings.HasPrefix(fullMethod, prefix) {
+		return fullMethod
+	}
+	return "/" + wireServiceNames[version] + "/" + strings.TrimPrefix(fullMethod, prefix)
+}
+
+// canonicalMethod returns the full method name of a method of any wire version in the v1alpha service.
+func canonicalMethod(fullMethod string) string {
+	for _, version := range WireVersions() {
+		prefix := "/" + wireServiceNames[version] + "/"
+		if strings.HasPrefix(fullMethod, prefix) {
+			return "/" + proto.RootfsServer_ServiceDesc.ServiceName + "/" + strings.TrimPrefix(fullMethod, prefix)
+		}
+	}
+	return fullMethod
+}
+
+// isUnknownServiceError returns true if the server does not serve the service of a wire version.
+func isUnknownServiceError(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.Unimplemented && strings.HasPrefix(s.Message(), "unknown service")
+}
+
+// wireVersionNegotiator calls the methods in the configured wire version. When the server does not serve
+// the version, as a server built before the version existed, the negotiator falls back to the older versions
+// and keeps using the first version served.
+type wireVersionNegotiator struct {
+	m       sync.Mutex
+	version string
+}
+
+func newWireVersionNegotiator(version string) *wireVersionNegotiator {
+	return &wireVersionNegotiator{version: version}
+}
+
+func (n *wireVersionNegotiator) current() string {
+	n.m.Lock()
+	defer n.m.Unlock()
+	return n.version
+}
+
+// downgrade switches to the version before the failed version,
+// returns false when there is no older version to try.
+func (n *wireVersionNegotiator) downgrade(failed string) (string, bool) {
+	n.m.Lock()
+	defer n.m.Unlock()
+	if n.version != failed {
+		// another call has downgraded already:
+		return n.version, true
+	}
+	versions := WireVersions()
+	for i := len(versions) - 1; i > 0; i-- {
+		if versions[i] == failed {
+			n.version = versions[i-1]
+			return n.version, true
+		}
+	}
+	return failed, false
+}
+
+func (n *wireVersionNegotiator) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	version := n.current()
+	for {
+		err := invoker(ctx, wireMethod(method, version), req, reply, cc, opts...)
+		if !isUnknownServiceError(err) {
+			return err
+		}
+		next, ok := n.downgrade(version)
+		if !ok {
+			return err
+		}
+		version = next
+	}
+}
+
+func (n *wireVersionNegotiator) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	version := n.current()
+	stream, err := streamer(ctx, desc, cc, wireMethod(method, version), opts...)
+	if err != nil || desc.ClientStreams {
+		return stream, err
+	}
+	// the server reports an unknown service with the first received message,
+	// a server stream is replayed with the request in an older version:
+	return &wireVersionClientStream{
+		ClientStream: stream,
+		negotiator:   n,
+		version:      version,
+		open: func(version string) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, wireMethod(method, version), opts...)
+		},
+	}, nil
+}
+
+type wireVersionClientStream struct {
+	grpc.ClientStream
+	negotiator *wireVersionNegotiator
+	version    string
+	open       func(version string) (grpc.ClientStream, error)
+	sent       []interface{}
+	closedSend bool
+	received   bool
+}
+
+func (s *wireVersionClientStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *wireVersionClientStream) CloseSend() error {
+	s.closedSend = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *wireVersionClientStream) RecvMsg(m interface{}) error {
+	for {
+		err := s.ClientStream.RecvMsg(m)
+		if s.received || !isUnknownServiceError(err) {
+			s.received = true
+			return err
+		}
+		next, ok := s.negotiator.downgrade(s.version)
+		if !ok {
+			return err
+		}
+		if replayErr := s.replay(next); replayErr != nil {
+			return replayErr
+		}
+	}
+}
+
+func (s *wireVersionClientStream) replay(version string) error {
+	stream, err := s.open(version)
+	if err != nil {
+		return err
+	}
+	for _, m := range s.sent {
+		if err := stream.SendMsg(m); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	if s.closedSend {
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+	}
+	s.ClientStream, s.version = stream, version
+	return nil
+}
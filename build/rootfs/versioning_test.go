@@ -0,0 +1,125 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWireVersionProtoInSync(t *testing.T) {
+	definitions := func(path string) string {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal("expected the proto file, got error", err)
+		}
+		lines := []string{}
+		for _, line := range strings.Split(string(contents), "\n") {
+			if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "package ") || strings.HasPrefix(line, "option go_package") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	assert.Equal(t, definitions("../../grpc/proto/rootfs_server.proto"), definitions("../../grpc/proto/v1/rootfs_server.proto"),
+		"the v1 proto must define the messages and the service of the v1alpha proto")
+}
+
+func TestClientWireVersions(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	newBuildCtx := func() *WorkContext {
+		return &WorkContext{
+			ExecutableCommands: []commands.VMInitSerializableCommand{
+				commands.RunWithDefaults("true"),
+			},
+			ResourcesResolved: Resources{
+				"file": []resources.ResolvedResource{
+					resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+						return ioutil.NopCloser(bytes.NewReader([]byte("contents"))), nil
+					}, fs.FileMode(0644), "file", "/opt/file", commands.DefaultWorkdir(), commands.DefaultUser(), ""),
+				},
+			},
+		}
+	}
+
+	newClient := func(grpcConfig *GRPCServiceConfig, tlsConfigIndex int, version string) ClientProvider {
+		tlsConfig := grpcConfig.TLSConfigClient
+		if tlsConfigIndex >= 0 {
+			tlsConfig = grpcConfig.TLSConfigObserverClients[tlsConfigIndex]
+		}
+		client, err := NewClient(logger.Named(version), &GRPCClientConfig{
+			HostPort:    grpcConfig.BindHostPort,
+			TLSConfig:   tlsConfig,
+			WireVersion: version,
+		})
+		if err != nil {
+			t.Fatal("expected the client, got error", err)
+		}
+		return client
+	}
+
+	mustStartServer := func(grpcConfig *GRPCServiceConfig) ServerProvider {
+		srv, _ := mustStartServerAndClient(t, logger, grpcConfig, newBuildCtx())
+		go func() {
+			for range srv.OnMessage() {
+			}
+		}()
+		return srv
+	}
+
+	mustWriteResource := func(client ClientProvider, rootDir string) {
+		assert.Nil(t, client.WriteResources(context.Background(), "file", rootDir, nil))
+		contents, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/file"))
+		assert.Nil(t, err)
+		assert.Equal(t, "contents", string(contents))
+	}
+
+	// a server serves every version:
+	grpcConfig := &GRPCServiceConfig{ObserverClients: 2}
+	srv := mustStartServer(grpcConfig)
+	for index, version := range WireVersions() {
+		client := newClient(grpcConfig, index, version)
+		_, err := client.Capabilities()
+		assert.Nil(t, err, "observer allowed in %s", version)
+		assert.Nil(t, client.Commands())
+		assert.NotNil(t, client.NextCommand())
+		mustWriteResource(client, filepath.Join(tempDir, version))
+	}
+	srv.Stop()
+
+	// a client falls back to the version served by an older server:
+	grpcConfig = &GRPCServiceConfig{WireVersions: []string{WireVersionV1Alpha}}
+	srv = mustStartServer(grpcConfig)
+	client := newClient(grpcConfig, -1, WireVersionV1)
+	mustWriteResource(client, filepath.Join(tempDir, "fallback"))
+	assert.Nil(t, client.Commands())
+	srv.Stop()
+
+	// there is no newer version to move to:
+	grpcConfig = &GRPCServiceConfig{WireVersions: []string{WireVersionV1}}
+	srv = mustStartServer(grpcConfig)
+	defer srv.Stop()
+	err = newClient(grpcConfig, -1, WireVersionV1Alpha).Commands()
+	assert.True(t, errors.Is(err, ErrProtocolMismatch), "expected protocol mismatch, got %v", err)
+
+	_, err = NewClient(logger, &GRPCClientConfig{HostPort: grpcConfig.BindHostPort, WireVersion: "v0"})
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+}
@@ -0,0 +1,110 @@
+package rootfs
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// routineGroup accounts for the goroutines of a server: the goroutines it starts
+// and the request handlers in flight, so that Wait returns only when all of them have exited.
+// Once closed, new request handlers are rejected with ErrServerStopped and new goroutines are refused
+// unless started by accounted work still in flight, for example an embedded server receiving requests after Stop.
+type routineGroup struct {
+	m       sync.Mutex
+	exited  *sync.Cond
+	closed  bool
+	running int
+}
+
+func newRoutineGroup() *routineGroup {
+	g := &routineGroup{}
+	g.exited = sync.NewCond(&g.m)
+	return g
+}
+
+// enter accounts for a goroutine, returns false when refused. A request handler is refused once the group
+// is closed, a goroutine once the group is closed and nothing accounted for is running.
+func (g *routineGroup) enter(handler bool) bool {
+	g.m.Lock()
+	defer g.m.Unlock()
+	if g.closed && (handler || g.running == 0) {
+		return false
+	}
+	g.running = g.running + 1
+	return true
+}
+
+func (g *routineGroup) exit() {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.running = g.running - 1
+	if g.running == 0 {
+		g.exited.Broadcast()
+	}
+}
+
+// goTracked runs the function in a goroutine accounted for by the group. Returns false
+// when the group refused the goroutine, the function is not run.
+func (g *routineGroup) goTracked(f func()) bool {
+	if !g.enter(false) {
+		return false
+	}
+	go func() {
+		defer g.exit()
+		f()
+	}()
+	return true
+}
+
+// close rejects new request handlers and goroutines, the accounted goroutines keep running.
+func (g *routineGroup) close() {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.closed = true
+}
+
+// wait closes the group and blocks until all accounted goroutines have exited.
+func (g *routineGroup) wait() {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.closed = true
+	for g.running > 0 {
+		g.exited.Wait()
+	}
+}
+
+func (g *routineGroup) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !g.enter(true) {
+		return nil, toStatusError(ErrServerStopped, "")
+	}
+	defer g.exit()
+	return handler(ctx, req)
+}
+
+func (g *routineGroup) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !g.enter(true) {
+		return toStatusError(ErrServerStopped, "")
+	}
+	defer g.exit()
+	return handler(srv, ss)
+}
+
+// Wait blocks until the goroutines of the service have exited, call after Stop.
+func (impl *serverImpl) Wait() {
+	impl.routines.wait()
+}
+
+// Wait blocks until the internal goroutines of the server have exited: the serving goroutine,
+// the request handlers, the resource walkers, the debug sessions and the build timeout.
+// Call after Stop so that a long running process does not accumulate goroutines per build.
+func (s *grpcSvc) Wait() {
+	s.routines.wait()
+	s.Lock()
+	svc := s.svc
+	s.Unlock()
+	if svc != nil {
+		svc.Wait()
+	}
+}
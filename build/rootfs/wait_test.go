@@ -0,0 +1,104 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestServerWaitLeavesNoGoroutines(t *testing.T) {
+	ignoreCurrent := goleak.IgnoreCurrent()
+
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "a"), []byte("a"))
+	MustPutTestResource(t, filepath.Join(sourceDir, "sub/b"), []byte("b"))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	for i := 0; i < 3; i++ {
+		grpcConfig := &GRPCServiceConfig{
+			ServerName:        "test-grpc-server",
+			BindHostPort:      "127.0.0.1:0",
+			EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+			BuildTimeout:      time.Minute,
+		}
+		srv := New(grpcConfig, logger.Named("grpc-server"))
+		srv.Start(&WorkContext{
+			ExecutableCommands: []commands.VMInitSerializableCommand{commands.RunWithDefaults("true")},
+			ResourcesResolved: Resources{
+				"dir": []resources.ResolvedResource{
+					resources.NewResolvedDirectoryResourceWithPath(0755, sourceDir, "dir", "/opt/dir",
+						commands.DefaultWorkdir(), commands.DefaultUser()),
+				},
+			},
+		})
+		select {
+		case startErr := <-srv.FailedNotify():
+			t.Fatal("expected the GRPC server to start but it failed", startErr)
+		case <-srv.ReadyNotify():
+		}
+
+		conn, err := DialConn(logger.Named("grpc-client"), &GRPCClientConfig{
+			HostPort:  grpcConfig.BindHostPort,
+			TLSConfig: grpcConfig.TLSConfigClient,
+		})
+		assert.Nil(t, err)
+		testClient := conn.Client("test")
+		go func() {
+			for {
+				select {
+				case <-srv.OnMessage():
+				case <-srv.StoppedNotify():
+					return
+				}
+			}
+		}()
+		assert.Nil(t, testClient.Commands())
+		assert.Nil(t, testClient.WriteResources(context.Background(), "dir", filepath.Join(tempDir, "root"), nil))
+		assert.Nil(t, testClient.Success())
+		assert.Nil(t, conn.Close(context.Background()))
+
+		srv.Stop()
+		srv.Wait()
+	}
+
+	goleak.VerifyNone(t, ignoreCurrent)
+}
+
+func TestRoutineGroupRejectsAfterClose(t *testing.T) {
+	group := newRoutineGroup()
+	chanRelease := make(chan struct{})
+	chanSpawned := make(chan bool, 1)
+	assert.True(t, group.goTracked(func() {
+		<-chanRelease
+		// accounted work still in flight may start goroutines after the group closed:
+		chanSpawned <- group.goTracked(func() {})
+	}))
+
+	group.close()
+	_, err := group.unaryInterceptor(context.Background(), nil, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Fatal("expected the handler rejected")
+		return nil, nil
+	})
+	assert.True(t, errors.Is(fromStatusError(err), ErrServerStopped))
+
+	close(chanRelease)
+	assert.True(t, <-chanSpawned)
+	group.wait()
+	assert.False(t, group.goTracked(func() { t.Fatal("expected the goroutine refused") }))
+}
@@ -0,0 +1,522 @@
+package rootfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/grpc/proto"
+	"github.com/pkg/errors"
+)
+
+// modeBits are the mode bits applied to written entries.
+const modeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// WriteOptions configures writing resources to a root directory.
+type WriteOptions struct {
+	// ApplyOwner changes the owner of written entries to the target user of the resource.
+	// Changing the owner usually requires elevated privileges.
+	ApplyOwner bool
+	// Existing, when set, lists the entries the root directory already has as target paths
+	// mapped to hex encoded SHA-256 digests, see ExistingDigests. Only new and changed entries are
+	// transferred and existing entries no longer served are deleted.
+	Existing map[string]string
+	// Layer, when set, receives the entries in its upper directory instead of the root directory.
+	Layer *OverlayLayer
+	// Multiplex, when greater than one, asks the server to stream up to this many files of a directory
+	// resource interleaved on the stream, each file is written by its own writer goroutine.
+	// Speeds up resources with many small files. A server without FeatureMultiplex streams the files one by one.
+	Multiplex int
+	// BatchFileSize, when greater than zero, asks the server to send the files of directory resources up to this size
+	// whole, many files with their headers in one message. Cuts the per file overhead of trees of tiny files.
+	// The server caps the size to fit its messages, a server without FeatureBatch sends every file in chunks.
+	BatchFileSize int64
+	// ProgressInterval, when greater than zero, is the interval the client reports the bytes received,
+	// the throughput and the estimated completion of the resource to the server at, see ClientMsgProgress.
+	// The total is the size of the manifest entries of the resource. For a delta transfer, the total is the size of the files
+	// the server announced so far, the unchanged entries are not included.
+	// A server without FeatureProgress receives no further reports after the first one failed.
+	ProgressInterval time.Duration
+}
+
+// writtenEntry is an entry written by the client, verified after the resources are written.
+type writtenEntry struct {
+	filePath string
+	header   ResourceHeader
+}
+
+// WriteResources writes all resources served for the path under the root directory at their target paths.
+// Missing parent directories are created. Modes are applied explicitly after writing so the process umask
+// does not drop any bits, the modes of directories are applied after all resources are written.
+// Files are synced according to the configured fsync policy.
+//
+// Every file is written under a temporary name in the target directory and renamed to the target path
+// once all its chunks are verified. A directory which does not exist yet is staged under a temporary name
+// with all its contents and moved into place when the directory is complete. An interrupted write never
+// leaves a partially written file at a target path, temporary files are removed on failure.
+//
+// With existing entries, only the delta is transferred and entries no longer served are deleted.
+// With an overlay layer, the entries are written to the upper directory of the layer and the root directory
+// is not written to. An entry written over a whiteout replaces the whiteout, a directory written over
+// a whiteout is made opaque.
+//
+// Once written, every entry is verified and any mode or owner mismatches are reported to the server as warnings.
+func (c *defaultClient) WriteResources(ctx context.Context, path, rootDir string, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	if opts.Layer != nil {
+		rootDir = opts.Layer.UpperDir
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var resourceClient proto.RootfsServer_ResourceClient
+	var err error
+	if opts.Existing != nil {
+		deltaReq := &proto.ResourceDeltaRequest{Path: path, Existing: []*proto.ResourceDeltaRequest_Entry{},
+			Multiplex: int32(opts.Multiplex), BatchFileSize: opts.BatchFileSize, AcceptCompression: acceptedCompression}
+		for targetPath, digest := range opts.Existing {
+			deltaReq.Existing = append(deltaReq.Existing, &proto.ResourceDeltaRequest_Entry{TargetPath: targetPath, Digest: digest})
+		}
+		resourceClient, err = c.underlying.ResourceDelta(streamCtx, deltaReq)
+	} else {
+		resourceClient, err = c.underlying.Resource(streamCtx, &proto.ResourceRequest{Path: path,
+			Multiplex: int32(opts.Multiplex), BatchFileSize: opts.BatchFileSize, AcceptCompression: acceptedCompression})
+	}
+	if err != nil {
+		return fromStatusError(err)
+	}
+
+	progress := c.startProgress(streamCtx, path, opts.ProgressInterval, opts.Existing != nil)
+	written := false
+	defer func() { progress.stop(written) }()
+
+	entries := []writtenEntry{}
+	sequence := &frameSequence{limit: opts.Multiplex}
+	// the entries between their header and their end by resource ID:
+	current := map[string]*writingEntry{}
+	var stage *stagedDirectory
+	defer func() {
+		// only set when the write did not complete:
+		for _, entry := range current {
+			entry.abandon()
+		}
+		if stage != nil {
+			os.RemoveAll(stage.tempPath)
+		}
+	}()
+
+	// begin starts writing the entry of a header, the contents of a file are written
+	// by a writer goroutine when concurrent:
+	begin := func(header ResourceHeader, concurrent bool) (*writingEntry, error) {
+		var err error
+		entry := &writingEntry{writtenEntry: writtenEntry{filePath: targetPathUnder(rootDir, header.TargetPath), header: header}}
+		current[header.ID] = entry
+		if stage != nil && !stage.contains(entry.filePath) {
+			if err := stage.commit(); err != nil {
+				return nil, err
+			}
+			stage = nil
+		}
+		overWhiteout := false
+		if opts.Layer != nil && stage == nil {
+			if overWhiteout, err = opts.Layer.replaceWhiteout(entry.filePath); err != nil {
+				return nil, err
+			}
+		}
+		if header.IsDir {
+			if stage != nil {
+				if err := os.MkdirAll(stage.staged(entry.filePath), 0700); err != nil {
+					return nil, errors.Wrapf(err, "failed creating directory '%s'", entry.filePath)
+				}
+				return entry, nil
+			}
+			if _, statErr := os.Lstat(entry.filePath); os.IsNotExist(statErr) {
+				stage, err = newStagedDirectory(entry.filePath)
+				if err != nil {
+					return nil, err
+				}
+				if overWhiteout {
+					if err := opts.Layer.makeOpaque(stage.tempPath); err != nil {
+						return nil, errors.Wrapf(err, "failed making directory '%s' opaque", entry.filePath)
+					}
+				}
+				return entry, nil
+			}
+			if err := os.MkdirAll(entry.filePath, 0700); err != nil {
+				return nil, errors.Wrapf(err, "failed creating directory '%s'", entry.filePath)
+			}
+			return entry, nil
+		}
+		if stage != nil {
+			// the staged directory is moved into place as a whole:
+			entry.writePath, entry.renameOnEOF = stage.staged(entry.filePath), false
+			entry.file, err = os.OpenFile(entry.writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		} else {
+			if err := os.MkdirAll(filepath.Dir(entry.filePath), 0755); err != nil {
+				return nil, errors.Wrapf(err, "failed creating parent directory of '%s'", entry.filePath)
+			}
+			entry.file, err = ioutil.TempFile(filepath.Dir(entry.filePath), "."+filepath.Base(entry.filePath)+".tmp-")
+			if entry.file != nil {
+				entry.writePath, entry.renameOnEOF = entry.file.Name(), true
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed creating file '%s'", entry.filePath)
+		}
+		entry.synced = c.syncer.track(entry.file)
+		if concurrent {
+			entry.writeConcurrently()
+		}
+		return entry, nil
+	}
+
+	for {
+		response, err := resourceClient.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(fromStatusError(err), "failed reading chunk")
+		}
+		if err := sequence.next(response); err != nil {
+			return err
+		}
+		switch tresponse := response.GetPayload().(type) {
+		case *proto.ResourceChunk_Header:
+			header, err := decodeResourceHeader(tresponse.Header)
+			if err != nil {
+				return err
+			}
+			if _, err := begin(header, opts.Multiplex > 1); err != nil {
+				return err
+			}
+			progress.announce(header)
+		case *proto.ResourceChunk_Chunk:
+			entry := current[tresponse.Chunk.Id]
+			if entry.file == nil {
+				return errors.Wrapf(ErrProtocolMismatch, "chunk of directory resource '%s'", tresponse.Chunk.Id)
+			}
+			contents, err := chunkContents(tresponse.Chunk)
+			if err != nil {
+				return err
+			}
+			if err := entry.write(contents); err != nil {
+				return err
+			}
+			progress.add(len(contents))
+		case *proto.ResourceChunk_Eof:
+			entry := current[tresponse.Eof.Id]
+			if entry.file != nil {
+				if err := entry.finish(opts.ApplyOwner); err != nil {
+					return err
+				}
+			}
+			delete(current, tresponse.Eof.Id)
+			entries = append(entries, entry.writtenEntry)
+		case *proto.ResourceChunk_Batch:
+			for _, batched := range tresponse.Batch.Entries {
+				header, err := decodeResourceHeader(batched.Header)
+				if err != nil {
+					return err
+				}
+				if header.IsDir {
+					return errors.Wrapf(ErrProtocolMismatch, "batched directory resource '%s'", header.ID)
+				}
+				hash := sha256.Sum256(batched.Contents)
+				if string(hash[:]) != string(batched.Checksum) {
+					return errors.Wrapf(ErrChecksumMismatch, "batched resource '%s'", header.ID)
+				}
+				entry, err := begin(header, false)
+				if err != nil {
+					return err
+				}
+				progress.announce(header)
+				if err := entry.write(batched.Contents); err != nil {
+					return err
+				}
+				progress.add(len(batched.Contents))
+				if err := entry.finish(opts.ApplyOwner); err != nil {
+					return err
+				}
+				delete(current, header.ID)
+				entries = append(entries, entry.writtenEntry)
+			}
+		case *proto.ResourceChunk_Delete:
+			if stage != nil {
+				if err := stage.commit(); err != nil {
+					return err
+				}
+				stage = nil
+			}
+			if err := ApplyDelete(commands.Delete{Paths: []string{tresponse.Delete.TargetPath}}, rootDir, opts.Layer); err != nil {
+				return err
+			}
+		case *proto.ResourceChunk_Error:
+			return fmt.Errorf("server failed streaming resource: %s", tresponse.Error.Message)
+		}
+	}
+
+	if stage != nil {
+		if err := stage.commit(); err != nil {
+			return err
+		}
+		stage = nil
+	}
+
+	for _, entry := range entries {
+		if entry.header.IsDir {
+			if err := applyModeAndOwner(entry.filePath, entry.header, opts.ApplyOwner); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.verifyWritten(path, entries, opts.ApplyOwner)
+	written = true
+	return nil
+}
+
+// writingEntry is an entry between its header and its end. The contents of a file are written
+// under the write path, by a writer goroutine when the stream is multiplexed.
+type writingEntry struct {
+	writtenEntry
+	file        *os.File
+	synced      *syncedFile
+	writePath   string
+	renameOnEOF bool
+	chunks      chan []byte
+	done        chan error
+}
+
+// writeConcurrently starts the writer goroutine of the file, so that the files of a multiplexed stream
+// are written in parallel while the frames of the other files are received.
+func (e *writingEntry) writeConcurrently() {
+	e.chunks = make(chan []byte, 4)
+	e.done = make(chan error, 1)
+	go func() {
+		defer close(e.done)
+		for chunk := range e.chunks {
+			if err := e.writeChunk(chunk); err != nil {
+				e.done <- err
+				for range e.chunks {
+				}
+				return
+			}
+		}
+	}()
+}
+
+// write writes a verified chunk to the file or hands it to the writer goroutine.
+func (e *writingEntry) write(chunk []byte) error {
+	if e.chunks == nil {
+		return e.writeChunk(chunk)
+	}
+	select {
+	case e.chunks <- chunk:
+		return nil
+	case err := <-e.done:
+		return err
+	}
+}
+
+func (e *writingEntry) writeChunk(chunk []byte) error {
+	if _, err := e.file.Write(chunk); err != nil {
+		return errors.Wrapf(err, "failed writing file '%s'", e.filePath)
+	}
+	return e.synced.wrote(len(chunk))
+}
+
+// wait stops the writer goroutine and returns its error.
+func (e *writingEntry) wait() error {
+	if e.chunks == nil {
+		return nil
+	}
+	close(e.chunks)
+	err := <-e.done
+	e.chunks, e.done = nil, nil
+	return err
+}
+
+// finish completes the written file: syncs, closes and moves the file into place with the target mode and owner.
+func (e *writingEntry) finish(applyOwner bool) error {
+	if err := e.wait(); err != nil {
+		return err
+	}
+	if err := e.synced.finish(e.filePath); err != nil {
+		return err
+	}
+	if err := e.file.Close(); err != nil {
+		return errors.Wrapf(err, "failed closing file '%s'", e.filePath)
+	}
+	if err := applyModeAndOwner(e.writePath, e.header, applyOwner); err != nil {
+		return err
+	}
+	if e.renameOnEOF {
+		if err := os.Rename(e.writePath, e.filePath); err != nil {
+			return errors.Wrapf(err, "failed moving file '%s' into place", e.filePath)
+		}
+	}
+	e.file = nil
+	return nil
+}
+
+// abandon closes and removes a file which was not completely written.
+func (e *writingEntry) abandon() {
+	if e.file == nil {
+		return
+	}
+	e.wait()
+	e.file.Close()
+	if e.renameOnEOF {
+		os.Remove(e.writePath)
+	}
+}
+
+// stagedDirectory is a new directory written under a temporary name and moved into place when complete.
+type stagedDirectory struct {
+	finalPath string
+	tempPath  string
+}
+
+func newStagedDirectory(finalPath string) (*stagedDirectory, error) {
+	if err := os.MkdirAll(filepath.Dir(finalPath), 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed creating parent directory of '%s'", finalPath)
+	}
+	tempPath, err := ioutil.TempDir(filepath.Dir(finalPath), "."+filepath.Base(finalPath)+".staging-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating staging directory for '%s'", finalPath)
+	}
+	return &stagedDirectory{finalPath: finalPath, tempPath: tempPath}, nil
+}
+
+// contains returns true if the path is the staged directory or is within the staged directory.
+func (s *stagedDirectory) contains(filePath string) bool {
+	return filePath == s.finalPath || strings.HasPrefix(filePath, s.finalPath+string(filepath.Separator))
+}
+
+// staged returns the temporary location of a path within the staged directory.
+func (s *stagedDirectory) staged(filePath string) string {
+	return s.tempPath + strings.TrimPrefix(filePath, s.finalPath)
+}
+
+// commit moves the staged directory into place.
+func (s *stagedDirectory) commit() error {
+	if err := os.Rename(s.tempPath, s.finalPath); err != nil {
+		return errors.Wrapf(err, "failed moving directory '%s' into place", s.finalPath)
+	}
+	return nil
+}
+
+// verifyWritten checks the modes and optionally the owners of the written entries
+// and reports mismatches to the server as warnings.
+func (c *defaultClient) verifyWritten(path string, entries []writtenEntry, verifyOwner bool) {
+	warnings := []string{}
+	for _, entry := range entries {
+		info, err := os.Lstat(entry.filePath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("'%s' not found after writing: %v", entry.header.TargetPath, err))
+			continue
+		}
+		if info.Mode()&modeBits != entry.header.TargetMode&modeBits {
+			warnings = append(warnings, fmt.Sprintf("'%s' has mode %v, expected %v",
+				entry.header.TargetPath, info.Mode()&modeBits, entry.header.TargetMode&modeBits))
+		}
+		if !verifyOwner {
+			continue
+		}
+		uid, gid, err := resolveOwner(entry.header.TargetUser)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("'%s' owner not verifiable: %v", entry.header.TargetPath, err))
+			continue
+		}
+		if actualUID, actualGID, ok := fileOwner(info); ok && (actualUID != uid || actualGID != gid) {
+			warnings = append(warnings, fmt.Sprintf("'%s' is owned by %d:%d, expected %d:%d",
+				entry.header.TargetPath, actualUID, actualGID, uid, gid))
+		}
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		c.fetchLogger.Warn("written resource mismatch", "path", path, "reason", warning)
+	}
+	if err := c.Warning(path, warnings); err != nil {
+		c.fetchLogger.Error("failed reporting warnings", "path", path, "reason", err)
+	}
+}
+
+// applyModeAndOwner applies the target mode and optionally the target owner to a written entry.
+func applyModeAndOwner(filePath string, header ResourceHeader, applyOwner bool) error {
+	if applyOwner {
+		uid, gid, err := resolveOwner(header.TargetUser)
+		if err != nil {
+			return errors.Wrapf(err, "failed resolving owner of '%s'", filePath)
+		}
+		if err := os.Lchown(filePath, uid, gid); err != nil {
+			return errors.Wrapf(err, "failed changing owner of '%s'", filePath)
+		}
+	}
+	// chown may clear the setuid and setgid bits, the mode is applied last:
+	if err := os.Chmod(filePath, header.TargetMode&modeBits); err != nil {
+		return errors.Wrapf(err, "failed changing mode of '%s'", filePath)
+	}
+	return nil
+}
+
+// resolveOwner resolves a user[:group] value to numeric IDs. Names are looked up on the client.
+// Without a group, the primary group of the user is used, or the root group for unknown numeric users.
+func resolveOwner(value string) (int, int, error) {
+	if value == "" || strings.HasPrefix(value, ":") {
+		value = "0" + value
+	}
+	spec, err := commands.ParseUserSpec(value)
+	if err != nil {
+		return 0, 0, err
+	}
+	uid, gid := 0, 0
+	if numeric, ok := spec.UID(); ok {
+		uid = int(numeric)
+		if spec.Group == "" {
+			if u, err := user.LookupId(spec.User); err == nil {
+				gid, _ = strconv.Atoi(u.Gid)
+			}
+		}
+	} else {
+		u, err := user.Lookup(spec.User)
+		if err != nil {
+			return 0, 0, err
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+	if spec.Group == "" {
+		return uid, gid, nil
+	}
+	if numeric, ok := spec.GID(); ok {
+		return uid, int(numeric), nil
+	}
+	g, err := user.LookupGroup(spec.Group)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, _ = strconv.Atoi(g.Gid)
+	return uid, gid, nil
+}
+
+// targetPathUnder returns the file path of a target path under the root directory.
+// The target path never escapes the root directory.
+func targetPathUnder(rootDir, targetPath string) string {
+	return filepath.Join(rootDir, filepath.FromSlash(path.Clean("/"+targetPath)))
+}
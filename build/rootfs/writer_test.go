@@ -0,0 +1,174 @@
+package rootfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/utilstest"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWriteResourcesAppliesModes(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "source")
+	MustPutTestResource(t, filepath.Join(sourceDir, "dir/sub/file"), []byte("file"))
+	assert.Nil(t, os.Chmod(filepath.Join(sourceDir, "dir/sub"), 0777))
+	assert.Nil(t, os.Chmod(filepath.Join(sourceDir, "dir/sub/file"), 0777))
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"dir": []resources.ResolvedResource{
+				resources.NewResolvedDirectoryResourceWithPath(0755, filepath.Join(sourceDir, "dir"), "dir", "/opt/dir",
+					commands.DefaultWorkdir(), commands.DefaultUser()),
+			},
+			"script": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("#!/bin/sh"))), nil
+				}, fs.FileMode(0777), "script", "/usr/bin/script", commands.DefaultWorkdir(), commands.DefaultUser(), "script"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, nil))
+	assert.Nil(t, testClient.WriteResources(context.Background(), "script", rootDir, nil))
+
+	// the modes are not subject to the umask:
+	for _, entryPath := range []string{"opt/dir/sub", "opt/dir/sub/file", "usr/bin/script"} {
+		info, err := os.Stat(filepath.Join(rootDir, entryPath))
+		if assert.Nil(t, err) {
+			assert.Equal(t, fs.FileMode(0777), info.Mode().Perm(), entryPath)
+		}
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/dir/sub/file"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("file"), contents)
+
+	// writing over existing entries replaces the files:
+	assert.Nil(t, testClient.WriteResources(context.Background(), "dir", rootDir, nil))
+
+	// a mismatch found by the verification is reported as a warning:
+	scriptPath := filepath.Join(rootDir, "usr/bin/script")
+	assert.Nil(t, os.Chmod(scriptPath, 0700))
+	testClient.(*defaultClient).verifyWritten("script", []writtenEntry{{
+		filePath: scriptPath,
+		header:   ResourceHeader{TargetPath: "/usr/bin/script", TargetMode: 0777},
+	}}, false)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+
+	assert.Equal(t, []string{"script: '/usr/bin/script' has mode -rwx------, expected -rwxrwxrwx"}, testServer.ReceivedWarnings())
+}
+
+type blockingReader struct {
+	chanRelease chan struct{}
+	read        bool
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if !r.read {
+		r.read = true
+		return copy(p, []byte("first chunk")), nil
+	}
+	<-r.chanRelease
+	return 0, io.EOF
+}
+
+func (r *blockingReader) Close() error {
+	return nil
+}
+
+func TestClientWriteResourcesInterrupted(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	chanRelease := make(chan struct{})
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+	testServer, testClient, cleanupFunc := MustStartTestGRPCServer(t, logger, &WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved: Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return &blockingReader{chanRelease: chanRelease}, nil
+				}, fs.FileMode(0644), "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+			},
+		},
+	})
+	defer cleanupFunc()
+
+	rootDir := filepath.Join(tempDir, "root")
+	ctx, cancel := context.WithCancel(context.Background())
+	chanResult := make(chan error, 1)
+	go func() {
+		chanResult <- testClient.WriteResources(ctx, "file", rootDir, nil)
+	}()
+
+	// wait for the first chunk to land in the temporary file:
+	utilstest.MustEventuallyWithDefaults(t, func() error {
+		entries, err := os.ReadDir(filepath.Join(rootDir, "etc"))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && info.Size() > 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("no contents written yet")
+	})
+	_, statErr := os.Stat(filepath.Join(rootDir, "etc/file"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	cancel()
+	assert.NotNil(t, <-chanResult)
+	close(chanRelease)
+
+	// nothing is left behind:
+	entries, err := os.ReadDir(filepath.Join(rootDir, "etc"))
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	assert.Nil(t, testClient.Success())
+	<-testServer.FinishedNotify()
+}
+
+func TestTargetPathUnder(t *testing.T) {
+	assert.Equal(t, filepath.Join("/root", "etc/passwd"), targetPathUnder("/root", "/../../etc/passwd"))
+	assert.Equal(t, filepath.Join("/root", "opt/file"), targetPathUnder("/root", "opt/./file"))
+}
+
+func TestResolveOwner(t *testing.T) {
+	for value, expected := range map[string][2]int{
+		"0:0":       {0, 0},
+		"1000:1001": {1000, 1001},
+		"root":      {0, 0},
+		"":          {0, 0},
+	} {
+		uid, gid, err := resolveOwner(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, [2]int{uid, gid}, value)
+	}
+	_, _, err := resolveOwner("no-such-user-on-this-host")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,127 @@
+// Package secrets resolves ADD and COPY sources from a secret store at serve time,
+// for example vault://secret/app/database#password.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+)
+
+const (
+	// DefaultMode is the mode of the resolved secrets.
+	DefaultMode = fs.FileMode(0400)
+	// DefaultReadTimeout is the default timeout of reading a secret from the store.
+	DefaultReadTimeout = 30 * time.Second
+)
+
+// ErrSecretNotFound is returned when the store has no secret or no field at the path.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Store reads the key-value pairs of a secret, for example a HashiCorp Vault KV secret.
+type Store interface {
+	Read(ctx context.Context, path string) (map[string]string, error)
+}
+
+// LeasedStore is a store readable only while its lease is renewed, for example with an expiring token.
+type LeasedStore interface {
+	Store
+	// Lease returns the lease of the store, the same value for every call.
+	Lease() resources.Lease
+}
+
+// Register registers the store as the resolver of the sources with the scheme,
+// for example Register("vault", store) resolves vault://app/database#password.
+// The path of the source is the path of the secret in the store and the fragment selects a field.
+// Without a fragment, the only field of the secret or all fields as a JSON object are served.
+// The secret is read from the store every time it is requested, held in memory only and never written
+// to disk. The resources are sensitive: the server serves them with the Secret RPC only and the client
+// reads them into memory. The lease of a LeasedStore is renewed by the server for the lifetime of the build.
+func Register(scheme string, store Store) {
+	resources.RegisterScheme(scheme, SchemeHandler(store))
+}
+
+// SchemeHandler returns the scheme handler resolving the sources from the store.
+func SchemeHandler(store Store) resources.SchemeHandler {
+	return func(request resources.SchemeRequest) ([]resources.ResolvedResource, error) {
+		uri, err := url.Parse(request.URI)
+		if err != nil {
+			return nil, err
+		}
+		secretPath := strings.Trim(uri.Host+uri.Path, "/")
+		if secretPath == "" {
+			return nil, fmt.Errorf("no secret path in '%s'", request.URI)
+		}
+		var lease resources.Lease
+		if leasedStore, ok := store.(LeasedStore); ok {
+			lease = leasedStore.Lease()
+		}
+		return []resources.ResolvedResource{&secretResource{
+			lease: lease,
+			ResolvedResource: resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), DefaultReadTimeout)
+				defer cancel()
+				value, err := readField(ctx, store, secretPath, uri.Fragment)
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.NopCloser(bytes.NewReader(value)), nil
+			}, DefaultMode, request.URI, request.TargetPath, request.TargetWorkdir, request.TargetUser, request.URI),
+		}}, nil
+	}
+}
+
+// secretResource marks the resolved resource as sensitive.
+type secretResource struct {
+	resources.ResolvedResource
+	lease resources.Lease
+}
+
+func (sr *secretResource) Lease() resources.Lease {
+	return sr.lease
+}
+
+func (sr *secretResource) Sensitive() bool {
+	return true
+}
+
+func (sr *secretResource) Unwrap() resources.ResolvedResource {
+	return sr.ResolvedResource
+}
+
+// readField reads a field of the secret, the only field or all fields as JSON when the field is empty.
+func readField(ctx context.Context, store Store, secretPath, field string) ([]byte, error) {
+	values, err := store.Read(ctx, secretPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading secret '%s': %w", secretPath, err)
+	}
+	if field != "" {
+		value, ok := values[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: no field '%s' in '%s'", ErrSecretNotFound, field, secretPath)
+		}
+		return []byte(value), nil
+	}
+	if len(values) == 1 {
+		for _, value := range values {
+			return []byte(value), nil
+		}
+	}
+	return json.Marshal(values)
+}
+
+// ensure the interfaces are implemented:
+var _ resources.SensitiveResource = (*secretResource)(nil)
+var _ resources.WrappedResource = (*secretResource)(nil)
+var _ resources.LeasedResource = (*secretResource)(nil)
+var _ LeasedStore = (*VaultStore)(nil)
@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVault struct {
+	m             sync.Mutex
+	reads         int
+	renewals      int
+	leaseRenewals int
+}
+
+func (v *testVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	if r.Header.Get("X-Vault-Token") != "test-token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/secret/data/app/database":
+		v.reads = v.reads + 1
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": map[string]interface{}{"username": "app", "password": "s3cr3t"},
+			},
+		})
+	case "/v1/secret/data/app/token":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": map[string]interface{}{"value": "t0k3n"},
+			},
+		})
+	case "/v1/secret/data/app/dynamic":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":  "secret/app/dynamic/lease",
+			"renewable": true,
+			"data": map[string]interface{}{
+				"data": map[string]interface{}{"value": "dyn4m1c"},
+			},
+		})
+	case "/v1/sys/leases/renew":
+		request := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request["lease_id"] != "secret/app/dynamic/lease" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.leaseRenewals = v.leaseRenewals + 1
+		w.Write([]byte("{}"))
+	case "/v1/auth/token/renew-self":
+		v.renewals = v.renewals + 1
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"lease_duration": 0},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultSecretsResolve(t *testing.T) {
+	vault := &testVault{}
+	vaultServer := httptest.NewServer(vault)
+	defer vaultServer.Close()
+
+	Register("test-vault", NewVaultStore(vaultServer.URL, "test-token"))
+
+	resolve := func(source string) resources.ResolvedResource {
+		resolved, err := resources.NewDefaultResolver().ResolveAdd(commands.Add{
+			OriginalSource: "/context/Dockerfile",
+			Source:         source,
+			Target:         "/run/secrets/value",
+			Workdir:        commands.DefaultWorkdir(),
+			User:           commands.DefaultUser(),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(resolved))
+		return resolved[0]
+	}
+	read := func(resource resources.ResolvedResource) (string, error) {
+		reader, err := resource.Contents()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		contents, err := ioutil.ReadAll(reader)
+		return string(contents), err
+	}
+
+	password := resolve("test-vault://app/database#password")
+	assert.True(t, resources.IsSensitive(password))
+	// the resources of a store share its lease:
+	assert.NotNil(t, resources.LeaseOf(password))
+	assert.Equal(t, resources.LeaseOf(password), resources.LeaseOf(resolve("test-vault://app/token")))
+	assert.Equal(t, DefaultMode, password.TargetMode())
+	// resolving does not read the secret, it is read when streamed:
+	assert.Equal(t, 0, vault.reads)
+	contents, err := read(password)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", contents)
+	contents, err = read(password)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", contents)
+	assert.Equal(t, 2, vault.reads)
+
+	contents, err = read(resolve("test-vault://app/token"))
+	assert.Nil(t, err)
+	assert.Equal(t, "t0k3n", contents)
+
+	contents, err = read(resolve("test-vault://app/database"))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"username":"app","password":"s3cr3t"}`, contents)
+
+	_, err = read(resolve("test-vault://app/database#missing"))
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	_, err = read(resolve("test-vault://app/missing"))
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+}
+
+func TestVaultTokenKeepAlive(t *testing.T) {
+	vault := &testVault{}
+	vaultServer := httptest.NewServer(vault)
+	defer vaultServer.Close()
+
+	store := NewVaultStore(vaultServer.URL, "test-token")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, store.KeepAlive(ctx, 10*time.Millisecond))
+	vault.m.Lock()
+	assert.True(t, vault.renewals > 1)
+	vault.m.Unlock()
+
+	store.SetToken("expired-token")
+	_, err := store.RenewToken(context.Background())
+	assert.NotNil(t, err)
+	assert.NotNil(t, store.KeepAlive(context.Background(), time.Millisecond))
+}
+
+func TestVaultSecretLeaseKeepAlive(t *testing.T) {
+	vault := &testVault{}
+	vaultServer := httptest.NewServer(vault)
+	defer vaultServer.Close()
+
+	store := NewVaultStore(vaultServer.URL, "test-token")
+	store.RenewInterval = 10 * time.Millisecond
+	values, err := store.Read(context.Background(), "app/dynamic")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"value": "dyn4m1c"}, values)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, store.Lease().KeepAlive(ctx))
+	vault.m.Lock()
+	assert.True(t, vault.renewals > 1)
+	assert.True(t, vault.leaseRenewals > 1)
+	vault.m.Unlock()
+}
@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/resources"
+)
+
+const (
+	// DefaultVaultMount is the default mount path of the KV version 2 secrets engine.
+	DefaultVaultMount = "secret"
+	// DefaultRenewInterval is the interval of the renewals until the time to live of the token is known.
+	DefaultRenewInterval = time.Minute
+)
+
+// VaultStore reads the secrets of a HashiCorp Vault KV version 2 secrets engine over the HTTP API.
+type VaultStore struct {
+	// Address is the address of Vault, for example https://vault.example.com:8200.
+	Address string
+	// Mount is the mount path of the KV secrets engine, DefaultVaultMount when empty.
+	Mount string
+	// Namespace is the optional Vault Enterprise namespace.
+	Namespace string
+	// Client optionally replaces the HTTP client, for example to configure TLS.
+	Client *http.Client
+	// RenewInterval is the interval of the renewals of the lease, DefaultRenewInterval when zero.
+	RenewInterval time.Duration
+
+	m      sync.Mutex
+	token  string
+	leases map[string]struct{}
+}
+
+// NewVaultStore returns a store reading the secrets with the token.
+func NewVaultStore(address, token string) *VaultStore {
+	return &VaultStore{Address: address, token: token}
+}
+
+// SetToken replaces the token, for example after the host application logged in again.
+func (s *VaultStore) SetToken(token string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.token = token
+}
+
+// Read reads the latest version of the secret at the path relative to the mount.
+func (s *VaultStore) Read(ctx context.Context, path string) (map[string]string, error) {
+	mount := s.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	response := struct {
+		LeaseID   string `json:"lease_id"`
+		Renewable bool   `json:"renewable"`
+		Data      struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+	if err := s.do(ctx, http.MethodGet, "/v1/"+strings.Trim(mount, "/")+"/data/"+strings.Trim(path, "/"), nil, &response); err != nil {
+		return nil, err
+	}
+	if response.LeaseID != "" && response.Renewable {
+		s.m.Lock()
+		if s.leases == nil {
+			s.leases = map[string]struct{}{}
+		}
+		s.leases[response.LeaseID] = struct{}{}
+		s.m.Unlock()
+	}
+	values := map[string]string{}
+	for key, value := range response.Data.Data {
+		if stringValue, ok := value.(string); ok {
+			values[key] = stringValue
+			continue
+		}
+		jsonValue, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = string(jsonValue)
+	}
+	return values, nil
+}
+
+// RenewToken renews the lease of the token and returns its new time to live.
+func (s *VaultStore) RenewToken(ctx context.Context) (time.Duration, error) {
+	response := struct {
+		Auth struct {
+			LeaseDuration int `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+	if err := s.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", nil, &response); err != nil {
+		return 0, err
+	}
+	return time.Duration(response.Auth.LeaseDuration) * time.Second, nil
+}
+
+// RenewLeases renews the renewable leases of the secrets read from the store.
+// A lease which fails to renew has expired or was revoked and is not renewed again.
+func (s *VaultStore) RenewLeases(ctx context.Context) {
+	s.m.Lock()
+	leaseIDs := make([]string, 0, len(s.leases))
+	for leaseID := range s.leases {
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+	s.m.Unlock()
+	for _, leaseID := range leaseIDs {
+		request := map[string]string{"lease_id": leaseID}
+		if err := s.do(ctx, http.MethodPut, "/v1/sys/leases/renew", request, &struct{}{}); err != nil && ctx.Err() == nil {
+			s.m.Lock()
+			delete(s.leases, leaseID)
+			s.m.Unlock()
+		}
+	}
+}
+
+// Lease returns the lease of the store, renewed by the server for the lifetime of the build.
+func (s *VaultStore) Lease() resources.Lease {
+	return vaultLease{store: s}
+}
+
+type vaultLease struct {
+	store *VaultStore
+}
+
+func (l vaultLease) KeepAlive(ctx context.Context) error {
+	interval := l.store.RenewInterval
+	if interval <= 0 {
+		interval = DefaultRenewInterval
+	}
+	return l.store.KeepAlive(ctx, interval)
+}
+
+// KeepAlive renews the lease of the token at two thirds of its time to live until the context is done,
+// together with the leases of the secrets read from the store, so that the secrets remain readable during
+// a long build. The interval is used until the first renewal and when the token has no time to live.
+// Returns the error of a failed token renewal or nil when the context is done.
+func (s *VaultStore) KeepAlive(ctx context.Context, interval time.Duration) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+		ttl, err := s.RenewToken(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		s.RenewLeases(ctx)
+		if ttl > 0 {
+			interval = ttl * 2 / 3
+		}
+	}
+}
+
+func (s *VaultStore) do(ctx context.Context, method, path string, input, output interface{}) error {
+	var body io.Reader
+	if input != nil {
+		encoded, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.Address, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	request.Header.Set("X-Vault-Token", s.token)
+	s.m.Unlock()
+	if s.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", s.Namespace)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return ErrSecretNotFound
+	}
+	if response.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("vault responded with %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(response.Body).Decode(output)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/spf13/cobra"
+)
+
+func newCommandsCommand(client *clientFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "commands",
+		Short: "Print the commands of the build as JSON lines",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			clientProvider, err := client.newClient()
+			if err != nil {
+				return err
+			}
+			if err := clientProvider.Commands(); err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			for {
+				next := clientProvider.NextCommand()
+				if next == nil {
+					return nil
+				}
+				if err := encoder.Encode(next); err != nil {
+					return err
+				}
+			}
+		},
+	}
+}
+
+func newFetchCommand(client *clientFlags) *cobra.Command {
+	opts := &rootfs.FetchOptions{}
+	var rootDir string
+	command := &cobra.Command{
+		Use:   "fetch PATH...",
+		Short: "Write the resources served for the paths under a root directory",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientProvider, err := client.newClient()
+			if err != nil {
+				return err
+			}
+			return clientProvider.FetchResources(commandContext(cmd), args, rootDir, opts)
+		},
+	}
+	command.Flags().StringVar(&rootDir, "root", ".", "Directory to write the resources under")
+	command.Flags().IntVar(&opts.Concurrency, "concurrency", rootfs.DefaultFetchConcurrency, "Number of resources fetched concurrently")
+	return command
+}
+
+func newLogsCommand(client *clientFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs",
+		Short: "Print the stdout and stderr lines of the build as JSON lines until the build finishes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			clientProvider, err := client.newClient()
+			if err != nil {
+				return err
+			}
+			lines, err := clientProvider.WatchLogs()
+			if err != nil {
+				return err
+			}
+			encoder := rootfs.NewJSONLinesEncoder(cmd.OutOrStdout())
+			ctx := commandContext(cmd)
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case line, ok := <-lines:
+					if !ok {
+						return nil
+					}
+					if err := encoder.Encode(line); err != nil {
+						return err
+					}
+				}
+			}
+		},
+	}
+}
+
+func newAbortCommand(client *clientFlags) *cobra.Command {
+	var reason string
+	command := &cobra.Command{
+		Use:   "abort",
+		Short: "Abort the build",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			clientProvider, err := client.newClient()
+			if err != nil {
+				return err
+			}
+			if err := clientProvider.Abort(errors.New(reason)); err != nil {
+				return fmt.Errorf("failed aborting the build: %v", err)
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&reason, "reason", "aborted manually", "Reason of the abort")
+	return command
+}
+
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
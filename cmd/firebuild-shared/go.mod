@@ -0,0 +1,13 @@
+module github.com/combust-labs/firebuild-shared/cmd/firebuild-shared
+
+go 1.16
+
+replace github.com/combust-labs/firebuild-shared => ../..
+
+require (
+	github.com/combust-labs/firebuild-embedded-ca v0.0.2
+	github.com/combust-labs/firebuild-shared v0.0.0
+	github.com/hashicorp/go-hclog v0.15.0
+	github.com/spf13/cobra v1.2.1
+	github.com/stretchr/testify v1.7.0
+)
@@ -0,0 +1,15 @@
+// Command firebuild-shared interacts with a rootfs build server manually,
+// for example to debug a failing guest by serving a build and stepping through it from a shell.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
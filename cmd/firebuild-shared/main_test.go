@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeAndInteract(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(tempDir, "source"), []byte("contents"), 0644))
+	tlsDir := filepath.Join(tempDir, "tls")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	serveOut, serveOutWriter := io.Pipe()
+	serve := newRootCommand()
+	serve.SetOut(serveOutWriter)
+	serve.SetArgs([]string{"serve", "--host-port", "127.0.0.1:0", "--tls-dir", tlsDir, "--log-level", "error",
+		"--context", tempDir, "--add", "source:/opt/file", "--run", "echo hello"})
+	chanServeErr := make(chan error, 1)
+	go func() {
+		chanServeErr <- serve.ExecuteContext(ctx)
+		serveOutWriter.Close()
+	}()
+
+	lines := bufio.NewScanner(serveOut)
+	if !lines.Scan() {
+		t.Fatal("expected the ready event, serve failed", <-chanServeErr)
+	}
+	ready := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(lines.Bytes(), &ready))
+	assert.Equal(t, "ready", ready["type"])
+	hostPort := ready["bindHostPort"].(string)
+	go func() {
+		for lines.Scan() {
+		}
+	}()
+
+	run := func(args ...string) (string, error) {
+		output := &bytes.Buffer{}
+		command := newRootCommand()
+		command.SetOut(output)
+		command.SetArgs(append(args, "--host-port", hostPort, "--tls-dir", tlsDir, "--log-level", "error"))
+		err := command.ExecuteContext(ctx)
+		return output.String(), err
+	}
+
+	output, err := run("commands", "--observer")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(strings.Split(strings.TrimSpace(output), "\n")))
+	assert.Contains(t, output, "echo hello")
+
+	rootDir := filepath.Join(tempDir, "root")
+	_, err = run("fetch", "source", "--root", rootDir)
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadFile(filepath.Join(rootDir, "opt/file"))
+	assert.Nil(t, err)
+	assert.Equal(t, "contents", string(contents))
+
+	_, err = run("abort", "--observer")
+	assert.NotNil(t, err, "observers cannot abort the build")
+
+	_, err = run("abort", "--reason", "manual test")
+	assert.Nil(t, err)
+	serveErr := <-chanServeErr
+	if assert.NotNil(t, serveErr) {
+		assert.Contains(t, serveErr.Error(), "manual test")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+)
+
+const (
+	caFileName = "ca.pem"
+	// identityClient is the identity of the client executing the build.
+	identityClient = "client"
+	// identityObserver is the identity of a client reading from the build without finishing it.
+	identityObserver = "observer"
+)
+
+// clientFlags are the flags shared by the commands connecting to a server.
+type clientFlags struct {
+	hostPort   string
+	serverName string
+	tlsDir     string
+	observer   bool
+	logLevel   string
+}
+
+func newRootCommand() *cobra.Command {
+	flags := &clientFlags{}
+	command := &cobra.Command{
+		Use:           "firebuild-shared",
+		Short:         "Interact with a rootfs build server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	command.PersistentFlags().StringVar(&flags.hostPort, "host-port", "127.0.0.1:50051", "Host and port of the server")
+	command.PersistentFlags().StringVar(&flags.serverName, "server-name", rootfs.DefaultServerName, "Name of the server, verified against the server certificate")
+	command.PersistentFlags().StringVar(&flags.tlsDir, "tls-dir", "", "Directory with the CA and the client certificates written by serve")
+	command.PersistentFlags().BoolVar(&flags.observer, "observer", false, "Connect with the observer certificate, observers cannot finish the build")
+	command.PersistentFlags().StringVar(&flags.logLevel, "log-level", "info", "Log level")
+	command.MarkPersistentFlagRequired("tls-dir")
+
+	command.AddCommand(
+		newServeCommand(flags),
+		newCommandsCommand(flags),
+		newFetchCommand(flags),
+		newLogsCommand(flags),
+		newAbortCommand(flags),
+	)
+	return command
+}
+
+func (f *clientFlags) logger() hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:   "firebuild-shared",
+		Level:  hclog.LevelFromString(f.logLevel),
+		Output: os.Stderr,
+	})
+}
+
+// newClient connects to the server with the certificates found in the TLS directory.
+func (f *clientFlags) newClient() (rootfs.ClientProvider, error) {
+	identity := identityClient
+	if f.observer {
+		identity = identityObserver
+	}
+	tlsConfig, err := loadClientTLSConfig(f.tlsDir, identity, f.serverName)
+	if err != nil {
+		return nil, err
+	}
+	return rootfs.NewClient(f.logger(), &rootfs.GRPCClientConfig{
+		HostPort:  f.hostPort,
+		TLSConfig: tlsConfig,
+	})
+}
+
+func certFileName(identity string) string {
+	return identity + ".pem"
+}
+
+func keyFileName(identity string) string {
+	return identity + "-key.pem"
+}
+
+func loadClientTLSConfig(tlsDir, identity, serverName string) (*tls.Config, error) {
+	caPEM, err := ioutil.ReadFile(filepath.Join(tlsDir, caFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading CA: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", filepath.Join(tlsDir, caFileName))
+	}
+	certificate, err := tls.LoadX509KeyPair(filepath.Join(tlsDir, certFileName(identity)), filepath.Join(tlsDir, keyFileName(identity)))
+	if err != nil {
+		return nil, fmt.Errorf("failed loading %s certificate: %v", identity, err)
+	}
+	return &tls.Config{
+		ServerName:   serverName,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{certificate},
+	}, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/combust-labs/firebuild-embedded-ca/ca"
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/spf13/cobra"
+)
+
+type serveFlags struct {
+	contextDir      string
+	adds            []string
+	runs            []string
+	connectProtocol bool
+}
+
+func newServeCommand(client *clientFlags) *cobra.Command {
+	flags := &serveFlags{}
+	command := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a build until a client finishes it",
+		Long: `Serves a build of the ADD and RUN commands given with the flags, ADD commands first.
+Writes the CA and the client and observer certificates to the TLS directory
+and the server events as JSON lines to stdout. Stops when a client finishes the build or on interrupt.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runServe(commandContext(cmd), cmd.OutOrStdout(), client, flags)
+		},
+	}
+	command.Flags().StringVar(&flags.contextDir, "context", ".", "Directory the ADD sources are relative to")
+	command.Flags().StringArrayVar(&flags.adds, "add", []string{}, "Resource to serve as SOURCE:TARGET, repeatable")
+	command.Flags().StringArrayVar(&flags.runs, "run", []string{}, "Shell command to execute, repeatable")
+	command.Flags().BoolVar(&flags.connectProtocol, "connect", false, "Serve the Connect protocol alongside gRPC")
+	return command
+}
+
+func runServe(ctx context.Context, out io.Writer, client *clientFlags, flags *serveFlags) error {
+	logger := client.logger()
+
+	workContext, err := flags.workContext()
+	if err != nil {
+		return err
+	}
+
+	grpcConfig := &rootfs.GRPCServiceConfig{
+		BindHostPort:    client.hostPort,
+		ConnectProtocol: flags.connectProtocol,
+		ServerName:      client.serverName,
+	}
+	if err := writeTLSDir(grpcConfig, client.tlsDir); err != nil {
+		return err
+	}
+
+	srv := rootfs.New(grpcConfig, logger.Named("server"))
+	srv.Start(workContext)
+	select {
+	case err := <-srv.FailedNotify():
+		return fmt.Errorf("server failed to start: %v", err)
+	case <-srv.ReadyNotify():
+	}
+	defer srv.Stop()
+
+	encoder := rootfs.NewJSONLinesEncoder(out)
+	encoder.Encode(&rootfs.ServerReady{BindHostPort: grpcConfig.BindHostPort})
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-srv.StoppedNotify():
+			return nil
+		case event := <-srv.OnMessage():
+			if err := encoder.Encode(event); err != nil {
+				logger.Warn("event not encoded", "reason", err)
+			}
+			switch tevent := event.(type) {
+			case *rootfs.ClientMsgSuccess:
+				return nil
+			case *rootfs.ClientMsgAborted:
+				return fmt.Errorf("build aborted: %v", tevent.Error)
+			}
+		}
+	}
+}
+
+// workContext builds the work context of the ADD and RUN commands.
+func (f *serveFlags) workContext() (*rootfs.WorkContext, error) {
+	workContext := &rootfs.WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{},
+		ResourcesResolved:  rootfs.Resources{},
+	}
+	resolver := resources.NewDefaultResolver()
+	for _, add := range f.adds {
+		parts := strings.SplitN(add, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid resource %q, expected SOURCE:TARGET", add)
+		}
+		cmd := commands.Add{
+			OriginalCommand: fmt.Sprintf("ADD %s %s", parts[0], parts[1]),
+			// the resolver resolves the sources relative to the directory of the Dockerfile:
+			OriginalSource: filepath.Join(f.contextDir, "Dockerfile"),
+			Source:         parts[0],
+			Target:         parts[1],
+			Workdir:        commands.DefaultWorkdir(),
+			User:           commands.DefaultUser(),
+		}
+		resolved, err := resolver.ResolveAdd(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed resolving resource %q: %v", add, err)
+		}
+		if len(resolved) == 0 {
+			return nil, fmt.Errorf("resource %q not found in %s", add, f.contextDir)
+		}
+		workContext.ExecutableCommands = append(workContext.ExecutableCommands, cmd)
+		workContext.ResourcesResolved[cmd.Source] = resolved
+	}
+	for _, run := range f.runs {
+		workContext.ExecutableCommands = append(workContext.ExecutableCommands, commands.RunWithDefaults(run))
+	}
+	return workContext, nil
+}
+
+// writeTLSDir creates a CA for the server, configures the server with it and writes the CA
+// and the client and observer certificates to the directory.
+func writeTLSDir(grpcConfig *rootfs.GRPCServiceConfig, tlsDir string) error {
+	embeddedCA, err := ca.NewDefaultEmbeddedCA(&ca.EmbeddedCAConfig{
+		Addresses: []string{grpcConfig.ServerName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed creating CA: %v", err)
+	}
+	serverTLSConfig, err := embeddedCA.NewServerCertTLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed creating server certificate: %v", err)
+	}
+	if err := os.MkdirAll(tlsDir, 0700); err != nil {
+		return fmt.Errorf("failed creating TLS directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tlsDir, caFileName), []byte(strings.Join(embeddedCA.CAPEMChain(), "")), 0600); err != nil {
+		return fmt.Errorf("failed writing CA: %v", err)
+	}
+	observers := map[string]struct{}{}
+	for _, identity := range []string{identityClient, identityObserver} {
+		certData, err := embeddedCA.NewClientCert()
+		if err != nil {
+			return fmt.Errorf("failed creating %s certificate: %v", identity, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tlsDir, certFileName(identity)), certData.CertificatePEM(), 0600); err != nil {
+			return fmt.Errorf("failed writing %s certificate: %v", identity, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tlsDir, keyFileName(identity)), certData.KeyPEM(), 0600); err != nil {
+			return fmt.Errorf("failed writing %s key: %v", identity, err)
+		}
+		if identity == identityObserver {
+			observers[certData.Certificate().SerialNumber.String()] = struct{}{}
+		}
+	}
+	grpcConfig.TLSConfigServer = serverTLSConfig
+	grpcConfig.ClientRoleResolver = func(cert *x509.Certificate) rootfs.ClientRole {
+		if _, ok := observers[cert.SerialNumber.String()]; ok {
+			return rootfs.ClientRoleObserver
+		}
+		return rootfs.ClientRoleExecutor
+	}
+	return nil
+}
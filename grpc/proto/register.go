@@ -0,0 +1,43 @@
+package proto
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RootfsServerV1ServiceName is the name of the v1 wire version of the RootfsServer service.
+// The v1 service is wire compatible with the service generated from rootfs_server.proto
+// and is served with the same handlers.
+const RootfsServerV1ServiceName = "firebuild.rootfs.v1.RootfsServer"
+
+// RootfsServerV1_ServiceDesc is the grpc.ServiceDesc of the v1 wire version of the RootfsServer service.
+var RootfsServerV1_ServiceDesc = func() grpc.ServiceDesc {
+	desc := RootfsServer_ServiceDesc
+	desc.ServiceName = RootfsServerV1ServiceName
+	return desc
+}()
+
+// RootfsServerServiceDescs returns the service descriptors of every wire version of the RootfsServer service,
+// oldest first.
+func RootfsServerServiceDescs() []*grpc.ServiceDesc {
+	return []*grpc.ServiceDesc{&RootfsServer_ServiceDesc, &RootfsServerV1_ServiceDesc}
+}
+
+// RegisterRootfsServerWith registers the RootfsServer service in every wire version on a service registrar,
+// for example a grpc.Server already serving other services.
+func RegisterRootfsServerWith(s grpc.ServiceRegistrar, srv RootfsServerServer) {
+	for _, desc := range RootfsServerServiceDescs() {
+		s.RegisterService(desc, srv)
+	}
+}
+
+// FileDescriptor returns the descriptor of rootfs_server.proto,
+// for example to serve it with the gRPC server reflection.
+func FileDescriptor() protoreflect.FileDescriptor {
+	return File_rootfs_server_proto
+}
+
+// RootfsServerServiceDescriptor returns the descriptor of the RootfsServer service.
+func RootfsServerServiceDescriptor() protoreflect.ServiceDescriptor {
+	return File_rootfs_server_proto.Services().ByName("RootfsServer")
+}
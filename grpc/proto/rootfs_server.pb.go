@@ -20,6 +20,156 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type CommandAck_Phase int32
+
+const (
+	CommandAck_STARTED  CommandAck_Phase = 0
+	CommandAck_FINISHED CommandAck_Phase = 1
+	CommandAck_SKIPPED  CommandAck_Phase = 2
+	CommandAck_RETRIED  CommandAck_Phase = 3
+)
+
+// Enum value maps for CommandAck_Phase.
+var (
+	CommandAck_Phase_name = map[int32]string{
+		0: "STARTED",
+		1: "FINISHED",
+		2: "SKIPPED",
+		3: "RETRIED",
+	}
+	CommandAck_Phase_value = map[string]int32{
+		"STARTED":  0,
+		"FINISHED": 1,
+		"SKIPPED":  2,
+		"RETRIED":  3,
+	}
+)
+
+func (x CommandAck_Phase) Enum() *CommandAck_Phase {
+	p := new(CommandAck_Phase)
+	*p = x
+	return p
+}
+
+func (x CommandAck_Phase) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CommandAck_Phase) Descriptor() protoreflect.EnumDescriptor {
+	return file_rootfs_server_proto_enumTypes[0].Descriptor()
+}
+
+func (CommandAck_Phase) Type() protoreflect.EnumType {
+	return &file_rootfs_server_proto_enumTypes[0]
+}
+
+func (x CommandAck_Phase) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CommandAck_Phase.Descriptor instead.
+func (CommandAck_Phase) EnumDescriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{5, 0}
+}
+
+type LogLine_Stream int32
+
+const (
+	LogLine_STDOUT LogLine_Stream = 0
+	LogLine_STDERR LogLine_Stream = 1
+)
+
+// Enum value maps for LogLine_Stream.
+var (
+	LogLine_Stream_name = map[int32]string{
+		0: "STDOUT",
+		1: "STDERR",
+	}
+	LogLine_Stream_value = map[string]int32{
+		"STDOUT": 0,
+		"STDERR": 1,
+	}
+)
+
+func (x LogLine_Stream) Enum() *LogLine_Stream {
+	p := new(LogLine_Stream)
+	*p = x
+	return p
+}
+
+func (x LogLine_Stream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogLine_Stream) Descriptor() protoreflect.EnumDescriptor {
+	return file_rootfs_server_proto_enumTypes[1].Descriptor()
+}
+
+func (LogLine_Stream) Type() protoreflect.EnumType {
+	return &file_rootfs_server_proto_enumTypes[1]
+}
+
+func (x LogLine_Stream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogLine_Stream.Descriptor instead.
+func (LogLine_Stream) EnumDescriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{12, 0}
+}
+
+type StatusResponse_State int32
+
+const (
+	StatusResponse_PENDING   StatusResponse_State = 0
+	StatusResponse_RUNNING   StatusResponse_State = 1
+	StatusResponse_SUCCEEDED StatusResponse_State = 2
+	StatusResponse_ABORTED   StatusResponse_State = 3
+)
+
+// Enum value maps for StatusResponse_State.
+var (
+	StatusResponse_State_name = map[int32]string{
+		0: "PENDING",
+		1: "RUNNING",
+		2: "SUCCEEDED",
+		3: "ABORTED",
+	}
+	StatusResponse_State_value = map[string]int32{
+		"PENDING":   0,
+		"RUNNING":   1,
+		"SUCCEEDED": 2,
+		"ABORTED":   3,
+	}
+)
+
+func (x StatusResponse_State) Enum() *StatusResponse_State {
+	p := new(StatusResponse_State)
+	*p = x
+	return p
+}
+
+func (x StatusResponse_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StatusResponse_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_rootfs_server_proto_enumTypes[2].Descriptor()
+}
+
+func (StatusResponse_State) Type() protoreflect.EnumType {
+	return &file_rootfs_server_proto_enumTypes[2]
+}
+
+func (x StatusResponse_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StatusResponse_State.Descriptor instead.
+func (StatusResponse_State) EnumDescriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{29, 0}
+}
+
 type AbortRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -67,16 +217,16 @@ func (x *AbortRequest) GetError() string {
 	return ""
 }
 
-type CommandsResponse struct {
+type AbortResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Command []string `protobuf:"bytes,1,rep,name=command,proto3" json:"command,omitempty"`
+	Debug bool `protobuf:"varint,1,opt,name=debug,proto3" json:"debug,omitempty"`
 }
 
-func (x *CommandsResponse) Reset() {
-	*x = CommandsResponse{}
+func (x *AbortResponse) Reset() {
+	*x = AbortResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -84,13 +234,13 @@ func (x *CommandsResponse) Reset() {
 	}
 }
 
-func (x *CommandsResponse) String() string {
+func (x *AbortResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandsResponse) ProtoMessage() {}
+func (*AbortResponse) ProtoMessage() {}
 
-func (x *CommandsResponse) ProtoReflect() protoreflect.Message {
+func (x *AbortResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -102,26 +252,33 @@ func (x *CommandsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandsResponse.ProtoReflect.Descriptor instead.
-func (*CommandsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use AbortResponse.ProtoReflect.Descriptor instead.
+func (*AbortResponse) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *CommandsResponse) GetCommand() []string {
+func (x *AbortResponse) GetDebug() bool {
 	if x != nil {
-		return x.Command
+		return x.Debug
 	}
-	return nil
+	return false
 }
 
-type Empty struct {
+type BlockDeltaFrame struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Payload:
+	//	*BlockDeltaFrame_Header
+	//	*BlockDeltaFrame_Copy_
+	//	*BlockDeltaFrame_Literal_
+	//	*BlockDeltaFrame_End_
+	Payload isBlockDeltaFrame_Payload `protobuf_oneof:"payload"`
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
+func (x *BlockDeltaFrame) Reset() {
+	*x = BlockDeltaFrame{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -129,13 +286,13 @@ func (x *Empty) Reset() {
 	}
 }
 
-func (x *Empty) String() string {
+func (x *BlockDeltaFrame) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*BlockDeltaFrame) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
+func (x *BlockDeltaFrame) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -147,21 +304,87 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
+// Deprecated: Use BlockDeltaFrame.ProtoReflect.Descriptor instead.
+func (*BlockDeltaFrame) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{2}
 }
 
-type LogMessage struct {
+func (m *BlockDeltaFrame) GetPayload() isBlockDeltaFrame_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame) GetHeader() *ResourceChunk_ResourceHeader {
+	if x, ok := x.GetPayload().(*BlockDeltaFrame_Header); ok {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame) GetCopy() *BlockDeltaFrame_Copy {
+	if x, ok := x.GetPayload().(*BlockDeltaFrame_Copy_); ok {
+		return x.Copy
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame) GetLiteral() *BlockDeltaFrame_Literal {
+	if x, ok := x.GetPayload().(*BlockDeltaFrame_Literal_); ok {
+		return x.Literal
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame) GetEnd() *BlockDeltaFrame_End {
+	if x, ok := x.GetPayload().(*BlockDeltaFrame_End_); ok {
+		return x.End
+	}
+	return nil
+}
+
+type isBlockDeltaFrame_Payload interface {
+	isBlockDeltaFrame_Payload()
+}
+
+type BlockDeltaFrame_Header struct {
+	Header *ResourceChunk_ResourceHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type BlockDeltaFrame_Copy_ struct {
+	Copy *BlockDeltaFrame_Copy `protobuf:"bytes,2,opt,name=copy,proto3,oneof"`
+}
+
+type BlockDeltaFrame_Literal_ struct {
+	Literal *BlockDeltaFrame_Literal `protobuf:"bytes,3,opt,name=literal,proto3,oneof"`
+}
+
+type BlockDeltaFrame_End_ struct {
+	End *BlockDeltaFrame_End `protobuf:"bytes,4,opt,name=end,proto3,oneof"`
+}
+
+func (*BlockDeltaFrame_Header) isBlockDeltaFrame_Payload() {}
+
+func (*BlockDeltaFrame_Copy_) isBlockDeltaFrame_Payload() {}
+
+func (*BlockDeltaFrame_Literal_) isBlockDeltaFrame_Payload() {}
+
+func (*BlockDeltaFrame_End_) isBlockDeltaFrame_Payload() {}
+
+type BlockDeltaRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Line []string `protobuf:"bytes,1,rep,name=line,proto3" json:"line,omitempty"`
+	Path      string                         `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Stage     string                         `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	BlockSize int32                          `protobuf:"varint,3,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Blocks    []*BlockDeltaRequest_Signature `protobuf:"bytes,4,rep,name=blocks,proto3" json:"blocks,omitempty"`
 }
 
-func (x *LogMessage) Reset() {
-	*x = LogMessage{}
+func (x *BlockDeltaRequest) Reset() {
+	*x = BlockDeltaRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -169,13 +392,13 @@ func (x *LogMessage) Reset() {
 	}
 }
 
-func (x *LogMessage) String() string {
+func (x *BlockDeltaRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LogMessage) ProtoMessage() {}
+func (*BlockDeltaRequest) ProtoMessage() {}
 
-func (x *LogMessage) ProtoReflect() protoreflect.Message {
+func (x *BlockDeltaRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -187,28 +410,55 @@ func (x *LogMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LogMessage.ProtoReflect.Descriptor instead.
-func (*LogMessage) Descriptor() ([]byte, []int) {
+// Deprecated: Use BlockDeltaRequest.ProtoReflect.Descriptor instead.
+func (*BlockDeltaRequest) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *LogMessage) GetLine() []string {
+func (x *BlockDeltaRequest) GetPath() string {
 	if x != nil {
-		return x.Line
+		return x.Path
+	}
+	return ""
+}
+
+func (x *BlockDeltaRequest) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *BlockDeltaRequest) GetBlockSize() int32 {
+	if x != nil {
+		return x.BlockSize
+	}
+	return 0
+}
+
+func (x *BlockDeltaRequest) GetBlocks() []*BlockDeltaRequest_Signature {
+	if x != nil {
+		return x.Blocks
 	}
 	return nil
 }
 
-type PingRequest struct {
+type CapabilitiesResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AuthModes          []string `protobuf:"bytes,1,rep,name=authModes,proto3" json:"authModes,omitempty"`
+	ChecksumAlgorithms []string `protobuf:"bytes,2,rep,name=checksumAlgorithms,proto3" json:"checksumAlgorithms,omitempty"`
+	Compression        []string `protobuf:"bytes,3,rep,name=compression,proto3" json:"compression,omitempty"`
+	Features           []string `protobuf:"bytes,4,rep,name=features,proto3" json:"features,omitempty"`
+	MaxMsgSize         int64    `protobuf:"varint,5,opt,name=maxMsgSize,proto3" json:"maxMsgSize,omitempty"`
+	MaxChunkSize       int64    `protobuf:"varint,6,opt,name=maxChunkSize,proto3" json:"maxChunkSize,omitempty"`
+	Resume             bool     `protobuf:"varint,7,opt,name=resume,proto3" json:"resume,omitempty"`
 }
 
-func (x *PingRequest) Reset() {
-	*x = PingRequest{}
+func (x *CapabilitiesResponse) Reset() {
+	*x = CapabilitiesResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -216,13 +466,13 @@ func (x *PingRequest) Reset() {
 	}
 }
 
-func (x *PingRequest) String() string {
+func (x *CapabilitiesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PingRequest) ProtoMessage() {}
+func (*CapabilitiesResponse) ProtoMessage() {}
 
-func (x *PingRequest) ProtoReflect() protoreflect.Message {
+func (x *CapabilitiesResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -234,28 +484,72 @@ func (x *PingRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
-func (*PingRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use CapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *PingRequest) GetId() string {
+func (x *CapabilitiesResponse) GetAuthModes() []string {
 	if x != nil {
-		return x.Id
+		return x.AuthModes
 	}
-	return ""
+	return nil
 }
 
-type PingResponse struct {
+func (x *CapabilitiesResponse) GetChecksumAlgorithms() []string {
+	if x != nil {
+		return x.ChecksumAlgorithms
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetCompression() []string {
+	if x != nil {
+		return x.Compression
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetMaxMsgSize() int64 {
+	if x != nil {
+		return x.MaxMsgSize
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetMaxChunkSize() int64 {
+	if x != nil {
+		return x.MaxChunkSize
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetResume() bool {
+	if x != nil {
+		return x.Resume
+	}
+	return false
+}
+
+type CommandAck struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Index int32            `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Phase CommandAck_Phase `protobuf:"varint,2,opt,name=phase,proto3,enum=proto.CommandAck_Phase" json:"phase,omitempty"`
+	Error string           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 }
 
-func (x *PingResponse) Reset() {
-	*x = PingResponse{}
+func (x *CommandAck) Reset() {
+	*x = CommandAck{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -263,13 +557,13 @@ func (x *PingResponse) Reset() {
 	}
 }
 
-func (x *PingResponse) String() string {
+func (x *CommandAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PingResponse) ProtoMessage() {}
+func (*CommandAck) ProtoMessage() {}
 
-func (x *PingResponse) ProtoReflect() protoreflect.Message {
+func (x *CommandAck) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -281,29 +575,43 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
-func (*PingResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use CommandAck.ProtoReflect.Descriptor instead.
+func (*CommandAck) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *PingResponse) GetId() string {
+func (x *CommandAck) GetIndex() int32 {
 	if x != nil {
-		return x.Id
+		return x.Index
+	}
+	return 0
+}
+
+func (x *CommandAck) GetPhase() CommandAck_Phase {
+	if x != nil {
+		return x.Phase
+	}
+	return CommandAck_STARTED
+}
+
+func (x *CommandAck) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-type ResourceRequest struct {
+type CommandsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Path  string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Stage string `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	Command []string `protobuf:"bytes,1,rep,name=command,proto3" json:"command,omitempty"`
+	Group   []int32  `protobuf:"varint,2,rep,packed,name=group,proto3" json:"group,omitempty"`
 }
 
-func (x *ResourceRequest) Reset() {
-	*x = ResourceRequest{}
+func (x *CommandsResponse) Reset() {
+	*x = CommandsResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -311,13 +619,13 @@ func (x *ResourceRequest) Reset() {
 	}
 }
 
-func (x *ResourceRequest) String() string {
+func (x *CommandsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceRequest) ProtoMessage() {}
+func (*CommandsResponse) ProtoMessage() {}
 
-func (x *ResourceRequest) ProtoReflect() protoreflect.Message {
+func (x *CommandsResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -329,41 +637,35 @@ func (x *ResourceRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceRequest.ProtoReflect.Descriptor instead.
-func (*ResourceRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use CommandsResponse.ProtoReflect.Descriptor instead.
+func (*CommandsResponse) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *ResourceRequest) GetPath() string {
+func (x *CommandsResponse) GetCommand() []string {
 	if x != nil {
-		return x.Path
+		return x.Command
 	}
-	return ""
+	return nil
 }
 
-func (x *ResourceRequest) GetStage() string {
+func (x *CommandsResponse) GetGroup() []int32 {
 	if x != nil {
-		return x.Stage
+		return x.Group
 	}
-	return ""
+	return nil
 }
 
-// A single resource path maps to one or multiple resources.
-// The targetPath indicates the actual file where the resource must be written to.
-type ResourceChunk struct {
+type DebugFrame struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// Types that are assignable to Payload:
-	//	*ResourceChunk_Header
-	//	*ResourceChunk_Chunk
-	//	*ResourceChunk_Eof
-	Payload isResourceChunk_Payload `protobuf_oneof:"payload"`
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
 }
 
-func (x *ResourceChunk) Reset() {
-	*x = ResourceChunk{}
+func (x *DebugFrame) Reset() {
+	*x = DebugFrame{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_rootfs_server_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -371,13 +673,13 @@ func (x *ResourceChunk) Reset() {
 	}
 }
 
-func (x *ResourceChunk) String() string {
+func (x *DebugFrame) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceChunk) ProtoMessage() {}
+func (*DebugFrame) ProtoMessage() {}
 
-func (x *ResourceChunk) ProtoReflect() protoreflect.Message {
+func (x *DebugFrame) ProtoReflect() protoreflect.Message {
 	mi := &file_rootfs_server_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -389,92 +691,129 @@ func (x *ResourceChunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceChunk.ProtoReflect.Descriptor instead.
-func (*ResourceChunk) Descriptor() ([]byte, []int) {
+// Deprecated: Use DebugFrame.ProtoReflect.Descriptor instead.
+func (*DebugFrame) Descriptor() ([]byte, []int) {
 	return file_rootfs_server_proto_rawDescGZIP(), []int{7}
 }
 
-func (m *ResourceChunk) GetPayload() isResourceChunk_Payload {
-	if m != nil {
-		return m.Payload
+func (x *DebugFrame) GetData() []byte {
+	if x != nil {
+		return x.Data
 	}
 	return nil
 }
 
-func (x *ResourceChunk) GetHeader() *ResourceChunk_ResourceHeader {
-	if x, ok := x.GetPayload().(*ResourceChunk_Header); ok {
-		return x.Header
-	}
-	return nil
+type Empty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 }
 
-func (x *ResourceChunk) GetChunk() *ResourceChunk_ResourceContents {
-	if x, ok := x.GetPayload().(*ResourceChunk_Chunk); ok {
-		return x.Chunk
+func (x *Empty) Reset() {
+	*x = Empty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return nil
 }
 
-func (x *ResourceChunk) GetEof() *ResourceChunk_ResourceEof {
-	if x, ok := x.GetPayload().(*ResourceChunk_Eof); ok {
-		return x.Eof
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-type isResourceChunk_Payload interface {
-	isResourceChunk_Payload()
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{8}
 }
 
-type ResourceChunk_Header struct {
-	Header *ResourceChunk_ResourceHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+type EnvironmentResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Env map[string]string `protobuf:"bytes,1,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
-type ResourceChunk_Chunk struct {
-	Chunk *ResourceChunk_ResourceContents `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+func (x *EnvironmentResponse) Reset() {
+	*x = EnvironmentResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-type ResourceChunk_Eof struct {
-	Eof *ResourceChunk_ResourceEof `protobuf:"bytes,3,opt,name=eof,proto3,oneof"`
+func (x *EnvironmentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceChunk_Header) isResourceChunk_Payload() {}
+func (*EnvironmentResponse) ProtoMessage() {}
 
-func (*ResourceChunk_Chunk) isResourceChunk_Payload() {}
+func (x *EnvironmentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
-func (*ResourceChunk_Eof) isResourceChunk_Payload() {}
+// Deprecated: Use EnvironmentResponse.ProtoReflect.Descriptor instead.
+func (*EnvironmentResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{9}
+}
 
-type ResourceChunk_ResourceHeader struct {
+func (x *EnvironmentResponse) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+type FlushRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	SourcePath    string `protobuf:"bytes,1,opt,name=sourcePath,proto3" json:"sourcePath,omitempty"`
-	TargetPath    string `protobuf:"bytes,2,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
-	FileMode      int64  `protobuf:"varint,3,opt,name=fileMode,proto3" json:"fileMode,omitempty"`
-	IsDir         bool   `protobuf:"varint,4,opt,name=isDir,proto3" json:"isDir,omitempty"`
-	TargetUser    string `protobuf:"bytes,5,opt,name=targetUser,proto3" json:"targetUser,omitempty"`
-	TargetWorkdir string `protobuf:"bytes,6,opt,name=targetWorkdir,proto3" json:"targetWorkdir,omitempty"`
-	Id            string `protobuf:"bytes,7,opt,name=id,proto3" json:"id,omitempty"`
+	StdoutLines int64 `protobuf:"varint,1,opt,name=stdoutLines,proto3" json:"stdoutLines,omitempty"`
+	StderrLines int64 `protobuf:"varint,2,opt,name=stderrLines,proto3" json:"stderrLines,omitempty"`
 }
 
-func (x *ResourceChunk_ResourceHeader) Reset() {
-	*x = ResourceChunk_ResourceHeader{}
+func (x *FlushRequest) Reset() {
+	*x = FlushRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rootfs_server_proto_msgTypes[8]
+		mi := &file_rootfs_server_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ResourceChunk_ResourceHeader) String() string {
+func (x *FlushRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceChunk_ResourceHeader) ProtoMessage() {}
+func (*FlushRequest) ProtoMessage() {}
 
-func (x *ResourceChunk_ResourceHeader) ProtoReflect() protoreflect.Message {
-	mi := &file_rootfs_server_proto_msgTypes[8]
+func (x *FlushRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -485,87 +824,116 @@ func (x *ResourceChunk_ResourceHeader) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceChunk_ResourceHeader.ProtoReflect.Descriptor instead.
-func (*ResourceChunk_ResourceHeader) Descriptor() ([]byte, []int) {
-	return file_rootfs_server_proto_rawDescGZIP(), []int{7, 0}
+// Deprecated: Use FlushRequest.ProtoReflect.Descriptor instead.
+func (*FlushRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *ResourceChunk_ResourceHeader) GetSourcePath() string {
+func (x *FlushRequest) GetStdoutLines() int64 {
 	if x != nil {
-		return x.SourcePath
+		return x.StdoutLines
 	}
-	return ""
+	return 0
 }
 
-func (x *ResourceChunk_ResourceHeader) GetTargetPath() string {
+func (x *FlushRequest) GetStderrLines() int64 {
 	if x != nil {
-		return x.TargetPath
+		return x.StderrLines
 	}
-	return ""
+	return 0
 }
 
-func (x *ResourceChunk_ResourceHeader) GetFileMode() int64 {
-	if x != nil {
-		return x.FileMode
+type FlushResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StdoutLines int64 `protobuf:"varint,1,opt,name=stdoutLines,proto3" json:"stdoutLines,omitempty"`
+	StderrLines int64 `protobuf:"varint,2,opt,name=stderrLines,proto3" json:"stderrLines,omitempty"`
+	Complete    bool  `protobuf:"varint,3,opt,name=complete,proto3" json:"complete,omitempty"`
+}
+
+func (x *FlushResponse) Reset() {
+	*x = FlushResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return 0
 }
 
-func (x *ResourceChunk_ResourceHeader) GetIsDir() bool {
-	if x != nil {
-		return x.IsDir
+func (x *FlushResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushResponse) ProtoMessage() {}
+
+func (x *FlushResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return false
+	return mi.MessageOf(x)
 }
 
-func (x *ResourceChunk_ResourceHeader) GetTargetUser() string {
+// Deprecated: Use FlushResponse.ProtoReflect.Descriptor instead.
+func (*FlushResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FlushResponse) GetStdoutLines() int64 {
 	if x != nil {
-		return x.TargetUser
+		return x.StdoutLines
 	}
-	return ""
+	return 0
 }
 
-func (x *ResourceChunk_ResourceHeader) GetTargetWorkdir() string {
+func (x *FlushResponse) GetStderrLines() int64 {
 	if x != nil {
-		return x.TargetWorkdir
+		return x.StderrLines
 	}
-	return ""
+	return 0
 }
 
-func (x *ResourceChunk_ResourceHeader) GetId() string {
+func (x *FlushResponse) GetComplete() bool {
 	if x != nil {
-		return x.Id
+		return x.Complete
 	}
-	return ""
+	return false
 }
 
-type ResourceChunk_ResourceContents struct {
+type LogLine struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Chunk    []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
-	Checksum []byte `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`
-	Id       string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Stream    LogLine_Stream `protobuf:"varint,1,opt,name=stream,proto3,enum=proto.LogLine_Stream" json:"stream,omitempty"`
+	Line      string         `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	Timestamp int64          `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	CommandId string         `protobuf:"bytes,4,opt,name=commandId,proto3" json:"commandId,omitempty"`
 }
 
-func (x *ResourceChunk_ResourceContents) Reset() {
-	*x = ResourceChunk_ResourceContents{}
+func (x *LogLine) Reset() {
+	*x = LogLine{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rootfs_server_proto_msgTypes[9]
+		mi := &file_rootfs_server_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ResourceChunk_ResourceContents) String() string {
+func (x *LogLine) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceChunk_ResourceContents) ProtoMessage() {}
+func (*LogLine) ProtoMessage() {}
 
-func (x *ResourceChunk_ResourceContents) ProtoReflect() protoreflect.Message {
-	mi := &file_rootfs_server_proto_msgTypes[9]
+func (x *LogLine) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -576,57 +944,64 @@ func (x *ResourceChunk_ResourceContents) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceChunk_ResourceContents.ProtoReflect.Descriptor instead.
-func (*ResourceChunk_ResourceContents) Descriptor() ([]byte, []int) {
-	return file_rootfs_server_proto_rawDescGZIP(), []int{7, 1}
+// Deprecated: Use LogLine.ProtoReflect.Descriptor instead.
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *ResourceChunk_ResourceContents) GetChunk() []byte {
+func (x *LogLine) GetStream() LogLine_Stream {
 	if x != nil {
-		return x.Chunk
+		return x.Stream
 	}
-	return nil
+	return LogLine_STDOUT
 }
 
-func (x *ResourceChunk_ResourceContents) GetChecksum() []byte {
+func (x *LogLine) GetLine() string {
 	if x != nil {
-		return x.Checksum
+		return x.Line
 	}
-	return nil
+	return ""
 }
 
-func (x *ResourceChunk_ResourceContents) GetId() string {
+func (x *LogLine) GetTimestamp() int64 {
 	if x != nil {
-		return x.Id
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *LogLine) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
 	}
 	return ""
 }
 
-type ResourceChunk_ResourceEof struct {
+type LogMessage struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Line []string `protobuf:"bytes,1,rep,name=line,proto3" json:"line,omitempty"`
 }
 
-func (x *ResourceChunk_ResourceEof) Reset() {
-	*x = ResourceChunk_ResourceEof{}
+func (x *LogMessage) Reset() {
+	*x = LogMessage{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rootfs_server_proto_msgTypes[10]
+		mi := &file_rootfs_server_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ResourceChunk_ResourceEof) String() string {
+func (x *LogMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceChunk_ResourceEof) ProtoMessage() {}
+func (*LogMessage) ProtoMessage() {}
 
-func (x *ResourceChunk_ResourceEof) ProtoReflect() protoreflect.Message {
-	mi := &file_rootfs_server_proto_msgTypes[10]
+func (x *LogMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -637,158 +1012,2763 @@ func (x *ResourceChunk_ResourceEof) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceChunk_ResourceEof.ProtoReflect.Descriptor instead.
-func (*ResourceChunk_ResourceEof) Descriptor() ([]byte, []int) {
-	return file_rootfs_server_proto_rawDescGZIP(), []int{7, 2}
+// Deprecated: Use LogMessage.ProtoReflect.Descriptor instead.
+func (*LogMessage) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *ResourceChunk_ResourceEof) GetId() string {
+func (x *LogMessage) GetLine() []string {
 	if x != nil {
-		return x.Id
+		return x.Line
 	}
-	return ""
+	return nil
 }
 
-var File_rootfs_server_proto protoreflect.FileDescriptor
+type ManifestEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_rootfs_server_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x72, 0x6f, 0x6f, 0x74, 0x66, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x24, 0x0a, 0x0c,
-	0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
-	0x6f, 0x72, 0x22, 0x2c, 0x0a, 0x10, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
-	0x64, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
-	0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x20, 0x0a, 0x0a, 0x4c, 0x6f, 0x67,
-	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0x1d, 0x0a, 0x0b, 0x50,
-	0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1e, 0x0a, 0x0c, 0x50, 0x69,
-	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x3b, 0x0a, 0x0f, 0x52, 0x65,
-	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
-	0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74,
-	0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x22, 0x9e, 0x04, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f,
-	0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x3d, 0x0a, 0x06, 0x68, 0x65, 0x61,
-	0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e,
-	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x48, 0x00,
-	0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x3d, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e,
-	0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65,
-	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x48, 0x00,
-	0x52, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x34, 0x0a, 0x03, 0x65, 0x6f, 0x66, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73,
-	0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75,
-	0x72, 0x63, 0x65, 0x45, 0x6f, 0x66, 0x48, 0x00, 0x52, 0x03, 0x65, 0x6f, 0x66, 0x1a, 0xd8, 0x01,
-	0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
-	0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x61, 0x74, 0x68, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x61, 0x74, 0x68,
-	0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68,
-	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05,
-	0x69, 0x73, 0x44, 0x69, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x69, 0x73, 0x44,
-	0x69, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72,
-	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x55, 0x73,
-	0x65, 0x72, 0x12, 0x24, 0x0a, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x57, 0x6f, 0x72, 0x6b,
-	0x64, 0x69, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65,
-	0x74, 0x57, 0x6f, 0x72, 0x6b, 0x64, 0x69, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x1a, 0x54, 0x0a, 0x10, 0x52, 0x65, 0x73, 0x6f,
-	0x75, 0x72, 0x63, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05,
-	0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x63, 0x68, 0x75,
-	0x6e, 0x6b, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x0e,
-	0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x1a, 0x1d,
-	0x0a, 0x0b, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x45, 0x6f, 0x66, 0x12, 0x0e, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x42, 0x09, 0x0a,
-	0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x32, 0xd7, 0x02, 0x0a, 0x0c, 0x52, 0x6f, 0x6f,
-	0x74, 0x66, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x31, 0x0a, 0x08, 0x43, 0x6f, 0x6d,
-	0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6f, 0x6d, 0x6d,
-	0x61, 0x6e, 0x64, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x04,
-	0x50, 0x69, 0x6e, 0x67, 0x12, 0x12, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x69, 0x6e,
-	0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3a, 0x0a,
-	0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
-	0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30, 0x01, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x64,
-	0x45, 0x72, 0x72, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x67, 0x4d,
-	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x64, 0x4f, 0x75, 0x74, 0x12, 0x11,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x67, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
-	0x65, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12,
-	0x2a, 0x0a, 0x05, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2e, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x25, 0x0a, 0x07, 0x53,
-	0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2f, 0x63, 0x6f, 0x6d, 0x62, 0x75, 0x73, 0x74, 0x2d, 0x6c, 0x61, 0x62, 0x73, 0x2f, 0x66, 0x69,
-	0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	Path       string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	TargetPath string `protobuf:"bytes,2,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+	FileMode   int64  `protobuf:"varint,3,opt,name=fileMode,proto3" json:"fileMode,omitempty"`
+	IsDir      bool   `protobuf:"varint,4,opt,name=isDir,proto3" json:"isDir,omitempty"`
+	Size       int64  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Platform   string `protobuf:"bytes,6,opt,name=platform,proto3" json:"platform,omitempty"`
+	Priority   int32  `protobuf:"varint,7,opt,name=priority,proto3" json:"priority,omitempty"`
 }
 
-var (
-	file_rootfs_server_proto_rawDescOnce sync.Once
-	file_rootfs_server_proto_rawDescData = file_rootfs_server_proto_rawDesc
-)
+func (x *ManifestEntry) Reset() {
+	*x = ManifestEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_rootfs_server_proto_rawDescGZIP() []byte {
-	file_rootfs_server_proto_rawDescOnce.Do(func() {
-		file_rootfs_server_proto_rawDescData = protoimpl.X.CompressGZIP(file_rootfs_server_proto_rawDescData)
-	})
-	return file_rootfs_server_proto_rawDescData
+func (x *ManifestEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_rootfs_server_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
-var file_rootfs_server_proto_goTypes = []interface{}{
-	(*AbortRequest)(nil),                   // 0: proto.AbortRequest
-	(*CommandsResponse)(nil),               // 1: proto.CommandsResponse
-	(*Empty)(nil),                          // 2: proto.Empty
-	(*LogMessage)(nil),                     // 3: proto.LogMessage
-	(*PingRequest)(nil),                    // 4: proto.PingRequest
-	(*PingResponse)(nil),                   // 5: proto.PingResponse
-	(*ResourceRequest)(nil),                // 6: proto.ResourceRequest
-	(*ResourceChunk)(nil),                  // 7: proto.ResourceChunk
-	(*ResourceChunk_ResourceHeader)(nil),   // 8: proto.ResourceChunk.ResourceHeader
-	(*ResourceChunk_ResourceContents)(nil), // 9: proto.ResourceChunk.ResourceContents
-	(*ResourceChunk_ResourceEof)(nil),      // 10: proto.ResourceChunk.ResourceEof
+func (*ManifestEntry) ProtoMessage() {}
+
+func (x *ManifestEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_rootfs_server_proto_depIdxs = []int32{
-	8,  // 0: proto.ResourceChunk.header:type_name -> proto.ResourceChunk.ResourceHeader
-	9,  // 1: proto.ResourceChunk.chunk:type_name -> proto.ResourceChunk.ResourceContents
-	10, // 2: proto.ResourceChunk.eof:type_name -> proto.ResourceChunk.ResourceEof
-	2,  // 3: proto.RootfsServer.Commands:input_type -> proto.Empty
-	4,  // 4: proto.RootfsServer.Ping:input_type -> proto.PingRequest
-	6,  // 5: proto.RootfsServer.Resource:input_type -> proto.ResourceRequest
-	3,  // 6: proto.RootfsServer.StdErr:input_type -> proto.LogMessage
-	3,  // 7: proto.RootfsServer.StdOut:input_type -> proto.LogMessage
-	0,  // 8: proto.RootfsServer.Abort:input_type -> proto.AbortRequest
-	2,  // 9: proto.RootfsServer.Success:input_type -> proto.Empty
-	1,  // 10: proto.RootfsServer.Commands:output_type -> proto.CommandsResponse
-	5,  // 11: proto.RootfsServer.Ping:output_type -> proto.PingResponse
-	7,  // 12: proto.RootfsServer.Resource:output_type -> proto.ResourceChunk
-	2,  // 13: proto.RootfsServer.StdErr:output_type -> proto.Empty
-	2,  // 14: proto.RootfsServer.StdOut:output_type -> proto.Empty
-	2,  // 15: proto.RootfsServer.Abort:output_type -> proto.Empty
-	2,  // 16: proto.RootfsServer.Success:output_type -> proto.Empty
-	10, // [10:17] is the sub-list for method output_type
-	3,  // [3:10] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+
+// Deprecated: Use ManifestEntry.ProtoReflect.Descriptor instead.
+func (*ManifestEntry) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{14}
 }
 
-func init() { file_rootfs_server_proto_init() }
-func file_rootfs_server_proto_init() {
-	if File_rootfs_server_proto != nil {
-		return
+func (x *ManifestEntry) GetPath() string {
+	if x != nil {
+		return x.Path
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_rootfs_server_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+	return ""
+}
+
+func (x *ManifestEntry) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *ManifestEntry) GetFileMode() int64 {
+	if x != nil {
+		return x.FileMode
+	}
+	return 0
+}
+
+func (x *ManifestEntry) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *ManifestEntry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ManifestEntry) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *ManifestEntry) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type ManifestRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path  string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Stage string `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+}
+
+func (x *ManifestRequest) Reset() {
+	*x = ManifestRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ManifestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManifestRequest) ProtoMessage() {}
+
+func (x *ManifestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManifestRequest.ProtoReflect.Descriptor instead.
+func (*ManifestRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ManifestRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ManifestRequest) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+type ManifestResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*ManifestEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *ManifestResponse) Reset() {
+	*x = ManifestResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ManifestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManifestResponse) ProtoMessage() {}
+
+func (x *ManifestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManifestResponse.ProtoReflect.Descriptor instead.
+func (*ManifestResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ManifestResponse) GetEntries() []*ManifestEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PingRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PingResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PortForwardCloseRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *PortForwardCloseRequest) Reset() {
+	*x = PortForwardCloseRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PortForwardCloseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortForwardCloseRequest) ProtoMessage() {}
+
+func (x *PortForwardCloseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortForwardCloseRequest.ProtoReflect.Descriptor instead.
+func (*PortForwardCloseRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PortForwardCloseRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PortForwardRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Protocol    string `protobuf:"bytes,1,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	HostAddress string `protobuf:"bytes,2,opt,name=hostAddress,proto3" json:"hostAddress,omitempty"`
+	GuestPort   int32  `protobuf:"varint,3,opt,name=guestPort,proto3" json:"guestPort,omitempty"`
+}
+
+func (x *PortForwardRequest) Reset() {
+	*x = PortForwardRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PortForwardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortForwardRequest) ProtoMessage() {}
+
+func (x *PortForwardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortForwardRequest.ProtoReflect.Descriptor instead.
+func (*PortForwardRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PortForwardRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *PortForwardRequest) GetHostAddress() string {
+	if x != nil {
+		return x.HostAddress
+	}
+	return ""
+}
+
+func (x *PortForwardRequest) GetGuestPort() int32 {
+	if x != nil {
+		return x.GuestPort
+	}
+	return 0
+}
+
+type PortForwardResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id           string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	GuestAddress string `protobuf:"bytes,2,opt,name=guestAddress,proto3" json:"guestAddress,omitempty"`
+}
+
+func (x *PortForwardResponse) Reset() {
+	*x = PortForwardResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PortForwardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortForwardResponse) ProtoMessage() {}
+
+func (x *PortForwardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortForwardResponse.ProtoReflect.Descriptor instead.
+func (*PortForwardResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *PortForwardResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PortForwardResponse) GetGuestAddress() string {
+	if x != nil {
+		return x.GuestAddress
+	}
+	return ""
+}
+
+type ProgressReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path           string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	BytesWritten   int64  `protobuf:"varint,2,opt,name=bytesWritten,proto3" json:"bytesWritten,omitempty"`
+	BytesTotal     int64  `protobuf:"varint,3,opt,name=bytesTotal,proto3" json:"bytesTotal,omitempty"`
+	BytesPerSecond int64  `protobuf:"varint,4,opt,name=bytesPerSecond,proto3" json:"bytesPerSecond,omitempty"`
+	EtaMillis      int64  `protobuf:"varint,5,opt,name=etaMillis,proto3" json:"etaMillis,omitempty"`
+	Done           bool   `protobuf:"varint,6,opt,name=done,proto3" json:"done,omitempty"`
+}
+
+func (x *ProgressReport) Reset() {
+	*x = ProgressReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProgressReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressReport) ProtoMessage() {}
+
+func (x *ProgressReport) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressReport.ProtoReflect.Descriptor instead.
+func (*ProgressReport) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ProgressReport) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ProgressReport) GetBytesWritten() int64 {
+	if x != nil {
+		return x.BytesWritten
+	}
+	return 0
+}
+
+func (x *ProgressReport) GetBytesTotal() int64 {
+	if x != nil {
+		return x.BytesTotal
+	}
+	return 0
+}
+
+func (x *ProgressReport) GetBytesPerSecond() int64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+func (x *ProgressReport) GetEtaMillis() int64 {
+	if x != nil {
+		return x.EtaMillis
+	}
+	return 0
+}
+
+func (x *ProgressReport) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+type ResourceDeltaRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path              string                        `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Stage             string                        `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	Existing          []*ResourceDeltaRequest_Entry `protobuf:"bytes,3,rep,name=existing,proto3" json:"existing,omitempty"`
+	Multiplex         int32                         `protobuf:"varint,4,opt,name=multiplex,proto3" json:"multiplex,omitempty"`
+	BatchFileSize     int64                         `protobuf:"varint,5,opt,name=batchFileSize,proto3" json:"batchFileSize,omitempty"`
+	AcceptCompression []string                      `protobuf:"bytes,6,rep,name=acceptCompression,proto3" json:"acceptCompression,omitempty"`
+}
+
+func (x *ResourceDeltaRequest) Reset() {
+	*x = ResourceDeltaRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceDeltaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceDeltaRequest) ProtoMessage() {}
+
+func (x *ResourceDeltaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceDeltaRequest.ProtoReflect.Descriptor instead.
+func (*ResourceDeltaRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ResourceDeltaRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ResourceDeltaRequest) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ResourceDeltaRequest) GetExisting() []*ResourceDeltaRequest_Entry {
+	if x != nil {
+		return x.Existing
+	}
+	return nil
+}
+
+func (x *ResourceDeltaRequest) GetMultiplex() int32 {
+	if x != nil {
+		return x.Multiplex
+	}
+	return 0
+}
+
+func (x *ResourceDeltaRequest) GetBatchFileSize() int64 {
+	if x != nil {
+		return x.BatchFileSize
+	}
+	return 0
+}
+
+func (x *ResourceDeltaRequest) GetAcceptCompression() []string {
+	if x != nil {
+		return x.AcceptCompression
+	}
+	return nil
+}
+
+type ResourceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path              string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Stage             string   `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	TargetPath        string   `protobuf:"bytes,3,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+	Offset            int64    `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Multiplex         int32    `protobuf:"varint,5,opt,name=multiplex,proto3" json:"multiplex,omitempty"`
+	BatchFileSize     int64    `protobuf:"varint,6,opt,name=batchFileSize,proto3" json:"batchFileSize,omitempty"`
+	AcceptCompression []string `protobuf:"bytes,7,rep,name=acceptCompression,proto3" json:"acceptCompression,omitempty"`
+	PrefixDigest      string   `protobuf:"bytes,8,opt,name=prefixDigest,proto3" json:"prefixDigest,omitempty"`
+	ContentsDigest    bool     `protobuf:"varint,9,opt,name=contentsDigest,proto3" json:"contentsDigest,omitempty"`
+}
+
+func (x *ResourceRequest) Reset() {
+	*x = ResourceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceRequest) ProtoMessage() {}
+
+func (x *ResourceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceRequest.ProtoReflect.Descriptor instead.
+func (*ResourceRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ResourceRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ResourceRequest) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ResourceRequest) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *ResourceRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ResourceRequest) GetMultiplex() int32 {
+	if x != nil {
+		return x.Multiplex
+	}
+	return 0
+}
+
+func (x *ResourceRequest) GetBatchFileSize() int64 {
+	if x != nil {
+		return x.BatchFileSize
+	}
+	return 0
+}
+
+func (x *ResourceRequest) GetAcceptCompression() []string {
+	if x != nil {
+		return x.AcceptCompression
+	}
+	return nil
+}
+
+func (x *ResourceRequest) GetPrefixDigest() string {
+	if x != nil {
+		return x.PrefixDigest
+	}
+	return ""
+}
+
+func (x *ResourceRequest) GetContentsDigest() bool {
+	if x != nil {
+		return x.ContentsDigest
+	}
+	return false
+}
+
+type ResumeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BuildId            string           `protobuf:"bytes,1,opt,name=buildId,proto3" json:"buildId,omitempty"`
+	LastCompletedIndex int32            `protobuf:"varint,2,opt,name=lastCompletedIndex,proto3" json:"lastCompletedIndex,omitempty"`
+	ResourceOffsets    map[string]int64 `protobuf:"bytes,3,rep,name=resourceOffsets,proto3" json:"resourceOffsets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ResumeRequest) GetBuildId() string {
+	if x != nil {
+		return x.BuildId
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetLastCompletedIndex() int32 {
+	if x != nil {
+		return x.LastCompletedIndex
+	}
+	return 0
+}
+
+func (x *ResumeRequest) GetResourceOffsets() map[string]int64 {
+	if x != nil {
+		return x.ResourceOffsets
+	}
+	return nil
+}
+
+type ResumeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BuildId         string           `protobuf:"bytes,1,opt,name=buildId,proto3" json:"buildId,omitempty"`
+	NextIndex       int32            `protobuf:"varint,2,opt,name=nextIndex,proto3" json:"nextIndex,omitempty"`
+	ResourceOffsets map[string]int64 `protobuf:"bytes,3,rep,name=resourceOffsets,proto3" json:"resourceOffsets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *ResumeResponse) Reset() {
+	*x = ResumeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResumeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeResponse) ProtoMessage() {}
+
+func (x *ResumeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeResponse.ProtoReflect.Descriptor instead.
+func (*ResumeResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ResumeResponse) GetBuildId() string {
+	if x != nil {
+		return x.BuildId
+	}
+	return ""
+}
+
+func (x *ResumeResponse) GetNextIndex() int32 {
+	if x != nil {
+		return x.NextIndex
+	}
+	return 0
+}
+
+func (x *ResumeResponse) GetResourceOffsets() map[string]int64 {
+	if x != nil {
+		return x.ResourceOffsets
+	}
+	return nil
+}
+
+// Requests the contents of a secret resource, the contents are sent in a single message.
+type SecretRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path       string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Stage      string `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	TargetPath string `protobuf:"bytes,3,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+}
+
+func (x *SecretRequest) Reset() {
+	*x = SecretRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretRequest) ProtoMessage() {}
+
+func (x *SecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretRequest.ProtoReflect.Descriptor instead.
+func (*SecretRequest) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *SecretRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SecretRequest) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *SecretRequest) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+type SecretResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header   *ResourceChunk_ResourceHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Contents []byte                        `protobuf:"bytes,2,opt,name=contents,proto3" json:"contents,omitempty"`
+}
+
+func (x *SecretResponse) Reset() {
+	*x = SecretResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretResponse) ProtoMessage() {}
+
+func (x *SecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretResponse.ProtoReflect.Descriptor instead.
+func (*SecretResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SecretResponse) GetHeader() *ResourceChunk_ResourceHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *SecretResponse) GetContents() []byte {
+	if x != nil {
+		return x.Contents
+	}
+	return nil
+}
+
+type StatusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State            StatusResponse_State `protobuf:"varint,1,opt,name=state,proto3,enum=proto.StatusResponse_State" json:"state,omitempty"`
+	CommandsCount    int32                `protobuf:"varint,2,opt,name=commandsCount,proto3" json:"commandsCount,omitempty"`
+	CommandsFinished int32                `protobuf:"varint,3,opt,name=commandsFinished,proto3" json:"commandsFinished,omitempty"`
+	CommandsSkipped  int32                `protobuf:"varint,4,opt,name=commandsSkipped,proto3" json:"commandsSkipped,omitempty"`
+	LastAckedIndex   int32                `protobuf:"varint,5,opt,name=lastAckedIndex,proto3" json:"lastAckedIndex,omitempty"`
+	BytesTotal       int64                `protobuf:"varint,6,opt,name=bytesTotal,proto3" json:"bytesTotal,omitempty"`
+	BytesServed      int64                `protobuf:"varint,7,opt,name=bytesServed,proto3" json:"bytesServed,omitempty"`
+	BytesRemaining   int64                `protobuf:"varint,8,opt,name=bytesRemaining,proto3" json:"bytesRemaining,omitempty"`
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *StatusResponse) GetState() StatusResponse_State {
+	if x != nil {
+		return x.State
+	}
+	return StatusResponse_PENDING
+}
+
+func (x *StatusResponse) GetCommandsCount() int32 {
+	if x != nil {
+		return x.CommandsCount
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetCommandsFinished() int32 {
+	if x != nil {
+		return x.CommandsFinished
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetCommandsSkipped() int32 {
+	if x != nil {
+		return x.CommandsSkipped
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLastAckedIndex() int32 {
+	if x != nil {
+		return x.LastAckedIndex
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetBytesTotal() int64 {
+	if x != nil {
+		return x.BytesTotal
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetBytesServed() int64 {
+	if x != nil {
+		return x.BytesServed
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetBytesRemaining() int64 {
+	if x != nil {
+		return x.BytesRemaining
+	}
+	return 0
+}
+
+type WarningMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path    string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Warning []string `protobuf:"bytes,2,rep,name=warning,proto3" json:"warning,omitempty"`
+}
+
+func (x *WarningMessage) Reset() {
+	*x = WarningMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WarningMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WarningMessage) ProtoMessage() {}
+
+func (x *WarningMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WarningMessage.ProtoReflect.Descriptor instead.
+func (*WarningMessage) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *WarningMessage) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *WarningMessage) GetWarning() []string {
+	if x != nil {
+		return x.Warning
+	}
+	return nil
+}
+
+type WatchEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Payload:
+	//	*WatchEvent_Cancel_
+	Payload isWatchEvent_Payload `protobuf_oneof:"payload"`
+}
+
+func (x *WatchEvent) Reset() {
+	*x = WatchEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent) ProtoMessage() {}
+
+func (x *WatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent.ProtoReflect.Descriptor instead.
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{31}
+}
+
+func (m *WatchEvent) GetPayload() isWatchEvent_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (x *WatchEvent) GetCancel() *WatchEvent_Cancel {
+	if x, ok := x.GetPayload().(*WatchEvent_Cancel_); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+type isWatchEvent_Payload interface {
+	isWatchEvent_Payload()
+}
+
+type WatchEvent_Cancel_ struct {
+	Cancel *WatchEvent_Cancel `protobuf:"bytes,1,opt,name=cancel,proto3,oneof"`
+}
+
+func (*WatchEvent_Cancel_) isWatchEvent_Payload() {}
+
+// A single resource path maps to one or multiple resources.
+// The targetPath indicates the actual file where the resource must be written to.
+type ResourceChunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Payload:
+	//	*ResourceChunk_Header
+	//	*ResourceChunk_Chunk
+	//	*ResourceChunk_Eof
+	//	*ResourceChunk_Error
+	//	*ResourceChunk_Delete
+	//	*ResourceChunk_Batch
+	Payload isResourceChunk_Payload `protobuf_oneof:"payload"`
+}
+
+func (x *ResourceChunk) Reset() {
+	*x = ResourceChunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk) ProtoMessage() {}
+
+func (x *ResourceChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk.ProtoReflect.Descriptor instead.
+func (*ResourceChunk) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32}
+}
+
+func (m *ResourceChunk) GetPayload() isResourceChunk_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetHeader() *ResourceChunk_ResourceHeader {
+	if x, ok := x.GetPayload().(*ResourceChunk_Header); ok {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetChunk() *ResourceChunk_ResourceContents {
+	if x, ok := x.GetPayload().(*ResourceChunk_Chunk); ok {
+		return x.Chunk
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetEof() *ResourceChunk_ResourceEof {
+	if x, ok := x.GetPayload().(*ResourceChunk_Eof); ok {
+		return x.Eof
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetError() *ResourceChunk_ResourceError {
+	if x, ok := x.GetPayload().(*ResourceChunk_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetDelete() *ResourceChunk_ResourceDelete {
+	if x, ok := x.GetPayload().(*ResourceChunk_Delete); ok {
+		return x.Delete
+	}
+	return nil
+}
+
+func (x *ResourceChunk) GetBatch() *ResourceChunk_ResourceBatch {
+	if x, ok := x.GetPayload().(*ResourceChunk_Batch); ok {
+		return x.Batch
+	}
+	return nil
+}
+
+type isResourceChunk_Payload interface {
+	isResourceChunk_Payload()
+}
+
+type ResourceChunk_Header struct {
+	Header *ResourceChunk_ResourceHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type ResourceChunk_Chunk struct {
+	Chunk *ResourceChunk_ResourceContents `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+type ResourceChunk_Eof struct {
+	Eof *ResourceChunk_ResourceEof `protobuf:"bytes,3,opt,name=eof,proto3,oneof"`
+}
+
+type ResourceChunk_Error struct {
+	Error *ResourceChunk_ResourceError `protobuf:"bytes,4,opt,name=error,proto3,oneof"`
+}
+
+type ResourceChunk_Delete struct {
+	Delete *ResourceChunk_ResourceDelete `protobuf:"bytes,5,opt,name=delete,proto3,oneof"`
+}
+
+type ResourceChunk_Batch struct {
+	Batch *ResourceChunk_ResourceBatch `protobuf:"bytes,6,opt,name=batch,proto3,oneof"`
+}
+
+func (*ResourceChunk_Header) isResourceChunk_Payload() {}
+
+func (*ResourceChunk_Chunk) isResourceChunk_Payload() {}
+
+func (*ResourceChunk_Eof) isResourceChunk_Payload() {}
+
+func (*ResourceChunk_Error) isResourceChunk_Payload() {}
+
+func (*ResourceChunk_Delete) isResourceChunk_Payload() {}
+
+func (*ResourceChunk_Batch) isResourceChunk_Payload() {}
+
+type BlockDeltaFrame_Copy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index int64 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+}
+
+func (x *BlockDeltaFrame_Copy) Reset() {
+	*x = BlockDeltaFrame_Copy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockDeltaFrame_Copy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockDeltaFrame_Copy) ProtoMessage() {}
+
+func (x *BlockDeltaFrame_Copy) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockDeltaFrame_Copy.ProtoReflect.Descriptor instead.
+func (*BlockDeltaFrame_Copy) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{2, 0}
+}
+
+func (x *BlockDeltaFrame_Copy) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type BlockDeltaFrame_Literal struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data     []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Checksum []byte `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (x *BlockDeltaFrame_Literal) Reset() {
+	*x = BlockDeltaFrame_Literal{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockDeltaFrame_Literal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockDeltaFrame_Literal) ProtoMessage() {}
+
+func (x *BlockDeltaFrame_Literal) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockDeltaFrame_Literal.ProtoReflect.Descriptor instead.
+func (*BlockDeltaFrame_Literal) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{2, 1}
+}
+
+func (x *BlockDeltaFrame_Literal) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame_Literal) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+type BlockDeltaFrame_End struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
+	Size   int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+}
+
+func (x *BlockDeltaFrame_End) Reset() {
+	*x = BlockDeltaFrame_End{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockDeltaFrame_End) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockDeltaFrame_End) ProtoMessage() {}
+
+func (x *BlockDeltaFrame_End) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockDeltaFrame_End.ProtoReflect.Descriptor instead.
+func (*BlockDeltaFrame_End) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{2, 2}
+}
+
+func (x *BlockDeltaFrame_End) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *BlockDeltaFrame_End) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type BlockDeltaRequest_Signature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Weak   uint32 `protobuf:"varint,1,opt,name=weak,proto3" json:"weak,omitempty"`
+	Strong []byte `protobuf:"bytes,2,opt,name=strong,proto3" json:"strong,omitempty"`
+}
+
+func (x *BlockDeltaRequest_Signature) Reset() {
+	*x = BlockDeltaRequest_Signature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[36]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockDeltaRequest_Signature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockDeltaRequest_Signature) ProtoMessage() {}
+
+func (x *BlockDeltaRequest_Signature) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[36]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockDeltaRequest_Signature.ProtoReflect.Descriptor instead.
+func (*BlockDeltaRequest_Signature) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{3, 0}
+}
+
+func (x *BlockDeltaRequest_Signature) GetWeak() uint32 {
+	if x != nil {
+		return x.Weak
+	}
+	return 0
+}
+
+func (x *BlockDeltaRequest_Signature) GetStrong() []byte {
+	if x != nil {
+		return x.Strong
+	}
+	return nil
+}
+
+type ResourceDeltaRequest_Entry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TargetPath string `protobuf:"bytes,1,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+	Digest     string `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+}
+
+func (x *ResourceDeltaRequest_Entry) Reset() {
+	*x = ResourceDeltaRequest_Entry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[38]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceDeltaRequest_Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceDeltaRequest_Entry) ProtoMessage() {}
+
+func (x *ResourceDeltaRequest_Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[38]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceDeltaRequest_Entry.ProtoReflect.Descriptor instead.
+func (*ResourceDeltaRequest_Entry) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{23, 0}
+}
+
+func (x *ResourceDeltaRequest_Entry) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *ResourceDeltaRequest_Entry) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+type WatchEvent_Cancel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Reason string `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *WatchEvent_Cancel) Reset() {
+	*x = WatchEvent_Cancel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[41]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchEvent_Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent_Cancel) ProtoMessage() {}
+
+func (x *WatchEvent_Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[41]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent_Cancel.ProtoReflect.Descriptor instead.
+func (*WatchEvent_Cancel) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{31, 0}
+}
+
+func (x *WatchEvent_Cancel) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ResourceChunk_ResourceHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SourcePath    string `protobuf:"bytes,1,opt,name=sourcePath,proto3" json:"sourcePath,omitempty"`
+	TargetPath    string `protobuf:"bytes,2,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+	FileMode      int64  `protobuf:"varint,3,opt,name=fileMode,proto3" json:"fileMode,omitempty"`
+	IsDir         bool   `protobuf:"varint,4,opt,name=isDir,proto3" json:"isDir,omitempty"`
+	TargetUser    string `protobuf:"bytes,5,opt,name=targetUser,proto3" json:"targetUser,omitempty"`
+	TargetWorkdir string `protobuf:"bytes,6,opt,name=targetWorkdir,proto3" json:"targetWorkdir,omitempty"`
+	Id            string `protobuf:"bytes,7,opt,name=id,proto3" json:"id,omitempty"`
+	Platform      string `protobuf:"bytes,8,opt,name=platform,proto3" json:"platform,omitempty"`
+	EscapedPaths  bool   `protobuf:"varint,9,opt,name=escapedPaths,proto3" json:"escapedPaths,omitempty"`
+	Size          int64  `protobuf:"varint,10,opt,name=size,proto3" json:"size,omitempty"`
+	HasSize       bool   `protobuf:"varint,11,opt,name=hasSize,proto3" json:"hasSize,omitempty"`
+	Offset        int64  `protobuf:"varint,12,opt,name=offset,proto3" json:"offset,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceHeader) Reset() {
+	*x = ResourceChunk_ResourceHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[42]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceHeader) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[42]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceHeader.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceHeader) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 0}
+}
+
+func (x *ResourceChunk_ResourceHeader) GetSourcePath() string {
+	if x != nil {
+		return x.SourcePath
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetFileMode() int64 {
+	if x != nil {
+		return x.FileMode
+	}
+	return 0
+}
+
+func (x *ResourceChunk_ResourceHeader) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *ResourceChunk_ResourceHeader) GetTargetUser() string {
+	if x != nil {
+		return x.TargetUser
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetTargetWorkdir() string {
+	if x != nil {
+		return x.TargetWorkdir
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceHeader) GetEscapedPaths() bool {
+	if x != nil {
+		return x.EscapedPaths
+	}
+	return false
+}
+
+func (x *ResourceChunk_ResourceHeader) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ResourceChunk_ResourceHeader) GetHasSize() bool {
+	if x != nil {
+		return x.HasSize
+	}
+	return false
+}
+
+func (x *ResourceChunk_ResourceHeader) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ResourceChunk_ResourceContents struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chunk       []byte `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Checksum    []byte `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	Id          string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Compression string `protobuf:"bytes,4,opt,name=compression,proto3" json:"compression,omitempty"`
+	Size        int64  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceContents) Reset() {
+	*x = ResourceChunk_ResourceContents{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[43]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceContents) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceContents) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceContents) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[43]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceContents.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceContents) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 1}
+}
+
+func (x *ResourceChunk_ResourceContents) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+func (x *ResourceChunk_ResourceContents) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+func (x *ResourceChunk_ResourceContents) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceContents) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceContents) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type ResourceChunk_ResourceEof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Digest string `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceEof) Reset() {
+	*x = ResourceChunk_ResourceEof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[44]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceEof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceEof) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceEof) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[44]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceEof.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceEof) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 2}
+}
+
+func (x *ResourceChunk_ResourceEof) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceEof) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+type ResourceChunk_ResourceError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceError) Reset() {
+	*x = ResourceChunk_ResourceError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[45]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceError) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceError) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[45]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceError.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceError) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 3}
+}
+
+func (x *ResourceChunk_ResourceError) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResourceChunk_ResourceError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ResourceChunk_ResourceDelete struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TargetPath string `protobuf:"bytes,1,opt,name=targetPath,proto3" json:"targetPath,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceDelete) Reset() {
+	*x = ResourceChunk_ResourceDelete{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[46]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceDelete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceDelete) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceDelete) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[46]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceDelete.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceDelete) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 4}
+}
+
+func (x *ResourceChunk_ResourceDelete) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+type ResourceChunk_ResourceBatch struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*ResourceChunk_ResourceBatch_Entry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceBatch) Reset() {
+	*x = ResourceChunk_ResourceBatch{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[47]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceBatch) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[47]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceBatch.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceBatch) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 5}
+}
+
+func (x *ResourceChunk_ResourceBatch) GetEntries() []*ResourceChunk_ResourceBatch_Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type ResourceChunk_ResourceBatch_Entry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header   *ResourceChunk_ResourceHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Contents []byte                        `protobuf:"bytes,2,opt,name=contents,proto3" json:"contents,omitempty"`
+	Checksum []byte                        `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (x *ResourceChunk_ResourceBatch_Entry) Reset() {
+	*x = ResourceChunk_ResourceBatch_Entry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rootfs_server_proto_msgTypes[48]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceChunk_ResourceBatch_Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceChunk_ResourceBatch_Entry) ProtoMessage() {}
+
+func (x *ResourceChunk_ResourceBatch_Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_rootfs_server_proto_msgTypes[48]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceChunk_ResourceBatch_Entry.ProtoReflect.Descriptor instead.
+func (*ResourceChunk_ResourceBatch_Entry) Descriptor() ([]byte, []int) {
+	return file_rootfs_server_proto_rawDescGZIP(), []int{32, 5, 0}
+}
+
+func (x *ResourceChunk_ResourceBatch_Entry) GetHeader() *ResourceChunk_ResourceHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *ResourceChunk_ResourceBatch_Entry) GetContents() []byte {
+	if x != nil {
+		return x.Contents
+	}
+	return nil
+}
+
+func (x *ResourceChunk_ResourceBatch_Entry) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+var File_rootfs_server_proto protoreflect.FileDescriptor
+
+var file_rootfs_server_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x72, 0x6f, 0x6f, 0x74, 0x66, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x24, 0x0a, 0x0c,
+	0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x22, 0x25, 0x0a, 0x0d, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x62, 0x75, 0x67, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x05, 0x64, 0x65, 0x62, 0x75, 0x67, 0x22, 0x86, 0x03, 0x0a, 0x0f, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x12, 0x3d, 0x0a,
+	0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68,
+	0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x48, 0x00, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x31, 0x0a, 0x04,
+	0x63, 0x6f, 0x70, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x72, 0x61,
+	0x6d, 0x65, 0x2e, 0x43, 0x6f, 0x70, 0x79, 0x48, 0x00, 0x52, 0x04, 0x63, 0x6f, 0x70, 0x79, 0x12,
+	0x3a, 0x0a, 0x07, 0x6c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65,
+	0x6c, 0x74, 0x61, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x2e, 0x4c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c,
+	0x48, 0x00, 0x52, 0x07, 0x6c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c, 0x12, 0x2e, 0x0a, 0x03, 0x65,
+	0x6e, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x72, 0x61, 0x6d, 0x65,
+	0x2e, 0x45, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x1a, 0x1c, 0x0a, 0x04, 0x43,
+	0x6f, 0x70, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x1a, 0x39, 0x0a, 0x07, 0x4c, 0x69, 0x74,
+	0x65, 0x72, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x73, 0x75, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x73, 0x75, 0x6d, 0x1a, 0x31, 0x0a, 0x03, 0x45, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x64,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f,
+	0x61, 0x64, 0x22, 0xd0, 0x01, 0x0a, 0x11, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74,
+	0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61,
+	0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65,
+	0x12, 0x3a, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65,
+	0x6c, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x1a, 0x37, 0x0a, 0x09,
+	0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x77, 0x65, 0x61,
+	0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x77, 0x65, 0x61, 0x6b, 0x12, 0x16, 0x0a,
+	0x06, 0x73, 0x74, 0x72, 0x6f, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73,
+	0x74, 0x72, 0x6f, 0x6e, 0x67, 0x22, 0xfe, 0x01, 0x0a, 0x14, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1c,
+	0x0a, 0x09, 0x61, 0x75, 0x74, 0x68, 0x4d, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x09, 0x61, 0x75, 0x74, 0x68, 0x4d, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x12,
+	0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68,
+	0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x12, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x75, 0x6d, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x73, 0x12, 0x20, 0x0a, 0x0b,
+	0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x61,
+	0x78, 0x4d, 0x73, 0x67, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x6d, 0x61, 0x78, 0x4d, 0x73, 0x67, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x6d, 0x61,
+	0x78, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0c, 0x6d, 0x61, 0x78, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06,
+	0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x22, 0xa5, 0x01, 0x0a, 0x0a, 0x43, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x41, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x2d, 0x0a, 0x05, 0x70,
+	0x68, 0x61, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x41, 0x63, 0x6b, 0x2e, 0x50, 0x68,
+	0x61, 0x73, 0x65, 0x52, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0x3c, 0x0a, 0x05, 0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x54, 0x41,
+	0x52, 0x54, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x46, 0x49, 0x4e, 0x49, 0x53, 0x48,
+	0x45, 0x44, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x4b, 0x49, 0x50, 0x50, 0x45, 0x44, 0x10,
+	0x02, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x45, 0x54, 0x52, 0x49, 0x45, 0x44, 0x10, 0x03, 0x22, 0x42,
+	0x0a, 0x10, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x22, 0x20, 0x0a, 0x0a, 0x44, 0x65, 0x62, 0x75, 0x67, 0x46, 0x72, 0x61, 0x6d, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
+	0x64, 0x61, 0x74, 0x61, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x84, 0x01,
+	0x0a, 0x13, 0x45, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6e, 0x76, 0x69, 0x72,
+	0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x45,
+	0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x03, 0x65, 0x6e, 0x76, 0x1a, 0x36, 0x0a, 0x08,
+	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x52, 0x0a, 0x0c, 0x46, 0x6c, 0x75, 0x73, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x4c, 0x69,
+	0x6e, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73, 0x74, 0x64, 0x6f, 0x75,
+	0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72,
+	0x4c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73, 0x74, 0x64,
+	0x65, 0x72, 0x72, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x22, 0x6f, 0x0a, 0x0d, 0x46, 0x6c, 0x75, 0x73,
+	0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x74, 0x64,
+	0x6f, 0x75, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b,
+	0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x73,
+	0x74, 0x64, 0x65, 0x72, 0x72, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0b, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x1a, 0x0a,
+	0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x22, 0xaa, 0x01, 0x0a, 0x07, 0x4c, 0x6f,
+	0x67, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x2d, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f,
+	0x67, 0x4c, 0x69, 0x6e, 0x65, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x06, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x49, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x49, 0x64, 0x22, 0x20, 0x0a, 0x06, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x0a,
+	0x0a, 0x06, 0x53, 0x54, 0x44, 0x4f, 0x55, 0x54, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x54,
+	0x44, 0x45, 0x52, 0x52, 0x10, 0x01, 0x22, 0x20, 0x0a, 0x0a, 0x4c, 0x6f, 0x67, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0xc1, 0x01, 0x0a, 0x0d, 0x4d, 0x61, 0x6e,
+	0x69, 0x66, 0x65, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61,
+	0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x1e,
+	0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x12, 0x1a,
+	0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x73,
+	0x44, 0x69, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x69, 0x73, 0x44, 0x69, 0x72,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
+	0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x3b, 0x0a, 0x0f,
+	0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x22, 0x42, 0x0a, 0x10, 0x4d, 0x61, 0x6e,
+	0x69, 0x66, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2e, 0x0a,
+	0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x22, 0x1d, 0x0a,
+	0x0b, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1e, 0x0a, 0x0c,
+	0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x29, 0x0a, 0x17,
+	0x50, 0x6f, 0x72, 0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x43, 0x6c, 0x6f, 0x73, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x70, 0x0a, 0x12, 0x50, 0x6f, 0x72, 0x74, 0x46,
+	0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x20, 0x0a, 0x0b, 0x68, 0x6f, 0x73,
+	0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x68, 0x6f, 0x73, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x67,
+	0x75, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09,
+	0x67, 0x75, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x49, 0x0a, 0x13, 0x50, 0x6f, 0x72,
+	0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x22, 0x0a, 0x0c, 0x67, 0x75, 0x65, 0x73, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x67, 0x75, 0x65, 0x73, 0x74, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x22, 0xc2, 0x01, 0x0a, 0x0e, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73,
+	0x73, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x22, 0x0a, 0x0c, 0x62,
+	0x79, 0x74, 0x65, 0x73, 0x57, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0c, 0x62, 0x79, 0x74, 0x65, 0x73, 0x57, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x12,
+	0x1e, 0x0a, 0x0a, 0x62, 0x79, 0x74, 0x65, 0x73, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x62, 0x79, 0x74, 0x65, 0x73, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x12,
+	0x26, 0x0a, 0x0e, 0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65,
+	0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x74, 0x61, 0x4d, 0x69,
+	0x6c, 0x6c, 0x69, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x65, 0x74, 0x61, 0x4d,
+	0x69, 0x6c, 0x6c, 0x69, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x6f, 0x6e, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x04, 0x64, 0x6f, 0x6e, 0x65, 0x22, 0xb2, 0x02, 0x0a, 0x14, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x12, 0x3d, 0x0a, 0x08,
+	0x65, 0x78, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44,
+	0x65, 0x6c, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x08, 0x65, 0x78, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x1c, 0x0a, 0x09, 0x6d,
+	0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09,
+	0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x65, 0x78, 0x12, 0x24, 0x0a, 0x0d, 0x62, 0x61, 0x74,
+	0x63, 0x68, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0d, 0x62, 0x61, 0x74, 0x63, 0x68, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x2c, 0x0a, 0x11, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x61, 0x63, 0x63, 0x65,
+	0x70, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x3f, 0x0a,
+	0x05, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x50, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x22, 0xb1,
+	0x02, 0x0a, 0x0f, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x12, 0x1e, 0x0a, 0x0a,
+	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06,
+	0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x65,
+	0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c,
+	0x65, 0x78, 0x12, 0x24, 0x0a, 0x0d, 0x62, 0x61, 0x74, 0x63, 0x68, 0x46, 0x69, 0x6c, 0x65, 0x53,
+	0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x62, 0x61, 0x74, 0x63, 0x68,
+	0x46, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x2c, 0x0a, 0x11, 0x61, 0x63, 0x63, 0x65,
+	0x70, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x11, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78,
+	0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x70, 0x72,
+	0x65, 0x66, 0x69, 0x78, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x0e, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0e, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x44, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x22, 0xf2, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x49, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x49, 0x64, 0x12, 0x2e,
+	0x0a, 0x12, 0x6c, 0x61, 0x73, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x6c, 0x61, 0x73, 0x74,
+	0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x53,
+	0x0a, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f, 0x66, 0x66, 0x73,
+	0x65, 0x74, 0x73, 0x1a, 0x42, 0x0a, 0x14, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f,
+	0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xe2, 0x01, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x75,
+	0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x62, 0x75, 0x69,
+	0x6c, 0x64, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x65, 0x78, 0x74, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6e, 0x65, 0x78, 0x74, 0x49, 0x6e, 0x64,
+	0x65, 0x78, 0x12, 0x54, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x1a, 0x42, 0x0a, 0x14, 0x52, 0x65, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x59, 0x0a, 0x0d,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74,
+	0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x50, 0x61, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72,
+	0x67, 0x65, 0x74, 0x50, 0x61, 0x74, 0x68, 0x22, 0x69, 0x0a, 0x0e, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x06, 0x68, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e,
+	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06,
+	0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x73, 0x22, 0x90, 0x03, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x73, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0d, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x2a,
+	0x0a, 0x10, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x46, 0x69, 0x6e, 0x69, 0x73, 0x68,
+	0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x73, 0x46, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x12, 0x28, 0x0a, 0x0f, 0x63, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x53, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x53, 0x6b, 0x69,
+	0x70, 0x70, 0x65, 0x64, 0x12, 0x26, 0x0a, 0x0e, 0x6c, 0x61, 0x73, 0x74, 0x41, 0x63, 0x6b, 0x65,
+	0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x6c, 0x61,
+	0x73, 0x74, 0x41, 0x63, 0x6b, 0x65, 0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1e, 0x0a, 0x0a,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x62, 0x79, 0x74, 0x65, 0x73, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x20, 0x0a, 0x0b,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0b, 0x62, 0x79, 0x74, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x64, 0x12, 0x26,
+	0x0a, 0x0e, 0x62, 0x79, 0x74, 0x65, 0x73, 0x52, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x62, 0x79, 0x74, 0x65, 0x73, 0x52, 0x65, 0x6d,
+	0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x22, 0x3d, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12,
+	0x0b, 0x0a, 0x07, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07,
+	0x52, 0x55, 0x4e, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x53, 0x55, 0x43,
+	0x43, 0x45, 0x45, 0x44, 0x45, 0x44, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x41, 0x42, 0x4f, 0x52,
+	0x54, 0x45, 0x44, 0x10, 0x03, 0x22, 0x3e, 0x0a, 0x0e, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x77,
+	0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x77, 0x61,
+	0x72, 0x6e, 0x69, 0x6e, 0x67, 0x22, 0x6d, 0x0a, 0x0a, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x12, 0x32, 0x0a, 0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x48, 0x00, 0x52,
+	0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x1a, 0x20, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79,
+	0x6c, 0x6f, 0x61, 0x64, 0x22, 0xeb, 0x09, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x3d, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x48, 0x00, 0x52, 0x06, 0x68,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x3d, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x48, 0x00, 0x52, 0x05, 0x63,
+	0x68, 0x75, 0x6e, 0x6b, 0x12, 0x34, 0x0a, 0x03, 0x65, 0x6f, 0x66, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x20, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x45, 0x6f, 0x66, 0x48, 0x00, 0x52, 0x03, 0x65, 0x6f, 0x66, 0x12, 0x3a, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e,
+	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x48, 0x00, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x3d, 0x0a, 0x06, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x48, 0x00, 0x52, 0x06, 0x64,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x3a, 0x0a, 0x05, 0x62, 0x61, 0x74, 0x63, 0x68, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x48, 0x00, 0x52, 0x05, 0x62, 0x61, 0x74, 0x63,
+	0x68, 0x1a, 0xde, 0x02, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x48, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x61,
+	0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x50, 0x61, 0x74, 0x68, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61,
+	0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x50, 0x61, 0x74, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x6f, 0x64, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x69, 0x73, 0x44, 0x69, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x05, 0x69, 0x73, 0x44, 0x69, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x55, 0x73, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x12, 0x24, 0x0a, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x57, 0x6f, 0x72, 0x6b, 0x64, 0x69, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x74,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x64, 0x69, 0x72, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08,
+	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x73, 0x63, 0x61,
+	0x70, 0x65, 0x64, 0x50, 0x61, 0x74, 0x68, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c,
+	0x65, 0x73, 0x63, 0x61, 0x70, 0x65, 0x64, 0x50, 0x61, 0x74, 0x68, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x68, 0x61, 0x73, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x68, 0x61, 0x73, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73,
+	0x65, 0x74, 0x1a, 0x8a, 0x01, 0x0a, 0x10, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43,
+	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x1a, 0x0a,
+	0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6d,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x1a,
+	0x35, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x45, 0x6f, 0x66, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x1a, 0x39, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x1a, 0x30, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x61, 0x74,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50,
+	0x61, 0x74, 0x68, 0x1a, 0xd1, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x42, 0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x1a, 0x7c, 0x0a, 0x05, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x3b, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12,
+	0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f,
+	0x61, 0x64, 0x32, 0x8e, 0x0a, 0x0a, 0x0c, 0x52, 0x6f, 0x6f, 0x74, 0x66, 0x73, 0x53, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x12, 0x39, 0x0a, 0x0c, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x1a, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31,
+	0x0a, 0x08, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x26, 0x0a, 0x03, 0x41, 0x63, 0x6b, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x41, 0x63, 0x6b, 0x1a, 0x0c, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x35, 0x0a, 0x06, 0x52, 0x65, 0x73,
+	0x75, 0x6d, 0x65, 0x12, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x75,
+	0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x37, 0x0a, 0x0b, 0x45, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x12,
+	0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x1a, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x4d, 0x61, 0x6e,
+	0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x61,
+	0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x12,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3a, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x43, 0x68, 0x75, 0x6e,
+	0x6b, 0x30, 0x01, 0x12, 0x44, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x44,
+	0x65, 0x6c, 0x74, 0x61, 0x12, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30, 0x01, 0x12, 0x48, 0x0a, 0x12, 0x52, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x12,
+	0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c,
+	0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x72, 0x61, 0x6d,
+	0x65, 0x30, 0x01, 0x12, 0x35, 0x0a, 0x06, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x14, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x06, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70,
+	0x74, 0x79, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x44, 0x0a, 0x0b, 0x50, 0x6f, 0x72,
+	0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x12, 0x19, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x50, 0x6f, 0x72, 0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x72, 0x74,
+	0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x40, 0x0a, 0x10, 0x50, 0x6f, 0x72, 0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x43, 0x6c,
+	0x6f, 0x73, 0x65, 0x12, 0x1e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x72, 0x74,
+	0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x64, 0x45, 0x72, 0x72, 0x12, 0x11, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x67, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0c,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x29, 0x0a, 0x06,
+	0x53, 0x74, 0x64, 0x4f, 0x75, 0x74, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c,
+	0x6f, 0x67, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x32, 0x0a, 0x05, 0x46, 0x6c, 0x75, 0x73, 0x68,
+	0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x6c, 0x75, 0x73, 0x68, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x6c,
+	0x75, 0x73, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x08, 0x50,
+	0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x1a, 0x0c,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x2e, 0x0a, 0x07,
+	0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0c,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x32, 0x0a, 0x05,
+	0x41, 0x62, 0x6f, 0x72, 0x74, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x41, 0x62,
+	0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x31, 0x0a, 0x05, 0x44, 0x65, 0x62, 0x75, 0x67, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x1a, 0x11, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x28,
+	0x01, 0x30, 0x01, 0x12, 0x2a, 0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x0c, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x11, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12,
+	0x2b, 0x0a, 0x09, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4c, 0x6f, 0x67, 0x73, 0x12, 0x0c, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0e, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x67, 0x4c, 0x69, 0x6e, 0x65, 0x30, 0x01, 0x12, 0x25, 0x0a, 0x07,
+	0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x63, 0x6f, 0x6d, 0x62, 0x75, 0x73, 0x74, 0x2d, 0x6c, 0x61, 0x62, 0x73, 0x2f, 0x66,
+	0x69, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rootfs_server_proto_rawDescOnce sync.Once
+	file_rootfs_server_proto_rawDescData = file_rootfs_server_proto_rawDesc
+)
+
+func file_rootfs_server_proto_rawDescGZIP() []byte {
+	file_rootfs_server_proto_rawDescOnce.Do(func() {
+		file_rootfs_server_proto_rawDescData = protoimpl.X.CompressGZIP(file_rootfs_server_proto_rawDescData)
+	})
+	return file_rootfs_server_proto_rawDescData
+}
+
+var file_rootfs_server_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_rootfs_server_proto_msgTypes = make([]protoimpl.MessageInfo, 49)
+var file_rootfs_server_proto_goTypes = []interface{}{
+	(CommandAck_Phase)(0),                     // 0: proto.CommandAck.Phase
+	(LogLine_Stream)(0),                       // 1: proto.LogLine.Stream
+	(StatusResponse_State)(0),                 // 2: proto.StatusResponse.State
+	(*AbortRequest)(nil),                      // 3: proto.AbortRequest
+	(*AbortResponse)(nil),                     // 4: proto.AbortResponse
+	(*BlockDeltaFrame)(nil),                   // 5: proto.BlockDeltaFrame
+	(*BlockDeltaRequest)(nil),                 // 6: proto.BlockDeltaRequest
+	(*CapabilitiesResponse)(nil),              // 7: proto.CapabilitiesResponse
+	(*CommandAck)(nil),                        // 8: proto.CommandAck
+	(*CommandsResponse)(nil),                  // 9: proto.CommandsResponse
+	(*DebugFrame)(nil),                        // 10: proto.DebugFrame
+	(*Empty)(nil),                             // 11: proto.Empty
+	(*EnvironmentResponse)(nil),               // 12: proto.EnvironmentResponse
+	(*FlushRequest)(nil),                      // 13: proto.FlushRequest
+	(*FlushResponse)(nil),                     // 14: proto.FlushResponse
+	(*LogLine)(nil),                           // 15: proto.LogLine
+	(*LogMessage)(nil),                        // 16: proto.LogMessage
+	(*ManifestEntry)(nil),                     // 17: proto.ManifestEntry
+	(*ManifestRequest)(nil),                   // 18: proto.ManifestRequest
+	(*ManifestResponse)(nil),                  // 19: proto.ManifestResponse
+	(*PingRequest)(nil),                       // 20: proto.PingRequest
+	(*PingResponse)(nil),                      // 21: proto.PingResponse
+	(*PortForwardCloseRequest)(nil),           // 22: proto.PortForwardCloseRequest
+	(*PortForwardRequest)(nil),                // 23: proto.PortForwardRequest
+	(*PortForwardResponse)(nil),               // 24: proto.PortForwardResponse
+	(*ProgressReport)(nil),                    // 25: proto.ProgressReport
+	(*ResourceDeltaRequest)(nil),              // 26: proto.ResourceDeltaRequest
+	(*ResourceRequest)(nil),                   // 27: proto.ResourceRequest
+	(*ResumeRequest)(nil),                     // 28: proto.ResumeRequest
+	(*ResumeResponse)(nil),                    // 29: proto.ResumeResponse
+	(*SecretRequest)(nil),                     // 30: proto.SecretRequest
+	(*SecretResponse)(nil),                    // 31: proto.SecretResponse
+	(*StatusResponse)(nil),                    // 32: proto.StatusResponse
+	(*WarningMessage)(nil),                    // 33: proto.WarningMessage
+	(*WatchEvent)(nil),                        // 34: proto.WatchEvent
+	(*ResourceChunk)(nil),                     // 35: proto.ResourceChunk
+	(*BlockDeltaFrame_Copy)(nil),              // 36: proto.BlockDeltaFrame.Copy
+	(*BlockDeltaFrame_Literal)(nil),           // 37: proto.BlockDeltaFrame.Literal
+	(*BlockDeltaFrame_End)(nil),               // 38: proto.BlockDeltaFrame.End
+	(*BlockDeltaRequest_Signature)(nil),       // 39: proto.BlockDeltaRequest.Signature
+	nil,                                       // 40: proto.EnvironmentResponse.EnvEntry
+	(*ResourceDeltaRequest_Entry)(nil),        // 41: proto.ResourceDeltaRequest.Entry
+	nil,                                       // 42: proto.ResumeRequest.ResourceOffsetsEntry
+	nil,                                       // 43: proto.ResumeResponse.ResourceOffsetsEntry
+	(*WatchEvent_Cancel)(nil),                 // 44: proto.WatchEvent.Cancel
+	(*ResourceChunk_ResourceHeader)(nil),      // 45: proto.ResourceChunk.ResourceHeader
+	(*ResourceChunk_ResourceContents)(nil),    // 46: proto.ResourceChunk.ResourceContents
+	(*ResourceChunk_ResourceEof)(nil),         // 47: proto.ResourceChunk.ResourceEof
+	(*ResourceChunk_ResourceError)(nil),       // 48: proto.ResourceChunk.ResourceError
+	(*ResourceChunk_ResourceDelete)(nil),      // 49: proto.ResourceChunk.ResourceDelete
+	(*ResourceChunk_ResourceBatch)(nil),       // 50: proto.ResourceChunk.ResourceBatch
+	(*ResourceChunk_ResourceBatch_Entry)(nil), // 51: proto.ResourceChunk.ResourceBatch.Entry
+}
+var file_rootfs_server_proto_depIdxs = []int32{
+	45, // 0: proto.BlockDeltaFrame.header:type_name -> proto.ResourceChunk.ResourceHeader
+	36, // 1: proto.BlockDeltaFrame.copy:type_name -> proto.BlockDeltaFrame.Copy
+	37, // 2: proto.BlockDeltaFrame.literal:type_name -> proto.BlockDeltaFrame.Literal
+	38, // 3: proto.BlockDeltaFrame.end:type_name -> proto.BlockDeltaFrame.End
+	39, // 4: proto.BlockDeltaRequest.blocks:type_name -> proto.BlockDeltaRequest.Signature
+	0,  // 5: proto.CommandAck.phase:type_name -> proto.CommandAck.Phase
+	40, // 6: proto.EnvironmentResponse.env:type_name -> proto.EnvironmentResponse.EnvEntry
+	1,  // 7: proto.LogLine.stream:type_name -> proto.LogLine.Stream
+	17, // 8: proto.ManifestResponse.entries:type_name -> proto.ManifestEntry
+	41, // 9: proto.ResourceDeltaRequest.existing:type_name -> proto.ResourceDeltaRequest.Entry
+	42, // 10: proto.ResumeRequest.resourceOffsets:type_name -> proto.ResumeRequest.ResourceOffsetsEntry
+	43, // 11: proto.ResumeResponse.resourceOffsets:type_name -> proto.ResumeResponse.ResourceOffsetsEntry
+	45, // 12: proto.SecretResponse.header:type_name -> proto.ResourceChunk.ResourceHeader
+	2,  // 13: proto.StatusResponse.state:type_name -> proto.StatusResponse.State
+	44, // 14: proto.WatchEvent.cancel:type_name -> proto.WatchEvent.Cancel
+	45, // 15: proto.ResourceChunk.header:type_name -> proto.ResourceChunk.ResourceHeader
+	46, // 16: proto.ResourceChunk.chunk:type_name -> proto.ResourceChunk.ResourceContents
+	47, // 17: proto.ResourceChunk.eof:type_name -> proto.ResourceChunk.ResourceEof
+	48, // 18: proto.ResourceChunk.error:type_name -> proto.ResourceChunk.ResourceError
+	49, // 19: proto.ResourceChunk.delete:type_name -> proto.ResourceChunk.ResourceDelete
+	50, // 20: proto.ResourceChunk.batch:type_name -> proto.ResourceChunk.ResourceBatch
+	51, // 21: proto.ResourceChunk.ResourceBatch.entries:type_name -> proto.ResourceChunk.ResourceBatch.Entry
+	45, // 22: proto.ResourceChunk.ResourceBatch.Entry.header:type_name -> proto.ResourceChunk.ResourceHeader
+	11, // 23: proto.RootfsServer.Capabilities:input_type -> proto.Empty
+	11, // 24: proto.RootfsServer.Commands:input_type -> proto.Empty
+	8,  // 25: proto.RootfsServer.Ack:input_type -> proto.CommandAck
+	28, // 26: proto.RootfsServer.Resume:input_type -> proto.ResumeRequest
+	11, // 27: proto.RootfsServer.Environment:input_type -> proto.Empty
+	18, // 28: proto.RootfsServer.Manifest:input_type -> proto.ManifestRequest
+	20, // 29: proto.RootfsServer.Ping:input_type -> proto.PingRequest
+	27, // 30: proto.RootfsServer.Resource:input_type -> proto.ResourceRequest
+	26, // 31: proto.RootfsServer.ResourceDelta:input_type -> proto.ResourceDeltaRequest
+	6,  // 32: proto.RootfsServer.ResourceBlockDelta:input_type -> proto.BlockDeltaRequest
+	30, // 33: proto.RootfsServer.Secret:input_type -> proto.SecretRequest
+	11, // 34: proto.RootfsServer.Status:input_type -> proto.Empty
+	23, // 35: proto.RootfsServer.PortForward:input_type -> proto.PortForwardRequest
+	22, // 36: proto.RootfsServer.PortForwardClose:input_type -> proto.PortForwardCloseRequest
+	16, // 37: proto.RootfsServer.StdErr:input_type -> proto.LogMessage
+	16, // 38: proto.RootfsServer.StdOut:input_type -> proto.LogMessage
+	13, // 39: proto.RootfsServer.Flush:input_type -> proto.FlushRequest
+	25, // 40: proto.RootfsServer.Progress:input_type -> proto.ProgressReport
+	33, // 41: proto.RootfsServer.Warning:input_type -> proto.WarningMessage
+	3,  // 42: proto.RootfsServer.Abort:input_type -> proto.AbortRequest
+	10, // 43: proto.RootfsServer.Debug:input_type -> proto.DebugFrame
+	11, // 44: proto.RootfsServer.Watch:input_type -> proto.Empty
+	11, // 45: proto.RootfsServer.WatchLogs:input_type -> proto.Empty
+	11, // 46: proto.RootfsServer.Success:input_type -> proto.Empty
+	7,  // 47: proto.RootfsServer.Capabilities:output_type -> proto.CapabilitiesResponse
+	9,  // 48: proto.RootfsServer.Commands:output_type -> proto.CommandsResponse
+	11, // 49: proto.RootfsServer.Ack:output_type -> proto.Empty
+	29, // 50: proto.RootfsServer.Resume:output_type -> proto.ResumeResponse
+	12, // 51: proto.RootfsServer.Environment:output_type -> proto.EnvironmentResponse
+	19, // 52: proto.RootfsServer.Manifest:output_type -> proto.ManifestResponse
+	21, // 53: proto.RootfsServer.Ping:output_type -> proto.PingResponse
+	35, // 54: proto.RootfsServer.Resource:output_type -> proto.ResourceChunk
+	35, // 55: proto.RootfsServer.ResourceDelta:output_type -> proto.ResourceChunk
+	5,  // 56: proto.RootfsServer.ResourceBlockDelta:output_type -> proto.BlockDeltaFrame
+	31, // 57: proto.RootfsServer.Secret:output_type -> proto.SecretResponse
+	32, // 58: proto.RootfsServer.Status:output_type -> proto.StatusResponse
+	24, // 59: proto.RootfsServer.PortForward:output_type -> proto.PortForwardResponse
+	11, // 60: proto.RootfsServer.PortForwardClose:output_type -> proto.Empty
+	11, // 61: proto.RootfsServer.StdErr:output_type -> proto.Empty
+	11, // 62: proto.RootfsServer.StdOut:output_type -> proto.Empty
+	14, // 63: proto.RootfsServer.Flush:output_type -> proto.FlushResponse
+	11, // 64: proto.RootfsServer.Progress:output_type -> proto.Empty
+	11, // 65: proto.RootfsServer.Warning:output_type -> proto.Empty
+	4,  // 66: proto.RootfsServer.Abort:output_type -> proto.AbortResponse
+	10, // 67: proto.RootfsServer.Debug:output_type -> proto.DebugFrame
+	34, // 68: proto.RootfsServer.Watch:output_type -> proto.WatchEvent
+	15, // 69: proto.RootfsServer.WatchLogs:output_type -> proto.LogLine
+	11, // 70: proto.RootfsServer.Success:output_type -> proto.Empty
+	47, // [47:71] is the sub-list for method output_type
+	23, // [23:47] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
+}
+
+func init() { file_rootfs_server_proto_init() }
+func file_rootfs_server_proto_init() {
+	if File_rootfs_server_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_rootfs_server_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AbortRequest); i {
 			case 0:
 				return &v.state
@@ -800,8 +3780,140 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CommandsResponse); i {
+		file_rootfs_server_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AbortResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaFrame); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapabilitiesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommandAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommandsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DebugFrame); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Empty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EnvironmentResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FlushRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FlushResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogLine); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -812,8 +3924,8 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Empty); i {
+		file_rootfs_server_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogMessage); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -824,8 +3936,8 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*LogMessage); i {
+		file_rootfs_server_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ManifestEntry); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -836,7 +3948,31 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ManifestRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ManifestResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*PingRequest); i {
 			case 0:
 				return &v.state
@@ -848,7 +3984,7 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*PingResponse); i {
 			case 0:
 				return &v.state
@@ -860,7 +3996,67 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PortForwardCloseRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PortForwardRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PortForwardResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProgressReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceDeltaRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ResourceRequest); i {
 			case 0:
 				return &v.state
@@ -872,7 +4068,91 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResumeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResumeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecretRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecretResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WarningMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[31].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[32].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ResourceChunk); i {
 			case 0:
 				return &v.state
@@ -884,7 +4164,79 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[33].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaFrame_Copy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[34].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaFrame_Literal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[35].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaFrame_End); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[36].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDeltaRequest_Signature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[38].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceDeltaRequest_Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[41].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchEvent_Cancel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[42].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ResourceChunk_ResourceHeader); i {
 			case 0:
 				return &v.state
@@ -896,7 +4248,7 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[43].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ResourceChunk_ResourceContents); i {
 			case 0:
 				return &v.state
@@ -908,7 +4260,7 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
-		file_rootfs_server_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+		file_rootfs_server_proto_msgTypes[44].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ResourceChunk_ResourceEof); i {
 			case 0:
 				return &v.state
@@ -920,24 +4272,85 @@ func file_rootfs_server_proto_init() {
 				return nil
 			}
 		}
+		file_rootfs_server_proto_msgTypes[45].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceChunk_ResourceError); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[46].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceChunk_ResourceDelete); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[47].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceChunk_ResourceBatch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rootfs_server_proto_msgTypes[48].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceChunk_ResourceBatch_Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_rootfs_server_proto_msgTypes[2].OneofWrappers = []interface{}{
+		(*BlockDeltaFrame_Header)(nil),
+		(*BlockDeltaFrame_Copy_)(nil),
+		(*BlockDeltaFrame_Literal_)(nil),
+		(*BlockDeltaFrame_End_)(nil),
+	}
+	file_rootfs_server_proto_msgTypes[31].OneofWrappers = []interface{}{
+		(*WatchEvent_Cancel_)(nil),
 	}
-	file_rootfs_server_proto_msgTypes[7].OneofWrappers = []interface{}{
+	file_rootfs_server_proto_msgTypes[32].OneofWrappers = []interface{}{
 		(*ResourceChunk_Header)(nil),
 		(*ResourceChunk_Chunk)(nil),
 		(*ResourceChunk_Eof)(nil),
+		(*ResourceChunk_Error)(nil),
+		(*ResourceChunk_Delete)(nil),
+		(*ResourceChunk_Batch)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_rootfs_server_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      3,
+			NumMessages:   49,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_rootfs_server_proto_goTypes,
 		DependencyIndexes: file_rootfs_server_proto_depIdxs,
+		EnumInfos:         file_rootfs_server_proto_enumTypes,
 		MessageInfos:      file_rootfs_server_proto_msgTypes,
 	}.Build()
 	File_rootfs_server_proto = out.File
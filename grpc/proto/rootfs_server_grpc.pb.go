@@ -18,12 +18,29 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RootfsServerClient interface {
+	Capabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
 	Commands(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandsResponse, error)
+	Ack(ctx context.Context, in *CommandAck, opts ...grpc.CallOption) (*Empty, error)
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error)
+	Environment(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EnvironmentResponse, error)
+	Manifest(ctx context.Context, in *ManifestRequest, opts ...grpc.CallOption) (*ManifestResponse, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	Resource(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (RootfsServer_ResourceClient, error)
+	ResourceDelta(ctx context.Context, in *ResourceDeltaRequest, opts ...grpc.CallOption) (RootfsServer_ResourceDeltaClient, error)
+	ResourceBlockDelta(ctx context.Context, in *BlockDeltaRequest, opts ...grpc.CallOption) (RootfsServer_ResourceBlockDeltaClient, error)
+	Secret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretResponse, error)
+	Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	PortForward(ctx context.Context, in *PortForwardRequest, opts ...grpc.CallOption) (*PortForwardResponse, error)
+	PortForwardClose(ctx context.Context, in *PortForwardCloseRequest, opts ...grpc.CallOption) (*Empty, error)
 	StdErr(ctx context.Context, in *LogMessage, opts ...grpc.CallOption) (*Empty, error)
 	StdOut(ctx context.Context, in *LogMessage, opts ...grpc.CallOption) (*Empty, error)
-	Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*Empty, error)
+	Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*FlushResponse, error)
+	Progress(ctx context.Context, in *ProgressReport, opts ...grpc.CallOption) (*Empty, error)
+	Warning(ctx context.Context, in *WarningMessage, opts ...grpc.CallOption) (*Empty, error)
+	Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*AbortResponse, error)
+	Debug(ctx context.Context, opts ...grpc.CallOption) (RootfsServer_DebugClient, error)
+	Watch(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RootfsServer_WatchClient, error)
+	WatchLogs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RootfsServer_WatchLogsClient, error)
 	Success(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
 }
 
@@ -35,6 +52,15 @@ func NewRootfsServerClient(cc grpc.ClientConnInterface) RootfsServerClient {
 	return &rootfsServerClient{cc}
 }
 
+func (c *rootfsServerClient) Capabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Capabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *rootfsServerClient) Commands(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandsResponse, error) {
 	out := new(CommandsResponse)
 	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Commands", in, out, opts...)
@@ -44,6 +70,42 @@ func (c *rootfsServerClient) Commands(ctx context.Context, in *Empty, opts ...gr
 	return out, nil
 }
 
+func (c *rootfsServerClient) Ack(ctx context.Context, in *CommandAck, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Ack", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error) {
+	out := new(ResumeResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Resume", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Environment(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EnvironmentResponse, error) {
+	out := new(EnvironmentResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Environment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Manifest(ctx context.Context, in *ManifestRequest, opts ...grpc.CallOption) (*ManifestResponse, error) {
+	out := new(ManifestResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Manifest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *rootfsServerClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
 	out := new(PingResponse)
 	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Ping", in, out, opts...)
@@ -85,6 +147,106 @@ func (x *rootfsServerResourceClient) Recv() (*ResourceChunk, error) {
 	return m, nil
 }
 
+func (c *rootfsServerClient) ResourceDelta(ctx context.Context, in *ResourceDeltaRequest, opts ...grpc.CallOption) (RootfsServer_ResourceDeltaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RootfsServer_ServiceDesc.Streams[1], "/proto.RootfsServer/ResourceDelta", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rootfsServerResourceDeltaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RootfsServer_ResourceDeltaClient interface {
+	Recv() (*ResourceChunk, error)
+	grpc.ClientStream
+}
+
+type rootfsServerResourceDeltaClient struct {
+	grpc.ClientStream
+}
+
+func (x *rootfsServerResourceDeltaClient) Recv() (*ResourceChunk, error) {
+	m := new(ResourceChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rootfsServerClient) ResourceBlockDelta(ctx context.Context, in *BlockDeltaRequest, opts ...grpc.CallOption) (RootfsServer_ResourceBlockDeltaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RootfsServer_ServiceDesc.Streams[2], "/proto.RootfsServer/ResourceBlockDelta", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rootfsServerResourceBlockDeltaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RootfsServer_ResourceBlockDeltaClient interface {
+	Recv() (*BlockDeltaFrame, error)
+	grpc.ClientStream
+}
+
+type rootfsServerResourceBlockDeltaClient struct {
+	grpc.ClientStream
+}
+
+func (x *rootfsServerResourceBlockDeltaClient) Recv() (*BlockDeltaFrame, error) {
+	m := new(BlockDeltaFrame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rootfsServerClient) Secret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretResponse, error) {
+	out := new(SecretResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Secret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) PortForward(ctx context.Context, in *PortForwardRequest, opts ...grpc.CallOption) (*PortForwardResponse, error) {
+	out := new(PortForwardResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/PortForward", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) PortForwardClose(ctx context.Context, in *PortForwardCloseRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/PortForwardClose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *rootfsServerClient) StdErr(ctx context.Context, in *LogMessage, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, "/proto.RootfsServer/StdErr", in, out, opts...)
@@ -103,8 +265,35 @@ func (c *rootfsServerClient) StdOut(ctx context.Context, in *LogMessage, opts ..
 	return out, nil
 }
 
-func (c *rootfsServerClient) Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*Empty, error) {
+func (c *rootfsServerClient) Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*FlushResponse, error) {
+	out := new(FlushResponse)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Flush", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Progress(ctx context.Context, in *ProgressReport, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Progress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Warning(ctx context.Context, in *WarningMessage, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Warning", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rootfsServerClient) Abort(ctx context.Context, in *AbortRequest, opts ...grpc.CallOption) (*AbortResponse, error) {
+	out := new(AbortResponse)
 	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Abort", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -112,6 +301,101 @@ func (c *rootfsServerClient) Abort(ctx context.Context, in *AbortRequest, opts .
 	return out, nil
 }
 
+func (c *rootfsServerClient) Debug(ctx context.Context, opts ...grpc.CallOption) (RootfsServer_DebugClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RootfsServer_ServiceDesc.Streams[3], "/proto.RootfsServer/Debug", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rootfsServerDebugClient{stream}
+	return x, nil
+}
+
+type RootfsServer_DebugClient interface {
+	Send(*DebugFrame) error
+	Recv() (*DebugFrame, error)
+	grpc.ClientStream
+}
+
+type rootfsServerDebugClient struct {
+	grpc.ClientStream
+}
+
+func (x *rootfsServerDebugClient) Send(m *DebugFrame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *rootfsServerDebugClient) Recv() (*DebugFrame, error) {
+	m := new(DebugFrame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rootfsServerClient) Watch(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RootfsServer_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RootfsServer_ServiceDesc.Streams[4], "/proto.RootfsServer/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rootfsServerWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RootfsServer_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type rootfsServerWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *rootfsServerWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rootfsServerClient) WatchLogs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (RootfsServer_WatchLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RootfsServer_ServiceDesc.Streams[5], "/proto.RootfsServer/WatchLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rootfsServerWatchLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RootfsServer_WatchLogsClient interface {
+	Recv() (*LogLine, error)
+	grpc.ClientStream
+}
+
+type rootfsServerWatchLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *rootfsServerWatchLogsClient) Recv() (*LogLine, error) {
+	m := new(LogLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *rootfsServerClient) Success(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, "/proto.RootfsServer/Success", in, out, opts...)
@@ -125,12 +409,29 @@ func (c *rootfsServerClient) Success(ctx context.Context, in *Empty, opts ...grp
 // All implementations should embed UnimplementedRootfsServerServer
 // for forward compatibility
 type RootfsServerServer interface {
+	Capabilities(context.Context, *Empty) (*CapabilitiesResponse, error)
 	Commands(context.Context, *Empty) (*CommandsResponse, error)
+	Ack(context.Context, *CommandAck) (*Empty, error)
+	Resume(context.Context, *ResumeRequest) (*ResumeResponse, error)
+	Environment(context.Context, *Empty) (*EnvironmentResponse, error)
+	Manifest(context.Context, *ManifestRequest) (*ManifestResponse, error)
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	Resource(*ResourceRequest, RootfsServer_ResourceServer) error
+	ResourceDelta(*ResourceDeltaRequest, RootfsServer_ResourceDeltaServer) error
+	ResourceBlockDelta(*BlockDeltaRequest, RootfsServer_ResourceBlockDeltaServer) error
+	Secret(context.Context, *SecretRequest) (*SecretResponse, error)
+	Status(context.Context, *Empty) (*StatusResponse, error)
+	PortForward(context.Context, *PortForwardRequest) (*PortForwardResponse, error)
+	PortForwardClose(context.Context, *PortForwardCloseRequest) (*Empty, error)
 	StdErr(context.Context, *LogMessage) (*Empty, error)
 	StdOut(context.Context, *LogMessage) (*Empty, error)
-	Abort(context.Context, *AbortRequest) (*Empty, error)
+	Flush(context.Context, *FlushRequest) (*FlushResponse, error)
+	Progress(context.Context, *ProgressReport) (*Empty, error)
+	Warning(context.Context, *WarningMessage) (*Empty, error)
+	Abort(context.Context, *AbortRequest) (*AbortResponse, error)
+	Debug(RootfsServer_DebugServer) error
+	Watch(*Empty, RootfsServer_WatchServer) error
+	WatchLogs(*Empty, RootfsServer_WatchLogsServer) error
 	Success(context.Context, *Empty) (*Empty, error)
 }
 
@@ -138,24 +439,75 @@ type RootfsServerServer interface {
 type UnimplementedRootfsServerServer struct {
 }
 
+func (UnimplementedRootfsServerServer) Capabilities(context.Context, *Empty) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
 func (UnimplementedRootfsServerServer) Commands(context.Context, *Empty) (*CommandsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Commands not implemented")
 }
+func (UnimplementedRootfsServerServer) Ack(context.Context, *CommandAck) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ack not implemented")
+}
+func (UnimplementedRootfsServerServer) Resume(context.Context, *ResumeRequest) (*ResumeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
+func (UnimplementedRootfsServerServer) Environment(context.Context, *Empty) (*EnvironmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Environment not implemented")
+}
+func (UnimplementedRootfsServerServer) Manifest(context.Context, *ManifestRequest) (*ManifestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Manifest not implemented")
+}
 func (UnimplementedRootfsServerServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
 func (UnimplementedRootfsServerServer) Resource(*ResourceRequest, RootfsServer_ResourceServer) error {
 	return status.Errorf(codes.Unimplemented, "method Resource not implemented")
 }
+func (UnimplementedRootfsServerServer) ResourceDelta(*ResourceDeltaRequest, RootfsServer_ResourceDeltaServer) error {
+	return status.Errorf(codes.Unimplemented, "method ResourceDelta not implemented")
+}
+func (UnimplementedRootfsServerServer) ResourceBlockDelta(*BlockDeltaRequest, RootfsServer_ResourceBlockDeltaServer) error {
+	return status.Errorf(codes.Unimplemented, "method ResourceBlockDelta not implemented")
+}
+func (UnimplementedRootfsServerServer) Secret(context.Context, *SecretRequest) (*SecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Secret not implemented")
+}
+func (UnimplementedRootfsServerServer) Status(context.Context, *Empty) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedRootfsServerServer) PortForward(context.Context, *PortForwardRequest) (*PortForwardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PortForward not implemented")
+}
+func (UnimplementedRootfsServerServer) PortForwardClose(context.Context, *PortForwardCloseRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PortForwardClose not implemented")
+}
 func (UnimplementedRootfsServerServer) StdErr(context.Context, *LogMessage) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StdErr not implemented")
 }
 func (UnimplementedRootfsServerServer) StdOut(context.Context, *LogMessage) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StdOut not implemented")
 }
-func (UnimplementedRootfsServerServer) Abort(context.Context, *AbortRequest) (*Empty, error) {
+func (UnimplementedRootfsServerServer) Flush(context.Context, *FlushRequest) (*FlushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedRootfsServerServer) Progress(context.Context, *ProgressReport) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Progress not implemented")
+}
+func (UnimplementedRootfsServerServer) Warning(context.Context, *WarningMessage) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Warning not implemented")
+}
+func (UnimplementedRootfsServerServer) Abort(context.Context, *AbortRequest) (*AbortResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Abort not implemented")
 }
+func (UnimplementedRootfsServerServer) Debug(RootfsServer_DebugServer) error {
+	return status.Errorf(codes.Unimplemented, "method Debug not implemented")
+}
+func (UnimplementedRootfsServerServer) Watch(*Empty, RootfsServer_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedRootfsServerServer) WatchLogs(*Empty, RootfsServer_WatchLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLogs not implemented")
+}
 func (UnimplementedRootfsServerServer) Success(context.Context, *Empty) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Success not implemented")
 }
@@ -171,6 +523,24 @@ func RegisterRootfsServerServer(s grpc.ServiceRegistrar, srv RootfsServerServer)
 	s.RegisterService(&RootfsServer_ServiceDesc, srv)
 }
 
+func _RootfsServer_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Capabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Capabilities(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RootfsServer_Commands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
@@ -189,6 +559,78 @@ func _RootfsServer_Commands_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RootfsServer_Ack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandAck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Ack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Ack",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Ack(ctx, req.(*CommandAck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Resume(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Environment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Environment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Environment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Environment(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Manifest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ManifestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Manifest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Manifest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Manifest(ctx, req.(*ManifestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RootfsServer_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PingRequest)
 	if err := dec(in); err != nil {
@@ -228,6 +670,120 @@ func (x *rootfsServerResourceServer) Send(m *ResourceChunk) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _RootfsServer_ResourceDelta_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ResourceDeltaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RootfsServerServer).ResourceDelta(m, &rootfsServerResourceDeltaServer{stream})
+}
+
+type RootfsServer_ResourceDeltaServer interface {
+	Send(*ResourceChunk) error
+	grpc.ServerStream
+}
+
+type rootfsServerResourceDeltaServer struct {
+	grpc.ServerStream
+}
+
+func (x *rootfsServerResourceDeltaServer) Send(m *ResourceChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RootfsServer_ResourceBlockDelta_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockDeltaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RootfsServerServer).ResourceBlockDelta(m, &rootfsServerResourceBlockDeltaServer{stream})
+}
+
+type RootfsServer_ResourceBlockDeltaServer interface {
+	Send(*BlockDeltaFrame) error
+	grpc.ServerStream
+}
+
+type rootfsServerResourceBlockDeltaServer struct {
+	grpc.ServerStream
+}
+
+func (x *rootfsServerResourceBlockDeltaServer) Send(m *BlockDeltaFrame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RootfsServer_Secret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Secret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Secret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Secret(ctx, req.(*SecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Status(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_PortForward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).PortForward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/PortForward",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).PortForward(ctx, req.(*PortForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_PortForwardClose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortForwardCloseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).PortForwardClose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/PortForwardClose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).PortForwardClose(ctx, req.(*PortForwardCloseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RootfsServer_StdErr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(LogMessage)
 	if err := dec(in); err != nil {
@@ -264,6 +820,60 @@ func _RootfsServer_StdOut_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RootfsServer_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Flush",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Flush(ctx, req.(*FlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Progress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProgressReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Progress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Progress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Progress(ctx, req.(*ProgressReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RootfsServer_Warning_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WarningMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RootfsServerServer).Warning(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RootfsServer/Warning",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RootfsServerServer).Warning(ctx, req.(*WarningMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RootfsServer_Abort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AbortRequest)
 	if err := dec(in); err != nil {
@@ -282,6 +892,74 @@ func _RootfsServer_Abort_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RootfsServer_Debug_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RootfsServerServer).Debug(&rootfsServerDebugServer{stream})
+}
+
+type RootfsServer_DebugServer interface {
+	Send(*DebugFrame) error
+	Recv() (*DebugFrame, error)
+	grpc.ServerStream
+}
+
+type rootfsServerDebugServer struct {
+	grpc.ServerStream
+}
+
+func (x *rootfsServerDebugServer) Send(m *DebugFrame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *rootfsServerDebugServer) Recv() (*DebugFrame, error) {
+	m := new(DebugFrame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _RootfsServer_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RootfsServerServer).Watch(m, &rootfsServerWatchServer{stream})
+}
+
+type RootfsServer_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type rootfsServerWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *rootfsServerWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RootfsServer_WatchLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RootfsServerServer).WatchLogs(m, &rootfsServerWatchLogsServer{stream})
+}
+
+type RootfsServer_WatchLogsServer interface {
+	Send(*LogLine) error
+	grpc.ServerStream
+}
+
+type rootfsServerWatchLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *rootfsServerWatchLogsServer) Send(m *LogLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _RootfsServer_Success_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
@@ -307,14 +985,50 @@ var RootfsServer_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.RootfsServer",
 	HandlerType: (*RootfsServerServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Capabilities",
+			Handler:    _RootfsServer_Capabilities_Handler,
+		},
 		{
 			MethodName: "Commands",
 			Handler:    _RootfsServer_Commands_Handler,
 		},
+		{
+			MethodName: "Ack",
+			Handler:    _RootfsServer_Ack_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _RootfsServer_Resume_Handler,
+		},
+		{
+			MethodName: "Environment",
+			Handler:    _RootfsServer_Environment_Handler,
+		},
+		{
+			MethodName: "Manifest",
+			Handler:    _RootfsServer_Manifest_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _RootfsServer_Ping_Handler,
 		},
+		{
+			MethodName: "Secret",
+			Handler:    _RootfsServer_Secret_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _RootfsServer_Status_Handler,
+		},
+		{
+			MethodName: "PortForward",
+			Handler:    _RootfsServer_PortForward_Handler,
+		},
+		{
+			MethodName: "PortForwardClose",
+			Handler:    _RootfsServer_PortForwardClose_Handler,
+		},
 		{
 			MethodName: "StdErr",
 			Handler:    _RootfsServer_StdErr_Handler,
@@ -323,6 +1037,18 @@ var RootfsServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StdOut",
 			Handler:    _RootfsServer_StdOut_Handler,
 		},
+		{
+			MethodName: "Flush",
+			Handler:    _RootfsServer_Flush_Handler,
+		},
+		{
+			MethodName: "Progress",
+			Handler:    _RootfsServer_Progress_Handler,
+		},
+		{
+			MethodName: "Warning",
+			Handler:    _RootfsServer_Warning_Handler,
+		},
 		{
 			MethodName: "Abort",
 			Handler:    _RootfsServer_Abort_Handler,
@@ -338,6 +1064,32 @@ var RootfsServer_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _RootfsServer_Resource_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ResourceDelta",
+			Handler:       _RootfsServer_ResourceDelta_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ResourceBlockDelta",
+			Handler:       _RootfsServer_ResourceBlockDelta_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Debug",
+			Handler:       _RootfsServer_Debug_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _RootfsServer_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchLogs",
+			Handler:       _RootfsServer_WatchLogs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "rootfs_server.proto",
 }
@@ -0,0 +1,19 @@
+// Package pipe serves the rootfs gRPC service over a pair of named pipes or a bidirectional character device,
+// for hosts and guests without TCP or vsock networking, for example nested or unit test setups.
+// The transport is not available on Windows.
+//
+// Importing the package registers the pipe transport. The address is the path the side reads from and the path
+// the side writes to, separated by a comma, or the path of a single bidirectional character device,
+// for example a virtio-serial port. The client address of a pair is the server address reversed, see PeerAddress:
+//
+//	import _ "github.com/combust-labs/firebuild-shared/transport/pipe"
+//
+//	srv, err := rootfs.NewWithTransport(&rootfs.GRPCServiceConfig{BindHostPort: "pipe:///run/build/to-host,/run/build/to-guest"}, logger)
+//	client, err := rootfs.NewClient(logger, &rootfs.GRPCClientConfig{HostPort: "pipe:///run/build/to-guest,/run/build/to-host", ...})
+//
+// The pipes carry a single connection at a time, the messages are the same as over TCP, TLS included.
+// The named pipes must exist, see syscall.Mkfifo.
+package pipe
+
+// Scheme is the scheme the transport is registered under.
+const Scheme = "pipe"
@@ -0,0 +1,267 @@
+//go:build !windows
+// +build !windows
+
+package pipe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+)
+
+func init() {
+	rootfs.RegisterTransport(Scheme, New)
+	rootfs.RegisterTransportDialer(Scheme, Dial)
+}
+
+// New returns a new instance of the server serving over named pipes.
+func New(cfg *rootfs.GRPCServiceConfig, logger hclog.Logger) rootfs.ServerProvider {
+	return rootfs.NewWithListener(cfg, logger, Listen)
+}
+
+// PeerAddress returns the address the other side of a pair of named pipes uses, the paths of the address reversed.
+// The address of a bidirectional character device is returned as is.
+func PeerAddress(address string) string {
+	readPath, writePath, err := parseAddress(address)
+	if err != nil || readPath == writePath {
+		return address
+	}
+	return writePath + "," + readPath
+}
+
+// Listen creates a listener accepting one connection at a time over the pipes of the address.
+// The next connection is accepted once the previous connection closed.
+func Listen(address string, _ *tls.Config) (net.Listener, error) {
+	readPath, writePath, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	for _, pipePath := range []string{readPath, writePath} {
+		if _, err := os.Stat(pipePath); err != nil {
+			return nil, err
+		}
+	}
+	return &pipeListener{
+		address:    pipeAddr(address),
+		readPath:   readPath,
+		writePath:  writePath,
+		chanClosed: make(chan struct{}),
+	}, nil
+}
+
+// Dial connects to the server over the pipes of the address. The pipe the client writes to is opened first,
+// it is the pipe the server waits on.
+func Dial(ctx context.Context, address string, _ *tls.Config) (net.Conn, error) {
+	readPath, writePath, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if readPath == writePath {
+		file, err := os.OpenFile(readPath, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		return newPipeConn(address, file, file), nil
+	}
+	writer, err := openContext(ctx, writePath, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := openContext(ctx, readPath, os.O_RDONLY)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	return newPipeConn(address, reader, writer), nil
+}
+
+// parseAddress returns the path to read from and the path to write to, equal for a character device.
+func parseAddress(address string) (string, string, error) {
+	paths := strings.Split(address, ",")
+	switch {
+	case len(paths) == 1 && paths[0] != "":
+		return paths[0], paths[0], nil
+	case len(paths) == 2 && paths[0] != "" && paths[1] != "" && paths[0] != paths[1]:
+		return paths[0], paths[1], nil
+	}
+	return "", "", fmt.Errorf("%w: pipe address '%s' is not a device path or a pair of named pipe paths", rootfs.ErrInvalidArgument, address)
+}
+
+// openContext opens a named pipe, the open blocks until the other side opens the pipe.
+// When the context is done first, the pipe is opened from this side to release the open.
+func openContext(ctx context.Context, pipePath string, flag int) (*os.File, error) {
+	type openResult struct {
+		file *os.File
+		err  error
+	}
+	chanResult := make(chan openResult, 1)
+	go func() {
+		file, err := os.OpenFile(pipePath, flag, 0)
+		chanResult <- openResult{file: file, err: err}
+	}()
+	select {
+	case result := <-chanResult:
+		return result.file, result.err
+	case <-ctx.Done():
+		releaseOpen(pipePath, flag)
+		if result := <-chanResult; result.file != nil {
+			result.file.Close()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaseOpen releases an open of the named pipe blocked waiting for the other side by briefly opening the other side.
+func releaseOpen(pipePath string, flag int) {
+	counterpart := os.O_WRONLY
+	if flag == os.O_WRONLY {
+		counterpart = os.O_RDONLY
+	}
+	if file, err := os.OpenFile(pipePath, counterpart|syscall.O_NONBLOCK, 0); err == nil {
+		file.Close()
+	}
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return Scheme }
+func (a pipeAddr) String() string  { return string(a) }
+
+// pipeListener accepts the connection over the pipes once the previous connection closed.
+type pipeListener struct {
+	m          sync.Mutex
+	address    pipeAddr
+	readPath   string
+	writePath  string
+	closed     bool
+	current    *pipeConn
+	chanClosed chan struct{}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.m.Lock()
+	current := l.current
+	l.m.Unlock()
+	if current != nil {
+		select {
+		case <-current.chanClosed:
+		case <-l.chanClosed:
+			return nil, net.ErrClosed
+		}
+	}
+
+	var conn *pipeConn
+	if l.readPath == l.writePath {
+		file, err := os.OpenFile(l.readPath, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		conn = newPipeConn(string(l.address), file, file)
+	} else {
+		reader, err := l.open(l.readPath, os.O_RDONLY)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := l.open(l.writePath, os.O_WRONLY)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		conn = newPipeConn(string(l.address), reader, writer)
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+	l.current = conn
+	return conn, nil
+}
+
+// open opens a named pipe of the listener, a closed listener releases the open.
+func (l *pipeListener) open(pipePath string, flag int) (*os.File, error) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	go func() {
+		select {
+		case <-l.chanClosed:
+			cancelFunc()
+		case <-ctx.Done():
+		}
+	}()
+	file, err := openContext(ctx, pipePath, flag)
+	if errors.Is(err, context.Canceled) {
+		return nil, net.ErrClosed
+	}
+	return file, err
+}
+
+func (l *pipeListener) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	close(l.chanClosed)
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return l.address
+}
+
+// pipeConn is a connection reading from one pipe and writing to the other, or reading and writing a device.
+type pipeConn struct {
+	address    pipeAddr
+	reader     *os.File
+	writer     *os.File
+	closeOnce  sync.Once
+	chanClosed chan struct{}
+}
+
+func newPipeConn(address string, reader, writer *os.File) *pipeConn {
+	return &pipeConn{address: pipeAddr(address), reader: reader, writer: writer, chanClosed: make(chan struct{})}
+}
+
+func (c *pipeConn) Read(p []byte) (int, error)  { return c.reader.Read(p) }
+func (c *pipeConn) Write(p []byte) (int, error) { return c.writer.Write(p) }
+
+func (c *pipeConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.reader.Close()
+		if c.writer != c.reader {
+			if writeErr := c.writer.Close(); err == nil {
+				err = writeErr
+			}
+		}
+		close(c.chanClosed)
+	})
+	return err
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.address }
+func (c *pipeConn) RemoteAddr() net.Addr { return pipeAddr(PeerAddress(string(c.address))) }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	if err := c.reader.SetDeadline(t); err != nil {
+		return err
+	}
+	return c.writer.SetDeadline(t)
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return c.reader.SetReadDeadline(t) }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return c.writer.SetWriteDeadline(t) }
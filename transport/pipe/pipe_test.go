@@ -0,0 +1,139 @@
+//go:build !windows
+// +build !windows
+
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustMakeFIFOs(t *testing.T, dir string) string {
+	toHost, toGuest := filepath.Join(dir, "to-host"), filepath.Join(dir, "to-guest")
+	for _, fifoPath := range []string{toHost, toGuest} {
+		if err := syscall.Mkfifo(fifoPath, 0600); err != nil {
+			t.Fatal("expected the named pipe to be created", err)
+		}
+	}
+	return toHost + "," + toGuest
+}
+
+func TestServeOverNamedPipes(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &rootfs.GRPCServiceConfig{
+		ServerName:        "test-grpc-server",
+		BindHostPort:      Scheme + "://" + mustMakeFIFOs(t, tempDir),
+		EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+	}
+	srv, err := rootfs.NewWithTransport(grpcConfig, logger.Named("grpc-server"))
+	assert.Nil(t, err)
+	srv.Start(&rootfs.WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.Add{
+				OriginalCommand: "ADD file /etc/file",
+				OriginalSource:  "file",
+				Source:          "file",
+				Target:          "/etc/file",
+				User:            commands.DefaultUser(),
+				Workdir:         commands.DefaultWorkdir(),
+			},
+		},
+		ResourcesResolved: rootfs.Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("contents"))), nil
+				}, fs.FileMode(0644), "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+			},
+		},
+	})
+	defer srv.Stop()
+	select {
+	case startErr := <-srv.FailedNotify():
+		t.Fatal("expected the pipe server to start but it failed", startErr)
+	case <-srv.ReadyNotify():
+	}
+
+	chanSucceeded := make(chan struct{})
+	go func() {
+		for message := range srv.OnMessage() {
+			if _, ok := message.(*rootfs.ClientMsgSuccess); ok {
+				close(chanSucceeded)
+				return
+			}
+		}
+	}()
+
+	testClient, err := rootfs.NewClient(logger.Named("grpc-client"), &rootfs.GRPCClientConfig{
+		HostPort:  Scheme + "://" + PeerAddress(grpcConfig.BindHostPort),
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.Commands())
+	rootfs.MustBeAddCommand(t, testClient, []byte("contents"))
+	assert.Nil(t, testClient.Success())
+	<-chanSucceeded
+}
+
+func TestListenerCloseReleasesAccept(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	listener, err := Listen(mustMakeFIFOs(t, tempDir), nil)
+	assert.Nil(t, err)
+	chanAccepted := make(chan error, 1)
+	go func() {
+		_, err := listener.Accept()
+		chanAccepted <- err
+	}()
+	time.Sleep(time.Millisecond * 50)
+	assert.Nil(t, listener.Close())
+	select {
+	case err := <-chanAccepted:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected the closed listener to stop accepting")
+	}
+}
+
+func TestDialContextDone(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelFunc()
+	_, err = Dial(ctx, PeerAddress(mustMakeFIFOs(t, tempDir)), nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestAddresses(t *testing.T) {
+	assert.Equal(t, "/b,/a", PeerAddress("/a,/b"))
+	assert.Equal(t, "/dev/vport0p1", PeerAddress("/dev/vport0p1"))
+	_, err := Listen("/a,/a", nil)
+	assert.True(t, errors.Is(err, rootfs.ErrInvalidArgument))
+	_, err = Listen(",", nil)
+	assert.True(t, errors.Is(err, rootfs.ErrInvalidArgument))
+}
@@ -0,0 +1,171 @@
+// Package quic serves the rootfs gRPC service over QUIC, for hosts and guests communicating over lossy networks,
+// for example with nested virtualization or remote builders. The transport is experimental.
+//
+// Importing the package registers the quic transport, servers bind and clients connect
+// with a quic:// prefixed host and port:
+//
+//	import _ "github.com/combust-labs/firebuild-shared/transport/quic"
+//
+//	srv, err := rootfs.NewWithTransport(&rootfs.GRPCServiceConfig{BindHostPort: "quic://0.0.0.0:5000"}, logger)
+//	client, err := rootfs.NewClient(logger, &rootfs.GRPCClientConfig{HostPort: "quic://10.0.0.1:5000", ...})
+//
+// Every client connection carries the gRPC traffic in a single QUIC stream, the messages are the same as over TCP.
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+	quicgo "github.com/quic-go/quic-go"
+)
+
+// Scheme is the scheme the transport is registered under.
+const Scheme = "quic"
+
+// NextProto is the ALPN protocol negotiated by the transport.
+const NextProto = "firebuild-rootfs"
+
+// DefaultKeepAlivePeriod is the period of the keep alive packets keeping idle connections open.
+const DefaultKeepAlivePeriod = 10 * time.Second
+
+// DefaultStreamAcceptTimeout is the time a client has to open its stream after the handshake.
+const DefaultStreamAcceptTimeout = 10 * time.Second
+
+func init() {
+	rootfs.RegisterTransport(Scheme, New)
+	rootfs.RegisterTransportDialer(Scheme, Dial)
+}
+
+// New returns a new instance of the server serving over QUIC.
+func New(cfg *rootfs.GRPCServiceConfig, logger hclog.Logger) rootfs.ServerProvider {
+	return rootfs.NewWithListener(cfg, logger, Listen)
+}
+
+// Listen creates a listener accepting QUIC connections on the UDP address.
+func Listen(address string, tlsConfig *tls.Config) (net.Listener, error) {
+	listener, err := quicgo.ListenAddr(address, withNextProto(tlsConfig), quicConfig())
+	if err != nil {
+		return nil, err
+	}
+	streamListener := &streamListener{
+		listener:     listener,
+		chanAccepted: make(chan net.Conn),
+		chanDone:     make(chan struct{}),
+	}
+	go streamListener.acceptConnections()
+	return streamListener, nil
+}
+
+// Dial connects to the QUIC server at the UDP address.
+func Dial(ctx context.Context, address string, tlsConfig *tls.Config) (net.Conn, error) {
+	conn, err := quicgo.DialAddr(ctx, address, withNextProto(tlsConfig), quicConfig())
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	return &streamConn{Stream: stream, conn: conn}, nil
+}
+
+func quicConfig() *quicgo.Config {
+	return &quicgo.Config{KeepAlivePeriod: DefaultKeepAlivePeriod}
+}
+
+func withNextProto(tlsConfig *tls.Config) *tls.Config {
+	result := &tls.Config{}
+	if tlsConfig != nil {
+		result = tlsConfig.Clone()
+	}
+	result.NextProtos = []string{NextProto}
+	return result
+}
+
+// streamListener accepts the first stream of every QUIC connection as a connection.
+// The streams are accepted concurrently, a client which never opens its stream does not hold up the others.
+type streamListener struct {
+	listener     *quicgo.Listener
+	chanAccepted chan net.Conn
+	chanDone     chan struct{}
+
+	m   sync.Mutex
+	err error
+}
+
+func (l *streamListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.chanAccepted:
+		return conn, nil
+	case <-l.chanDone:
+		l.m.Lock()
+		defer l.m.Unlock()
+		return nil, l.err
+	}
+}
+
+// acceptConnections accepts QUIC connections until the listener fails or is closed.
+func (l *streamListener) acceptConnections() {
+	for {
+		conn, err := l.listener.Accept(context.Background())
+		if err != nil {
+			l.m.Lock()
+			l.err = err
+			l.m.Unlock()
+			close(l.chanDone)
+			return
+		}
+		go l.acceptStream(conn)
+	}
+}
+
+// acceptStream hands the first stream of the connection to Accept.
+func (l *streamListener) acceptStream(conn quicgo.Connection) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultStreamAcceptTimeout)
+	defer cancel()
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		// the client went away or did not open the stream in time:
+		conn.CloseWithError(0, "")
+		return
+	}
+	select {
+	case l.chanAccepted <- &streamConn{Stream: stream, conn: conn}:
+	case <-l.chanDone:
+		stream.Close()
+		conn.CloseWithError(0, "")
+	}
+}
+
+func (l *streamListener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *streamListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// streamConn is a connection reading and writing a QUIC stream.
+type streamConn struct {
+	quicgo.Stream
+	conn quicgo.Connection
+}
+
+func (c *streamConn) Close() error {
+	c.Stream.Close()
+	return c.conn.CloseWithError(0, "")
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
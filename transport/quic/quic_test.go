@@ -0,0 +1,81 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+
+	"github.com/combust-labs/firebuild-shared/build/commands"
+	"github.com/combust-labs/firebuild-shared/build/resources"
+	"github.com/combust-labs/firebuild-shared/build/rootfs"
+	"github.com/hashicorp/go-hclog"
+	quicgo "github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeOverQUIC(t *testing.T) {
+	logger := hclog.Default()
+	logger.SetLevel(hclog.Debug)
+
+	grpcConfig := &rootfs.GRPCServiceConfig{
+		ServerName:        "test-grpc-server",
+		BindHostPort:      "quic://127.0.0.1:0",
+		EmbeddedCAKeySize: 1024, // use this low for tests only! low value speeds up tests
+	}
+	srv, err := rootfs.NewWithTransport(grpcConfig, logger.Named("grpc-server"))
+	assert.Nil(t, err)
+	srv.Start(&rootfs.WorkContext{
+		ExecutableCommands: []commands.VMInitSerializableCommand{
+			commands.Add{
+				OriginalCommand: "ADD file /etc/file",
+				OriginalSource:  "file",
+				Source:          "file",
+				Target:          "/etc/file",
+				User:            commands.DefaultUser(),
+				Workdir:         commands.DefaultWorkdir(),
+			},
+		},
+		ResourcesResolved: rootfs.Resources{
+			"file": []resources.ResolvedResource{
+				resources.NewResolvedFileResourceWithPath(func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader([]byte("contents"))), nil
+				}, fs.FileMode(0644), "file", "/etc/file", commands.DefaultWorkdir(), commands.DefaultUser(), "file"),
+			},
+		},
+	})
+	defer srv.Stop()
+	select {
+	case startErr := <-srv.FailedNotify():
+		t.Fatal("expected the QUIC server to start but it failed", startErr)
+	case <-srv.ReadyNotify():
+	}
+
+	chanSucceeded := make(chan struct{})
+	go func() {
+		for message := range srv.OnMessage() {
+			if _, ok := message.(*rootfs.ClientMsgSuccess); ok {
+				close(chanSucceeded)
+				return
+			}
+		}
+	}()
+
+	// a peer which completes the handshake but never opens a stream does not block the other clients:
+	idleConn, err := quicgo.DialAddr(context.Background(), grpcConfig.BindHostPort, withNextProto(grpcConfig.TLSConfigClient), quicConfig())
+	assert.Nil(t, err)
+	defer idleConn.CloseWithError(0, "")
+
+	testClient, err := rootfs.NewClient(logger.Named("grpc-client"), &rootfs.GRPCClientConfig{
+		HostPort:  Scheme + "://" + grpcConfig.BindHostPort,
+		TLSConfig: grpcConfig.TLSConfigClient,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, testClient.Commands())
+	rootfs.MustBeAddCommand(t, testClient, []byte("contents"))
+	assert.Nil(t, testClient.Success())
+	<-chanSucceeded
+}